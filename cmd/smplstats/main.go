@@ -0,0 +1,212 @@
+/*
+smplstats reads numbers from the standard input or from the files named on
+the command line and prints a summary of their statistics and a histogram
+of their values.
+
+The numbers should be separated by white space (including newlines) or, if
+the -col flag is given, be in a column of CSV records.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/smpls.mod/smpls"
+)
+
+// formats maps the names of the output formats to the template used to
+// produce them. The text format (the String and Hist of the Stat) and the
+// json format are handled separately.
+var formats = map[string]string{
+	"compact":  smpls.TmplCompact,
+	"verbose":  smpls.TmplVerbose,
+	"markdown": smpls.TmplMarkdown,
+}
+
+// prog holds the values set by the command line flags
+type prog struct {
+	units     string
+	buckets   int
+	quantiles []float64
+	format    string
+	prefixes  bool
+	col       int
+	hasHeader bool
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "smplstats:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the arguments, reads the values and writes the report
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	p, files, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	opts := []smpls.StatOpt{}
+	if p.buckets > 0 {
+		opts = append(opts, smpls.StatHistBucketCount(p.buckets))
+	}
+	if p.prefixes {
+		opts = append(opts, smpls.StatUnitPrefixes())
+	}
+	s, err := smpls.NewStat(p.units, opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		if err := p.addFrom(s, stdin); err != nil {
+			return fmt.Errorf("<stdin>: %w", err)
+		}
+	}
+	for _, fname := range files {
+		if err := p.addFromFile(s, fname); err != nil {
+			return err
+		}
+	}
+
+	return p.report(s, stdout)
+}
+
+// parseArgs parses the command line flags, returning the program settings
+// and the remaining (file name) arguments
+func parseArgs(args []string) (*prog, []string, error) {
+	p := &prog{}
+	var quantiles string
+
+	fs := flag.NewFlagSet("smplstats", flag.ContinueOnError)
+	fs.StringVar(&p.units, "units", "", "the units of the values")
+	fs.IntVar(&p.buckets, "buckets", 0,
+		"the number of histogram buckets (0 for the default)")
+	fs.StringVar(&quantiles, "quantiles", "50,90,99",
+		"a comma-separated list of the percentiles to report"+
+			" (text and json formats only)")
+	fs.StringVar(&p.format, "format", "text",
+		"the output format: text, compact, verbose, markdown or json")
+	fs.BoolVar(&p.prefixes, "prefixes", false,
+		"show the values with unit prefixes (ms, MiB, ...)")
+	fs.IntVar(&p.col, "col", -1,
+		"read the values from this column of CSV records"+
+			" (counting from zero)")
+	fs.BoolVar(&p.hasHeader, "header", false,
+		"the first CSV record is a header and is skipped")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := formats[p.format]; !ok &&
+		p.format != "text" && p.format != "json" {
+		return nil, nil, fmt.Errorf("unknown format: %q", p.format)
+	}
+	// the templated formats show their own, fixed, percentiles
+	if _, ok := formats[p.format]; ok {
+		var err error
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "quantiles" {
+				err = fmt.Errorf(
+					"the -quantiles flag cannot be used with the %s format",
+					p.format)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, q := range strings.Split(quantiles, ",") {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		if err != nil || v < 0 || v > 100 {
+			return nil, nil, fmt.Errorf(
+				"bad quantile: %q - it must be a number from 0 to 100", q)
+		}
+		p.quantiles = append(p.quantiles, v)
+	}
+
+	return p, fs.Args(), nil
+}
+
+// addFromFile adds the values from the named file to the Stat
+func (p prog) addFromFile(s *smpls.Stat, fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := p.addFrom(s, f); err != nil {
+		return fmt.Errorf("%s: %w", fname, err)
+	}
+	return nil
+}
+
+// addFrom adds the values read from r to the Stat
+func (p prog) addFrom(s *smpls.Stat, r io.Reader) error {
+	var err error
+	if p.col >= 0 {
+		_, err = s.AddFromCSVColumn(r, p.col, p.hasHeader)
+	} else {
+		_, err = s.AddFromReader(r)
+	}
+	return err
+}
+
+// report writes the statistics in the chosen format
+func (p prog) report(s *smpls.Stat, w io.Writer) error {
+	switch p.format {
+	case "text":
+		fmt.Fprintln(w, s)
+		for _, q := range p.quantiles {
+			fmt.Fprintf(w, "p%g: %s\n", q, p.fmtVal(s, s.Percentile(q)))
+		}
+		if s.Count() > 0 {
+			fmt.Fprint(w, s.Hist())
+		}
+		return nil
+	case "json":
+		snap := s.Snapshot()
+		snap.Percentiles = nil
+		for _, q := range p.quantiles {
+			snap.Percentiles = append(snap.Percentiles,
+				smpls.Pctile{Pct: q, Val: s.Percentile(q)})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snap)
+	}
+
+	str, err := s.Render(formats[p.format])
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, str)
+	if err == nil && !strings.HasSuffix(str, "\n") {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
+
+// fmtVal formats the value either with a unit prefix or in scientific
+// notation
+func (p prog) fmtVal(s *smpls.Stat, v float64) string {
+	if p.prefixes {
+		return smpls.FmtWithPrefix(v, s.Units())
+	}
+	return fmt.Sprintf("%8.2e", v)
+}
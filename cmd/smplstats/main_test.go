@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "vals.txt")
+	if err := os.WriteFile(fname, []byte("10 20\n30\n"), 0o600); err != nil {
+		t.Fatal("cannot create the test file:", err)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		args      []string
+		stdin     string
+		expOutput []string
+	}{
+		{
+			ID:    testhelper.MkID("stdin, text"),
+			args:  []string{"-units", "ms", "-quantiles", "50"},
+			stdin: "1 2 3\n",
+			expOutput: []string{
+				"      3 observations,",
+				"p50: 2.00e+00\n",
+				"units: ms\n",
+			},
+		},
+		{
+			ID:        testhelper.MkID("file, compact"),
+			args:      []string{"-format", "compact", fname},
+			expOutput: []string{"3 obs, min: 1.00e+01,"},
+		},
+		{
+			ID:    testhelper.MkID("csv, json"),
+			args:  []string{"-col", "1", "-header", "-format", "json"},
+			stdin: "name,val\na,4\nb,6\n",
+			expOutput: []string{
+				`"Count": 2,`,
+				`"Mean": 5,`,
+				`"Pct": 99,`,
+			},
+		},
+		{
+			ID:        testhelper.MkID("prefixes"),
+			args:      []string{"-units", "bytes", "-prefixes"},
+			stdin:     "2048 4096\n",
+			expOutput: []string{"avg: 3.00 KiB", "p90: 3.80 KiB"},
+		},
+		{
+			ID:     testhelper.MkID("bad format"),
+			ExpErr: testhelper.MkExpErr(`unknown format: "xml"`),
+			args:   []string{"-format", "xml"},
+		},
+		{
+			ID:     testhelper.MkID("bad quantile"),
+			ExpErr: testhelper.MkExpErr(`bad quantile: "101"`),
+			args:   []string{"-quantiles", "50,101"},
+		},
+		{
+			ID: testhelper.MkID("quantiles with a fixed format"),
+			ExpErr: testhelper.MkExpErr(
+				"the -quantiles flag cannot be used with the markdown format"),
+			args: []string{"-format", "markdown", "-quantiles", "50"},
+		},
+		{
+			ID:     testhelper.MkID("bad value"),
+			ExpErr: testhelper.MkExpErr(`<stdin>: line 2: cannot parse "x"`),
+			stdin:  "1\nx\n",
+		},
+		{
+			ID:     testhelper.MkID("missing file"),
+			ExpErr: testhelper.MkExpErr("no such file"),
+			args:   []string{filepath.Join(dir, "nonesuch")},
+		},
+	}
+
+	for _, tc := range testCases {
+		var out strings.Builder
+		err := run(tc.args, strings.NewReader(tc.stdin), &out)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.ShouldContain(t, tc.IDStr(), "output",
+				out.String(), tc.expOutput)
+		}
+	}
+}
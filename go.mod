@@ -1,8 +1,8 @@
 module github.com/nickwells/smpls.mod
 
-go 1.21
+go 1.23
 
-toolchain go1.21.0
+toolchain go1.23.0
 
 require (
 	github.com/nickwells/mathutil.mod/v2 v2.4.0
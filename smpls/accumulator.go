@@ -0,0 +1,86 @@
+package smpls
+
+import (
+	"slices"
+)
+
+// Accumulator is the interface to be satisfied by any custom computation
+// that is to be given the values added to a Stat (see
+// StatWithAccumulator). This lets you attach your own online calculations
+// to a Stat.
+//
+// If the Accumulator also has a Reset method, taking no parameters and
+// returning nothing, it is called when the Stat is Reset. If it has a
+// Clone method returning an Accumulator then it is cloned, by calling
+// this, when the Stat is cloned; otherwise the clone of the Stat will not
+// have the Accumulator.
+type Accumulator interface {
+	Add(v float64)
+	Result() any
+}
+
+// accResetter is the interface satisfied by an Accumulator which can be
+// reset
+type accResetter interface {
+	Reset()
+}
+
+// accCloner is the interface satisfied by an Accumulator which can be
+// cloned
+type accCloner interface {
+	Clone() Accumulator
+}
+
+// StatWithAccumulator returns a function that will add the Accumulators to
+// the Stat. Each value recorded by the Stat is passed to each of the
+// Accumulators, in the order they were added; if the Stat has a sample
+// rate (see StatSampleRate) only the sampled values are passed. Note that
+// the Accumulators are not saved by the Save method and are not merged by
+// the Merge method.
+func StatWithAccumulator(accs ...Accumulator) StatOpt {
+	return func(s *Stat) error {
+		for _, acc := range accs {
+			if acc == nil {
+				return invalidValue("the Accumulator must not be nil")
+			}
+		}
+
+		s.accs = append(s.accs, accs...)
+		return nil
+	}
+}
+
+// Accumulators returns the Accumulators added to the Stat
+func (s Stat) Accumulators() []Accumulator {
+	return slices.Clone(s.accs)
+}
+
+// AccumulatorResults returns the results of the Accumulators added to the
+// Stat, in the order they were added
+func (s Stat) AccumulatorResults() []any {
+	results := make([]any, 0, len(s.accs))
+	for _, acc := range s.accs {
+		results = append(results, acc.Result())
+	}
+	return results
+}
+
+// resetAccs resets those Accumulators which can be reset
+func (s *Stat) resetAccs() {
+	for _, acc := range s.accs {
+		if r, ok := acc.(accResetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// cloneAccs returns clones of those Accumulators which can be cloned
+func cloneAccs(accs []Accumulator) []Accumulator {
+	var clones []Accumulator
+	for _, acc := range accs {
+		if c, ok := acc.(accCloner); ok {
+			clones = append(clones, c.Clone())
+		}
+	}
+	return clones
+}
@@ -0,0 +1,76 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// sumOfDiffs is an Accumulator recording the sum of the absolute
+// differences between successive values
+type sumOfDiffs struct {
+	prev    float64
+	started bool
+	total   float64
+}
+
+func (sd *sumOfDiffs) Add(v float64) {
+	if sd.started {
+		d := v - sd.prev
+		if d < 0 {
+			d = -d
+		}
+		sd.total += d
+	}
+	sd.prev, sd.started = v, true
+}
+
+func (sd *sumOfDiffs) Result() any { return sd.total }
+
+func (sd *sumOfDiffs) Reset() { *sd = sumOfDiffs{} }
+
+func (sd *sumOfDiffs) Clone() Accumulator {
+	c := *sd
+	return &c
+}
+
+// counter is an Accumulator counting the values; it cannot be reset or
+// cloned
+type counter struct{ n int }
+
+func (c *counter) Add(_ float64) { c.n++ }
+
+func (c *counter) Result() any { return c.n }
+
+func TestAccumulator(t *testing.T) {
+	_, err := NewStat("units", StatWithAccumulator(nil))
+	testhelper.CheckExpErrWithID(t, "nil Accumulator", err,
+		testhelper.MkExpErr("the Accumulator must not be nil"))
+
+	sd, c := &sumOfDiffs{}, &counter{}
+	s := NewStatOrPanic("units",
+		StatMinMaxCount(2), StatCacheSize(2), StatWithAccumulator(sd, c))
+	for _, v := range []float64{1, 4, 2, 2, 7} {
+		s.Add(v)
+	}
+
+	results := s.AccumulatorResults()
+	testhelper.DiffInt(t, "results", "count", len(results), 2)
+	testhelper.DiffFloat(t, "results", "sum of diffs", results[0].(float64),
+		3+2+0+5, 0)
+	testhelper.DiffInt(t, "results", "counter", results[1].(int), 5)
+	testhelper.DiffInt(t, "Accumulators", "count",
+		len(s.Accumulators()), 2)
+
+	clone := s.Clone()
+	testhelper.DiffInt(t, "clone", "Accumulators",
+		len(clone.Accumulators()), 1)
+	clone.Add(17)
+	testhelper.DiffFloat(t, "clone", "sum of diffs",
+		clone.AccumulatorResults()[0].(float64), 20, 0)
+	testhelper.DiffFloat(t, "original", "sum of diffs", sd.total, 10, 0)
+
+	s.Reset()
+	testhelper.DiffFloat(t, "Reset", "sum of diffs", sd.total, 0, 0)
+	testhelper.DiffInt(t, "Reset", "counter", c.n, 5)
+}
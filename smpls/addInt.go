@@ -0,0 +1,56 @@
+package smpls
+
+import "fmt"
+
+// maxExactInt is the largest integer magnitude up to which every integer
+// can be held exactly in a float64
+const maxExactInt = 1 << 53
+
+// checkExactInt returns an error if the value cannot be held exactly in a
+// float64
+func checkExactInt(v int64) error {
+	if v > maxExactInt || v < -maxExactInt {
+		return fmt.Errorf("the value (%d) cannot be held exactly"+
+			" - its magnitude must be <= 2^53", v)
+	}
+	return nil
+}
+
+// AddInt adds the integer value to the Stat. The value is not added and an
+// error is returned if it is too large to be held exactly as a float64;
+// that is, if its magnitude is greater than 2^53.
+func (s *Stat) AddInt(v int64) error {
+	if err := checkExactInt(v); err != nil {
+		return err
+	}
+	s.addVal(float64(v))
+	return nil
+}
+
+// AddUint adds the unsigned integer value to the Stat. The value is not
+// added and an error is returned if it is too large to be held exactly as
+// a float64; that is, if it is greater than 2^53.
+func (s *Stat) AddUint(v uint64) error {
+	if v > maxExactInt {
+		return fmt.Errorf("the value (%d) cannot be held exactly"+
+			" - it must be <= 2^53", v)
+	}
+	s.addVal(float64(v))
+	return nil
+}
+
+// AddInts adds the integer values to the Stat. The values are all checked
+// before any are added and if any of them is too large to be held exactly
+// as a float64 (see AddInt) none of them are added and an error is
+// returned.
+func (s *Stat) AddInts(vals ...int64) error {
+	for i, v := range vals {
+		if err := checkExactInt(v); err != nil {
+			return fmt.Errorf("bad value [%d]: %w", i, err)
+		}
+	}
+	for _, v := range vals {
+		s.addVal(float64(v))
+	}
+	return nil
+}
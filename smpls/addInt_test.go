@@ -0,0 +1,77 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddInt(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		add      func(s *Stat) error
+		expCount int
+		expSum   float64
+	}{
+		{
+			ID:       testhelper.MkID("AddInt: small"),
+			add:      func(s *Stat) error { return s.AddInt(-42) },
+			expCount: 1,
+			expSum:   -42,
+		},
+		{
+			ID:       testhelper.MkID("AddInt: 2^53"),
+			add:      func(s *Stat) error { return s.AddInt(maxExactInt) },
+			expCount: 1,
+			expSum:   maxExactInt,
+		},
+		{
+			ID:  testhelper.MkID("AddInt: too large"),
+			add: func(s *Stat) error { return s.AddInt(maxExactInt + 1) },
+			ExpErr: testhelper.MkExpErr("the value (9007199254740993)" +
+				" cannot be held exactly - its magnitude must be <= 2^53"),
+		},
+		{
+			ID:  testhelper.MkID("AddInt: too small"),
+			add: func(s *Stat) error { return s.AddInt(math.MinInt64) },
+			ExpErr: testhelper.MkExpErr("the value (-9223372036854775808)" +
+				" cannot be held exactly"),
+		},
+		{
+			ID:       testhelper.MkID("AddUint: small"),
+			add:      func(s *Stat) error { return s.AddUint(42) },
+			expCount: 1,
+			expSum:   42,
+		},
+		{
+			ID:  testhelper.MkID("AddUint: too large"),
+			add: func(s *Stat) error { return s.AddUint(math.MaxUint64) },
+			ExpErr: testhelper.MkExpErr("the value (18446744073709551615)" +
+				" cannot be held exactly - it must be <= 2^53"),
+		},
+		{
+			ID:       testhelper.MkID("AddInts: good"),
+			add:      func(s *Stat) error { return s.AddInts(1, 2, 3) },
+			expCount: 3,
+			expSum:   6,
+		},
+		{
+			ID: testhelper.MkID("AddInts: one bad"),
+			add: func(s *Stat) error {
+				return s.AddInts(1, 2, maxExactInt+1)
+			},
+			ExpErr: testhelper.MkExpErr("bad value [2]",
+				"cannot be held exactly"),
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("bytes")
+		err := tc.add(s)
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), tc.expCount)
+		testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 0)
+	}
+}
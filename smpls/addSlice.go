@@ -0,0 +1,89 @@
+package smpls
+
+import (
+	"math"
+	"slices"
+)
+
+// minMaxFoldFactor controls how many candidate minimum (or maximum) values
+// AddSlice will gather before folding them into the slice of minimum (or
+// maximum) values. It is a multiple of the size of that slice.
+const minMaxFoldFactor = 8
+
+// AddSlice adds the values to the Stat. The result is the same as adding
+// each value in turn with Add but, once the histogram is in use and if none
+// of the optional features which need extra work when a value is added are
+// in use, it is considerably faster. The values are added in a single pass
+// and the candidate minimum and maximum values are gathered and merged into
+// the slices of minimum and maximum values in bulk.
+func (s *Stat) AddSlice(vals []float64) {
+	for len(vals) > 0 && !s.hot {
+		s.addVal(vals[0])
+		vals = vals[1:]
+	}
+	if len(vals) == 0 {
+		return
+	}
+
+	sum, sumSq := s.sum, s.sumSq
+	minLim, maxLim := s.mins[len(s.mins)-1], s.maxs[0]
+	foldAt := minMaxFoldFactor * cap(s.mins)
+	var lows, highs []float64
+
+	for _, v := range vals {
+		sum += v
+		sumSq += v * v
+
+		if v < minLim {
+			lows = append(lows, v)
+			if len(lows) >= foldAt {
+				minLim = s.foldMins(lows)
+				lows = lows[:0]
+			}
+		}
+		if v > maxLim {
+			highs = append(highs, v)
+			if len(highs) >= foldAt {
+				maxLim = s.foldMaxs(highs)
+				highs = highs[:0]
+			}
+		}
+
+		idx := int(math.Floor((v - s.bucketStart) / s.bucketWidth))
+		if uint(idx) < uint(len(s.hist)) {
+			s.hist[idx]++
+		} else if idx < 0 {
+			s.underflow++
+		} else {
+			s.overflow++
+		}
+	}
+
+	s.sum, s.sumSq = sum, sumSq
+	s.count += len(vals)
+	s.adds += len(vals)
+	s.foldMins(lows)
+	s.foldMaxs(highs)
+}
+
+// foldMins merges the values into the slice of minimum values keeping only
+// the smallest. It returns the new largest minimum value.
+func (s *Stat) foldMins(vals []float64) float64 {
+	if len(vals) > 0 {
+		merged := append(slices.Clone(s.mins), vals...)
+		slices.Sort(merged)
+		copy(s.mins, merged)
+	}
+	return s.mins[len(s.mins)-1]
+}
+
+// foldMaxs merges the values into the slice of maximum values keeping only
+// the largest. It returns the new smallest maximum value.
+func (s *Stat) foldMaxs(vals []float64) float64 {
+	if len(vals) > 0 {
+		merged := append(slices.Clone(s.maxs), vals...)
+		slices.Sort(merged)
+		copy(s.maxs, merged[len(merged)-len(s.maxs):])
+	}
+	return s.maxs[0]
+}
@@ -0,0 +1,82 @@
+package smpls
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	random := make([]float64, 5000)
+	for i := range random {
+		random[i] = r.NormFloat64() * 10
+	}
+	falling := make([]float64, 5000)
+	for i := range falling {
+		falling[i] = float64(len(falling) - i)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		vals []float64
+		opts []StatOpt
+	}{
+		{ID: testhelper.MkID("empty"), vals: []float64{}},
+		{ID: testhelper.MkID("short"), vals: []float64{3, 1, 2}},
+		{ID: testhelper.MkID("random"), vals: random},
+		{
+			ID:   testhelper.MkID("random, small cache"),
+			vals: random,
+			opts: []StatOpt{StatCacheSize(100)},
+		},
+		{
+			ID:   testhelper.MkID("falling, small cache"),
+			vals: falling,
+			opts: []StatOpt{StatCacheSize(100), StatMinMaxCount(3)},
+		},
+		{
+			ID:   testhelper.MkID("random, compensated"),
+			vals: random,
+			opts: []StatOpt{StatCacheSize(100), StatCompensatedSum()},
+		},
+	}
+
+	for _, tc := range testCases {
+		byAdd := NewStatOrPanic("units", tc.opts...)
+		bySlice := NewStatOrPanic("units", tc.opts...)
+
+		for _, v := range tc.vals {
+			byAdd.Add(v)
+		}
+		// add in two parts to check that a Stat already in use is handled
+		half := len(tc.vals) / 2
+		bySlice.AddSlice(tc.vals[:half])
+		bySlice.AddSlice(tc.vals[half:])
+
+		testhelper.DiffString(t, tc.IDStr(), "String",
+			bySlice.String(), byAdd.String())
+		testhelper.DiffString(t, tc.IDStr(), "Hist",
+			bySlice.Hist(), byAdd.Hist())
+		testhelper.DiffFloatSlice(t, tc.IDStr(), "mins",
+			bySlice.mins, byAdd.mins, 0.0)
+		testhelper.DiffFloatSlice(t, tc.IDStr(), "maxs",
+			bySlice.maxs, byAdd.maxs, 0.0)
+		testhelper.DiffFloat(t, tc.IDStr(), "sumSq",
+			bySlice.sumSq, byAdd.sumSq, 0.0)
+	}
+}
+
+func BenchmarkAddSlice(b *testing.B) {
+	vals := make([]float64, 1000)
+	for i := range vals {
+		vals[i] = float64(i)
+	}
+
+	s := NewStatOrPanic("units")
+	b.ResetTimer()
+	for i := 0; i < b.N; i += len(vals) {
+		s.AddSlice(vals)
+	}
+}
@@ -0,0 +1,176 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+// These are the periods of some common periodic quantities
+const (
+	PeriodDegrees = 360.0
+	PeriodRadians = 2 * math.Pi
+	PeriodHours   = 24.0
+)
+
+// AngleStat collects statistics on periodic quantities such as phases,
+// headings or times of day. The ordinary mean and standard deviation are
+// wrong for such values since they wrap around: the mean of 350 and 10
+// degrees should be 0 degrees, not 180. Instead each value is treated as a
+// point on a circle, with the period of the values being a full turn, and
+// the statistics are calculated from the mean of these points.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type AngleStat struct {
+	units  string
+	period float64
+	count  int
+	sumSin float64
+	sumCos float64
+}
+
+// NewAngleStat creates a new AngleStat for values in the given units which
+// repeat after the given period; for instance 360 for angles in degrees or
+// 24 for times of day in hours (see PeriodDegrees, PeriodRadians and
+// PeriodHours).
+func NewAngleStat(units string, period float64) (*AngleStat, error) {
+	if !(period > 0) || math.IsInf(period, 1) {
+		return nil, invalidValue(
+			"Invalid period (%g) - it must be a finite number > 0", period)
+	}
+	return &AngleStat{units: units, period: period}, nil
+}
+
+// NewAngleStatOrPanic creates a new AngleStat and will panic if any errors
+// are detected
+func NewAngleStatOrPanic(units string, period float64) *AngleStat {
+	as, err := NewAngleStat(units, period)
+	if err != nil {
+		panic(err)
+	}
+	return as
+}
+
+// toRadians converts a value to an angle in radians
+func (as AngleStat) toRadians(v float64) float64 {
+	return v / as.period * 2 * math.Pi
+}
+
+// fromRadians converts an angle in radians to a value in the units of the
+// AngleStat
+func (as AngleStat) fromRadians(a float64) float64 {
+	return a / (2 * math.Pi) * as.period
+}
+
+// Add adds at least one value to the AngleStat. The values need not lie
+// within a single period.
+func (as *AngleStat) Add(v float64, vals ...float64) {
+	as.add(v)
+	for _, v := range vals {
+		as.add(v)
+	}
+}
+
+// add adds a single value to the AngleStat
+func (as *AngleStat) add(v float64) {
+	sin, cos := math.Sincos(as.toRadians(v))
+	as.sumSin += sin
+	as.sumCos += cos
+	as.count++
+}
+
+// Merge adds the values recorded by the other AngleStat to this one. The
+// AngleStats must have the same units and period.
+func (as *AngleStat) Merge(other *AngleStat) error {
+	if as.units != other.units {
+		return fmt.Errorf(
+			"cannot merge AngleStats with different units: %q and %q",
+			as.units, other.units)
+	}
+	if as.period != other.period {
+		return fmt.Errorf(
+			"cannot merge AngleStats with different periods: %g and %g",
+			as.period, other.period)
+	}
+	as.sumSin += other.sumSin
+	as.sumCos += other.sumCos
+	as.count += other.count
+	return nil
+}
+
+// Reset discards all the values
+func (as *AngleStat) Reset() {
+	as.count, as.sumSin, as.sumCos = 0, 0, 0
+}
+
+// Units returns the units of the AngleStat
+func (as AngleStat) Units() string {
+	return as.units
+}
+
+// Period returns the period of the values
+func (as AngleStat) Period() float64 {
+	return as.period
+}
+
+// Count returns the number of values that have been added
+func (as AngleStat) Count() int {
+	return as.count
+}
+
+// MeanResultantLength returns the length of the mean of the values taken
+// as points on a circle of unit radius. It is 1 if all the values are the
+// same (modulo the period) and near 0 if they are spread evenly around the
+// circle. It returns 0.0 if no values have been added.
+func (as AngleStat) MeanResultantLength() float64 {
+	if as.count == 0 {
+		return 0.0
+	}
+	return math.Hypot(as.sumSin, as.sumCos) / float64(as.count)
+}
+
+// Mean returns the circular mean of the values, in the range [0, period).
+// It returns NaN if the mean has no direction, which is the case if no
+// values have been added or if they cancel each other out, as 0 and 180
+// degrees do.
+func (as AngleStat) Mean() float64 {
+	if as.MeanResultantLength() < 1e-12 {
+		return math.NaN()
+	}
+	m := as.fromRadians(math.Atan2(as.sumSin, as.sumCos))
+	if m < 0 {
+		m += as.period
+	}
+	return m
+}
+
+// Variance returns the circular variance of the values. This is between 0,
+// if all the values are the same, and 1 if they are spread evenly around
+// the circle; note that it has no units. It returns 0.0 if no values have
+// been added.
+func (as AngleStat) Variance() float64 {
+	if as.count == 0 {
+		return 0.0
+	}
+	return 1 - as.MeanResultantLength()
+}
+
+// StdDev returns the circular standard deviation of the values, in the
+// units of the AngleStat. For values tightly clustered around the mean
+// this is close to the ordinary standard deviation. It returns +Inf if the
+// values are spread evenly around the circle and 0.0 if fewer than 2
+// values have been added.
+func (as AngleStat) StdDev() float64 {
+	if as.count < 2 {
+		return 0.0
+	}
+	r := min(as.MeanResultantLength(), 1)
+	return as.fromRadians(math.Sqrt(-2 * math.Log(r)))
+}
+
+// String returns a string summarising the AngleStat
+func (as AngleStat) String() string {
+	return fmt.Sprintf("%7d observations, circular mean: %.4g %s,"+
+		" circular variance: %.3f, circular SD: %.4g %s",
+		as.count, as.Mean(), as.units, as.Variance(), as.StdDev(), as.units)
+}
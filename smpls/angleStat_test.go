@@ -0,0 +1,130 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewAngleStat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		period float64
+	}{
+		{
+			ID:     testhelper.MkID("degrees"),
+			period: PeriodDegrees,
+		},
+		{
+			ID:     testhelper.MkID("zero"),
+			period: 0,
+			ExpErr: testhelper.MkExpErr(
+				"Invalid period (0) - it must be a finite number > 0"),
+		},
+		{
+			ID:     testhelper.MkID("NaN"),
+			period: math.NaN(),
+			ExpErr: testhelper.MkExpErr("Invalid period (NaN)"),
+		},
+		{
+			ID:     testhelper.MkID("infinite"),
+			period: math.Inf(1),
+			ExpErr: testhelper.MkExpErr("Invalid period (+Inf)"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewAngleStat("degrees", tc.period)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestAngleStat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		period    float64
+		vals      []float64
+		expMean   float64
+		expVar    float64
+		expStdDev float64
+	}{
+		{
+			ID:      testhelper.MkID("wrap around zero"),
+			period:  PeriodDegrees,
+			vals:    []float64{350, 10},
+			expMean: 0,
+			expVar:  1 - math.Cos(10*math.Pi/180),
+			expStdDev: math.Sqrt(-2*math.Log(math.Cos(10*math.Pi/180))) *
+				180 / math.Pi,
+		},
+		{
+			ID:      testhelper.MkID("times of day around midnight"),
+			period:  PeriodHours,
+			vals:    []float64{23, 1, 23, 1},
+			expMean: 0,
+			expVar:  1 - math.Cos(math.Pi/12),
+			expStdDev: math.Sqrt(-2*math.Log(math.Cos(math.Pi/12))) *
+				12 / math.Pi,
+		},
+		{
+			ID:      testhelper.MkID("all the same, several turns"),
+			period:  PeriodDegrees,
+			vals:    []float64{90, 450, -270},
+			expMean: 90,
+		},
+		{
+			ID:        testhelper.MkID("radians"),
+			period:    PeriodRadians,
+			vals:      []float64{3, 3.2},
+			expMean:   3.1,
+			expVar:    1 - math.Cos(0.1),
+			expStdDev: math.Sqrt(-2 * math.Log(math.Cos(0.1))),
+		},
+	}
+
+	for _, tc := range testCases {
+		as := NewAngleStatOrPanic("units", tc.period)
+		as.Add(tc.vals[0], tc.vals[1:]...)
+		testhelper.DiffInt(t, tc.IDStr(), "count", as.Count(), len(tc.vals))
+		mean := as.Mean()
+		if mean > tc.period-1e-9 {
+			mean -= tc.period
+		}
+		testhelper.DiffFloat(t, tc.IDStr(), "mean", mean, tc.expMean, 1e-9)
+		testhelper.DiffFloat(t, tc.IDStr(), "variance",
+			as.Variance(), tc.expVar, 1e-9)
+		testhelper.DiffFloat(t, tc.IDStr(), "std dev",
+			as.StdDev(), tc.expStdDev, 1e-6)
+	}
+
+	as := NewAngleStatOrPanic("degrees", PeriodDegrees)
+	testhelper.DiffBool(t, "empty", "mean is NaN", math.IsNaN(as.Mean()), true)
+	as.Add(0, 180)
+	testhelper.DiffBool(t, "opposed", "mean is NaN", math.IsNaN(as.Mean()), true)
+	testhelper.DiffFloat(t, "opposed", "variance", as.Variance(), 1, 1e-9)
+
+	other := NewAngleStatOrPanic("degrees", PeriodDegrees)
+	other.Add(90, 90)
+	if err := as.Merge(other); err != nil {
+		t.Fatalf("unexpected error merging: %v", err)
+	}
+	testhelper.DiffInt(t, "merged", "count", as.Count(), 4)
+	testhelper.DiffFloat(t, "merged", "mean", as.Mean(), 90, 1e-9)
+
+	err := as.Merge(NewAngleStatOrPanic("degrees", PeriodRadians))
+	testhelper.CheckExpErrWithID(t, "merge - bad period", err,
+		testhelper.MkExpErr("cannot merge AngleStats with different periods"))
+	err = as.Merge(NewAngleStatOrPanic("rad", PeriodDegrees))
+	testhelper.CheckExpErrWithID(t, "merge - bad units", err,
+		testhelper.MkExpErr("cannot merge AngleStats with different units"))
+
+	as.Reset()
+	testhelper.DiffInt(t, "Reset", "count", as.Count(), 0)
+
+	as.Add(10, 20, 30)
+	testhelper.DiffString(t, "String", "value", as.String(),
+		"      3 observations, circular mean: 20 degrees,"+
+			" circular variance: 0.010, circular SD: 8.175 degrees")
+}
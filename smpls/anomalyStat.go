@@ -0,0 +1,242 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	dfltAnomalyWarmup    = 30
+	minAnomalyWarmup     = 2
+	dfltAnomalyThreshold = 3.0
+)
+
+// Anomaly describes a value flagged as anomalous by an AnomalyStat
+type Anomaly struct {
+	// Val is the anomalous value
+	Val float64
+	// Seq is the position of the value among all the values added, the
+	// first value being at position 1
+	Seq int
+	// Expected is the value expected: the running mean or the
+	// exponentially weighted moving average
+	Expected float64
+	// Lo and Hi are the limits outside which the value was anomalous
+	Lo, Hi float64
+}
+
+// String returns a description of the Anomaly
+func (a Anomaly) String() string {
+	return fmt.Sprintf("value %d (%g) is outside [%g, %g], expected: %g",
+		a.Seq, a.Val, a.Lo, a.Hi, a.Expected)
+}
+
+// AnomalyStat wraps a Stat and checks each value added against the values
+// that came before it. Once enough values have been added to warm up, any
+// value more than a given number of standard deviations from the mean is
+// flagged as anomalous. By default the mean and standard deviation are
+// those of all the values added so far but they can instead be
+// exponentially weighted moving averages (see AnomalyEWMA) so that the
+// limits follow changes in the values. All the values, anomalous or not,
+// are added to the Stat.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type AnomalyStat struct {
+	stat *Stat
+
+	warmup    int
+	threshold float64
+	alpha     float64
+	onAnomaly func(Anomaly)
+
+	seen      int
+	mean      float64
+	variance  float64 // a running sum of squares unless using the EWMA
+	anomalies int
+	last      *Anomaly
+}
+
+// AnomalyOpt is the type of an option function that can be passed to
+// NewAnomalyStat
+type AnomalyOpt func(as *AnomalyStat) error
+
+// AnomalyWarmup returns a function that will set the number of values to
+// be added before any values are checked. The default is 30.
+func AnomalyWarmup(n int) AnomalyOpt {
+	return func(as *AnomalyStat) error {
+		if n < minAnomalyWarmup {
+			return invalidValue("Invalid warm-up count (%d) - it must be >= %d",
+				n, minAnomalyWarmup)
+		}
+		as.warmup = n
+		return nil
+	}
+}
+
+// AnomalyThreshold returns a function that will set the number of
+// standard deviations a value must be from the mean to be anomalous. The
+// default is 3.
+func AnomalyThreshold(k float64) AnomalyOpt {
+	return func(as *AnomalyStat) error {
+		if !(k > 0) {
+			return invalidValue("Invalid anomaly threshold (%g) - it must be > 0",
+				k)
+		}
+		as.threshold = k
+		return nil
+	}
+}
+
+// AnomalyEWMA returns a function that will make the AnomalyStat compare
+// each value with exponentially weighted moving averages of the values and
+// of their variance rather than with the mean and standard deviation of
+// all the values. The alpha gives the weight of each new value; the
+// larger it is, the faster the limits follow changes in the values.
+func AnomalyEWMA(alpha float64) AnomalyOpt {
+	return func(as *AnomalyStat) error {
+		if !(alpha > 0 && alpha < 1) {
+			return invalidValue(
+				"Invalid EWMA alpha (%g) - it must be > 0 and < 1", alpha)
+		}
+		as.alpha = alpha
+		return nil
+	}
+}
+
+// AnomalyCallback returns a function that will set a function to be called
+// with each Anomaly as it is found. This can be used for real-time
+// alerting. The function is called before the value is added to the Stat.
+func AnomalyCallback(f func(Anomaly)) AnomalyOpt {
+	return func(as *AnomalyStat) error {
+		as.onAnomaly = f
+		return nil
+	}
+}
+
+// NewAnomalyStat creates a new AnomalyStat wrapping the Stat. The values
+// added to the AnomalyStat are added to the Stat.
+func NewAnomalyStat(s *Stat, opts ...AnomalyOpt) (*AnomalyStat, error) {
+	as := &AnomalyStat{
+		stat:      s,
+		warmup:    dfltAnomalyWarmup,
+		threshold: dfltAnomalyThreshold,
+	}
+	for _, o := range opts {
+		if err := o(as); err != nil {
+			return nil, err
+		}
+	}
+	return as, nil
+}
+
+// NewAnomalyStatOrPanic creates a new AnomalyStat and will panic if any
+// errors are detected
+func NewAnomalyStatOrPanic(s *Stat, opts ...AnomalyOpt) *AnomalyStat {
+	as, err := NewAnomalyStat(s, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return as
+}
+
+// sd returns the current standard deviation
+func (as AnomalyStat) sd() float64 {
+	if as.alpha > 0 {
+		return math.Sqrt(as.variance)
+	}
+	return math.Sqrt(as.variance / float64(as.seen))
+}
+
+// update adds the value to the running mean and variance
+func (as *AnomalyStat) update(v float64) {
+	as.seen++
+	if as.alpha > 0 {
+		if as.seen == 1 {
+			as.mean = v
+			return
+		}
+		diff := v - as.mean
+		as.mean += as.alpha * diff
+		as.variance = (1 - as.alpha) * (as.variance + as.alpha*diff*diff)
+		return
+	}
+
+	diff := v - as.mean
+	as.mean += diff / float64(as.seen)
+	as.variance += diff * (v - as.mean)
+}
+
+// Add adds the value to the AnomalyStat and to the Stat it wraps. It
+// returns true if the value is anomalous.
+func (as *AnomalyStat) Add(v float64) bool {
+	anomalous := false
+	if as.seen >= as.warmup {
+		width := as.threshold * as.sd()
+		lo, hi := as.mean-width, as.mean+width
+		if v < lo || v > hi {
+			anomalous = true
+			as.anomalies++
+			as.last = &Anomaly{
+				Val:      v,
+				Seq:      as.seen + 1,
+				Expected: as.mean,
+				Lo:       lo,
+				Hi:       hi,
+			}
+			if as.onAnomaly != nil {
+				as.onAnomaly(*as.last)
+			}
+		}
+	}
+
+	as.update(v)
+	as.stat.Add(v)
+	return anomalous
+}
+
+// Stat returns the Stat wrapped by the AnomalyStat
+func (as AnomalyStat) Stat() *Stat {
+	return as.stat
+}
+
+// Anomalies returns the number of anomalous values found
+func (as AnomalyStat) Anomalies() int {
+	return as.anomalies
+}
+
+// Checked returns the number of values that have been checked; this is the
+// number of values added after the warm-up
+func (as AnomalyStat) Checked() int {
+	return max(0, as.seen-as.warmup)
+}
+
+// LastAnomaly returns the most recent Anomaly and true or, if no anomalous
+// values have been found, a zero Anomaly and false
+func (as AnomalyStat) LastAnomaly() (Anomaly, bool) {
+	if as.last == nil {
+		return Anomaly{}, false
+	}
+	return *as.last, true
+}
+
+// Limits returns the current limits outside which a value would be
+// anomalous. Until the AnomalyStat has warmed up the limits are infinite.
+func (as AnomalyStat) Limits() (lo, hi float64) {
+	if as.seen < as.warmup {
+		return math.Inf(-1), math.Inf(1)
+	}
+	width := as.threshold * as.sd()
+	return as.mean - width, as.mean + width
+}
+
+// Reset discards all the values, the anomaly counts and the values in the
+// Stat. The AnomalyStat will need to warm up again.
+func (as *AnomalyStat) Reset() {
+	as.seen = 0
+	as.mean = 0
+	as.variance = 0
+	as.anomalies = 0
+	as.last = nil
+	as.stat.Reset()
+}
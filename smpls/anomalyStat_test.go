@@ -0,0 +1,96 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewAnomalyStat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []AnomalyOpt
+	}{
+		{
+			ID: testhelper.MkID("good"),
+			opts: []AnomalyOpt{
+				AnomalyWarmup(10), AnomalyThreshold(2), AnomalyEWMA(0.1),
+			},
+		},
+		{
+			ID: testhelper.MkID("bad warm-up"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid warm-up count (1) - it must be >= 2"),
+			opts: []AnomalyOpt{AnomalyWarmup(1)},
+		},
+		{
+			ID: testhelper.MkID("bad threshold"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid anomaly threshold (0) - it must be > 0"),
+			opts: []AnomalyOpt{AnomalyThreshold(0)},
+		},
+		{
+			ID: testhelper.MkID("bad alpha"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid EWMA alpha (1) - it must be > 0 and < 1"),
+			opts: []AnomalyOpt{AnomalyEWMA(1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewAnomalyStat(NewStatOrPanic("units"), tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestAnomalyStat(t *testing.T) {
+	var alerts []Anomaly
+	as := NewAnomalyStatOrPanic(NewStatOrPanic("ms"),
+		AnomalyWarmup(10),
+		AnomalyCallback(func(a Anomaly) { alerts = append(alerts, a) }))
+
+	for i := 0; i < 10; i++ {
+		if as.Add(float64(10 + i%2)) {
+			t.Errorf("value %d should not be anomalous during the warm-up", i)
+		}
+	}
+	lo, hi := as.Limits()
+	testhelper.DiffFloat(t, "warmed up", "lo", lo, 9, 1e-9)
+	testhelper.DiffFloat(t, "warmed up", "hi", hi, 12, 1e-9)
+
+	testhelper.DiffBool(t, "normal value", "anomalous", as.Add(11), false)
+	testhelper.DiffBool(t, "big value", "anomalous", as.Add(100), true)
+	testhelper.DiffInt(t, "AnomalyStat", "anomalies", as.Anomalies(), 1)
+	testhelper.DiffInt(t, "AnomalyStat", "checked", as.Checked(), 2)
+	testhelper.DiffInt(t, "AnomalyStat", "Stat count", as.Stat().Count(), 12)
+	testhelper.DiffInt(t, "AnomalyStat", "alerts", len(alerts), 1)
+
+	last, ok := as.LastAnomaly()
+	testhelper.DiffBool(t, "LastAnomaly", "ok", ok, true)
+	testhelper.DiffFloat(t, "LastAnomaly", "value", last.Val, 100, 0)
+	testhelper.DiffInt(t, "LastAnomaly", "seq", last.Seq, 12)
+
+	as.Reset()
+	testhelper.DiffInt(t, "Reset", "anomalies", as.Anomalies(), 0)
+	testhelper.DiffInt(t, "Reset", "Stat count", as.Stat().Count(), 0)
+	lo, hi = as.Limits()
+	testhelper.DiffBool(t, "Reset", "infinite limits",
+		math.IsInf(lo, -1) && math.IsInf(hi, 1), true)
+}
+
+func TestAnomalyStatEWMA(t *testing.T) {
+	as := NewAnomalyStatOrPanic(NewStatOrPanic("ms"),
+		AnomalyWarmup(5), AnomalyEWMA(0.3))
+
+	// a level shift is at first anomalous but the limits follow it
+	for i := 0; i < 20; i++ {
+		as.Add(float64(10 + i%2))
+	}
+	testhelper.DiffBool(t, "level shift", "anomalous", as.Add(50), true)
+	for i := 0; i < 30; i++ {
+		as.Add(float64(50 + i%2))
+	}
+	testhelper.DiffBool(t, "after the shift", "anomalous", as.Add(50), false)
+}
@@ -0,0 +1,249 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+const maxAutocorrLag = 100
+
+// autocorrTracker records what is needed to calculate the autocorrelation
+// of the values, in the order they are added, for lags of 1 up to maxLag.
+// The values are shifted by the first value to reduce rounding errors. The
+// first and last few values are kept so that the sums of products can be
+// corrected for the mean and so that two trackers can be joined end to end.
+type autocorrTracker struct {
+	maxLag int
+	shift  float64
+
+	n       int
+	sum     float64
+	sumSq   float64
+	lagSums []float64 // lagSums[k-1] is the sum of x[t]*x[t-k]
+
+	first []float64 // the first maxLag values
+	last  []float64 // the last maxLag values, the most recent last
+}
+
+// newAutocorrTracker creates an autocorrTracker for lags up to maxLag
+func newAutocorrTracker(maxLag int) *autocorrTracker {
+	return &autocorrTracker{
+		maxLag:  maxLag,
+		lagSums: make([]float64, maxLag),
+		first:   make([]float64, 0, maxLag),
+		last:    make([]float64, 0, maxLag),
+	}
+}
+
+// add records the next value
+func (at *autocorrTracker) add(v float64) {
+	if at.n == 0 {
+		at.shift = v
+	}
+	x := v - at.shift
+
+	for k := 1; k <= len(at.last); k++ {
+		at.lagSums[k-1] += x * at.last[len(at.last)-k]
+	}
+	if len(at.first) < at.maxLag {
+		at.first = append(at.first, x)
+	}
+	if len(at.last) < at.maxLag {
+		at.last = append(at.last, x)
+	} else {
+		copy(at.last, at.last[1:])
+		at.last[len(at.last)-1] = x
+	}
+
+	at.n++
+	at.sum += x
+	at.sumSq += x * x
+}
+
+// reset discards all the values
+func (at *autocorrTracker) reset() {
+	at.shift, at.n, at.sum, at.sumSq = 0, 0, 0, 0
+	clear(at.lagSums)
+	at.first = at.first[:0]
+	at.last = at.last[:0]
+}
+
+// clone returns a deep copy of the autocorrTracker
+func (at autocorrTracker) clone() *autocorrTracker {
+	c := newAutocorrTracker(at.maxLag)
+	c.shift, c.n, c.sum, c.sumSq = at.shift, at.n, at.sum, at.sumSq
+	copy(c.lagSums, at.lagSums)
+	c.first = append(c.first, at.first...)
+	c.last = append(c.last, at.last...)
+	return c
+}
+
+// sumOf returns the sum of the values
+func sumOf(vals []float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum
+}
+
+// reshift changes the shift of the values to the new shift
+func (at *autocorrTracker) reshift(shift float64) {
+	d := at.shift - shift
+	if d == 0 {
+		return
+	}
+	for k := 1; k <= at.maxLag && k < at.n; k++ {
+		head := at.sum - sumOf(at.first[:k])
+		tail := at.sum - sumOf(at.last[len(at.last)-k:])
+		at.lagSums[k-1] += d*(head+tail) + float64(at.n-k)*d*d
+	}
+	at.sumSq += 2*d*at.sum + float64(at.n)*d*d
+	at.sum += float64(at.n) * d
+	for i := range at.first {
+		at.first[i] += d
+	}
+	for i := range at.last {
+		at.last[i] += d
+	}
+	at.shift = shift
+}
+
+// merge records the values from the other autocorrTracker as if they had
+// been added after the values already recorded. The trackers must have the
+// same maximum lag.
+func (at *autocorrTracker) merge(other *autocorrTracker) {
+	if other.n == 0 {
+		return
+	}
+	if at.n == 0 {
+		*at = *other.clone()
+		return
+	}
+
+	o := other.clone()
+	o.reshift(at.shift)
+
+	for k := 1; k <= at.maxLag; k++ {
+		at.lagSums[k-1] += o.lagSums[k-1]
+		// the pairs with one value from each tracker
+		for j := 0; j < k && j < len(o.first); j++ {
+			if i := len(at.last) - k + j; i >= 0 {
+				at.lagSums[k-1] += o.first[j] * at.last[i]
+			}
+		}
+	}
+
+	for _, x := range o.first {
+		if len(at.first) == at.maxLag {
+			break
+		}
+		at.first = append(at.first, x)
+	}
+	for _, x := range o.last {
+		if len(at.last) < at.maxLag {
+			at.last = append(at.last, x)
+		} else {
+			copy(at.last, at.last[1:])
+			at.last[len(at.last)-1] = x
+		}
+	}
+
+	at.n += o.n
+	at.sum += o.sum
+	at.sumSq += o.sumSq
+}
+
+// autocorrelation returns the autocorrelation of the values at the given
+// lag, which must be between 1 and the maximum lag, or NaN if there are
+// too few values or they are all the same
+func (at autocorrTracker) autocorrelation(k int) float64 {
+	if at.n <= k {
+		return math.NaN()
+	}
+	n := float64(at.n)
+	mean := at.sum / n
+	denom := at.sumSq - n*mean*mean
+	if !(denom > 0) {
+		return math.NaN()
+	}
+
+	head := at.sum - sumOf(at.first[:k])
+	tail := at.sum - sumOf(at.last[len(at.last)-k:])
+	num := at.lagSums[k-1] - mean*(head+tail) + float64(at.n-k)*mean*mean
+	return num / denom
+}
+
+// check returns an error if the autocorrTracker is not consistent
+func (at autocorrTracker) check() error {
+	if at.maxLag < 1 || at.maxLag > maxAutocorrLag ||
+		len(at.lagSums) != at.maxLag {
+		return fmt.Errorf("bad autocorrelation lag (%d)", at.maxLag)
+	}
+	if at.n < 0 ||
+		len(at.first) != min(at.n, at.maxLag) ||
+		len(at.last) != min(at.n, at.maxLag) {
+		return fmt.Errorf("bad autocorrelation values for %d values", at.n)
+	}
+	return nil
+}
+
+// StatAutocorrelation returns a function that will make the Stat record the
+// autocorrelation of the values, in the order in which they are added, for
+// lags from 1 up to maxLag. A lag 1 autocorrelation near zero means that
+// each value is unrelated to the one before it. Consecutive values which
+// are correlated, as latencies often are, carry less information than
+// independent ones and so the standard deviation of the mean, and any
+// confidence interval based on it, is too small; this is a key check
+// before trusting them (see EffectiveCount).
+func StatAutocorrelation(maxLag int) StatOpt {
+	return func(s *Stat) error {
+		if s.autocorr != nil {
+			return repeatedOption("the autocorrelation is already being tracked")
+		}
+		if maxLag < 1 || maxLag > maxAutocorrLag {
+			return invalidValue(
+				"Invalid autocorrelation lag (%d) - it must be >= 1 and <= %d",
+				maxLag, maxAutocorrLag)
+		}
+
+		s.autocorr = newAutocorrTracker(maxLag)
+		return nil
+	}
+}
+
+// AutocorrelationLags returns the largest lag for which the
+// autocorrelation is recorded or 0 if it is not recorded (see
+// StatAutocorrelation)
+func (s Stat) AutocorrelationLags() int {
+	if s.autocorr == nil {
+		return 0
+	}
+	return s.autocorr.maxLag
+}
+
+// Autocorrelation returns the autocorrelation of the values at the given
+// lag: the correlation between each value and the value added lag values
+// before it. It is between -1 and 1. It returns NaN if the autocorrelation
+// is not recorded for the lag (see StatAutocorrelation), if there are no
+// more values than the lag or if all the values are the same.
+func (s Stat) Autocorrelation(lag int) float64 {
+	if s.autocorr == nil || lag < 1 || lag > s.autocorr.maxLag {
+		return math.NaN()
+	}
+	return s.autocorr.autocorrelation(lag)
+}
+
+// EffectiveCount returns an estimate of the number of independent values
+// which would carry the same information as the values added, allowing for
+// the lag 1 autocorrelation: n(1-r)/(1+r). It is less than the Count if
+// consecutive values are positively correlated. It returns the Count if
+// the autocorrelation is not recorded or cannot be calculated.
+func (s Stat) EffectiveCount() float64 {
+	n := float64(s.Count())
+	r := s.Autocorrelation(1)
+	if math.IsNaN(r) {
+		return n
+	}
+	return n * (1 - r) / (1 + r)
+}
@@ -0,0 +1,142 @@
+package smpls
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// bruteAutocorr returns the autocorrelation of the values at the lag
+func bruteAutocorr(vals []float64, lag int) float64 {
+	mean := calcMean(vals)
+	var num, denom float64
+	for i, v := range vals {
+		denom += (v - mean) * (v - mean)
+		if i >= lag {
+			num += (v - mean) * (vals[i-lag] - mean)
+		}
+	}
+	return num / denom
+}
+
+// ar1 returns n values following an autoregressive process in which each
+// value is phi times the previous one plus some noise, offset by the base
+func ar1(r *rand.Rand, n int, phi, base float64) []float64 {
+	vals := make([]float64, n)
+	x := 0.0
+	for i := range vals {
+		x = phi*x + r.NormFloat64()
+		vals[i] = base + x
+	}
+	return vals
+}
+
+func TestStatAutocorrelation(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatAutocorrelation(5)},
+		},
+		{
+			ID:   testhelper.MkID("zero"),
+			opts: []StatOpt{StatAutocorrelation(0)},
+			ExpErr: testhelper.MkExpErr("Invalid autocorrelation lag (0)" +
+				" - it must be >= 1 and <= 100"),
+		},
+		{
+			ID:     testhelper.MkID("too big"),
+			opts:   []StatOpt{StatAutocorrelation(101)},
+			ExpErr: testhelper.MkExpErr("Invalid autocorrelation lag (101)"),
+		},
+		{
+			ID:   testhelper.MkID("repeated"),
+			opts: []StatOpt{StatAutocorrelation(1), StatAutocorrelation(2)},
+			ExpErr: testhelper.MkExpErr(
+				"the autocorrelation is already being tracked"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	const maxLag = 3
+
+	testCases := []struct {
+		testhelper.ID
+		vals []float64
+	}{
+		{ID: testhelper.MkID("independent"), vals: ar1(r, 500, 0, 0)},
+		{ID: testhelper.MkID("correlated"), vals: ar1(r, 500, 0.8, 0)},
+		{ID: testhelper.MkID("large offset"), vals: ar1(r, 500, 0.5, 1e9)},
+		{ID: testhelper.MkID("few values"), vals: []float64{3, 1, 4, 1, 5}},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", StatAutocorrelation(maxLag))
+		s.AddVals(tc.vals...)
+		testhelper.DiffInt(t, tc.IDStr(), "lags",
+			s.AutocorrelationLags(), maxLag)
+		for lag := 1; lag <= maxLag; lag++ {
+			testhelper.DiffFloat(t, tc.IDStr(), "autocorrelation",
+				s.Autocorrelation(lag), bruteAutocorr(tc.vals, lag), 1e-6)
+		}
+
+		// split the values at various points and merge the parts
+		for _, split := range []int{0, 1, 2, 4, len(tc.vals) / 2} {
+			a := NewStatOrPanic("units", StatAutocorrelation(maxLag))
+			b := NewStatOrPanic("units", StatAutocorrelation(maxLag))
+			a.AddVals(tc.vals[:split]...)
+			b.AddVals(tc.vals[split:]...)
+			if err := a.Merge(b); err != nil {
+				t.Fatalf("unexpected error merging: %v", err)
+			}
+			for lag := 1; lag <= maxLag; lag++ {
+				testhelper.DiffFloat(t, tc.IDStr(), "merged autocorrelation",
+					a.Autocorrelation(lag), s.Autocorrelation(lag), 1e-6)
+			}
+		}
+	}
+}
+
+func TestAutocorrelationEdges(t *testing.T) {
+	s := NewStatOrPanic("units", StatAutocorrelation(2))
+	testhelper.DiffBool(t, "empty", "NaN",
+		math.IsNaN(s.Autocorrelation(1)), true)
+	s.Add(5, 5, 5)
+	testhelper.DiffBool(t, "all the same", "NaN",
+		math.IsNaN(s.Autocorrelation(1)), true)
+	testhelper.DiffBool(t, "lag not recorded", "NaN",
+		math.IsNaN(s.Autocorrelation(3)), true)
+	testhelper.DiffFloat(t, "all the same", "effective count",
+		s.EffectiveCount(), 3, 0)
+
+	s.Reset()
+	r := rand.New(rand.NewSource(1))
+	s.AddVals(ar1(r, 2000, 0.9, 0)...)
+	if ec := s.EffectiveCount(); ec > 300 {
+		t.Errorf("the effective count should be much less than the count,"+
+			" it is %g", ec)
+	}
+
+	plain := NewStatOrPanic("units")
+	plain.Add(1, 2, 3)
+	testhelper.DiffInt(t, "not recorded", "lags", plain.AutocorrelationLags(), 0)
+	testhelper.DiffFloat(t, "not recorded", "effective count",
+		plain.EffectiveCount(), 3, 0)
+
+	err := s.Merge(NewStatOrPanic("units", StatAutocorrelation(3)))
+	testhelper.CheckExpErrWithID(t, "merge - different lags", err,
+		testhelper.MkExpErr("cannot merge Stats with different"+
+			" autocorrelation lags: 2 and 3"))
+}
@@ -0,0 +1,134 @@
+package smpls
+
+import (
+	"math"
+)
+
+// maxDensitySlope is the largest slope of the linear density used to
+// position values within a histogram bucket; any steeper and the density
+// would be negative at one end of the bucket
+const maxDensitySlope = 2.0
+
+// StatHistBucketSums returns a function that will make the Stat record the
+// sum of the values in each histogram bucket as well as the number of
+// values. This gives the mean of the values in each bucket (see
+// BucketMeans) and lets percentiles estimated from the histogram take
+// account of how the values are spread within the bucket which can be much
+// more accurate for skewed distributions. It cannot be used with a Stat
+// that has no histogram.
+func StatHistBucketSums() StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		s.bucketSums = true
+		return nil
+	}
+}
+
+// BucketMeans returns the mean of the values in each histogram bucket. The
+// mean of an empty bucket is NaN. It returns nil if the Stat is not
+// recording the sum of the values in each bucket (see StatHistBucketSums)
+// or has no histogram.
+func (s Stat) BucketMeans() []float64 {
+	if !s.bucketSums || s.hist == nil {
+		return nil
+	}
+	s = s.histStat()
+
+	means := make([]float64, len(s.hist))
+	for i, count := range s.hist {
+		means[i] = segMean(count, s.histSums[i])
+	}
+	return means
+}
+
+// segMean returns the mean of count values with the given sum, or NaN if
+// the count is zero
+func segMean(count int, sum float64) float64 {
+	if count == 0 {
+		return math.NaN()
+	}
+	return sum / float64(count)
+}
+
+// bucketSum returns the sum of the values in the i'th bucket or NaN if the
+// sums are not being recorded
+func (s Stat) bucketSum(i int) float64 {
+	if s.histSums == nil {
+		return math.NaN()
+	}
+	return s.histSums[i]
+}
+
+// flowSum returns the sum or NaN if the sums are not being recorded
+func (s Stat) flowSum(sum float64) float64 {
+	if s.histSums == nil {
+		return math.NaN()
+	}
+	return sum
+}
+
+// underflowSegment returns the underflow as a histSegment stretching from
+// the minimum value to the start of the histogram
+func (s Stat) underflowSegment() histSegment {
+	return histSegment{
+		lo:    s.Min(),
+		hi:    s.histStart(),
+		count: s.underflow,
+		sum:   s.flowSum(s.underflowSum),
+	}
+}
+
+// bucketSegment returns the i'th bucket as a histSegment
+func (s Stat) bucketSegment(i int) histSegment {
+	lo, hi := s.bucketLimits(i)
+	return histSegment{lo: lo, hi: hi, count: s.hist[i], sum: s.bucketSum(i)}
+}
+
+// overflowSegment returns the overflow as a histSegment stretching from the
+// end of the histogram to the maximum value
+func (s Stat) overflowSegment() histSegment {
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	return histSegment{
+		lo:    end,
+		hi:    s.Max(),
+		count: s.overflow,
+		sum:   s.flowSum(s.overflowSum),
+	}
+}
+
+// knownSum returns the sum of the values in the segment. If the sum is not
+// recorded it is estimated by taking the values to be at the middle of the
+// segment.
+func (seg histSegment) knownSum() float64 {
+	if math.IsNaN(seg.sum) {
+		return float64(seg.count) * (seg.lo + seg.hi) / 2
+	}
+	return seg.sum
+}
+
+// position returns the fractional position across the segment below which
+// the given fraction of the values in the segment lie. If the sum of the
+// values is not known they are taken to be evenly spread and the position
+// is the fraction. Otherwise the values are taken to have a linear density
+// with a slope chosen to give the mean of the values; the slope is limited
+// so that the density is never negative.
+func (seg histSegment) position(f float64) float64 {
+	width := seg.hi - seg.lo
+	if math.IsNaN(seg.sum) || seg.count == 0 || width <= 0 {
+		return f
+	}
+
+	// with the position, u, in [0, 1] the density is 1 + a(u - ½) which has
+	// a mean of ½ + a/12 and a cumulative distribution of
+	// (a/2)u² + (1 - a/2)u
+	mu := (seg.sum/float64(seg.count) - seg.lo) / width
+	a := math.Max(-maxDensitySlope, math.Min(maxDensitySlope, 12*(mu-0.5)))
+	if math.Abs(a) < 1e-9 {
+		return f
+	}
+
+	b := 1 - a/2
+	return (-b + math.Sqrt(b*b+2*a*f)) / a
+}
@@ -0,0 +1,189 @@
+package smpls
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// bucketSumsTotal returns the total of the bucket sums including the
+// underflow and overflow
+func bucketSumsTotal(s *Stat) float64 {
+	total := s.underflowSum + s.overflowSum
+	for _, sum := range s.histSums {
+		total += sum
+	}
+	return total
+}
+
+func TestStatHistBucketSums(t *testing.T) {
+	_, err := NewStat("units", StatNoHist(), StatHistBucketSums())
+	testhelper.CheckExpErrWithID(t, "StatHistBucketSums - no histogram", err,
+		testhelper.MkExpErr("the Stat has no histogram"))
+
+	s := NewStatOrPanic("units")
+	if s.BucketMeans() != nil {
+		t.Errorf("BucketMeans should be nil if the sums are not recorded")
+	}
+}
+
+func TestBucketMeans(t *testing.T) {
+	s := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketCount(10),
+		StatHistBucketSums())
+	addSeq(s, 0, 1, 100)
+	addSeq(s, 0, 0.5, 200)
+	s.Add(-5, 200)
+
+	means := s.BucketMeans()
+	testhelper.DiffInt(t, "bucket means", "count", len(means), len(s.hist))
+	for i, m := range means {
+		lo, hi := s.bucketLimits(i)
+		if s.hist[i] == 0 {
+			if !math.IsNaN(m) {
+				t.Errorf("bucket %d: empty bucket mean should be NaN: %g", i, m)
+			}
+			continue
+		}
+		if m < lo || m >= hi {
+			t.Errorf("bucket %d: mean (%g) should be in [%g, %g)", i, m, lo, hi)
+		}
+	}
+	testhelper.DiffFloat(t, "bucket means", "underflow sum",
+		s.underflowSum, -5, 0)
+	testhelper.DiffFloat(t, "bucket means", "overflow sum",
+		s.overflowSum, 299.5, 0)
+	testhelper.DiffFloat(t, "bucket means", "total of sums",
+		bucketSumsTotal(s), s.Sum(), 1e-9)
+}
+
+func TestBucketSumsPercentile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	vals := make([]float64, 20000)
+	for i := range vals {
+		vals[i] = rnd.ExpFloat64()
+	}
+	sorted := sortedCopy(vals)
+
+	plain := NewStatOrPanic("units", StatHistBucketCount(10))
+	withSums := NewStatOrPanic("units",
+		StatHistBucketCount(10), StatHistBucketSums())
+	plain.AddSlice(vals)
+	withSums.AddSlice(vals)
+
+	var plainErr, sumsErr float64
+	for _, p := range []float64{10, 25, 50, 75, 90} {
+		exp := sortedPercentile(sorted, p)
+		plainErr += math.Abs(plain.HistPercentile(p) - exp)
+		sumsErr += math.Abs(withSums.HistPercentile(p) - exp)
+	}
+	if sumsErr >= plainErr {
+		t.Errorf("the bucket sums should improve the percentiles:"+
+			" error with sums: %g, without: %g", sumsErr, plainErr)
+	}
+}
+
+func TestSegPosition(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		seg histSegment
+		f   float64
+		exp float64
+	}{
+		{
+			ID:  testhelper.MkID("no sum"),
+			seg: histSegment{lo: 0, hi: 10, count: 4, sum: math.NaN()},
+			f:   0.25,
+			exp: 0.25,
+		},
+		{
+			ID:  testhelper.MkID("mean in the middle"),
+			seg: histSegment{lo: 0, hi: 10, count: 4, sum: 20},
+			f:   0.25,
+			exp: 0.25,
+		},
+		{
+			ID:  testhelper.MkID("skewed low, half way"),
+			seg: histSegment{lo: 0, hi: 12, count: 1, sum: 5},
+			f:   0.5,
+			exp: (3 - math.Sqrt(5)) / 2,
+		},
+		{
+			ID:  testhelper.MkID("skewed high, all"),
+			seg: histSegment{lo: 0, hi: 10, count: 1, sum: 9},
+			f:   1,
+			exp: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, tc.IDStr(), "position",
+			tc.seg.position(tc.f), tc.exp, 1e-9)
+	}
+}
+
+func TestBucketSumsRebinMerge(t *testing.T) {
+	s := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketCount(10),
+		StatHistAutoRebin(0.1),
+		StatHistBucketSums())
+	addSeq(s, 0, 1, 100)
+	addSeq(s, 100, 1, 100)
+	testhelper.DiffFloat(t, "rebin", "total of sums",
+		bucketSumsTotal(s), s.Sum(), 1e-9)
+
+	other := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketCount(10),
+		StatHistBucketSums())
+	addSeq(other, -50, 1, 150)
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("unexpected merge error: %s", err)
+	}
+	testhelper.DiffFloat(t, "merge", "total of sums",
+		bucketSumsTotal(s), s.Sum(), 1e-9)
+}
+
+func TestBucketSumsSaveLoad(t *testing.T) {
+	s := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketSums())
+	addSeq(s, 0, 0.25, 500)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("unexpected load error: %s", err)
+	}
+
+	testhelper.DiffFloatSlice(t, "save/load", "bucket means",
+		loaded.BucketMeans(), s.BucketMeans(), 0)
+	loaded.Add(1)
+	s.Add(1)
+	testhelper.DiffFloat(t, "save/load", "total of sums",
+		bucketSumsTotal(&loaded), bucketSumsTotal(s), 0)
+}
+
+func TestHistShowBucketMean(t *testing.T) {
+	s := NewStatOrPanic("units",
+		StatCacheSize(10),
+		StatHistBucketCount(2),
+		StatHistBucketSums())
+	s.Add(1, 1, 1, 1, 1, 9, 9, 9, 9, 9, 9.5)
+
+	hist := s.HistWithOpts(HistOpts{ShowBucketMean: true})
+	testhelper.ShouldContain(t, "hist", "bucket mean", hist,
+		[]string{"mean:"})
+	plain := s.HistWithOpts(HistOpts{})
+	if bytes.Contains([]byte(plain), []byte("mean:")) {
+		t.Errorf("the bucket means should not be shown by default")
+	}
+}
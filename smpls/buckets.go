@@ -0,0 +1,46 @@
+package smpls
+
+// BucketCount returns the number of histogram buckets. This is 0 if the
+// Stat has no histogram. While the values are still held in the cache the
+// number is that of the provisional histogram (see Snapshot).
+func (s Stat) BucketCount() int {
+	if s.count == 0 {
+		return len(s.hist)
+	}
+	return len(s.histStat().hist)
+}
+
+// Underflow returns the number of values below the start of the histogram
+func (s Stat) Underflow() int {
+	return s.histStat().underflow
+}
+
+// Overflow returns the number of values at or above the end of the
+// histogram
+func (s Stat) Overflow() int {
+	return s.histStat().overflow
+}
+
+// ForEachBucket calls the function for each histogram bucket in turn,
+// lowest first, passing the bucket limits and the number of values in the
+// bucket. The bucket holds values at or above low and below high. It stops
+// as soon as the function returns false. The function is not called if no
+// values have been added or the Stat has no histogram. The underflow and
+// overflow are not included (see Underflow and Overflow).
+//
+// Once the histogram is in use this does not allocate memory; while the
+// values are still held in the cache the provisional histogram is built
+// first (see Snapshot).
+func (s Stat) ForEachBucket(f func(low, high float64, count int) bool) {
+	if s.count == 0 || s.hist == nil {
+		return
+	}
+	s = s.histStat()
+
+	for i, count := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		if !f(lo, hi, count) {
+			return
+		}
+	}
+}
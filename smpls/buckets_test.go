@@ -0,0 +1,46 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestForEachBucket(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5))
+	addSeq(s, 0, 1, 10)
+	s.Add(-1, 20, 4.5)
+
+	snap := s.Snapshot()
+	var got []Bucket
+	s.ForEachBucket(func(lo, hi float64, count int) bool {
+		got = append(got, Bucket{Low: lo, High: hi, Count: count})
+		return true
+	})
+	if err := testhelper.DiffVals(got, snap.Buckets); err != nil {
+		t.Errorf("the buckets differ from the Snapshot: %v", err)
+	}
+	testhelper.DiffInt(t, "laid out", "BucketCount", s.BucketCount(), 5)
+	testhelper.DiffInt(t, "laid out", "Underflow", s.Underflow(), 1)
+	testhelper.DiffInt(t, "laid out", "Overflow", s.Overflow(), 1)
+
+	calls := 0
+	s.ForEachBucket(func(_, _ float64, _ int) bool {
+		calls++
+		return calls < 2
+	})
+	testhelper.DiffInt(t, "stopped early", "calls", calls, 2)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		s.ForEachBucket(func(_, _ float64, _ int) bool { return true })
+	})
+	testhelper.DiffFloat(t, "laid out", "allocations", allocs, 0, 0)
+
+	empty := NewStatOrPanic("ms")
+	empty.ForEachBucket(func(_, _ float64, _ int) bool {
+		t.Error("the function should not be called for an empty Stat")
+		return false
+	})
+	testhelper.DiffInt(t, "no hist", "BucketCount",
+		NewStatOrPanic("ms", StatNoHist()).BucketCount(), 0)
+}
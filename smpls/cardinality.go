@@ -0,0 +1,207 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	minCardinalityPrecision  = 4
+	maxCardinalityPrecision  = 16
+	dfltCardinalityPrecision = 14
+)
+
+// Cardinality gives an estimate of the number of distinct values it has
+// seen using the HyperLogLog algorithm. It uses a fixed amount of memory
+// (2^precision bytes) however many values are added. The relative
+// standard error of the estimate is about 1.04/sqrt(2^precision); with the
+// default precision of 14 this is under 1% and it uses 16KiB.
+//
+// The values are hashed with a fixed hash function and so Cardinalities
+// with the same precision can be merged even if they were created by
+// different programs.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type Cardinality struct {
+	precision uint
+	registers []uint8
+}
+
+// NewCardinality creates a new Cardinality with the given precision which
+// must be between 4 and 16. Each increase in the precision doubles the
+// memory used and improves the accuracy by a factor of the square root of
+// two.
+func NewCardinality(precision int) (*Cardinality, error) {
+	if precision < minCardinalityPrecision ||
+		precision > maxCardinalityPrecision {
+		return nil,
+			invalidValue("Invalid cardinality precision (%d)"+
+				" - it must be >= %d and <= %d",
+				precision, minCardinalityPrecision, maxCardinalityPrecision)
+	}
+	return &Cardinality{
+		precision: uint(precision),
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// NewCardinalityOrPanic creates a new Cardinality and will panic if any
+// errors are detected
+func NewCardinalityOrPanic(precision int) *Cardinality {
+	c, err := NewCardinality(precision)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// mix64 scrambles the bits of the value so that similar values give very
+// different results. It is the finalizer of the SplitMix64 generator.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// hashFloat returns the hash of the value. Zero and negative zero give the
+// same hash.
+func hashFloat(v float64) uint64 {
+	if v == 0 {
+		v = 0
+	}
+	return mix64(math.Float64bits(v))
+}
+
+// hashString returns the hash of the string, an FNV-1a hash scrambled
+// by mix64
+func hashString(str string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(str); i++ {
+		h ^= uint64(str[i])
+		h *= prime
+	}
+	return mix64(h)
+}
+
+// addHash records the hash of a value
+func (c *Cardinality) addHash(h uint64) {
+	idx := h >> (64 - c.precision)
+	rank := uint8(bits.LeadingZeros64(h<<c.precision|1<<(c.precision-1))) + 1
+	if rank > c.registers[idx] {
+		c.registers[idx] = rank
+	}
+}
+
+// Add records the value. NaN values are ignored.
+func (c *Cardinality) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	c.addHash(hashFloat(v))
+}
+
+// AddString records the string. This lets you count distinct values such
+// as user ids or host names.
+func (c *Cardinality) AddString(str string) {
+	c.addHash(hashString(str))
+}
+
+// Precision returns the precision of the Cardinality
+func (c Cardinality) Precision() int {
+	return int(c.precision)
+}
+
+// Estimate returns the estimated number of distinct values seen
+func (c Cardinality) Estimate() int {
+	m := float64(len(c.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range c.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	switch len(c.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	}
+
+	est := alpha * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros)) // linear counting
+	}
+	return int(math.Round(est))
+}
+
+// Merge adds the values seen by the other Cardinality to this one. They
+// must have the same precision.
+func (c *Cardinality) Merge(other *Cardinality) error {
+	if c.precision != other.precision {
+		return fmt.Errorf(
+			"cannot merge Cardinalities with different precisions: %d and %d",
+			c.precision, other.precision)
+	}
+	for i, r := range other.registers {
+		c.registers[i] = max(c.registers[i], r)
+	}
+	return nil
+}
+
+// Reset discards all the values seen
+func (c *Cardinality) Reset() {
+	clear(c.registers)
+}
+
+// clone returns an independent copy of the Cardinality
+func (c Cardinality) clone() *Cardinality {
+	c.registers = append([]uint8(nil), c.registers...)
+	return &c
+}
+
+// StatTrackDistinct returns a function that will make the Stat estimate
+// the number of distinct values added (see Distinct). The precision is
+// passed to NewCardinality; a precision of 0 gives the default precision
+// (14).
+func StatTrackDistinct(precision int) StatOpt {
+	return func(s *Stat) error {
+		if s.distinct != nil {
+			return repeatedOption("distinct value tracking has already been set up")
+		}
+		if precision == 0 {
+			precision = dfltCardinalityPrecision
+		}
+
+		c, err := NewCardinality(precision)
+		if err != nil {
+			return err
+		}
+		s.distinct = c
+		return nil
+	}
+}
+
+// Distinct returns an estimate of the number of distinct values added. The
+// final result is false if the Stat is not tracking distinct values (see
+// StatTrackDistinct).
+func (s Stat) Distinct() (int, bool) {
+	if s.distinct == nil {
+		return 0, false
+	}
+	return s.distinct.Estimate(), true
+}
@@ -0,0 +1,121 @@
+package smpls
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewCardinality(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		precision int
+	}{
+		{
+			ID:        testhelper.MkID("good"),
+			precision: 10,
+		},
+		{
+			ID: testhelper.MkID("too small"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid cardinality precision (3) - it must be >= 4 and <= 16"),
+			precision: 3,
+		},
+		{
+			ID: testhelper.MkID("too big"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid cardinality precision (17) - it must be >= 4 and <= 16"),
+			precision: 17,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewCardinality(tc.precision)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestCardinalityEstimate(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		id := fmt.Sprintf("%d distinct values", n)
+		c := NewCardinalityOrPanic(dfltCardinalityPrecision)
+		for i := 0; i < n; i++ {
+			c.Add(float64(i))
+			c.Add(float64(i)) // repeats should not be counted
+		}
+		tolerance := math.Max(1, 0.03*float64(n))
+		testhelper.DiffFloat(t, id, "estimate",
+			float64(c.Estimate()), float64(n), tolerance)
+	}
+
+	c := NewCardinalityOrPanic(8)
+	c.Add(0)
+	c.Add(math.Copysign(0, -1))
+	c.Add(math.NaN())
+	testhelper.DiffInt(t, "zeros and NaN", "estimate", c.Estimate(), 1)
+
+	c.Reset()
+	for i := 0; i < 50; i++ {
+		c.AddString(fmt.Sprintf("user-%d", i%20))
+	}
+	testhelper.DiffFloat(t, "strings", "estimate",
+		float64(c.Estimate()), 20, 2)
+}
+
+func TestCardinalityMerge(t *testing.T) {
+	a := NewCardinalityOrPanic(12)
+	b := NewCardinalityOrPanic(12)
+	for i := 0; i < 3000; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 2000))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("unexpected merge error: %s", err)
+	}
+	testhelper.DiffFloat(t, "merge", "estimate",
+		float64(a.Estimate()), 5000, 0.05*5000)
+
+	err := a.Merge(NewCardinalityOrPanic(10))
+	testhelper.CheckExpErrWithID(t, "merge - bad precision", err,
+		testhelper.MkExpErr(
+			"cannot merge Cardinalities with different precisions: 12 and 10"))
+}
+
+func TestStatTrackDistinct(t *testing.T) {
+	_, err := NewStat("units", StatTrackDistinct(2))
+	testhelper.CheckExpErrWithID(t, "StatTrackDistinct - bad precision", err,
+		testhelper.MkExpErr("Invalid cardinality precision (2)"))
+
+	s := NewStatOrPanic("units")
+	_, ok := s.Distinct()
+	testhelper.DiffBool(t, "not tracking", "ok", ok, false)
+
+	s = NewStatOrPanic("units", StatTrackDistinct(0))
+	testhelper.DiffInt(t, "default", "precision",
+		s.distinct.Precision(), dfltCardinalityPrecision)
+	for i := 0; i < 5000; i++ {
+		s.Add(float64(i % 250))
+	}
+	n, ok := s.Distinct()
+	testhelper.DiffBool(t, "tracking", "ok", ok, true)
+	testhelper.DiffFloat(t, "tracking", "distinct", float64(n), 250, 5)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("unexpected load error: %s", err)
+	}
+	loadedN, _ := loaded.Distinct()
+	testhelper.DiffInt(t, "save/load", "distinct", loadedN, n)
+
+	s.Reset()
+	n, _ = s.Distinct()
+	testhelper.DiffInt(t, "reset", "distinct", n, 0)
+}
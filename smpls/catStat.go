@@ -0,0 +1,152 @@
+package smpls
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// KeyCount records the number of times a key has been seen
+type KeyCount struct {
+	Key   string
+	Count int
+}
+
+// CatStat records the number of times each of a set of categorical values
+// (such as status codes, host names or error kinds) has been seen. Each
+// distinct value is identified by a string key. It is the counterpart of a
+// Stat for values which have no numeric meaning.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type CatStat struct {
+	units  string
+	counts map[string]int
+	count  int
+}
+
+// NewCatStat creates a new CatStat. The units describe what is being
+// counted.
+func NewCatStat(units string) *CatStat {
+	return &CatStat{
+		units:  units,
+		counts: map[string]int{},
+	}
+}
+
+// Add records at least one occurrence of a key
+func (cs *CatStat) Add(key string, keys ...string) {
+	cs.counts[key]++
+	for _, k := range keys {
+		cs.counts[k]++
+	}
+	cs.count += 1 + len(keys)
+}
+
+// Reset discards all the counts
+func (cs *CatStat) Reset() {
+	clear(cs.counts)
+	cs.count = 0
+}
+
+// Units returns the units of the CatStat
+func (cs CatStat) Units() string {
+	return cs.units
+}
+
+// Count returns the number of keys that have been added
+func (cs CatStat) Count() int {
+	return cs.count
+}
+
+// Unique returns the number of distinct keys that have been added
+func (cs CatStat) Unique() int {
+	return len(cs.counts)
+}
+
+// CountOf returns the number of times the key has been added
+func (cs CatStat) CountOf(key string) int {
+	return cs.counts[key]
+}
+
+// sorted returns the keys and their counts, the most frequent first. Keys
+// seen equally often are in ascending order.
+func (cs CatStat) sorted() []KeyCount {
+	kcs := make([]KeyCount, 0, len(cs.counts))
+	for k, count := range cs.counts {
+		kcs = append(kcs, KeyCount{Key: k, Count: count})
+	}
+	slices.SortFunc(kcs, func(a, b KeyCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return kcs
+}
+
+// TopN returns the n most frequently added keys with their counts, the
+// most frequent first. Keys added equally often are in ascending order.
+func (cs CatStat) TopN(n int) []KeyCount {
+	kcs := cs.sorted()
+	if n < len(kcs) {
+		kcs = kcs[:max(n, 0)]
+	}
+	return kcs
+}
+
+// Entropy returns the Shannon entropy, in bits, of the distribution of the
+// keys. It is 0 if all the keys are the same and log2 of the number of
+// distinct keys if they are all equally frequent. It returns 0.0 if no
+// keys have been added.
+func (cs CatStat) Entropy() float64 {
+	var h float64
+	for _, count := range cs.counts {
+		p := float64(count) / float64(cs.count)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// String returns a string summarising the CatStat
+func (cs CatStat) String() string {
+	str := fmt.Sprintf("%7d observations, %d unique, entropy: %.2f bits",
+		cs.count, cs.Unique(), cs.Entropy())
+	if kcs := cs.TopN(1); len(kcs) > 0 {
+		str += fmt.Sprintf(", mode: %q (%d)", kcs[0].Key, kcs[0].Count)
+	}
+	return str
+}
+
+// Hist returns a string showing a bar chart of the counts of each key, the
+// most frequent first
+func (cs CatStat) Hist() string {
+	return cs.HistWithOpts(HistOpts{})
+}
+
+// HistWithOpts returns a string showing a bar chart of the counts of each
+// key, the most frequent first, formatted according to the HistOpts. The
+// CDF and ShowBucketMean settings are ignored. It returns the empty string
+// if no keys have been added.
+func (cs CatStat) HistWithOpts(ho HistOpts) string {
+	if cs.count == 0 {
+		return ""
+	}
+
+	kcs := cs.sorted()
+	width := 0
+	for _, kc := range kcs {
+		width = max(width, len(kc.Key))
+	}
+	keyFmt := fmt.Sprintf("%%-%ds: %%s\n", width)
+
+	hl := newCountsLine(ho, cs.count, kcs[0].Count)
+	var b strings.Builder
+	b.WriteString("units: " + cs.units + "\n")
+	for _, kc := range kcs {
+		fmt.Fprintf(&b, keyFmt, kc.Key, hl.str(kc.Count, math.NaN()))
+	}
+	return b.String()
+}
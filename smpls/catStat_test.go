@@ -0,0 +1,106 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCatStat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		keys       []string
+		expUnique  int
+		expEntropy float64
+		expTop     []KeyCount
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:         testhelper.MkID("one key"),
+			keys:       []string{"a", "a", "a"},
+			expUnique:  1,
+			expEntropy: 0,
+			expTop:     []KeyCount{{Key: "a", Count: 3}},
+		},
+		{
+			ID:         testhelper.MkID("four equal keys"),
+			keys:       []string{"d", "c", "b", "a"},
+			expUnique:  4,
+			expEntropy: 2,
+			expTop: []KeyCount{
+				{Key: "a", Count: 1},
+				{Key: "b", Count: 1},
+			},
+		},
+		{
+			ID:         testhelper.MkID("skewed"),
+			keys:       []string{"200", "200", "404", "200"},
+			expUnique:  2,
+			expEntropy: -(0.75*math.Log2(0.75) + 0.25*math.Log2(0.25)),
+			expTop: []KeyCount{
+				{Key: "200", Count: 3},
+				{Key: "404", Count: 1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		id := tc.IDStr()
+		cs := NewCatStat("status")
+		for _, k := range tc.keys {
+			cs.Add(k)
+		}
+
+		testhelper.DiffInt(t, id, "count", cs.Count(), len(tc.keys))
+		testhelper.DiffInt(t, id, "unique", cs.Unique(), tc.expUnique)
+		testhelper.DiffFloat(t, id, "entropy", cs.Entropy(), tc.expEntropy,
+			1e-9)
+		top := cs.TopN(2)
+		testhelper.DiffInt(t, id, "top N count", len(top), len(tc.expTop))
+		for i, kc := range top {
+			if i < len(tc.expTop) && kc != tc.expTop[i] {
+				t.Log(id)
+				t.Errorf("\t: top %d: expected %v, got %v\n",
+					i, tc.expTop[i], kc)
+			}
+		}
+	}
+}
+
+func TestCatStatAddReset(t *testing.T) {
+	cs := NewCatStat("hosts")
+	cs.Add("x", "y", "x")
+	testhelper.DiffInt(t, "Add", "count", cs.Count(), 3)
+	testhelper.DiffInt(t, "Add", "count of x", cs.CountOf("x"), 2)
+	testhelper.DiffInt(t, "Add", "count of z", cs.CountOf("z"), 0)
+	testhelper.DiffString(t, "Add", "string", cs.String(),
+		"      3 observations, 2 unique, entropy: 0.92 bits, mode: \"x\" (2)")
+
+	cs.Reset()
+	testhelper.DiffInt(t, "Reset", "count", cs.Count(), 0)
+	testhelper.DiffInt(t, "Reset", "unique", cs.Unique(), 0)
+	testhelper.DiffString(t, "Reset", "hist", cs.Hist(), "")
+}
+
+func TestCatStatHist(t *testing.T) {
+	cs := NewCatStat("status")
+	cs.Add("200", "200", "200", "404")
+
+	testhelper.DiffString(t, "Hist", "default", cs.Hist(),
+		"units: status\n"+
+			"200: 3  75.00% "+
+			"*************************************\n"+
+			"404: 1  25.00% ************\n")
+	testhelper.DiffString(t, "Hist", "scaled",
+		cs.HistWithOpts(HistOpts{
+			MaxBarWidth:     4,
+			ScaleToMaxCount: true,
+			HidePct:         true,
+		}),
+		"units: status\n"+
+			"200: 3 ****\n"+
+			"404: 1 *\n")
+}
@@ -0,0 +1,135 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+// CDFPoint records the number and fraction of the values that are less
+// than a bucket boundary of the histogram
+type CDFPoint struct {
+	UpperBound float64
+	Count      int
+	Fraction   float64
+}
+
+// bucketLimits returns the lower and upper limits of the i'th histogram
+// bucket
+func (s Stat) bucketLimits(i int) (lo, hi float64) {
+	lo = s.bucketStart + float64(i)*s.bucketWidth
+	return s.histVal(lo), s.histVal(lo + s.bucketWidth)
+}
+
+// CDF returns the cumulative distribution of the values. There is a point
+// for the start of the histogram and for the upper limit of each bucket;
+// values at or above the last point are in the overflow and so the last
+// Fraction will be less than one if there is any overflow. The values are
+// taken from the histogram and so the resolution of the CDF is limited by
+// the histogram bucket width. It returns nil if no values have been added
+// or if the Stat has no histogram.
+func (s Stat) CDF() []CDFPoint {
+	s = s.histStat()
+	if s.count == 0 || s.hist == nil {
+		return nil
+	}
+
+	cdf := make([]CDFPoint, 0, len(s.hist)+1)
+	total := float64(s.count)
+
+	cumCount := s.underflow
+	cdf = append(cdf, CDFPoint{
+		UpperBound: s.histStart(),
+		Count:      cumCount,
+		Fraction:   float64(cumCount) / total,
+	})
+	for i, count := range s.hist {
+		cumCount += count
+		_, hi := s.bucketLimits(i)
+		cdf = append(cdf, CDFPoint{
+			UpperBound: hi,
+			Count:      cumCount,
+			Fraction:   float64(cumCount) / total,
+		})
+	}
+	return cdf
+}
+
+// cdfStr returns a string showing the cumulative distribution of the
+// values. Each line shows the number and percentage of values less than
+// the bucket boundary.
+func (s Stat) cdfStr(ho HistOpts) string {
+	s = s.histStat()
+	cdf := s.CDF()
+	if s.count < len(s.hist) || len(cdf) == 0 {
+		return ""
+	}
+
+	countFmt := fmt.Sprintf("%%%dd", mathutil.Digits(int64(s.count)))
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		cdf[0].UpperBound,
+		s.histWidth(),
+		cdf[len(cdf)-1].UpperBound)
+	lineFmt := fmt.Sprintf("< %%%d.%df: %%s\n", width, precision)
+	barScale := float64(ho.maxBarWidth())
+
+	str := "units: " + s.units + "\n"
+	prevCount := -1
+	for _, p := range cdf {
+		if ho.HideEmpty && p.Count == prevCount {
+			continue
+		}
+		prevCount = p.Count
+
+		val := fmt.Sprintf(countFmt, p.Count)
+		if !ho.HidePct {
+			val += fmt.Sprintf(" %6.2f%%", 100.0*p.Fraction)
+		}
+		val += " " +
+			strings.Repeat(string(ho.barRune()), int(p.Fraction*barScale))
+		str += fmt.Sprintf(lineFmt, p.UpperBound, val)
+	}
+	return str
+}
+
+// cdfAt returns an estimate of the fraction of the values that are less
+// than x. The values in each bucket are taken to be evenly spread across
+// the bucket. The underflow and overflow are taken to stretch from the
+// histogram to the minimum and maximum values. It returns 0.0 if no values
+// have been added and NaN if the Stat has no histogram.
+func (s Stat) cdfAt(x float64) float64 {
+	s = s.histStat()
+	if s.hist == nil {
+		return math.NaN()
+	}
+	if s.count == 0 || x <= s.Min() {
+		return 0.0
+	}
+	if x > s.Max() {
+		return 1.0
+	}
+
+	total := float64(s.count)
+	if start := s.histStart(); x < start {
+		return float64(s.underflow) * (x - s.Min()) /
+			(start - s.Min()) / total
+	}
+
+	cum := s.underflow
+	for i, count := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		if x < hi {
+			return (float64(cum) + float64(count)*(x-lo)/(hi-lo)) / total
+		}
+		cum += count
+	}
+
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	if s.Max() <= end {
+		return float64(cum) / total
+	}
+	return (float64(cum) +
+		float64(s.overflow)*(x-end)/(s.Max()-end)) / total
+}
@@ -0,0 +1,39 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCDF(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistBucketCount(3))
+	if cdf := s.CDF(); cdf != nil {
+		t.Errorf("the CDF of an empty Stat should be nil, got: %v", cdf)
+	}
+
+	s.Add(0, 1, 2, 3, 3, 3, 3, 4, 5, 9)
+
+	cdf := s.CDF()
+	hs := s.histStat()
+	expCounts := []int{0, 7, 9, 10}
+	expFractions := []float64{0, 0.7, 0.9, 1.0}
+	if testhelper.DiffInt(t, "CDF", "length", len(cdf), len(expCounts)) {
+		return
+	}
+	for i, p := range cdf {
+		testhelper.DiffInt(t, "CDF", "count", p.Count, expCounts[i])
+		testhelper.DiffFloat(t, "CDF", "fraction",
+			p.Fraction, expFractions[i], 0.0)
+		testhelper.DiffFloat(t, "CDF", "upper bound",
+			p.UpperBound, hs.bucketStart+float64(i)*hs.bucketWidth, 0.0)
+	}
+
+	testhelper.DiffString(t, "CDF", "rendered CDF",
+		s.HistWithOpts(HistOpts{CDF: true, MaxBarWidth: 10}),
+		"units: ms\n"+
+			"< 0.00:  0   0.00% \n"+
+			"< 3.00:  7  70.00% *******\n"+
+			"< 6.00:  9  90.00% *********\n"+
+			"< 9.00: 10 100.00% **********\n")
+}
@@ -0,0 +1,241 @@
+package smpls
+
+import (
+	"maps"
+	"slices"
+)
+
+// cloneFloat64Slice returns a copy of the slice with the same length and
+// capacity. A nil slice gives a nil copy.
+func cloneFloat64Slice(vals []float64) []float64 {
+	if vals == nil {
+		return nil
+	}
+	return append(make([]float64, 0, cap(vals)), vals...)
+}
+
+// clone returns an independent copy of the Rate
+func (r Rate) clone() *Rate {
+	r.slots = slices.Clone(r.slots)
+	return &r
+}
+
+// empty returns a new Rate with the same window as the Rate but with no
+// values recorded
+func (r Rate) empty() *Rate {
+	return &Rate{
+		window:    r.window,
+		slotWidth: r.slotWidth,
+		slots:     make([]rateSlot, len(r.slots)),
+		now:       r.now,
+	}
+}
+
+// clone returns an independent copy of the freqTracker
+func (ft freqTracker) clone() *freqTracker {
+	ft.counts = maps.Clone(ft.counts)
+	return &ft
+}
+
+// Clone returns an independent copy of the Stat. Subsequent changes to
+// either Stat will not affect the other. If the Stat was created by Fork
+// the copy is not attached to the parent Stat. The copy only has those
+// Accumulators which can be cloned (see Accumulator) and does not write raw
+// values (see StatRawValues), append them to a sample store (see
+// StatSampleStore) nor send them to any Sinks (see Subscribe).
+func (s Stat) Clone() *Stat {
+	s.mins = cloneFloat64Slice(s.mins)
+	s.maxs = cloneFloat64Slice(s.maxs)
+	s.cache = cloneFloat64Slice(s.cache)
+	if s.cache != nil {
+		s.cacheBuf = s.cache
+	} else if s.cacheBuf != nil {
+		s.cacheBuf = make([]float64, 0, cap(s.cacheBuf))
+	}
+	s.rebinHist = slices.Clone(s.rebinHist)
+	s.rebinSums = slices.Clone(s.rebinSums)
+	s.hist = slices.Clone(s.hist)
+	s.knots = slices.Clone(s.knots)
+	s.histSums = slices.Clone(s.histSums)
+	s.cloneRefs()
+
+	return &s
+}
+
+// cloneRefs replaces the trackers and other values the Stat refers to,
+// apart from the slices of values and counts, with independent copies and
+// detaches the Stat from any parent, raw value writer, sample store or
+// Sinks
+func (s *Stat) cloneRefs() {
+	if s.minInfo != nil {
+		s.minInfo = append(make([]any, 0, cap(s.minInfo)), s.minInfo...)
+		s.maxInfo = append(make([]any, 0, cap(s.maxInfo)), s.maxInfo...)
+	}
+	if s.rate != nil {
+		s.rate = s.rate.clone()
+	}
+	if s.freq != nil {
+		s.freq = s.freq.clone()
+	}
+	if s.heavy != nil {
+		s.heavy = s.heavy.clone()
+	}
+	if s.remedian != nil {
+		s.remedian = s.remedian.clone()
+	}
+	if s.distinct != nil {
+		s.distinct = s.distinct.clone()
+	}
+	if s.reservoir != nil {
+		s.reservoir = s.reservoir.clone()
+	}
+	if s.deadband != nil {
+		db := *s.deadband
+		s.deadband = &db
+	}
+	if s.warmup != nil {
+		w := *s.warmup
+		s.warmup = &w
+	}
+	if s.valid != nil {
+		vr := *s.valid
+		s.valid = &vr
+	}
+	if s.autocorr != nil {
+		s.autocorr = s.autocorr.clone()
+	}
+	if s.tails != nil {
+		s.tails = s.tails.clone()
+	}
+	if s.expRange != nil {
+		er := *s.expRange
+		s.expRange = &er
+	}
+	if s.runs != nil {
+		runs := *s.runs
+		s.runs = &runs
+	}
+	s.accs = cloneAccs(s.accs)
+	s.history = slices.Clone(s.history)
+	s.meta = maps.Clone(s.meta)
+	s.raw = nil
+	s.store = nil
+	s.sinks = nil
+	s.parent = nil
+}
+
+// Fork returns a new, empty, Stat configured like this one. Any values added
+// to the new Stat are also added to this Stat (and so on up the chain if
+// this Stat was itself created by Fork). This lets you keep statistics for
+// each phase of some work as well as for the work as a whole. The values
+// are passed through the warm-up, valid range, deadband and sampling of
+// the new Stat alone; those it records are recorded in this Stat without
+// being filtered or sampled again.
+//
+// The new Stat has the same units, the same number of minimum and maximum
+// values, histogram size, options and metadata. It has a cache of the same
+// size as this Stat's if it still has one, otherwise one of the default
+// size, unless this Stat is exact (see StatExact) in which case the cache
+// is the size of the exact limit.
+func (s *Stat) Fork() *Stat {
+	child := &Stat{
+		units:          s.units,
+		mins:           make([]float64, 0, cap(s.mins)),
+		maxs:           make([]float64, 0, cap(s.maxs)),
+		compensated:    s.compensated,
+		histSizeChosen: s.histSizeChosen,
+		noCache:        s.noCache,
+		noHist:         s.noHist,
+		rebinFraction:  s.rebinFraction,
+		unitPrefixes:   s.unitPrefixes,
+		trackInfo:      s.trackInfo,
+		bucketSums:     s.bucketSums,
+		logBase:        s.logBase,
+		anchor:         s.anchor,
+		anchored:       s.anchored,
+		niceBounds:     s.niceBounds,
+		expRange:       s.expRange,
+		quantileLayout: s.quantileLayout,
+		sampleRate:     s.sampleRate,
+		historyLen:     s.historyLen,
+		trackChange:    s.trackChange,
+		exactLim:       s.exactLim,
+		bessel:         s.bessel,
+		emptyNaN:       s.emptyNaN,
+		transform:      s.transform,
+		meta:           maps.Clone(s.meta),
+		strTmpl:        s.strTmpl,
+		fmtOpts:        s.fmtOpts,
+		parent:         s,
+	}
+
+	if child.trackInfo {
+		child.makeInfo()
+	}
+	if s.exactLim > 0 {
+		child.cache = make([]float64, 0, s.exactLim+1)
+	} else if s.cache != nil {
+		child.cache = make([]float64, 0, cap(s.cache))
+	} else if !s.noCache && !s.noHist && s.expRange == nil {
+		child.makeDfltCache()
+	}
+	child.cacheBuf = child.cache
+	if s.hist != nil {
+		child.hist = make([]int, cap(s.hist))
+		if child.rebinFraction > 0 || child.anchored {
+			child.makeRebinBufs()
+		}
+		if child.bucketSums {
+			child.histSums = make([]float64, len(child.hist))
+		}
+		if child.expRange != nil {
+			child.layoutExpectedRange()
+		}
+	}
+	if s.rate != nil {
+		child.rate = s.rate.empty()
+	}
+	if s.freq != nil {
+		child.freq = newFreqTracker(s.freq.maxSize)
+	}
+	if s.heavy != nil {
+		child.heavy = newHeavyHitters(s.heavy.k)
+	}
+	if s.remedian != nil {
+		child.remedian = newRemedian(s.remedian.base)
+	}
+	if s.distinct != nil {
+		child.distinct = NewCardinalityOrPanic(s.distinct.Precision())
+	}
+	if s.reservoir != nil {
+		child.reservoir = newReservoir(cap(s.reservoir.vals))
+	}
+	if s.deadband != nil {
+		child.deadband = &deadband{epsilon: s.deadband.epsilon}
+	}
+	if s.warmup != nil {
+		child.warmup = &warmup{
+			n:   s.warmup.n,
+			dur: s.warmup.dur,
+			now: s.warmup.now,
+		}
+	}
+	if s.valid != nil {
+		child.valid = &validRange{
+			lo:     s.valid.lo,
+			hi:     s.valid.hi,
+			action: s.valid.action,
+		}
+	}
+	if s.autocorr != nil {
+		child.autocorr = newAutocorrTracker(s.autocorr.maxLag)
+	}
+	if s.tails != nil {
+		child.tails = newTailTracker(s.tails.k)
+	}
+	if s.runs != nil {
+		child.runs = &runTracker{threshold: s.runs.threshold}
+	}
+
+	return child
+}
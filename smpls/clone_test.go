@@ -0,0 +1,54 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestClone(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatTrackFreq(5))
+	addSeq(s, 1, 1, 20)
+
+	c := s.Clone()
+	testhelper.DiffString(t, "Clone", "String", c.String(), s.String())
+	testhelper.DiffString(t, "Clone", "Hist", c.Hist(), s.Hist())
+
+	c.Add(1000)
+	testhelper.DiffInt(t, "Clone - after Add", "original count",
+		s.Count(), 20)
+	testhelper.DiffInt(t, "Clone - after Add", "clone count",
+		c.Count(), 21)
+	testhelper.DiffFloat(t, "Clone - after Add", "original max",
+		s.Max(), 20, 0.0)
+	testhelper.DiffInt(t, "Clone - after Add", "original evicted",
+		s.FreqEvicted(), 15)
+	testhelper.DiffInt(t, "Clone - after Add", "clone evicted",
+		c.FreqEvicted(), 16)
+}
+
+func TestFork(t *testing.T) {
+	overall := NewStatOrPanic("ms", StatMinMaxCount(3), StatCacheSize(10))
+	phase1 := overall.Fork()
+	phase2 := overall.Fork()
+	step := phase2.Fork()
+
+	phase1.Add(1, 2, 3)
+	phase2.Add(10)
+	step.Add(20, 30)
+
+	testhelper.DiffInt(t, "Fork", "overall count", overall.Count(), 6)
+	testhelper.DiffFloat(t, "Fork", "overall sum", overall.Sum(), 66, 0.0)
+	testhelper.DiffInt(t, "Fork", "phase1 count", phase1.Count(), 3)
+	testhelper.DiffInt(t, "Fork", "phase2 count", phase2.Count(), 3)
+	testhelper.DiffFloat(t, "Fork", "phase2 min", phase2.Min(), 10, 0.0)
+	testhelper.DiffInt(t, "Fork", "step count", step.Count(), 2)
+	testhelper.DiffInt(t, "Fork", "phase1 mins", cap(phase1.mins), 3)
+	testhelper.DiffInt(t, "Fork", "phase1 cache", cap(phase1.cache), 10)
+	testhelper.DiffString(t, "Fork", "units", phase1.Units(), "ms")
+
+	c := step.Clone()
+	c.Add(40)
+	testhelper.DiffInt(t, "Fork - clone detached", "overall count",
+		overall.Count(), 6)
+}
@@ -0,0 +1,216 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+// cmpPercentiles are the percentiles compared by the Compare function
+var cmpPercentiles = []float64{50, 90, 99}
+
+// welchSignificantT is the magnitude of the Welch's t statistic above which
+// the difference in means is reported as significant. It is a rule of thumb
+// approximating the 95% confidence level for reasonably large samples.
+const welchSignificantT = 2.0
+
+// CmpVal holds a pair of values, A and B, taken from two Stats
+type CmpVal struct {
+	A float64
+	B float64
+}
+
+// Diff returns the difference between the values (B - A)
+func (cv CmpVal) Diff() float64 {
+	return cv.B - cv.A
+}
+
+// PctDiff returns the difference between the values as a percentage of the
+// A value. It returns NaN if A is zero.
+func (cv CmpVal) PctDiff() float64 {
+	if cv.A == 0 {
+		return math.NaN()
+	}
+	return 100.0 * (cv.B - cv.A) / math.Abs(cv.A)
+}
+
+// PctileCmp holds the values of a percentile from two Stats
+type PctileCmp struct {
+	Pct float64
+	CmpVal
+}
+
+// Comparison holds the results of comparing two Stats
+type Comparison struct {
+	UnitsA string
+	UnitsB string
+	CountA int
+	CountB int
+
+	Mean        CmpVal
+	StdDev      CmpVal
+	Min         CmpVal
+	Max         CmpVal
+	Percentiles []PctileCmp
+
+	// MaxCDFDiff is the largest difference between the fractions of values
+	// below any histogram bucket boundary in either Stat. It is an estimate
+	// of the Kolmogorov-Smirnov statistic and shows how much the shapes of
+	// the distributions differ: 0 means they are the same and 1 means they
+	// don't overlap at all. It is NaN if either Stat has no histogram.
+	MaxCDFDiff float64
+
+	// WelchT is the Welch's t statistic for the difference in means and
+	// WelchDF is the associated degrees of freedom. They are NaN if either
+	// Stat has fewer than two values or both have no variance. WelchP is
+	// the approximate two-sided p-value (see WelchT).
+	WelchT  float64
+	WelchDF float64
+	WelchP  float64
+}
+
+// Significant returns true if the magnitude of the Welch's t statistic
+// suggests that the difference in means is significant. This is only a
+// rough indicator.
+func (c Comparison) Significant() bool {
+	return math.Abs(c.WelchT) > welchSignificantT
+}
+
+// sampleVariance returns the sample variance of the values (using Bessel's
+// correction). It is the same whether or not the Stat reports the sample
+// standard deviation (see StatBesselCorrection). It returns 0.0 if fewer
+// than two values have been added.
+func (s Stat) sampleVariance() float64 {
+	if s.count < 2 {
+		return 0.0
+	}
+	n := float64(s.count)
+	_, variance := s.meanVar()
+	return variance * n / (n - 1)
+}
+
+// welch returns the Welch's t statistic and the Welch-Satterthwaite degrees
+// of freedom for the difference between the means of the two Stats. They
+// are NaN if either Stat has fewer than two values or if both have no
+// variance.
+func welch(a, b *Stat) (t, df float64) {
+	if a.count < 2 || b.count < 2 {
+		return math.NaN(), math.NaN()
+	}
+
+	na, nb := float64(a.count), float64(b.count)
+	va := a.sampleVariance() / na
+	vb := b.sampleVariance() / nb
+	if va+vb == 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	t = (a.Mean() - b.Mean()) / math.Sqrt(va+vb)
+	df = (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	return t, df
+}
+
+// WelchT performs Welch's t-test on the two Stats. It returns the t
+// statistic for the difference between their means, the Welch-Satterthwaite
+// degrees of freedom and the two-sided p-value: the probability of a
+// difference at least this large if the means were equal. The test only
+// needs the count, mean and variance of each Stat and so it can be used
+// however the Stats were created. The p-value is approximate, the test
+// assumes the means are roughly normally distributed, which holds for
+// large samples. The values are all NaN if either Stat has fewer than two
+// values or if both have no variance.
+func WelchT(a, b *Stat) (t, df, p float64) {
+	t, df = welch(a, b)
+	return t, df, welchP(t, df)
+}
+
+// welchP returns the two-sided p-value for the Welch's t statistic with the
+// given degrees of freedom
+func welchP(t, df float64) float64 {
+	return 2 * studentTCDF(-math.Abs(t), df)
+}
+
+// maxCDFDiff returns the largest difference between the CDFs of the two
+// Stats evaluated at each of their histogram bucket boundaries
+func maxCDFDiff(a, b *Stat) float64 {
+	if a.hist == nil || b.hist == nil {
+		return math.NaN()
+	}
+	if a.count == 0 || b.count == 0 {
+		return 0.0
+	}
+
+	var maxDiff float64
+	for _, cdf := range [][]CDFPoint{a.CDF(), b.CDF()} {
+		for _, p := range cdf {
+			maxDiff = math.Max(maxDiff,
+				math.Abs(a.cdfAt(p.UpperBound)-b.cdfAt(p.UpperBound)))
+		}
+	}
+	return maxDiff
+}
+
+// Compare compares the two Stats and returns a Comparison recording their
+// differences.
+func Compare(a, b *Stat) Comparison {
+	c := Comparison{
+		UnitsA: a.units,
+		UnitsB: b.units,
+		CountA: a.Count(),
+		CountB: b.Count(),
+
+		Mean:   CmpVal{A: a.Mean(), B: b.Mean()},
+		StdDev: CmpVal{A: a.StdDev(), B: b.StdDev()},
+		Min:    CmpVal{A: a.Min(), B: b.Min()},
+		Max:    CmpVal{A: a.Max(), B: b.Max()},
+
+		MaxCDFDiff: maxCDFDiff(a, b),
+	}
+
+	for _, p := range cmpPercentiles {
+		c.Percentiles = append(c.Percentiles, PctileCmp{
+			Pct:    p,
+			CmpVal: CmpVal{A: a.Percentile(p), B: b.Percentile(p)},
+		})
+	}
+
+	c.WelchT, c.WelchDF, c.WelchP = WelchT(a, b)
+
+	return c
+}
+
+// String returns a report showing the differences between the two Stats
+func (c Comparison) String() string {
+	const (
+		hdrFmt = "%-8s %9s %9s %9s %8s\n"
+		valFmt = "%-8s %9.2e %9.2e %9.2e %7.2f%%\n"
+	)
+
+	units := c.UnitsA
+	if c.UnitsB != c.UnitsA {
+		units += " / " + c.UnitsB
+	}
+
+	str := "units: " + units + "\n"
+	str += fmt.Sprintf(hdrFmt, "", "A", "B", "B-A", "change")
+	str += fmt.Sprintf("%-8s %9d %9d %9d\n",
+		"count:", c.CountA, c.CountB, c.CountB-c.CountA)
+
+	addVal := func(name string, cv CmpVal) {
+		str += fmt.Sprintf(valFmt, name, cv.A, cv.B, cv.Diff(), cv.PctDiff())
+	}
+	addVal("min:", c.Min)
+	addVal("mean:", c.Mean)
+	addVal("SD:", c.StdDev)
+	for _, p := range c.Percentiles {
+		addVal(fmt.Sprintf("p%g:", p.Pct), p.CmpVal)
+	}
+	addVal("max:", c.Max)
+
+	str += fmt.Sprintf("max CDF difference: %.3f\n", c.MaxCDFDiff)
+	str += fmt.Sprintf("Welch's t: %.3f (df: %.1f, p: %.3g)",
+		c.WelchT, c.WelchDF, c.WelchP)
+	if c.Significant() {
+		str += " - the difference in means is probably significant"
+	}
+	return str + "\n"
+}
@@ -0,0 +1,103 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCompare(t *testing.T) {
+	a := NewStatOrPanic("ms")
+	addSeq(a, 1, 1, 10)
+	b := NewStatOrPanic("ms")
+	addSeq(b, 2, 1, 10)
+
+	c := Compare(a, b)
+	id := "Compare"
+	testhelper.DiffInt(t, id, "count A", c.CountA, 10)
+	testhelper.DiffInt(t, id, "count B", c.CountB, 10)
+	testhelper.DiffFloat(t, id, "mean diff", c.Mean.Diff(), 1.0, 0.0)
+	testhelper.DiffFloat(t, id, "mean pct diff",
+		c.Mean.PctDiff(), 100.0/5.5, 0.00001)
+	testhelper.DiffFloat(t, id, "SD diff", c.StdDev.Diff(), 0.0, 0.00001)
+	testhelper.DiffFloat(t, id, "min diff", c.Min.Diff(), 1.0, 0.0)
+	testhelper.DiffFloat(t, id, "max diff", c.Max.Diff(), 1.0, 0.0)
+	testhelper.DiffInt(t, id, "percentile count", len(c.Percentiles), 3)
+	for _, p := range c.Percentiles {
+		testhelper.DiffFloat(t, id, "percentile diff", p.Diff(), 1.0, 0.00001)
+	}
+	testhelper.DiffFloat(t, id, "Welch's t",
+		c.WelchT, -1.0/math.Sqrt(2*(82.5/9)/10), 0.00001)
+	testhelper.DiffFloat(t, id, "Welch's DF", c.WelchDF, 18.0, 0.00001)
+	testhelper.DiffFloat(t, id, "Welch's p", c.WelchP, 0.4697, 0.0001)
+	testhelper.DiffBool(t, id, "significant", c.Significant(), false)
+	if c.MaxCDFDiff <= 0 || c.MaxCDFDiff > 1 {
+		t.Log(id)
+		t.Errorf("\t: bad max CDF difference: %g\n", c.MaxCDFDiff)
+	}
+
+	same := Compare(a, a)
+	testhelper.DiffFloat(t, id+" - same", "max CDF diff",
+		same.MaxCDFDiff, 0.0, 0.0)
+
+	testhelper.ShouldContain(t, id, "report", c.String(),
+		[]string{"units: ms\n", "mean:", "p99:", "Welch's t"})
+
+	empty := Compare(NewStatOrPanic("ms"), b)
+	testhelper.DiffBool(t, id+" - empty", "Welch's t is NaN",
+		math.IsNaN(empty.WelchT), true)
+}
+
+func TestWelchT(t *testing.T) {
+	a := NewStatOrPanic("ms")
+	addSeq(a, 1, 1, 10)
+	b := NewStatOrPanic("ms")
+	addSeq(b, 2, 1, 10)
+
+	tStat, df, p := WelchT(a, b)
+	testhelper.DiffFloat(t, "overlapping", "t", tStat, -0.7385, 0.0001)
+	testhelper.DiffFloat(t, "overlapping", "df", df, 18.0, 0.00001)
+	testhelper.DiffFloat(t, "overlapping", "p", p, 0.4697, 0.0001)
+
+	_, _, p = WelchT(a, a)
+	testhelper.DiffFloat(t, "same", "p", p, 1.0, 0.0)
+
+	far := NewStatOrPanic("ms")
+	addSeq(far, 11, 1, 10)
+	tStat, _, p = WelchT(a, far)
+	testhelper.DiffFloat(t, "separated", "t", tStat, -7.385, 0.001)
+	if p > 1e-5 {
+		t.Log("separated")
+		t.Errorf("\t: the p-value (%g) should be tiny", p)
+	}
+
+	// the Bessel correction is applied once whatever the Stat reports
+	aBessel := NewStatOrPanic("ms", StatBesselCorrection())
+	addSeq(aBessel, 1, 1, 10)
+	bBessel := NewStatOrPanic("ms", StatBesselCorrection())
+	addSeq(bBessel, 2, 1, 10)
+	tBessel, dfBessel, _ := WelchT(aBessel, bBessel)
+	testhelper.DiffFloat(t, "Bessel corrected", "t", tBessel, -0.7385, 0.0001)
+	testhelper.DiffFloat(t, "Bessel corrected", "df", dfBessel, 18.0, 0.00001)
+
+	same := NewStatOrPanic("ms", StatBesselCorrection())
+	addSeq(same, 1, 1, 5)
+	sameShifted := NewStatOrPanic("ms", StatBesselCorrection())
+	addSeq(sameShifted, 2, 1, 5)
+	plain := NewStatOrPanic("ms")
+	addSeq(plain, 1, 1, 5)
+	plainShifted := NewStatOrPanic("ms")
+	addSeq(plainShifted, 2, 1, 5)
+	tBessel, _, _ = WelchT(same, sameShifted)
+	tStat, _, _ = WelchT(plain, plainShifted)
+	testhelper.DiffFloat(t, "Bessel corrected", "t (5 values)",
+		tBessel, tStat, 1e-12)
+	testhelper.DiffFloat(t, "Bessel corrected", "t (5 values) value",
+		tBessel, -1, 1e-12)
+
+	tStat, df, p = WelchT(NewStatOrPanic("ms"), b)
+	testhelper.DiffBool(t, "empty", "t is NaN", math.IsNaN(tStat), true)
+	testhelper.DiffBool(t, "empty", "df is NaN", math.IsNaN(df), true)
+	testhelper.DiffBool(t, "empty", "p is NaN", math.IsNaN(p), true)
+}
@@ -0,0 +1,380 @@
+package smpls
+
+import (
+	"slices"
+	"time"
+)
+
+// Config describes how to construct a Stat. It can be read from a
+// configuration file (it has JSON, YAML and TOML field tags) so that
+// services can configure their statistics without changing their code.
+// The zero value of each field gives the default behaviour; each field
+// corresponds to one of the StatOpt functions, as noted below, and is
+// checked in the same way.
+type Config struct {
+	Units string `json:"units" yaml:"units" toml:"units"`
+
+	// MinMaxCount sets the number of minimum and maximum values kept (see
+	// StatMinMaxCount)
+	MinMaxCount int `json:"minMaxCount,omitempty" yaml:"minMaxCount,omitempty" toml:"minMaxCount,omitempty"`
+	// CacheSize sets the size of the cache (see StatCacheSize)
+	CacheSize int `json:"cacheSize,omitempty" yaml:"cacheSize,omitempty" toml:"cacheSize,omitempty"`
+	// NoCache stops the Stat keeping a cache (see StatNoCache)
+	NoCache bool `json:"noCache,omitempty" yaml:"noCache,omitempty" toml:"noCache,omitempty"`
+	// NoHist stops the Stat keeping a histogram (see StatNoHist)
+	NoHist bool `json:"noHist,omitempty" yaml:"noHist,omitempty" toml:"noHist,omitempty"`
+
+	// HistBucketCount sets the number of histogram buckets (see
+	// StatHistBucketCount)
+	HistBucketCount int `json:"histBucketCount,omitempty" yaml:"histBucketCount,omitempty" toml:"histBucketCount,omitempty"`
+	// HistBucketSums records the sum of the values in each bucket (see
+	// StatHistBucketSums)
+	HistBucketSums bool `json:"histBucketSums,omitempty" yaml:"histBucketSums,omitempty" toml:"histBucketSums,omitempty"`
+	// HistAutoRebin sets the fraction of values outside the histogram at
+	// which it is rebuilt (see StatHistAutoRebin)
+	HistAutoRebin float64 `json:"histAutoRebin,omitempty" yaml:"histAutoRebin,omitempty" toml:"histAutoRebin,omitempty"`
+	// HistLogBase lays out the histogram on a logarithmic scale (see
+	// StatLogTransform)
+	HistLogBase float64 `json:"histLogBase,omitempty" yaml:"histLogBase,omitempty" toml:"histLogBase,omitempty"`
+	// HistQuantileLayout lays out the histogram at the quantiles of the
+	// values (see StatHistQuantileLayout)
+	HistQuantileLayout bool `json:"histQuantileLayout,omitempty" yaml:"histQuantileLayout,omitempty" toml:"histQuantileLayout,omitempty"`
+	// HistAnchor, if set, anchors the histogram at the value (see
+	// StatHistAnchor)
+	HistAnchor *float64 `json:"histAnchor,omitempty" yaml:"histAnchor,omitempty" toml:"histAnchor,omitempty"`
+	// HistNiceBounds rounds the bucket boundaries to nice numbers (see
+	// StatHistNiceBounds)
+	HistNiceBounds bool `json:"histNiceBounds,omitempty" yaml:"histNiceBounds,omitempty" toml:"histNiceBounds,omitempty"`
+
+	// CompensatedSum uses compensated summation (see StatCompensatedSum)
+	CompensatedSum bool `json:"compensatedSum,omitempty" yaml:"compensatedSum,omitempty" toml:"compensatedSum,omitempty"`
+	// UnitPrefixes shows values with SI unit prefixes (see
+	// StatUnitPrefixes)
+	UnitPrefixes bool `json:"unitPrefixes,omitempty" yaml:"unitPrefixes,omitempty" toml:"unitPrefixes,omitempty"`
+	// TrackInfo records information with the minimum and maximum values
+	// (see StatTrackInfo)
+	TrackInfo bool `json:"trackInfo,omitempty" yaml:"trackInfo,omitempty" toml:"trackInfo,omitempty"`
+	// TrackFreq records the frequencies of up to this many distinct
+	// values (see StatTrackFreq)
+	TrackFreq int `json:"trackFreq,omitempty" yaml:"trackFreq,omitempty" toml:"trackFreq,omitempty"`
+	// HeavyHitters counts approximately this many of the most frequent
+	// values (see StatHeavyHitters)
+	HeavyHitters int `json:"heavyHitters,omitempty" yaml:"heavyHitters,omitempty" toml:"heavyHitters,omitempty"`
+	// ApproxMedian estimates the median with a remedian of this base (see
+	// StatApproxMedian)
+	ApproxMedian int `json:"approxMedian,omitempty" yaml:"approxMedian,omitempty" toml:"approxMedian,omitempty"`
+	// TrackDistinct estimates the number of distinct values, with the
+	// given precision or the default if DistinctPrecision is 0 (see
+	// StatTrackDistinct)
+	TrackDistinct     bool `json:"trackDistinct,omitempty" yaml:"trackDistinct,omitempty" toml:"trackDistinct,omitempty"`
+	DistinctPrecision int  `json:"distinctPrecision,omitempty" yaml:"distinctPrecision,omitempty" toml:"distinctPrecision,omitempty"`
+	// TrackRuns, if set, records runs of values using the value as the
+	// threshold (see StatTrackRuns)
+	TrackRuns *float64 `json:"trackRuns,omitempty" yaml:"trackRuns,omitempty" toml:"trackRuns,omitempty"`
+	// Deadband, if set, ignores values within this of the last value
+	// recorded (see StatDeadband)
+	Deadband *float64 `json:"deadband,omitempty" yaml:"deadband,omitempty" toml:"deadband,omitempty"`
+	// Warmup discards this many values at the start (see StatWarmup)
+	Warmup int `json:"warmup,omitempty" yaml:"warmup,omitempty" toml:"warmup,omitempty"`
+	// WarmupDuration discards the values added in this period, a duration
+	// as accepted by time.ParseDuration, from the first value (see
+	// StatWarmupDuration)
+	WarmupDuration string `json:"warmupDuration,omitempty" yaml:"warmupDuration,omitempty" toml:"warmupDuration,omitempty"`
+	// Range, if set, gives the valid range of the values (see StatRange)
+	Range *RangeConfig `json:"range,omitempty" yaml:"range,omitempty" toml:"range,omitempty"`
+	// ExpectedRange, if set, lays out the histogram to cover the range
+	// from the start (see StatExpectedRange); the Action is not used
+	ExpectedRange *RangeConfig `json:"expectedRange,omitempty" yaml:"expectedRange,omitempty" toml:"expectedRange,omitempty"`
+	// TailCapture keeps this many of the most extreme values in the
+	// underflow and the overflow (see StatTailCapture)
+	TailCapture int `json:"tailCapture,omitempty" yaml:"tailCapture,omitempty" toml:"tailCapture,omitempty"`
+	// Autocorrelation records the autocorrelation of the values for lags
+	// up to this (see StatAutocorrelation)
+	Autocorrelation int `json:"autocorrelation,omitempty" yaml:"autocorrelation,omitempty" toml:"autocorrelation,omitempty"`
+	// ReservoirSize keeps a random sample of the values (see
+	// StatReservoirSize)
+	ReservoirSize int `json:"reservoirSize,omitempty" yaml:"reservoirSize,omitempty" toml:"reservoirSize,omitempty"`
+	// SampleRate records only this fraction of the values (see
+	// StatSampleRate)
+	SampleRate float64 `json:"sampleRate,omitempty" yaml:"sampleRate,omitempty" toml:"sampleRate,omitempty"`
+	// MaxMemory sets the memory budget (see StatMaxMemory)
+	MaxMemory int `json:"maxMemory,omitempty" yaml:"maxMemory,omitempty" toml:"maxMemory,omitempty"`
+	// Exact keeps all the values, and calculates the statistics exactly,
+	// until more than this many have been added (see StatExact)
+	Exact int `json:"exact,omitempty" yaml:"exact,omitempty" toml:"exact,omitempty"`
+	// BesselCorrection reports the sample standard deviation (see
+	// StatBesselCorrection)
+	BesselCorrection bool `json:"besselCorrection,omitempty" yaml:"besselCorrection,omitempty" toml:"besselCorrection,omitempty"`
+	// EmptyNaN makes Vals return NaN values for an empty Stat (see
+	// StatEmptyNaN)
+	EmptyNaN bool `json:"emptyNaN,omitempty" yaml:"emptyNaN,omitempty" toml:"emptyNaN,omitempty"`
+	// HistoryLen sets the number of Snapshots kept by Rotate (see
+	// StatHistoryLen)
+	HistoryLen int `json:"historyLen,omitempty" yaml:"historyLen,omitempty" toml:"historyLen,omitempty"`
+	// TrackChange remembers the values of the last cycle when the Stat is
+	// Reset (see StatTrackChange)
+	TrackChange bool `json:"trackChange,omitempty" yaml:"trackChange,omitempty" toml:"trackChange,omitempty"`
+
+	// Rate records the rate at which values are added (see StatRate). The
+	// RateWindow is a duration, as accepted by time.ParseDuration, and with
+	// RateSlots sets the window of the Rate (see RateWindow); if they are
+	// not set the defaults are used.
+	Rate       bool   `json:"rate,omitempty" yaml:"rate,omitempty" toml:"rate,omitempty"`
+	RateWindow string `json:"rateWindow,omitempty" yaml:"rateWindow,omitempty" toml:"rateWindow,omitempty"`
+	RateSlots  int    `json:"rateSlots,omitempty" yaml:"rateSlots,omitempty" toml:"rateSlots,omitempty"`
+
+	// StringTemplate sets the template used by String (see
+	// StatStringTemplate)
+	StringTemplate string `json:"stringTemplate,omitempty" yaml:"stringTemplate,omitempty" toml:"stringTemplate,omitempty"`
+	// Format sets the format used by String (see StatFormat)
+	Format *FmtOpts `json:"format,omitempty" yaml:"format,omitempty" toml:"format,omitempty"`
+}
+
+// RangeConfig describes the valid range of the values in a Stat (see
+// StatRange). The Action is the name of a RangeAction; if it is not set
+// values outside the range are clamped.
+type RangeConfig struct {
+	Min    float64 `json:"min" yaml:"min" toml:"min"`
+	Max    float64 `json:"max" yaml:"max" toml:"max"`
+	Action string  `json:"action,omitempty" yaml:"action,omitempty" toml:"action,omitempty"`
+}
+
+// rateOpts returns the options for the Rate
+func (cfg Config) rateOpts() ([]RateOpt, error) {
+	if cfg.RateWindow == "" && cfg.RateSlots == 0 {
+		return nil, nil
+	}
+
+	window := dfltRateWindow
+	if cfg.RateWindow != "" {
+		var err error
+		window, err = time.ParseDuration(cfg.RateWindow)
+		if err != nil {
+			return nil, invalidValue("Invalid rate window (%q): %w",
+				cfg.RateWindow, err)
+		}
+	}
+	slots := cfg.RateSlots
+	if slots == 0 {
+		slots = dfltRateSlotCount
+	}
+	return []RateOpt{RateWindow(window, slots)}, nil
+}
+
+// Opts returns the StatOpt functions described by the Config
+func (cfg Config) Opts() ([]StatOpt, error) {
+	var opts []StatOpt
+	addIf := func(cond bool, o StatOpt) {
+		if cond {
+			opts = append(opts, o)
+		}
+	}
+
+	addIf(cfg.MinMaxCount != 0, StatMinMaxCount(cfg.MinMaxCount))
+	addIf(cfg.NoCache, StatNoCache())
+	addIf(cfg.NoHist, StatNoHist())
+	addIf(cfg.CacheSize != 0, StatCacheSize(cfg.CacheSize))
+	addIf(cfg.MaxMemory != 0, StatMaxMemory(cfg.MaxMemory))
+	addIf(cfg.HistBucketCount != 0, StatHistBucketCount(cfg.HistBucketCount))
+	addIf(cfg.HistBucketSums, StatHistBucketSums())
+	addIf(cfg.HistAutoRebin != 0, StatHistAutoRebin(cfg.HistAutoRebin))
+	addIf(cfg.HistLogBase != 0, StatLogTransform(cfg.HistLogBase))
+	addIf(cfg.HistQuantileLayout, StatHistQuantileLayout())
+	if cfg.HistAnchor != nil {
+		opts = append(opts, StatHistAnchor(*cfg.HistAnchor))
+	}
+	addIf(cfg.HistNiceBounds, StatHistNiceBounds())
+	addIf(cfg.CompensatedSum, StatCompensatedSum())
+	addIf(cfg.UnitPrefixes, StatUnitPrefixes())
+	addIf(cfg.TrackInfo, StatTrackInfo())
+	addIf(cfg.TrackFreq != 0, StatTrackFreq(cfg.TrackFreq))
+	addIf(cfg.HeavyHitters != 0, StatHeavyHitters(cfg.HeavyHitters))
+	addIf(cfg.ApproxMedian != 0, StatApproxMedian(cfg.ApproxMedian))
+	addIf(cfg.TrackDistinct, StatTrackDistinct(cfg.DistinctPrecision))
+	if cfg.TrackRuns != nil {
+		opts = append(opts, StatTrackRuns(*cfg.TrackRuns))
+	}
+	if cfg.Deadband != nil {
+		opts = append(opts, StatDeadband(*cfg.Deadband))
+	}
+	addIf(cfg.Warmup != 0, StatWarmup(cfg.Warmup))
+	if cfg.WarmupDuration != "" {
+		d, err := time.ParseDuration(cfg.WarmupDuration)
+		if err != nil {
+			return nil, invalidValue("Invalid warm-up duration (%q): %w",
+				cfg.WarmupDuration, err)
+		}
+		opts = append(opts, StatWarmupDuration(d))
+	}
+	if cfg.Range != nil {
+		action := RangeClamp
+		if cfg.Range.Action != "" {
+			var err error
+			action, err = ParseRangeAction(cfg.Range.Action)
+			if err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, StatRange(cfg.Range.Min, cfg.Range.Max, action))
+	}
+	if cfg.ExpectedRange != nil {
+		opts = append(opts,
+			StatExpectedRange(cfg.ExpectedRange.Min, cfg.ExpectedRange.Max))
+	}
+	addIf(cfg.TailCapture != 0, StatTailCapture(cfg.TailCapture))
+	addIf(cfg.Autocorrelation != 0, StatAutocorrelation(cfg.Autocorrelation))
+	addIf(cfg.ReservoirSize != 0, StatReservoirSize(cfg.ReservoirSize))
+	addIf(cfg.SampleRate != 0, StatSampleRate(cfg.SampleRate))
+	addIf(cfg.Exact != 0, StatExact(cfg.Exact))
+	addIf(cfg.BesselCorrection, StatBesselCorrection())
+	addIf(cfg.EmptyNaN, StatEmptyNaN())
+	addIf(cfg.TrackChange, StatTrackChange())
+	addIf(cfg.HistoryLen != 0, StatHistoryLen(cfg.HistoryLen))
+
+	if cfg.Rate {
+		rateOpts, err := cfg.rateOpts()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, StatRate(rateOpts...))
+	}
+
+	addIf(cfg.StringTemplate != "", StatStringTemplate(cfg.StringTemplate))
+	if cfg.Format != nil {
+		opts = append(opts, StatFormat(*cfg.Format))
+	}
+
+	return opts, nil
+}
+
+// NewStatFromConfig creates a new Stat as described by the Config. Any
+// extra options are applied after those from the Config.
+func NewStatFromConfig(cfg Config, extra ...StatOpt) (*Stat, error) {
+	opts, err := cfg.Opts()
+	if err != nil {
+		return nil, err
+	}
+	return NewStat(cfg.Units, append(opts, extra...)...)
+}
+
+// NewStatFromConfigOrPanic creates a new Stat as described by the Config
+// and will panic if any errors are detected
+func NewStatFromConfigOrPanic(cfg Config, extra ...StatOpt) *Stat {
+	s, err := NewStatFromConfig(cfg, extra...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Config returns the effective configuration of the Stat: the sizes it has
+// chosen, including any defaults, and the options it was created with.
+// This lets a test check that a Stat has been set up as intended. Creating
+// a Stat from the Config (see NewStatFromConfig) gives a Stat configured
+// in the same way but without the values. The number of histogram buckets
+// is only given if it was chosen (see StatHistBucketCount).
+//
+// The Config cannot describe a transform (see StatTransform), any
+// Accumulators, a raw value Writer (see StatRawValues), a sample store (see
+// StatSampleStore) or the metadata and these are not included. The format
+// (see StatFormat) is a copy.
+func (s Stat) Config() Config {
+	cfg := Config{
+		Units:              s.units,
+		MinMaxCount:        cap(s.mins),
+		NoCache:            s.noCache,
+		NoHist:             s.noHist,
+		HistBucketSums:     s.bucketSums,
+		HistAutoRebin:      s.rebinFraction,
+		HistLogBase:        s.logBase,
+		HistQuantileLayout: s.quantileLayout,
+		HistNiceBounds:     s.niceBounds,
+		CompensatedSum:     s.compensated,
+		UnitPrefixes:       s.unitPrefixes,
+		TrackInfo:          s.trackInfo,
+		SampleRate:         s.sampleRate,
+		MaxMemory:          s.maxMemory,
+		Exact:              s.exactLim,
+		BesselCorrection:   s.bessel,
+		EmptyNaN:           s.emptyNaN,
+		HistoryLen:         s.historyLen,
+		TrackChange:        s.trackChange,
+	}
+
+	// an exact Stat chooses its own cache size
+	if s.exactLim == 0 {
+		cfg.CacheSize = max(cap(s.cache), cap(s.cacheBuf))
+	}
+	// a Stat whose histogram size was not chosen sizes it to the values
+	if !s.noHist && s.histSizeChosen {
+		cfg.HistBucketCount = cap(s.hist)
+	}
+	if s.anchored {
+		anchor := s.anchor
+		cfg.HistAnchor = &anchor
+	}
+	if s.freq != nil {
+		cfg.TrackFreq = s.freq.maxSize
+	}
+	if s.heavy != nil {
+		cfg.HeavyHitters = s.heavy.k
+	}
+	if s.remedian != nil {
+		cfg.ApproxMedian = s.remedian.base
+	}
+	if s.distinct != nil {
+		cfg.TrackDistinct = true
+		cfg.DistinctPrecision = s.distinct.Precision()
+	}
+	if s.runs != nil {
+		threshold := s.runs.threshold
+		cfg.TrackRuns = &threshold
+	}
+	if s.warmup != nil {
+		cfg.Warmup = s.warmup.n
+		if s.warmup.dur > 0 {
+			cfg.WarmupDuration = s.warmup.dur.String()
+		}
+	}
+	if s.deadband != nil {
+		epsilon := s.deadband.epsilon
+		cfg.Deadband = &epsilon
+	}
+	if s.valid != nil {
+		cfg.Range = &RangeConfig{
+			Min:    s.valid.lo,
+			Max:    s.valid.hi,
+			Action: s.valid.action.String(),
+		}
+	}
+	if s.expRange != nil {
+		cfg.ExpectedRange = &RangeConfig{
+			Min: s.expRange.lo,
+			Max: s.expRange.hi,
+		}
+	}
+	if s.tails != nil {
+		cfg.TailCapture = s.tails.k
+	}
+	if s.autocorr != nil {
+		cfg.Autocorrelation = s.autocorr.maxLag
+	}
+	if s.reservoir != nil {
+		cfg.ReservoirSize = cap(s.reservoir.vals)
+	}
+	if s.rate != nil {
+		cfg.Rate = true
+		cfg.RateWindow = s.rate.window.String()
+		cfg.RateSlots = len(s.rate.slots)
+	}
+	if s.strTmpl != nil {
+		cfg.StringTemplate = s.strTmpl.Tree.Root.String()
+	}
+	if s.fmtOpts != nil {
+		fo := *s.fmtOpts
+		fo.Fields = slices.Clone(fo.Fields)
+		cfg.Format = &fo
+	}
+
+	return cfg
+}
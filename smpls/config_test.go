@@ -0,0 +1,246 @@
+package smpls
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewStatFromConfig(t *testing.T) {
+	const cfgJSON = `{
+		"units": "ms",
+		"minMaxCount": 5,
+		"cacheSize": 100,
+		"histBucketCount": 10,
+		"histBucketSums": true,
+		"histAnchor": 0,
+		"trackRuns": 50,
+		"reservoirSize": 20,
+		"historyLen": 3,
+		"rate": true,
+		"rateWindow": "10s",
+		"rateSlots": 5,
+		"format": {"Fields": [0, 3]}
+	}`
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(cfgJSON), &cfg); err != nil {
+		t.Fatal("Couldn't unmarshal the Config:", err)
+	}
+	s, err := NewStatFromConfig(cfg)
+	if err != nil {
+		t.Fatal("Couldn't create the Stat:", err)
+	}
+
+	id := "from JSON"
+	testhelper.DiffString(t, id, "units", s.Units(), "ms")
+	testhelper.DiffInt(t, id, "min/max count", cap(s.mins), 5)
+	testhelper.DiffInt(t, id, "cache size", cap(s.cache), 100)
+	testhelper.DiffInt(t, id, "bucket count", len(s.hist), 10)
+	testhelper.DiffBool(t, id, "bucket sums", s.bucketSums, true)
+	_, anchored := s.Anchor()
+	testhelper.DiffBool(t, id, "anchored", anchored, true)
+	testhelper.DiffFloat(t, id, "run threshold", s.RunThreshold(), 50, 0)
+	testhelper.DiffInt(t, id, "reservoir size", cap(s.reservoir.vals), 20)
+	testhelper.DiffInt(t, id, "history length", s.historyCap(), 3)
+	testhelper.DiffInt(t, id, "rate window",
+		int(s.Rate().Window()/time.Second), 10)
+
+	s.Add(1, 2)
+	testhelper.DiffString(t, id, "String", s.String(),
+		"      2 observations, avg: 1.50e+00")
+}
+
+func TestNewStatFromConfigErrs(t *testing.T) {
+	anchor := 0.0
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		cfg Config
+	}{
+		{
+			ID:  testhelper.MkID("default"),
+			cfg: Config{Units: "ms"},
+		},
+		{
+			ID:  testhelper.MkID("bad cache size"),
+			cfg: Config{CacheSize: 1},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid cache size (1) - it must be >= 2"),
+		},
+		{
+			ID:  testhelper.MkID("bad rate window"),
+			cfg: Config{Rate: true, RateWindow: "forever"},
+			ExpErr: testhelper.MkExpErr(
+				`Invalid rate window ("forever")`),
+		},
+		{
+			ID:  testhelper.MkID("anchor with log scale"),
+			cfg: Config{HistLogBase: 10, HistAnchor: &anchor},
+			ExpErr: testhelper.MkExpErr(
+				"an anchored histogram cannot use a log transform"),
+		},
+		{
+			ID: testhelper.MkID("bad range action"),
+			cfg: Config{
+				Range: &RangeConfig{Min: 0, Max: 1, Action: "ignore"},
+			},
+			ExpErr: testhelper.MkExpErr(`Invalid range action ("ignore")`),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStatFromConfig(tc.cfg)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestStatConfig(t *testing.T) {
+	anchor := 2.5
+	testCases := []struct {
+		testhelper.ID
+		opts []StatOpt
+		exp  Config
+	}{
+		{
+			ID: testhelper.MkID("defaults"),
+			exp: Config{
+				Units:       "ms",
+				MinMaxCount: dfltMinMaxCount,
+				CacheSize:   dfltCacheSize,
+			},
+		},
+		{
+			ID:   testhelper.MkID("exact"),
+			opts: []StatOpt{StatExact(10)},
+			exp: Config{
+				Units:       "ms",
+				MinMaxCount: dfltMinMaxCount,
+				Exact:       10,
+			},
+		},
+		{
+			ID:   testhelper.MkID("no histogram"),
+			opts: []StatOpt{StatNoHist(), StatMinMaxCount(3)},
+			exp:  Config{Units: "ms", MinMaxCount: 3, NoHist: true},
+		},
+		{
+			ID: testhelper.MkID("many options"),
+			opts: []StatOpt{
+				StatHistBucketCount(20),
+				StatHistAnchor(anchor),
+				StatHistNiceBounds(),
+				StatHistBucketSums(),
+				StatTrackFreq(5),
+				StatTrackRuns(anchor),
+				StatDeadband(anchor),
+				StatRange(0, 10, RangeReject),
+				StatAutocorrelation(2),
+				StatExact(50),
+				StatRate(RateWindow(time.Minute, 6)),
+				StatTrackChange(),
+				StatWarmupDuration(time.Minute),
+			},
+			exp: Config{
+				Units:           "ms",
+				MinMaxCount:     dfltMinMaxCount,
+				HistBucketCount: 20,
+				HistBucketSums:  true,
+				HistAnchor:      &anchor,
+				HistNiceBounds:  true,
+				TrackFreq:       5,
+				TrackRuns:       &anchor,
+				Deadband:        &anchor,
+				Range:           &RangeConfig{Min: 0, Max: 10, Action: "reject"},
+				Autocorrelation: 2,
+				Exact:           50,
+				Rate:            true,
+				RateWindow:      "1m0s",
+				RateSlots:       6,
+				TrackChange:     true,
+				WarmupDuration:  "1m0s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms", tc.opts...)
+		cfg := s.Config()
+		if err := testhelper.DiffVals(cfg, tc.exp); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad Config: %v\n", err)
+		}
+
+		copied, err := NewStatFromConfig(cfg)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: cannot create a Stat from the Config: %v\n", err)
+			continue
+		}
+		if err := testhelper.DiffVals(copied.Config(), cfg); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the recreated Stat has a different Config: %v\n",
+				err)
+		}
+
+		addSeq(s, 1, 1, 100)
+		addSeq(copied, 1, 1, 100)
+		testhelper.DiffInt(t, tc.IDStr(), "histogram buckets",
+			len(copied.hist), len(s.hist))
+		testhelper.DiffString(t, tc.IDStr(), "Hist", copied.Hist(), s.Hist())
+	}
+}
+
+func TestConfigTags(t *testing.T) {
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[Config](),
+		reflect.TypeFor[RangeConfig](),
+	} {
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			id := typ.Name() + "." + f.Name
+			jsonTag := f.Tag.Get("json")
+			testhelper.DiffString(t, id, "yaml tag",
+				f.Tag.Get("yaml"), jsonTag)
+			testhelper.DiffString(t, id, "toml tag",
+				f.Tag.Get("toml"), jsonTag)
+		}
+	}
+}
+
+// TestConfigFileRoundTrip checks that a Stat created from the Config of
+// another, after it has been through a configuration file, is laid out in
+// the same way as the original
+func TestConfigFileRoundTrip(t *testing.T) {
+	for _, opts := range [][]StatOpt{
+		nil,
+		{StatExact(10)},
+		{StatHistBucketCount(7), StatCacheSize(30)},
+		{StatHistQuantileLayout(), StatMinMaxCount(3)},
+	} {
+		s := NewStatOrPanic("ms", opts...)
+		b, err := json.Marshal(s.Config())
+		if err != nil {
+			t.Fatal("Couldn't marshal the Config:", err)
+		}
+		var cfg Config
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			t.Fatal("Couldn't unmarshal the Config:", err)
+		}
+		copied, err := NewStatFromConfig(cfg)
+		if err != nil {
+			t.Fatal("Couldn't create the Stat:", err)
+		}
+
+		id := string(b)
+		addSeq(s, 1, 1, 100)
+		addSeq(copied, 1, 1, 100)
+		testhelper.DiffInt(t, id, "histogram buckets",
+			len(copied.hist), len(s.hist))
+		testhelper.DiffString(t, id, "Hist", copied.Hist(), s.Hist())
+		testhelper.DiffString(t, id, "String", copied.String(), s.String())
+	}
+}
@@ -0,0 +1,172 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// corrTracker records the co-moments of the values in each row added by
+// SampleRow so that the correlations between them can be calculated. The
+// means and co-moments are updated incrementally (Welford's method) to
+// avoid the loss of precision of summing products.
+type corrTracker struct {
+	names    []string
+	count    int
+	means    []float64
+	comoment [][]float64
+	deltas   []float64
+}
+
+// newCorrTracker creates a new corrTracker for the named columns
+func newCorrTracker(names []string) *corrTracker {
+	ct := &corrTracker{
+		names:    names,
+		means:    make([]float64, len(names)),
+		comoment: make([][]float64, len(names)),
+		deltas:   make([]float64, len(names)),
+	}
+	for i := range ct.comoment {
+		ct.comoment[i] = make([]float64, len(names))
+	}
+	return ct
+}
+
+// add records the values from the row, which must hold a value for each
+// of the names
+func (ct *corrTracker) add(row map[string]float64) {
+	ct.count++
+	n := float64(ct.count)
+	for i, name := range ct.names {
+		ct.deltas[i] = row[name] - ct.means[i]
+		ct.means[i] += ct.deltas[i] / n
+	}
+	for i, name := range ct.names {
+		newDelta := row[name] - ct.means[i]
+		for j := range ct.names {
+			ct.comoment[j][i] += ct.deltas[j] * newDelta
+		}
+	}
+}
+
+// Correlations holds the Pearson correlation coefficients between the
+// values of each pair of Stats in a StatSet which have been fed with rows
+// of values (see SampleRow)
+type Correlations struct {
+	// Names are the names of the Stats, sorted
+	Names []string
+	// Count is the number of rows added
+	Count int
+	// R holds the correlations; R[i][j] is the correlation between the
+	// values of the Stats named Names[i] and Names[j]. It is NaN if fewer
+	// than two rows have been added or if either Stat has values which do
+	// not vary.
+	R [][]float64
+}
+
+// Corr returns the correlation between the values of the named Stats and
+// true or NaN and false if either name is not known
+func (c Correlations) Corr(a, b string) (float64, bool) {
+	i, okA := slices.BinarySearch(c.Names, a)
+	j, okB := slices.BinarySearch(c.Names, b)
+	if !okA || !okB {
+		return math.NaN(), false
+	}
+	return c.R[i][j], true
+}
+
+// String returns the correlation matrix as a table
+func (c Correlations) String() string {
+	width := len("-0.000")
+	for _, name := range c.Names {
+		width = max(width, len(name))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rows\n", c.Count)
+	fmt.Fprintf(&b, "%*s", width, "")
+	for _, name := range c.Names {
+		fmt.Fprintf(&b, " %*s", width, name)
+	}
+	b.WriteString("\n")
+	for i, name := range c.Names {
+		fmt.Fprintf(&b, "%-*s", width, name)
+		for _, r := range c.R[i] {
+			fmt.Fprintf(&b, " %*.3f", width, r)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SampleRow adds a row of aligned observations, one value to each named
+// Stat, creating the Stats as necessary. As well as adding the values to
+// the Stats this records how the values in each row vary together so that
+// the correlations between them can be reported (see CorrelationMatrix).
+// The first row sets the names and every later row must have exactly the
+// same names; if it does not an error is returned and none of the values
+// are added.
+func (ss *StatSet) SampleRow(row map[string]float64) error {
+	if ss.corr == nil {
+		names := make([]string, 0, len(row))
+		for name := range row {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		ss.corr = newCorrTracker(names)
+	}
+
+	if len(row) != len(ss.corr.names) {
+		return fmt.Errorf("the row has %d values, expected %d",
+			len(row), len(ss.corr.names))
+	}
+	for _, name := range ss.corr.names {
+		if _, ok := row[name]; !ok {
+			return fmt.Errorf("the row has no value for %q", name)
+		}
+	}
+
+	stats := make([]*Stat, 0, len(ss.corr.names))
+	for _, name := range ss.corr.names {
+		s, err := ss.Stat(name)
+		if err != nil {
+			return err
+		}
+		stats = append(stats, s)
+	}
+
+	for i, name := range ss.corr.names {
+		stats[i].Add(row[name])
+	}
+	ss.corr.add(row)
+	return nil
+}
+
+// CorrelationMatrix returns the correlations between the values of each
+// pair of Stats added by SampleRow. The values added to the Stats in other
+// ways are not included.
+func (ss StatSet) CorrelationMatrix() Correlations {
+	ct := ss.corr
+	if ct == nil {
+		return Correlations{}
+	}
+
+	c := Correlations{
+		Names: slices.Clone(ct.names),
+		Count: ct.count,
+		R:     make([][]float64, len(ct.names)),
+	}
+	for i := range ct.names {
+		c.R[i] = make([]float64, len(ct.names))
+		for j := range ct.names {
+			denom := math.Sqrt(ct.comoment[i][i] * ct.comoment[j][j])
+			if ct.count < 2 || denom == 0 {
+				c.R[i][j] = math.NaN()
+				continue
+			}
+			c.R[i][j] = math.Max(-1, math.Min(1, ct.comoment[i][j]/denom))
+		}
+	}
+	return c
+}
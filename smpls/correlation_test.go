@@ -0,0 +1,80 @@
+package smpls
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCorrelationMatrix(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	for i := range 100 {
+		x := float64(i)
+		err := ss.SampleRow(map[string]float64{
+			"latency":  2*x + 1,
+			"inverse":  -0.5 * x,
+			"constant": 7,
+			"wave":     math.Sin(x),
+		})
+		if err != nil {
+			t.Fatal("unexpected error adding a row:", err)
+		}
+	}
+
+	c := ss.CorrelationMatrix()
+	testhelper.DiffInt(t, "matrix", "count", c.Count, 100)
+	testhelper.DiffStringSlice(t, "matrix", "names", c.Names,
+		[]string{"constant", "inverse", "latency", "wave"})
+
+	check := func(a, b string, exp float64) {
+		t.Helper()
+		r, ok := c.Corr(a, b)
+		testhelper.DiffBool(t, a+"/"+b, "found", ok, true)
+		if math.IsNaN(exp) {
+			testhelper.DiffBool(t, a+"/"+b, "NaN", math.IsNaN(r), true)
+			return
+		}
+		testhelper.DiffFloat(t, a+"/"+b, "correlation", r, exp, 1e-9)
+	}
+	check("latency", "latency", 1)
+	check("latency", "inverse", -1)
+	check("inverse", "latency", -1)
+	check("latency", "constant", math.NaN())
+	if r, _ := c.Corr("latency", "wave"); math.Abs(r) > 0.2 {
+		t.Errorf("latency and wave should be barely correlated: %g", r)
+	}
+	if _, ok := c.Corr("latency", "nonesuch"); ok {
+		t.Error("an unknown name should not be found")
+	}
+
+	s, _ := ss.Lookup("latency")
+	testhelper.DiffInt(t, "latency Stat", "count", s.Count(), 100)
+
+	str := c.String()
+	if !strings.HasPrefix(str, "100 rows\n") ||
+		!strings.Contains(str, "-1.000") {
+		t.Errorf("bad correlation report:\n%s", str)
+	}
+}
+
+func TestSampleRowErrs(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	if err := ss.SampleRow(map[string]float64{"a": 1, "b": 2}); err != nil {
+		t.Fatal("unexpected error adding a row:", err)
+	}
+
+	err := ss.SampleRow(map[string]float64{"a": 1})
+	testhelper.CheckExpErrWithID(t, "short row", err,
+		testhelper.MkExpErr("the row has 1 values, expected 2"))
+	err = ss.SampleRow(map[string]float64{"a": 1, "c": 2})
+	testhelper.CheckExpErrWithID(t, "wrong names", err,
+		testhelper.MkExpErr(`the row has no value for "b"`))
+
+	s, _ := ss.Lookup("a")
+	testhelper.DiffInt(t, "after errors", "count", s.Count(), 1)
+
+	c := NewStatSetOrPanic("ms").CorrelationMatrix()
+	testhelper.DiffInt(t, "no rows", "count", c.Count, 0)
+}
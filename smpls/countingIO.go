@@ -0,0 +1,66 @@
+package smpls
+
+import (
+	"io"
+)
+
+// CountingWriter wraps an io.Writer, recording the number of bytes written
+// by each call to Write in a Stat. This lets you collect the distribution
+// of the sizes of the chunks written to an existing stream without
+// changing the code writing them.
+type CountingWriter struct {
+	w io.Writer
+	s *Stat
+}
+
+// NewCountingWriter returns a CountingWriter writing to w and recording
+// the sizes of the writes in s
+func NewCountingWriter(w io.Writer, s *Stat) *CountingWriter {
+	return &CountingWriter{w: w, s: s}
+}
+
+// Write writes the bytes to the underlying Writer and adds the number
+// written to the Stat. Nothing is added if no bytes are written.
+func (cw *CountingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	if n > 0 {
+		cw.s.Add(float64(n))
+	}
+	return n, err
+}
+
+// Stat returns the Stat recording the sizes of the writes
+func (cw CountingWriter) Stat() *Stat {
+	return cw.s
+}
+
+// CountingReader wraps an io.Reader, recording the number of bytes read by
+// each call to Read in a Stat. This lets you collect the distribution of
+// the sizes of the chunks read from an existing stream without changing
+// the code reading them.
+type CountingReader struct {
+	r io.Reader
+	s *Stat
+}
+
+// NewCountingReader returns a CountingReader reading from r and recording
+// the sizes of the reads in s
+func NewCountingReader(r io.Reader, s *Stat) *CountingReader {
+	return &CountingReader{r: r, s: s}
+}
+
+// Read reads from the underlying Reader and adds the number of bytes read
+// to the Stat. Nothing is added if no bytes are read, as when the end of
+// the input is reached.
+func (cr *CountingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.s.Add(float64(n))
+	}
+	return n, err
+}
+
+// Stat returns the Stat recording the sizes of the reads
+func (cr CountingReader) Stat() *Stat {
+	return cr.s
+}
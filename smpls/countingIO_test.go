@@ -0,0 +1,49 @@
+package smpls
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCountingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStatOrPanic("bytes")
+	cw := NewCountingWriter(&buf, s)
+
+	for _, str := range []string{"a", "bcd", "", "efghij"} {
+		if _, err := io.WriteString(cw, str); err != nil {
+			t.Fatal("unexpected write error:", err)
+		}
+	}
+	testhelper.DiffString(t, "CountingWriter", "written",
+		buf.String(), "abcdefghij")
+	testhelper.DiffInt(t, "CountingWriter", "count", cw.Stat().Count(), 3)
+	testhelper.DiffFloat(t, "CountingWriter", "sum", s.Sum(), 10, 0)
+	testhelper.DiffFloat(t, "CountingWriter", "max", s.Max(), 6, 0)
+}
+
+func TestCountingReader(t *testing.T) {
+	s := NewStatOrPanic("bytes")
+	cr := NewCountingReader(
+		iotest.OneByteReader(strings.NewReader("abcdefghij")), s)
+
+	b, err := io.ReadAll(cr)
+	if err != nil {
+		t.Fatal("unexpected read error:", err)
+	}
+	testhelper.DiffString(t, "CountingReader", "read", string(b),
+		"abcdefghij")
+	testhelper.DiffFloat(t, "CountingReader", "sum", s.Sum(), 10, 0)
+	testhelper.DiffInt(t, "CountingReader", "count", cr.Stat().Count(), 10)
+	testhelper.DiffFloat(t, "CountingReader", "max", s.Max(), 1, 0)
+
+	errReader := NewCountingReader(iotest.ErrReader(io.ErrUnexpectedEOF), s)
+	_, err = errReader.Read(make([]byte, 10))
+	testhelper.DiffBool(t, "error", "error passed on",
+		err == io.ErrUnexpectedEOF, true)
+}
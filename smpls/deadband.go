@@ -0,0 +1,74 @@
+package smpls
+
+import (
+	"math"
+)
+
+// deadband suppresses values which are close to the last value recorded
+type deadband struct {
+	epsilon    float64
+	last       float64
+	seen       bool
+	suppressed int
+}
+
+// skip returns true if the value is within epsilon of the last value
+// recorded, counting it as suppressed. Otherwise the value becomes the last
+// value recorded.
+func (db *deadband) skip(v float64) bool {
+	if db.seen && math.Abs(v-db.last) <= db.epsilon {
+		db.suppressed++
+		return true
+	}
+	db.last, db.seen = v, true
+	return false
+}
+
+// reset clears the record of the last value and the suppressed count
+func (db *deadband) reset() {
+	db.last, db.seen, db.suppressed = 0, false, 0
+}
+
+// StatDeadband returns a function that will make the Stat ignore any value
+// within epsilon of the last value it recorded. Pass 0 to ignore only
+// repeats of the last value so that a run of identical values is recorded
+// once. This is useful when a sensor reports at a fixed frequency but only
+// the distribution of the changes is of interest.
+//
+// The value is compared with the last value recorded, not the last value
+// added, so that a slow drift is still recorded once it has moved by more
+// than epsilon. The number of values ignored is reported by Suppressed;
+// they are not counted in the Count or the Sum.
+func StatDeadband(epsilon float64) StatOpt {
+	return func(s *Stat) error {
+		if s.deadband != nil {
+			return repeatedOption("the deadband has already been set")
+		}
+		if !(epsilon >= 0) || math.IsInf(epsilon, 1) {
+			return invalidValue(
+				"Invalid deadband (%g) - it must be a finite number >= 0",
+				epsilon)
+		}
+
+		s.deadband = &deadband{epsilon: epsilon}
+		return nil
+	}
+}
+
+// Deadband returns the deadband of the Stat and true or 0 and false if the
+// Stat has no deadband (see StatDeadband)
+func (s Stat) Deadband() (float64, bool) {
+	if s.deadband == nil {
+		return 0, false
+	}
+	return s.deadband.epsilon, true
+}
+
+// Suppressed returns the number of values ignored because they were within
+// the deadband of the last value recorded (see StatDeadband)
+func (s Stat) Suppressed() int {
+	if s.deadband == nil {
+		return 0
+	}
+	return s.deadband.suppressed
+}
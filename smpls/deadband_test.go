@@ -0,0 +1,115 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatDeadband(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatDeadband(0.5)},
+		},
+		{
+			ID:   testhelper.MkID("zero"),
+			opts: []StatOpt{StatDeadband(0)},
+		},
+		{
+			ID:   testhelper.MkID("negative"),
+			opts: []StatOpt{StatDeadband(-1)},
+			ExpErr: testhelper.MkExpErr("Invalid deadband (-1)" +
+				" - it must be a finite number >= 0"),
+		},
+		{
+			ID:     testhelper.MkID("NaN"),
+			opts:   []StatOpt{StatDeadband(math.NaN())},
+			ExpErr: testhelper.MkExpErr("Invalid deadband (NaN)"),
+		},
+		{
+			ID:     testhelper.MkID("infinite"),
+			opts:   []StatOpt{StatDeadband(math.Inf(1))},
+			ExpErr: testhelper.MkExpErr("Invalid deadband (+Inf)"),
+		},
+		{
+			ID:     testhelper.MkID("repeated"),
+			opts:   []StatOpt{StatDeadband(1), StatDeadband(2)},
+			ExpErr: testhelper.MkExpErr("the deadband has already been set"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestDeadband(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		epsilon       float64
+		vals          []float64
+		expCount      int
+		expSum        float64
+		expSuppressed int
+	}{
+		{
+			ID:            testhelper.MkID("duplicates"),
+			vals:          []float64{1, 1, 1, 2, 2, 1, 1},
+			expCount:      3,
+			expSum:        4,
+			expSuppressed: 4,
+		},
+		{
+			ID:            testhelper.MkID("within epsilon"),
+			epsilon:       0.5,
+			vals:          []float64{10, 10.2, 9.6, 11, 10.6, 10.4},
+			expCount:      3,
+			expSum:        31.4,
+			expSuppressed: 3,
+		},
+		{
+			ID:            testhelper.MkID("slow drift"),
+			epsilon:       1,
+			vals:          []float64{0, 0.4, 0.8, 1.2, 1.6, 2.0, 2.4},
+			expCount:      3,
+			expSum:        3.6,
+			expSuppressed: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", StatDeadband(tc.epsilon))
+		s.AddVals(tc.vals...)
+		testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), tc.expCount)
+		testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 1e-9)
+		testhelper.DiffInt(t, tc.IDStr(), "suppressed",
+			s.Suppressed(), tc.expSuppressed)
+	}
+
+	s := NewStatOrPanic("units", StatDeadband(0.25))
+	eps, ok := s.Deadband()
+	testhelper.DiffFloat(t, "Deadband", "epsilon", eps, 0.25, 0)
+	testhelper.DiffBool(t, "Deadband", "ok", ok, true)
+	s.Add(1, 1, 1)
+	s.Reset()
+	testhelper.DiffInt(t, "after Reset", "suppressed", s.Suppressed(), 0)
+	s.Add(1)
+	testhelper.DiffInt(t, "after Reset", "count", s.Count(), 1)
+
+	other := NewStatOrPanic("units", StatDeadband(0.25))
+	other.Add(5, 5)
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("unexpected error merging: %v", err)
+	}
+	testhelper.DiffInt(t, "after Merge", "suppressed", s.Suppressed(), 1)
+
+	_, ok = NewStatOrPanic("units").Deadband()
+	testhelper.DiffBool(t, "no Deadband", "ok", ok, false)
+}
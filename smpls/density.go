@@ -0,0 +1,138 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+const (
+	// kdeReach is the number of bandwidths beyond the smallest and largest
+	// values over which the density is estimated
+	kdeReach = 3.0
+	// minDensityPoints is the smallest number of points at which the
+	// density can be estimated
+	minDensityPoints = 2
+)
+
+// DensityPoint records the estimated density of the values at X
+type DensityPoint struct {
+	X       float64
+	Density float64
+}
+
+// densitySample returns the values from which the density is estimated:
+// all the values if they are still held, otherwise the random sample of the
+// values (see StatReservoirSize). The values are sorted. It returns nil if
+// there are no such values.
+func (s Stat) densitySample() []float64 {
+	vals := s.retained()
+	if vals == nil && s.reservoir != nil {
+		vals = s.reservoir.vals
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	vals = slices.Clone(vals)
+	slices.Sort(vals)
+	return vals
+}
+
+// kdeBandwidth returns the kernel bandwidth for the sorted values using
+// Silverman's rule of thumb. If the values are all the same a bandwidth
+// proportional to their size is used.
+func kdeBandwidth(sorted []float64) float64 {
+	n := float64(len(sorted))
+	var sum, sumSq float64
+	for _, v := range sorted {
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / n
+	sd := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+	iqr := sortedPercentile(sorted, 75) - sortedPercentile(sorted, 25)
+
+	spread := sd
+	if iqr > 0 {
+		spread = math.Min(sd, iqr/1.34)
+	}
+	h := 0.9 * spread * math.Pow(n, -0.2)
+	if !(h > 0) {
+		h = math.Max(math.Abs(mean), 1) / 100
+	}
+	return h
+}
+
+// kde returns the Gaussian kernel density estimate of the sorted values at
+// x with the given bandwidth
+func kde(sorted []float64, h, x float64) float64 {
+	norm := 1 / (float64(len(sorted)) * h * math.Sqrt(2*math.Pi))
+	var d float64
+	for _, v := range sorted {
+		z := (x - v) / h
+		d += math.Exp(-z * z / 2)
+	}
+	return d * norm
+}
+
+// Density returns a kernel density estimate of the distribution of the
+// values at the given number of evenly spaced points. This is a smoothed
+// version of the histogram which avoids the jagged and misleading shapes
+// that a histogram of only a few values can have. The density is
+// estimated, with a Gaussian kernel whose bandwidth is chosen by
+// Silverman's rule of thumb, from all the values while they are still
+// held or else from the random sample of the values (see
+// StatReservoirSize). The points cover the range of the values and a
+// little beyond. It returns nil if there are no values to estimate the
+// density from or if fewer than 2 points are asked for.
+func (s Stat) Density(points int) []DensityPoint {
+	sorted := s.densitySample()
+	if sorted == nil || points < minDensityPoints {
+		return nil
+	}
+	h := kdeBandwidth(sorted)
+
+	lo := sorted[0] - kdeReach*h
+	step := (sorted[len(sorted)-1] + kdeReach*h - lo) / float64(points-1)
+	dps := make([]DensityPoint, points)
+	for i := range dps {
+		x := lo + float64(i)*step
+		dps[i] = DensityPoint{X: x, Density: kde(sorted, h, x)}
+	}
+	return dps
+}
+
+// densityStr returns a string showing the kernel density estimate of the
+// values (see Density), with a line for each of as many points as there
+// are histogram buckets. Each line shows the point, the density there and
+// a bar scaled so that the highest density has a bar of the maximum width.
+func (s Stat) densityStr(ho HistOpts) string {
+	points := len(s.hist)
+	if points < minDensityPoints {
+		points = dfltHistBucketCount
+	}
+	dps := s.Density(points)
+	if dps == nil {
+		return ""
+	}
+
+	maxDensity := 0.0
+	for _, dp := range dps {
+		maxDensity = math.Max(maxDensity, dp.Density)
+	}
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		dps[0].X, dps[1].X-dps[0].X, dps[len(dps)-1].X)
+	lineFmt := fmt.Sprintf("%%%d.%df: %%9.3g %%s\n", width, precision)
+	barScale := float64(ho.maxBarWidth()) / maxDensity
+
+	str := "units: " + s.units + "\n"
+	for _, dp := range dps {
+		str += fmt.Sprintf(lineFmt, dp.X, dp.Density,
+			strings.Repeat(string(ho.barRune()),
+				int(math.Round(dp.Density*barScale))))
+	}
+	return str
+}
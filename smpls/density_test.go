@@ -0,0 +1,63 @@
+package smpls
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestDensity(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistBucketCount(5))
+	testhelper.DiffInt(t, "empty", "points", len(s.Density(10)), 0)
+	testhelper.DiffString(t, "empty", "smoothed",
+		s.HistWithOpts(HistOpts{Smooth: true}), "")
+
+	s.Add(1, 2, 2, 3, 3, 3, 4, 4, 5)
+	testhelper.DiffInt(t, "too few points", "points", len(s.Density(1)), 0)
+
+	dps := s.Density(101)
+	testhelper.DiffInt(t, "values", "points", len(dps), 101)
+	if dps[0].X >= 1 || dps[100].X <= 5 {
+		t.Errorf("the points should extend beyond the values: %g to %g",
+			dps[0].X, dps[100].X)
+	}
+
+	// the density should integrate to about 1 and peak at the mode
+	area, peak := 0.0, dps[0]
+	for i := 1; i < len(dps); i++ {
+		area += (dps[i].X - dps[i-1].X) *
+			(dps[i].Density + dps[i-1].Density) / 2
+		if dps[i].Density > peak.Density {
+			peak = dps[i]
+		}
+	}
+	testhelper.DiffFloat(t, "values", "area", area, 1, 0.01)
+	testhelper.DiffFloat(t, "values", "peak", peak.X, 3, 0.1)
+
+	smoothed := s.HistWithOpts(HistOpts{Smooth: true, MaxBarWidth: 10})
+	lines := strings.Split(strings.TrimSuffix(smoothed, "\n"), "\n")
+	testhelper.DiffString(t, "values", "units line", lines[0], "units: ms")
+	testhelper.DiffInt(t, "values", "lines", len(lines), 6)
+	testhelper.DiffBool(t, "values", "the middle line has the longest bar",
+		strings.HasSuffix(lines[3], strings.Repeat("*", 10)), true)
+}
+
+func TestDensitySample(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatReservoirSize(100))
+	addSeq(s, 1, 1, 50)
+	testhelper.DiffInt(t, "reservoir", "points", len(s.Density(5)), 5)
+
+	noSample := NewStatOrPanic("ms", StatCacheSize(10))
+	addSeq(noSample, 1, 1, 50)
+	testhelper.DiffInt(t, "no sample", "points", len(noSample.Density(5)), 0)
+
+	same := NewStatOrPanic("ms")
+	same.Add(2, 2, 2)
+	for _, dp := range same.Density(5) {
+		if math.IsNaN(dp.Density) || math.IsInf(dp.Density, 0) {
+			t.Errorf("bad density for equal values: %g", dp.Density)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package smpls
+
+import (
+	"fmt"
+	"time"
+)
+
+// DerivStat takes successive readings of a monotonically increasing
+// counter, such as the number of requests served or bytes sent, and
+// collects statistics on the rate at which the counter grew between each
+// reading and the next. This gives the distribution of the rate directly
+// from the raw counter readings.
+//
+// If a reading is less than the one before it the counter is taken to have
+// been reset (for instance, because the process keeping it was restarted)
+// and to have counted up from zero since then. Readings that are not later
+// than the one before are ignored.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type DerivStat struct {
+	rates *Stat
+	per   time.Duration
+
+	hasPrev   bool
+	prevTime  time.Time
+	prevVal   float64
+	resets    int
+	discarded int
+}
+
+// perUnits returns the name of the time unit used in the units of a rate
+func perUnits(per time.Duration) string {
+	switch per {
+	case time.Nanosecond:
+		return "ns"
+	case time.Microsecond:
+		return "us"
+	case time.Millisecond:
+		return "ms"
+	case time.Second:
+		return "s"
+	case time.Minute:
+		return "min"
+	case time.Hour:
+		return "h"
+	}
+	return per.String()
+}
+
+// NewDerivStat creates a new DerivStat. The units are those of the counter
+// and the rates are given as the change in the counter per the given
+// duration; the units of the rates are the counter units followed by "/"
+// and the duration, as in "requests/s". The options are used when creating
+// the Stat of the rates.
+func NewDerivStat(
+	units string, per time.Duration, opts ...StatOpt,
+) (*DerivStat, error) {
+	if per <= 0 {
+		return nil, invalidValue(
+			"Invalid rate duration (%s) - it must be > 0", per)
+	}
+	rates, err := NewStat(units+"/"+perUnits(per), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DerivStat{rates: rates, per: per}, nil
+}
+
+// NewDerivStatOrPanic creates a new DerivStat and will panic if any errors
+// are detected
+func NewDerivStatOrPanic(
+	units string, per time.Duration, opts ...StatOpt,
+) *DerivStat {
+	ds, err := NewDerivStat(units, per, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ds
+}
+
+// AddReading adds a reading of the counter taken at the given time. The
+// first reading only sets the starting point; each later reading adds the
+// rate since the reading before it. A reading which is not later than the
+// previous one is discarded.
+func (ds *DerivStat) AddReading(t time.Time, v float64) {
+	if !ds.hasPrev {
+		ds.hasPrev = true
+		ds.prevTime, ds.prevVal = t, v
+		return
+	}
+	if !t.After(ds.prevTime) {
+		ds.discarded++
+		return
+	}
+
+	delta := v - ds.prevVal
+	if v < ds.prevVal {
+		ds.resets++
+		delta = v
+	}
+	elapsed := t.Sub(ds.prevTime)
+	ds.rates.Add(delta * float64(ds.per) / float64(elapsed))
+	ds.prevTime, ds.prevVal = t, v
+}
+
+// Reset discards all the readings and rates
+func (ds *DerivStat) Reset() {
+	ds.rates.Reset()
+	ds.hasPrev = false
+	ds.prevTime = time.Time{}
+	ds.prevVal = 0
+	ds.resets = 0
+	ds.discarded = 0
+}
+
+// Rates returns the Stat recording the rates between readings
+func (ds DerivStat) Rates() *Stat {
+	return ds.rates
+}
+
+// Per returns the duration the rates are given per
+func (ds DerivStat) Per() time.Duration {
+	return ds.per
+}
+
+// Count returns the number of rates recorded. This is one less than the
+// number of readings used.
+func (ds DerivStat) Count() int {
+	return ds.rates.Count()
+}
+
+// Resets returns the number of times the counter was seen to go backwards
+// and so was taken to have been reset
+func (ds DerivStat) Resets() int {
+	return ds.resets
+}
+
+// Discarded returns the number of readings that were discarded because
+// they were not later than the reading before
+func (ds DerivStat) Discarded() int {
+	return ds.discarded
+}
+
+// Last returns the time and value of the most recent reading used. The
+// final result is false if no readings have been added.
+func (ds DerivStat) Last() (time.Time, float64, bool) {
+	return ds.prevTime, ds.prevVal, ds.hasPrev
+}
+
+// String returns the statistics of the rates followed by the number of
+// counter resets and discarded readings, if any
+func (ds DerivStat) String() string {
+	str := ds.rates.String()
+	if ds.resets > 0 {
+		str += fmt.Sprintf(", counter resets: %d", ds.resets)
+	}
+	if ds.discarded > 0 {
+		str += fmt.Sprintf(", discarded readings: %d", ds.discarded)
+	}
+	return str
+}
@@ -0,0 +1,73 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestDerivStat(t *testing.T) {
+	ds := NewDerivStatOrPanic("requests", time.Second, StatCacheSize(10))
+	testhelper.DiffString(t, "DerivStat", "units",
+		ds.Rates().Units(), "requests/s")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	readings := []struct {
+		offset time.Duration
+		val    float64
+	}{
+		{0, 100},
+		{2 * time.Second, 120},        // 10/s
+		{3 * time.Second, 150},        // 30/s
+		{3 * time.Second, 160},        // discarded
+		{7 * time.Second, 170},        // 5/s
+		{8 * time.Second, 20},         // reset: 20/s
+		{8500 * time.Millisecond, 25}, // 10/s
+	}
+	for _, r := range readings {
+		ds.AddReading(start.Add(r.offset), r.val)
+	}
+
+	testhelper.DiffInt(t, "DerivStat", "count", ds.Count(), 5)
+	testhelper.DiffInt(t, "DerivStat", "resets", ds.Resets(), 1)
+	testhelper.DiffInt(t, "DerivStat", "discarded", ds.Discarded(), 1)
+	testhelper.DiffFloat(t, "DerivStat", "min", ds.Rates().Min(), 5, 1e-12)
+	testhelper.DiffFloat(t, "DerivStat", "max", ds.Rates().Max(), 30, 1e-12)
+	testhelper.DiffFloat(t, "DerivStat", "mean", ds.Rates().Mean(),
+		(10+30+5+20+10)/5.0, 1e-12)
+
+	lastT, lastV, ok := ds.Last()
+	testhelper.DiffBool(t, "DerivStat", "last ok", ok, true)
+	testhelper.DiffBool(t, "DerivStat", "last time",
+		lastT.Equal(start.Add(8500*time.Millisecond)), true)
+	testhelper.DiffFloat(t, "DerivStat", "last val", lastV, 25, 0)
+
+	testhelper.DiffString(t, "DerivStat", "string", ds.String(),
+		ds.Rates().String()+", counter resets: 1, discarded readings: 1")
+
+	ds.Reset()
+	testhelper.DiffInt(t, "Reset", "count", ds.Count(), 0)
+	testhelper.DiffInt(t, "Reset", "resets", ds.Resets(), 0)
+	_, _, ok = ds.Last()
+	testhelper.DiffBool(t, "Reset", "last ok", ok, false)
+}
+
+func TestDerivStatPer(t *testing.T) {
+	ds := NewDerivStatOrPanic("bytes", time.Minute)
+	testhelper.DiffString(t, "per minute", "units",
+		ds.Rates().Units(), "bytes/min")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ds.AddReading(start, 0)
+	ds.AddReading(start.Add(30*time.Second), 50)
+	testhelper.DiffFloat(t, "per minute", "rate", ds.Rates().Mean(), 100, 1e-12)
+
+	ds = NewDerivStatOrPanic("bytes", 5*time.Second)
+	testhelper.DiffString(t, "per 5s", "units", ds.Rates().Units(), "bytes/5s")
+
+	_, err := NewDerivStat("bytes", 0)
+	testhelper.DiffBool(t, "zero duration", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+}
@@ -0,0 +1,153 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+// dfltDiffConfidence is the confidence level used when reporting the
+// confidence interval of the mean difference
+const dfltDiffConfidence = 0.95
+
+// DiffStat records paired values, such as the timings of the same
+// benchmark before and after a change, and collects statistics on the
+// differences (after - before) and on the ratios (after / before) of each
+// pair. Because each pair is compared with itself the variation between
+// pairs does not hide the effect of the change, as it would if the before
+// and after values were collected separately and compared with Compare.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type DiffStat struct {
+	diffs  *Stat
+	ratios *Stat
+
+	noRatio int
+}
+
+// NewDiffStat creates a new DiffStat. The units are those of the values in
+// each pair. The options are used when creating the Stats of the
+// differences and of the ratios.
+func NewDiffStat(units string, opts ...StatOpt) (*DiffStat, error) {
+	diffs, err := NewStat(units, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ratios, err := NewStat("ratio", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiffStat{diffs: diffs, ratios: ratios}, nil
+}
+
+// NewDiffStatOrPanic creates a new DiffStat and will panic if any errors
+// are detected
+func NewDiffStatOrPanic(units string, opts ...StatOpt) *DiffStat {
+	ds, err := NewDiffStat(units, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ds
+}
+
+// AddPair adds a pair of values. The difference (after - before) is always
+// recorded but the ratio (after / before) is only recorded if the before
+// value is not zero.
+func (ds *DiffStat) AddPair(before, after float64) {
+	ds.diffs.Add(after - before)
+	if before == 0 {
+		ds.noRatio++
+		return
+	}
+	ds.ratios.Add(after / before)
+}
+
+// Reset discards all the pairs
+func (ds *DiffStat) Reset() {
+	ds.diffs.Reset()
+	ds.ratios.Reset()
+	ds.noRatio = 0
+}
+
+// Count returns the number of pairs added
+func (ds DiffStat) Count() int {
+	return ds.diffs.Count()
+}
+
+// NoRatioCount returns the number of pairs whose ratio was not recorded
+// because the before value was zero
+func (ds DiffStat) NoRatioCount() int {
+	return ds.noRatio
+}
+
+// Diffs returns the Stat recording the differences between the values of
+// each pair
+func (ds DiffStat) Diffs() *Stat {
+	return ds.diffs
+}
+
+// Ratios returns the Stat recording the ratios of the values of each pair
+func (ds DiffStat) Ratios() *Stat {
+	return ds.ratios
+}
+
+// MeanDiff returns the mean of the differences between the values of each
+// pair or 0.0 if no pairs have been added
+func (ds DiffStat) MeanDiff() float64 {
+	return ds.diffs.Mean()
+}
+
+// MeanRatio returns the mean of the ratios of the values of each pair or
+// 0.0 if no ratios have been recorded
+func (ds DiffStat) MeanRatio() float64 {
+	return ds.ratios.Mean()
+}
+
+// MeanDiffCI returns the confidence interval of the mean difference at the
+// given confidence level, which must be between 0 and 1 (0.95 is a
+// common choice). It uses Student's t distribution and so assumes that the
+// differences are roughly normally distributed or that there are many
+// pairs. The interval is NaN if fewer than two pairs have been added or if
+// the level is not valid.
+func (ds DiffStat) MeanDiffCI(level float64) (lo, hi float64) {
+	n := ds.diffs.count
+	if n < 2 || !(level > 0 && level < 1) {
+		return math.NaN(), math.NaN()
+	}
+
+	t := studentTQuantile((1+level)/2, float64(n-1))
+	halfWidth := t * math.Sqrt(ds.diffs.sampleVariance()/float64(n))
+	mean := ds.diffs.Mean()
+	return mean - halfWidth, mean + halfWidth
+}
+
+// Significant returns true if the confidence interval of the mean
+// difference, at the given level, does not include zero
+func (ds DiffStat) Significant(level float64) bool {
+	lo, hi := ds.MeanDiffCI(level)
+	return lo > 0 || hi < 0
+}
+
+// String returns a report of the mean difference, with its 95% confidence
+// interval, and of the mean ratio
+func (ds DiffStat) String() string {
+	str := fmt.Sprintf("pairs: %d", ds.Count())
+	if ds.Count() == 0 {
+		return str
+	}
+
+	units := ds.diffs.units
+	str += fmt.Sprintf(", mean difference: %.3g %s", ds.MeanDiff(), units)
+	if lo, hi := ds.MeanDiffCI(dfltDiffConfidence); !math.IsNaN(lo) {
+		str += fmt.Sprintf(" (%g%% CI: %.3g to %.3g)",
+			dfltDiffConfidence*100, lo, hi)
+	}
+	if ds.ratios.Count() > 0 {
+		str += fmt.Sprintf(", mean ratio: %.3g", ds.MeanRatio())
+	}
+	if ds.Significant(dfltDiffConfidence) {
+		str += " - the difference is probably significant"
+	}
+	return str
+}
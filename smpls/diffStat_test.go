@@ -0,0 +1,60 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestDiffStat(t *testing.T) {
+	ds := NewDiffStatOrPanic("ms")
+	testhelper.DiffString(t, "empty", "string", ds.String(), "pairs: 0")
+	lo, hi := ds.MeanDiffCI(0.95)
+	testhelper.DiffBool(t, "empty", "CI is NaN",
+		math.IsNaN(lo) && math.IsNaN(hi), true)
+
+	for i, before := range []float64{10, 20, 30, 40, 50} {
+		ds.AddPair(before, before+float64(i+1))
+	}
+	ds.AddPair(0, 3)
+
+	testhelper.DiffInt(t, "DiffStat", "count", ds.Count(), 6)
+	testhelper.DiffInt(t, "DiffStat", "no ratio", ds.NoRatioCount(), 1)
+	testhelper.DiffInt(t, "DiffStat", "ratios", ds.Ratios().Count(), 5)
+	testhelper.DiffFloat(t, "DiffStat", "mean diff", ds.MeanDiff(), 3, 1e-12)
+	testhelper.DiffFloat(t, "DiffStat", "mean ratio", ds.MeanRatio(),
+		(11.0/10+22.0/20+33.0/30+44.0/40+55.0/50)/5, 1e-12)
+
+	// the differences are 1, 2, 3, 4, 5 and 3: the sample SD is
+	// sqrt(2) and the t value for 5 degrees of freedom is 2.5706
+	halfWidth := 2.5706 * math.Sqrt(2.0/6.0)
+	lo, hi = ds.MeanDiffCI(0.95)
+	testhelper.DiffFloat(t, "DiffStat", "CI lo", lo, 3-halfWidth, 0.001)
+	testhelper.DiffFloat(t, "DiffStat", "CI hi", hi, 3+halfWidth, 0.001)
+	testhelper.DiffBool(t, "DiffStat", "significant",
+		ds.Significant(0.95), true)
+	lo, _ = ds.MeanDiffCI(1)
+	testhelper.DiffBool(t, "DiffStat", "bad level is NaN", math.IsNaN(lo), true)
+
+	testhelper.DiffString(t, "DiffStat", "string", ds.String(),
+		"pairs: 6, mean difference: 3 ms (95% CI: 1.52 to 4.48),"+
+			" mean ratio: 1.1 - the difference is probably significant")
+
+	ds.Reset()
+	testhelper.DiffInt(t, "Reset", "count", ds.Count(), 0)
+	testhelper.DiffInt(t, "Reset", "no ratio", ds.NoRatioCount(), 0)
+}
+
+func TestDiffStatNotSignificant(t *testing.T) {
+	ds := NewDiffStatOrPanic("ms")
+	for i := 0; i < 10; i++ {
+		d := 1.0
+		if i%2 == 0 {
+			d = -1.0
+		}
+		ds.AddPair(100, 100+d)
+	}
+	testhelper.DiffBool(t, "balanced", "significant",
+		ds.Significant(0.95), false)
+}
@@ -0,0 +1,76 @@
+package smpls
+
+import (
+	"math"
+)
+
+// StatEmptyNaN returns a function that will make the Vals method return
+// NaN rather than 0.0 for each of the calculated values if no values have
+// been added. This makes an empty Stat stand out rather than appearing to
+// have values all of zero. It also applies to the values shown by the
+// String method but not to a Snapshot, whose values are still 0.0 so that
+// it can be encoded as JSON.
+func StatEmptyNaN() StatOpt {
+	return func(s *Stat) error {
+		s.emptyNaN = true
+		return nil
+	}
+}
+
+// EmptyNaN returns true if the Vals method returns NaN values for an empty
+// Stat (see StatEmptyNaN)
+func (s Stat) EmptyNaN() bool {
+	return s.emptyNaN
+}
+
+// MinOK returns the min of the collected values and true or 0.0 and false
+// if no values have been added
+func (s Stat) MinOK() (float64, bool) {
+	return s.Min(), s.count > 0
+}
+
+// MeanMinOK returns the mean of the N smallest collected values and true
+// or 0.0 and false if no values have been added
+func (s Stat) MeanMinOK() (float64, bool) {
+	return s.MeanMin(), s.count > 0
+}
+
+// MeanOK returns the mean of the collected values and true or 0.0 and
+// false if no values have been added
+func (s Stat) MeanOK() (float64, bool) {
+	return s.Mean(), s.count > 0
+}
+
+// MaxOK returns the max of the collected values and true or 0.0 and false
+// if no values have been added
+func (s Stat) MaxOK() (float64, bool) {
+	return s.Max(), s.count > 0
+}
+
+// MeanMaxOK returns the mean of the N largest collected values and true or
+// 0.0 and false if no values have been added
+func (s Stat) MeanMaxOK() (float64, bool) {
+	return s.MeanMax(), s.count > 0
+}
+
+// StdDevOK returns the standard deviation of the collected values (see
+// StdDev) and true or 0.0 and false if fewer than 2 values have been added
+func (s Stat) StdDevOK() (float64, bool) {
+	return s.StdDev(), s.count > 1
+}
+
+// PercentileOK returns the value below which the given percentage of the
+// values fall (see Percentile) and true or 0.0 and false if no values have
+// been added
+func (s Stat) PercentileOK(p float64) (float64, bool) {
+	return s.Percentile(p), s.count > 0
+}
+
+// emptyVals returns the values returned by Vals for an empty Stat
+func (s Stat) emptyVals() (min, meanMin, avg, sd, max, meanMax float64) {
+	if s.emptyNaN {
+		nan := math.NaN()
+		return nan, nan, nan, nan, nan, nan
+	}
+	return
+}
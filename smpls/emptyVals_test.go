@@ -0,0 +1,78 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestOKAccessors(t *testing.T) {
+	s := NewStatOrPanic("ms")
+
+	accessors := []struct {
+		name   string
+		f      func(Stat) (float64, bool)
+		minCnt int
+	}{
+		{"MinOK", Stat.MinOK, 1},
+		{"MeanMinOK", Stat.MeanMinOK, 1},
+		{"MeanOK", Stat.MeanOK, 1},
+		{"MaxOK", Stat.MaxOK, 1},
+		{"MeanMaxOK", Stat.MeanMaxOK, 1},
+		{"StdDevOK", Stat.StdDevOK, 2},
+		{
+			"PercentileOK",
+			func(s Stat) (float64, bool) { return s.PercentileOK(50) },
+			1,
+		},
+	}
+
+	for _, a := range accessors {
+		v, ok := a.f(*s)
+		testhelper.DiffBool(t, "empty: "+a.name, "ok", ok, false)
+		testhelper.DiffFloat(t, "empty: "+a.name, "value", v, 0, 0)
+	}
+
+	s.Add(3)
+	for _, a := range accessors {
+		_, ok := a.f(*s)
+		testhelper.DiffBool(t, "one value: "+a.name, "ok", ok, a.minCnt <= 1)
+	}
+
+	s.Add(5)
+	for _, a := range accessors {
+		_, ok := a.f(*s)
+		testhelper.DiffBool(t, "two values: "+a.name, "ok", ok, true)
+	}
+	v, _ := s.MeanOK()
+	testhelper.DiffFloat(t, "two values", "mean", v, 4, 0)
+}
+
+func TestStatEmptyNaN(t *testing.T) {
+	s := NewStatOrPanic("ms", StatEmptyNaN())
+	testhelper.DiffBool(t, "empty", "EmptyNaN", s.EmptyNaN(), true)
+
+	min, meanMin, avg, sd, max, meanMax, count := s.Vals()
+	testhelper.DiffInt(t, "empty", "count", count, 0)
+	for name, v := range map[string]float64{
+		"min": min, "meanMin": meanMin, "avg": avg,
+		"sd": sd, "max": max, "meanMax": meanMax,
+	} {
+		testhelper.DiffBool(t, "empty", name+" is NaN", math.IsNaN(v), true)
+	}
+
+	snap := s.Snapshot()
+	testhelper.DiffFloat(t, "empty", "snapshot mean", snap.Mean, 0, 0)
+
+	s.Add(2, 4)
+	min, _, avg, _, max, _, count = s.Vals()
+	testhelper.DiffInt(t, "values added", "count", count, 2)
+	testhelper.DiffFloat(t, "values added", "min", min, 2, 0)
+	testhelper.DiffFloat(t, "values added", "avg", avg, 3, 0)
+	testhelper.DiffFloat(t, "values added", "max", max, 4, 0)
+
+	plain := NewStatOrPanic("ms")
+	min, _, _, _, _, _, _ = plain.Vals()
+	testhelper.DiffFloat(t, "no option", "min", min, 0, 0)
+}
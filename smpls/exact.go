@@ -0,0 +1,120 @@
+package smpls
+
+import (
+	"math"
+)
+
+const minExactLimit = minCacheSize - 1
+
+// StatExact returns a function that will make the Stat keep all the
+// values until more than the given number have been added (or more than
+// the number of minimum and maximum values kept, if that is larger) and,
+// while it does, calculate every statistic exactly from them. The mean and
+// standard deviation are calculated in two passes over the values with
+// compensated summation rather than from the running totals and the
+// percentiles are taken from the sorted values. Once the limit is passed
+// the values are discarded and the Stat switches to the streaming
+// approximations, as usual. IsExact reports whether the statistics are
+// still exact.
+//
+// This is useful when the number of values is often small, as with
+// benchmark runs, but might occasionally be large. The Stat must keep a
+// histogram and it must not be given a cache size (see StatCacheSize) as
+// the limit sets the size of the cache.
+func StatExact(limit int) StatOpt {
+	return func(s *Stat) error {
+		if s.exactLim > 0 {
+			return repeatedOption("the Stat is already exact")
+		}
+		if limit < minExactLimit {
+			return invalidValue("Invalid exact limit (%d) - it must be >= %d",
+				limit, minExactLimit)
+		}
+		// the cache is laid out as a histogram when it is full so it must
+		// have room for one more value than the limit
+		if err := StatCacheSize(limit + 1)(s); err != nil {
+			return err
+		}
+
+		s.exactLim = limit
+		return nil
+	}
+}
+
+// StatBesselCorrection returns a function that will make the Stat report
+// the sample standard deviation, which divides by n-1 rather than by n,
+// from StdDev and Vals. This is the usual estimate of the standard
+// deviation of the population from which the values are drawn.
+func StatBesselCorrection() StatOpt {
+	return func(s *Stat) error {
+		s.bessel = true
+		return nil
+	}
+}
+
+// ExactLimit returns the number of values up to which the statistics are
+// calculated exactly or 0 if the Stat is not exact (see StatExact)
+func (s Stat) ExactLimit() int {
+	return s.exactLim
+}
+
+// exactVals returns all the values recorded by the Stat if it is exact
+// (see StatExact) and still holds them, otherwise nil
+func (s Stat) exactVals() []float64 {
+	if s.exactLim == 0 {
+		return nil
+	}
+	vals := s.retained()
+	if len(vals) != s.count {
+		return nil
+	}
+	return vals
+}
+
+// IsExact returns true if the statistics are calculated exactly from all
+// the values (see StatExact)
+func (s Stat) IsExact() bool {
+	return s.exactVals() != nil
+}
+
+// meanSD returns the mean and standard deviation of the values, exactly if
+// the Stat is exact (see StatExact). The standard deviation is 0.0 if
+// fewer than 2 values have been added and is the sample standard deviation
+// if the Stat uses the Bessel correction (see StatBesselCorrection).
+func (s Stat) meanSD() (avg, sd float64) {
+	avg, variance := s.meanVar()
+	if s.count < 2 {
+		return avg, 0.0
+	}
+	if s.bessel {
+		variance *= float64(s.count) / float64(s.count-1)
+	}
+	return avg, math.Sqrt(variance)
+}
+
+// meanVar returns the mean and population variance of the values, exactly
+// if the Stat is exact (see StatExact)
+func (s Stat) meanVar() (avg, variance float64) {
+	if s.count == 0 {
+		return 0.0, 0.0
+	}
+
+	n := float64(s.count)
+	if vals := s.exactVals(); vals != nil {
+		var sum, c float64
+		for _, v := range vals {
+			compensatedAdd(&sum, &c, v)
+		}
+		avg = (sum + c) / n
+
+		var sumSq, cSq float64
+		for _, v := range vals {
+			compensatedAdd(&sumSq, &cSq, (v-avg)*(v-avg))
+		}
+		variance = (sumSq + cSq) / n
+	} else {
+		avg = s.fullSum() / n
+		variance = (s.fullSumSq() / n) - (avg * avg)
+	}
+	return avg, max(variance, 0)
+}
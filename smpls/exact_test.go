@@ -0,0 +1,121 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatExact(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatExact(100)},
+		},
+		{
+			ID:   testhelper.MkID("too small"),
+			opts: []StatOpt{StatExact(0)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid exact limit (0) - it must be >= 1"),
+		},
+		{
+			ID:     testhelper.MkID("repeated"),
+			opts:   []StatOpt{StatExact(10), StatExact(20)},
+			ExpErr: testhelper.MkExpErr("the Stat is already exact"),
+		},
+		{
+			ID:   testhelper.MkID("with a cache size"),
+			opts: []StatOpt{StatCacheSize(10), StatExact(20)},
+			ExpErr: testhelper.MkExpErr(
+				"the cache of values has already been created"),
+		},
+		{
+			ID:     testhelper.MkID("no hist"),
+			opts:   []StatOpt{StatNoHist(), StatExact(20)},
+			ExpErr: testhelper.MkExpErr("the Stat has no cache"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestExact(t *testing.T) {
+	const offset = 1e9
+	vals := []float64{offset + 4, offset + 7, offset + 13, offset + 16}
+
+	s := NewStatOrPanic("units", StatExact(30))
+	testhelper.DiffInt(t, "exact", "limit", s.ExactLimit(), 30)
+	s.AddVals(vals...)
+	testhelper.DiffBool(t, "exact", "IsExact", s.IsExact(), true)
+	testhelper.DiffFloat(t, "exact", "mean", s.Mean(), offset+10, 0)
+	testhelper.DiffFloat(t, "exact", "std dev", s.StdDev(), math.Sqrt(22.5), 0)
+	testhelper.DiffFloat(t, "exact", "median", s.Percentile(50), offset+10, 0)
+
+	fork := s.Fork()
+	testhelper.DiffInt(t, "Fork", "limit", fork.ExactLimit(), 30)
+
+	addSeq(s, offset, 1, 26)
+	testhelper.DiffBool(t, "at the limit", "IsExact", s.IsExact(), true)
+	s.Add(offset)
+	testhelper.DiffBool(t, "beyond the limit", "IsExact", s.IsExact(), false)
+	testhelper.DiffInt(t, "beyond the limit", "count", s.Count(), 31)
+
+	s.Reset()
+	testhelper.DiffBool(t, "after Reset", "IsExact", s.IsExact(), true)
+
+	s = NewStatOrPanic("units")
+	s.AddVals(vals...)
+	testhelper.DiffBool(t, "not exact", "IsExact", s.IsExact(), false)
+	testhelper.DiffInt(t, "not exact", "limit", s.ExactLimit(), 0)
+}
+
+func TestBesselCorrection(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts  []StatOpt
+		vals  []float64
+		expSD float64
+	}{
+		{
+			ID:    testhelper.MkID("population"),
+			vals:  []float64{1, 2, 3, 4},
+			expSD: math.Sqrt(1.25),
+		},
+		{
+			ID:    testhelper.MkID("sample"),
+			opts:  []StatOpt{StatBesselCorrection()},
+			vals:  []float64{1, 2, 3, 4},
+			expSD: math.Sqrt(5.0 / 3.0),
+		},
+		{
+			ID:    testhelper.MkID("sample, exact"),
+			opts:  []StatOpt{StatBesselCorrection(), StatExact(10)},
+			vals:  []float64{1, 2, 3, 4},
+			expSD: math.Sqrt(5.0 / 3.0),
+		},
+		{
+			ID:    testhelper.MkID("sample, one value"),
+			opts:  []StatOpt{StatBesselCorrection()},
+			vals:  []float64{1},
+			expSD: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", tc.opts...)
+		s.AddVals(tc.vals...)
+		testhelper.DiffFloat(t, tc.IDStr(), "std dev",
+			s.StdDev(), tc.expSD, 1e-12)
+		_, _, _, sd, _, _, _ := s.Vals()
+		testhelper.DiffFloat(t, tc.IDStr(), "Vals std dev",
+			sd, tc.expSD, 1e-12)
+	}
+}
@@ -0,0 +1,98 @@
+package smpls
+
+import (
+	"math"
+)
+
+// expectedRange records the range of values the Stat expects to be added
+// (see StatExpectedRange)
+type expectedRange struct {
+	lo, hi float64
+}
+
+// StatExpectedRange returns a function that will lay out the histogram to
+// cover the given range as soon as the Stat is created. The values are then
+// added to the histogram from the first one, rather than being held in a
+// cache until there are enough to choose the bucket boundaries, and so the
+// histogram is useful even if only a few values are added. It also saves
+// the memory and time used by the cache. Values outside the range are
+// counted in the underflow or overflow; automatic rebinning (see
+// StatHistAutoRebin) can be used to recover if the range proves to be
+// wrong.
+//
+// The histogram is laid out again from the range when the Stat is Reset.
+// If the histogram is anchored (see StatHistAnchor) or uses nice bucket
+// boundaries (see StatHistNiceBounds) the range is adjusted accordingly
+// and if it uses a log transform (see StatLogTransform) the lower limit
+// must be greater than zero. This cannot be used with a quantile layout
+// (see StatHistQuantileLayout), which needs the values to choose the
+// bucket boundaries, with an exact Stat (see StatExact), with a cache
+// size (see StatCacheSize) or with a starting Snapshot (see
+// StatFromSnapshot).
+func StatExpectedRange(lo, hi float64) StatOpt {
+	return func(s *Stat) error {
+		if s.expRange != nil {
+			return repeatedOption(
+				"the expected range of values has already been set")
+		}
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if math.IsNaN(lo) || math.IsInf(lo, 0) ||
+			math.IsNaN(hi) || math.IsInf(hi, 0) || !(lo < hi) {
+			return invalidValue("Invalid expected range (%g to %g)"+
+				" - the limits must be finite and lo must be < hi", lo, hi)
+		}
+
+		s.expRange = &expectedRange{lo: lo, hi: hi}
+		return nil
+	}
+}
+
+// ExpectedRange returns the range of values the histogram was laid out to
+// cover and true or 0.0, 0.0 and false if no range was given (see
+// StatExpectedRange)
+func (s Stat) ExpectedRange() (lo, hi float64, ok bool) {
+	if s.expRange == nil {
+		return 0.0, 0.0, false
+	}
+	return s.expRange.lo, s.expRange.hi, true
+}
+
+// checkExpectedRange checks that the expected range can be used with the
+// other options given
+func (s Stat) checkExpectedRange() error {
+	if s.expRange == nil {
+		return nil
+	}
+	if s.quantileLayout {
+		return conflictingOptions(
+			"an expected range cannot be used with a quantile layout")
+	}
+	if s.exactLim > 0 {
+		return conflictingOptions(
+			"an expected range cannot be used with an exact Stat")
+	}
+	if s.cache != nil {
+		return conflictingOptions(
+			"an expected range cannot be used with a cache size")
+	}
+	if s.seed != nil && s.seed.Count > 0 {
+		return conflictingOptions(
+			"an expected range cannot be used with a starting Snapshot")
+	}
+	if s.logBase != 0 && !(s.expRange.lo > 0) {
+		return invalidValue("Invalid expected range (%g to %g)"+
+			" - with a log transform lo must be > 0",
+			s.expRange.lo, s.expRange.hi)
+	}
+	return nil
+}
+
+// layoutExpectedRange discards the cache and lays out the histogram to
+// cover the expected range
+func (s *Stat) layoutExpectedRange() {
+	s.cache = nil
+	s.cacheBuf = nil
+	s.setHistLayout(s.histPos(s.expRange.lo), s.histPos(s.expRange.hi))
+}
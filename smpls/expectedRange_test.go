@@ -0,0 +1,92 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatExpectedRange(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistBucketCount(10), StatExpectedRange(0, 100))
+	lo, hi, ok := s.ExpectedRange()
+	testhelper.DiffBool(t, "ExpectedRange", "ok", ok, true)
+	testhelper.DiffFloat(t, "ExpectedRange", "lo", lo, 0, 0)
+	testhelper.DiffFloat(t, "ExpectedRange", "hi", hi, 100, 0)
+	testhelper.DiffInt(t, "new", "cache capacity", cap(s.cache), 0)
+
+	s.Add(5, 15, 15, 95, 150)
+	testhelper.DiffInt(t, "few values", "bucket 0", s.hist[0], 1)
+	testhelper.DiffInt(t, "few values", "bucket 1", s.hist[1], 2)
+	testhelper.DiffInt(t, "few values", "bucket 9", s.hist[9], 1)
+	testhelper.DiffInt(t, "few values", "overflow", s.overflow, 1)
+	testhelper.DiffBool(t, "few values", "histogram shown",
+		s.Hist() != "", true)
+
+	s.Reset()
+	testhelper.DiffFloat(t, "Reset", "bucket start", s.bucketStart, 0, 0)
+	testhelper.DiffBool(t, "Reset", "bucket width set", s.bucketWidth > 0, true)
+	testhelper.DiffInt(t, "Reset", "cache capacity", cap(s.cache), 0)
+	s.Add(55)
+	testhelper.DiffInt(t, "after Reset", "bucket 5", s.hist[5], 1)
+
+	child := s.Fork()
+	child.Add(25)
+	testhelper.DiffInt(t, "fork", "child bucket 2", child.hist[2], 1)
+	testhelper.DiffInt(t, "fork", "parent bucket 2", s.hist[2], 1)
+
+	cfg := s.Config()
+	if cfg.ExpectedRange == nil {
+		t.Error("Config: the expected range is not set")
+	} else {
+		testhelper.DiffFloat(t, "Config", "max", cfg.ExpectedRange.Max, 100, 0)
+	}
+
+	_, _, ok = NewStatOrPanic("ms").ExpectedRange()
+	testhelper.DiffBool(t, "no range", "ok", ok, false)
+}
+
+func TestStatExpectedRangeErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts    []StatOpt
+		expKind error
+	}{
+		{
+			ID:      testhelper.MkID("bad range"),
+			opts:    []StatOpt{StatExpectedRange(5, 5)},
+			expKind: ErrInvalidValue,
+			ExpErr:  testhelper.MkExpErr("Invalid expected range (5 to 5)"),
+		},
+		{
+			ID:      testhelper.MkID("cache size"),
+			opts:    []StatOpt{StatExpectedRange(0, 5), StatCacheSize(10)},
+			expKind: ErrConflictingOptions,
+			ExpErr: testhelper.MkExpErr(
+				"an expected range cannot be used with a cache size"),
+		},
+		{
+			ID:      testhelper.MkID("no histogram"),
+			opts:    []StatOpt{StatExpectedRange(0, 5), StatNoHist()},
+			expKind: ErrConflictingOptions,
+			ExpErr:  testhelper.MkExpErr("the Stat has no histogram"),
+		},
+		{
+			ID: testhelper.MkID("log transform"),
+			opts: []StatOpt{
+				StatLogTransform(10),
+				StatExpectedRange(0, 5),
+			},
+			expKind: ErrInvalidValue,
+			ExpErr:  testhelper.MkExpErr("with a log transform lo must be > 0"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("ms", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffBool(t, tc.IDStr(), "errors.Is",
+			errors.Is(err, tc.expKind), true)
+	}
+}
@@ -0,0 +1,302 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	dfltFmtSigFigs = 3
+	maxFmtSigFigs  = 17
+)
+
+// FmtStyle gives the notation used to show values
+type FmtStyle int
+
+// These are the available formatting styles
+const (
+	// FmtScientific shows values in scientific notation, as in 1.25e+01
+	FmtScientific FmtStyle = iota
+	// FmtFixed shows values in fixed point notation, as in 12.5
+	FmtFixed
+	// FmtGeneral shows values in fixed point notation unless they are very
+	// large or very small in which case scientific notation is used
+	FmtGeneral
+	// FmtPrefixed shows values with an SI unit prefix and the units (see
+	// FmtWithPrefix), as in 12.50 ms. The SigFigs setting is ignored.
+	FmtPrefixed
+)
+
+// StatField identifies one of the values that can be shown by String
+type StatField int
+
+// These are the fields that can be shown
+const (
+	FieldCount StatField = iota
+	FieldMin
+	FieldMeanMin
+	FieldMean
+	FieldMax
+	FieldMeanMax
+	FieldStdDev
+	FieldMedian
+	FieldP90
+	FieldP99
+	FieldSampleStdDev
+	fieldCount // the number of fields, it must be last
+)
+
+// dfltFields are the fields shown if none are given
+var dfltFields = []StatField{
+	FieldCount,
+	FieldMin, FieldMeanMin,
+	FieldMean,
+	FieldMax, FieldMeanMax,
+	FieldStdDev,
+}
+
+// String returns the label used for the field
+func (f StatField) String() string {
+	switch f {
+	case FieldCount:
+		return "observations"
+	case FieldMin:
+		return "min"
+	case FieldMeanMin:
+		return "mean min"
+	case FieldMean:
+		return "avg"
+	case FieldMax:
+		return "max"
+	case FieldMeanMax:
+		return "mean max"
+	case FieldStdDev:
+		return "SD"
+	case FieldMedian:
+		return "median"
+	case FieldP90:
+		return "p90"
+	case FieldP99:
+		return "p99"
+	case FieldSampleStdDev:
+		return "sample SD"
+	}
+	return fmt.Sprintf("StatField(%d)", int(f))
+}
+
+// FmtOpts controls how the values of a Stat are shown by StringWithOpts
+// and, if given with the StatFormat option, by String. The zero value
+// shows the default fields to three significant figures in scientific
+// notation.
+type FmtOpts struct {
+	// SigFigs is the number of significant figures to show. If it is not
+	// set then 3 are shown.
+	SigFigs int
+	// Style gives the notation used to show the values.
+	Style FmtStyle
+	// Fields gives the values to show, in the order they are to be shown.
+	// If it is not set then the count, the min, mean min, avg, max, mean
+	// max and SD are shown.
+	Fields []StatField
+	// Number gives the separators used between the groups of digits and
+	// for the decimal point, for instance NumFmtEuropean. If it is not set
+	// the values are shown with no group separators and a full stop as
+	// the decimal point.
+	Number NumFormat
+}
+
+// check returns an error if the FmtOpts are invalid
+func (fo FmtOpts) check() error {
+	if fo.SigFigs < 0 || fo.SigFigs > maxFmtSigFigs {
+		return invalidValue(
+			"Invalid significant figures (%d) - it must be >= 1 and <= %d",
+			fo.SigFigs, maxFmtSigFigs)
+	}
+	if fo.Style < FmtScientific || fo.Style > FmtPrefixed {
+		return invalidValue("Invalid format style (%d)", fo.Style)
+	}
+	for _, f := range fo.Fields {
+		if f < 0 || f >= fieldCount {
+			return invalidValue("Invalid field (%d)", f)
+		}
+	}
+	return fo.Number.check()
+}
+
+// sigFigs returns the number of significant figures to show
+func (fo FmtOpts) sigFigs() int {
+	if fo.SigFigs == 0 {
+		return dfltFmtSigFigs
+	}
+	return fo.SigFigs
+}
+
+// fields returns the fields to show
+func (fo FmtOpts) fields() []StatField {
+	if len(fo.Fields) == 0 {
+		return dfltFields
+	}
+	return fo.Fields
+}
+
+// fmtVal returns the value formatted according to the FmtOpts
+func (fo FmtOpts) fmtVal(v float64, units string) string {
+	sf := fo.sigFigs()
+	var str string
+	switch fo.Style {
+	case FmtFixed:
+		decimals := 0
+		if v != 0 && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			magnitude := int(math.Floor(math.Log10(math.Abs(v))))
+			// rounding can carry into the next power of ten, as 9.996
+			// rounds to 10.00, which would show one digit too many
+			if roundedMag(v, sf-1-magnitude) > magnitude {
+				magnitude++
+			}
+			decimals = max(0, sf-1-magnitude)
+		}
+		str = fmt.Sprintf("%.*f", decimals, v)
+	case FmtGeneral:
+		str = fmt.Sprintf("%.*g", sf, v)
+	case FmtPrefixed:
+		str = FmtWithPrefix(v, units)
+	default:
+		str = fmt.Sprintf("%.*e", sf-1, v)
+	}
+	return fo.Number.apply(str)
+}
+
+// roundedMag returns the power of ten of the value once it has been rounded
+// to the given number of decimal places
+func roundedMag(v float64, decimals int) int {
+	scale := math.Pow(10, float64(decimals))
+	return int(math.Floor(math.Log10(math.Abs(math.Round(v*scale) / scale))))
+}
+
+// fmtCount returns the count formatted as an integer with any group
+// separators (see NumFormat)
+func (fo FmtOpts) fmtCount(n int) string {
+	return fo.Number.apply(fmt.Sprintf("%d", n))
+}
+
+// pct returns the percentage of the percentile shown by the field and true
+// or 0.0 and false if the field is not a percentile
+func (f StatField) pct() (float64, bool) {
+	switch f {
+	case FieldMedian:
+		return 50, true
+	case FieldP90:
+		return 90, true
+	case FieldP99:
+		return 99, true
+	}
+	return 0.0, false
+}
+
+// fieldVal returns the value of the field
+func (s Stat) fieldVal(f StatField) float64 {
+	switch f {
+	case FieldCount:
+		return float64(s.Count())
+	case FieldMin:
+		return s.Min()
+	case FieldMeanMin:
+		return s.MeanMin()
+	case FieldMean:
+		return s.Mean()
+	case FieldMax:
+		return s.Max()
+	case FieldMeanMax:
+		return s.MeanMax()
+	case FieldStdDev:
+		return s.StdDev()
+	case FieldSampleStdDev:
+		return s.SampleStdDev()
+	}
+	if p, ok := f.pct(); ok {
+		return s.Percentile(p)
+	}
+	return math.NaN()
+}
+
+// fieldStr returns the value of the field formatted according to the
+// FmtOpts. The count is always shown as an integer.
+func (s Stat) fieldStr(f StatField, fo FmtOpts) string {
+	if f == FieldCount {
+		return fo.fmtCount(s.Count())
+	}
+	return fo.fmtVal(s.fieldVal(f), s.units)
+}
+
+// StringWithOpts returns a string showing the values of the Stat formatted
+// according to the FmtOpts. The count is shown first, if requested, as
+// "N observations" followed by each of the other fields as "label: value".
+// Invalid settings in the FmtOpts are replaced with the defaults.
+func (s Stat) StringWithOpts(fo FmtOpts) string {
+	if fo.check() != nil {
+		fo = FmtOpts{}
+	}
+
+	parts := make([]string, 0, len(fo.fields()))
+	for _, f := range fo.fields() {
+		if f == FieldCount {
+			parts = append(parts,
+				fmt.Sprintf("%7s %s", fo.fmtCount(s.Count()), FieldCount))
+			continue
+		}
+		parts = append(parts, f.String()+": "+s.fieldStr(f, fo))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StatFormat returns a function that will make the String method format
+// the values according to the FmtOpts (see StringWithOpts). A template
+// given with StatStringTemplate takes precedence.
+func StatFormat(fo FmtOpts) StatOpt {
+	return func(s *Stat) error {
+		if s.fmtOpts != nil {
+			return repeatedOption("the format has already been set")
+		}
+		if err := fo.check(); err != nil {
+			return err
+		}
+
+		fo.Fields = slices.Clone(fo.Fields)
+		s.fmtOpts = &fo
+		return nil
+	}
+}
+
+// summaryPctiles are the percentiles shown by SummaryString
+var summaryPctiles = []float64{0, 25, 50, 75, 90, 99, 100}
+
+// SummaryString returns a string showing the count and the percentiles of
+// the values, as "min p25 p50 p75 p90 p99 max". This extends the classic
+// five-number summary with the tail percentiles and is often more useful
+// than the mean and standard deviation for skewed data such as latencies.
+// The min and max are exact; the other percentiles are estimates once the
+// values are no longer held (see Percentile).
+func (s Stat) SummaryString() string {
+	pvs := s.Percentiles(summaryPctiles...)
+	pvs[0], pvs[len(pvs)-1] = s.Min(), s.Max()
+
+	f := func(v float64) string { return fmt.Sprintf("%8.2e", v) }
+	if s.unitPrefixes {
+		f = func(v float64) string { return FmtWithPrefix(v, s.units) }
+	}
+
+	str := fmt.Sprintf("%7d observations", s.count)
+	for i, p := range summaryPctiles {
+		label := fmt.Sprintf("p%g", p)
+		switch p {
+		case 0:
+			label = "min"
+		case 100:
+			label = "max"
+		}
+		str += ", " + label + ": " + f(pvs[i])
+	}
+	return str
+}
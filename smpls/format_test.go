@@ -0,0 +1,193 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestFmtVal(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		fo  FmtOpts
+		v   float64
+		exp string
+	}{
+		{
+			ID:  testhelper.MkID("default"),
+			v:   12.5,
+			exp: "1.25e+01",
+		},
+		{
+			ID:  testhelper.MkID("scientific, 5 sig figs"),
+			fo:  FmtOpts{SigFigs: 5},
+			v:   12.5,
+			exp: "1.2500e+01",
+		},
+		{
+			ID:  testhelper.MkID("fixed"),
+			fo:  FmtOpts{Style: FmtFixed},
+			v:   12.5,
+			exp: "12.5",
+		},
+		{
+			ID:  testhelper.MkID("fixed, large"),
+			fo:  FmtOpts{Style: FmtFixed},
+			v:   12345.678,
+			exp: "12346",
+		},
+		{
+			ID:  testhelper.MkID("fixed, small"),
+			fo:  FmtOpts{Style: FmtFixed, SigFigs: 2},
+			v:   0.0012345,
+			exp: "0.0012",
+		},
+		{
+			ID:  testhelper.MkID("fixed, zero"),
+			fo:  FmtOpts{Style: FmtFixed},
+			v:   0,
+			exp: "0",
+		},
+		{
+			ID:  testhelper.MkID("fixed, rounds up to the next power of ten"),
+			fo:  FmtOpts{Style: FmtFixed},
+			v:   9.996,
+			exp: "10.0",
+		},
+		{
+			ID:  testhelper.MkID("fixed, english separators"),
+			fo:  FmtOpts{Style: FmtFixed, SigFigs: 9, Number: NumFmtEnglish},
+			v:   -1234567.5,
+			exp: "-1,234,567.50",
+		},
+		{
+			ID:  testhelper.MkID("fixed, european separators"),
+			fo:  FmtOpts{Style: FmtFixed, SigFigs: 6, Number: NumFmtEuropean},
+			v:   1234.5,
+			exp: "1.234,50",
+		},
+		{
+			ID:  testhelper.MkID("scientific, decimal comma"),
+			fo:  FmtOpts{Number: NumFormat{Decimal: ","}},
+			v:   12345,
+			exp: "1,23e+04",
+		},
+		{
+			ID:  testhelper.MkID("scientific, NaN, european separators"),
+			fo:  FmtOpts{Number: NumFmtEuropean},
+			v:   math.NaN(),
+			exp: "NaN",
+		},
+		{
+			ID:  testhelper.MkID("general"),
+			fo:  FmtOpts{Style: FmtGeneral, SigFigs: 4},
+			v:   12.5,
+			exp: "12.5",
+		},
+		{
+			ID:  testhelper.MkID("prefixed"),
+			fo:  FmtOpts{Style: FmtPrefixed},
+			v:   0.0125,
+			exp: "12.50 ms",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "value",
+			tc.fo.fmtVal(tc.v, "seconds"), tc.exp)
+	}
+}
+
+func TestStatFormat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		fo FmtOpts
+	}{
+		{
+			ID: testhelper.MkID("good"),
+			fo: FmtOpts{SigFigs: 4, Fields: []StatField{FieldMean}},
+		},
+		{
+			ID: testhelper.MkID("bad sig figs"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid significant figures (18) - it must be >= 1 and <= 17"),
+			fo: FmtOpts{SigFigs: 18},
+		},
+		{
+			ID:     testhelper.MkID("bad style"),
+			ExpErr: testhelper.MkExpErr("Invalid format style (9)"),
+			fo:     FmtOpts{Style: 9},
+		},
+		{
+			ID:     testhelper.MkID("bad field"),
+			ExpErr: testhelper.MkExpErr("Invalid field (99)"),
+			fo:     FmtOpts{Fields: []StatField{FieldMin, 99}},
+		},
+		{
+			ID: testhelper.MkID("bad number separators, same"),
+			ExpErr: testhelper.MkExpErr("Invalid number separators" +
+				` - the group separator and the decimal point are both "."`),
+			fo: FmtOpts{Number: NumFormat{Group: "."}},
+		},
+		{
+			ID: testhelper.MkID("bad number separators, digit"),
+			ExpErr: testhelper.MkExpErr(`Invalid number separator ("1")` +
+				" - it must not contain digits, signs or exponents"),
+			fo: FmtOpts{Number: NumFormat{Group: "1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", StatFormat(tc.fo))
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestStringWithOpts(t *testing.T) {
+	s := NewStatOrPanic("ms", StatFormat(FmtOpts{
+		Style:  FmtFixed,
+		Fields: []StatField{FieldMean, FieldCount, FieldMax, FieldMedian},
+	}))
+	s.Add(10, 12.5, 15)
+
+	testhelper.DiffString(t, "StatFormat", "String", s.String(),
+		"avg: 12.5,       3 observations, max: 15.0, median: 12.5")
+	testhelper.DiffString(t, "default opts", "StringWithOpts",
+		s.StringWithOpts(FmtOpts{}),
+		"      3 observations, min: 1.00e+01, mean min: 1.25e+01,"+
+			" avg: 1.25e+01, max: 1.50e+01, mean max: 1.25e+01, SD: 2.04e+00")
+
+	child := s.Fork()
+	child.Add(1)
+	testhelper.DiffString(t, "forked", "String", child.String(),
+		"avg: 1.00,       1 observations, max: 1.00, median: 1.00")
+
+	big := NewStatOrPanic("ms")
+	for i := range 12345 {
+		big.Add(float64(i))
+	}
+	testhelper.DiffString(t, "european separators", "StringWithOpts",
+		big.StringWithOpts(FmtOpts{
+			Style:  FmtFixed,
+			Number: NumFmtEuropean,
+			Fields: []StatField{FieldCount, FieldMax},
+		}),
+		" 12.345 observations, max: 12.344")
+}
+
+func TestSummaryString(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	addSeq(s, 1, 1, 101)
+	testhelper.DiffString(t, "cached", "SummaryString", s.SummaryString(),
+		"    101 observations, min: 1.00e+00, p25: 2.60e+01, p50: 5.10e+01,"+
+			" p75: 7.60e+01, p90: 9.10e+01, p99: 1.00e+02, max: 1.01e+02")
+
+	prefixed := NewStatOrPanic("s", StatUnitPrefixes())
+	prefixed.Add(0.001, 0.002)
+	testhelper.DiffString(t, "prefixed", "SummaryString",
+		prefixed.SummaryString(),
+		"      2 observations, min: 1.00 ms, p25: 1.25 ms, p50: 1.50 ms,"+
+			" p75: 1.75 ms, p90: 1.90 ms, p99: 1.99 ms, max: 2.00 ms")
+}
@@ -0,0 +1,56 @@
+package smpls
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Format implements the fmt.Formatter interface. The %v and %s verbs give
+// the summary returned by String, %+v adds the histogram (as returned by
+// Hist) on the following lines and %#v gives a machine-readable form: the
+// Go-syntax representation of the Stat's Snapshot.
+func (s Stat) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		switch {
+		case f.Flag('#'):
+			fmt.Fprintf(f, "%#v", s.Snapshot())
+		case f.Flag('+'):
+			io.WriteString(f, s.String()+"\n"+s.Hist())
+		default:
+			io.WriteString(f, s.String())
+		}
+	case 's':
+		io.WriteString(f, s.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(smpls.Stat=%s)", verb, s.String())
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The Stat is
+// written using the Save method and the result is base64 encoded. This
+// allows a Stat to be embedded in structs written as JSON, XML or any other
+// text-based format.
+func (s Stat) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		return nil, err
+	}
+	text := make([]byte, base64.StdEncoding.EncodedLen(buf.Len()))
+	base64.StdEncoding.Encode(text, buf.Bytes())
+	return text, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It
+// reverses the MarshalText method, decoding the base64 text and reading the
+// Stat using the Load method.
+func (s *Stat) UnmarshalText(text []byte) error {
+	b := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(b, text)
+	if err != nil {
+		return fmt.Errorf("cannot decode the Stat text: %w", err)
+	}
+	return s.Load(bytes.NewReader(b[:n]))
+}
@@ -0,0 +1,84 @@
+package smpls
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestFormat(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5))
+	addSeq(s, 1.0, 1.0, 20)
+
+	testCases := []struct {
+		testhelper.ID
+		format string
+		exp    string
+	}{
+		{
+			ID:     testhelper.MkID("v"),
+			format: "%v",
+			exp:    s.String(),
+		},
+		{
+			ID:     testhelper.MkID("s"),
+			format: "%s",
+			exp:    s.String(),
+		},
+		{
+			ID:     testhelper.MkID("+v"),
+			format: "%+v",
+			exp:    s.String() + "\n" + s.Hist(),
+		},
+		{
+			ID:     testhelper.MkID("#v"),
+			format: "%#v",
+			exp:    fmt.Sprintf("%#v", s.Snapshot()),
+		},
+		{
+			ID:     testhelper.MkID("bad verb"),
+			format: "%d",
+			exp:    "%!d(smpls.Stat=" + s.String() + ")",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "pointer",
+			fmt.Sprintf(tc.format, s), tc.exp)
+		testhelper.DiffString(t, tc.IDStr(), "value",
+			fmt.Sprintf(tc.format, *s), tc.exp)
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	type wrapper struct {
+		Name string
+		Stat Stat
+	}
+
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatTrackFreq(5))
+	addSeq(s, 1.0, 0.5, 50)
+
+	b, err := json.Marshal(wrapper{Name: "latency", Stat: *s})
+	if err != nil {
+		t.Fatal("Couldn't marshal the Stat:", err)
+	}
+
+	var loaded wrapper
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		t.Fatal("Couldn't unmarshal the Stat:", err)
+	}
+	testhelper.DiffString(t, "json", "Name", loaded.Name, "latency")
+	testhelper.DiffString(t, "json", "String",
+		loaded.Stat.String(), s.String())
+	testhelper.DiffString(t, "json", "Hist", loaded.Stat.Hist(), s.Hist())
+	testhelper.DiffString(t, "json", "TopNString",
+		loaded.Stat.TopNString(3), s.TopNString(3))
+
+	var bad Stat
+	if err := bad.UnmarshalText([]byte("not base64!")); err == nil {
+		t.Error("bad text: an error was expected but none was returned")
+	}
+}
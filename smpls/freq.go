@@ -0,0 +1,168 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+const minFreqMaxSize = 1
+
+// ValCount records the number of times a value has been seen
+type ValCount struct {
+	Val   float64
+	Count int
+}
+
+// freqTracker counts the number of times each distinct value is seen. It
+// holds at most maxSize distinct values; when it is full and a new value is
+// seen the least frequently seen value is evicted to make space and the
+// number of times it was seen is added to the evicted count.
+type freqTracker struct {
+	maxSize int
+	counts  map[float64]int
+	evicted int
+}
+
+// newFreqTracker creates a new freqTracker
+func newFreqTracker(maxSize int) *freqTracker {
+	return &freqTracker{
+		maxSize: maxSize,
+		counts:  make(map[float64]int),
+	}
+}
+
+// add records the value. NaN values are ignored.
+func (ft *freqTracker) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if _, ok := ft.counts[v]; !ok && len(ft.counts) >= ft.maxSize {
+		ft.evictLeastFrequent()
+	}
+	ft.counts[v]++
+}
+
+// evictLeastFrequent removes the least frequently seen value. If several
+// values have been seen equally rarely the largest is removed so that the
+// choice does not depend on the map iteration order.
+func (ft *freqTracker) evictLeastFrequent() {
+	first := true
+	var minVal float64
+	var minCount int
+	for v, count := range ft.counts {
+		if first || count < minCount || (count == minCount && v > minVal) {
+			minVal, minCount = v, count
+			first = false
+		}
+	}
+	delete(ft.counts, minVal)
+	ft.evicted += minCount
+}
+
+// reset clears all the counts
+func (ft *freqTracker) reset() {
+	clear(ft.counts)
+	ft.evicted = 0
+}
+
+// sorted returns the values and their counts, the most frequent first.
+// Values seen equally often are in ascending order.
+func (ft freqTracker) sorted() []ValCount {
+	vcs := make([]ValCount, 0, len(ft.counts))
+	for v, count := range ft.counts {
+		vcs = append(vcs, ValCount{Val: v, Count: count})
+	}
+	slices.SortFunc(vcs, func(a, b ValCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		if a.Val < b.Val {
+			return -1
+		}
+		if a.Val > b.Val {
+			return 1
+		}
+		return 0
+	})
+	return vcs
+}
+
+// StatTrackFreq returns a function that will make the Stat count how often
+// each distinct value is added. At most maxSize distinct values are
+// tracked; when a new value is added and the limit has been reached the
+// least frequently seen value is forgotten. This is most useful for
+// discrete data such as status codes or retry counts.
+func StatTrackFreq(maxSize int) StatOpt {
+	return func(s *Stat) error {
+		if s.freq != nil {
+			return repeatedOption("frequency tracking has already been set up")
+		}
+		if maxSize < minFreqMaxSize {
+			return invalidValue(
+				"Invalid frequency tracking size (%d) - it must be >= %d",
+				maxSize, minFreqMaxSize)
+		}
+
+		s.freq = newFreqTracker(maxSize)
+		return nil
+	}
+}
+
+// Mode returns the most frequently added value and the number of times it
+// was added. If several values were added equally often the smallest is
+// returned. The final result is false if the Stat is not tracking
+// frequencies (see StatTrackFreq) or if no values have been added.
+func (s Stat) Mode() (float64, int, bool) {
+	if s.freq == nil || len(s.freq.counts) == 0 {
+		return 0.0, 0, false
+	}
+	vc := s.freq.sorted()[0]
+	return vc.Val, vc.Count, true
+}
+
+// TopN returns the n most frequently added values with their counts, the
+// most frequent first. It returns nil if the Stat is not tracking
+// frequencies (see StatTrackFreq).
+func (s Stat) TopN(n int) []ValCount {
+	if s.freq == nil {
+		return nil
+	}
+	vcs := s.freq.sorted()
+	if n < len(vcs) {
+		vcs = vcs[:n]
+	}
+	return vcs
+}
+
+// FreqEvicted returns the number of added values that are no longer
+// counted because the value was evicted to make room for another.
+func (s Stat) FreqEvicted() int {
+	if s.freq == nil {
+		return 0
+	}
+	return s.freq.evicted
+}
+
+// TopNString returns a report of the n most frequently added values. Each
+// line shows the value, the number of times it was added and that as a
+// percentage of all the values added. It returns the empty string if the
+// Stat is not tracking frequencies (see StatTrackFreq).
+func (s Stat) TopNString(n int) string {
+	if s.freq == nil {
+		return ""
+	}
+
+	str := "units: " + s.units + "\n"
+	countFmt := fmt.Sprintf("%%%dd", mathutil.Digits(int64(s.count)))
+	for _, vc := range s.TopN(n) {
+		str += fmt.Sprintf("%12g: "+countFmt+" %6.2f%%\n",
+			vc.Val, vc.Count, 100.0*float64(vc.Count)/float64(s.count))
+	}
+	if s.freq.evicted > 0 {
+		str += fmt.Sprintf("(%d values no longer tracked)\n", s.freq.evicted)
+	}
+	return str
+}
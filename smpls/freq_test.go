@@ -0,0 +1,49 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMode(t *testing.T) {
+	s := NewStatOrPanic("status", StatTrackFreq(3))
+	_, _, ok := s.Mode()
+	testhelper.DiffBool(t, "Mode - no values", "ok", ok, false)
+
+	s.Add(200, 200, 404, 200, 500, 404, 503)
+
+	mode, count, ok := s.Mode()
+	testhelper.DiffBool(t, "Mode", "ok", ok, true)
+	testhelper.DiffFloat(t, "Mode", "mode", mode, 200, 0.0)
+	testhelper.DiffInt(t, "Mode", "count", count, 3)
+
+	top := s.TopN(2)
+	if testhelper.DiffInt(t, "TopN", "length", len(top), 2) {
+		return
+	}
+	testhelper.DiffFloat(t, "TopN", "1st value", top[0].Val, 200, 0.0)
+	testhelper.DiffFloat(t, "TopN", "2nd value", top[1].Val, 404, 0.0)
+	testhelper.DiffInt(t, "TopN", "2nd count", top[1].Count, 2)
+	testhelper.DiffInt(t, "TopN", "all", len(s.TopN(10)), 3)
+
+	// 500 was evicted to make room for 503
+	testhelper.DiffInt(t, "FreqEvicted", "evicted", s.FreqEvicted(), 1)
+	testhelper.DiffString(t, "TopNString", "report", s.TopNString(2),
+		"units: status\n"+
+			"         200: 3  42.86%\n"+
+			"         404: 2  28.57%\n"+
+			"(1 values no longer tracked)\n")
+
+	s.Reset()
+	_, _, ok = s.Mode()
+	testhelper.DiffBool(t, "Mode - after Reset", "ok", ok, false)
+
+	_, _, ok = NewStatOrPanic("units").Mode()
+	testhelper.DiffBool(t, "Mode - not tracking", "ok", ok, false)
+
+	_, err := NewStat("units", StatTrackFreq(0))
+	testhelper.CheckExpErrWithID(t, "StatTrackFreq - bad size", err,
+		testhelper.MkExpErr(
+			"Invalid frequency tracking size (0) - it must be >= 1"))
+}
@@ -0,0 +1,321 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	minHDRSigFigs = 1
+	maxHDRSigFigs = 5
+	minHDRMaxVal  = 2.0
+)
+
+// HDRStat records statistics like a Stat but, rather than a histogram with
+// buckets of a fixed width, it uses a high dynamic range histogram whose
+// buckets grow with the values. Values from 1 up to a configurable maximum
+// are recorded with a bounded relative error given by the number of
+// significant digits. This is well suited to values such as latencies which
+// can span many orders of magnitude; for instance, in microseconds, from
+// 1µs to 100s with a maximum of 1e8.
+//
+// Each power of two from 1 to the maximum is split into enough equal
+// buckets to give the required precision. Values below 1 (including zero
+// and negative values) are counted in an underflow and values above the
+// maximum in an overflow; all the values contribute to the count, mean,
+// standard deviation, minimum and maximum.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type HDRStat struct {
+	units   string
+	maxVal  float64
+	sigFigs int
+
+	count    int
+	sum      float64
+	sumSq    float64
+	minVal   float64
+	maxSeen  float64
+	subCount int
+
+	underflow int
+	hist      []int
+	overflow  int
+}
+
+// NewHDRStat creates a new HDRStat. The maxVal gives the largest value that
+// will be recorded precisely; it must be at least 2. The sigFigs gives the
+// number of significant decimal digits to which the values are recorded;
+// it must be between 1 and 5. The memory used grows tenfold with each
+// extra significant digit.
+func NewHDRStat(units string, maxVal float64, sigFigs int) (*HDRStat, error) {
+	if !(maxVal >= minHDRMaxVal) || math.IsInf(maxVal, 1) {
+		return nil, invalidValue("Invalid HDR max value (%g) - it must be >= %g",
+			maxVal, minHDRMaxVal)
+	}
+	if sigFigs < minHDRSigFigs || sigFigs > maxHDRSigFigs {
+		return nil,
+			invalidValue("Invalid HDR significant figures (%d)"+
+				" - it must be >= %d and <= %d",
+				sigFigs, minHDRSigFigs, maxHDRSigFigs)
+	}
+
+	// each power of two is split into a power of two number of buckets so
+	// that the bucket width relative to the value is at most 10^-sigFigs
+	subCount := 1
+	for subCount < int(math.Pow10(sigFigs)) {
+		subCount *= 2
+	}
+	_, exp := math.Frexp(maxVal)
+
+	return &HDRStat{
+		units:    units,
+		maxVal:   maxVal,
+		sigFigs:  sigFigs,
+		subCount: subCount,
+		hist:     make([]int, exp*subCount),
+	}, nil
+}
+
+// NewHDRStatOrPanic creates a new HDRStat and will panic if any errors are
+// detected
+func NewHDRStatOrPanic(units string, maxVal float64, sigFigs int) *HDRStat {
+	hs, err := NewHDRStat(units, maxVal, sigFigs)
+	if err != nil {
+		panic(err)
+	}
+	return hs
+}
+
+// bucketIdx returns the index of the bucket holding the value which must
+// be at least 1. The result may be beyond the end of the histogram.
+func (hs HDRStat) bucketIdx(v float64) int {
+	frac, exp := math.Frexp(v) // v = frac * 2^exp with frac in [0.5, 1)
+	sub := int((2*frac - 1) * float64(hs.subCount))
+	return (exp-1)*hs.subCount + sub
+}
+
+// bucketLimits returns the lowest and highest values in the i'th bucket
+func (hs HDRStat) bucketLimits(i int) (lo, hi float64) {
+	power := math.Ldexp(1, i/hs.subCount)
+	width := power / float64(hs.subCount)
+	lo = power + width*float64(i%hs.subCount)
+	return lo, lo + width
+}
+
+// Add adds at least one new value to the HDRStat
+func (hs *HDRStat) Add(v float64, vals ...float64) {
+	hs.addVal(v)
+	for _, v := range vals {
+		hs.addVal(v)
+	}
+}
+
+// addVal adds a single value to the HDRStat
+func (hs *HDRStat) addVal(v float64) {
+	if hs.count == 0 {
+		hs.minVal, hs.maxSeen = v, v
+	} else {
+		hs.minVal = math.Min(hs.minVal, v)
+		hs.maxSeen = math.Max(hs.maxSeen, v)
+	}
+	hs.count++
+	hs.sum += v
+	hs.sumSq += v * v
+
+	switch {
+	case v < 1:
+		hs.underflow++
+	case v > hs.maxVal:
+		hs.overflow++
+	default:
+		idx := hs.bucketIdx(v)
+		if idx >= len(hs.hist) {
+			hs.overflow++
+			return
+		}
+		hs.hist[idx]++
+	}
+}
+
+// Reset discards all the values
+func (hs *HDRStat) Reset() {
+	hs.count = 0
+	hs.sum = 0
+	hs.sumSq = 0
+	hs.minVal = 0
+	hs.maxSeen = 0
+	hs.underflow = 0
+	hs.overflow = 0
+	clear(hs.hist)
+}
+
+// Merge adds the values recorded by the other HDRStat to this one. They
+// must have the same units, maximum value and significant figures.
+func (hs *HDRStat) Merge(other *HDRStat) error {
+	if hs.units != other.units || hs.maxVal != other.maxVal ||
+		hs.sigFigs != other.sigFigs {
+		return fmt.Errorf("cannot merge HDRStats with different settings:"+
+			" %q (max: %g, sig figs: %d) and %q (max: %g, sig figs: %d)",
+			hs.units, hs.maxVal, hs.sigFigs,
+			other.units, other.maxVal, other.sigFigs)
+	}
+	if other.count == 0 {
+		return nil
+	}
+
+	if hs.count == 0 {
+		hs.minVal, hs.maxSeen = other.minVal, other.maxSeen
+	} else {
+		hs.minVal = math.Min(hs.minVal, other.minVal)
+		hs.maxSeen = math.Max(hs.maxSeen, other.maxSeen)
+	}
+	hs.count += other.count
+	hs.sum += other.sum
+	hs.sumSq += other.sumSq
+	hs.underflow += other.underflow
+	hs.overflow += other.overflow
+	for i, count := range other.hist {
+		hs.hist[i] += count
+	}
+	return nil
+}
+
+// Units returns the units of the HDRStat
+func (hs HDRStat) Units() string {
+	return hs.units
+}
+
+// Count returns the number of values added
+func (hs HDRStat) Count() int {
+	return hs.count
+}
+
+// Underflow returns the number of values added that were less than 1
+func (hs HDRStat) Underflow() int {
+	return hs.underflow
+}
+
+// Overflow returns the number of values added that were greater than the
+// maximum value
+func (hs HDRStat) Overflow() int {
+	return hs.overflow
+}
+
+// Min returns the smallest value added or 0.0 if no values have been added
+func (hs HDRStat) Min() float64 {
+	return hs.minVal
+}
+
+// Max returns the largest value added or 0.0 if no values have been added
+func (hs HDRStat) Max() float64 {
+	return hs.maxSeen
+}
+
+// Mean returns the mean of the values added or 0.0 if no values have been
+// added
+func (hs HDRStat) Mean() float64 {
+	if hs.count == 0 {
+		return 0.0
+	}
+	return hs.sum / float64(hs.count)
+}
+
+// StdDev returns the standard deviation of the values added or 0.0 if
+// fewer than 2 values have been added
+func (hs HDRStat) StdDev() float64 {
+	if hs.count < 2 {
+		return 0.0
+	}
+	avg := hs.sum / float64(hs.count)
+	return math.Sqrt(math.Max(0, hs.sumSq/float64(hs.count)-avg*avg))
+}
+
+// RelativeError returns the largest error, as a fraction of the value,
+// of a percentile estimated by the HDRStat (see Percentile) for values
+// between 1 and the maximum value
+func (hs HDRStat) RelativeError() float64 {
+	return 0.5 / float64(hs.subCount)
+}
+
+// PercentileBounds returns the limits between which the value below which
+// the given percentage of the values fall is known to lie; p should be in
+// the range [0, 100] and is forced into that range if not. The limits are
+// those of the bucket holding the percentile, or of the underflow or
+// overflow which are taken to stretch from the minimum value to 1 and from
+// the maximum value recorded precisely to the maximum value seen, and are
+// never outside the minimum and maximum values. It returns NaNs if p is NaN
+// and zeros if no values have been added.
+func (hs HDRStat) PercentileBounds(p float64) (lo, hi float64) {
+	if math.IsNaN(p) {
+		return math.NaN(), math.NaN()
+	}
+	if hs.count == 0 {
+		return 0.0, 0.0
+	}
+	target := clampPct(p) / 100.0 * float64(hs.count)
+
+	lo, hi = hs.maxVal, hs.maxSeen
+	if target <= float64(hs.underflow) && hs.underflow > 0 {
+		lo, hi = hs.minVal, 1
+	} else {
+		cum := hs.underflow
+		for i, count := range hs.hist {
+			cum += count
+			if count > 0 && target <= float64(cum) {
+				lo, hi = hs.bucketLimits(i)
+				break
+			}
+		}
+	}
+	return math.Max(hs.minVal, math.Min(hs.maxSeen, lo)),
+		math.Max(hs.minVal, math.Min(hs.maxSeen, hi))
+}
+
+// Percentile returns an estimate of the value below which the given
+// percentage of the values fall; p should be in the range [0, 100] and is
+// forced into that range if not. The estimate is the middle of the bucket
+// holding the percentile and so, for values between 1 and the maximum
+// value, is within the relative error given by the significant figures.
+// Percentiles falling in the underflow or overflow give the minimum or
+// maximum value. It returns NaN if p is NaN and 0.0 if no values have been
+// added.
+func (hs HDRStat) Percentile(p float64) float64 {
+	if math.IsNaN(p) {
+		return math.NaN()
+	}
+	if hs.count == 0 {
+		return 0.0
+	}
+	target := clampPct(p) / 100.0 * float64(hs.count)
+
+	if target <= float64(hs.underflow) && hs.underflow > 0 {
+		return hs.minVal
+	}
+	cum := hs.underflow
+	for i, count := range hs.hist {
+		cum += count
+		if count > 0 && target <= float64(cum) {
+			lo, hi := hs.bucketLimits(i)
+			return math.Max(hs.minVal, math.Min(hs.maxSeen, (lo+hi)/2))
+		}
+	}
+	return hs.maxSeen
+}
+
+// String returns a summary of the HDRStat showing the count, the minimum,
+// mean and maximum values and some percentiles
+func (hs HDRStat) String() string {
+	return fmt.Sprintf(
+		"%7d observations,"+
+			" min: %8.2e,"+
+			" avg: %8.2e,"+
+			" p50: %8.2e,"+
+			" p99: %8.2e,"+
+			" p99.9: %8.2e,"+
+			" max: %8.2e",
+		hs.count, hs.minVal, hs.Mean(),
+		hs.Percentile(50), hs.Percentile(99), hs.Percentile(99.9),
+		hs.maxSeen)
+}
@@ -0,0 +1,136 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewHDRStat(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		maxVal  float64
+		sigFigs int
+	}{
+		{
+			ID:      testhelper.MkID("good"),
+			maxVal:  1e8,
+			sigFigs: 3,
+		},
+		{
+			ID:      testhelper.MkID("bad max"),
+			ExpErr:  testhelper.MkExpErr("Invalid HDR max value (1)"),
+			maxVal:  1,
+			sigFigs: 3,
+		},
+		{
+			ID:      testhelper.MkID("NaN max"),
+			ExpErr:  testhelper.MkExpErr("Invalid HDR max value (NaN)"),
+			maxVal:  math.NaN(),
+			sigFigs: 3,
+		},
+		{
+			ID: testhelper.MkID("bad sig figs"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid HDR significant figures (6) - it must be >= 1 and <= 5"),
+			maxVal:  100,
+			sigFigs: 6,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewHDRStat("units", tc.maxVal, tc.sigFigs)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestHDRStatBuckets(t *testing.T) {
+	hs := NewHDRStatOrPanic("µs", 1e8, 2)
+	for _, v := range []float64{1, 1.5, 2, 3.999, 1000, 12345.6, 9.9e7} {
+		idx := hs.bucketIdx(v)
+		lo, hi := hs.bucketLimits(idx)
+		if v < lo || v >= hi {
+			t.Errorf("value %g is not in its bucket [%g, %g)", v, lo, hi)
+		}
+		if (hi-lo)/lo > 0.01 {
+			t.Errorf("bucket [%g, %g) is too wide for 2 significant figures",
+				lo, hi)
+		}
+	}
+}
+
+func TestHDRStatPercentile(t *testing.T) {
+	hs := NewHDRStatOrPanic("µs", 1e8, 3)
+	// values spanning eight orders of magnitude
+	for i := 0; i < 8000; i++ {
+		hs.Add(math.Pow(10, float64(i)/1000))
+	}
+	hs.Add(0.5, 2e8)
+
+	testhelper.DiffInt(t, "HDR", "count", hs.Count(), 8002)
+	testhelper.DiffInt(t, "HDR", "underflow", hs.Underflow(), 1)
+	testhelper.DiffInt(t, "HDR", "overflow", hs.Overflow(), 1)
+	testhelper.DiffFloat(t, "HDR", "min", hs.Min(), 0.5, 0)
+	testhelper.DiffFloat(t, "HDR", "max", hs.Max(), 2e8, 0)
+
+	for _, p := range []float64{10, 50, 90, 99} {
+		exp := math.Pow(10, (p/100*8002-1)/1000)
+		act := hs.Percentile(p)
+		if math.Abs(act-exp)/exp > 0.005 {
+			t.Errorf("percentile %g: expected about %g, got %g", p, exp, act)
+		}
+	}
+	testhelper.DiffFloat(t, "HDR", "p0", hs.Percentile(0), 0.5, 0)
+	testhelper.DiffFloat(t, "HDR", "p100", hs.Percentile(100), 2e8, 0)
+
+	// 3 significant figures needs 1024 buckets per power of two
+	testhelper.DiffFloat(t, "HDR", "relative error",
+		hs.RelativeError(), 0.5/1024, 0)
+	for _, p := range []float64{10, 50, 90, 99} {
+		lo, hi := hs.PercentileBounds(p)
+		v := hs.Percentile(p)
+		if v < lo || v > hi || (hi-lo)/2 > hs.RelativeError()*lo*1.0001 {
+			t.Errorf("percentile %g: bad bounds [%g, %g] for %g",
+				p, lo, hi, v)
+		}
+	}
+	lo, hi := hs.PercentileBounds(0)
+	testhelper.DiffFloat(t, "HDR underflow", "lo", lo, 0.5, 0)
+	testhelper.DiffFloat(t, "HDR underflow", "hi", hi, 1, 0)
+	lo, hi = hs.PercentileBounds(100)
+	testhelper.DiffFloat(t, "HDR overflow", "lo", lo, 1e8, 0)
+	testhelper.DiffFloat(t, "HDR overflow", "hi", hi, 2e8, 0)
+
+	testhelper.DiffBool(t, "HDR", "percentile NaN is NaN",
+		math.IsNaN(hs.Percentile(math.NaN())), true)
+	lo, hi = hs.PercentileBounds(math.NaN())
+	testhelper.DiffBool(t, "HDR", "bounds of NaN are NaN",
+		math.IsNaN(lo) && math.IsNaN(hi), true)
+}
+
+func TestHDRStatMergeReset(t *testing.T) {
+	a := NewHDRStatOrPanic("ms", 1000, 2)
+	b := NewHDRStatOrPanic("ms", 1000, 2)
+	a.Add(1, 2, 3)
+	b.Add(10, 20)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("unexpected merge error: %s", err)
+	}
+	testhelper.DiffInt(t, "merge", "count", a.Count(), 5)
+	testhelper.DiffFloat(t, "merge", "mean", a.Mean(), 7.2, 1e-9)
+	testhelper.DiffFloat(t, "merge", "max", a.Max(), 20, 0)
+
+	err := a.Merge(NewHDRStatOrPanic("ms", 1000, 3))
+	testhelper.CheckExpErrWithID(t, "merge - different settings", err,
+		testhelper.MkExpErr("cannot merge HDRStats with different settings"))
+
+	a.Reset()
+	testhelper.DiffInt(t, "reset", "count", a.Count(), 0)
+	testhelper.DiffFloat(t, "reset", "p50", a.Percentile(50), 0, 0)
+	testhelper.DiffString(t, "reset", "string", a.String(),
+		"      0 observations, min: 0.00e+00, avg: 0.00e+00,"+
+			" p50: 0.00e+00, p99: 0.00e+00, p99.9: 0.00e+00, max: 0.00e+00")
+}
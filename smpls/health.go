@@ -0,0 +1,120 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// healthExactLimit is the magnitude beyond which not every integer can
+	// be held exactly in a float64 (2^53)
+	healthExactLimit = 1 << 53
+	// healthMaxOutsideFraction is the largest proportion of the values that
+	// can be in the histogram underflow and overflow before it is reported
+	healthMaxOutsideFraction = 0.1
+)
+
+// HealthCheck identifies the problem found by a health check
+type HealthCheck int
+
+// These are the problems that the Health method checks for
+const (
+	// HealthNonFinite - the sum or sum of squares is infinite or NaN so the
+	// mean and standard deviation are meaningless
+	HealthNonFinite HealthCheck = iota
+	// HealthPrecisionLoss - the count, sum or sum of squares is so large
+	// that adding further values loses precision
+	HealthPrecisionLoss
+	// HealthOutOfRange - many values lie outside the histogram so it shows
+	// little of the distribution
+	HealthOutOfRange
+	// HealthCacheExhausted - the cache of values is full so percentiles
+	// and the like are now estimated from the histogram
+	HealthCacheExhausted
+)
+
+// String returns a short name for the HealthCheck
+func (hc HealthCheck) String() string {
+	switch hc {
+	case HealthNonFinite:
+		return "non-finite"
+	case HealthPrecisionLoss:
+		return "precision-loss"
+	case HealthOutOfRange:
+		return "out-of-range"
+	case HealthCacheExhausted:
+		return "cache-exhausted"
+	}
+	return fmt.Sprintf("HealthCheck(%d)", int(hc))
+}
+
+// HealthWarning records a problem found by the Health method
+type HealthWarning struct {
+	Check  HealthCheck
+	Detail string
+}
+
+// String returns the warning as a string
+func (hw HealthWarning) String() string {
+	return hw.Check.String() + ": " + hw.Detail
+}
+
+// Health checks the Stat for conditions which make its results less
+// accurate than they might appear and returns a warning for each one
+// found. It returns nil if there are no problems. It checks for:
+//
+//   - a sum or sum of squares which is infinite or NaN
+//   - a count, sum or sum of squares so large (beyond 2^53) that adding
+//     further values loses precision; the sums are not checked if the
+//     Stat uses compensated summation (see StatCompensatedSum)
+//   - more than 10% of the values lying outside the histogram
+//   - a cache which has been filled so that percentiles are now estimated
+//     from the histogram
+func (s Stat) Health() []HealthWarning {
+	var hws []HealthWarning
+	add := func(hc HealthCheck, format string, args ...any) {
+		hws = append(hws,
+			HealthWarning{Check: hc, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	sum, sumSq := s.fullSum(), s.fullSumSq()
+	if math.IsInf(sum, 0) || math.IsNaN(sum) ||
+		math.IsInf(sumSq, 0) || math.IsNaN(sumSq) {
+		add(HealthNonFinite, "sum: %g, sum of squares: %g", sum, sumSq)
+	} else if !s.compensated &&
+		(math.Abs(sum) >= healthExactLimit || sumSq >= healthExactLimit) {
+		add(HealthPrecisionLoss,
+			"sum: %g, sum of squares: %g (limit: 2^53)", sum, sumSq)
+	}
+	if s.Count() >= healthExactLimit {
+		add(HealthPrecisionLoss, "count: %d (limit: 2^53)", s.Count())
+	}
+
+	if s.hist != nil && s.count > 0 && s.histPending() == nil {
+		outside := s.underflow + s.overflow
+		if float64(outside) > healthMaxOutsideFraction*float64(s.count) {
+			add(HealthOutOfRange,
+				"%d of %d values (%.1f%%) are outside the histogram",
+				outside, s.count, 100*float64(outside)/float64(s.count))
+		}
+		// only a Stat whose cache has overflowed has had to give up the
+		// values; a Stat laid out from its expected range never had a cache
+		if s.cacheBuf != nil && s.retained() == nil {
+			add(HealthCacheExhausted,
+				"the cache is full, percentiles are estimated")
+		}
+	}
+
+	return hws
+}
+
+// HealthErr returns nil if the Health method reports no problems and
+// otherwise returns an error holding each of the warnings
+func (s Stat) HealthErr() error {
+	var errs []error
+	for _, hw := range s.Health() {
+		errs = append(errs, errors.New(hw.String()))
+	}
+	return errors.Join(errs...)
+}
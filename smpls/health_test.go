@@ -0,0 +1,88 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestHealth(t *testing.T) {
+	healthy := NewStatOrPanic("ms")
+	healthy.Add(1, 2, 3)
+
+	huge := NewStatOrPanic("ms", StatNoHist())
+	huge.Add(1e10, 1e10)
+
+	hugeCompensated := NewStatOrPanic("ms", StatNoHist(), StatCompensatedSum())
+	hugeCompensated.Add(1e10)
+
+	nonFinite := NewStatOrPanic("ms", StatNoHist())
+	nonFinite.Add(math.Inf(1))
+
+	outside := NewStatOrPanic("ms", StatCacheSize(10), StatMinMaxCount(5))
+	addSeq(outside, 0, 1, 10)
+	addSeq(outside, 100, 1, 5)
+
+	noCache := NewStatOrPanic("ms", StatNoCache(), StatMinMaxCount(5))
+	addSeq(noCache, 0, 1, 10)
+
+	expRange := NewStatOrPanic("ms", StatExpectedRange(0, 10))
+	expRange.Add(3)
+
+	cacheNotFull := NewStatOrPanic("ms", StatCacheSize(10))
+	addSeq(cacheNotFull, 0, 1, 5)
+
+	testCases := []struct {
+		testhelper.ID
+		s         *Stat
+		expChecks []HealthCheck
+	}{
+		{ID: testhelper.MkID("healthy"), s: healthy},
+		{
+			ID:        testhelper.MkID("huge"),
+			s:         huge,
+			expChecks: []HealthCheck{HealthPrecisionLoss},
+		},
+		{ID: testhelper.MkID("huge, compensated"), s: hugeCompensated},
+		{
+			ID:        testhelper.MkID("non-finite"),
+			s:         nonFinite,
+			expChecks: []HealthCheck{HealthNonFinite},
+		},
+		{
+			ID:        testhelper.MkID("outside"),
+			s:         outside,
+			expChecks: []HealthCheck{HealthOutOfRange, HealthCacheExhausted},
+		},
+		{
+			ID:        testhelper.MkID("no cache"),
+			s:         noCache,
+			expChecks: []HealthCheck{HealthOutOfRange},
+		},
+		{ID: testhelper.MkID("expected range"), s: expRange},
+		{ID: testhelper.MkID("cache not full"), s: cacheNotFull},
+	}
+
+	for _, tc := range testCases {
+		hws := tc.s.Health()
+		checks := []HealthCheck{}
+		for _, hw := range hws {
+			checks = append(checks, hw.Check)
+		}
+		if len(tc.expChecks) == 0 {
+			tc.expChecks = []HealthCheck{}
+		}
+		if err := testhelper.DiffVals(checks, tc.expChecks); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected health checks: %v\n", err)
+		}
+		testhelper.DiffBool(t, tc.IDStr(), "HealthErr is nil",
+			tc.s.HealthErr() == nil, len(hws) == 0)
+	}
+
+	testhelper.CheckExpErrWithID(t, "HealthErr", outside.HealthErr(),
+		testhelper.MkExpErr(
+			"out-of-range: 5 of 15 values (33.3%) are outside the histogram",
+			"cache-exhausted: the cache is full"))
+}
@@ -0,0 +1,293 @@
+package smpls
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	minHeavyHitters = 1
+	maxHeavyHitters = 100000
+)
+
+// HeavyHitter gives an estimate of the number of times a value has been
+// added (see StatHeavyHitters). The Count may be too high but by no more
+// than the Err; the value has been added at least Count - Err times.
+type HeavyHitter struct {
+	Val   float64
+	Count int
+	Err   int
+}
+
+// hhEntry is a value counted by a heavyHitters tracker
+type hhEntry struct {
+	val   float64
+	count int
+	err   int
+}
+
+// heavyHitters counts the most frequent values using the Space-Saving
+// algorithm (Metwally, Agrawal and El Abbadi). It counts at most k values;
+// when it is full a new value takes the place of the value with the
+// smallest count, inheriting that count as its possible error. Every value
+// added more than n/k times, where n is the number of values added, is
+// guaranteed to be counted.
+//
+// The entries are kept in a min-heap ordered by count, with a map from
+// each value to its place in the heap, so that each value is added in
+// O(log k) time.
+type heavyHitters struct {
+	k       int
+	entries []hhEntry
+	idx     map[float64]int
+}
+
+// newHeavyHitters creates a new heavyHitters tracker counting at most k
+// values
+func newHeavyHitters(k int) *heavyHitters {
+	return &heavyHitters{
+		k:       k,
+		entries: make([]hhEntry, 0, k),
+		idx:     make(map[float64]int, k),
+	}
+}
+
+// less reports whether entry i should be nearer the top of the heap than
+// entry j
+func (hh *heavyHitters) less(i, j int) bool {
+	a, b := hh.entries[i], hh.entries[j]
+	if a.count != b.count {
+		return a.count < b.count
+	}
+	return a.val > b.val
+}
+
+// swap exchanges entries i and j, keeping the index up to date
+func (hh *heavyHitters) swap(i, j int) {
+	hh.entries[i], hh.entries[j] = hh.entries[j], hh.entries[i]
+	hh.idx[hh.entries[i].val] = i
+	hh.idx[hh.entries[j].val] = j
+}
+
+// up moves entry i towards the top of the heap until it is in place
+func (hh *heavyHitters) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !hh.less(i, parent) {
+			break
+		}
+		hh.swap(i, parent)
+		i = parent
+	}
+}
+
+// down moves entry i towards the bottom of the heap until it is in place
+func (hh *heavyHitters) down(i int) {
+	n := len(hh.entries)
+	for {
+		least := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < n && hh.less(child, least) {
+				least = child
+			}
+		}
+		if least == i {
+			return
+		}
+		hh.swap(i, least)
+		i = least
+	}
+}
+
+// add counts the value. NaN values are ignored.
+func (hh *heavyHitters) add(v float64) {
+	hh.addCount(v, 1, 0)
+}
+
+// addCount adds the count, with the given possible error, to the value,
+// replacing the value with the smallest count if the value is not already
+// counted and the tracker is full
+func (hh *heavyHitters) addCount(v float64, count, err int) {
+	if math.IsNaN(v) {
+		return
+	}
+	if i, ok := hh.idx[v]; ok {
+		hh.entries[i].count += count
+		hh.entries[i].err += err
+		hh.down(i)
+		return
+	}
+	if len(hh.entries) < hh.k {
+		hh.entries = append(hh.entries, hhEntry{val: v, count: count, err: err})
+		hh.idx[v] = len(hh.entries) - 1
+		hh.up(len(hh.entries) - 1)
+		return
+	}
+
+	least := hh.entries[0]
+	delete(hh.idx, least.val)
+	hh.entries[0] = hhEntry{
+		val:   v,
+		count: least.count + count,
+		err:   least.count + err,
+	}
+	hh.idx[v] = 0
+	hh.down(0)
+}
+
+// minCount returns the smallest count if the tracker is full, otherwise 0.
+// A value not counted by a full tracker may have been added this many
+// times.
+func (hh heavyHitters) minCount() int {
+	if len(hh.entries) < hh.k {
+		return 0
+	}
+	return hh.entries[0].count
+}
+
+// reset discards all the counts
+func (hh *heavyHitters) reset() {
+	hh.entries = hh.entries[:0]
+	clear(hh.idx)
+}
+
+// clone returns an independent copy of the heavyHitters tracker
+func (hh heavyHitters) clone() *heavyHitters {
+	hh.entries = append(make([]hhEntry, 0, hh.k), hh.entries...)
+	idx := make(map[float64]int, hh.k)
+	for v, i := range hh.idx {
+		idx[v] = i
+	}
+	hh.idx = idx
+	return &hh
+}
+
+// merge adds the counts from the other tracker. A value counted by only
+// one of the trackers may have been added to the other as often as that
+// tracker's smallest count and so this is added to its count and its
+// possible error. The k values with the largest combined counts are kept.
+func (hh *heavyHitters) merge(o *heavyHitters) {
+	sMin, oMin := hh.minCount(), o.minCount()
+	combined := make(map[float64]hhEntry, len(hh.entries)+len(o.entries))
+	for _, e := range hh.entries {
+		combined[e.val] = e
+	}
+	for _, e := range o.entries {
+		if c, ok := combined[e.val]; ok {
+			c.count += e.count
+			c.err += e.err
+			combined[e.val] = c
+			continue
+		}
+		combined[e.val] = hhEntry{
+			val: e.val, count: e.count + sMin, err: e.err + sMin,
+		}
+	}
+	for _, e := range hh.entries {
+		if _, ok := o.idx[e.val]; !ok {
+			c := combined[e.val]
+			c.count += oMin
+			c.err += oMin
+			combined[e.val] = c
+		}
+	}
+
+	all := make([]hhEntry, 0, len(combined))
+	for _, e := range combined {
+		all = append(all, e)
+	}
+	slices.SortFunc(all, func(a, b hhEntry) int {
+		if c := cmp.Compare(b.count, a.count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.val, b.val)
+	})
+
+	hh.reset()
+	for _, e := range all[:min(len(all), hh.k)] {
+		hh.addCount(e.val, e.count, e.err)
+	}
+}
+
+// sorted returns the values counted, the most frequent first. Values with
+// the same count are in ascending order.
+func (hh heavyHitters) sorted() []HeavyHitter {
+	hhs := make([]HeavyHitter, 0, len(hh.entries))
+	for _, e := range hh.entries {
+		hhs = append(hhs, HeavyHitter{Val: e.val, Count: e.count, Err: e.err})
+	}
+	slices.SortFunc(hhs, func(a, b HeavyHitter) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Val, b.Val)
+	})
+	return hhs
+}
+
+// check returns an error if the heavyHitters tracker is not valid
+func (hh heavyHitters) check() error {
+	if hh.k < minHeavyHitters || hh.k > maxHeavyHitters ||
+		len(hh.entries) > hh.k {
+		return fmt.Errorf("bad heavy hitter count (%d of %d)",
+			len(hh.entries), hh.k)
+	}
+	if len(hh.idx) != len(hh.entries) {
+		return fmt.Errorf("bad heavy hitter index (%d for %d values)",
+			len(hh.idx), len(hh.entries))
+	}
+	for i := 1; i < len(hh.entries); i++ {
+		if hh.less(i, (i-1)/2) {
+			return errors.New("the heavy hitters are not in heap order")
+		}
+	}
+	for _, e := range hh.entries {
+		if e.count < 1 || e.err < 0 || e.err >= e.count ||
+			math.IsNaN(e.val) {
+			return fmt.Errorf("bad heavy hitter (%g: %d, error %d)",
+				e.val, e.count, e.err)
+		}
+	}
+	return nil
+}
+
+// StatHeavyHitters returns a function that will make the Stat count the k
+// most frequently added values, approximately, using the Space-Saving
+// algorithm. Unlike StatTrackFreq, which forgets the counts of values it
+// evicts, this gives an upper bound on the count of every value it reports
+// and guarantees to report every value added more than Count()/k times,
+// however many values are added. This suits finding the most common values
+// (such as payload sizes) in a long stream of values. The counts are given
+// by HeavyHitters. NaN values are not counted.
+func StatHeavyHitters(k int) StatOpt {
+	return func(s *Stat) error {
+		if s.heavy != nil {
+			return repeatedOption("heavy hitter tracking has already been set up")
+		}
+		if k < minHeavyHitters || k > maxHeavyHitters {
+			return invalidValue(
+				"Invalid heavy hitter count (%d) - it must be >= %d and <= %d",
+				k, minHeavyHitters, maxHeavyHitters)
+		}
+
+		s.heavy = newHeavyHitters(k)
+		return nil
+	}
+}
+
+// HeavyHitters returns up to n of the most frequently added values with
+// their estimated counts, the most frequent first. It returns nil if the
+// Stat is not tracking them (see StatHeavyHitters).
+func (s Stat) HeavyHitters(n int) []HeavyHitter {
+	if s.heavy == nil {
+		return nil
+	}
+	hhs := s.heavy.sorted()
+	if n < len(hhs) {
+		hhs = hhs[:max(n, 0)]
+	}
+	return hhs
+}
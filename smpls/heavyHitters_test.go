@@ -0,0 +1,73 @@
+package smpls
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestHeavyHitters(t *testing.T) {
+	const k = 50
+	s := NewStatOrPanic("bytes", StatHeavyHitters(k))
+	r := rand.New(rand.NewPCG(3, 4))
+
+	exact := map[float64]int{}
+	add := func(v float64) {
+		s.Add(v)
+		exact[v]++
+	}
+	for i := range 20000 {
+		switch {
+		case i%4 == 0:
+			add(512)
+		case i%10 == 1:
+			add(1500)
+		case i%25 == 2:
+			add(64)
+		default:
+			add(float64(r.IntN(5000)))
+		}
+	}
+	add(math.NaN())
+
+	hhs := s.HeavyHitters(3)
+	testhelper.DiffInt(t, "HeavyHitters", "count", len(hhs), 3)
+	for i, exp := range []float64{512, 1500, 64} {
+		testhelper.DiffFloat(t, "HeavyHitters", "value", hhs[i].Val, exp, 0)
+	}
+	for _, hh := range s.HeavyHitters(k) {
+		actual := exact[hh.Val]
+		if hh.Count < actual || hh.Count-hh.Err > actual {
+			t.Errorf("bad count for %g: %d (error %d), actual: %d\n",
+				hh.Val, hh.Count, hh.Err, actual)
+		}
+	}
+	testhelper.DiffInt(t, "HeavyHitters", "all", len(s.HeavyHitters(100)), k)
+
+	other := s.Clone()
+	if err := s.Merge(other); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	hhs = s.HeavyHitters(1)
+	testhelper.DiffFloat(t, "merged", "top value", hhs[0].Val, 512, 0)
+	testhelper.DiffInt(t, "merged", "top count", hhs[0].Count, 2*exact[512])
+	if err := s.heavy.check(); err != nil {
+		t.Error("merged: bad tracker:", err)
+	}
+
+	s.Reset()
+	testhelper.DiffInt(t, "reset", "count", len(s.HeavyHitters(k)), 0)
+	if NewStatOrPanic("bytes").HeavyHitters(3) != nil {
+		t.Error("expected no heavy hitters without the option")
+	}
+
+	_, err := NewStat("bytes", StatHeavyHitters(0))
+	testhelper.CheckExpErrWithID(t, "bad count", err,
+		testhelper.MkExpErr(
+			"Invalid heavy hitter count (0) - it must be >= 1 and <= 100000"))
+	_, err = NewStat("bytes", StatHeavyHitters(1), StatHeavyHitters(1))
+	testhelper.CheckExpErrWithID(t, "repeated", err,
+		testhelper.MkExpErr("heavy hitter tracking has already been set up"))
+}
@@ -0,0 +1,282 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+// hist2DAxis describes the buckets along one axis of a Hist2D
+type hist2DAxis struct {
+	units       string
+	bucketCount int
+	bucketStart float64
+	bucketWidth float64
+}
+
+// idx returns the index of the bucket holding the value and true or false
+// if the value lies outside the buckets
+func (a hist2DAxis) idx(v float64) (int, bool) {
+	i := math.Floor((v - a.bucketStart) / a.bucketWidth)
+	if i < 0 || i >= float64(a.bucketCount) {
+		return 0, false
+	}
+	return int(i), true
+}
+
+// end returns the upper limit of the last bucket
+func (a hist2DAxis) end() float64 {
+	return a.bucketStart + float64(a.bucketCount)*a.bucketWidth
+}
+
+// bounds returns the boundaries of the buckets, from the lower limit of the
+// first to the upper limit of the last
+func (a hist2DAxis) bounds() []float64 {
+	b := make([]float64, a.bucketCount+1)
+	for i := range b {
+		b[i] = a.bucketStart + float64(i)*a.bucketWidth
+	}
+	return b
+}
+
+// valFmt returns the format used to show the bucket boundaries and the
+// width of the formatted values
+func (a hist2DAxis) valFmt() (string, int) {
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		a.bucketStart, a.bucketWidth, a.end())
+	return fmt.Sprintf("%%%d.%df", width, precision), width
+}
+
+// describe returns a description of the buckets along the axis
+func (a hist2DAxis) describe(name string) string {
+	valFmt, _ := a.valFmt()
+	return fmt.Sprintf("%s (%s) from "+valFmt+" to "+valFmt+
+		" in %d buckets of "+valFmt,
+		name, a.units, a.bucketStart, a.end(), a.bucketCount, a.bucketWidth)
+}
+
+// xyPair holds a pair of values added to a Hist2D
+type xyPair struct {
+	x, y float64
+}
+
+// Hist2D counts pairs of values in a grid of buckets, the x value choosing
+// the column and the y value the row. This shows how two measurements of
+// the same events vary together, such as the latency and payload size of
+// requests, which a separate histogram of each would hide.
+//
+// As with a Stat the pairs are held in a cache until enough have been
+// added to choose the bucket boundaries; these cover the range of the
+// cached values. Pairs added later which lie outside that range are not
+// counted in the grid but in the number of pairs outside it.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type Hist2D struct {
+	x, y      hist2DAxis
+	cacheSize int
+
+	laidOut bool
+	cache   []xyPair
+
+	count   int
+	outside int
+	cells   [][]int // indexed by the x bucket and then the y bucket
+}
+
+// NewHist2D creates a new Hist2D with the given number of buckets along
+// each axis. The bucket boundaries are chosen once cacheSize pairs have
+// been added; if cacheSize is 0 a default size is used.
+func NewHist2D(
+	xUnits, yUnits string, xBuckets, yBuckets, cacheSize int,
+) (*Hist2D, error) {
+	for _, n := range []int{xBuckets, yBuckets} {
+		if n < minHistBucketCount {
+			return nil, invalidValue(
+				"Invalid histogram bucket count (%d) - it must be >= %d",
+				n, minHistBucketCount)
+		}
+	}
+	if cacheSize == 0 {
+		cacheSize = dfltKeyedCacheSize
+	}
+	if cacheSize < minKeyedCacheSize {
+		return nil, invalidValue("Invalid cache size (%d) - it must be >= %d",
+			cacheSize, minKeyedCacheSize)
+	}
+
+	return &Hist2D{
+		x:         hist2DAxis{units: xUnits, bucketCount: xBuckets},
+		y:         hist2DAxis{units: yUnits, bucketCount: yBuckets},
+		cacheSize: cacheSize,
+	}, nil
+}
+
+// NewHist2DOrPanic creates a new Hist2D and will panic if any errors are
+// detected
+func NewHist2DOrPanic(
+	xUnits, yUnits string, xBuckets, yBuckets, cacheSize int,
+) *Hist2D {
+	h, err := NewHist2D(xUnits, yUnits, xBuckets, yBuckets, cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Add adds the pair of values to the histogram. Pairs where either value
+// is NaN are ignored.
+func (h *Hist2D) Add(x, y float64) {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return
+	}
+	h.count++
+
+	if h.laidOut {
+		h.addToGrid(x, y)
+		return
+	}
+	h.cache = append(h.cache, xyPair{x: x, y: y})
+	if len(h.cache) >= h.cacheSize {
+		h.layout()
+	}
+}
+
+// addToGrid adds the pair to the cell of the grid holding it or to the
+// count of pairs outside the grid
+func (h *Hist2D) addToGrid(x, y float64) {
+	i, xOK := h.x.idx(x)
+	j, yOK := h.y.idx(y)
+	if !xOK || !yOK {
+		h.outside++
+		return
+	}
+	h.cells[i][j]++
+}
+
+// layout chooses the bucket boundaries from the cached pairs and then adds
+// them to the grid
+func (h *Hist2D) layout() {
+	xLo, xHi := math.Inf(1), math.Inf(-1)
+	yLo, yHi := math.Inf(1), math.Inf(-1)
+	for _, p := range h.cache {
+		xLo, xHi = math.Min(xLo, p.x), math.Max(xHi, p.x)
+		yLo, yHi = math.Min(yLo, p.y), math.Max(yHi, p.y)
+	}
+	h.x.bucketStart, h.x.bucketWidth = evenLayout(xLo, xHi, h.x.bucketCount)
+	h.y.bucketStart, h.y.bucketWidth = evenLayout(yLo, yHi, h.y.bucketCount)
+
+	h.cells = make([][]int, h.x.bucketCount)
+	for i := range h.cells {
+		h.cells[i] = make([]int, h.y.bucketCount)
+	}
+	for _, p := range h.cache {
+		h.addToGrid(p.x, p.y)
+	}
+	h.cache = nil
+	h.laidOut = true
+}
+
+// laidOutCopy returns a copy of the Hist2D with the bucket boundaries
+// chosen. If they have already been chosen the Hist2D is returned
+// unchanged, otherwise the copy is laid out from the cached pairs without
+// affecting the original.
+func (h Hist2D) laidOutCopy() Hist2D {
+	if h.laidOut || len(h.cache) == 0 {
+		return h
+	}
+	h.layout()
+	return h
+}
+
+// Reset discards all the pairs. The bucket boundaries are chosen again once
+// enough new pairs have been added.
+func (h *Hist2D) Reset() {
+	h.laidOut = false
+	h.cache = nil
+	h.count = 0
+	h.outside = 0
+	h.cells = nil
+}
+
+// Units returns the units of the x and y values
+func (h Hist2D) Units() (xUnits, yUnits string) {
+	return h.x.units, h.y.units
+}
+
+// Count returns the number of pairs added
+func (h Hist2D) Count() int {
+	return h.count
+}
+
+// Outside returns the number of pairs which lie outside the grid
+func (h Hist2D) Outside() int {
+	return h.laidOutCopy().outside
+}
+
+// Grid returns the counts of the pairs in each cell of the grid, indexed by
+// the x bucket and then the y bucket, together with the boundaries of the
+// buckets along each axis. The cell [i][j] counts the pairs with x between
+// xBounds[i] and xBounds[i+1] and y between yBounds[j] and yBounds[j+1].
+// The counts are a copy and may be changed freely. It returns nil values if
+// no pairs have been added.
+func (h Hist2D) Grid() (cells [][]int, xBounds, yBounds []float64) {
+	if h.count == 0 {
+		return nil, nil, nil
+	}
+	h = h.laidOutCopy()
+
+	cells = make([][]int, len(h.cells))
+	for i, col := range h.cells {
+		cells[i] = append([]int(nil), col...)
+	}
+	return cells, h.x.bounds(), h.y.bounds()
+}
+
+// String returns the histogram as a heat map with a row for each y bucket,
+// the highest first, and a column for each x bucket. Each cell is shaded
+// according to the number of pairs in it compared with the fullest cell,
+// from a space for an empty cell to a full block. Each row is labelled
+// with the lower limit of its y bucket and the buckets along each axis are
+// described under the grid. It returns the empty string if no pairs have
+// been added.
+func (h Hist2D) String() string {
+	if h.count == 0 {
+		return ""
+	}
+	h = h.laidOutCopy()
+
+	maxCount := 0
+	for _, col := range h.cells {
+		for _, c := range col {
+			maxCount = max(maxCount, c)
+		}
+	}
+
+	yFmt, yWidth := h.y.valFmt()
+	var b strings.Builder
+	for j := h.y.bucketCount - 1; j >= 0; j-- {
+		fmt.Fprintf(&b, yFmt+" |",
+			h.y.bucketStart+float64(j)*h.y.bucketWidth)
+		for i := range h.x.bucketCount {
+			cell := heatRunes[0]
+			if c := h.cells[i][j]; c > 0 {
+				level := int(math.Ceil(float64(c) /
+					float64(maxCount) * float64(len(heatRunes)-1)))
+				cell = heatRunes[min(level, len(heatRunes)-1)]
+			}
+			b.WriteRune(cell)
+		}
+		b.WriteString("|\n")
+	}
+
+	indent := strings.Repeat(" ", yWidth+1)
+	b.WriteString(indent + h.x.describe("x") + "\n")
+	b.WriteString(indent + h.y.describe("y") + "\n")
+	if h.outside > 0 {
+		fmt.Fprintf(&b, "%s%d outside the grid\n", indent, h.outside)
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package smpls
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestHist2D(t *testing.T) {
+	h := NewHist2DOrPanic("bytes", "ms", 5, 3, 10)
+	testhelper.DiffString(t, "empty", "String", h.String(), "")
+	cells, _, _ := h.Grid()
+	testhelper.DiffBool(t, "empty", "nil grid", cells == nil, true)
+
+	for i := range 10 {
+		h.Add(float64(i), float64(i%3))
+	}
+	h.Add(20, 1) // outside the grid
+	h.Add(1, 0)
+
+	testhelper.DiffInt(t, "Hist2D", "count", h.Count(), 12)
+	testhelper.DiffInt(t, "Hist2D", "outside", h.Outside(), 1)
+
+	cells, xBounds, yBounds := h.Grid()
+	testhelper.DiffInt(t, "Hist2D", "x bounds", len(xBounds), 6)
+	for i, exp := range []float64{0, 1.8, 3.6, 5.4, 7.2, 9} {
+		testhelper.DiffFloat(t, "Hist2D", "x bound", xBounds[i], exp, 1e-5)
+	}
+	testhelper.DiffInt(t, "Hist2D", "y bounds", len(yBounds), 4)
+	testhelper.DiffInt(t, "Hist2D", "cell [0][0]", cells[0][0], 2)
+	testhelper.DiffInt(t, "Hist2D", "cell [0][1]", cells[0][1], 1)
+	testhelper.DiffInt(t, "Hist2D", "cell [4][2]", cells[4][2], 1)
+
+	cells[0][0] = 99
+	cells, _, _ = h.Grid()
+	testhelper.DiffInt(t, "Hist2D", "copied cell", cells[0][0], 2)
+
+	testhelper.DiffString(t, "Hist2D", "String", h.String(),
+		"1.333 | ▒▒ ▒|\n"+
+			"0.667 |▒ ▒▒ |\n"+
+			"0.000 |█▒ ▒▒|\n"+
+			"      x (bytes) from 0.00 to 9.00 in 5 buckets of 1.80\n"+
+			"      y (ms) from 0.000 to 2.000 in 3 buckets of 0.667\n"+
+			"      1 outside the grid\n")
+
+	h.Add(math.NaN(), 0.5) // NaN values are ignored
+	testhelper.DiffInt(t, "NaN", "count", h.Count(), 12)
+
+	h.Reset()
+	testhelper.DiffInt(t, "Reset", "count", h.Count(), 0)
+	testhelper.DiffString(t, "Reset", "String", h.String(), "")
+}
+
+func TestHist2DCached(t *testing.T) {
+	h := NewHist2DOrPanic("x", "y", 2, 2, 0)
+	h.Add(0, 0)
+	h.Add(1, 1)
+
+	cells, _, _ := h.Grid()
+	testhelper.DiffInt(t, "cached", "cell [0][0]", cells[0][0], 1)
+	testhelper.DiffInt(t, "cached", "cell [1][1]", cells[1][1], 1)
+	testhelper.DiffBool(t, "cached", "still not laid out", h.laidOut, false)
+	testhelper.DiffInt(t, "cached", "cache", len(h.cache), 2)
+}
+
+func TestNewHist2D(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		xBuckets, yBuckets, cacheSize int
+	}{
+		{
+			ID:       testhelper.MkID("good"),
+			xBuckets: 5, yBuckets: 5,
+		},
+		{
+			ID: testhelper.MkID("bad x buckets"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid histogram bucket count (1) - it must be >= 2"),
+			xBuckets: 1, yBuckets: 5,
+		},
+		{
+			ID: testhelper.MkID("bad y buckets"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid histogram bucket count (0) - it must be >= 2"),
+			xBuckets: 5, yBuckets: 0,
+		},
+		{
+			ID: testhelper.MkID("bad cache size"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid cache size (-1) - it must be >= 1"),
+			xBuckets: 5, yBuckets: 5, cacheSize: -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewHist2D("x", "y", tc.xBuckets, tc.yBuckets, tc.cacheSize)
+		testhelper.CheckExpErr(t, err, tc)
+		if err != nil && !errors.Is(err, ErrInvalidValue) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the error should be an ErrInvalidValue")
+		}
+	}
+}
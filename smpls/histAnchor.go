@@ -0,0 +1,144 @@
+package smpls
+
+import (
+	"math"
+)
+
+// anchorSlack allows for rounding errors when finding which bucket of an
+// expanded histogram an old bucket falls in
+const anchorSlack = 1e-9
+
+// StatHistAnchor returns a function that will make the Stat anchor its
+// histogram at the given value. The anchor is always on a bucket boundary
+// and the histogram starts at the anchor or, if there are smaller values,
+// at the boundary below the smallest value. A value later added below the
+// start of the histogram does not go into the underflow; instead the
+// histogram is expanded downwards to hold it, the bucket width being
+// doubled as many times as needed to keep the same number of buckets.
+// Since the anchor stays on a boundary the existing bucket counts are
+// combined into the new buckets exactly.
+//
+// This is useful for signed data, such as clock drift, where the first
+// values seen may not show how low the values will go. An anchor of zero
+// also keeps the positive and negative values in separate buckets.
+//
+// This cannot be used with a log transform (see StatLogTransform), with
+// automatic rebinning (see StatHistAutoRebin) or with a quantile layout
+// (see StatHistQuantileLayout).
+func StatHistAnchor(anchor float64) StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if s.logBase != 0 {
+			return conflictingOptions(
+				"an anchored histogram cannot use a log transform")
+		}
+		if s.rebinFraction != 0 {
+			return conflictingOptions(
+				"an anchored histogram cannot be used with automatic rebinning")
+		}
+		if s.quantileLayout {
+			return conflictingOptions(
+				"an anchored histogram cannot have a quantile layout")
+		}
+		if math.IsNaN(anchor) || math.IsInf(anchor, 0) {
+			return invalidValue("Invalid histogram anchor (%g)"+
+				" - it must be a finite number", anchor)
+		}
+
+		s.anchor = anchor
+		s.anchored = true
+		return nil
+	}
+}
+
+// Anchor returns the value at which the histogram is anchored and true or
+// 0.0 and false if the histogram is not anchored (see StatHistAnchor)
+func (s Stat) Anchor() (float64, bool) {
+	return s.anchor, s.anchored
+}
+
+// setHistLayout sets the start and width of the histogram buckets so that
+// they cover the positions from lo to hi. If the histogram is anchored
+// the start is at or below the anchor and the anchor is on a bucket
+// boundary. If the Stat uses nice bucket boundaries (see
+// StatHistNiceBounds) they are then rounded.
+func (s *Stat) setHistLayout(lo, hi float64) {
+	s.setAnchoredLayout(lo, hi)
+	if s.niceBounds {
+		s.setNiceLayout(lo, hi)
+	}
+}
+
+// setAnchoredLayout sets the start and width of the histogram buckets so
+// that they cover the positions from lo to hi, keeping any anchor on a
+// bucket boundary
+func (s *Stat) setAnchoredLayout(lo, hi float64) {
+	bucketCount := float64(len(s.hist))
+	if !s.anchored || lo == s.anchor {
+		s.bucketStart = lo
+		s.bucketWidth = histBucketWidthScale * (hi - lo) / bucketCount
+		return
+	}
+
+	if lo > s.anchor {
+		s.bucketStart = s.anchor
+		s.bucketWidth = histBucketWidthScale * (hi - s.anchor) / bucketCount
+		return
+	}
+
+	// the start is moved down to a bucket boundary so one bucket is spare
+	s.bucketWidth = histBucketWidthScale * (hi - lo) / (bucketCount - 1)
+	if s.bucketWidth == 0 {
+		s.bucketStart = lo
+		return
+	}
+	s.bucketStart = s.anchor -
+		math.Ceil((s.anchor-lo)/s.bucketWidth)*s.bucketWidth
+}
+
+// expandDown expands an anchored histogram downwards so that it holds the
+// value, which must be below the start of the histogram. The histogram
+// keeps the same number of buckets and still covers all the values it
+// covered before.
+func (s *Stat) expandDown(v float64) {
+	n := float64(len(s.hist))
+	oldStart, oldWidth := s.bucketStart, s.bucketWidth
+	end := oldStart + n*oldWidth
+
+	width := oldWidth
+	newStart := s.anchor - math.Ceil((s.anchor-v)/width)*width
+	for newStart+n*width < end {
+		width *= 2
+		newStart = s.anchor - math.Ceil((s.anchor-v)/width)*width
+	}
+
+	s.makeRebinBufs()
+	newHist := s.rebinHist[:len(s.hist)]
+	clear(newHist)
+	newIdx := func(i int) int {
+		lo := oldStart + float64(i)*oldWidth
+		idx := int(math.Floor((lo-newStart)/width + anchorSlack))
+		return min(max(idx, 0), len(s.hist)-1)
+	}
+	for i, count := range s.hist {
+		if count > 0 {
+			newHist[newIdx(i)] += count
+		}
+	}
+	if s.histSums != nil {
+		newSums := s.rebinSums[:len(s.hist)]
+		clear(newSums)
+		for i, sum := range s.histSums {
+			if s.hist[i] > 0 {
+				newSums[newIdx(i)] += sum
+			}
+		}
+		copy(s.histSums, newSums)
+	}
+
+	s.bucketStart = newStart
+	s.bucketWidth = width
+	copy(s.hist, newHist)
+}
@@ -0,0 +1,163 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatHistAnchorOpts(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatHistAnchor(0)},
+		},
+		{
+			ID: testhelper.MkID("bad anchor"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid histogram anchor (NaN) - it must be a finite number"),
+			opts: []StatOpt{StatHistAnchor(math.NaN())},
+		},
+		{
+			ID:     testhelper.MkID("no histogram"),
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+			opts:   []StatOpt{StatNoHist(), StatHistAnchor(0)},
+		},
+		{
+			ID: testhelper.MkID("log then anchor"),
+			ExpErr: testhelper.MkExpErr(
+				"an anchored histogram cannot use a log transform"),
+			opts: []StatOpt{StatLogTransform(10), StatHistAnchor(0)},
+		},
+		{
+			ID: testhelper.MkID("anchor then log"),
+			ExpErr: testhelper.MkExpErr(
+				"an anchored histogram cannot use a log transform"),
+			opts: []StatOpt{StatHistAnchor(0), StatLogTransform(10)},
+		},
+		{
+			ID: testhelper.MkID("rebin then anchor"),
+			ExpErr: testhelper.MkExpErr("an anchored histogram" +
+				" cannot be used with automatic rebinning"),
+			opts: []StatOpt{StatHistAutoRebin(0.1), StatHistAnchor(0)},
+		},
+		{
+			ID: testhelper.MkID("anchor then rebin"),
+			ExpErr: testhelper.MkExpErr("an anchored histogram" +
+				" cannot be used with automatic rebinning"),
+			opts: []StatOpt{StatHistAnchor(0), StatHistAutoRebin(0.1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+// checkAnchored checks that the anchor is on a bucket boundary and that
+// each value is counted in the bucket that holds it
+func checkAnchored(t *testing.T, id string, s *Stat, vals []float64) {
+	t.Helper()
+
+	anchor, ok := s.Anchor()
+	testhelper.DiffBool(t, id, "anchored", ok, true)
+	steps := (anchor - s.bucketStart) / s.bucketWidth
+	testhelper.DiffFloat(t, id, "anchor offset (in buckets)",
+		steps, math.Round(steps), 1e-6)
+
+	expHist := make([]int, len(s.hist))
+	expUnderflow, expOverflow := 0, 0
+	for _, v := range vals {
+		switch idx := s.bucketIdx(v); {
+		case idx < 0:
+			expUnderflow++
+		case idx >= len(s.hist):
+			expOverflow++
+		default:
+			expHist[idx]++
+		}
+	}
+	testhelper.DiffInt(t, id, "underflow", s.underflow, expUnderflow)
+	testhelper.DiffInt(t, id, "overflow", s.overflow, expOverflow)
+	if err := testhelper.DiffVals(s.hist, expHist); err != nil {
+		t.Log(id)
+		t.Errorf("\t: bad histogram: %v\n", err)
+	}
+}
+
+func TestStatHistAnchor(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		anchor   float64
+		vals     []float64
+		later    []float64
+		expStart float64
+	}{
+		{
+			ID:       testhelper.MkID("positive values, anchored at 0"),
+			vals:     []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			later:    []float64{0.5, 3, 9.5},
+			expStart: 0,
+		},
+		{
+			ID:       testhelper.MkID("signed values, anchored at 0"),
+			vals:     []float64{-3, -2, -1, 0, 1, 2, 3, 4, 5, 6},
+			later:    []float64{-2.5, 0, 5.5},
+			expStart: -4.5,
+		},
+		{
+			ID:       testhelper.MkID("expanded down"),
+			vals:     []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			later:    []float64{-1, 5, -35, 9.9, -1000, 2},
+			expStart: -1024,
+		},
+		{
+			ID:       testhelper.MkID("anchored at 100"),
+			anchor:   100,
+			vals:     []float64{101, 102, 103, 104, 105, 106, 107, 108, 109, 110},
+			later:    []float64{99},
+			expStart: 96,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms", StatCacheSize(10),
+			StatHistBucketCount(5), StatHistAnchor(tc.anchor))
+		s.AddSlice(tc.vals)
+		s.AddSlice(tc.later)
+
+		id := tc.IDStr()
+		testhelper.DiffFloat(t, id, "start", s.bucketStart, tc.expStart, 0.05)
+		checkAnchored(t, id, s, append(tc.vals, tc.later...))
+	}
+
+	s := NewStatOrPanic("ms")
+	if _, ok := s.Anchor(); ok {
+		t.Error("a Stat without an anchor should not report one")
+	}
+}
+
+func TestStatHistAnchorMerge(t *testing.T) {
+	a := NewStatOrPanic("ms", StatCacheSize(10),
+		StatHistBucketCount(5), StatHistAnchor(0))
+	b := NewStatOrPanic("ms", StatCacheSize(10),
+		StatHistBucketCount(5), StatHistAnchor(0))
+	addSeq(a, 1, 1, 10)
+	addSeq(b, -30, 1, 10)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	anchor, _ := a.Anchor()
+	steps := (anchor - a.bucketStart) / a.bucketWidth
+	testhelper.DiffFloat(t, "merged", "anchor offset (in buckets)",
+		steps, math.Round(steps), 1e-6)
+	testhelper.DiffInt(t, "merged", "underflow", a.underflow, 0)
+	testhelper.DiffInt(t, "merged", "overflow", a.overflow, 0)
+}
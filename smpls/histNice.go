@@ -0,0 +1,95 @@
+package smpls
+
+import (
+	"math"
+)
+
+// niceMantissas are the leading digits of the nice bucket widths
+var niceMantissas = []float64{1, 2, 5, 10}
+
+// StatHistNiceBounds returns a function that will make the Stat choose
+// bucket boundaries that are easy to read. The bucket width is rounded up
+// to 1, 2 or 5 times a power of ten and the histogram starts at a multiple
+// of the width so, for instance, the buckets might start at 30, 35, 40 ...
+// rather than at 37.2841, 41.1075 .... The price is that the histogram
+// may cover a little more than the range of the values and so the buckets
+// at the ends may be less full.
+//
+// If the histogram is also anchored (see StatHistAnchor) the anchor stays
+// on a bucket boundary and the bucket width is still rounded but note that
+// when the histogram expands downwards the width is doubled and so may no
+// longer be a nice number.
+//
+// This cannot be used with a log transform (see StatLogTransform), with
+// automatic rebinning (see StatHistAutoRebin) or with a quantile layout
+// (see StatHistQuantileLayout).
+func StatHistNiceBounds() StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if s.logBase != 0 {
+			return conflictingOptions(
+				"nice bucket boundaries cannot be used with a log transform")
+		}
+		if s.rebinFraction != 0 {
+			return conflictingOptions(
+				"nice bucket boundaries cannot be used with automatic rebinning")
+		}
+		if s.quantileLayout {
+			return conflictingOptions(
+				"nice bucket boundaries cannot be used with a quantile layout")
+		}
+
+		s.niceBounds = true
+		return nil
+	}
+}
+
+// NiceBounds returns true if the histogram bucket boundaries are rounded to
+// nice numbers (see StatHistNiceBounds)
+func (s Stat) NiceBounds() bool {
+	return s.niceBounds
+}
+
+// niceAbove returns the smallest number of the form 1, 2 or 5 times a power
+// of ten which is not less than x. The value of x must be greater than 0.
+func niceAbove(x float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(x)))
+	for _, m := range niceMantissas {
+		if nice := m * p; nice >= x*(1-anchorSlack) {
+			return nice
+		}
+	}
+	return 10 * p
+}
+
+// setNiceLayout rounds the bucket width up to a nice number and moves the
+// start of the histogram down to a multiple of the width (or, if the
+// histogram is anchored, to a whole number of buckets below the anchor) so
+// that the buckets still cover the positions from lo to hi
+func (s *Stat) setNiceLayout(lo, hi float64) {
+	if !(s.bucketWidth > 0) || math.IsInf(s.bucketWidth, 0) {
+		return
+	}
+
+	n := float64(len(s.hist))
+	start := func(width float64) float64 {
+		if !s.anchored {
+			return math.Floor(lo/width) * width
+		}
+		if lo >= s.anchor {
+			return s.anchor
+		}
+		return s.anchor - math.Ceil((s.anchor-lo)/width)*width
+	}
+
+	width := niceAbove(s.bucketWidth)
+	for start(width)+n*width <= hi {
+		// the nice numbers are at least twice the size of the one before
+		width = niceAbove(width * 1.5)
+	}
+
+	s.bucketWidth = width
+	s.bucketStart = start(width)
+}
@@ -0,0 +1,95 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNiceAbove(t *testing.T) {
+	testCases := []struct {
+		x   float64
+		exp float64
+	}{
+		{x: 1, exp: 1},
+		{x: 1.01, exp: 2},
+		{x: 2, exp: 2},
+		{x: 3.7, exp: 5},
+		{x: 5.2, exp: 10},
+		{x: 37.2841, exp: 50},
+		{x: 0.0123, exp: 0.02},
+		{x: 0.3, exp: 0.5},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, fmt.Sprintf("niceAbove(%g)", tc.x), "value",
+			niceAbove(tc.x), tc.exp, 1e-12)
+	}
+}
+
+func TestStatHistNiceBounds(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts     []StatOpt
+		init     float64
+		incr     float64
+		expStart float64
+		expWidth float64
+	}{
+		{
+			ID:       testhelper.MkID("positive"),
+			init:     37.2841,
+			incr:     1.3,
+			expStart: 30,
+			expWidth: 10,
+		},
+		{
+			ID:       testhelper.MkID("small values"),
+			init:     0.0123,
+			incr:     0.0007,
+			expStart: 0.01,
+			expWidth: 0.005,
+		},
+		{
+			ID:       testhelper.MkID("negative"),
+			init:     -12.3,
+			incr:     0.2,
+			expStart: -13,
+			expWidth: 1,
+		},
+		{
+			ID:       testhelper.MkID("anchored"),
+			opts:     []StatOpt{StatHistAnchor(1)},
+			init:     3.3,
+			incr:     0.7,
+			expStart: 1,
+			expWidth: 5,
+		},
+	}
+
+	for _, tc := range testCases {
+		opts := append([]StatOpt{
+			StatCacheSize(20),
+			StatHistBucketCount(5),
+			StatHistNiceBounds(),
+		}, tc.opts...)
+		s := NewStatOrPanic("units", opts...)
+		addSeq(s, tc.init, tc.incr, 40)
+
+		testhelper.DiffBool(t, tc.IDStr(), "NiceBounds", s.NiceBounds(), true)
+		testhelper.DiffFloat(t, tc.IDStr(), "start",
+			s.bucketStart, tc.expStart, 1e-12)
+		testhelper.DiffFloat(t, tc.IDStr(), "width",
+			s.bucketWidth, tc.expWidth, 1e-12)
+
+		// all the values seen while the cache was in use are in the
+		// histogram
+		end := s.bucketStart + float64(len(s.hist))*s.bucketWidth
+		testhelper.DiffBool(t, tc.IDStr(), "covers the cached values",
+			s.bucketStart <= tc.init &&
+				end > tc.init+19*tc.incr &&
+				!math.IsNaN(end), true)
+	}
+}
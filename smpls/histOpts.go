@@ -0,0 +1,206 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+const (
+	dfltHistBarRune  = '*'
+	dfltHistBarWidth = 50
+)
+
+// HistOpts controls how the histogram is shown by the HistWithOpts
+// method. The zero value gives the same output as the Hist method.
+type HistOpts struct {
+	// BarRune is the character used to draw the bars. If it is not set
+	// then '*' is used.
+	BarRune rune
+	// MaxBarWidth is the length of the longest possible bar. If it is not
+	// set then a width of 50 is used.
+	MaxBarWidth int
+	// ScaleToMaxCount, if set, will scale the bars so that the bucket with
+	// the largest count has a bar of the maximum width. Otherwise the bars
+	// are scaled so that a bucket holding all the values would have a bar
+	// of the maximum width.
+	ScaleToMaxCount bool
+
+	// HidePct, if set, suppresses the percentage of the values in each
+	// bucket.
+	HidePct bool
+	// ShowCumulativePct, if set, will show the percentage of the values in
+	// each bucket and all the buckets before it.
+	ShowCumulativePct bool
+	// HideEmpty, if set, suppresses the buckets with no values.
+	HideEmpty bool
+	// ShowBucketMean, if set, will show the mean of the values in each
+	// bucket. It has no effect unless the Stat records the sum of the values
+	// in each bucket (see StatHistBucketSums).
+	ShowBucketMean bool
+
+	// CDF, if set, shows the cumulative distribution of the values rather
+	// than the histogram. Each line shows the number and percentage of the
+	// values less than a bucket boundary. The ScaleToMaxCount and
+	// ShowCumulativePct settings are ignored and HideEmpty suppresses the
+	// boundaries where the cumulative count is unchanged.
+	CDF bool
+
+	// Smooth, if set, shows a kernel density estimate of the distribution
+	// of the values (see Density) rather than the histogram, which gives a
+	// truer picture of the shape of the distribution when there are few
+	// values. There is a line for each histogram bucket showing the
+	// density at evenly spaced points, with the bars scaled so that the
+	// highest density has a bar of the maximum width. Only the BarRune and
+	// MaxBarWidth settings are used and it is ignored if CDF is set. Nothing
+	// is shown unless the values are all still held or the Stat keeps a
+	// random sample of them (see StatReservoirSize).
+	Smooth bool
+}
+
+// barRune returns the rune to use for the bars
+func (ho HistOpts) barRune() rune {
+	if ho.BarRune == 0 {
+		return dfltHistBarRune
+	}
+	return ho.BarRune
+}
+
+// maxBarWidth returns the width of the longest bar
+func (ho HistOpts) maxBarWidth() int {
+	if ho.MaxBarWidth <= 0 {
+		return dfltHistBarWidth
+	}
+	return ho.MaxBarWidth
+}
+
+// histLine holds the information needed to format a line of the histogram
+type histLine struct {
+	ho       HistOpts
+	countFmt string
+	total    int
+	barScale float64
+	cumCount int
+	meanFmt  string
+	noMean   string
+}
+
+// newHistLine creates and initialises a histLine
+func newHistLine(ho HistOpts, s Stat) *histLine {
+	maxCount := max(s.underflow, s.overflow)
+	for _, count := range s.hist {
+		maxCount = max(maxCount, count)
+	}
+	return newCountsLine(ho, s.count, maxCount)
+}
+
+// newCountsLine creates and initialises a histLine for showing counts out
+// of the given total, the largest of which is maxCount
+func newCountsLine(ho HistOpts, total, maxCount int) *histLine {
+	hl := &histLine{
+		ho:       ho,
+		countFmt: fmt.Sprintf("%%%dd", mathutil.Digits(int64(total))),
+		total:    total,
+	}
+
+	divisor := 100.0
+	if ho.ScaleToMaxCount {
+		divisor = 100.0 * float64(maxCount) / float64(total)
+	}
+	hl.barScale = float64(ho.maxBarWidth()) / divisor
+
+	return hl
+}
+
+// str returns a string holding the formatted value. The value is shown,
+// followed by the value as a percentage of the total, the cumulative
+// percentage, the mean of the values and a bar corresponding to the value
+func (hl *histLine) str(val int, sum float64) string {
+	hl.cumCount += val
+	pct := 100.0 * float64(val) / float64(hl.total)
+
+	rval := fmt.Sprintf(hl.countFmt, val)
+	if !hl.ho.HidePct {
+		rval += fmt.Sprintf(" %6.2f%%", pct)
+	}
+	if hl.ho.ShowCumulativePct {
+		rval += fmt.Sprintf(" %6.2f%%",
+			100.0*float64(hl.cumCount)/float64(hl.total))
+	}
+	if hl.meanFmt != "" {
+		mean := segMean(val, sum)
+		if math.IsNaN(mean) {
+			rval += " mean: " + hl.noMean
+		} else {
+			rval += fmt.Sprintf(" mean: "+hl.meanFmt, mean)
+		}
+	}
+	return rval + " " +
+		strings.Repeat(string(hl.ho.barRune()), int(pct*hl.barScale))
+}
+
+// HistWithOpts returns a string showing the histogram of values formatted
+// according to the HistOpts.
+func (s Stat) HistWithOpts(ho HistOpts) string {
+	if ho.CDF {
+		return s.cdfStr(ho)
+	}
+	if ho.Smooth {
+		return s.densityStr(ho)
+	}
+
+	s = s.histStat()
+
+	if s.hist == nil || s.count == 0 ||
+		(s.count < len(s.hist) && s.expRange == nil) {
+		return ""
+	}
+
+	hl := newHistLine(ho, s)
+
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		s.histStart(),
+		s.histWidth(),
+		end)
+	valFmt := fmt.Sprintf("%%%d.%df", width, precision)
+	valSpace := strings.Repeat(" ", width)
+	if ho.ShowBucketMean && s.histSums != nil {
+		hl.meanFmt = valFmt
+		hl.noMean = fmt.Sprintf("%*s", width, "-")
+	}
+	fromFmt := ">= " + valFmt
+	toFmt := "< " + valFmt
+
+	underflowFmt := valSpace + "      " + toFmt + ": %s\n"
+	overflowFmt := fromFmt + "     " + valSpace + ": %s\n"
+	stdFmt := fromFmt + " , " + toFmt + ": %s\n"
+
+	hist := "units: " + s.units + "\n"
+	if s.underflow > 0 || !ho.HideEmpty {
+		hist += fmt.Sprintf(underflowFmt, s.histStart(),
+			hl.str(s.underflow, s.underflowSum))
+	}
+	if s.tails != nil && s.tails.lowCount > 0 {
+		hist += "underflow tail: " + s.UnderflowTail().String() + "\n"
+	}
+
+	for i, count := range s.hist {
+		if count > 0 || !ho.HideEmpty {
+			minVal, maxVal := s.bucketLimits(i)
+			hist += fmt.Sprintf(stdFmt, minVal, maxVal,
+				hl.str(count, s.bucketSum(i)))
+		}
+	}
+
+	if s.overflow > 0 || !ho.HideEmpty {
+		hist += fmt.Sprintf(overflowFmt, end,
+			hl.str(s.overflow, s.overflowSum))
+	}
+	if s.tails != nil && s.tails.highCount > 0 {
+		hist += "overflow tail: " + s.OverflowTail().String() + "\n"
+	}
+	return hist
+}
@@ -0,0 +1,77 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestHistWithOpts(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		ho     HistOpts
+		expVal string
+	}{
+		{
+			ID: testhelper.MkID("default"),
+			expVal: "units: ms\n" +
+				"          < 0.00:  0   0.00% \n" +
+				">= 0.00 , < 3.00:  7  70.00% " +
+				"***********************************\n" +
+				">= 3.00 , < 6.00:  2  20.00% **********\n" +
+				">= 6.00 , < 9.00:  1  10.00% *****\n" +
+				">= 9.00         :  0   0.00% \n",
+		},
+		{
+			ID: testhelper.MkID("bar rune and width, cumulative pct"),
+			ho: HistOpts{
+				BarRune:           '#',
+				MaxBarWidth:       10,
+				ShowCumulativePct: true,
+			},
+			expVal: "units: ms\n" +
+				"          < 0.00:  0   0.00%   0.00% \n" +
+				">= 0.00 , < 3.00:  7  70.00%  70.00% #######\n" +
+				">= 3.00 , < 6.00:  2  20.00%  90.00% ##\n" +
+				">= 6.00 , < 9.00:  1  10.00% 100.00% #\n" +
+				">= 9.00         :  0   0.00% 100.00% \n",
+		},
+		{
+			ID: testhelper.MkID("hide empty and pct, scale to max"),
+			ho: HistOpts{
+				MaxBarWidth:     7,
+				ScaleToMaxCount: true,
+				HidePct:         true,
+				HideEmpty:       true,
+			},
+			expVal: "units: ms\n" +
+				">= 0.00 , < 3.00:  7 *******\n" +
+				">= 3.00 , < 6.00:  2 **\n" +
+				">= 6.00 , < 9.00:  1 *\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms", StatHistBucketCount(3))
+		s.Add(0, 1, 2, 3, 3, 3, 3, 4, 5, 9)
+
+		testhelper.DiffString(t, tc.IDStr(), "hist",
+			s.HistWithOpts(tc.ho), tc.expVal)
+	}
+}
+
+func TestHistNoSideEffects(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistBucketCount(2))
+	s.Add(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	first := s.Hist()
+	second := s.Hist()
+	testhelper.DiffString(t, "Hist called twice", "hist", second, first)
+	for _, count := range s.hist {
+		testhelper.DiffInt(t, "Hist called with a live cache",
+			"hist count", count, 0)
+	}
+
+	testhelper.DiffString(t, "empty Stat", "hist",
+		NewStatOrPanic("ms").Hist(), "")
+}
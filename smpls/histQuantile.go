@@ -0,0 +1,163 @@
+package smpls
+
+import (
+	"cmp"
+	"slices"
+)
+
+// StatHistQuantileLayout returns a function that will make the Stat lay
+// out its histogram with the bucket boundaries at the quantiles of the
+// values held when the histogram is laid out, rather than spreading the
+// buckets evenly between the smallest and largest values. Each bucket
+// then holds about the same number of those values; the buckets are
+// narrow where the values are dense and wide where they are sparse. This
+// gives a far more informative histogram for skewed data, where an even
+// layout puts most of the values into the first few buckets.
+//
+// If many of the values are the same there may be fewer distinct
+// quantiles than buckets, in which case fewer buckets are used. If all the
+// values are the same the buckets are laid out evenly.
+//
+// This cannot be used with a log transform (see StatLogTransform), an
+// anchored histogram (see StatHistAnchor) or automatic rebinning (see
+// StatHistAutoRebin).
+func StatHistQuantileLayout() StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if s.logBase != 0 {
+			return conflictingOptions(
+				"a quantile layout cannot be used with a log transform")
+		}
+		if s.anchored {
+			return conflictingOptions(
+				"an anchored histogram cannot have a quantile layout")
+		}
+		if s.rebinFraction != 0 {
+			return conflictingOptions(
+				"a quantile layout cannot be used with automatic rebinning")
+		}
+
+		s.quantileLayout = true
+		return nil
+	}
+}
+
+// HasQuantileLayout returns true if the Stat lays out its histogram at the
+// quantiles of the values (see StatHistQuantileLayout)
+func (s Stat) HasQuantileLayout() bool {
+	return s.quantileLayout
+}
+
+// quantiled returns true if the histogram has been laid out at quantiles
+// and so the positions on the scale of the histogram are given by the
+// knots
+func (s Stat) quantiled() bool {
+	return len(s.knots) > 0
+}
+
+// knotPos returns the position of the value on a scale on which the knots
+// are at positions 0, 1, 2 and so on. The position is interpolated
+// linearly between the knots and extrapolated from the first and last
+// pairs of knots.
+func (s Stat) knotPos(v float64) float64 {
+	i, found := slices.BinarySearch(s.knots, v)
+	if found {
+		return float64(i)
+	}
+	i = min(max(i-1, 0), len(s.knots)-2)
+	lo, hi := s.knots[i], s.knots[i+1]
+	return float64(i) + (v-lo)/(hi-lo)
+}
+
+// knotVal returns the value at the given position on the scale of the
+// knots. It is the inverse of knotPos.
+func (s Stat) knotVal(pos float64) float64 {
+	i := min(max(int(pos), 0), len(s.knots)-2)
+	lo, hi := s.knots[i], s.knots[i+1]
+	return lo + (pos-float64(i))*(hi-lo)
+}
+
+// setKnots sets the histogram buckets to lie between the knots, which
+// must be in ascending order, discarding any repeated knots. The last
+// knot is moved up slightly so that the largest value lies within the
+// last bucket. If fewer than two distinct knots are given the histogram is
+// laid out evenly instead.
+func (s *Stat) setKnots(knots []float64) {
+	s.knots = s.knots[:0]
+	for _, k := range knots {
+		if len(s.knots) == 0 || k > s.knots[len(s.knots)-1] {
+			s.knots = append(s.knots, k)
+		}
+	}
+	if len(s.knots) < minHistBucketCount {
+		s.knots = s.knots[:0]
+		s.setHistLayout(s.histRange())
+		return
+	}
+
+	n := len(s.knots)
+	lo, hi := s.knots[n-2], s.knots[n-1]
+	s.knots[n-1] = lo + histBucketWidthScale*(hi-lo)
+	s.hist = s.hist[:n-1]
+	if s.histSums != nil {
+		s.histSums = s.histSums[:len(s.hist)]
+	}
+	s.bucketStart = 0
+	s.bucketWidth = 1
+}
+
+// setQuantileLayout lays out the histogram with the bucket boundaries at
+// the quantiles of the values
+func (s *Stat) setQuantileLayout(vals []float64) {
+	if len(vals) == 0 {
+		s.setKnots(nil)
+		return
+	}
+
+	sorted := sortedCopy(vals)
+	n := len(s.hist)
+	knots := make([]float64, 0, n+1)
+	for i := range n + 1 {
+		knots = append(knots,
+			sortedPercentile(sorted, 100*float64(i)/float64(n)))
+	}
+	s.setKnots(knots)
+}
+
+// segmentKnots returns the values at n+1 evenly spaced quantiles of the
+// values in the segments, the values in each segment being taken to be
+// evenly spread across it. The knots are in ascending order but may be
+// repeated.
+func segmentKnots(segs []histSegment, n int) []float64 {
+	segs = slices.Clone(segs)
+	slices.SortFunc(segs, func(a, b histSegment) int {
+		return cmp.Compare(a.lo, b.lo)
+	})
+	total := 0
+	for _, seg := range segs {
+		total += seg.count
+	}
+
+	knots := make([]float64, 0, n+1)
+	cum, i := 0, 0
+	for k := range n + 1 {
+		target := float64(total) * float64(k) / float64(n)
+		for i < len(segs)-1 && float64(cum+segs[i].count) < target {
+			cum += segs[i].count
+			i++
+		}
+		seg := segs[i]
+		v := seg.hi
+		if seg.count > 0 {
+			frac := min(1, (target-float64(cum))/float64(seg.count))
+			v = seg.lo + frac*(seg.hi-seg.lo)
+		}
+		if len(knots) > 0 {
+			v = max(v, knots[len(knots)-1])
+		}
+		knots = append(knots, v)
+	}
+	return knots
+}
@@ -0,0 +1,175 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatHistQuantileLayout(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatHistQuantileLayout()},
+		},
+		{
+			ID:     testhelper.MkID("no hist"),
+			opts:   []StatOpt{StatNoHist(), StatHistQuantileLayout()},
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+		},
+		{
+			ID:   testhelper.MkID("log transform first"),
+			opts: []StatOpt{StatLogTransform(10), StatHistQuantileLayout()},
+			ExpErr: testhelper.MkExpErr(
+				"a quantile layout cannot be used with a log transform"),
+		},
+		{
+			ID:   testhelper.MkID("log transform second"),
+			opts: []StatOpt{StatHistQuantileLayout(), StatLogTransform(10)},
+			ExpErr: testhelper.MkExpErr(
+				"a quantile layout cannot be used with a log transform"),
+		},
+		{
+			ID:   testhelper.MkID("anchored first"),
+			opts: []StatOpt{StatHistAnchor(0), StatHistQuantileLayout()},
+			ExpErr: testhelper.MkExpErr(
+				"an anchored histogram cannot have a quantile layout"),
+		},
+		{
+			ID:   testhelper.MkID("anchored second"),
+			opts: []StatOpt{StatHistQuantileLayout(), StatHistAnchor(0)},
+			ExpErr: testhelper.MkExpErr(
+				"an anchored histogram cannot have a quantile layout"),
+		},
+		{
+			ID:   testhelper.MkID("auto rebin first"),
+			opts: []StatOpt{StatHistAutoRebin(0.1), StatHistQuantileLayout()},
+			ExpErr: testhelper.MkExpErr(
+				"a quantile layout cannot be used with automatic rebinning"),
+		},
+		{
+			ID:   testhelper.MkID("auto rebin second"),
+			opts: []StatOpt{StatHistQuantileLayout(), StatHistAutoRebin(0.1)},
+			ExpErr: testhelper.MkExpErr(
+				"a quantile layout cannot be used with automatic rebinning"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+// addSkewed adds n values which are heavily skewed towards zero
+func addSkewed(s *Stat, n int) {
+	for i := range n {
+		x := float64(i) / float64(n)
+		s.Add(x * x * x * 1000)
+	}
+}
+
+// checkBuckets checks that the histogram has the expected number of
+// buckets, that their boundaries are ascending and that each holds about
+// the same number of values
+func checkBuckets(t *testing.T, id string, s *Stat, expBuckets, perBucket int) {
+	t.Helper()
+
+	testhelper.DiffInt(t, id, "bucket count", s.BucketCount(), expBuckets)
+	prevHigh := s.Min()
+	s.ForEachBucket(func(lo, hi float64, count int) bool {
+		if lo != prevHigh || !(hi > lo) {
+			t.Log(id)
+			t.Errorf("\t: bad bucket [%g, %g) after %g\n", lo, hi, prevHigh)
+		}
+		prevHigh = hi
+		testhelper.DiffInt(t, id, "bucket count",
+			min(max(count, perBucket-1), perBucket+1), count)
+		return true
+	})
+	testhelper.DiffInt(t, id, "underflow", s.Underflow(), 0)
+	testhelper.DiffInt(t, id, "overflow", s.Overflow(), 0)
+}
+
+func TestHistQuantileLayout(t *testing.T) {
+	s := NewStatOrPanic("units",
+		StatCacheSize(1000),
+		StatHistBucketCount(10),
+		StatHistQuantileLayout())
+	testhelper.DiffBool(t, "quantile layout", "HasQuantileLayout",
+		s.HasQuantileLayout(), true)
+	addSkewed(s, 1000)
+	checkBuckets(t, "skewed", s, 10, 100)
+
+	even := NewStatOrPanic("units",
+		StatCacheSize(1000), StatHistBucketCount(10))
+	addSkewed(even, 1000)
+	firstCount := 0
+	even.ForEachBucket(func(_, _ float64, count int) bool {
+		firstCount = count
+		return false
+	})
+	if firstCount < 400 {
+		t.Errorf("an even layout should put most of the skewed values"+
+			" in the first bucket, it has %d", firstCount)
+	}
+
+	s.Reset()
+	addSkewed(s, 1000)
+	checkBuckets(t, "after Reset", s, 10, 100)
+
+	same := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketCount(10),
+		StatHistQuantileLayout())
+	for range 100 {
+		same.Add(42)
+	}
+	testhelper.DiffInt(t, "all the same", "bucket count",
+		same.BucketCount(), 10)
+	testhelper.DiffBool(t, "all the same", "quantiled",
+		same.quantiled(), false)
+
+	ties := NewStatOrPanic("units",
+		StatCacheSize(100),
+		StatHistBucketCount(10),
+		StatHistQuantileLayout())
+	for i := range 100 {
+		ties.Add(float64(i % 4))
+	}
+	if n := ties.BucketCount(); n >= 10 || n < minHistBucketCount {
+		t.Errorf("with only 4 distinct values there should be fewer"+
+			" buckets, there are %d", n)
+	}
+	testhelper.DiffInt(t, "ties", "histogram total", histTotal(ties), 100)
+}
+
+func TestHistQuantileLayoutMerge(t *testing.T) {
+	opts := []StatOpt{
+		StatCacheSize(200),
+		StatHistBucketCount(10),
+		StatHistQuantileLayout(),
+	}
+	a := NewStatOrPanic("units", opts...)
+	addSkewed(a, 200)
+	b := NewStatOrPanic("units", opts...)
+	addSeq(b, 500, 2.5, 200)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("unexpected error merging: %v", err)
+	}
+	testhelper.DiffInt(t, "merged", "count", a.Count(), 400)
+	testhelper.DiffInt(t, "merged", "histogram total", histTotal(a), 400)
+	testhelper.DiffBool(t, "merged", "quantiled", a.quantiled(), true)
+	testhelper.DiffInt(t, "merged", "underflow", a.Underflow(), 0)
+	testhelper.DiffInt(t, "merged", "overflow", a.Overflow(), 0)
+	for i := 1; i < len(a.knots); i++ {
+		if !(a.knots[i] > a.knots[i-1]) {
+			t.Fatalf("the knots are not ascending: %v", a.knots)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package smpls
+
+import (
+	"math"
+)
+
+// StatHistAutoRebin returns a function that will make the Stat rebuild
+// its histogram whenever the proportion of values in the underflow and
+// overflow exceeds the given fraction. The bucket width is doubled (as
+// many times as needed) and the range extended so that the histogram
+// covers all the values seen so far. The existing bucket counts are
+// combined into the new buckets exactly but, since the values in the
+// underflow and overflow are not recorded, their counts are spread evenly
+// between the old ends of the histogram and the minimum and maximum values.
+func StatHistAutoRebin(fraction float64) StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if s.logBase != 0 {
+			return conflictingOptions(
+				"a log transform cannot be used with automatic rebinning")
+		}
+		if s.anchored {
+			return conflictingOptions(
+				"an anchored histogram cannot be used with automatic rebinning")
+		}
+		if s.quantileLayout {
+			return conflictingOptions(
+				"a quantile layout cannot be used with automatic rebinning")
+		}
+		if fraction <= 0 || fraction >= 1 {
+			return invalidValue(
+				"Invalid rebin fraction (%g) - it must be > 0 and < 1",
+				fraction)
+		}
+
+		s.rebinFraction = fraction
+		return nil
+	}
+}
+
+// makeRebinBufs makes the buffers used when rebinning the histogram, if
+// they are not already big enough, so that rebinning does not allocate
+// memory
+func (s *Stat) makeRebinBufs() {
+	if len(s.rebinHist) < len(s.hist) {
+		s.rebinHist = make([]int, len(s.hist))
+	}
+	if s.bucketSums && len(s.rebinSums) < len(s.hist) {
+		s.rebinSums = make([]float64, len(s.hist))
+	}
+}
+
+// checkRebin rebins the histogram if too many values lie outside it
+func (s *Stat) checkRebin() {
+	outside := s.underflow + s.overflow
+	if float64(outside) > s.rebinFraction*float64(s.count) {
+		s.rebin()
+	}
+}
+
+// rebin rebuilds the histogram so that it covers all the values seen so
+// far. The new bucket width is the old width multiplied by a power of two
+// and the new start is a whole number of old buckets away from the old
+// start so that every old bucket lies entirely within a new bucket. Empty
+// buckets may be dropped from either end.
+func (s *Stat) rebin() {
+	if s.bucketWidth <= 0 {
+		return
+	}
+
+	n := len(s.hist)
+	oldStart, oldWidth := s.bucketStart, s.bucketWidth
+	_, oldEnd := s.bucketLimits(n - 1)
+	lo, hi := s.Min(), s.Max()
+
+	shift := int(math.Ceil((oldStart - lo) / oldWidth))
+	newStart := oldStart - float64(shift)*oldWidth
+	scale := 1
+	for newStart+float64(scale*n)*oldWidth <= hi {
+		scale *= 2
+	}
+
+	s.makeRebinBufs()
+	newHist := s.rebinHist[:n]
+	clear(newHist)
+	for i, count := range s.hist {
+		if count > 0 {
+			newHist[(i+shift)/scale] += count
+		}
+	}
+	if s.histSums != nil {
+		newSums := s.rebinSums[:n]
+		clear(newSums)
+		for i, sum := range s.histSums {
+			if s.hist[i] > 0 {
+				newSums[(i+shift)/scale] += sum
+			}
+		}
+		copy(s.histSums, newSums)
+	}
+
+	s.bucketStart = newStart
+	s.bucketWidth = oldWidth * float64(scale)
+	copy(s.hist, newHist)
+
+	s.spreadCount(s.underflow, s.underflowSum, lo, oldStart)
+	s.spreadCount(s.overflow, s.overflowSum, oldEnd, hi)
+	s.underflow = 0
+	s.overflow = 0
+	if s.tails != nil {
+		s.tails.reset()
+	}
+	s.underflowSum = 0
+	s.overflowSum = 0
+}
+
+// spreadCount adds the count to the histogram buckets spreading it evenly
+// over the range from lo to hi. The counts are rounded so that the total
+// added is exactly the count. If the Stat records the sum of the values in
+// each bucket the sum is shared out in proportion to the counts.
+func (s *Stat) spreadCount(count int, sum, lo, hi float64) {
+	if count == 0 {
+		return
+	}
+	if hi <= lo {
+		idx := min(max(int((lo-s.bucketStart)/s.bucketWidth), 0),
+			len(s.hist)-1)
+		s.addToBucket(idx, count, sum)
+		return
+	}
+	meanVal := sum / float64(count)
+
+	added := 0
+	for i := range s.hist {
+		_, bHi := s.bucketLimits(i)
+		covered := math.Min(bHi, hi) - lo
+		if covered <= 0 {
+			continue
+		}
+		target := int(math.Round(
+			float64(count) * math.Min(1, covered/(hi-lo))))
+		s.addToBucket(i, target-added, meanVal*float64(target-added))
+		added = target
+		if added == count {
+			return
+		}
+	}
+	s.addToBucket(len(s.hist)-1, count-added, meanVal*float64(count-added))
+}
+
+// addToBucket adds the count and, if the Stat records the sum of the values
+// in each bucket, the sum to the i'th histogram bucket
+func (s *Stat) addToBucket(i, count int, sum float64) {
+	s.hist[i] += count
+	if s.histSums != nil {
+		s.histSums[i] += sum
+	}
+}
@@ -0,0 +1,114 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// histTotal returns the total of the histogram counts including the
+// underflow and overflow
+func histTotal(s *Stat) int {
+	total := s.underflow + s.overflow
+	for _, count := range s.hist {
+		total += count
+	}
+	return total
+}
+
+func TestHistAutoRebin(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		init           float64
+		incr           float64
+		count          int
+		expBucketStart float64
+		expWidthScale  float64
+	}{
+		{
+			ID:             testhelper.MkID("no rebin needed"),
+			init:           10,
+			incr:           0.5,
+			count:          100,
+			expBucketStart: 0,
+			expWidthScale:  1,
+		},
+		{
+			ID:             testhelper.MkID("overflow"),
+			init:           100,
+			incr:           1,
+			count:          100,
+			expBucketStart: 0,
+			expWidthScale:  2,
+		},
+		{
+			ID:             testhelper.MkID("far overflow"),
+			init:           1000,
+			incr:           1,
+			count:          100,
+			expBucketStart: 0,
+			expWidthScale:  16,
+		},
+		{
+			ID:             testhelper.MkID("underflow"),
+			init:           -1,
+			incr:           -1,
+			count:          100,
+			expBucketStart: -9.9 * 1.000001 * 10,
+			expWidthScale:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units",
+			StatCacheSize(100),
+			StatHistBucketCount(10),
+			StatHistAutoRebin(0.1))
+		addSeq(s, 0, 1, 100)
+		width := s.bucketWidth
+		addSeq(s, tc.init, tc.incr, tc.count)
+
+		id := tc.IDStr()
+		testhelper.DiffInt(t, id, "histogram total", histTotal(s), s.count)
+		testhelper.DiffFloat(t, id, "bucket start",
+			s.bucketStart, tc.expBucketStart, 0.00001)
+		testhelper.DiffFloat(t, id, "bucket width",
+			s.bucketWidth, width*tc.expWidthScale, 0.00001)
+		if float64(s.underflow+s.overflow) > 0.1*float64(s.count) {
+			t.Log(id)
+			t.Errorf("\t: too many values outside the histogram: %d, %d\n",
+				s.underflow, s.overflow)
+		}
+	}
+}
+
+func TestHistAutoRebinErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("fraction too small"),
+			opts: []StatOpt{StatHistAutoRebin(0)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid rebin fraction (0) - it must be > 0 and < 1"),
+		},
+		{
+			ID:   testhelper.MkID("fraction too big"),
+			opts: []StatOpt{StatHistAutoRebin(1)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid rebin fraction (1) - it must be > 0 and < 1"),
+		},
+		{
+			ID:     testhelper.MkID("no histogram"),
+			opts:   []StatOpt{StatNoHist(), StatHistAutoRebin(0.5)},
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
@@ -0,0 +1,141 @@
+package smpls
+
+import (
+	"math"
+)
+
+// StatLogTransform returns a function that will make the Stat lay out its
+// histogram on a logarithmic scale with the given base; pass 10 for log10
+// or math.E for the natural log. The buckets are of equal width in the
+// logarithms of the values so that data with a heavy tail gives a readable
+// histogram. Only the bucketing is affected; the mean, standard deviation
+// and other values are calculated from the values themselves, and the
+// bucket boundaries are reported as values, not as their logarithms.
+//
+// Values which are zero or negative have no logarithm and are always
+// counted in the histogram underflow.
+//
+// This cannot be used with automatic rebinning (see StatHistAutoRebin) or
+// with a quantile layout (see StatHistQuantileLayout).
+func StatLogTransform(base float64) StatOpt {
+	return func(s *Stat) error {
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if s.rebinFraction != 0 {
+			return conflictingOptions(
+				"a log transform cannot be used with automatic rebinning")
+		}
+		if s.anchored {
+			return conflictingOptions(
+				"an anchored histogram cannot use a log transform")
+		}
+		if s.quantileLayout {
+			return conflictingOptions(
+				"a quantile layout cannot be used with a log transform")
+		}
+		if !(base > 1) || math.IsInf(base, 1) {
+			return invalidValue("Invalid log base (%g) - it must be > 1", base)
+		}
+
+		s.logBase = base
+		return nil
+	}
+}
+
+// LogBase returns the base of the logarithmic scale of the histogram or 0
+// if the histogram has a linear scale (see StatLogTransform)
+func (s Stat) LogBase() float64 {
+	return s.logBase
+}
+
+// histPos returns the position of the value on the scale of the histogram.
+// For a logarithmic scale this is the logarithm of the value or -Inf if
+// the value is not positive. For a histogram laid out at quantiles (see
+// StatHistQuantileLayout) the bucket boundaries are at positions 0, 1, 2
+// and so on.
+func (s Stat) histPos(v float64) float64 {
+	if s.quantiled() {
+		return s.knotPos(v)
+	}
+	if s.logBase == 0 {
+		return v
+	}
+	if v <= 0 {
+		return math.Inf(-1)
+	}
+	return math.Log(v) / math.Log(s.logBase)
+}
+
+// histVal returns the value at the given position on the scale of the
+// histogram. It is the inverse of histPos.
+func (s Stat) histVal(pos float64) float64 {
+	if s.quantiled() {
+		return s.knotVal(pos)
+	}
+	if s.logBase == 0 {
+		return pos
+	}
+	return math.Pow(s.logBase, pos)
+}
+
+// histStart returns the value at the start of the histogram
+func (s Stat) histStart() float64 {
+	return s.histVal(s.bucketStart)
+}
+
+// histWidth returns the width of the first histogram bucket. This is the
+// narrowest bucket.
+func (s Stat) histWidth() float64 {
+	if s.quantiled() {
+		width := s.knots[1] - s.knots[0]
+		for i := 2; i < len(s.knots); i++ {
+			width = min(width, s.knots[i]-s.knots[i-1])
+		}
+		return width
+	}
+	if s.logBase == 0 {
+		return s.bucketWidth
+	}
+	lo, hi := s.bucketLimits(0)
+	return hi - lo
+}
+
+// bucketIdx returns the index of the histogram bucket holding the value.
+// The index is negative if the value is below the start of the histogram
+// and is at least the number of buckets if it is above the end.
+func (s Stat) bucketIdx(v float64) int {
+	pos := (s.histPos(v) - s.bucketStart) / s.bucketWidth
+	switch {
+	case pos < 0:
+		return -1
+	case pos >= float64(len(s.hist)):
+		return len(s.hist)
+	}
+	return int(pos)
+}
+
+// histRange returns the positions on the scale of the histogram between
+// which the histogram should be laid out. For a linear scale this is the
+// range of the values held in the slices of minimum and maximum values;
+// for a logarithmic scale any values which are not positive are ignored
+// and if there are no positive values the range covers the values from 1
+// to the log base.
+func (s Stat) histRange() (lo, hi float64) {
+	if s.logBase == 0 {
+		return s.mins[0], s.maxs[len(s.maxs)-1]
+	}
+
+	maxVal := s.maxs[len(s.maxs)-1]
+	if maxVal <= 0 {
+		return 0, 1
+	}
+	minVal := maxVal
+	for _, v := range s.mins {
+		if v > 0 {
+			minVal = v
+			break
+		}
+	}
+	return s.histPos(minVal), s.histPos(maxVal)
+}
@@ -0,0 +1,127 @@
+package smpls
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatLogTransformOpts(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatLogTransform(math.E)},
+		},
+		{
+			ID:     testhelper.MkID("bad base"),
+			ExpErr: testhelper.MkExpErr("Invalid log base (1) - it must be > 1"),
+			opts:   []StatOpt{StatLogTransform(1)},
+		},
+		{
+			ID:     testhelper.MkID("no histogram"),
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+			opts:   []StatOpt{StatNoHist(), StatLogTransform(10)},
+		},
+		{
+			ID: testhelper.MkID("rebin then log"),
+			ExpErr: testhelper.MkExpErr(
+				"a log transform cannot be used with automatic rebinning"),
+			opts: []StatOpt{StatHistAutoRebin(0.1), StatLogTransform(10)},
+		},
+		{
+			ID: testhelper.MkID("log then rebin"),
+			ExpErr: testhelper.MkExpErr(
+				"a log transform cannot be used with automatic rebinning"),
+			opts: []StatOpt{StatLogTransform(10), StatHistAutoRebin(0.1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+// addLogUniform adds n values spread evenly in their logarithms from 1 to
+// 10^decades
+func addLogUniform(s *Stat, n int, decades float64) {
+	for i := 0; i < n; i++ {
+		s.Add(math.Pow(10, decades*float64(i)/float64(n-1)))
+	}
+}
+
+func TestStatLogTransform(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(1000),
+		StatHistBucketCount(12), StatLogTransform(10))
+	addLogUniform(s, 1200, 6)
+	testhelper.DiffFloat(t, "log", "LogBase", s.LogBase(), 10, 0)
+
+	// the buckets are of equal width in the logarithms of the values
+	lo0, hi0 := s.bucketLimits(0)
+	testhelper.DiffFloat(t, "log", "start", lo0, 1, 1e-9)
+	for i := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		testhelper.DiffFloat(t, "log", "bucket ratio", hi/lo, hi0/lo0, 1e-6)
+		if s.hist[i] < 80 || s.hist[i] > 120 {
+			t.Errorf("bucket %d: unexpected count: %d", i, s.hist[i])
+		}
+	}
+
+	// the mean is that of the values, not of their logarithms
+	var sum float64
+	for i := 0; i < 1200; i++ {
+		sum += math.Pow(10, 6*float64(i)/1199)
+	}
+	testhelper.DiffFloat(t, "log", "mean", s.Mean(), sum/1200, 1e-6)
+	testhelper.DiffFloat(t, "log", "hist median",
+		s.HistPercentile(50), 1000, 100)
+
+	s.Add(0, -5)
+	testhelper.DiffInt(t, "log", "underflow", s.underflow, 2)
+
+	hs := NewStatOrPanic("ms", StatHistBucketCount(12))
+	err := hs.Merge(s)
+	testhelper.CheckExpErrWithID(t, "merge different bases", err,
+		testhelper.MkExpErr("cannot merge Stats with different"+
+			" histogram log bases: 0 and 10"))
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal("Couldn't load the Stat:", err)
+	}
+	testhelper.DiffFloat(t, "loaded", "LogBase", loaded.LogBase(), 10, 0)
+	testhelper.DiffFloatSlice(t, "loaded", "CDF",
+		cdfFractions(loaded.CDF()), cdfFractions(s.CDF()), 0)
+}
+
+// cdfFractions returns the fractions from the CDF
+func cdfFractions(cdf []CDFPoint) []float64 {
+	fractions := make([]float64, 0, len(cdf))
+	for _, p := range cdf {
+		fractions = append(fractions, p.Fraction)
+	}
+	return fractions
+}
+
+func TestStatLogTransformNonPositive(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatLogTransform(10))
+	for i := 0; i < 10; i++ {
+		s.Add(float64(i - 5))
+	}
+	testhelper.DiffInt(t, "non-positive", "underflow", s.underflow, 6)
+	testhelper.DiffFloat(t, "non-positive", "start", s.histStart(), 1, 1e-9)
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	if end <= 4 {
+		t.Errorf("the histogram should cover the largest value: %g", end)
+	}
+}
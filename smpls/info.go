@@ -0,0 +1,109 @@
+package smpls
+
+import (
+	"slices"
+)
+
+// ValInfo records a value and the information added with it
+type ValInfo struct {
+	Val  float64
+	Info any
+}
+
+// StatTrackInfo returns a function that will make the Stat record the
+// information added with each value (see AddWithInfo) for those values
+// among the minimum and maximum values. This lets you trace the extreme
+// values back to their source. Note that neither the information nor the
+// choice to track it is saved by the Save method.
+func StatTrackInfo() StatOpt {
+	return func(s *Stat) error {
+		s.trackInfo = true
+		return nil
+	}
+}
+
+// makeInfo creates the slices of information to match the slices of
+// minimum and maximum values
+func (s *Stat) makeInfo() {
+	s.minInfo = make([]any, 0, cap(s.mins))
+	s.maxInfo = make([]any, 0, cap(s.maxs))
+}
+
+// AddWithInfo adds the value to the Stat along with some associated
+// information such as a request id or a timestamp. If the Stat is not
+// tracking the information (see StatTrackInfo) the information is
+// discarded.
+func (s *Stat) AddWithInfo(v float64, info any) {
+	s.addValInfo(v, info)
+}
+
+// addMinMaxInfo records the value and its information if it is among the
+// minimum or maximum values
+func (s *Stat) addMinMaxInfo(v float64, info any) {
+	if s.count <= cap(s.mins) {
+		var i int
+		s.mins, i = insertSorted(v, s.mins)
+		s.minInfo = slices.Insert(s.minInfo, i, info)
+		s.maxs, i = insertSorted(v, s.maxs)
+		s.maxInfo = slices.Insert(s.maxInfo, i, info)
+		return
+	}
+
+	if v < s.mins[len(s.mins)-1] { // smaller than the largest min value
+		i := insert(v, s.mins, dropFromEnd)
+		insertAt(info, s.minInfo, i, dropFromEnd)
+	}
+	if v > s.maxs[0] { // larger than the smallest max value
+		i := insert(v, s.maxs, dropFromStart)
+		insertAt(info, s.maxInfo, i, dropFromStart)
+	}
+}
+
+// valInfos returns the values paired with their information
+func valInfos(vals []float64, info []any) []ValInfo {
+	vis := make([]ValInfo, 0, len(vals))
+	for i, v := range vals {
+		vi := ValInfo{Val: v}
+		if i < len(info) {
+			vi.Info = info[i]
+		}
+		vis = append(vis, vi)
+	}
+	return vis
+}
+
+// MinWithInfo returns the smallest value and the information added with
+// it. The information is nil if the Stat is not tracking the information
+// (see StatTrackInfo) or if no values have been added.
+func (s Stat) MinWithInfo() (float64, any) {
+	if len(s.minInfo) == 0 {
+		return s.Min(), nil
+	}
+	return s.mins[0], s.minInfo[0]
+}
+
+// MaxWithInfo returns the largest value and the information added with
+// it. The information is nil if the Stat is not tracking the information
+// (see StatTrackInfo) or if no values have been added.
+func (s Stat) MaxWithInfo() (float64, any) {
+	if len(s.maxInfo) == 0 {
+		return s.Max(), nil
+	}
+	return s.maxs[len(s.maxs)-1], s.maxInfo[len(s.maxInfo)-1]
+}
+
+// MinsWithInfo returns the smallest values (those averaged to give
+// MeanMin), in ascending order, with the information added with them. The
+// information is nil if the Stat is not tracking the information (see
+// StatTrackInfo).
+func (s Stat) MinsWithInfo() []ValInfo {
+	return valInfos(s.mins, s.minInfo)
+}
+
+// MaxsWithInfo returns the largest values (those averaged to give
+// MeanMax), in ascending order, with the information added with them. The
+// information is nil if the Stat is not tracking the information (see
+// StatTrackInfo).
+func (s Stat) MaxsWithInfo() []ValInfo {
+	return valInfos(s.maxs, s.maxInfo)
+}
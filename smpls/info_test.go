@@ -0,0 +1,66 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddWithInfo(t *testing.T) {
+	s := NewStatOrPanic("ms", StatTrackInfo(), StatMinMaxCount(3))
+
+	s.AddWithInfo(5, "req-5")
+	s.AddWithInfo(1, "req-1")
+	min, info := s.MinWithInfo()
+	testhelper.DiffFloat(t, "filling", "min", min, 1, 0.0)
+	diffInfo(t, "filling", "min info", info, "req-1")
+
+	s.AddWithInfo(9, "req-9")
+	s.AddWithInfo(3, "req-3")
+	s.Add(0)
+	s.AddWithInfo(12, "req-12")
+	s.AddWithInfo(7, "req-7")
+
+	min, info = s.MinWithInfo()
+	testhelper.DiffFloat(t, "full", "min", min, 0, 0.0)
+	diffInfo(t, "full", "min info", info, nil)
+	max, info := s.MaxWithInfo()
+	testhelper.DiffFloat(t, "full", "max", max, 12, 0.0)
+	diffInfo(t, "full", "max info", info, "req-12")
+
+	diffInfo(t, "full", "mins", s.MinsWithInfo(),
+		[]ValInfo{{0, nil}, {1, "req-1"}, {3, "req-3"}})
+	diffInfo(t, "full", "maxs", s.MaxsWithInfo(),
+		[]ValInfo{{7, "req-7"}, {9, "req-9"}, {12, "req-12"}})
+
+	child := s.Fork()
+	child.AddWithInfo(100, "req-100")
+	_, info = s.MaxWithInfo()
+	diffInfo(t, "Fork", "parent max info", info, "req-100")
+
+	c := s.Clone()
+	c.AddWithInfo(-1, "req-neg")
+	_, info = s.MinWithInfo()
+	diffInfo(t, "Clone", "original min info", info, nil)
+
+	s.Reset()
+	s.AddWithInfo(2, "req-2")
+	diffInfo(t, "Reset", "maxs", s.MaxsWithInfo(),
+		[]ValInfo{{2, "req-2"}})
+
+	plain := NewStatOrPanic("ms")
+	plain.AddWithInfo(4, "ignored")
+	max, info = plain.MaxWithInfo()
+	testhelper.DiffFloat(t, "not tracking", "max", max, 4, 0.0)
+	diffInfo(t, "not tracking", "max info", info, nil)
+}
+
+// diffInfo reports an error if the actual and expected values differ
+func diffInfo(t *testing.T, id, name string, act, exp any) {
+	t.Helper()
+
+	if err := testhelper.DiffVals(act, exp); err != nil {
+		t.Log(id)
+		t.Errorf("\t: %s differs: %v\n", name, err)
+	}
+}
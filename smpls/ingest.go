@@ -0,0 +1,169 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// ReadFunc reads values from the Reader and adds them to the Stat,
+// returning the number of values added. The AddFromReader method has this
+// form and can be given as (*Stat).AddFromReader.
+type ReadFunc func(s *Stat, r io.Reader) (int, error)
+
+// ReadCSVColumn returns a ReadFunc that reads the values from the given
+// CSV column (see AddFromCSVColumn)
+func ReadCSVColumn(col int, hasHeader bool) ReadFunc {
+	return func(s *Stat, r io.Reader) (int, error) {
+		return s.AddFromCSVColumn(r, col, hasHeader)
+	}
+}
+
+// Source is a named source of values to be read by IngestParallel. Open is
+// called, from the goroutine reading the Source, to get the values and the
+// ReadCloser it returns is closed once they have been read.
+type Source struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// FileSource returns a Source reading the named file
+func FileSource(name string) Source {
+	return Source{
+		Name: name,
+		Open: func() (io.ReadCloser, error) { return os.Open(name) },
+	}
+}
+
+// ReaderSource returns a Source reading from the Reader. If the Reader is
+// also an io.Closer it is closed once the values have been read.
+func ReaderSource(name string, r io.Reader) Source {
+	return Source{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc, nil
+			}
+			return io.NopCloser(r), nil
+		},
+	}
+}
+
+// SourceStat holds the Stat built from a single Source by IngestParallel
+type SourceStat struct {
+	Name  string
+	Stat  *Stat
+	Added int
+	Err   error
+}
+
+// ingest reads the Source into a new Stat
+func (src Source) ingest(units string, opts []StatOpt, read ReadFunc,
+) SourceStat {
+	ss := SourceStat{Name: src.Name}
+
+	ss.Stat, ss.Err = NewStat(units, opts...)
+	if ss.Err != nil {
+		return ss
+	}
+	rc, err := src.Open()
+	if err != nil {
+		ss.Err = err
+		return ss
+	}
+	ss.Added, ss.Err = read(ss.Stat, rc)
+	ss.Err = errors.Join(ss.Err, rc.Close())
+	return ss
+}
+
+// IngestParallel reads the Sources concurrently, using up to the given
+// number of goroutines, and builds a Stat for each of them, created with
+// the units and options. A worker count of 0 gives one goroutine per
+// available processor (see runtime.GOMAXPROCS). The Stats are then merged,
+// in the order of the Sources, into a combined Stat, created in the same
+// way, which is returned along with the Stat for each Source. This makes
+// use of multiple processors when analysing large numbers of log files.
+//
+// A Source which cannot be read does not stop the others being read; the
+// values read from it before the error are still included. The errors from
+// all the Sources are returned together, each prefixed with the name of
+// its Source. The options must not include a starting summary (see
+// StatFromSummary) since each Stat would start from it. Nor can they
+// include Accumulators, a raw value writer or a sample store since these
+// would be shared, unlocked, by the goroutines reading the Sources.
+func IngestParallel(units string, sources []Source, read ReadFunc,
+	workers int, opts ...StatOpt,
+) (*Stat, []SourceStat, error) {
+	if workers < 0 {
+		return nil, nil,
+			invalidValue("Invalid worker count (%d) - it must be >= 0", workers)
+	}
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if read == nil {
+		return nil, nil, invalidValue("the ReadFunc must not be nil")
+	}
+
+	combined, err := NewStat(units, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if combined.Count() != 0 {
+		return nil, nil, errors.New(
+			"parallel ingestion cannot start from a summary")
+	}
+	if err := combined.checkShardable("parallel ingestion",
+		"Sources"); err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]SourceStat, len(sources))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(sources)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = sources[i].ingest(units, opts, read)
+			}
+		}()
+	}
+	for i := range sources {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Name, res.Err))
+		}
+		if res.Stat == nil {
+			continue
+		}
+		if err := combined.Merge(res.Stat); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Name, err))
+		}
+	}
+	return combined, results, errors.Join(errs...)
+}
+
+// IngestFiles reads the named files concurrently with IngestParallel,
+// reading each one as numbers separated by white space (see
+// AddFromReader)
+func IngestFiles(units string, names []string, workers int,
+	opts ...StatOpt,
+) (*Stat, []SourceStat, error) {
+	sources := make([]Source, 0, len(names))
+	for _, name := range names {
+		sources = append(sources, FileSource(name))
+	}
+	return IngestParallel(units, sources, (*Stat).AddFromReader,
+		workers, opts...)
+}
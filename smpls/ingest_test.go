@@ -0,0 +1,102 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestIngestParallel(t *testing.T) {
+	sources := []Source{
+		ReaderSource("a", strings.NewReader("1 2 3\n4")),
+		ReaderSource("b", strings.NewReader("x,5\ny,6\n")),
+		ReaderSource("c", strings.NewReader("10 bad 20")),
+		{
+			Name: "d",
+			Open: func() (io.ReadCloser, error) {
+				return nil, errors.New("no such source")
+			},
+		},
+	}
+
+	combined, results, err := IngestParallel("ms", sources,
+		(*Stat).AddFromReader, 2, StatMinMaxCount(3))
+	testhelper.CheckExpErrWithID(t, "whitespace", err,
+		testhelper.MkExpErr(`b: line 1: cannot parse "x,5" as a number`,
+			`c: line 1: cannot parse "bad" as a number`,
+			"d: no such source"))
+	testhelper.DiffInt(t, "whitespace", "count", combined.Count(), 5)
+	testhelper.DiffFloat(t, "whitespace", "sum", combined.Sum(), 20, 0)
+	testhelper.DiffInt(t, "whitespace", "results", len(results), 4)
+	testhelper.DiffString(t, "whitespace", "result name",
+		results[2].Name, "c")
+	testhelper.DiffInt(t, "whitespace", "result added", results[2].Added, 1)
+
+	combined, _, err = IngestParallel("ms",
+		[]Source{ReaderSource("b", strings.NewReader("x,5\ny,6\n"))},
+		ReadCSVColumn(1, false), 0)
+	testhelper.CheckExpErrWithID(t, "CSV", err, testhelper.ExpErr{})
+	testhelper.DiffFloat(t, "CSV", "sum", combined.Sum(), 11, 0)
+
+	_, _, err = IngestParallel("ms", nil, nil, 1)
+	testhelper.CheckExpErrWithID(t, "nil ReadFunc", err,
+		testhelper.MkExpErr("the ReadFunc must not be nil"))
+	_, _, err = IngestParallel("ms", nil, (*Stat).AddFromReader, -1)
+	testhelper.CheckExpErrWithID(t, "bad worker count", err,
+		testhelper.MkExpErr("Invalid worker count (-1) - it must be >= 0"))
+}
+
+// TestIngestParallelShared checks that options whose state would be shared
+// by the goroutines reading the Sources are rejected. Run it with the race
+// detector (go test -race) to check that the other options share nothing.
+func TestIngestParallelShared(t *testing.T) {
+	sources := make([]Source, 0, 16)
+	for i := range 16 {
+		sources = append(sources, ReaderSource(fmt.Sprintf("src%d", i),
+			strings.NewReader(strings.Repeat("1 2 3 4 5\n", 200))))
+	}
+
+	_, _, err := IngestParallel("ms", sources, (*Stat).AddFromReader, 8,
+		StatWithAccumulator(&counter{}))
+	testhelper.CheckExpErrWithID(t, "Accumulator", err,
+		testhelper.MkExpErr("parallel ingestion cannot have Accumulators",
+			"they would be shared by the Sources"))
+	testhelper.DiffBool(t, "Accumulator", "conflicting options",
+		errors.Is(err, ErrConflictingOptions), true)
+
+	combined, _, err := IngestParallel("ms", sources,
+		(*Stat).AddFromReader, 8,
+		StatHeavyHitters(5), StatApproxMedian(5), StatReservoirSize(10),
+		StatTrackFreq(10))
+	testhelper.CheckExpErrWithID(t, "unshared", err, testhelper.ExpErr{})
+	testhelper.DiffInt(t, "unshared", "count", combined.Count(), 16*1000)
+}
+
+func TestIngestFiles(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i, content := range []string{"1 2\n", "3\n4\n", "5"} {
+		name := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal("Couldn't write the file:", err)
+		}
+		names = append(names, name)
+	}
+
+	combined, results, err := IngestFiles("ms", names, 0)
+	testhelper.CheckExpErrWithID(t, "files", err, testhelper.ExpErr{})
+	testhelper.DiffInt(t, "files", "count", combined.Count(), 5)
+	testhelper.DiffFloat(t, "files", "mean", combined.Mean(), 3, 0)
+	testhelper.DiffInt(t, "files", "second file count",
+		results[1].Stat.Count(), 2)
+
+	_, _, err = IngestFiles("ms", []string{filepath.Join(dir, "missing")}, 1)
+	testhelper.CheckExpErrWithID(t, "missing file", err,
+		testhelper.MkExpErr("missing: open", "no such file"))
+}
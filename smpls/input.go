@@ -0,0 +1,130 @@
+package smpls
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordSplitter returns a bufio.SplitFunc which splits the input into words
+// separated by white space, as bufio.ScanWords does, and counts the
+// newlines it passes in the given line number. The separator after a word
+// is left to the next call so that the line number is that of the word
+// just returned.
+func wordSplitter(lineNum *int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		start := 0
+		for start < len(data) {
+			r, width := utf8.DecodeRune(data[start:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			if r == '\n' {
+				*lineNum++
+			}
+			start += width
+		}
+
+		for i := start; i < len(data); {
+			r, width := utf8.DecodeRune(data[i:])
+			if unicode.IsSpace(r) {
+				return i, data[start:i], nil
+			}
+			i += width
+		}
+		if atEOF && start < len(data) {
+			return len(data), data[start:], nil
+		}
+		// the white space is consumed so its newlines are not counted again
+		return start, nil, nil
+	}
+}
+
+// AddFromReader reads numbers separated by white space (including
+// newlines) from the Reader and adds them to the Stat. The input is read a
+// word at a time so there is no limit on the length of a line. It stops at
+// the first value that cannot be parsed as a number, or on the first read
+// error, and returns an error giving the line number. The values read
+// before the error are added. It returns the number of values added.
+func (s *Stat) AddFromReader(r io.Reader) (int, error) {
+	added := 0
+	lineNum := 1
+	scanner := bufio.NewScanner(r)
+	scanner.Split(wordSplitter(&lineNum))
+
+	for scanner.Scan() {
+		field := scanner.Text()
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return added,
+				fmt.Errorf("line %d: cannot parse %q as a number",
+					lineNum, field)
+		}
+		s.addVal(v)
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		return added, fmt.Errorf("line %d: %w", lineNum, err)
+	}
+
+	return added, nil
+}
+
+// AddFromCSVColumn reads CSV records from the Reader and adds the values in
+// the given column (counting from zero) to the Stat. If hasHeader is true
+// the first record is skipped. Surrounding white space is ignored and empty
+// values are skipped. It stops at the first value that cannot be parsed as
+// a number, the first record that is too short or the first badly formed
+// record and returns an error giving the line number (a badly formed record
+// gives a csv.ParseError). The values read before the error are added. It
+// returns the number of values added.
+func (s *Stat) AddFromCSVColumn(
+	r io.Reader, col int, hasHeader bool,
+) (int, error) {
+	if col < 0 {
+		return 0, invalidValue("Invalid CSV column (%d) - it must be >= 0", col)
+	}
+
+	added := 0
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return added, nil
+		}
+		if err != nil {
+			return added, err
+		}
+		if first && hasHeader {
+			continue
+		}
+
+		if col >= len(rec) {
+			line, _ := cr.FieldPos(0)
+			return added,
+				fmt.Errorf("line %d: there is no column %d (%d columns)",
+					line, col, len(rec))
+		}
+		field := strings.TrimSpace(rec[col])
+		if field == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			line, _ := cr.FieldPos(col)
+			return added,
+				fmt.Errorf("line %d: cannot parse %q as a number",
+					line, field)
+		}
+		s.addVal(v)
+		added++
+	}
+}
@@ -0,0 +1,146 @@
+package smpls
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// longLineCount is the number of values on the long line, enough to make
+// it much longer than the default bufio.Scanner buffer
+const longLineCount = 20000
+
+// longLine holds the numbers from 1 to longLineCount on a single line
+var longLine = func() string {
+	var b strings.Builder
+	for i := 1; i <= longLineCount; i++ {
+		b.WriteString(strconv.Itoa(i) + " ")
+	}
+	return b.String()
+}()
+
+func TestAddFromReader(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		input    string
+		expAdded int
+		expSum   float64
+	}{
+		{
+			ID:       testhelper.MkID("good"),
+			input:    "1 2\t3\n\n4\n  5.5e1  \n",
+			expAdded: 5,
+			expSum:   65,
+		},
+		{
+			ID:       testhelper.MkID("empty"),
+			input:    "",
+			expAdded: 0,
+		},
+		{
+			ID:       testhelper.MkID("bad value"),
+			ExpErr:   testhelper.MkExpErr(`line 3: cannot parse "x" as a number`),
+			input:    "1 2\n3\n4 x 5\n",
+			expAdded: 4,
+			expSum:   10,
+		},
+		{
+			ID:       testhelper.MkID("unicode white space"),
+			input:    "1\u00a02\u2003\n3",
+			expAdded: 3,
+			expSum:   6,
+		},
+		{
+			ID:       testhelper.MkID("one very long line"),
+			input:    longLine,
+			expAdded: longLineCount,
+			expSum:   longLineCount * (longLineCount + 1) / 2,
+		},
+		{
+			ID: testhelper.MkID("bad value after a very long line"),
+			ExpErr: testhelper.MkExpErr(
+				`line 3: cannot parse "x" as a number`),
+			input:    longLine + "\n\n x\n",
+			expAdded: longLineCount,
+			expSum:   longLineCount * (longLineCount + 1) / 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units")
+		added, err := s.AddFromReader(strings.NewReader(tc.input))
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffInt(t, tc.IDStr(), "values added", added, tc.expAdded)
+		testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), tc.expAdded)
+		testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 0.0)
+	}
+}
+
+func TestAddFromCSVColumn(t *testing.T) {
+	const csvData = "name,latency\n" +
+		"a, 1.5\n" +
+		"b,2.5\n" +
+		"c,\n" +
+		"d,3\n"
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		input     string
+		col       int
+		hasHeader bool
+		expAdded  int
+		expSum    float64
+	}{
+		{
+			ID:        testhelper.MkID("good"),
+			input:     csvData,
+			col:       1,
+			hasHeader: true,
+			expAdded:  3,
+			expSum:    7,
+		},
+		{
+			ID:       testhelper.MkID("header not skipped"),
+			ExpErr:   testhelper.MkExpErr(`line 1: cannot parse "latency"`),
+			input:    csvData,
+			col:      1,
+			expAdded: 0,
+		},
+		{
+			ID:        testhelper.MkID("short record"),
+			ExpErr:    testhelper.MkExpErr("line 3: there is no column 1"),
+			input:     "h1,h2\n1,2\n3\n",
+			col:       1,
+			hasHeader: true,
+			expAdded:  1,
+			expSum:    2,
+		},
+		{
+			ID:       testhelper.MkID("bad column"),
+			ExpErr:   testhelper.MkExpErr("Invalid CSV column (-1)"),
+			input:    csvData,
+			col:      -1,
+			expAdded: 0,
+		},
+		{
+			ID:       testhelper.MkID("bad CSV"),
+			ExpErr:   testhelper.MkExpErr(`bare " in non-quoted-field`),
+			input:    "1\n2\"x\n",
+			expAdded: 1,
+			expSum:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms")
+		added, err := s.AddFromCSVColumn(strings.NewReader(tc.input),
+			tc.col, tc.hasHeader)
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffInt(t, tc.IDStr(), "values added", added, tc.expAdded)
+		testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 0.0)
+	}
+}
@@ -0,0 +1,195 @@
+package smpls
+
+import (
+	"fmt"
+	"time"
+)
+
+const minIntervalCount = 1
+
+// Interval holds the statistics for the values added during a period of
+// time. The period starts at Start and ends just before End.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+	Snapshot
+}
+
+// IntervalStats records statistics for each of a sequence of fixed length
+// intervals of time as well as for the whole time. When an interval ends
+// the statistics for it are recorded as an Interval and a new interval is
+// started. Only the most recent completed Intervals are kept.
+//
+// This is useful for spotting changes in behaviour over the course of a
+// long running process such as a load test.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type IntervalStats struct {
+	interval time.Duration
+	keep     int
+
+	overall      *Stat
+	current      *Stat
+	currentStart time.Time
+
+	history []Interval
+
+	now func() time.Time
+}
+
+// NewIntervalStats creates a new IntervalStats. The interval gives the
+// length of each interval and keep gives the number of completed Intervals
+// to keep. The options are applied to the Stat recording the overall
+// statistics and the Stats for each interval are created like it (see the
+// Fork method of Stat).
+func NewIntervalStats(units string, interval time.Duration, keep int,
+	opts ...StatOpt,
+) (*IntervalStats, error) {
+	if interval <= 0 {
+		return nil,
+			invalidValue("Invalid interval (%s) - it must be > 0", interval)
+	}
+	if keep < minIntervalCount {
+		return nil,
+			invalidValue("Invalid interval count (%d) - it must be >= %d",
+				keep, minIntervalCount)
+	}
+
+	overall, err := NewStat(units, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	is := &IntervalStats{
+		interval: interval,
+		keep:     keep,
+		overall:  overall,
+		current:  overall.Fork(),
+		now:      time.Now,
+	}
+	is.currentStart = is.now()
+
+	return is, nil
+}
+
+// NewIntervalStatsOrPanic creates a new IntervalStats and will panic if
+// any errors are detected
+func NewIntervalStatsOrPanic(units string, interval time.Duration, keep int,
+	opts ...StatOpt,
+) *IntervalStats {
+	is, err := NewIntervalStats(units, interval, keep, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return is
+}
+
+// advance completes the current interval and any following intervals
+// which have ended by time t. Intervals with no values are recorded as
+// such.
+func (is *IntervalStats) advance(t time.Time) {
+	n := int(t.Sub(is.currentStart) / is.interval)
+	if n <= 0 {
+		return
+	}
+
+	is.closeCurrent()
+
+	// there is no point recording more empty intervals than will be kept
+	empty := min(n-1, is.keep)
+	skip := n - 1 - empty
+	is.currentStart = is.currentStart.Add(time.Duration(skip) * is.interval)
+	for i := 0; i < empty; i++ {
+		is.closeCurrent()
+	}
+}
+
+// closeCurrent records the current interval and starts the next one
+func (is *IntervalStats) closeCurrent() {
+	end := is.currentStart.Add(is.interval)
+	is.history = append(is.history, Interval{
+		Start:    is.currentStart,
+		End:      end,
+		Snapshot: is.current.Snapshot(),
+	})
+	if len(is.history) > is.keep {
+		is.history = is.history[len(is.history)-is.keep:]
+	}
+
+	if is.current.Count() > 0 {
+		is.current = is.overall.Fork()
+	}
+	is.currentStart = end
+}
+
+// Add adds at least one new value to the current interval
+func (is *IntervalStats) Add(v float64, vals ...float64) {
+	t := is.now()
+	is.AddAt(t, v)
+	for _, v := range vals {
+		is.AddAt(t, v)
+	}
+}
+
+// AddAt adds the value as having been observed at the given time. Values
+// should be added in time order; a value with a time before the start of
+// the current interval is added to the current interval.
+func (is *IntervalStats) AddAt(t time.Time, v float64) {
+	is.advance(t)
+	is.current.Add(v)
+}
+
+// Interval returns the length of each interval
+func (is IntervalStats) Interval() time.Duration {
+	return is.interval
+}
+
+// Current returns the Stat for the current interval, first completing any
+// intervals which have ended. Values added to the returned Stat are also
+// added to the overall Stat.
+func (is *IntervalStats) Current() *Stat {
+	is.advance(is.now())
+	return is.current
+}
+
+// Overall returns the Stat for all the values added
+func (is IntervalStats) Overall() *Stat {
+	return is.overall
+}
+
+// Intervals returns the completed Intervals, the oldest first. Any
+// intervals which have ended are first completed.
+func (is *IntervalStats) Intervals() []Interval {
+	is.advance(is.now())
+	return append([]Interval(nil), is.history...)
+}
+
+// Reset discards all the values and Intervals and starts a new interval
+func (is *IntervalStats) Reset() {
+	is.overall.Reset()
+	is.current = is.overall.Fork()
+	is.history = nil
+	is.currentStart = is.now()
+}
+
+// String returns a report showing the statistics for each completed
+// Interval followed by the overall statistics
+func (is *IntervalStats) String() string {
+	const timeFmt = "15:04:05.000"
+
+	var str string
+	for _, iv := range is.Intervals() {
+		str += fmt.Sprintf("%s: %7d observations", iv.Start.Format(timeFmt),
+			iv.Count)
+		if iv.Count > 0 {
+			p99, _ := iv.Percentile(99)
+			str += fmt.Sprintf(", min: %8.2e, avg: %8.2e,"+
+				" p99: %8.2e, max: %8.2e",
+				iv.Min, iv.Mean, p99, iv.Max)
+		}
+		str += "\n"
+	}
+	str += "overall: " + is.overall.String() + "\n"
+	return str
+}
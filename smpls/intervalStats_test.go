@@ -0,0 +1,89 @@
+package smpls
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestIntervalStats(t *testing.T) {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	now := start
+	is := NewIntervalStatsOrPanic("ms", time.Minute, 3)
+	is.now = func() time.Time { return now }
+	is.currentStart = start
+
+	is.Add(1, 2, 3)
+	testhelper.DiffInt(t, "first interval", "completed intervals",
+		len(is.Intervals()), 0)
+
+	now = start.Add(70 * time.Second)
+	is.Add(10)
+	ivs := is.Intervals()
+	if !testhelper.DiffInt(t, "second interval", "completed intervals",
+		len(ivs), 1) {
+		testhelper.DiffInt(t, "second interval", "1st count", ivs[0].Count, 3)
+		testhelper.DiffFloat(t, "second interval", "1st mean",
+			ivs[0].Mean, 2, 0.0)
+		testhelper.DiffString(t, "second interval", "1st end",
+			ivs[0].End.Format(time.TimeOnly), "10:01:00")
+	}
+	testhelper.DiffInt(t, "second interval", "current count",
+		is.Current().Count(), 1)
+	testhelper.DiffInt(t, "second interval", "overall count",
+		is.Overall().Count(), 4)
+
+	// skip past two empty intervals
+	now = start.Add(4*time.Minute + time.Second)
+	is.AddAt(now, 20)
+	ivs = is.Intervals()
+	if !testhelper.DiffInt(t, "after gap", "completed intervals",
+		len(ivs), 3) {
+		testhelper.DiffInt(t, "after gap", "1st count", ivs[0].Count, 1)
+		testhelper.DiffInt(t, "after gap", "2nd count", ivs[1].Count, 0)
+		testhelper.DiffString(t, "after gap", "3rd start",
+			ivs[2].Start.Format(time.TimeOnly), "10:03:00")
+	}
+
+	// a long gap keeps only the most recent intervals
+	now = start.Add(time.Hour)
+	ivs = is.Intervals()
+	if !testhelper.DiffInt(t, "after long gap", "completed intervals",
+		len(ivs), 3) {
+		testhelper.DiffString(t, "after long gap", "last end",
+			ivs[2].End.Format(time.TimeOnly), "11:00:00")
+		testhelper.DiffInt(t, "after long gap", "last count",
+			ivs[2].Count, 0)
+	}
+	testhelper.DiffInt(t, "after long gap", "overall count",
+		is.Overall().Count(), 5)
+
+	testhelper.ShouldContain(t, "String", "report", is.String(),
+		[]string{
+			"10:59:00.000:       0 observations\n",
+			"overall:       5 observations,",
+		})
+
+	is.Reset()
+	testhelper.DiffInt(t, "Reset", "overall count", is.Overall().Count(), 0)
+	testhelper.DiffInt(t, "Reset", "intervals", len(is.Intervals()), 0)
+	is.Add(1)
+	testhelper.DiffInt(t, "Reset", "overall count after Add",
+		is.Overall().Count(), 1)
+	testhelper.DiffBool(t, "Reset", "String has no intervals",
+		strings.HasPrefix(is.String(), "overall:"), true)
+}
+
+func TestNewIntervalStatsErrs(t *testing.T) {
+	_, err := NewIntervalStats("ms", 0, 3)
+	testhelper.CheckExpErrWithID(t, "bad interval", err,
+		testhelper.MkExpErr("Invalid interval (0s) - it must be > 0"))
+	_, err = NewIntervalStats("ms", time.Second, 0)
+	testhelper.CheckExpErrWithID(t, "bad count", err,
+		testhelper.MkExpErr("Invalid interval count (0) - it must be >= 1"))
+	_, err = NewIntervalStats("ms", time.Second, 1, StatMinMaxCount(0))
+	testhelper.CheckExpErrWithID(t, "bad StatOpt", err,
+		testhelper.MkExpErr("Invalid Min/Max Count (0)"))
+}
@@ -0,0 +1,371 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+const (
+	dfltKeyedCacheSize = 1000
+	minKeyedCacheSize  = 1
+)
+
+// heatRunes are the characters used to show how full each cell of a heat
+// map is, from empty to full
+var heatRunes = []rune(" ░▒▓█")
+
+// keyedCounts holds the values for one key of a KeyedHist
+type keyedCounts struct {
+	count     int
+	cache     []float64
+	underflow int
+	hist      []int
+	overflow  int
+}
+
+// KeyedHist keeps a histogram of values for each of a set of string keys,
+// such as the endpoints of a service. All the histograms share the same
+// bucket boundaries so that the distributions for each key can be compared
+// at a glance, either side by side (see String) or as a heat map (see
+// HeatMap).
+//
+// As with a Stat the values are held in a cache until enough have been
+// added, across all the keys, to choose the bucket boundaries; these cover
+// the range of all the cached values. Values added later which lie outside
+// that range are counted in the underflow or overflow of their key.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type KeyedHist struct {
+	units       string
+	bucketCount int
+	cacheSize   int
+
+	laidOut     bool
+	cached      int
+	bucketStart float64
+	bucketWidth float64
+
+	keys map[string]*keyedCounts
+}
+
+// NewKeyedHist creates a new KeyedHist with the given number of buckets in
+// each histogram. The bucket boundaries are chosen once cacheSize values
+// have been added; if cacheSize is 0 a default size is used.
+func NewKeyedHist(
+	units string, bucketCount, cacheSize int,
+) (*KeyedHist, error) {
+	if bucketCount < minHistBucketCount {
+		return nil, invalidValue(
+			"Invalid histogram bucket count (%d) - it must be >= %d",
+			bucketCount, minHistBucketCount)
+	}
+	if cacheSize == 0 {
+		cacheSize = dfltKeyedCacheSize
+	}
+	if cacheSize < minKeyedCacheSize {
+		return nil, invalidValue("Invalid cache size (%d) - it must be >= %d",
+			cacheSize, minKeyedCacheSize)
+	}
+
+	return &KeyedHist{
+		units:       units,
+		bucketCount: bucketCount,
+		cacheSize:   cacheSize,
+		keys:        make(map[string]*keyedCounts),
+	}, nil
+}
+
+// NewKeyedHistOrPanic creates a new KeyedHist and will panic if any errors
+// are detected
+func NewKeyedHistOrPanic(units string, bucketCount, cacheSize int) *KeyedHist {
+	kh, err := NewKeyedHist(units, bucketCount, cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	return kh
+}
+
+// Add adds the value to the histogram for the key. NaN values are ignored.
+func (kh *KeyedHist) Add(key string, v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	kc, ok := kh.keys[key]
+	if !ok {
+		kc = &keyedCounts{}
+		kh.keys[key] = kc
+	}
+	kc.count++
+
+	if kh.laidOut {
+		kh.addToHist(kc, v)
+		return
+	}
+	kc.cache = append(kc.cache, v)
+	kh.cached++
+	if kh.cached >= kh.cacheSize {
+		kh.layout()
+	}
+}
+
+// addToHist adds the value to the histogram of the keyedCounts
+func (kh KeyedHist) addToHist(kc *keyedCounts, v float64) {
+	if kc.hist == nil {
+		kc.hist = make([]int, kh.bucketCount)
+	}
+	idx := math.Floor((v - kh.bucketStart) / kh.bucketWidth)
+	switch {
+	case idx < 0:
+		kc.underflow++
+	case idx >= float64(kh.bucketCount):
+		kc.overflow++
+	default:
+		kc.hist[int(idx)]++
+	}
+}
+
+// layout chooses the bucket boundaries from the cached values and then adds
+// them to the histograms
+func (kh *KeyedHist) layout() {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, kc := range kh.keys {
+		for _, v := range kc.cache {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+
+	kh.bucketStart, kh.bucketWidth = evenLayout(lo, hi, kh.bucketCount)
+
+	for _, kc := range kh.keys {
+		for _, v := range kc.cache {
+			kh.addToHist(kc, v)
+		}
+		kc.cache = nil
+	}
+	kh.cached = 0
+	kh.laidOut = true
+}
+
+// evenLayout returns the start and width of bucketCount equal buckets
+// covering the range from lo to hi. If the range is empty (or not finite)
+// the buckets are centred on lo.
+func evenLayout(lo, hi float64, bucketCount int) (start, width float64) {
+	n := float64(bucketCount)
+	start = lo
+	width = histBucketWidthScale * (hi - lo) / n
+	if !(width > 0) || math.IsInf(width, 0) {
+		width = math.Max(math.Abs(lo), 1) / n
+		if math.IsInf(width, 0) {
+			width = 1 / n
+		}
+		start = lo - width*n/2
+	}
+	return start, width
+}
+
+// laidOutCopy returns a copy of the KeyedHist with the bucket boundaries
+// chosen. If they have already been chosen the KeyedHist is returned
+// unchanged, otherwise the copy is laid out from the cached values without
+// affecting the original.
+func (kh KeyedHist) laidOutCopy() KeyedHist {
+	if kh.laidOut || kh.cached == 0 {
+		return kh
+	}
+	keys := make(map[string]*keyedCounts, len(kh.keys))
+	for k, kc := range kh.keys {
+		keys[k] = &keyedCounts{count: kc.count, cache: kc.cache}
+	}
+	kh.keys = keys
+	kh.layout()
+	return kh
+}
+
+// Reset discards all the values and keys. The bucket boundaries are chosen
+// again once enough new values have been added.
+func (kh *KeyedHist) Reset() {
+	clear(kh.keys)
+	kh.laidOut = false
+	kh.cached = 0
+	kh.bucketStart = 0
+	kh.bucketWidth = 0
+}
+
+// Units returns the units of the values
+func (kh KeyedHist) Units() string {
+	return kh.units
+}
+
+// Keys returns the keys for which values have been added, in sorted order
+func (kh KeyedHist) Keys() []string {
+	keys := make([]string, 0, len(kh.keys))
+	for k := range kh.keys {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Count returns the number of values added for the key
+func (kh KeyedHist) Count(key string) int {
+	if kc, ok := kh.keys[key]; ok {
+		return kc.count
+	}
+	return 0
+}
+
+// Buckets returns the histogram buckets for the key together with the
+// number of its values in the underflow and overflow. It returns nil
+// buckets if no values have been added for the key.
+func (kh KeyedHist) Buckets(
+	key string,
+) (underflow int, buckets []Bucket, overflow int) {
+	if _, ok := kh.keys[key]; !ok {
+		return 0, nil, 0
+	}
+	kh = kh.laidOutCopy()
+	kc := kh.keys[key]
+
+	buckets = make([]Bucket, kh.bucketCount)
+	for i := range buckets {
+		buckets[i] = Bucket{
+			Low:  kh.bucketStart + float64(i)*kh.bucketWidth,
+			High: kh.bucketStart + float64(i+1)*kh.bucketWidth,
+		}
+		if kc.hist != nil {
+			buckets[i].Count = kc.hist[i]
+		}
+	}
+	return kc.underflow, buckets, kc.overflow
+}
+
+// valFmt returns the format used to show the bucket boundaries and the
+// width of the formatted values
+func (kh KeyedHist) valFmt() (string, int) {
+	end := kh.bucketStart + float64(kh.bucketCount)*kh.bucketWidth
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		kh.bucketStart, kh.bucketWidth, end)
+	return fmt.Sprintf("%%%d.%df", width, precision), width
+}
+
+// String returns the histograms for each key side by side, a column for
+// each key and a row for each bucket. The underflow and overflow rows are
+// only shown if they hold any values. It returns the empty string if no
+// values have been added.
+func (kh KeyedHist) String() string {
+	if len(kh.keys) == 0 {
+		return ""
+	}
+	kh = kh.laidOutCopy()
+	keys := kh.Keys()
+
+	colWidths := make([]int, len(keys))
+	underflow, overflow := 0, 0
+	for i, k := range keys {
+		kc := kh.keys[k]
+		colWidths[i] = max(len(k), mathutil.Digits(int64(kc.count)))
+		underflow += kc.underflow
+		overflow += kc.overflow
+	}
+
+	valFmt, valWidth := kh.valFmt()
+	rangeWidth := 2*valWidth + 8
+	var b strings.Builder
+	b.WriteString("units: " + kh.units + "\n")
+
+	writeRow := func(label string, count func(*keyedCounts) int) {
+		fmt.Fprintf(&b, "%-*s:", rangeWidth, label)
+		for i, k := range keys {
+			fmt.Fprintf(&b, " %*d", colWidths[i], count(kh.keys[k]))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "%*s ", rangeWidth, "")
+	for i, k := range keys {
+		fmt.Fprintf(&b, " %*s", colWidths[i], k)
+	}
+	b.WriteString("\n")
+
+	if underflow > 0 {
+		writeRow(fmt.Sprintf("%*s      < "+valFmt, valWidth, "",
+			kh.bucketStart),
+			func(kc *keyedCounts) int { return kc.underflow })
+	}
+	for i := range kh.bucketCount {
+		lo := kh.bucketStart + float64(i)*kh.bucketWidth
+		writeRow(fmt.Sprintf(">= "+valFmt+" , < "+valFmt,
+			lo, lo+kh.bucketWidth),
+			func(kc *keyedCounts) int {
+				if kc.hist == nil {
+					return 0
+				}
+				return kc.hist[i]
+			})
+	}
+	if overflow > 0 {
+		writeRow(fmt.Sprintf(">= "+valFmt+"     %*s",
+			kh.bucketStart+float64(kh.bucketCount)*kh.bucketWidth,
+			valWidth, ""),
+			func(kc *keyedCounts) int { return kc.overflow })
+	}
+
+	return b.String()
+}
+
+// HeatMap returns the histograms as a grid with a row for each key and a
+// column for each bucket. Each cell is shaded according to the proportion
+// of the key's values in that bucket compared with its fullest bucket,
+// from a space for an empty bucket to a full block. The number of the
+// key's values in the underflow and overflow are shown at either end of
+// the row and the bucket boundaries are shown under the grid. It returns
+// the empty string if no values have been added.
+func (kh KeyedHist) HeatMap() string {
+	if len(kh.keys) == 0 {
+		return ""
+	}
+	kh = kh.laidOutCopy()
+	keys := kh.Keys()
+
+	keyWidth, uWidth, oWidth := 0, 1, 1
+	for _, k := range keys {
+		kc := kh.keys[k]
+		keyWidth = max(keyWidth, len(k))
+		uWidth = max(uWidth, mathutil.Digits(int64(kc.underflow)))
+		oWidth = max(oWidth, mathutil.Digits(int64(kc.overflow)))
+	}
+
+	var b strings.Builder
+	b.WriteString("units: " + kh.units + "\n")
+	for _, k := range keys {
+		kc := kh.keys[k]
+		fmt.Fprintf(&b, "%-*s %*d |", keyWidth, k, uWidth, kc.underflow)
+		maxCount := 0
+		if kc.hist != nil {
+			maxCount = slices.Max(kc.hist)
+		}
+		for i := range kh.bucketCount {
+			cell := heatRunes[0]
+			if maxCount > 0 && kc.hist[i] > 0 {
+				level := int(math.Ceil(float64(kc.hist[i]) /
+					float64(maxCount) * float64(len(heatRunes)-1)))
+				cell = heatRunes[min(level, len(heatRunes)-1)]
+			}
+			b.WriteRune(cell)
+		}
+		fmt.Fprintf(&b, "| %*d\n", oWidth, kc.overflow)
+	}
+
+	valFmt, _ := kh.valFmt()
+	fmt.Fprintf(&b, "%*s from "+valFmt+" to "+valFmt+
+		" in %d buckets of "+valFmt+"\n",
+		keyWidth+uWidth+1, "",
+		kh.bucketStart,
+		kh.bucketStart+float64(kh.bucketCount)*kh.bucketWidth,
+		kh.bucketCount, kh.bucketWidth)
+	return b.String()
+}
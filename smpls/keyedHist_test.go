@@ -0,0 +1,85 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestKeyedHist(t *testing.T) {
+	kh := NewKeyedHistOrPanic("ms", 5, 20)
+	testhelper.DiffString(t, "empty", "String", kh.String(), "")
+	testhelper.DiffString(t, "empty", "HeatMap", kh.HeatMap(), "")
+
+	for i := range 10 {
+		kh.Add("a", float64(i))
+		kh.Add("b", float64(i%2)+8)
+	}
+	kh.Add("b", -1) // below the range of the cached values
+	kh.Add("c", 20) // above it
+
+	testhelper.DiffStringSlice(t, "KeyedHist", "keys",
+		kh.Keys(), []string{"a", "b", "c"})
+	testhelper.DiffInt(t, "a", "count", kh.Count("a"), 10)
+	testhelper.DiffInt(t, "b", "count", kh.Count("b"), 11)
+	testhelper.DiffInt(t, "missing", "count", kh.Count("x"), 0)
+
+	underflow, buckets, overflow := kh.Buckets("b")
+	testhelper.DiffInt(t, "b", "underflow", underflow, 1)
+	testhelper.DiffInt(t, "b", "overflow", overflow, 0)
+	testhelper.DiffInt(t, "b", "buckets", len(buckets), 5)
+	testhelper.DiffInt(t, "b", "last bucket count", buckets[4].Count, 10)
+	testhelper.DiffFloat(t, "b", "first bucket low", buckets[0].Low, 0, 0)
+
+	_, buckets, overflow = kh.Buckets("c")
+	testhelper.DiffInt(t, "c", "overflow", overflow, 1)
+	testhelper.DiffInt(t, "c", "first bucket count", buckets[0].Count, 0)
+
+	testhelper.DiffString(t, "KeyedHist", "String", kh.String(),
+		"units: ms\n"+
+			"                   a  b c\n"+
+			"          < 0.00:  0  1 0\n"+
+			">= 0.00 , < 1.80:  2  0 0\n"+
+			">= 1.80 , < 3.60:  2  0 0\n"+
+			">= 3.60 , < 5.40:  2  0 0\n"+
+			">= 5.40 , < 7.20:  2  0 0\n"+
+			">= 7.20 , < 9.00:  2 10 0\n"+
+			">= 9.00         :  0  0 1\n")
+	testhelper.DiffString(t, "KeyedHist", "HeatMap", kh.HeatMap(),
+		"units: ms\n"+
+			"a 0 |█████| 0\n"+
+			"b 1 |    █| 0\n"+
+			"c 0 |     | 1\n"+
+			"    from 0.00 to 9.00 in 5 buckets of 1.80\n")
+
+	kh.Reset()
+	testhelper.DiffInt(t, "Reset", "keys", len(kh.Keys()), 0)
+}
+
+func TestKeyedHistCached(t *testing.T) {
+	kh := NewKeyedHistOrPanic("ms", 4, 0)
+	kh.Add("a", 1)
+	kh.Add("a", 2)
+	kh.Add("b", 5)
+
+	_, buckets, _ := kh.Buckets("b")
+	testhelper.DiffInt(t, "cached", "last bucket count", buckets[3].Count, 1)
+	testhelper.DiffBool(t, "cached", "still not laid out", kh.laidOut, false)
+	testhelper.DiffInt(t, "cached", "cached values", kh.cached, 3)
+
+	same := NewKeyedHistOrPanic("ms", 4, 2)
+	same.Add("a", 3)
+	same.Add("a", 3)
+	_, buckets, _ = same.Buckets("a")
+	testhelper.DiffInt(t, "same values", "bucket count", buckets[2].Count, 2)
+}
+
+func TestKeyedHistErrs(t *testing.T) {
+	_, err := NewKeyedHist("ms", 1, 0)
+	testhelper.DiffBool(t, "too few buckets", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+	_, err = NewKeyedHist("ms", 5, -1)
+	testhelper.DiffBool(t, "bad cache size", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+}
@@ -0,0 +1,174 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// labelSep separates the label values when forming the map key
+const labelSep = "\x00"
+
+// labeledStat associates a Stat with the label values that identify it
+type labeledStat struct {
+	labelVals []string
+	stat      *Stat
+}
+
+// LabeledStats holds a collection of Stats, each identified by a set of
+// label values (for instance, an endpoint and a status code). The Stats
+// are created as they are first needed and all share the same units and
+// options.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type LabeledStats struct {
+	units      string
+	labelNames []string
+	opts       []StatOpt
+	stats      map[string]*labeledStat
+}
+
+// NewLabeledStats creates a new LabeledStats. The label names give the
+// meaning of each of the label values used to identify the Stats and the
+// units and options are used when creating each Stat. An error is returned
+// if there are no label names, if any label name is repeated or if the
+// options are invalid.
+func NewLabeledStats(units string, labelNames []string, opts ...StatOpt,
+) (*LabeledStats, error) {
+	if len(labelNames) == 0 {
+		return nil, errors.New("no label names have been given")
+	}
+	for i, name := range labelNames {
+		if slices.Contains(labelNames[:i], name) {
+			return nil, fmt.Errorf("the label name %q is repeated", name)
+		}
+	}
+	if _, err := NewStat(units, opts...); err != nil {
+		return nil, err
+	}
+
+	return &LabeledStats{
+		units:      units,
+		labelNames: slices.Clone(labelNames),
+		opts:       opts,
+		stats:      map[string]*labeledStat{},
+	}, nil
+}
+
+// NewLabeledStatsOrPanic creates a new LabeledStats and will panic if any
+// errors are detected
+func NewLabeledStatsOrPanic(units string, labelNames []string,
+	opts ...StatOpt,
+) *LabeledStats {
+	ls, err := NewLabeledStats(units, labelNames, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ls
+}
+
+// LabelNames returns a copy of the label names
+func (ls LabeledStats) LabelNames() []string {
+	return slices.Clone(ls.labelNames)
+}
+
+// Len returns the number of Stats that have been created
+func (ls LabeledStats) Len() int {
+	return len(ls.stats)
+}
+
+// Stat returns the Stat identified by the label values, creating it if
+// necessary. An error is returned if the number of label values differs
+// from the number of label names.
+func (ls *LabeledStats) Stat(labelVals ...string) (*Stat, error) {
+	if len(labelVals) != len(ls.labelNames) {
+		return nil, fmt.Errorf(
+			"%d label values were given, %d are needed (%s)",
+			len(labelVals), len(ls.labelNames),
+			strings.Join(ls.labelNames, ", "))
+	}
+
+	key := strings.Join(labelVals, labelSep)
+	if lst, ok := ls.stats[key]; ok {
+		return lst.stat, nil
+	}
+
+	s, err := NewStat(ls.units, ls.opts...)
+	if err != nil {
+		return nil, err
+	}
+	ls.stats[key] = &labeledStat{labelVals: slices.Clone(labelVals), stat: s}
+	return s, nil
+}
+
+// StatOrPanic returns the Stat identified by the label values, creating
+// it if necessary. It will panic if any errors are detected.
+func (ls *LabeledStats) StatOrPanic(labelVals ...string) *Stat {
+	s, err := ls.Stat(labelVals...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Add adds the value to the Stat identified by the label values, creating
+// it if necessary
+func (ls *LabeledStats) Add(v float64, labelVals ...string) error {
+	s, err := ls.Stat(labelVals...)
+	if err != nil {
+		return err
+	}
+	s.Add(v)
+	return nil
+}
+
+// sorted returns the labeled Stats sorted by their label values
+func (ls LabeledStats) sorted() []*labeledStat {
+	lsts := make([]*labeledStat, 0, len(ls.stats))
+	for _, lst := range ls.stats {
+		lsts = append(lsts, lst)
+	}
+	slices.SortFunc(lsts, func(a, b *labeledStat) int {
+		return slices.Compare(a.labelVals, b.labelVals)
+	})
+	return lsts
+}
+
+// Labels returns the label values of each of the Stats, sorted
+func (ls LabeledStats) Labels() [][]string {
+	labels := make([][]string, 0, len(ls.stats))
+	for _, lst := range ls.sorted() {
+		labels = append(labels, slices.Clone(lst.labelVals))
+	}
+	return labels
+}
+
+// String returns a report of all the Stats grouped by their label
+// values. Each group is introduced by a line giving the label name and
+// value and is indented below that.
+func (ls LabeledStats) String() string {
+	const indent = "    "
+
+	var str string
+	var prevVals []string
+	last := len(ls.labelNames) - 1
+
+	for _, lst := range ls.sorted() {
+		diffIdx := 0
+		for diffIdx < last && diffIdx < len(prevVals) &&
+			prevVals[diffIdx] == lst.labelVals[diffIdx] {
+			diffIdx++
+		}
+		for i := diffIdx; i < last; i++ {
+			str += strings.Repeat(indent, i) +
+				ls.labelNames[i] + "=" + lst.labelVals[i] + "\n"
+		}
+		str += strings.Repeat(indent, last) +
+			ls.labelNames[last] + "=" + lst.labelVals[last] + ": " +
+			lst.stat.String() + "\n"
+		prevVals = lst.labelVals
+	}
+	return str
+}
@@ -0,0 +1,83 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewLabeledStats(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		labelNames []string
+		opts       []StatOpt
+	}{
+		{
+			ID:         testhelper.MkID("good"),
+			labelNames: []string{"endpoint", "status"},
+		},
+		{
+			ID:     testhelper.MkID("no label names"),
+			ExpErr: testhelper.MkExpErr("no label names have been given"),
+		},
+		{
+			ID:         testhelper.MkID("repeated label name"),
+			labelNames: []string{"endpoint", "status", "endpoint"},
+			ExpErr: testhelper.MkExpErr(
+				`the label name "endpoint" is repeated`),
+		},
+		{
+			ID:         testhelper.MkID("bad option"),
+			labelNames: []string{"endpoint"},
+			opts:       []StatOpt{StatCacheSize(1)},
+			ExpErr:     testhelper.MkExpErr("Invalid cache size (1)"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewLabeledStats("ms", tc.labelNames, tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestLabeledStats(t *testing.T) {
+	ls := NewLabeledStatsOrPanic("ms", []string{"endpoint", "status"})
+
+	for _, err := range []error{
+		ls.Add(1, "/b", "200"),
+		ls.Add(2, "/a", "500"),
+		ls.Add(3, "/a", "200"),
+		ls.Add(4, "/a", "200"),
+	} {
+		if err != nil {
+			t.Fatal("unexpected error adding a value:", err)
+		}
+	}
+
+	err := ls.Add(5, "/a")
+	testhelper.CheckExpErrWithID(t, "too few label values", err,
+		testhelper.MkExpErr(
+			"1 label values were given, 2 are needed (endpoint, status)"))
+
+	testhelper.DiffInt(t, "LabeledStats", "len", ls.Len(), 3)
+	testhelper.DiffInt(t, "LabeledStats", "count of /a 200",
+		ls.StatOrPanic("/a", "200").Count(), 2)
+
+	labels := ls.Labels()
+	expLabels := [][]string{{"/a", "200"}, {"/a", "500"}, {"/b", "200"}}
+	if testhelper.DiffInt(t, "LabeledStats", "labels",
+		len(labels), len(expLabels)) {
+		return
+	}
+	for i, l := range labels {
+		testhelper.DiffStringSlice(t, "LabeledStats", "label", l, expLabels[i])
+	}
+
+	testhelper.DiffString(t, "LabeledStats", "report", ls.String(),
+		"endpoint=/a\n"+
+			"    status=200: "+ls.StatOrPanic("/a", "200").String()+"\n"+
+			"    status=500: "+ls.StatOrPanic("/a", "500").String()+"\n"+
+			"endpoint=/b\n"+
+			"    status=200: "+ls.StatOrPanic("/b", "200").String()+"\n")
+}
@@ -0,0 +1,202 @@
+package smpls
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	float64Bytes = int(unsafe.Sizeof(float64(0)))
+	intBytes     = int(unsafe.Sizeof(int(0)))
+	anyBytes     = int(unsafe.Sizeof(any(nil)))
+
+	// freqEntryBytes is the approximate cost of each entry in the map of
+	// value frequencies, allowing for the overhead of the map
+	freqEntryBytes = 48
+)
+
+// MemUsage returns the approximate number of bytes used by the Stat. This
+// includes the Stat itself, the cache, the histogram, the slices of
+// minimum and maximum values and any optional parts such as the reservoir
+// or the frequency counts. It does not include the memory used by any
+// information added with the values (see AddWithInfo) nor by the string
+// template.
+func (s Stat) MemUsage() int {
+	n := int(unsafe.Sizeof(s))
+
+	n += (cap(s.mins) + cap(s.maxs)) * float64Bytes
+	n += (cap(s.minInfo) + cap(s.maxInfo)) * anyBytes
+	n += max(cap(s.cache), cap(s.cacheBuf)) * float64Bytes
+	n += cap(s.rebinHist)*intBytes + cap(s.rebinSums)*float64Bytes
+	n += cap(s.hist) * intBytes
+	n += cap(s.knots) * float64Bytes
+	if s.bucketSums {
+		n += max(cap(s.histSums), len(s.hist)) * float64Bytes
+	}
+
+	if s.rate != nil {
+		n += int(unsafe.Sizeof(*s.rate)) +
+			cap(s.rate.slots)*int(unsafe.Sizeof(rateSlot{}))
+	}
+	if s.freq != nil {
+		n += int(unsafe.Sizeof(*s.freq)) + len(s.freq.counts)*freqEntryBytes
+	}
+	if s.heavy != nil {
+		n += int(unsafe.Sizeof(*s.heavy)) +
+			cap(s.heavy.entries)*int(unsafe.Sizeof(hhEntry{})) +
+			len(s.heavy.idx)*freqEntryBytes
+	}
+	if s.warmup != nil {
+		n += int(unsafe.Sizeof(*s.warmup))
+	}
+	if s.remedian != nil {
+		n += int(unsafe.Sizeof(*s.remedian)) +
+			len(s.remedian.levels)*(int(unsafe.Sizeof([]float64{}))+
+				s.remedian.base*float64Bytes)
+	}
+	if s.distinct != nil {
+		n += int(unsafe.Sizeof(*s.distinct)) + cap(s.distinct.registers)
+	}
+	if s.reservoir != nil {
+		n += int(unsafe.Sizeof(*s.reservoir)) +
+			cap(s.reservoir.vals)*float64Bytes
+	}
+	if s.deadband != nil {
+		n += int(unsafe.Sizeof(*s.deadband))
+	}
+	if s.valid != nil {
+		n += int(unsafe.Sizeof(*s.valid))
+	}
+	if s.autocorr != nil {
+		n += int(unsafe.Sizeof(*s.autocorr)) +
+			(cap(s.autocorr.lagSums)+cap(s.autocorr.first)+
+				cap(s.autocorr.last))*float64Bytes
+	}
+	if s.runs != nil {
+		n += int(unsafe.Sizeof(*s.runs))
+	}
+	if s.tails != nil {
+		n += int(unsafe.Sizeof(*s.tails)) +
+			(cap(s.tails.lows)+cap(s.tails.highs))*float64Bytes
+	}
+	for _, snap := range s.history {
+		n += int(unsafe.Sizeof(snap)) +
+			cap(snap.Percentiles)*int(unsafe.Sizeof(Pctile{})) +
+			cap(snap.Buckets)*int(unsafe.Sizeof(Bucket{}))
+	}
+	if snap := s.lastCycle; snap != nil {
+		n += int(unsafe.Sizeof(*snap)) +
+			cap(snap.Percentiles)*int(unsafe.Sizeof(Pctile{})) +
+			cap(snap.Buckets)*int(unsafe.Sizeof(Bucket{}))
+	}
+	if s.fmtOpts != nil {
+		n += int(unsafe.Sizeof(*s.fmtOpts)) +
+			cap(s.fmtOpts.Fields)*int(unsafe.Sizeof(StatField(0)))
+	}
+
+	return n
+}
+
+// StatMaxMemory returns a function that will make the Stat choose the
+// sizes of its cache, its histogram and any reservoir (see
+// StatReservoirSize) so that the memory it uses (see MemUsage) is no
+// more than the given number of bytes. Any size given explicitly, with
+// StatCacheSize or StatHistBucketCount, is kept and the remaining sizes
+// are chosen to fit in what is left. The reservoir size is taken as the
+// largest size wanted. The sizes chosen are never larger than the default
+// sizes. An error is reported by NewStat if the Stat cannot be made to
+// fit.
+//
+// Note that the frequency counts (see StatTrackFreq) grow as values are
+// added and so these are not allowed for.
+func StatMaxMemory(bytes int) StatOpt {
+	return func(s *Stat) error {
+		if s.maxMemory != 0 {
+			return repeatedOption("the memory budget has already been set")
+		}
+		if bytes <= 0 {
+			return invalidValue("Invalid memory budget (%d) - it must be > 0",
+				bytes)
+		}
+
+		s.maxMemory = bytes
+		return nil
+	}
+}
+
+// fitMemory sizes the cache, histogram and reservoir so that the Stat will
+// use no more than the memory budget. It must be called after the options
+// have been applied and before the default cache and histogram are
+// created.
+func (s *Stat) fitMemory() error {
+	sizeHist := !s.noHist && s.hist == nil
+	sizeCache := !s.noCache && !s.noHist && s.cache == nil && s.expRange == nil
+
+	resvWanted := 0
+	if s.reservoir != nil {
+		resvWanted = cap(s.reservoir.vals)
+		s.reservoir = newReservoir(minReservoirSize)
+	}
+
+	bucketBytes := intBytes
+	if s.bucketSums {
+		bucketBytes += float64Bytes
+	}
+
+	histBuckets, cacheSize := 0, 0
+	if sizeHist {
+		histBuckets = minHistBucketCount
+	}
+	if sizeCache {
+		cacheSize = minCacheSize
+	}
+
+	needed := s.MemUsage() +
+		histBuckets*bucketBytes + cacheSize*float64Bytes
+	spare := s.maxMemory - needed
+	if spare < 0 {
+		return fmt.Errorf(
+			"the memory budget (%d bytes) is too small - at least %d are needed",
+			s.maxMemory, needed)
+	}
+
+	// the histogram is given up to an eighth of the spare memory, the rest
+	// is shared between the cache and the reservoir
+	if sizeHist {
+		extra := min(dfltHistBucketCount-histBuckets,
+			spare/8/bucketBytes)
+		histBuckets += extra
+		spare -= extra * bucketBytes
+		s.hist = make([]int, histBuckets)
+	}
+
+	resvSize := min(resvWanted, minReservoirSize)
+	growCache := func(share int) {
+		extra := min(dfltCacheSize-cacheSize, share/float64Bytes)
+		cacheSize += extra
+		spare -= extra * float64Bytes
+	}
+	growResv := func(share int) {
+		extra := min(resvWanted-resvSize, share/float64Bytes)
+		resvSize += extra
+		spare -= extra * float64Bytes
+	}
+	switch {
+	case sizeCache && resvWanted > 0:
+		growCache(spare / 2)
+		growResv(spare)
+		growCache(spare)
+	case sizeCache:
+		growCache(spare)
+	case resvWanted > 0:
+		growResv(spare)
+	}
+
+	if sizeCache {
+		s.cache = make([]float64, 0, cacheSize)
+	}
+	if resvWanted > 0 {
+		s.reservoir = newReservoir(resvSize)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMemUsage(t *testing.T) {
+	full := NewStatOrPanic("units")
+	small := NewStatOrPanic("units", StatNoHist())
+	if full.MemUsage() <= small.MemUsage() {
+		t.Errorf("a Stat with a cache and histogram (%d bytes)"+
+			" should use more than one without (%d bytes)",
+			full.MemUsage(), small.MemUsage())
+	}
+	if minSize := dfltCacheSize * float64Bytes; full.MemUsage() < minSize {
+		t.Errorf("the default Stat uses %d bytes, expected at least %d",
+			full.MemUsage(), minSize)
+	}
+
+	withResv := NewStatOrPanic("units", StatReservoirSize(100))
+	testhelper.DiffBool(t, "reservoir", "counted",
+		withResv.MemUsage() >= full.MemUsage()+100*float64Bytes, true)
+}
+
+func TestStatMaxMemory(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts        []StatOpt
+		expHist     int
+		expCache    int
+		expResvSize int
+	}{
+		{
+			ID:       testhelper.MkID("large budget"),
+			opts:     []StatOpt{StatMaxMemory(1 << 20)},
+			expHist:  dfltHistBucketCount,
+			expCache: dfltCacheSize,
+		},
+		{
+			ID:       testhelper.MkID("small budget"),
+			opts:     []StatOpt{StatMaxMemory(5120)},
+			expHist:  dfltHistBucketCount,
+			expCache: -1,
+		},
+		{
+			ID: testhelper.MkID("small budget, explicit hist"),
+			opts: []StatOpt{
+				StatMaxMemory(4096), StatHistBucketCount(10),
+			},
+			expHist:  10,
+			expCache: -1,
+		},
+		{
+			ID: testhelper.MkID("small budget, reservoir"),
+			opts: []StatOpt{
+				StatReservoirSize(1000), StatMaxMemory(8192),
+			},
+			expHist:     dfltHistBucketCount,
+			expCache:    -1,
+			expResvSize: -1,
+		},
+		{
+			ID: testhelper.MkID("budget too small"),
+			ExpErr: testhelper.MkExpErr("the memory budget (100 bytes)" +
+				" is too small - at least "),
+			opts: []StatOpt{StatMaxMemory(100)},
+		},
+		{
+			ID: testhelper.MkID("bad budget"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid memory budget (0) - it must be > 0"),
+			opts: []StatOpt{StatMaxMemory(0)},
+		},
+		{
+			ID: testhelper.MkID("budget set twice"),
+			ExpErr: testhelper.MkExpErr(
+				"the memory budget has already been set"),
+			opts: []StatOpt{StatMaxMemory(4096), StatMaxMemory(4096)},
+		},
+	}
+
+	for _, tc := range testCases {
+		s, err := NewStat("units", tc.opts...)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			if s.MemUsage() > s.maxMemory {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the Stat uses %d bytes, more than the budget: %d",
+					s.MemUsage(), s.maxMemory)
+			}
+			checkSize(t, tc.IDStr(), "hist buckets", len(s.hist), tc.expHist)
+			checkSize(t, tc.IDStr(), "cache size", cap(s.cache), tc.expCache)
+			if tc.expResvSize != 0 {
+				checkSize(t, tc.IDStr(), "reservoir size",
+					cap(s.reservoir.vals), tc.expResvSize)
+			}
+		}
+	}
+}
+
+// checkSize checks the size against the expected size; an expected size
+// of -1 means that the size should be reduced but not to zero
+func checkSize(t *testing.T, id, name string, size, exp int) {
+	t.Helper()
+
+	if exp >= 0 {
+		testhelper.DiffInt(t, id, name, size, exp)
+		return
+	}
+	if size <= 0 || size >= dfltCacheSize {
+		t.Log(id)
+		t.Errorf("\t: unexpected %s: %d", name, size)
+	}
+}
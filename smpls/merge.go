@@ -0,0 +1,391 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Merge adds the values recorded by the other Stat to this Stat as if they
+// had been added to it directly. The Stats must have the same units and if
+// this Stat has a histogram the other must have one too.
+//
+// Where the histograms have different bucket boundaries a new set of
+// buckets covering both is chosen and the counts from each are spread
+// across the new buckets in proportion to the overlap; the values are
+// taken to be evenly spread across each bucket. Where the values are still
+// held they are added exactly.
+//
+// The minimum and maximum values are merged but if the other Stat keeps
+// fewer of them than this one some may be missing. The estimate of the
+// number of distinct values is only merged if both Stats track it, in which
+// case they must have the same precision. Similarly the random sample of
+// values is only merged if both Stats keep one and the runs of values (see
+// StatTrackRuns) only if both Stats track them, in which case they must
+// have the same threshold and the values of the other Stat are taken to
+// have been added after those of this Stat. The autocorrelation (see
+// StatAutocorrelation) is treated in the same way. The counts of values
+// suppressed by a deadband (see StatDeadband) are added if both Stats have
+// one, as are the counts of values outside the valid range (see
+// StatRange). The counts of the most frequent values (see
+// StatHeavyHitters) are only merged if both Stats keep them and the
+// approximate median (see StatApproxMedian) only if both Stats estimate it,
+// in which case they must have the same base. The time-weighted statistics
+// (see AddFor) are merged. The Rate, if any, is not changed. If this Stat
+// was created by Fork the values are also merged into the parent Stat.
+//
+// If the other Stat has a sample rate (see StatSampleRate) and this one
+// does not then the values the other Stat recorded are taken to stand for
+// all the values added to it: its count, sums and histogram counts are
+// scaled up by the sampling factor before they are merged.
+func (s *Stat) Merge(other *Stat) error {
+	if s.units != other.units {
+		return fmt.Errorf("cannot merge Stats with different units: %q and %q",
+			s.units, other.units)
+	}
+	if !s.noHist && other.noHist && other.count > 0 {
+		return errors.New(
+			"cannot merge a Stat with no histogram into one with a histogram")
+	}
+	if !s.noHist && !other.noHist && s.logBase != other.logBase {
+		return fmt.Errorf("cannot merge Stats with different"+
+			" histogram log bases: %g and %g", s.logBase, other.logBase)
+	}
+	if s.distinct != nil && other.distinct != nil &&
+		s.distinct.precision != other.distinct.precision {
+		return fmt.Errorf("cannot merge Stats with different"+
+			" distinct value precisions: %d and %d",
+			s.distinct.precision, other.distinct.precision)
+	}
+	if s.runs != nil && other.runs != nil &&
+		s.runs.threshold != other.runs.threshold {
+		return fmt.Errorf("cannot merge Stats with different"+
+			" run thresholds: %g and %g",
+			s.runs.threshold, other.runs.threshold)
+	}
+	if s.autocorr != nil && other.autocorr != nil &&
+		s.autocorr.maxLag != other.autocorr.maxLag {
+		return fmt.Errorf("cannot merge Stats with different"+
+			" autocorrelation lags: %d and %d",
+			s.autocorr.maxLag, other.autocorr.maxLag)
+	}
+	if s.remedian != nil && other.remedian != nil &&
+		s.remedian.base != other.remedian.base {
+		return fmt.Errorf("cannot merge Stats with different"+
+			" remedian bases: %d and %d",
+			s.remedian.base, other.remedian.base)
+	}
+	if other.count == 0 {
+		return nil
+	}
+
+	o := other.Clone() // in case other is s or is changed by merging
+	if s.sampleRate == 0 {
+		o.unsample()
+	}
+	sPending := slices.Clone(s.histPending())
+	oPending := o.histPending()
+	wasEmpty := s.count == 0
+	var sSegs []histSegment
+	if !s.noHist && sPending == nil && !wasEmpty {
+		sSegs = s.histSegments()
+	}
+
+	s.mergeTotals(o)
+	s.adds += o.Count()
+	s.mergeExtremes(o)
+	if s.heavy != nil && o.heavy != nil {
+		s.heavy.merge(o.heavy)
+	}
+	if s.remedian != nil && o.remedian != nil {
+		s.remedian.merge(o.remedian)
+	}
+	if s.freq != nil {
+		s.freq.merge(o.freq, o.count)
+	}
+	if s.distinct != nil && o.distinct != nil {
+		_ = s.distinct.Merge(o.distinct) // the precisions are checked above
+	}
+	if s.reservoir != nil && o.reservoir != nil {
+		s.reservoir.merge(o.reservoir)
+	}
+	if s.runs != nil && o.runs != nil {
+		s.runs.merge(o.runs)
+	}
+	if s.autocorr != nil && o.autocorr != nil {
+		s.autocorr.merge(o.autocorr)
+	}
+	if s.valid != nil && o.valid != nil {
+		s.valid.outside += o.valid.outside
+	}
+	if s.deadband != nil && o.deadband != nil {
+		s.deadband.suppressed += o.deadband.suppressed
+	}
+	s.tw.merge(o.tw)
+	s.hot = false
+
+	if !s.noHist {
+		s.mergeHist(o, sPending, oPending, sSegs, wasEmpty)
+	}
+
+	if s.parent != nil {
+		return s.parent.Merge(o)
+	}
+	return nil
+}
+
+// MergeHist returns a new Stat holding the values recorded by both the
+// Stats. It is created as a copy of the first Stat (see Clone) and the
+// second is merged into it (see Merge).
+func MergeHist(a, b *Stat) (*Stat, error) {
+	s := a.Clone()
+	if err := s.Merge(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// mergeTotals adds the count, sum and sum of squares of the other Stat
+func (s *Stat) mergeTotals(o *Stat) {
+	if s.compensated {
+		compensatedAdd(&s.sum, &s.sumC, o.sum)
+		compensatedAdd(&s.sumSq, &s.sumSqC, o.sumSq)
+		s.sumC += o.sumC
+		s.sumSqC += o.sumSqC
+	} else {
+		s.sum += o.fullSum()
+		s.sumSq += o.fullSumSq()
+	}
+	s.count += o.count
+	if s.sampleRate > 0 {
+		s.calls += o.Count()
+		s.sampled = int(float64(s.calls)*s.sampleRate +
+			sampleRoundingAllowance)
+	}
+}
+
+// extremeCandidates returns the values which may be among the minimum or
+// maximum values of the merged Stat along with their information, if any.
+// If all the values are retained they are returned, otherwise the given
+// minimum or maximum values are returned.
+func extremeCandidates(st *Stat, extremes []float64, info []any) []ValInfo {
+	if vals := st.retained(); vals != nil && len(vals) > len(extremes) {
+		return valInfos(vals, nil)
+	}
+	return valInfos(extremes, info)
+}
+
+// mergeExtremes merges the minimum and maximum values of the other Stat
+func (s *Stat) mergeExtremes(o *Stat) {
+	byVal := func(a, b ValInfo) int {
+		switch {
+		case a.Val < b.Val:
+			return -1
+		case a.Val > b.Val:
+			return 1
+		}
+		return 0
+	}
+
+	mins := append(valInfos(s.mins, s.minInfo),
+		extremeCandidates(o, o.mins, o.minInfo)...)
+	slices.SortStableFunc(mins, byVal)
+	mins = mins[:min(len(mins), cap(s.mins))]
+
+	maxs := append(valInfos(s.maxs, s.maxInfo),
+		extremeCandidates(o, o.maxs, o.maxInfo)...)
+	slices.SortStableFunc(maxs, byVal)
+	maxs = maxs[len(maxs)-min(len(maxs), cap(s.maxs)):]
+
+	s.mins, s.minInfo = unpackValInfos(mins, s.mins, s.minInfo)
+	s.maxs, s.maxInfo = unpackValInfos(maxs, s.maxs, s.maxInfo)
+}
+
+// unpackValInfos copies the values and, if info is not nil, the
+// information into the slices, reusing them, and returns them
+func unpackValInfos(vis []ValInfo, vals []float64, info []any,
+) ([]float64, []any) {
+	vals = vals[:0]
+	for _, vi := range vis {
+		vals = append(vals, vi.Val)
+	}
+	if info != nil {
+		info = info[:0]
+		for _, vi := range vis {
+			info = append(info, vi.Info)
+		}
+	}
+	return vals, info
+}
+
+// mergeHist merges the histogram (or the values pending for the histogram)
+// of the other Stat. The totals and the minimum and maximum values must
+// already have been merged. The pending values and the segments of this
+// Stat's histogram are those from before the merge.
+func (s *Stat) mergeHist(o *Stat, sPending, oPending []float64,
+	sSegs []histSegment, wasEmpty bool,
+) {
+	if sPending != nil || wasEmpty {
+		if oPending != nil {
+			s.mergePending(append(sPending, oPending...))
+			return
+		}
+		s.layoutHist(sPending)
+		s.cache = nil
+		sSegs = nil
+	}
+
+	if oPending != nil {
+		for _, v := range oPending {
+			s.addToHist(v)
+		}
+		return
+	}
+
+	s.mergeLaidOutHist(o, sSegs)
+}
+
+// mergePending handles the case where neither Stat had laid out its
+// histogram. The values are all the pending values from both Stats.
+func (s *Stat) mergePending(vals []float64) {
+	switch {
+	case s.cache != nil && len(vals) < cap(s.cache):
+		s.cache = append(s.cache[:0], vals...)
+	case s.noCache && s.count < cap(s.mins):
+		// the values are all held in the slice of minimum values
+	default:
+		s.layoutHist(vals)
+		s.cache = nil
+	}
+}
+
+// mergeLaidOutHist merges the laid out histogram of the other Stat into
+// the laid out histogram of this Stat. If this histogram does not cover
+// the other histogram new buckets are chosen to cover them both and the
+// segments of this histogram are spread across them. If this histogram is
+// laid out at quantiles the new buckets are at the quantiles of the
+// segments of both histograms.
+func (s *Stat) mergeLaidOutHist(o *Stat, sSegs []histSegment) {
+	_, sEnd := s.bucketLimits(len(s.hist) - 1)
+	_, oEnd := o.bucketLimits(len(o.hist) - 1)
+	sEnd, oEnd = s.histPos(sEnd), s.histPos(oEnd)
+	oStart := s.histPos(o.histStart())
+
+	if o.bucketStart == s.bucketStart && o.bucketWidth == s.bucketWidth &&
+		len(o.hist) == len(s.hist) && slices.Equal(o.knots, s.knots) {
+		for i, count := range o.hist {
+			s.hist[i] += count
+		}
+		s.underflow += o.underflow
+		s.overflow += o.overflow
+		if s.tails != nil && o.tails != nil {
+			s.tails.merge(o.tails)
+		}
+		if s.histSums != nil {
+			for i := range o.hist {
+				s.histSums[i] += o.bucketSegment(i).knownSum()
+			}
+			s.underflowSum += o.underflowSegment().knownSum()
+			s.overflowSum += o.overflowSegment().knownSum()
+		}
+		return
+	}
+
+	segs := o.histSegments()
+	if oStart < s.bucketStart || oEnd > sEnd ||
+		(s.quantileLayout && !s.quantiled()) {
+		segs = append(segs, sSegs...)
+
+		if s.quantileLayout {
+			s.setKnots(segmentKnots(segs, len(s.hist)))
+		} else {
+			s.setHistLayout(math.Min(s.bucketStart, oStart),
+				math.Max(sEnd, oEnd))
+		}
+		clear(s.hist)
+		clear(s.histSums)
+		s.underflow = 0
+		s.overflow = 0
+		if s.tails != nil {
+			s.tails.reset()
+		}
+		s.underflowSum = 0
+		s.overflowSum = 0
+	}
+
+	for _, seg := range segs {
+		s.spreadSegment(seg)
+	}
+}
+
+// spreadSegment adds the count of values in the segment to the histogram
+// spreading it across the buckets in proportion to the overlap. Any part
+// of the segment below the start or above the end of the histogram is
+// added to the underflow or overflow. The counts are rounded so that the
+// total added is exactly the segment count. The sum of the values, if
+// recorded, is shared out in proportion to the counts.
+func (s *Stat) spreadSegment(seg histSegment) {
+	sum := seg.knownSum()
+	if seg.hi <= seg.lo {
+		s.addCountToHist(seg.lo, seg.count, sum)
+		return
+	}
+
+	count := float64(seg.count)
+	meanVal := sum / count
+	width := seg.hi - seg.lo
+	added := 0
+	// share returns the part of the count below hi not yet added
+	share := func(hi float64) int {
+		covered := math.Max(0, math.Min(1, (hi-seg.lo)/width))
+		target := int(math.Round(count * covered))
+		n := target - added
+		added = target
+		return n
+	}
+
+	n := share(s.histStart())
+	s.addCountToHist(seg.lo, n, meanVal*float64(n))
+	for i := range s.hist {
+		_, hi := s.bucketLimits(i)
+		n := share(hi)
+		s.addToBucket(i, n, meanVal*float64(n))
+	}
+	n = seg.count - added
+	s.addCountToHist(seg.hi, n, meanVal*float64(n))
+}
+
+// addCountToHist adds the count and the sum to the bucket holding the
+// value. Values below the start of the histogram are added to the
+// underflow and values above the end are added to the overflow.
+func (s *Stat) addCountToHist(v float64, count int, sum float64) {
+	idx := s.bucketIdx(v)
+	switch {
+	case idx < 0:
+		s.underflow += count
+		s.underflowSum += sum
+	case idx >= len(s.hist):
+		s.overflow += count
+		s.overflowSum += sum
+	default:
+		s.addToBucket(idx, count, sum)
+	}
+}
+
+// merge adds the counts from the other freqTracker. If the other is nil its
+// values are counted as evicted.
+func (ft *freqTracker) merge(other *freqTracker, otherCount int) {
+	if other == nil {
+		ft.evicted += otherCount
+		return
+	}
+
+	for _, vc := range other.sorted() {
+		if _, ok := ft.counts[vc.Val]; !ok && len(ft.counts) >= ft.maxSize {
+			ft.evictLeastFrequent()
+		}
+		ft.counts[vc.Val] += vc.Count
+	}
+	ft.evicted += other.evicted
+}
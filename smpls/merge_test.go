@@ -0,0 +1,166 @@
+package smpls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMergeCached(t *testing.T) {
+	a := NewStatOrPanic("ms", StatMinMaxCount(5))
+	b := NewStatOrPanic("ms", StatMinMaxCount(5))
+	all := NewStatOrPanic("ms", StatMinMaxCount(5))
+	addSeq(a, 1, 2, 50)
+	addSeq(b, 2, 2, 50)
+	addSeq(all, 1, 2, 50)
+	addSeq(all, 2, 2, 50)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	testhelper.DiffString(t, "cached", "String", a.String(), all.String())
+	testhelper.DiffString(t, "cached", "Hist", a.Hist(), all.Hist())
+	testhelper.DiffFloat(t, "cached", "p90",
+		a.Percentile(90), all.Percentile(90), 0.0)
+	testhelper.DiffInt(t, "cached", "b count", b.Count(), 50)
+}
+
+func TestMergeHist(t *testing.T) {
+	opts := []StatOpt{StatCacheSize(1000), StatHistBucketCount(10)}
+	lo := NewStatOrPanic("ms", opts...)
+	hi := NewStatOrPanic("ms", opts...)
+	addSeq(lo, 0.05, 0.1, 1000)
+	addSeq(hi, 100.05, 0.1, 1000)
+
+	merged, err := MergeHist(lo, hi)
+	if err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	testhelper.DiffInt(t, "MergeHist", "count", merged.Count(), 2000)
+	testhelper.DiffInt(t, "MergeHist", "histogram total",
+		histTotal(merged), 2000)
+	testhelper.DiffFloat(t, "MergeHist", "min", merged.Min(), 0.05, 0.0)
+	testhelper.DiffFloat(t, "MergeHist", "max", merged.Max(), 199.95, 1e-9)
+	testhelper.DiffFloat(t, "MergeHist", "mean", merged.Mean(), 100, 1e-9)
+	_, end := merged.bucketLimits(len(merged.hist) - 1)
+	testhelper.DiffBool(t, "MergeHist", "covers both",
+		merged.bucketStart <= 0.05 && end > 199.95, true)
+	testhelper.DiffFloat(t, "MergeHist", "p25", merged.Percentile(25),
+		50, merged.bucketWidth)
+	testhelper.DiffFloat(t, "MergeHist", "p75", merged.Percentile(75),
+		150, merged.bucketWidth)
+	testhelper.DiffInt(t, "MergeHist", "lo count unchanged", lo.Count(), 1000)
+
+	// the same layout is merged exactly
+	double, err := MergeHist(lo, lo)
+	if err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	for i, count := range double.hist {
+		testhelper.DiffInt(t, "MergeHist - same layout", "bucket count",
+			count, 2*lo.hist[i])
+	}
+
+	// the pending values are added exactly
+	few := NewStatOrPanic("ms", opts...)
+	few.Add(151, 151.5)
+	withFew, err := MergeHist(hi, few)
+	if err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	testhelper.DiffInt(t, "MergeHist - pending", "histogram total",
+		histTotal(withFew), 1002)
+	idx := int((151 - withFew.bucketStart) / withFew.bucketWidth)
+	testhelper.DiffInt(t, "MergeHist - pending", "bucket count",
+		withFew.hist[idx], hi.hist[idx]+2)
+}
+
+func TestMergeFork(t *testing.T) {
+	parent := NewStatOrPanic("ms", StatTrackFreq(10))
+	child := parent.Fork()
+	other := NewStatOrPanic("ms")
+	other.Add(1, 2, 3)
+
+	if err := child.Merge(other); err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	testhelper.DiffInt(t, "Merge - Fork", "child count", child.Count(), 3)
+	testhelper.DiffInt(t, "Merge - Fork", "parent count", parent.Count(), 3)
+	testhelper.DiffInt(t, "Merge - Fork", "parent freq evicted",
+		parent.FreqEvicted(), 3)
+
+	tracked := NewStatOrPanic("ms", StatTrackFreq(10))
+	tracked.Add(2, 2)
+	if err := parent.Merge(tracked); err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	mode, count, _ := parent.Mode()
+	testhelper.DiffFloat(t, "Merge - freq", "mode", mode, 2, 0.0)
+	testhelper.DiffInt(t, "Merge - freq", "mode count", count, 2)
+}
+
+func TestMergeErrs(t *testing.T) {
+	s := NewStatOrPanic("ms")
+
+	err := s.Merge(NewStatOrPanic("s"))
+	testhelper.CheckExpErrWithID(t, "different units", err,
+		testhelper.MkExpErr(
+			`cannot merge Stats with different units: "ms" and "s"`))
+
+	noHist := NewStatOrPanic("ms", StatNoHist())
+	noHist.Add(1)
+	err = s.Merge(noHist)
+	testhelper.CheckExpErrWithID(t, "no histogram", err,
+		testhelper.MkExpErr("cannot merge a Stat with no histogram"))
+
+	err = noHist.Merge(s)
+	testhelper.CheckExpErrWithID(t, "into no histogram", err,
+		testhelper.ExpErr{})
+}
+
+func TestMergeNoCache(t *testing.T) {
+	a := NewStatOrPanic("ms", StatNoCache())
+	b := NewStatOrPanic("ms", StatNoCache())
+	addSeq(a, 0, 1, 15)
+	addSeq(b, 15, 1, 15)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal("unexpected error merging:", err)
+	}
+	testhelper.DiffInt(t, "no cache", "histogram total", histTotal(a), 30)
+	testhelper.DiffFloat(t, "no cache", "min", a.Min(), 0, 0.0)
+	testhelper.DiffFloat(t, "no cache", "max", a.Max(), 29, 0.0)
+
+	a.Add(30)
+	testhelper.DiffInt(t, "no cache - after Add", "histogram total",
+		histTotal(a), 31)
+}
+
+func TestMergeSampled(t *testing.T) {
+	for _, n := range []int{50, 1000} {
+		id := fmt.Sprintf("%d sampled values", n)
+		plain := NewStatOrPanic("ms")
+		sampled := NewStatOrPanic("ms", StatSampleRate(0.1))
+		all := NewStatOrPanic("ms")
+		addSeq(plain, 1, 1, 100)
+		addSeq(sampled, 1, 1, n)
+		addSeq(all, 1, 1, 100)
+		addSeq(all, 1, 1, n)
+
+		if err := plain.Merge(sampled); err != nil {
+			t.Fatal("unexpected error merging:", err)
+		}
+		testhelper.DiffInt(t, id, "count", plain.Count(), all.Count())
+		testhelper.DiffInt(t, id, "histogram total",
+			histTotal(plain), plain.Count())
+		expSum := 5050 + sampled.Sum()
+		testhelper.DiffFloat(t, id, "sum", plain.Sum(), expSum, 1e-9)
+		testhelper.DiffFloat(t, id, "mean", plain.Mean(),
+			expSum/float64(100+n), 1e-9)
+		testhelper.DiffFloat(t, id, "median",
+			plain.Percentile(50), all.Percentile(50),
+			all.Percentile(50)*0.1)
+		testhelper.DiffInt(t, id, "sampled count", sampled.Count(), n)
+	}
+}
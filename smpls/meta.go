@@ -0,0 +1,44 @@
+package smpls
+
+import (
+	"maps"
+	"slices"
+)
+
+// SetMeta records a piece of static metadata, such as the host name, the
+// build version or the test scenario, with the Stat. The metadata is
+// copied into Snapshots, saved by Save and can be shown in a Report (see
+// ReportMeta) so that it travels with the statistics. Setting a key that
+// is already set replaces its value.
+func (s *Stat) SetMeta(key, value string) {
+	if s.meta == nil {
+		s.meta = map[string]string{}
+	}
+	s.meta[key] = value
+}
+
+// DeleteMeta removes the metadata with the given key
+func (s *Stat) DeleteMeta(key string) {
+	delete(s.meta, key)
+}
+
+// Meta returns a copy of the metadata recorded with the Stat (see
+// SetMeta). It returns nil if there is none.
+func (s Stat) Meta() map[string]string {
+	if len(s.meta) == 0 {
+		return nil
+	}
+	return maps.Clone(s.meta)
+}
+
+// MetaValue returns the value of the metadata with the given key and true
+// or an empty string and false if the key is not set
+func (s Stat) MetaValue(key string) (string, bool) {
+	v, ok := s.meta[key]
+	return v, ok
+}
+
+// sortedMetaKeys returns the keys of the metadata, sorted
+func sortedMetaKeys(meta map[string]string) []string {
+	return slices.Sorted(maps.Keys(meta))
+}
@@ -0,0 +1,139 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// metaStatUnits are the units of the Stat recording the number of values
+// in each run
+const metaStatUnits = "values"
+
+// MetaStat collects statistics on the statistics of a number of runs, such
+// as repeated runs of a benchmark. Each run is summarised by a Snapshot
+// and the MetaStat records the distribution, across the runs, of each of
+// the values in the Snapshots: the means, the standard deviations, the
+// p99 values and so on. This lets you see how stable the results are from
+// one run to the next; for instance the mean of the means and the spread
+// of the p99 values.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type MetaStat struct {
+	units string
+	runs  int
+	stats [fieldCount]*Stat
+}
+
+// NewMetaStat creates a new MetaStat for runs with values in the given
+// units. The options are used when creating the Stat for each of the
+// values recorded across the runs.
+func NewMetaStat(units string, opts ...StatOpt) (*MetaStat, error) {
+	ms := &MetaStat{units: units}
+	for f := range ms.stats {
+		u := units
+		if StatField(f) == FieldCount {
+			u = metaStatUnits
+		}
+		s, err := NewStat(u, opts...)
+		if err != nil {
+			return nil, err
+		}
+		ms.stats[f] = s
+	}
+	return ms, nil
+}
+
+// NewMetaStatOrPanic creates a new MetaStat and will panic if any errors
+// are detected
+func NewMetaStatOrPanic(units string, opts ...StatOpt) *MetaStat {
+	ms, err := NewMetaStat(units, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+// AddSnapshot adds the Snapshot of a run to the MetaStat. An error is
+// returned if the Snapshot has no values or if its units differ from those
+// of the MetaStat.
+func (ms *MetaStat) AddSnapshot(snap Snapshot) error {
+	if snap.Units != ms.units {
+		return fmt.Errorf(
+			"the snapshot units (%q) differ from the MetaStat units (%q)",
+			snap.Units, ms.units)
+	}
+	if snap.Count == 0 {
+		return errors.New("the snapshot has no values")
+	}
+
+	ms.runs++
+	for f, s := range ms.stats {
+		if v := snap.fieldVal(StatField(f)); !math.IsNaN(v) {
+			s.Add(v)
+		}
+	}
+	return nil
+}
+
+// AddStat adds a Snapshot of the Stat to the MetaStat. See AddSnapshot.
+func (ms *MetaStat) AddStat(s *Stat) error {
+	return ms.AddSnapshot(s.Snapshot())
+}
+
+// Runs returns the number of runs added
+func (ms MetaStat) Runs() int {
+	return ms.runs
+}
+
+// Units returns the units of the values in each run
+func (ms MetaStat) Units() string {
+	return ms.units
+}
+
+// Field returns the Stat recording the distribution of the given value
+// across the runs. For instance Field(FieldP99) returns the Stat of the
+// p99 values of the runs. It returns nil if the field is not valid.
+func (ms MetaStat) Field(f StatField) *Stat {
+	if f < 0 || f >= fieldCount {
+		return nil
+	}
+	return ms.stats[f]
+}
+
+// Means returns the Stat recording the means of the runs
+func (ms MetaStat) Means() *Stat {
+	return ms.stats[FieldMean]
+}
+
+// StdDevs returns the Stat recording the standard deviations of the runs
+func (ms MetaStat) StdDevs() *Stat {
+	return ms.stats[FieldStdDev]
+}
+
+// P99s returns the Stat recording the p99 values of the runs
+func (ms MetaStat) P99s() *Stat {
+	return ms.stats[FieldP99]
+}
+
+// Reset discards all the runs
+func (ms *MetaStat) Reset() {
+	ms.runs = 0
+	for _, s := range ms.stats {
+		s.Reset()
+	}
+}
+
+// String returns a report with a row for each of the values recorded for
+// the runs showing the distribution of that value across the runs
+func (ms MetaStat) String() string {
+	r := NewReportOrPanic(ReportFormat(FmtOpts{
+		Style:  FmtGeneral,
+		Fields: []StatField{FieldMean, FieldStdDev, FieldMin, FieldMax},
+	}))
+	for f, s := range ms.stats {
+		r.AddStat(StatField(f).String(), s)
+	}
+	return fmt.Sprintf("runs: %d\n", ms.runs) + r.String()
+}
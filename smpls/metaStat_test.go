@@ -0,0 +1,54 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMetaStat(t *testing.T) {
+	ms := NewMetaStatOrPanic("ms")
+
+	err := ms.AddStat(NewStatOrPanic("ms"))
+	testhelper.CheckExpErrWithID(t, "empty run", err,
+		testhelper.MkExpErr("the snapshot has no values"))
+	err = ms.AddSnapshot(Snapshot{Units: "s", Count: 1})
+	testhelper.CheckExpErrWithID(t, "bad units", err,
+		testhelper.MkExpErr(`the snapshot units ("s") differ`))
+
+	for run := 0; run < 3; run++ {
+		s := NewStatOrPanic("ms")
+		for i := 1; i <= 100; i++ {
+			s.Add(float64(i + 10*run))
+		}
+		err = ms.AddStat(s)
+		testhelper.CheckExpErrWithID(t, "good run", err, testhelper.ExpErr{})
+	}
+
+	testhelper.DiffInt(t, "MetaStat", "runs", ms.Runs(), 3)
+	testhelper.DiffInt(t, "MetaStat", "means count", ms.Means().Count(), 3)
+	testhelper.DiffFloat(t, "MetaStat", "mean of the means",
+		ms.Means().Mean(), 60.5, 1e-9)
+	testhelper.DiffFloat(t, "MetaStat", "min of the means",
+		ms.Means().Min(), 50.5, 1e-9)
+	testhelper.DiffFloat(t, "MetaStat", "spread of the SDs",
+		ms.StdDevs().StdDev(), 0, 1e-9)
+	testhelper.DiffInt(t, "MetaStat", "p99 count", ms.P99s().Count(), 3)
+	testhelper.DiffFloat(t, "MetaStat", "range of the p99s",
+		ms.P99s().Max()-ms.P99s().Min(), 20, 1)
+	testhelper.DiffFloat(t, "MetaStat", "mean count",
+		ms.Field(FieldCount).Mean(), 100, 0)
+	testhelper.DiffString(t, "MetaStat", "count units",
+		ms.Field(FieldCount).Units(), "values")
+	if ms.Field(fieldCount) != nil {
+		t.Error("an invalid field should give a nil Stat")
+	}
+
+	str := ms.String()
+	testhelper.ShouldContain(t, "MetaStat", "string", str,
+		[]string{"runs: 3\n", "avg", "p99", "SD"})
+
+	ms.Reset()
+	testhelper.DiffInt(t, "Reset", "runs", ms.Runs(), 0)
+	testhelper.DiffInt(t, "Reset", "means count", ms.Means().Count(), 0)
+}
@@ -0,0 +1,120 @@
+package smpls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMeta(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	testhelper.DiffInt(t, "new Stat", "metadata count", len(s.Meta()), 0)
+
+	s.SetMeta("host", "alpha")
+	s.SetMeta("build", "v1.2.3")
+	s.SetMeta("host", "beta")
+	expMeta := map[string]string{"host": "beta", "build": "v1.2.3"}
+	if err := testhelper.DiffVals(s.Meta(), expMeta); err != nil {
+		t.Errorf("unexpected metadata: %v", err)
+	}
+
+	v, ok := s.MetaValue("build")
+	testhelper.DiffString(t, "MetaValue", "value", v, "v1.2.3")
+	testhelper.DiffBool(t, "MetaValue", "ok", ok, true)
+	_, ok = s.MetaValue("scenario")
+	testhelper.DiffBool(t, "MetaValue - missing", "ok", ok, false)
+
+	m := s.Meta()
+	m["host"] = "changed"
+	v, _ = s.MetaValue("host")
+	testhelper.DiffString(t, "Meta returns a copy", "host", v, "beta")
+
+	s.Add(1, 2, 3)
+	s.Reset()
+	if err := testhelper.DiffVals(s.Meta(), expMeta); err != nil {
+		t.Errorf("the metadata should survive a Reset: %v", err)
+	}
+
+	c := s.Clone()
+	f := s.Fork()
+	s.SetMeta("scenario", "peak")
+	for name, other := range map[string]*Stat{"Clone": c, "Fork": f} {
+		if err := testhelper.DiffVals(other.Meta(), expMeta); err != nil {
+			t.Errorf("%s: unexpected metadata: %v", name, err)
+		}
+	}
+
+	s.DeleteMeta("scenario")
+	_, ok = s.MetaValue("scenario")
+	testhelper.DiffBool(t, "DeleteMeta", "ok", ok, false)
+}
+
+func TestMetaTravels(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	s.SetMeta("host", "alpha")
+	s.SetMeta("scenario", "peak")
+	s.Add(1, 2, 3)
+	expMeta := map[string]string{"host": "alpha", "scenario": "peak"}
+
+	snap := s.Snapshot()
+	if err := testhelper.DiffVals(snap.Meta, expMeta); err != nil {
+		t.Errorf("Snapshot: unexpected metadata: %v", err)
+	}
+
+	b, err := snap.MarshalProto()
+	if err != nil {
+		t.Fatalf("unexpected error marshalling: %v", err)
+	}
+	var got Snapshot
+	if err := got.UnmarshalProto(b); err != nil {
+		t.Fatalf("unexpected error unmarshalling: %v", err)
+	}
+	if err := testhelper.DiffVals(got.Meta, expMeta); err != nil {
+		t.Errorf("UnmarshalProto: unexpected metadata: %v", err)
+	}
+
+	seeded := NewStatOrPanic("ms", StatFromSnapshot(snap))
+	if err := testhelper.DiffVals(seeded.Meta(), expMeta); err != nil {
+		t.Errorf("StatFromSnapshot: unexpected metadata: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if err := testhelper.DiffVals(loaded.Meta(), expMeta); err != nil {
+		t.Errorf("Load: unexpected metadata: %v", err)
+	}
+}
+
+func TestReportMeta(t *testing.T) {
+	_, err := NewReport(ReportMeta("host", "host"))
+	testhelper.CheckExpErrWithID(t, "ReportMeta - repeated key", err,
+		testhelper.MkExpErr(`the metadata key "host" is already shown`))
+
+	a := NewStatOrPanic("ms")
+	a.SetMeta("host", "alpha")
+	a.SetMeta("build", "v1")
+	a.Add(1, 2, 3)
+	b := NewStatOrPanic("ms")
+	b.SetMeta("host", "beta-long")
+	b.Add(10)
+
+	r := NewReportOrPanic(
+		ReportFormat(FmtOpts{
+			Style:  FmtGeneral,
+			Fields: []StatField{FieldCount, FieldMean},
+		}),
+		ReportMeta("host", "build"))
+	r.AddStat("a", a)
+	r.AddStat("b", b)
+	testhelper.DiffString(t, "with metadata", "report", r.String(),
+		"name  units  host       build  observations  avg\n"+
+			"a     ms     alpha      v1                3    2\n"+
+			"b     ms     beta-long                    1   10\n")
+}
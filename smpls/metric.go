@@ -0,0 +1,223 @@
+package smpls
+
+import (
+	"math"
+)
+
+// Metric is the interface satisfied by the types in this package that
+// record values and report on them. It allows the different types to be
+// collected together and reported in the same way.
+//
+// A Metric can be registered with a StatSet (see RegisterMetric) and added
+// to a Report (see AddMetric).
+type Metric interface {
+	Units() string
+	String() string
+	StringWithOpts(fo FmtOpts) string
+	Reset()
+}
+
+var (
+	_ Metric = (*Stat)(nil)
+	_ Metric = (*Counter)(nil)
+	_ Metric = (*Gauge)(nil)
+)
+
+// Counter records a total that can only increase, such as the number of
+// requests made or bytes sent.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type Counter struct {
+	units   string
+	total   float64
+	fmtOpts *FmtOpts
+}
+
+// NewCounter creates a new instance of a Counter
+func NewCounter(units string) *Counter {
+	return &Counter{units: units}
+}
+
+// Units returns the units of the Counter
+func (c Counter) Units() string {
+	return c.units
+}
+
+// Inc adds one to the Counter
+func (c *Counter) Inc() {
+	c.total++
+}
+
+// Add adds the value to the Counter. An error is returned and the Counter
+// is unchanged if the value is negative, infinite or NaN or if the new
+// total would be infinite.
+func (c *Counter) Add(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return invalidValue("Invalid Counter increment (%g)"+
+			" - it must be finite", v)
+	}
+	if v < 0 {
+		return invalidValue("Invalid Counter increment (%g)"+
+			" - a Counter cannot be decreased", v)
+	}
+	total := c.total + v
+	if math.IsInf(total, 0) {
+		return invalidValue("Invalid Counter total (%g) - it must be finite",
+			total)
+	}
+	c.total = total
+	return nil
+}
+
+// Value returns the total of the Counter
+func (c Counter) Value() float64 {
+	return c.total
+}
+
+// Reset resets the Counter back to zero
+func (c *Counter) Reset() {
+	c.total = 0
+}
+
+// SetFormat makes the String method format the total according to the
+// FmtOpts (see StringWithOpts). The Fields are ignored. An error is
+// returned if the FmtOpts are invalid.
+func (c *Counter) SetFormat(fo FmtOpts) error {
+	if err := fo.check(); err != nil {
+		return err
+	}
+	c.fmtOpts = &fo
+	return nil
+}
+
+// StringWithOpts returns a string showing the total of the Counter
+// formatted according to the FmtOpts, as "total: value". The Fields are
+// ignored and invalid settings are replaced with the defaults.
+func (c Counter) StringWithOpts(fo FmtOpts) string {
+	if fo.check() != nil {
+		fo = FmtOpts{}
+	}
+	return "total: " + fo.fmtVal(c.total, c.units)
+}
+
+// String prints the value of the Counter, formatted as set by SetFormat
+func (c Counter) String() string {
+	if c.fmtOpts != nil {
+		return c.StringWithOpts(*c.fmtOpts)
+	}
+	return c.StringWithOpts(FmtOpts{})
+}
+
+// Gauge records the most recent value of something that can go up or down,
+// such as the length of a queue. It also records the smallest and largest
+// values it has been set to.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type Gauge struct {
+	units   string
+	val     float64
+	min     float64
+	max     float64
+	count   int
+	fmtOpts *FmtOpts
+}
+
+// NewGauge creates a new instance of a Gauge
+func NewGauge(units string) *Gauge {
+	return &Gauge{units: units}
+}
+
+// Units returns the units of the Gauge
+func (g Gauge) Units() string {
+	return g.units
+}
+
+// Set sets the value of the Gauge. An error is returned and the Gauge is
+// unchanged if the value is infinite or NaN.
+func (g *Gauge) Set(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return invalidValue("Invalid Gauge value (%g) - it must be finite", v)
+	}
+	if g.count == 0 {
+		g.min, g.max = v, v
+	} else {
+		g.min = math.Min(g.min, v)
+		g.max = math.Max(g.max, v)
+	}
+	g.val = v
+	g.count++
+	return nil
+}
+
+// Add adds the value (which may be negative) to the Gauge. An error is
+// returned and the Gauge is unchanged if the value is infinite or NaN or
+// if the new value of the Gauge would be infinite.
+func (g *Gauge) Add(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return invalidValue("Invalid Gauge increment (%g)"+
+			" - it must be finite", v)
+	}
+	return g.Set(g.val + v)
+}
+
+// Value returns the current value of the Gauge
+func (g Gauge) Value() float64 {
+	return g.val
+}
+
+// Min returns the smallest value the Gauge has been set to or 0.0 if it
+// has never been set
+func (g Gauge) Min() float64 {
+	return g.min
+}
+
+// Max returns the largest value the Gauge has been set to or 0.0 if it has
+// never been set
+func (g Gauge) Max() float64 {
+	return g.max
+}
+
+// Count returns the number of times the Gauge has been set
+func (g Gauge) Count() int {
+	return g.count
+}
+
+// Reset resets the Gauge back to its initial state. Any format set with
+// SetFormat is kept.
+func (g *Gauge) Reset() {
+	*g = Gauge{units: g.units, fmtOpts: g.fmtOpts}
+}
+
+// SetFormat makes the String method format the values according to the
+// FmtOpts (see StringWithOpts). The Fields are ignored. An error is
+// returned if the FmtOpts are invalid.
+func (g *Gauge) SetFormat(fo FmtOpts) error {
+	if err := fo.check(); err != nil {
+		return err
+	}
+	g.fmtOpts = &fo
+	return nil
+}
+
+// StringWithOpts returns a string showing the current, smallest and
+// largest values of the Gauge formatted according to the FmtOpts, as
+// "value: v, min: v, max: v". The Fields are ignored and invalid settings
+// are replaced with the defaults.
+func (g Gauge) StringWithOpts(fo FmtOpts) string {
+	if fo.check() != nil {
+		fo = FmtOpts{}
+	}
+	return "value: " + fo.fmtVal(g.val, g.units) +
+		", min: " + fo.fmtVal(g.min, g.units) +
+		", max: " + fo.fmtVal(g.max, g.units)
+}
+
+// String prints the values of the Gauge, formatted as set by SetFormat
+func (g Gauge) String() string {
+	if g.fmtOpts != nil {
+		return g.StringWithOpts(*g.fmtOpts)
+	}
+	return g.StringWithOpts(FmtOpts{})
+}
@@ -0,0 +1,99 @@
+package smpls
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("bytes")
+	c.Inc()
+	if err := c.Add(41); err != nil {
+		t.Fatal("unexpected error adding to the Counter:", err)
+	}
+	err := c.Add(-1)
+	testhelper.CheckExpErrWithID(t, "Counter: negative", err,
+		testhelper.MkExpErr("Invalid Counter increment (-1)",
+			"a Counter cannot be decreased"))
+	testhelper.DiffBool(t, "Counter: negative", "invalid value",
+		errors.Is(err, ErrInvalidValue), true)
+	err = c.Add(math.NaN())
+	testhelper.CheckExpErrWithID(t, "Counter: NaN", err,
+		testhelper.MkExpErr("Invalid Counter increment (NaN)"))
+	err = c.Add(math.Inf(1))
+	testhelper.CheckExpErrWithID(t, "Counter: Inf", err,
+		testhelper.MkExpErr("Invalid Counter increment (+Inf)"))
+
+	testhelper.DiffString(t, "Counter", "units", c.Units(), "bytes")
+	testhelper.DiffFloat(t, "Counter", "value", c.Value(), 42, 0.0)
+	testhelper.DiffString(t, "Counter", "String", c.String(),
+		"total: 4.20e+01")
+	testhelper.DiffString(t, "Counter", "StringWithOpts",
+		c.StringWithOpts(FmtOpts{Style: FmtFixed}), "total: 42.0")
+
+	err = c.SetFormat(FmtOpts{SigFigs: -1})
+	testhelper.CheckExpErrWithID(t, "Counter: SetFormat - bad", err,
+		testhelper.MkExpErr("Invalid significant figures (-1)"))
+	if err := c.SetFormat(FmtOpts{Style: FmtPrefixed}); err != nil {
+		t.Fatal("unexpected error setting the Counter format:", err)
+	}
+	if err := c.Add(1958); err != nil {
+		t.Fatal("unexpected error adding to the Counter:", err)
+	}
+	testhelper.DiffString(t, "Counter - prefixed", "String", c.String(),
+		"total: 1.95 KiB")
+
+	big := NewCounter("bytes")
+	if err := big.Add(math.MaxFloat64); err != nil {
+		t.Fatal("unexpected error adding to the Counter:", err)
+	}
+	err = big.Add(math.MaxFloat64)
+	testhelper.CheckExpErrWithID(t, "Counter: overflow", err,
+		testhelper.MkExpErr("Invalid Counter total (+Inf)"))
+	testhelper.DiffFloat(t, "Counter: overflow", "value",
+		big.Value(), math.MaxFloat64, 0.0)
+
+	c.Reset()
+	testhelper.DiffFloat(t, "Counter - after Reset", "value",
+		c.Value(), 0, 0.0)
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("requests")
+	for _, err := range []error{g.Set(5), g.Set(2), g.Add(7)} {
+		if err != nil {
+			t.Fatal("unexpected error changing the Gauge:", err)
+		}
+	}
+	testhelper.CheckExpErrWithID(t, "Gauge: Set NaN", g.Set(math.NaN()),
+		testhelper.MkExpErr("Invalid Gauge value (NaN)"))
+	testhelper.CheckExpErrWithID(t, "Gauge: Add -Inf", g.Add(math.Inf(-1)),
+		testhelper.MkExpErr("Invalid Gauge increment (-Inf)"))
+
+	testhelper.DiffString(t, "Gauge", "units", g.Units(), "requests")
+	testhelper.DiffFloat(t, "Gauge", "value", g.Value(), 9, 0.0)
+	testhelper.DiffFloat(t, "Gauge", "min", g.Min(), 2, 0.0)
+	testhelper.DiffFloat(t, "Gauge", "max", g.Max(), 9, 0.0)
+	testhelper.DiffInt(t, "Gauge", "count", g.Count(), 3)
+	testhelper.DiffString(t, "Gauge", "String", g.String(),
+		"value: 9.00e+00, min: 2.00e+00, max: 9.00e+00")
+	testhelper.DiffString(t, "Gauge", "StringWithOpts",
+		g.StringWithOpts(FmtOpts{Style: FmtGeneral}),
+		"value: 9, min: 2, max: 9")
+
+	big := NewGauge("requests")
+	if err := big.Set(math.MaxFloat64); err != nil {
+		t.Fatal("unexpected error setting the Gauge:", err)
+	}
+	testhelper.CheckExpErrWithID(t, "Gauge: Add overflow",
+		big.Add(math.MaxFloat64),
+		testhelper.MkExpErr("Invalid Gauge value (+Inf)"))
+
+	g.Reset()
+	testhelper.DiffInt(t, "Gauge - after Reset", "count", g.Count(), 0)
+	testhelper.DiffString(t, "Gauge - after Reset", "units",
+		g.Units(), "requests")
+}
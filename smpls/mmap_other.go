@@ -0,0 +1,11 @@
+//go:build !unix
+
+package smpls
+
+import "os"
+
+// mapFile always returns errNoMmap as files cannot be memory-mapped on
+// this operating system
+func mapFile(_ *os.File, _ int) ([]byte, func() error, error) {
+	return nil, nil, errNoMmap
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package smpls
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of the file into memory for reading.
+// It returns the mapped bytes and a function to unmap them.
+func mapFile(f *os.File, size int) ([]byte, func() error, error) {
+	b, err := syscall.Mmap(int(f.Fd()), 0, size,
+		syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, func() error { return syscall.Munmap(b) }, nil
+}
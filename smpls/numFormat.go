@@ -0,0 +1,102 @@
+package smpls
+
+import (
+	"strings"
+)
+
+// digitGroupSize is the number of digits in each group separated by the
+// group separator
+const digitGroupSize = 3
+
+// NumFormat gives the separators used when showing numbers so that they
+// can be shown in the way that is usual in the reader's locale. The zero
+// value shows numbers as Go formats them, with no separator between the
+// groups of digits and a full stop as the decimal point.
+type NumFormat struct {
+	// Group, if set, is placed between each group of three digits before
+	// the decimal point, as in 1,234,567
+	Group string
+	// Decimal, if set, is used in place of the decimal point, as in 3,14
+	Decimal string
+}
+
+// These are the separators used in some common locales
+var (
+	// NumFmtEnglish separates the groups of digits with commas and uses a
+	// full stop as the decimal point, as in 1,234.5
+	NumFmtEnglish = NumFormat{Group: ",", Decimal: "."}
+	// NumFmtEuropean separates the groups of digits with full stops and
+	// uses a comma as the decimal point, as in 1.234,5, as is usual in
+	// Germany, Italy and Spain among others
+	NumFmtEuropean = NumFormat{Group: ".", Decimal: ","}
+	// NumFmtFrench separates the groups of digits with narrow no-break
+	// spaces and uses a comma as the decimal point, as in 1 234,5
+	NumFmtFrench = NumFormat{Group: " ", Decimal: ","}
+	// NumFmtSwiss separates the groups of digits with apostrophes and uses
+	// a full stop as the decimal point, as in 1'234.5
+	NumFmtSwiss = NumFormat{Group: "'", Decimal: "."}
+)
+
+// decimal returns the decimal point
+func (nf NumFormat) decimal() string {
+	if nf.Decimal == "" {
+		return "."
+	}
+	return nf.Decimal
+}
+
+// check returns an error if the separators could not be told apart from
+// each other or from the digits
+func (nf NumFormat) check() error {
+	for _, sep := range []string{nf.Group, nf.Decimal} {
+		if strings.ContainsAny(sep, "0123456789+-eE") {
+			return invalidValue("Invalid number separator (%q)"+
+				" - it must not contain digits, signs or exponents", sep)
+		}
+	}
+	if nf.Group != "" && nf.Group == nf.decimal() {
+		return invalidValue("Invalid number separators"+
+			" - the group separator and the decimal point are both %q",
+			nf.Group)
+	}
+	return nil
+}
+
+// apply returns the formatted number with the separators applied. Only the
+// first number in the string is changed so that any exponent or units
+// which follow it are left as they are. A string not starting with a
+// number, such as "NaN", is returned unchanged.
+func (nf NumFormat) apply(str string) string {
+	if nf == (NumFormat{}) {
+		return str
+	}
+
+	start := 0
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		start = 1
+	}
+	end := start
+	for end < len(str) && str[end] >= '0' && str[end] <= '9' {
+		end++
+	}
+	if end == start {
+		return str
+	}
+
+	var b strings.Builder
+	b.WriteString(str[:start])
+	digits := str[start:end]
+	for i, d := range digits {
+		if i > 0 && nf.Group != "" && (len(digits)-i)%digitGroupSize == 0 {
+			b.WriteString(nf.Group)
+		}
+		b.WriteRune(d)
+	}
+	rest := str[end:]
+	if strings.HasPrefix(rest, ".") {
+		b.WriteString(nf.decimal())
+		rest = rest[1:]
+	}
+	b.WriteString(rest)
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These errors are matched, using errors.Is, by the errors returned when
+// an option or a constructor is given a bad value (ErrInvalidValue), when
+// an option is given more than once (ErrRepeatedOption) and when an
+// option cannot be used with another option given with it
+// (ErrConflictingOptions). The error messages give the details.
+var (
+	ErrInvalidValue       = errors.New("invalid value")
+	ErrRepeatedOption     = errors.New("repeated option")
+	ErrConflictingOptions = errors.New("conflicting options")
+)
+
+// optError is an error which is also matched by errors.Is against its
+// kind, one of the errors above
+type optError struct {
+	err  error
+	kind error
+}
+
+// Error returns the error message
+func (e optError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the kind of the error and the error itself, which may
+// wrap another error
+func (e optError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// invalidValue returns an error, formatted as by fmt.Errorf, matching
+// ErrInvalidValue
+func invalidValue(format string, args ...any) error {
+	return optError{err: fmt.Errorf(format, args...), kind: ErrInvalidValue}
+}
+
+// repeatedOption returns an error with the message matching
+// ErrRepeatedOption
+func repeatedOption(msg string) error {
+	return optError{err: errors.New(msg), kind: ErrRepeatedOption}
+}
+
+// conflictingOptions returns an error with the message matching
+// ErrConflictingOptions
+func conflictingOptions(msg string) error {
+	return optError{err: errors.New(msg), kind: ErrConflictingOptions}
+}
+
+// checkOpts checks that the options applied to the Stat can be used
+// together. Most options check this as they are applied but some
+// combinations are only detected if the options are given in a particular
+// order; this finds them whatever the order.
+func (s Stat) checkOpts() error {
+	if s.noHist && (s.bucketSums || s.logBase > 0 || s.anchored ||
+		s.quantileLayout || s.rebinFraction > 0 || s.tails != nil ||
+		s.niceBounds || s.expRange != nil) {
+		return conflictingOptions("the Stat has no histogram")
+	}
+	if s.niceBounds &&
+		(s.logBase != 0 || s.rebinFraction != 0 || s.quantileLayout) {
+		return conflictingOptions("nice bucket boundaries cannot be used" +
+			" with a log transform, automatic rebinning or a quantile layout")
+	}
+	if s.exactLim > 0 && s.sampleRate > 0 {
+		return conflictingOptions("an exact Stat cannot be sampled")
+	}
+	return s.checkExpectedRange()
+}
@@ -0,0 +1,70 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestOptErrors(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts    []StatOpt
+		expKind error
+	}{
+		{
+			ID:      testhelper.MkID("bad cache size"),
+			opts:    []StatOpt{StatCacheSize(1)},
+			expKind: ErrInvalidValue,
+			ExpErr: testhelper.MkExpErr(
+				"Invalid cache size (1) - it must be >= 2"),
+		},
+		{
+			ID:      testhelper.MkID("repeated deadband"),
+			opts:    []StatOpt{StatDeadband(1), StatDeadband(2)},
+			expKind: ErrRepeatedOption,
+			ExpErr: testhelper.MkExpErr(
+				"the deadband has already been set"),
+		},
+		{
+			ID:      testhelper.MkID("cache size after no cache"),
+			opts:    []StatOpt{StatNoCache(), StatCacheSize(10)},
+			expKind: ErrConflictingOptions,
+			ExpErr:  testhelper.MkExpErr("the Stat has no cache"),
+		},
+		{
+			ID:      testhelper.MkID("bucket sums before no histogram"),
+			opts:    []StatOpt{StatHistBucketSums(), StatNoHist()},
+			expKind: ErrConflictingOptions,
+			ExpErr:  testhelper.MkExpErr("the Stat has no histogram"),
+		},
+		{
+			ID:      testhelper.MkID("sampled exact Stat"),
+			opts:    []StatOpt{StatExact(10), StatSampleRate(0.5)},
+			expKind: ErrConflictingOptions,
+			ExpErr:  testhelper.MkExpErr("an exact Stat cannot be sampled"),
+		},
+		{
+			ID:      testhelper.MkID("nice bounds before log transform"),
+			opts:    []StatOpt{StatHistNiceBounds(), StatLogTransform(10)},
+			expKind: ErrConflictingOptions,
+			ExpErr: testhelper.MkExpErr(
+				"nice bucket boundaries cannot be used"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+		testhelper.DiffBool(t, tc.IDStr(), "errors.Is",
+			errors.Is(err, tc.expKind), true)
+	}
+
+	_, err := NewRollingStat("ms", 0)
+	testhelper.DiffBool(t, "constructor", "errors.Is",
+		errors.Is(err, ErrInvalidValue), true)
+	testhelper.DiffBool(t, "constructor", "errors.Is - wrong kind",
+		errors.Is(err, ErrRepeatedOption), false)
+}
@@ -0,0 +1,106 @@
+package smpls
+
+import (
+	"math"
+	"sort"
+)
+
+// Outliers records the values lying outside a pair of fences
+type Outliers struct {
+	// LowFence and HighFence are the limits beyond which a value is an
+	// outlier
+	LowFence  float64
+	HighFence float64
+
+	// Vals holds the outlying values in ascending order. If not Exact this
+	// will only hold those outliers which are among the smallest and
+	// largest values recorded by the Stat.
+	Vals []float64
+	// Count is the number of outliers. If not Exact this is estimated from
+	// the histogram though it is never less than the number of Vals.
+	Count int
+	// Exact is true if all the values were still held when the outliers
+	// were found and so Vals and Count are exact.
+	Exact bool
+}
+
+// OutliersTukey returns the values lying outside Tukey's fences. These are
+// k times the interquartile range below the lower quartile and above the
+// upper quartile; k is conventionally 1.5 for outliers and 3 for "far out"
+// values.
+func (s Stat) OutliersTukey(k float64) Outliers {
+	q1, q3 := s.Percentile(25), s.Percentile(75)
+	iqr := q3 - q1
+	return s.outliers(q1-k*iqr, q3+k*iqr)
+}
+
+// OutliersZScore returns the values lying more than z standard deviations
+// from the mean.
+func (s Stat) OutliersZScore(z float64) Outliers {
+	mean, sd := s.Mean(), s.StdDev()
+	return s.outliers(mean-z*sd, mean+z*sd)
+}
+
+// outliers returns the values below lo or above hi
+func (s Stat) outliers(lo, hi float64) Outliers {
+	o := Outliers{LowFence: lo, HighFence: hi}
+	if s.count == 0 {
+		o.Exact = true
+		return o
+	}
+
+	isOutlier := func(v float64) bool { return v < lo || v > hi }
+
+	if vals := s.retained(); vals != nil {
+		for _, v := range vals {
+			if isOutlier(v) {
+				o.Vals = append(o.Vals, v)
+			}
+		}
+		sort.Float64s(o.Vals)
+		o.Count = len(o.Vals)
+		o.Exact = true
+		return o
+	}
+
+	for _, v := range s.mins {
+		if v < lo {
+			o.Vals = append(o.Vals, v)
+		}
+	}
+	for _, v := range s.maxs {
+		if v > hi {
+			o.Vals = append(o.Vals, v)
+		}
+	}
+
+	if s.hist != nil {
+		total := float64(s.count)
+		o.Count = int(math.Round(total * (s.cdfAt(lo) + 1 - s.cdfAt(hi))))
+	}
+	o.Count = max(o.Count, len(o.Vals))
+
+	return o
+}
+
+// RobustMeanStdDev returns the mean and standard deviation of the values
+// with the outlying values excluded. Only the outliers in the Vals are
+// excluded so if the Outliers are not Exact some outliers may remain. It
+// returns zeros if all the values are outliers.
+func (s Stat) RobustMeanStdDev(o Outliers) (mean, sd float64) {
+	sum, sumSq := s.fullSum(), s.fullSumSq()
+	for _, v := range o.Vals {
+		sum -= v
+		sumSq -= v * v
+	}
+
+	n := float64(s.count - len(o.Vals))
+	if n <= 0 {
+		return 0.0, 0.0
+	}
+	mean = sum / n
+	if n > 1 {
+		sd = math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+	}
+	return mean, sd
+}
@@ -0,0 +1,48 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestOutliers(t *testing.T) {
+	vals := []float64{10, 11, 12, 12, 13, 13, 13, 14, 14, 15, 16, 100, -50}
+
+	s := NewStatOrPanic("ms")
+	s.Add(vals[0], vals[1:]...)
+
+	o := s.OutliersTukey(1.5)
+	id := "Tukey - all values held"
+	testhelper.DiffBool(t, id, "exact", o.Exact, true)
+	testhelper.DiffInt(t, id, "count", o.Count, 2)
+	testhelper.DiffFloatSlice(t, id, "values", o.Vals, []float64{-50, 100},
+		0.0)
+	testhelper.DiffFloat(t, id, "low fence", o.LowFence, 12-1.5*2, 0.0)
+	testhelper.DiffFloat(t, id, "high fence", o.HighFence, 14+1.5*2, 0.0)
+
+	mean, sd := s.RobustMeanStdDev(o)
+	clean := NewStatOrPanic("ms")
+	clean.Add(vals[0], vals[1:11]...)
+	testhelper.DiffFloat(t, id, "robust mean", mean, clean.Mean(), 0.00001)
+	testhelper.DiffFloat(t, id, "robust SD", sd, clean.StdDev(), 0.00001)
+
+	o = s.OutliersZScore(1.5)
+	id = "z-score - all values held"
+	testhelper.DiffInt(t, id, "count", o.Count, 2)
+	testhelper.DiffFloatSlice(t, id, "values", o.Vals, []float64{-50, 100},
+		0.0)
+
+	big := NewStatOrPanic("ms", StatCacheSize(100), StatMinMaxCount(5))
+	addSeq(big, 0, 1, 1000)
+	big.Add(5000, 6000)
+	o = big.OutliersZScore(3)
+	id = "z-score - values not held"
+	testhelper.DiffBool(t, id, "exact", o.Exact, false)
+	testhelper.DiffFloatSlice(t, id, "values", o.Vals, []float64{5000, 6000},
+		0.0)
+	if o.Count < 2 {
+		t.Log(id)
+		t.Errorf("\t: the count (%d) should be at least 2\n", o.Count)
+	}
+}
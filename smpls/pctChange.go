@@ -0,0 +1,57 @@
+package smpls
+
+import (
+	"math"
+)
+
+// StatTrackChange returns a function that will make the Stat remember a
+// Snapshot of its values each time it is Reset (or Rotated, see Rotate) so
+// that the values for the new cycle can be compared with those for the
+// last one (see PctChange). Only cycles in which values were added are
+// remembered so that resetting an empty Stat, as when taking it from a
+// sync.Pool, does not lose the last cycle. Note that taking the Snapshot
+// means that Reset allocates memory.
+func StatTrackChange() StatOpt {
+	return func(s *Stat) error {
+		s.trackChange = true
+		return nil
+	}
+}
+
+// TracksChange returns true if the Stat remembers the values of the last
+// cycle when it is Reset (see StatTrackChange)
+func (s Stat) TracksChange() bool {
+	return s.trackChange
+}
+
+// rememberCycle records a Snapshot of the values of the cycle being ended
+// if the Stat tracks changes and any values have been added
+func (s *Stat) rememberCycle() {
+	if !s.trackChange || s.count == 0 {
+		return
+	}
+	snap := s.Snapshot()
+	s.lastCycle = &snap
+}
+
+// LastCycle returns the Snapshot remembered when the Stat was last Reset
+// and true or an empty Snapshot and false if none has been remembered (see
+// StatTrackChange)
+func (s Stat) LastCycle() (Snapshot, bool) {
+	if s.lastCycle == nil {
+		return Snapshot{}, false
+	}
+	return *s.lastCycle, true
+}
+
+// PctChange returns the change in the value of the field since the last
+// cycle as a percentage of the value in the last cycle; so if the mean was
+// 10.0 last cycle and is 10.5 now it returns 5.0. It returns NaN if no
+// cycle has been remembered (see StatTrackChange), if no values have been
+// added in this cycle or if the value last cycle was zero or not recorded.
+func (s Stat) PctChange(f StatField) float64 {
+	if s.lastCycle == nil || s.count == 0 {
+		return math.NaN()
+	}
+	return CmpVal{A: s.lastCycle.fieldVal(f), B: s.fieldVal(f)}.PctDiff()
+}
@@ -0,0 +1,69 @@
+package smpls
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestPctChange(t *testing.T) {
+	s := NewStatOrPanic("ms", StatTrackChange())
+	testhelper.DiffBool(t, "new", "tracks change", s.TracksChange(), true)
+	addSeq(s, 1, 1, 10) // mean 5.5
+	testhelper.DiffBool(t, "first cycle", "change is NaN",
+		math.IsNaN(s.PctChange(FieldMean)), true)
+	_, ok := s.LastCycle()
+	testhelper.DiffBool(t, "first cycle", "last cycle", ok, false)
+
+	s.Reset()
+	s.Reset() // an empty cycle is not remembered
+	last, ok := s.LastCycle()
+	testhelper.DiffBool(t, "second cycle", "last cycle", ok, true)
+	testhelper.DiffFloat(t, "second cycle", "last mean", last.Mean, 5.5, 0)
+	testhelper.DiffBool(t, "second cycle, empty", "change is NaN",
+		math.IsNaN(s.PctChange(FieldMean)), true)
+
+	addSeq(s, 2, 1, 10) // mean 6.5
+	testhelper.DiffFloat(t, "second cycle", "mean change",
+		s.PctChange(FieldMean), 100.0/5.5, 1e-9)
+	testhelper.DiffFloat(t, "second cycle", "count change",
+		s.PctChange(FieldCount), 0, 0)
+	testhelper.DiffFloat(t, "second cycle", "max change",
+		s.PctChange(FieldMax), 10, 1e-9)
+
+	s.Rotate()
+	last, _ = s.LastCycle()
+	testhelper.DiffFloat(t, "rotated", "last mean", last.Mean, 6.5, 0)
+
+	untracked := NewStatOrPanic("ms")
+	addSeq(untracked, 1, 1, 10)
+	untracked.Reset()
+	addSeq(untracked, 1, 1, 10)
+	testhelper.DiffBool(t, "untracked", "change is NaN",
+		math.IsNaN(untracked.PctChange(FieldMean)), true)
+}
+
+func TestPctChangeSaveLoad(t *testing.T) {
+	s := NewStatOrPanic("ms", StatTrackChange())
+	addSeq(s, 1, 1, 10)
+	s.Reset()
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal("Couldn't load the Stat:", err)
+	}
+	testhelper.DiffBool(t, "loaded", "tracks change",
+		loaded.TracksChange(), true)
+	exp, _ := s.LastCycle()
+	act, ok := loaded.LastCycle()
+	testhelper.DiffBool(t, "loaded", "last cycle", ok, true)
+	if err := testhelper.DiffVals(act, exp); err != nil {
+		t.Errorf("the loaded last cycle differs: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package smpls
+
+import (
+	"math"
+	"sort"
+)
+
+// clampPct returns the percentage forced into the range [0, 100]. The
+// percentage must not be NaN.
+func clampPct(p float64) float64 {
+	return math.Max(0, math.Min(100, p))
+}
+
+// Percentile returns an estimate of the value below which the given
+// percentage of the values fall; p should be in the range [0, 100] and is
+// forced into that range if not. While all the values are still held,
+// either in the cache or in the slices of minimum and maximum values, the
+// result is exact (interpolating between neighbouring values); thereafter
+// it is estimated from the histogram. It returns NaN if p is NaN, 0.0 if
+// no values have been added and NaN if the value cannot be calculated
+// because the values are no longer held and the Stat has no histogram.
+func (s Stat) Percentile(p float64) float64 {
+	if math.IsNaN(p) {
+		return math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0
+	}
+	p = clampPct(p)
+
+	if vals := s.retained(); vals != nil {
+		return sortedPercentile(sortedCopy(vals), p)
+	}
+	if s.hist == nil {
+		return math.NaN()
+	}
+	return s.histPercentile(p)
+}
+
+// Percentiles returns the values of each of the given percentiles. The
+// values are as would be given by Percentile but the values held are only
+// sorted once.
+func (s Stat) Percentiles(ps ...float64) []float64 {
+	pvs := make([]float64, len(ps))
+
+	var sorted []float64
+	if vals := s.retained(); vals != nil && s.count > 0 {
+		sorted = sortedCopy(vals)
+	}
+	for i, p := range ps {
+		switch {
+		case math.IsNaN(p):
+			pvs[i] = math.NaN()
+		case s.count == 0:
+			pvs[i] = 0.0
+		case sorted != nil:
+			pvs[i] = sortedPercentile(sorted, clampPct(p))
+		case s.hist == nil:
+			pvs[i] = math.NaN()
+		default:
+			pvs[i] = s.histPercentile(clampPct(p))
+		}
+	}
+	return pvs
+}
+
+// sortedCopy returns a sorted copy of the values
+func sortedCopy(vals []float64) []float64 {
+	sorted := make([]float64, len(vals))
+	copy(sorted, vals)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// sortedPercentile returns the value below which the given percentage of
+// the sorted values fall. It interpolates linearly between the values
+// either side of the exact rank. The slice must not be empty.
+func sortedPercentile(sorted []float64, p float64) float64 {
+	rank := p / 100.0 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// HistPercentile returns an estimate, from the histogram, of the value
+// below which the given percentage of the values fall; p should be in the
+// range [0, 100] and is forced into that range if not. Unlike Percentile
+// this always uses the histogram, even while all the values are still held.
+// The values in each bucket are taken to be evenly spread across the bucket
+// and the estimate is interpolated linearly within the bucket holding the
+// percentile. The error is therefore no more than the width of that bucket;
+// the HistPercentileBounds method gives the limits of the bucket. It returns
+// NaN if p is NaN, 0.0 if no values have been added and NaN if the Stat has
+// no histogram.
+func (s Stat) HistPercentile(p float64) float64 {
+	if math.IsNaN(p) {
+		return math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0
+	}
+	if s.hist == nil {
+		return math.NaN()
+	}
+	return s.histPercentile(clampPct(p))
+}
+
+// HistPercentileBounds returns the limits of the part of the histogram
+// holding the value below which the given percentage of the values fall;
+// the value lies between lo and hi. The limits are those of a histogram
+// bucket, or of the underflow or overflow which are taken to stretch from
+// the histogram to the minimum and maximum values, and are never outside
+// the minimum and maximum values. It returns NaNs if p is NaN, zeros if no
+// values have been added and NaNs if the Stat has no histogram.
+func (s Stat) HistPercentileBounds(p float64) (lo, hi float64) {
+	if math.IsNaN(p) {
+		return math.NaN(), math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0, 0.0
+	}
+	if s.hist == nil {
+		return math.NaN(), math.NaN()
+	}
+	s = s.histStat()
+	seg, _ := s.pctileSegment(clampPct(p))
+	return math.Max(s.Min(), seg.lo), math.Min(s.Max(), seg.hi)
+}
+
+// PercentileBounds returns the limits between which the value below which
+// the given percentage of the values fall is known to lie; p should be in
+// the range [0, 100] and is forced into that range if not. While all the
+// values are still held the percentile is exact and both limits are the
+// value returned by Percentile; thereafter they are the limits given by
+// HistPercentileBounds. It returns NaNs if p is NaN, zeros if no values
+// have been added and NaNs if the values are no longer held and the Stat
+// has no histogram.
+func (s Stat) PercentileBounds(p float64) (lo, hi float64) {
+	if math.IsNaN(p) {
+		return math.NaN(), math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0, 0.0
+	}
+	if vals := s.retained(); vals != nil {
+		v := sortedPercentile(sortedCopy(vals), clampPct(p))
+		return v, v
+	}
+	return s.HistPercentileBounds(p)
+}
+
+// PercentileError returns the largest difference there can be between the
+// value returned by Percentile and the true value of the percentile; that
+// is, the distance from the estimate to the further of the limits given by
+// PercentileBounds. It is 0.0 while all the values are still held or if
+// no values have been added and NaN if the values are no longer held and
+// the Stat has no histogram.
+func (s Stat) PercentileError(p float64) float64 {
+	lo, hi := s.PercentileBounds(p)
+	v := s.Percentile(p)
+	return math.Max(v-lo, hi-v)
+}
+
+// pctileSegment returns the part of the histogram holding the value below
+// which the given percentage of the values fall along with the number of
+// values below it. The underflow and overflow are taken to stretch from
+// the histogram to the minimum and maximum values. The histogram must be
+// populated and the Stat must have at least one value.
+func (s Stat) pctileSegment(p float64) (seg histSegment, cum int) {
+	target := p / 100.0 * float64(s.count)
+
+	if s.underflow > 0 && target <= float64(s.underflow) {
+		return s.underflowSegment(), 0
+	}
+	cum = s.underflow
+
+	for i, count := range s.hist {
+		if count > 0 && target <= float64(cum+count) {
+			return s.bucketSegment(i), cum
+		}
+		cum += count
+	}
+
+	return s.overflowSegment(), cum
+}
+
+// histPercentile returns an estimate of the value below which the given
+// percentage of the values fall. The values in each bucket are taken to be
+// evenly spread across the bucket unless the sum of the values in each
+// bucket is recorded in which case the spread is skewed to match the mean
+// of the values in the bucket. The underflow and overflow are taken to
+// stretch from the histogram to the minimum and maximum values. The Stat
+// must have at least one value.
+func (s Stat) histPercentile(p float64) float64 {
+	s = s.histStat()
+	minVal, maxVal := s.Min(), s.Max()
+
+	seg, cum := s.pctileSegment(p)
+	if seg.count == 0 {
+		return maxVal
+	}
+	target := p / 100.0 * float64(s.count)
+	v := seg.lo + (seg.hi-seg.lo)*
+		seg.position((target-float64(cum))/float64(seg.count))
+	return math.Max(minVal, math.Min(maxVal, v))
+}
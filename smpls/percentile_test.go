@@ -0,0 +1,146 @@
+package smpls
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestPercentile(t *testing.T) {
+	cached := NewStatOrPanic("units")
+	addSeq(cached, 1, 1, 10)
+
+	fromHist := NewStatOrPanic("units",
+		StatCacheSize(100), StatHistBucketCount(10))
+	addSeq(fromHist, 0, 1, 100)
+
+	testCases := []struct {
+		testhelper.ID
+		s      *Stat
+		p      float64
+		expVal float64
+	}{
+		{ID: testhelper.MkID("empty"), s: NewStatOrPanic("units"), p: 50},
+		{ID: testhelper.MkID("cached: p0"), s: cached, p: 0, expVal: 1},
+		{ID: testhelper.MkID("cached: p50"), s: cached, p: 50, expVal: 5.5},
+		{ID: testhelper.MkID("cached: p90"), s: cached, p: 90, expVal: 9.1},
+		{ID: testhelper.MkID("cached: p100"), s: cached, p: 100, expVal: 10},
+		{ID: testhelper.MkID("cached: p>100"), s: cached, p: 150, expVal: 10},
+		{ID: testhelper.MkID("hist: p0"), s: fromHist, p: 0, expVal: 0},
+		{ID: testhelper.MkID("hist: p25"), s: fromHist, p: 25, expVal: 24.75},
+		{ID: testhelper.MkID("hist: p50"), s: fromHist, p: 50, expVal: 49.5},
+		{ID: testhelper.MkID("hist: p100"), s: fromHist, p: 100, expVal: 99},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, tc.IDStr(), "percentile",
+			tc.s.Percentile(tc.p), tc.expVal, 0.0001)
+		testhelper.DiffFloat(t, tc.IDStr(), "Percentiles",
+			tc.s.Percentiles(50, tc.p)[1], tc.expVal, 0.0001)
+	}
+}
+
+func TestPercentileNaN(t *testing.T) {
+	cached := NewStatOrPanic("units")
+	addSeq(cached, 1, 1, 10)
+
+	fromHist := NewStatOrPanic("units",
+		StatCacheSize(100), StatHistBucketCount(10))
+	addSeq(fromHist, 0, 1, 1000)
+
+	nan := math.NaN()
+	for _, tc := range []struct {
+		name string
+		s    *Stat
+	}{
+		{name: "empty", s: NewStatOrPanic("units")},
+		{name: "cached", s: cached},
+		{name: "hist", s: fromHist},
+	} {
+		lo, hi := tc.s.PercentileBounds(nan)
+		hLo, hHi := tc.s.HistPercentileBounds(nan)
+		for name, v := range map[string]float64{
+			"Percentile":                tc.s.Percentile(nan),
+			"Percentiles":               tc.s.Percentiles(50, nan)[1],
+			"HistPercentile":            tc.s.HistPercentile(nan),
+			"PercentileBounds (lo)":     lo,
+			"PercentileBounds (hi)":     hi,
+			"HistPercentileBounds (lo)": hLo,
+			"HistPercentileBounds (hi)": hHi,
+			"PercentileError":           tc.s.PercentileError(nan),
+		} {
+			testhelper.DiffBool(t, tc.name, name+" is NaN", math.IsNaN(v), true)
+		}
+		testhelper.DiffBool(t, tc.name, "Percentiles(50) is NaN",
+			math.IsNaN(tc.s.Percentiles(50, nan)[0]), false)
+	}
+}
+
+func TestHistPercentile(t *testing.T) {
+	s := NewStatOrPanic("units", StatCacheSize(100), StatHistBucketCount(10))
+	addSeq(s, 0.5, 1, 50)
+
+	// while the values are cached the estimate still comes from the
+	// histogram
+	testhelper.DiffFloat(t, "cached", "p50", s.HistPercentile(50), 25, 0.01)
+
+	addSeq(s, 50.5, 1, 50)
+	for _, p := range []float64{1, 10, 25, 50, 90, 99} {
+		id := "p" + strconv.FormatFloat(p, 'g', -1, 64)
+		est := s.HistPercentile(p)
+		lo, hi := s.HistPercentileBounds(p)
+		testhelper.DiffFloat(t, id, "estimate", est, p, 0.5)
+		if est < lo || est > hi {
+			t.Log(id)
+			t.Errorf("\t: the estimate (%g) is outside its bounds [%g, %g]\n",
+				est, lo, hi)
+		}
+		testhelper.DiffFloat(t, id, "bounds width", hi-lo, 9.9, 0.001)
+	}
+
+	lo, hi := s.HistPercentileBounds(0)
+	testhelper.DiffFloat(t, "p0", "lower bound", lo, 0.5, 0.0)
+	testhelper.DiffFloat(t, "p0", "upper bound", hi, 10.4, 0.001)
+
+	empty := NewStatOrPanic("units")
+	testhelper.DiffFloat(t, "empty", "p50", empty.HistPercentile(50), 0, 0.0)
+
+	noHist := NewStatOrPanic("units", StatNoHist())
+	noHist.Add(1)
+	testhelper.DiffBool(t, "no hist", "p50 is NaN",
+		math.IsNaN(noHist.HistPercentile(50)), true)
+	lo, _ = noHist.HistPercentileBounds(50)
+	testhelper.DiffBool(t, "no hist", "lower bound is NaN",
+		math.IsNaN(lo), true)
+}
+
+func TestPercentileBounds(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(10))
+	lo, hi := s.PercentileBounds(50)
+	testhelper.DiffFloat(t, "empty", "lo", lo, 0, 0)
+	testhelper.DiffFloat(t, "empty", "hi", hi, 0, 0)
+
+	addSeq(s, 1, 1, 5)
+	lo, hi = s.PercentileBounds(50)
+	testhelper.DiffFloat(t, "exact", "lo", lo, 3, 0)
+	testhelper.DiffFloat(t, "exact", "hi", hi, 3, 0)
+	testhelper.DiffFloat(t, "exact", "error", s.PercentileError(50), 0, 0)
+
+	addSeq(s, 6, 1, 95)
+	lo, hi = s.PercentileBounds(50)
+	hLo, hHi := s.HistPercentileBounds(50)
+	testhelper.DiffFloat(t, "histogram", "lo", lo, hLo, 0)
+	testhelper.DiffFloat(t, "histogram", "hi", hi, hHi, 0)
+	v := s.Percentile(50)
+	testhelper.DiffFloat(t, "histogram", "error", s.PercentileError(50),
+		math.Max(v-lo, hi-v), 0)
+	testhelper.DiffBool(t, "histogram", "error > 0",
+		s.PercentileError(50) > 0, true)
+
+	noHist := NewStatOrPanic("ms", StatNoHist(), StatMinMaxCount(2))
+	addSeq(noHist, 1, 1, 10)
+	testhelper.DiffBool(t, "no histogram", "error is NaN",
+		math.IsNaN(noHist.PercentileError(50)), true)
+}
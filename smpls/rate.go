@@ -0,0 +1,262 @@
+package smpls
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	dfltRateWindow    = time.Minute
+	dfltRateSlotCount = 60
+	minRateSlotCount  = 1
+)
+
+// rateSlot records the observations made during one sub-interval of the
+// Rate window
+type rateSlot struct {
+	start time.Time
+	count int
+	sum   float64
+}
+
+// Rate records the times at which values are added and reports the rate at
+// which observations arrive and the rate at which the sum of their values
+// grows (for instance, requests per second and bytes per second). As well as
+// the overall rates since the first value was added it will report the rates
+// over a recent window of time.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type Rate struct {
+	first time.Time
+	last  time.Time
+	count int
+	sum   float64
+
+	window    time.Duration
+	slotWidth time.Duration
+	slots     []rateSlot
+
+	now func() time.Time
+}
+
+// RateOpt is the type of an option function that can be passed to NewRate
+type RateOpt func(r *Rate) error
+
+// RateWindow returns a function that will set the window over which the
+// windowed rates are calculated and the number of slots the window is
+// divided into. The more slots there are the more accurately the window
+// will track the passage of time.
+func RateWindow(d time.Duration, slotCount int) RateOpt {
+	return func(r *Rate) error {
+		if r.slots != nil {
+			return repeatedOption("the rate window has already been set")
+		}
+		if d <= 0 {
+			return invalidValue("Invalid rate window (%s) - it must be > 0", d)
+		}
+		if slotCount < minRateSlotCount {
+			return invalidValue(
+				"Invalid rate window slot count (%d) - it must be >= %d",
+				slotCount, minRateSlotCount)
+		}
+		if d/time.Duration(slotCount) == 0 {
+			return invalidValue(
+				"Invalid rate window (%s) - too short for %d slots",
+				d, slotCount)
+		}
+
+		r.setWindow(d, slotCount)
+		return nil
+	}
+}
+
+// setWindow sets the window duration and creates the slots
+func (r *Rate) setWindow(d time.Duration, slotCount int) {
+	r.window = d
+	r.slotWidth = d / time.Duration(slotCount)
+	r.slots = make([]rateSlot, slotCount)
+}
+
+// NewRate creates a new instance of a Rate
+func NewRate(opts ...RateOpt) (*Rate, error) {
+	r := &Rate{now: time.Now}
+
+	for _, o := range opts {
+		err := o(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.slots == nil {
+		r.setWindow(dfltRateWindow, dfltRateSlotCount)
+	}
+
+	return r, nil
+}
+
+// NewRateOrPanic creates a new instance of a Rate and will panic if any
+// errors are detected
+func NewRateOrPanic(opts ...RateOpt) *Rate {
+	r, err := NewRate(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Reset resets the Rate back to its initial state
+func (r *Rate) Reset() {
+	r.first = time.Time{}
+	r.last = time.Time{}
+	r.count = 0
+	r.sum = 0
+
+	for i := range r.slots {
+		r.slots[i] = rateSlot{}
+	}
+}
+
+// Add records the value as having been observed now
+func (r *Rate) Add(v float64) {
+	r.AddAt(r.now(), v)
+}
+
+// AddAt records the value as having been observed at the given time. Values
+// should be added in time order; the window rates will not account for
+// values added with a time earlier than the start of the window.
+func (r *Rate) AddAt(t time.Time, v float64) {
+	if r.count == 0 || t.Before(r.first) {
+		r.first = t
+	}
+	if t.After(r.last) {
+		r.last = t
+	}
+	r.count++
+	r.sum += v
+
+	slotStart := t.Truncate(r.slotWidth)
+	idx := int((slotStart.UnixNano() / int64(r.slotWidth)) %
+		int64(len(r.slots)))
+	slot := &r.slots[idx]
+	if !slot.start.Equal(slotStart) {
+		if slot.start.After(slotStart) { // too old to be in the window
+			return
+		}
+		*slot = rateSlot{start: slotStart}
+	}
+	slot.count++
+	slot.sum += v
+}
+
+// Count returns the number of values that have been added
+func (r Rate) Count() int {
+	return r.count
+}
+
+// Sum returns the sum of values that have been added
+func (r Rate) Sum() float64 {
+	return r.sum
+}
+
+// First returns the time of the first observation. It returns the zero time
+// if no values have been added
+func (r Rate) First() time.Time {
+	return r.first
+}
+
+// Last returns the time of the last observation. It returns the zero time
+// if no values have been added
+func (r Rate) Last() time.Time {
+	return r.last
+}
+
+// Elapsed returns the time between the first and last observations
+func (r Rate) Elapsed() time.Duration {
+	return r.last.Sub(r.first)
+}
+
+// ObsPerSec returns the number of observations per second between the first
+// and last observations. It returns 0.0 if no time has elapsed.
+func (r Rate) ObsPerSec() float64 {
+	secs := r.Elapsed().Seconds()
+	if secs <= 0 {
+		return 0.0
+	}
+	return float64(r.count) / secs
+}
+
+// SumPerSec returns the rate at which the sum of values has grown per second
+// between the first and last observations. It returns 0.0 if no time has
+// elapsed.
+func (r Rate) SumPerSec() float64 {
+	secs := r.Elapsed().Seconds()
+	if secs <= 0 {
+		return 0.0
+	}
+	return r.sum / secs
+}
+
+// Window returns the duration of the window over which the windowed rates
+// are calculated
+func (r Rate) Window() time.Duration {
+	return r.window
+}
+
+// windowTotals returns the count and sum of the values observed in the
+// window ending now and the length of time covered by the window. If the
+// first observation was made after the start of the window the time covered
+// starts from the first observation.
+func (r Rate) windowTotals() (int, float64, time.Duration) {
+	now := r.now()
+	windowStart := now.Truncate(r.slotWidth).Add(-r.window + r.slotWidth)
+
+	var count int
+	var sum float64
+	for _, slot := range r.slots {
+		if slot.start.Before(windowStart) || slot.start.After(now) {
+			continue
+		}
+		count += slot.count
+		sum += slot.sum
+	}
+
+	covered := r.window
+	if r.first.After(now.Add(-r.window)) {
+		covered = now.Sub(r.first)
+	}
+	return count, sum, covered
+}
+
+// WindowObsPerSec returns the number of observations per second over the
+// most recent window. It returns 0.0 if no values have been added.
+func (r Rate) WindowObsPerSec() float64 {
+	count, _, covered := r.windowTotals()
+	if r.count == 0 || covered <= 0 {
+		return 0.0
+	}
+	return float64(count) / covered.Seconds()
+}
+
+// WindowSumPerSec returns the rate at which the sum of values has grown per
+// second over the most recent window. It returns 0.0 if no values have been
+// added.
+func (r Rate) WindowSumPerSec() float64 {
+	_, sum, covered := r.windowTotals()
+	if r.count == 0 || covered <= 0 {
+		return 0.0
+	}
+	return sum / covered.Seconds()
+}
+
+// String prints the rates
+func (r Rate) String() string {
+	return fmt.Sprintf(
+		"%7d observations in %s,"+
+			" obs/sec: %8.2e (%8.2e),"+
+			" sum/sec: %8.2e (%8.2e)",
+		r.count, r.Elapsed(),
+		r.ObsPerSec(), r.WindowObsPerSec(),
+		r.SumPerSec(), r.WindowSumPerSec())
+}
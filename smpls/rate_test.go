@@ -0,0 +1,141 @@
+package smpls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRate(t *testing.T) {
+	start := time.Date(2020, time.August, 6, 13, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		testhelper.ID
+		offsets         []time.Duration
+		vals            []float64
+		now             time.Duration
+		expElapsed      time.Duration
+		expObsPerSec    float64
+		expSumPerSec    float64
+		expWinObsPerSec float64
+		expWinSumPerSec float64
+	}{
+		{
+			ID: testhelper.MkID("no values"),
+		},
+		{
+			ID:              testhelper.MkID("one value"),
+			offsets:         []time.Duration{0},
+			vals:            []float64{100},
+			now:             0,
+			expWinObsPerSec: 0,
+		},
+		{
+			ID: testhelper.MkID("values within the window"),
+			offsets: []time.Duration{
+				0, time.Second, 2 * time.Second, 4 * time.Second,
+			},
+			vals:            []float64{100, 200, 300, 400},
+			now:             4 * time.Second,
+			expElapsed:      4 * time.Second,
+			expObsPerSec:    1,
+			expSumPerSec:    250,
+			expWinObsPerSec: 1,
+			expWinSumPerSec: 250,
+		},
+		{
+			ID: testhelper.MkID("some values before the window"),
+			offsets: []time.Duration{
+				0, time.Second,
+				100 * time.Second, 110 * time.Second,
+			},
+			vals:            []float64{100, 200, 300, 300},
+			now:             120 * time.Second,
+			expElapsed:      110 * time.Second,
+			expObsPerSec:    4.0 / 110.0,
+			expSumPerSec:    900.0 / 110.0,
+			expWinObsPerSec: 2.0 / 60.0,
+			expWinSumPerSec: 600.0 / 60.0,
+		},
+	}
+
+	for _, tc := range testCases {
+		r := NewRateOrPanic()
+		for i, offset := range tc.offsets {
+			r.AddAt(start.Add(offset), tc.vals[i])
+		}
+		r.now = func() time.Time { return start.Add(tc.now) }
+
+		id := tc.IDStr()
+		testhelper.DiffInt(t, id, "count", r.Count(), len(tc.vals))
+		testhelper.DiffInt(t, id, "elapsed", r.Elapsed(), tc.expElapsed)
+		testhelper.DiffFloat(t, id, "obs/sec",
+			r.ObsPerSec(), tc.expObsPerSec, 0.00001)
+		testhelper.DiffFloat(t, id, "sum/sec",
+			r.SumPerSec(), tc.expSumPerSec, 0.00001)
+		testhelper.DiffFloat(t, id, "window obs/sec",
+			r.WindowObsPerSec(), tc.expWinObsPerSec, 0.00001)
+		testhelper.DiffFloat(t, id, "window sum/sec",
+			r.WindowSumPerSec(), tc.expWinSumPerSec, 0.00001)
+	}
+}
+
+func TestNewRate(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []RateOpt
+	}{
+		{
+			ID: testhelper.MkID("no options"),
+		},
+		{
+			ID:   testhelper.MkID("good window"),
+			opts: []RateOpt{RateWindow(time.Second, 10)},
+		},
+		{
+			ID:   testhelper.MkID("bad window"),
+			opts: []RateOpt{RateWindow(0, 10)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid rate window (0s) - it must be > 0"),
+		},
+		{
+			ID:   testhelper.MkID("bad slot count"),
+			opts: []RateOpt{RateWindow(time.Second, 0)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid rate window slot count (0) - it must be >= 1"),
+		},
+		{
+			ID: testhelper.MkID("window set twice"),
+			opts: []RateOpt{
+				RateWindow(time.Second, 10),
+				RateWindow(time.Second, 10),
+			},
+			ExpErr: testhelper.MkExpErr("the rate window has already been set"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewRate(tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestStatRate(t *testing.T) {
+	s := NewStatOrPanic("bytes", StatRate())
+	if s.Rate() == nil {
+		t.Fatal("the Stat was created with StatRate but has no Rate")
+	}
+	s.Add(1, 2, 3)
+	testhelper.DiffInt(t, "StatRate", "rate count", s.Rate().Count(), 3)
+	testhelper.DiffFloat(t, "StatRate", "rate sum", s.Rate().Sum(), 6, 0.0)
+
+	s.Reset()
+	testhelper.DiffInt(t, "StatRate - after Reset", "rate count",
+		s.Rate().Count(), 0)
+
+	if NewStatOrPanic("bytes").Rate() != nil {
+		t.Error("a Stat created without StatRate should have no Rate")
+	}
+}
@@ -0,0 +1,104 @@
+package smpls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+)
+
+const minRawInterval = 1
+
+// RawFormat gives the form in which raw values are written (see
+// StatRawValues)
+type RawFormat int
+
+// These are the available raw value formats
+const (
+	// RawCSV writes each value on a line of its own in the shortest
+	// decimal form which reads back as the same value
+	RawCSV RawFormat = iota
+	// RawBinary writes each value as 8 bytes, the IEEE 754 bits in
+	// little-endian order
+	RawBinary
+)
+
+// rawWriter writes every Nth value added to a Stat to a Writer
+type rawWriter struct {
+	w      *bufio.Writer
+	every  int
+	seen   int
+	format RawFormat
+	buf    []byte
+	err    error
+}
+
+// add writes the value if it is due to be written. Once an error has been
+// seen nothing more is written.
+func (rw *rawWriter) add(v float64) {
+	rw.seen++
+	if rw.err != nil || rw.seen%rw.every != 0 {
+		return
+	}
+
+	switch rw.format {
+	case RawBinary:
+		rw.buf = binary.LittleEndian.AppendUint64(rw.buf[:0],
+			math.Float64bits(v))
+	default:
+		rw.buf = strconv.AppendFloat(rw.buf[:0], v, 'g', -1, 64)
+		rw.buf = append(rw.buf, '\n')
+	}
+	_, rw.err = rw.w.Write(rw.buf)
+}
+
+// StatRawValues returns a function that will make the Stat write every
+// Nth value added to the Writer, in the given format, while still
+// collecting statistics from all of them; pass 1 to write every value.
+// This allows detailed offline analysis of a sample of the values without
+// changing the code adding them.
+//
+// The values are buffered so FlushRaw must be called once all the values
+// have been added. Writing stops at the first error, which is reported by
+// FlushRaw. Values are not written by copies of the Stat (see Clone) nor
+// by a Stat recreated by Load.
+func StatRawValues(w io.Writer, every int, format RawFormat) StatOpt {
+	return func(s *Stat) error {
+		if s.raw != nil {
+			return repeatedOption("the raw value Writer has already been set")
+		}
+		if w == nil {
+			return invalidValue("the raw value Writer must not be nil")
+		}
+		if every < minRawInterval {
+			return invalidValue(
+				"Invalid raw value interval (%d) - it must be >= %d",
+				every, minRawInterval)
+		}
+		if format != RawCSV && format != RawBinary {
+			return invalidValue("Invalid raw value format (%d)", format)
+		}
+
+		s.raw = &rawWriter{
+			w:      bufio.NewWriter(w),
+			every:  every,
+			format: format,
+			buf:    make([]byte, 0, 32),
+		}
+		return nil
+	}
+}
+
+// FlushRaw writes any buffered raw values (see StatRawValues) and returns
+// the first error seen while writing them. It does nothing if the Stat is
+// not writing raw values.
+func (s *Stat) FlushRaw() error {
+	if s.raw == nil {
+		return nil
+	}
+	if s.raw.err == nil {
+		s.raw.err = s.raw.w.Flush()
+	}
+	return s.raw.err
+}
@@ -0,0 +1,121 @@
+package smpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatRawValues(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		every  int
+		format RawFormat
+		vals   []float64
+		exp    []byte
+	}{
+		{
+			ID:     testhelper.MkID("CSV, every value"),
+			every:  1,
+			format: RawCSV,
+			vals:   []float64{1, 2.5, -0.125, 1e300},
+			exp:    []byte("1\n2.5\n-0.125\n1e+300\n"),
+		},
+		{
+			ID:     testhelper.MkID("CSV, every third value"),
+			every:  3,
+			format: RawCSV,
+			vals:   []float64{1, 2, 3, 4, 5, 6, 7},
+			exp:    []byte("3\n6\n"),
+		},
+		{
+			ID:     testhelper.MkID("binary, every other value"),
+			every:  2,
+			format: RawBinary,
+			vals:   []float64{1, 2, 3, 4},
+			exp: binary.LittleEndian.AppendUint64(
+				binary.LittleEndian.AppendUint64(nil, math.Float64bits(2)),
+				math.Float64bits(4)),
+		},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		s := NewStatOrPanic("ms", StatRawValues(&buf, tc.every, tc.format))
+		s.AddSlice(tc.vals)
+		if err := s.FlushRaw(); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error flushing: %v\n", err)
+		}
+		testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), len(tc.vals))
+		if !bytes.Equal(buf.Bytes(), tc.exp) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad raw values:\n\t: got: %q\n\t: exp: %q\n",
+				buf.Bytes(), tc.exp)
+		}
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+// Write always returns an error
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestStatRawValuesErrs(t *testing.T) {
+	var buf bytes.Buffer
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID: testhelper.MkID("nil writer"),
+			ExpErr: testhelper.MkExpErr(
+				"the raw value Writer must not be nil"),
+			opts: []StatOpt{StatRawValues(nil, 1, RawCSV)},
+		},
+		{
+			ID: testhelper.MkID("bad interval"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid raw value interval (0) - it must be >= 1"),
+			opts: []StatOpt{StatRawValues(&buf, 0, RawCSV)},
+		},
+		{
+			ID:     testhelper.MkID("bad format"),
+			ExpErr: testhelper.MkExpErr("Invalid raw value format (7)"),
+			opts:   []StatOpt{StatRawValues(&buf, 1, RawFormat(7))},
+		},
+		{
+			ID: testhelper.MkID("twice"),
+			ExpErr: testhelper.MkExpErr(
+				"the raw value Writer has already been set"),
+			opts: []StatOpt{
+				StatRawValues(&buf, 1, RawCSV),
+				StatRawValues(&buf, 1, RawCSV),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("ms", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+
+	s := NewStatOrPanic("ms", StatRawValues(failingWriter{}, 1, RawBinary))
+	for i := range 10000 {
+		s.Add(float64(i))
+	}
+	testhelper.CheckExpErrWithID(t, "failing writer", s.FlushRaw(),
+		testhelper.MkExpErr("write failed"))
+	testhelper.DiffInt(t, "failing writer", "count", s.Count(), 10000)
+
+	testhelper.CheckExpErrWithID(t, "no raw values",
+		NewStatOrPanic("ms").FlushRaw(), testhelper.ExpErr{})
+}
@@ -0,0 +1,175 @@
+package smpls
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	minRemedianBase = 3
+	maxRemedianBase = 1001
+)
+
+// remedian estimates the median of a stream of values using the remedian
+// algorithm (Rousseeuw and Bassett). The values are collected in a buffer
+// of base values; when it is full its median is passed up to the buffer of
+// the next level and it is emptied. Each value at level l thus stands for
+// base^l of the values added and the estimate is the weighted median of the
+// values in all the buffers. It needs only base values for each level and
+// the number of levels grows with the logarithm of the number of values.
+type remedian struct {
+	base   int
+	levels [][]float64
+}
+
+// newRemedian creates a new remedian with buffers of the given size
+func newRemedian(base int) *remedian {
+	return &remedian{
+		base:   base,
+		levels: [][]float64{make([]float64, 0, base)},
+	}
+}
+
+// add adds the value to the lowest level
+func (rm *remedian) add(v float64) {
+	rm.addAt(v, 0)
+}
+
+// addAt adds the value to the buffer at the given level. If that fills
+// the buffer its median is added to the next level and it is emptied.
+func (rm *remedian) addAt(v float64, level int) {
+	for {
+		for level >= len(rm.levels) {
+			rm.levels = append(rm.levels, make([]float64, 0, rm.base))
+		}
+		buf := append(rm.levels[level], v)
+		if len(buf) < rm.base {
+			rm.levels[level] = buf
+			return
+		}
+		slices.Sort(buf)
+		v = buf[len(buf)/2]
+		rm.levels[level] = buf[:0]
+		level++
+	}
+}
+
+// estimate returns the weighted median of the values in the buffers or NaN
+// if there are none
+func (rm remedian) estimate() float64 {
+	type weighted struct {
+		val    float64
+		weight float64
+	}
+	var vals []weighted
+	var total float64
+	weight := 1.0
+	for _, buf := range rm.levels {
+		for _, v := range buf {
+			vals = append(vals, weighted{val: v, weight: weight})
+			total += weight
+		}
+		weight *= float64(rm.base)
+	}
+	if len(vals) == 0 {
+		return math.NaN()
+	}
+
+	slices.SortFunc(vals, func(a, b weighted) int {
+		return cmp.Compare(a.val, b.val)
+	})
+	var cum float64
+	for _, wv := range vals {
+		cum += wv.weight
+		if cum >= total/2 {
+			return wv.val
+		}
+	}
+	return vals[len(vals)-1].val
+}
+
+// reset discards the values, keeping the buffers for reuse
+func (rm *remedian) reset() {
+	for i := range rm.levels {
+		rm.levels[i] = rm.levels[i][:0]
+	}
+}
+
+// clone returns an independent copy of the remedian
+func (rm remedian) clone() *remedian {
+	levels := make([][]float64, len(rm.levels))
+	for i, buf := range rm.levels {
+		levels[i] = cloneFloat64Slice(buf)
+	}
+	rm.levels = levels
+	return &rm
+}
+
+// merge adds the values in the buffers of the other remedian to this one,
+// each at the level it had, so that it keeps its weight. The bases must be
+// the same.
+func (rm *remedian) merge(o *remedian) {
+	for level, buf := range o.levels {
+		for _, v := range buf {
+			rm.addAt(v, level)
+		}
+	}
+}
+
+// check returns an error if the remedian is not valid
+func (rm remedian) check() error {
+	if rm.base < minRemedianBase || rm.base > maxRemedianBase ||
+		rm.base%2 == 0 {
+		return fmt.Errorf("bad remedian base (%d)", rm.base)
+	}
+	for i, buf := range rm.levels {
+		if len(buf) >= rm.base {
+			return fmt.Errorf("bad remedian level %d size (%d of %d)",
+				i, len(buf), rm.base)
+		}
+	}
+	return nil
+}
+
+// StatApproxMedian returns a function that will make the Stat estimate the
+// median of the values with the remedian algorithm (see ApproxMedian). The
+// base is the number of values held at each level of the estimator; it
+// must be odd. The estimator needs only base values for each level and
+// adds a level each time the number of values grows by a factor of the
+// base, so a base of 11 needs fewer than 100 values to estimate the median
+// of a billion. A larger base gives a more accurate estimate. This is much
+// cheaper than keeping a histogram or a quantile sketch when only the
+// median is wanted, for instance with StatNoHist. Note that the estimate
+// can be poor if the values follow a trend, such as arriving in sorted
+// order, rather than arriving in no particular order.
+func StatApproxMedian(base int) StatOpt {
+	return func(s *Stat) error {
+		if s.remedian != nil {
+			return repeatedOption(
+				"the approximate median has already been set up")
+		}
+		if base < minRemedianBase || base > maxRemedianBase || base%2 == 0 {
+			return invalidValue("Invalid remedian base (%d)"+
+				" - it must be odd, >= %d and <= %d",
+				base, minRemedianBase, maxRemedianBase)
+		}
+
+		s.remedian = newRemedian(base)
+		return nil
+	}
+}
+
+// ApproxMedian returns the estimate of the median of the values (see
+// StatApproxMedian). It returns 0.0 if no values have been added and NaN
+// if the Stat is not estimating the median.
+func (s Stat) ApproxMedian() float64 {
+	if s.remedian == nil {
+		return math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0
+	}
+	return s.remedian.estimate()
+}
@@ -0,0 +1,74 @@
+package smpls
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestApproxMedian(t *testing.T) {
+	s := NewStatOrPanic("ms", StatNoHist(), StatApproxMedian(11))
+	testhelper.DiffFloat(t, "empty", "median", s.ApproxMedian(), 0, 0)
+
+	s.Add(3, 1, 2)
+	testhelper.DiffFloat(t, "few values", "median", s.ApproxMedian(), 2, 0)
+
+	s.Reset()
+	r := rand.New(rand.NewPCG(1, 2))
+	vals := make([]float64, 0, 100000)
+	for range cap(vals) {
+		v := r.NormFloat64()*10 + 100
+		vals = append(vals, v)
+		s.Add(v)
+	}
+	slices.Sort(vals)
+	exp := vals[len(vals)/2]
+	testhelper.DiffFloat(t, "normal", "median", s.ApproxMedian(), exp, 0.5)
+	if n := len(s.remedian.levels); n > 6 {
+		t.Errorf("too many remedian levels: %d", n)
+	}
+
+	testhelper.DiffBool(t, "not estimated", "median is NaN",
+		math.IsNaN(NewStatOrPanic("ms").ApproxMedian()), true)
+}
+
+func TestApproxMedianMerge(t *testing.T) {
+	a := NewStatOrPanic("ms", StatApproxMedian(5))
+	b := NewStatOrPanic("ms", StatApproxMedian(5))
+	r := rand.New(rand.NewPCG(3, 4))
+	for i, n := range r.Perm(1000) {
+		if i%2 == 0 {
+			a.Add(float64(n))
+		} else {
+			b.Add(float64(n))
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal("unexpected merge error:", err)
+	}
+	testhelper.DiffFloat(t, "merged", "median", a.ApproxMedian(), 500, 50)
+
+	c := NewStatOrPanic("ms", StatApproxMedian(7))
+	c.Add(1)
+	err := a.Merge(c)
+	testhelper.DiffBool(t, "different bases", "error", err != nil, true)
+
+	f := a.Fork()
+	f.Add(1, 2, 3)
+	testhelper.DiffFloat(t, "forked", "median", f.ApproxMedian(), 2, 0)
+}
+
+func TestStatApproxMedian(t *testing.T) {
+	for _, base := range []int{1, 4, 1003} {
+		_, err := NewStat("ms", StatApproxMedian(base))
+		testhelper.DiffBool(t, "bad base", "invalid value error",
+			errors.Is(err, ErrInvalidValue), true)
+	}
+	_, err := NewStat("ms", StatApproxMedian(3), StatApproxMedian(3))
+	testhelper.DiffBool(t, "repeated", "repeated option error",
+		errors.Is(err, ErrRepeatedOption), true)
+}
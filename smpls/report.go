@@ -0,0 +1,402 @@
+package smpls
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// reportColSep separates the columns of a Report
+const reportColSep = "  "
+
+// NamedStat associates a name with a Stat
+type NamedStat struct {
+	Name string
+	Stat *Stat
+}
+
+// NamedMetric associates a name with a Metric
+type NamedMetric struct {
+	Name   string
+	Metric Metric
+}
+
+// Report writes a table of the values of a collection of Stats, one row
+// per Stat, with the columns aligned. The rows are sorted by name unless
+// another order is chosen with ReportSortBy and the histograms of the
+// Stats can follow the table. Any other Metrics, such as Counters and
+// Gauges, are shown in a second table, sorted by name.
+type Report struct {
+	stats   []NamedStat
+	metrics []NamedMetric
+
+	fmtOpts    *FmtOpts
+	sortField  StatField
+	sortSet    bool
+	descending bool
+	showHist   bool
+	histOpts   HistOpts
+	metaKeys   []string
+	pctileErrs bool
+	pctChange  bool
+
+	shareBarWidth int
+	shareFirst    bool
+}
+
+// ReportOpt is the type of an option function that can be passed to
+// NewReport
+type ReportOpt func(r *Report) error
+
+// ReportFormat returns a function that will set the fields shown in the
+// Report, as columns, and the way the values are formatted. If it is not
+// given the default fields are shown. Any Stat which has its own format
+// (see StatFormat) has its values formatted according to that but the
+// columns are always those given here.
+func ReportFormat(fo FmtOpts) ReportOpt {
+	return func(r *Report) error {
+		if err := fo.check(); err != nil {
+			return err
+		}
+		fo.Fields = slices.Clone(fo.Fields)
+		r.fmtOpts = &fo
+		return nil
+	}
+}
+
+// ReportSortBy returns a function that will sort the rows of the Report by
+// the value of the field rather than by name. Rows with the same value
+// are sorted by name.
+func ReportSortBy(f StatField, descending bool) ReportOpt {
+	return func(r *Report) error {
+		if f < 0 || f >= fieldCount {
+			return invalidValue("Invalid field (%d)", f)
+		}
+		r.sortField, r.sortSet, r.descending = f, true, descending
+		return nil
+	}
+}
+
+// ReportHist returns a function that will make the Report show the
+// histogram of each Stat, formatted according to the HistOpts, after the
+// table of values
+func ReportHist(ho HistOpts) ReportOpt {
+	return func(r *Report) error {
+		r.showHist = true
+		r.histOpts = ho
+		return nil
+	}
+}
+
+// ReportMeta returns a function that will add a column to the Report, after
+// the units, for each of the metadata keys (see SetMeta) showing the value
+// recorded with each Stat. The value is blank for a Stat without that key.
+func ReportMeta(keys ...string) ReportOpt {
+	return func(r *Report) error {
+		for _, k := range keys {
+			if slices.Contains(r.metaKeys, k) {
+				return fmt.Errorf("the metadata key %q is already shown", k)
+			}
+			r.metaKeys = append(r.metaKeys, k)
+		}
+		return nil
+	}
+}
+
+// ReportPctileErrors returns a function that will add a column to the
+// Report after each percentile field (the median, p90 and p99) showing
+// the largest error there can be in the percentile (see PercentileError).
+// This shows how far an estimated percentile can be trusted; it is zero
+// while a Stat still holds all its values.
+func ReportPctileErrors() ReportOpt {
+	return func(r *Report) error {
+		r.pctileErrs = true
+		return nil
+	}
+}
+
+// ReportPctChange returns a function that will make the Report follow each
+// value with its percentage change since the last cycle of the Stat, as in
+// "5.50 (+3.2%)" (see PctChange). The change is only shown for Stats which
+// track changes (see StatTrackChange) and have remembered a cycle.
+func ReportPctChange() ReportOpt {
+	return func(r *Report) error {
+		r.pctChange = true
+		return nil
+	}
+}
+
+// NewReport creates a new Report
+func NewReport(opts ...ReportOpt) (*Report, error) {
+	r := &Report{}
+	for _, o := range opts {
+		if err := o(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// NewReportOrPanic creates a new Report and will panic if any errors are
+// detected
+func NewReportOrPanic(opts ...ReportOpt) *Report {
+	r, err := NewReport(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Add adds the named Stats to the Report
+func (r *Report) Add(stats ...NamedStat) {
+	r.stats = append(r.stats, stats...)
+}
+
+// AddStat adds the Stat to the Report with the given name
+func (r *Report) AddStat(name string, s *Stat) {
+	r.stats = append(r.stats, NamedStat{Name: name, Stat: s})
+}
+
+// AddMetric adds the Metric to the Report with the given name. A Stat is
+// added as if by AddStat.
+func (r *Report) AddMetric(name string, m Metric) {
+	if s, ok := m.(*Stat); ok {
+		r.AddStat(name, s)
+		return
+	}
+	r.metrics = append(r.metrics, NamedMetric{Name: name, Metric: m})
+}
+
+// AddStatSet adds all the Stats and other Metrics in the StatSet to the
+// Report
+func (r *Report) AddStatSet(ss *StatSet) {
+	for _, name := range ss.Names() {
+		r.AddStat(name, ss.stats[name])
+	}
+	for _, name := range ss.MetricNames() {
+		r.AddMetric(name, ss.metrics[name])
+	}
+}
+
+// sorted returns the named Stats in the order they are to be reported
+func (r Report) sorted() []NamedStat {
+	stats := slices.Clone(r.stats)
+	slices.SortStableFunc(stats, func(a, b NamedStat) int {
+		if r.shareFirst {
+			if c := cmp.Compare(b.Stat.Sum(), a.Stat.Sum()); c != 0 {
+				return c
+			}
+		}
+		if r.sortSet {
+			c := cmp.Compare(a.Stat.fieldVal(r.sortField),
+				b.Stat.fieldVal(r.sortField))
+			if r.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return stats
+}
+
+// fmtOptsFor returns the FmtOpts to use for the values of the Stat
+func (r Report) fmtOptsFor(s *Stat) FmtOpts {
+	if s.fmtOpts != nil {
+		return *s.fmtOpts
+	}
+	if r.fmtOpts != nil {
+		return *r.fmtOpts
+	}
+	return FmtOpts{}
+}
+
+// metricFmtOpts returns the FmtOpts to use for the values of the Metric
+func (r Report) metricFmtOpts(m Metric) FmtOpts {
+	var fo *FmtOpts
+	switch m := m.(type) {
+	case *Counter:
+		fo = m.fmtOpts
+	case *Gauge:
+		fo = m.fmtOpts
+	}
+	if fo != nil {
+		return *fo
+	}
+	if r.fmtOpts != nil {
+		return *r.fmtOpts
+	}
+	return FmtOpts{}
+}
+
+// metricTable returns the cells of the table of the Metrics which are not
+// Stats, including the header row, sorted by name
+func (r Report) metricTable() [][]string {
+	metrics := slices.Clone(r.metrics)
+	slices.SortStableFunc(metrics, func(a, b NamedMetric) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	rows := [][]string{{"name", "units", "value"}}
+	for _, nm := range metrics {
+		rows = append(rows, []string{
+			nm.Name,
+			nm.Metric.Units(),
+			nm.Metric.StringWithOpts(r.metricFmtOpts(nm.Metric)),
+		})
+	}
+	return rows
+}
+
+// table returns the cells of the Report table, including the header row
+func (r Report) table(stats []NamedStat) [][]string {
+	fields := FmtOpts{}.fields()
+	if r.fmtOpts != nil {
+		fields = r.fmtOpts.fields()
+	}
+
+	header := []string{"name", "units"}
+	header = append(header, r.metaKeys...)
+	for _, f := range fields {
+		header = append(header, f.String())
+		if _, ok := f.pct(); ok && r.pctileErrs {
+			header = append(header, f.String()+" error")
+		}
+	}
+	if r.shareBarWidth > 0 {
+		header = append(header, "sum", "share", "")
+	}
+	rows := [][]string{header}
+
+	var sh []Share
+	if r.shareBarWidth > 0 {
+		sh, _ = shares(stats)
+	}
+	for i, ns := range stats {
+		fo := r.fmtOptsFor(ns.Stat)
+		row := []string{ns.Name, ns.Stat.units}
+		for _, k := range r.metaKeys {
+			row = append(row, ns.Stat.meta[k])
+		}
+		for _, f := range fields {
+			row = append(row, r.fieldStr(ns.Stat, f, fo))
+			if p, ok := f.pct(); ok && r.pctileErrs {
+				row = append(row, "±"+
+					fo.fmtVal(ns.Stat.PercentileError(p), ns.Stat.units))
+			}
+		}
+		if sh != nil {
+			row = append(row,
+				fo.fmtVal(sh[i].Sum, ns.Stat.units),
+				fmt.Sprintf("%.1f%%", sh[i].Pct),
+				shareBar(sh[i].Pct, r.shareBarWidth, r.histOpts.barRune()))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// fieldStr returns the value of the field of the Stat formatted for the
+// Report, followed by the change since the last cycle if it is shown
+func (r Report) fieldStr(s *Stat, f StatField, fo FmtOpts) string {
+	str := s.fieldStr(f, fo)
+	if !r.pctChange {
+		return str
+	}
+	if pc := s.PctChange(f); !math.IsNaN(pc) && !math.IsInf(pc, 0) {
+		str += fmt.Sprintf(" (%+.1f%%)", pc)
+	}
+	return str
+}
+
+// writeRows writes the rows to the Builder with the columns aligned. The
+// columns for which left returns true are left aligned and the others are
+// right aligned.
+func writeRows(b *strings.Builder, rows [][]string, left func(i int) bool) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+			if left(i) {
+				cells = append(cells, cell+pad)
+			} else {
+				cells = append(cells, pad+cell)
+			}
+		}
+		b.WriteString(strings.TrimRight(
+			strings.Join(cells, reportColSep), " ") + "\n")
+	}
+}
+
+// Write writes the Report to the Writer. The name, units and metadata
+// columns are left aligned and the values are right aligned, apart from
+// the bars showing the share of the sum (see ReportShareOfSum). Any
+// Metrics which are not Stats follow in a table of their own, separated by
+// a blank line, with all the columns left aligned. It returns an error if
+// the Report cannot be written, if any of the Stats or Metrics is nil or
+// if the share of the sum is shown and the Stats have different units.
+func (r Report) Write(w io.Writer) error {
+	for _, ns := range r.stats {
+		if ns.Stat == nil {
+			return fmt.Errorf("the Stat named %q is nil", ns.Name)
+		}
+	}
+	for _, nm := range r.metrics {
+		if nm.Metric == nil {
+			return fmt.Errorf("the Metric named %q is nil", nm.Name)
+		}
+	}
+	if r.shareBarWidth > 0 {
+		if _, err := shares(r.stats); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	stats := r.sorted()
+	if len(stats) > 0 || len(r.metrics) == 0 {
+		rows := r.table(stats)
+		writeRows(&b, rows, func(i int) bool {
+			return i < 2+len(r.metaKeys) ||
+				(r.shareBarWidth > 0 && i == len(rows[0])-1)
+		})
+	}
+
+	if len(r.metrics) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		writeRows(&b, r.metricTable(), func(int) bool { return true })
+	}
+
+	if r.showHist {
+		for _, ns := range stats {
+			b.WriteString("\n" + ns.Name + ":\n")
+			b.WriteString(ns.Stat.HistWithOpts(r.histOpts))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String returns the Report as a string
+func (r Report) String() string {
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		return err.Error()
+	}
+	return b.String()
+}
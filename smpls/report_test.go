@@ -0,0 +1,136 @@
+package smpls
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewReport(t *testing.T) {
+	_, err := NewReport(ReportSortBy(99, false))
+	testhelper.CheckExpErrWithID(t, "ReportSortBy - bad field", err,
+		testhelper.MkExpErr("Invalid field (99)"))
+
+	_, err = NewReport(ReportFormat(FmtOpts{SigFigs: -1}))
+	testhelper.CheckExpErrWithID(t, "ReportFormat - bad sig figs", err,
+		testhelper.MkExpErr("Invalid significant figures (-1)"))
+}
+
+func TestReport(t *testing.T) {
+	fast := NewStatOrPanic("ms")
+	fast.Add(1, 2, 3)
+	slow := NewStatOrPanic("ms")
+	slow.Add(100, 250)
+	size := NewStatOrPanic("bytes",
+		StatFormat(FmtOpts{Style: FmtFixed, SigFigs: 2}))
+	size.Add(1024)
+
+	fo := FmtOpts{
+		Style:  FmtGeneral,
+		Fields: []StatField{FieldCount, FieldMean, FieldMax},
+	}
+
+	r := NewReportOrPanic(ReportFormat(fo))
+	r.AddStat("slow", slow)
+	r.Add(NamedStat{Name: "fast", Stat: fast},
+		NamedStat{Name: "size", Stat: size})
+	testhelper.DiffString(t, "by name", "report", r.String(),
+		"name  units  observations   avg   max\n"+
+			"fast  ms                3     2     3\n"+
+			"size  bytes             1  1024  1024\n"+
+			"slow  ms                2   175   250\n")
+
+	r = NewReportOrPanic(ReportFormat(fo), ReportSortBy(FieldMax, true))
+	ss := NewStatSetOrPanic("ms")
+	if err := errors.Join(ss.Register("fast", fast),
+		ss.Register("slow", slow)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r.AddStatSet(ss)
+	testhelper.DiffString(t, "by max, descending", "report", r.String(),
+		"name  units  observations  avg  max\n"+
+			"slow  ms                2  175  250\n"+
+			"fast  ms                3    2    3\n")
+
+	r = NewReportOrPanic(ReportHist(HistOpts{}))
+	r.AddStat("fast", fast)
+	testhelper.ShouldContain(t, "with hist", "report", r.String(),
+		[]string{"observations", "\nfast:\nunits: ms\n"})
+
+	r = NewReportOrPanic(ReportPctileErrors(), ReportFormat(FmtOpts{
+		Style:  FmtGeneral,
+		Fields: []StatField{FieldCount, FieldP90},
+	}))
+	r.AddStat("fast", fast)
+	testhelper.DiffString(t, "percentile errors", "report", r.String(),
+		"name  units  observations  p90  p90 error\n"+
+			"fast  ms                3  2.8         ±0\n")
+
+	tracked := NewStatOrPanic("ms", StatTrackChange())
+	tracked.Add(10, 20)
+	tracked.Reset()
+	tracked.Add(12, 18, 21)
+	r = NewReportOrPanic(ReportPctChange(), ReportFormat(fo))
+	r.AddStat("fast", fast)
+	r.AddStat("tracked", tracked)
+	testhelper.DiffString(t, "percent change", "report", r.String(),
+		"name     units  observations          avg         max\n"+
+			"fast     ms                3            2           3\n"+
+			"tracked  ms       3 (+50.0%)  17 (+13.3%)  21 (+5.0%)\n")
+
+	r.AddStat("missing", nil)
+	testhelper.DiffString(t, "nil Stat", "report", r.String(),
+		`the Stat named "missing" is nil`)
+}
+
+func TestReportMetrics(t *testing.T) {
+	lat := NewStatOrPanic("ms")
+	lat.Add(1, 2, 3)
+	reqs := NewCounter("requests")
+	if err := reqs.Add(1500); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	queue := NewGauge("jobs")
+	if err := errors.Join(queue.Set(4), queue.Set(2)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fo := FmtOpts{
+		SigFigs: 4,
+		Style:   FmtGeneral,
+		Fields:  []StatField{FieldCount, FieldMax},
+		Number:  NumFmtEnglish,
+	}
+	r := NewReportOrPanic(ReportFormat(fo))
+	r.AddMetric("requests", reqs)
+	r.AddMetric("latency", lat)
+	r.AddMetric("queue", queue)
+	testhelper.DiffString(t, "Stats and Metrics", "report", r.String(),
+		"name     units  observations  max\n"+
+			"latency  ms                3    3\n"+
+			"\n"+
+			"name      units     value\n"+
+			"queue     jobs      value: 2, min: 2, max: 4\n"+
+			"requests  requests  total: 1,500\n")
+
+	err := reqs.SetFormat(FmtOpts{Style: FmtFixed, SigFigs: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r = NewReportOrPanic()
+	ss := NewStatSetOrPanic("ms")
+	if err := ss.RegisterMetric("requests", reqs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r.AddStatSet(ss)
+	testhelper.DiffString(t, "Metrics only", "report", r.String(),
+		"name      units     value\n"+
+			"requests  requests  total: 1500\n")
+
+	r = NewReportOrPanic()
+	r.AddMetric("nil", (Metric)(nil))
+	testhelper.CheckExpErrWithID(t, "nil Metric", r.Write(io.Discard),
+		testhelper.MkExpErr(`the Metric named "nil" is nil`))
+}
@@ -0,0 +1,162 @@
+package smpls
+
+import (
+	"fmt"
+	"time"
+)
+
+// RequestStats collects the statistics most often wanted when measuring a
+// service, for instance in a load test: the duration of each request, the
+// number of bytes it carried, the number of requests in progress at once
+// and the rate at which requests complete and bytes are carried.
+//
+// The durations are recorded in seconds and the sizes in bytes so that,
+// if the Stats are created with the StatUnitPrefixes option, they are
+// shown with suitable prefixes, as in "12.5 ms" or "1.20 MiB".
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type RequestStats struct {
+	durations   *Stat
+	sizes       *Stat
+	concurrency *Stat
+	rate        *Rate
+
+	inFlight int
+	now      func() time.Time
+}
+
+// NewRequestStats creates a new RequestStats. The options are used when
+// creating each of the Stats; the rate of completed requests is measured
+// by a Rate with the default window.
+func NewRequestStats(opts ...StatOpt) (*RequestStats, error) {
+	durations, err := NewStat("s", opts...)
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := NewStat("bytes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := NewStat("requests", opts...)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := NewRate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestStats{
+		durations:   durations,
+		sizes:       sizes,
+		concurrency: concurrency,
+		rate:        rate,
+		now:         time.Now,
+	}, nil
+}
+
+// NewRequestStatsOrPanic creates a new RequestStats and will panic if any
+// errors are detected
+func NewRequestStatsOrPanic(opts ...StatOpt) *RequestStats {
+	rs, err := NewRequestStats(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// Begin records that a request has started. The number of requests in
+// progress, including this one, is added to the concurrency Stat. Each
+// call should be matched by a later call to Observe; if Begin is never
+// called the concurrency is not recorded.
+func (rs *RequestStats) Begin() {
+	rs.inFlight++
+	rs.concurrency.Add(float64(rs.inFlight))
+}
+
+// Observe records a completed request which took the given time and
+// carried the given number of bytes. If any requests have been started
+// with Begin the number in progress is reduced by one.
+func (rs *RequestStats) Observe(d time.Duration, bytes int) {
+	if rs.inFlight > 0 {
+		rs.inFlight--
+	}
+	rs.durations.Add(d.Seconds())
+	rs.sizes.Add(float64(bytes))
+	rs.rate.AddAt(rs.now(), float64(bytes))
+}
+
+// Track calls Begin and returns a function which, when called with the
+// number of bytes carried, calls Observe with the time since Track was
+// called. This makes it simple to record a request:
+//
+//	done := rs.Track()
+//	n, err := doRequest()
+//	done(n)
+func (rs *RequestStats) Track() func(bytes int) {
+	start := rs.now()
+	rs.Begin()
+	return func(bytes int) {
+		rs.Observe(rs.now().Sub(start), bytes)
+	}
+}
+
+// Reset discards all the recorded requests. The count of requests in
+// progress is also cleared.
+func (rs *RequestStats) Reset() {
+	rs.durations.Reset()
+	rs.sizes.Reset()
+	rs.concurrency.Reset()
+	rs.rate.Reset()
+	rs.inFlight = 0
+}
+
+// Count returns the number of completed requests
+func (rs RequestStats) Count() int {
+	return rs.durations.Count()
+}
+
+// InFlight returns the number of requests started with Begin which have
+// not yet been observed
+func (rs RequestStats) InFlight() int {
+	return rs.inFlight
+}
+
+// Durations returns the Stat recording the durations of the requests, in
+// seconds
+func (rs RequestStats) Durations() *Stat {
+	return rs.durations
+}
+
+// Sizes returns the Stat recording the number of bytes carried by each
+// request
+func (rs RequestStats) Sizes() *Stat {
+	return rs.sizes
+}
+
+// Concurrency returns the Stat recording the number of requests in
+// progress as each request began
+func (rs RequestStats) Concurrency() *Stat {
+	return rs.concurrency
+}
+
+// Rate returns the Rate recording the completed requests. Its observation
+// rate is the number of requests per second and its sum rate is the number
+// of bytes per second.
+func (rs RequestStats) Rate() *Rate {
+	return rs.rate
+}
+
+// String returns a report of the durations, sizes and concurrency of the
+// requests and the rates at which they completed, one per line
+func (rs RequestStats) String() string {
+	str := fmt.Sprintf("duration:    %s\n", rs.durations)
+	str += fmt.Sprintf("size:        %s\n", rs.sizes)
+	if rs.concurrency.Count() > 0 {
+		str += fmt.Sprintf("concurrency: %s\n", rs.concurrency)
+	}
+	str += fmt.Sprintf("throughput:  %.3g requests/sec, %.3g bytes/sec\n",
+		rs.rate.ObsPerSec(), rs.rate.SumPerSec())
+	return str
+}
@@ -0,0 +1,57 @@
+package smpls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRequestStats(t *testing.T) {
+	rs := NewRequestStatsOrPanic(StatCacheSize(10))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rs.now = func() time.Time { return now }
+
+	done1 := rs.Track()
+	now = now.Add(100 * time.Millisecond)
+	done2 := rs.Track()
+	testhelper.DiffInt(t, "two started", "in flight", rs.InFlight(), 2)
+
+	now = now.Add(100 * time.Millisecond)
+	done1(1000)
+	now = now.Add(300 * time.Millisecond)
+	done2(3000)
+	rs.Observe(50*time.Millisecond, 500) // no matching Begin
+
+	testhelper.DiffInt(t, "RequestStats", "count", rs.Count(), 3)
+	testhelper.DiffInt(t, "RequestStats", "in flight", rs.InFlight(), 0)
+	testhelper.DiffString(t, "RequestStats", "duration units",
+		rs.Durations().Units(), "s")
+	testhelper.DiffFloat(t, "RequestStats", "max duration",
+		rs.Durations().Max(), 0.4, 1e-12)
+	testhelper.DiffFloat(t, "RequestStats", "min duration",
+		rs.Durations().Min(), 0.05, 1e-12)
+	testhelper.DiffFloat(t, "RequestStats", "bytes",
+		rs.Sizes().Sum(), 4500, 0)
+	testhelper.DiffInt(t, "RequestStats", "concurrency count",
+		rs.Concurrency().Count(), 2)
+	testhelper.DiffFloat(t, "RequestStats", "max concurrency",
+		rs.Concurrency().Max(), 2, 0)
+	testhelper.DiffInt(t, "RequestStats", "rate count", rs.Rate().Count(), 3)
+	testhelper.DiffFloat(t, "RequestStats", "bytes/sec",
+		rs.Rate().SumPerSec(), 4500/0.3, 1e-9)
+
+	testhelper.ShouldContain(t, "RequestStats", "report", rs.String(),
+		[]string{
+			"duration:    " + rs.Durations().String(),
+			"size:        " + rs.Sizes().String(),
+			"concurrency: " + rs.Concurrency().String(),
+			"throughput:  10 requests/sec, 1.5e+04 bytes/sec",
+		})
+
+	rs.Begin()
+	rs.Reset()
+	testhelper.DiffInt(t, "Reset", "count", rs.Count(), 0)
+	testhelper.DiffInt(t, "Reset", "in flight", rs.InFlight(), 0)
+	testhelper.DiffInt(t, "Reset", "rate count", rs.Rate().Count(), 0)
+}
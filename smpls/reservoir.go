@@ -0,0 +1,144 @@
+package smpls
+
+import (
+	"iter"
+	"slices"
+)
+
+const (
+	minReservoirSize = 1
+
+	// reservoirSeed is the starting state of the random number generator
+	// used to choose the values kept in a reservoir. A fixed seed means
+	// that the same values added in the same order give the same sample.
+	reservoirSeed = 0x5eed5eed5eed5eed
+)
+
+// reservoir holds a uniform random sample of the values added using
+// Vitter's Algorithm R. Each value seen has an equal chance of being in
+// the sample.
+type reservoir struct {
+	vals []float64
+	seen int
+	rng  uint64
+}
+
+// newReservoir creates a new reservoir holding at most size values
+func newReservoir(size int) *reservoir {
+	return &reservoir{
+		vals: make([]float64, 0, size),
+		rng:  reservoirSeed,
+	}
+}
+
+// next returns the next random number, generated by SplitMix64
+func (r *reservoir) next() uint64 {
+	r.rng += 0x9e3779b97f4a7c15
+	return mix64(r.rng)
+}
+
+// intn returns a random number in the range [0, n)
+func (r *reservoir) intn(n int) int {
+	return int(r.next() % uint64(n))
+}
+
+// add offers the value to the reservoir
+func (r *reservoir) add(v float64) {
+	r.seen++
+	if len(r.vals) < cap(r.vals) {
+		r.vals = append(r.vals, v)
+		return
+	}
+	if j := r.intn(r.seen); j < len(r.vals) {
+		r.vals[j] = v
+	}
+}
+
+// reset discards all the values
+func (r *reservoir) reset() {
+	r.vals = r.vals[:0]
+	r.seen = 0
+	r.rng = reservoirSeed
+}
+
+// clone returns an independent copy of the reservoir
+func (r reservoir) clone() *reservoir {
+	r.vals = cloneFloat64Slice(r.vals)
+	return &r
+}
+
+// shuffle randomly reorders the values
+func (r *reservoir) shuffle(vals []float64) {
+	for i := len(vals) - 1; i > 0; i-- {
+		j := r.intn(i + 1)
+		vals[i], vals[j] = vals[j], vals[i]
+	}
+}
+
+// merge combines the sample from the other reservoir with this one so that
+// it is, approximately, a uniform sample of the values seen by both. Each
+// value is drawn from one or other sample in proportion to the number of
+// values each has still to account for.
+func (r *reservoir) merge(other *reservoir) {
+	if other.seen == 0 {
+		return
+	}
+
+	a := slices.Clone(r.vals)
+	b := slices.Clone(other.vals)
+	r.shuffle(a)
+	r.shuffle(b)
+	aSeen, bSeen := r.seen, other.seen
+
+	r.vals = r.vals[:0]
+	for len(r.vals) < cap(r.vals) && (len(a) > 0 || len(b) > 0) {
+		if len(b) == 0 || (len(a) > 0 && r.intn(aSeen+bSeen) < aSeen) {
+			r.vals = append(r.vals, a[len(a)-1])
+			a = a[:len(a)-1]
+			aSeen = max(aSeen-1, len(a))
+		} else {
+			r.vals = append(r.vals, b[len(b)-1])
+			b = b[:len(b)-1]
+			bSeen = max(bSeen-1, len(b))
+		}
+	}
+	r.seen += other.seen
+}
+
+// StatReservoirSize returns a function that will make the Stat keep a
+// uniform random sample, of the given size, of the values added. Unlike
+// the cache, which only holds the first values added, the sample
+// represents all the values added and is kept for the life of the Stat.
+// The sample can be examined with the ReservoirValues method.
+func StatReservoirSize(size int) StatOpt {
+	return func(s *Stat) error {
+		if s.reservoir != nil {
+			return repeatedOption("the reservoir has already been created")
+		}
+		if size < minReservoirSize {
+			return invalidValue("Invalid reservoir size (%d) - it must be >= %d",
+				size, minReservoirSize)
+		}
+
+		s.reservoir = newReservoir(size)
+		return nil
+	}
+}
+
+// CachedValues returns an iterator over all the values added, in no
+// particular order, while they are still held, either in the cache or in
+// the slice of minimum values. Once the values are no longer all held the
+// iterator yields no values.
+func (s Stat) CachedValues() iter.Seq[float64] {
+	return slices.Values(s.retained())
+}
+
+// ReservoirValues returns an iterator over the values in the random sample
+// of the values added (see StatReservoirSize), in no particular order. If
+// the Stat does not keep a sample the iterator yields no values.
+func (s Stat) ReservoirValues() iter.Seq[float64] {
+	if s.reservoir == nil {
+		return slices.Values([]float64(nil))
+	}
+	return slices.Values(s.reservoir.vals)
+}
@@ -0,0 +1,104 @@
+package smpls
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatReservoirSize(t *testing.T) {
+	_, err := NewStat("units", StatReservoirSize(0))
+	testhelper.CheckExpErrWithID(t, "StatReservoirSize - bad size", err,
+		testhelper.MkExpErr("Invalid reservoir size (0) - it must be >= 1"))
+
+	_, err = NewStat("units", StatReservoirSize(5), StatReservoirSize(5))
+	testhelper.CheckExpErrWithID(t, "StatReservoirSize - repeated", err,
+		testhelper.MkExpErr("the reservoir has already been created"))
+}
+
+func TestCachedValues(t *testing.T) {
+	s := NewStatOrPanic("units", StatCacheSize(10))
+	s.Add(3, 1, 2)
+	vals := slices.Sorted(s.CachedValues())
+	testhelper.DiffFloatSlice(t, "cache in use", "values",
+		vals, []float64{1, 2, 3}, 0)
+
+	addSeq(s, 0, 1, 20)
+	testhelper.DiffInt(t, "cache full", "count",
+		len(slices.Collect(s.CachedValues())), 0)
+
+	var count int
+	for range s.ReservoirValues() {
+		count++
+	}
+	testhelper.DiffInt(t, "no reservoir", "count", count, 0)
+}
+
+func TestReservoirValues(t *testing.T) {
+	const size = 100
+	s := NewStatOrPanic("units", StatReservoirSize(size))
+
+	s.Add(5, 6, 7)
+	testhelper.DiffFloatSlice(t, "part full", "values",
+		slices.Collect(s.ReservoirValues()), []float64{5, 6, 7}, 0)
+
+	s.Reset()
+	addSeq(s, 0, 1, 10000)
+	vals := slices.Collect(s.ReservoirValues())
+	testhelper.DiffInt(t, "full", "count", len(vals), size)
+
+	// a uniform sample should be spread across all the values added
+	var high int
+	for _, v := range vals {
+		if v >= 5000 {
+			high++
+		}
+	}
+	if high < size/4 || high > 3*size/4 {
+		t.Errorf("the sample is not uniform: %d of %d from the top half",
+			high, size)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("unexpected load error: %s", err)
+	}
+	s.Add(-1, -2, -3)
+	loaded.Add(-1, -2, -3)
+	testhelper.DiffFloatSlice(t, "save/load", "values",
+		slices.Collect(loaded.ReservoirValues()),
+		slices.Collect(s.ReservoirValues()), 0)
+}
+
+func TestReservoirMerge(t *testing.T) {
+	const size = 200
+	a := NewStatOrPanic("units", StatReservoirSize(size))
+	b := NewStatOrPanic("units", StatReservoirSize(size))
+	addSeq(a, 0, 1, 3000)
+	addSeq(b, 10000, 1, 1000)
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("unexpected merge error: %s", err)
+	}
+	vals := slices.Collect(a.ReservoirValues())
+	testhelper.DiffInt(t, "merge", "count", len(vals), size)
+	testhelper.DiffInt(t, "merge", "seen", a.reservoir.seen, 4000)
+
+	var fromB int
+	for _, v := range vals {
+		if v >= 10000 {
+			fromB++
+		}
+	}
+	// about a quarter of the values should come from b
+	if fromB < size/8 || fromB > 3*size/8 {
+		t.Errorf("the merged sample is not proportionate: %d of %d from b",
+			fromB, size)
+	}
+}
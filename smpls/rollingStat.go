@@ -0,0 +1,310 @@
+package smpls
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+)
+
+const minRollingWindow = 1
+
+// rollingNode is a node in the order-statistic treap of a RollingStat. The
+// values are ordered by value and then by the order in which they were
+// added so that every node has a distinct key.
+type rollingNode struct {
+	v           float64
+	seq         uint64
+	pri         uint32
+	left, right int32
+	size        int32
+}
+
+// RollingStat holds exactly the last N values added and gives the exact
+// minimum, maximum, median and percentiles of them. Unlike a Rate, which
+// covers a period of time, the window covers a number of values, however
+// quickly they arrive. This is useful for adaptive decisions, such as
+// throttling, which should respond to recent values only.
+//
+// The values are kept both in the order in which they were added and in a
+// balanced tree ordered by value so that adding a value and finding a
+// percentile each take O(log N) time.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type RollingStat struct {
+	units string
+
+	// nodes[0] is the empty tree; the nodes holding values start at 1
+	nodes []rollingNode
+	root  int32
+	ring  []int32
+	head  int
+	seq   uint64
+	rand  uint32
+
+	sum   float64
+	total int
+}
+
+// NewRollingStat creates a new RollingStat holding the last n values. It
+// returns an error if n is less than 1.
+func NewRollingStat(units string, n int) (*RollingStat, error) {
+	if n < minRollingWindow || n >= math.MaxInt32 {
+		return nil, invalidValue(
+			"Invalid rolling window (%d) - it must be >= %d and < %d",
+			n, minRollingWindow, math.MaxInt32)
+	}
+
+	return &RollingStat{
+		units: units,
+		nodes: make([]rollingNode, 1, n+1),
+		ring:  make([]int32, 0, n),
+		rand:  0x9e3779b9,
+	}, nil
+}
+
+// NewRollingStatOrPanic creates a new RollingStat and will panic if any
+// errors are detected
+func NewRollingStatOrPanic(units string, n int) *RollingStat {
+	rs, err := NewRollingStat(units, n)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// nextPri returns the next pseudo-random priority (from a xorshift
+// generator) for a node of the treap
+func (rs *RollingStat) nextPri() uint32 {
+	x := rs.rand
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	rs.rand = x
+	return x
+}
+
+// less reports whether node a sorts before node b
+func (rs *RollingStat) less(a, b int32) bool {
+	na, nb := &rs.nodes[a], &rs.nodes[b]
+	if c := cmp.Compare(na.v, nb.v); c != 0 {
+		return c < 0
+	}
+	return na.seq < nb.seq
+}
+
+// fix recalculates the size of the tree rooted at the node
+func (rs *RollingStat) fix(t int32) {
+	n := &rs.nodes[t]
+	n.size = 1 + rs.nodes[n.left].size + rs.nodes[n.right].size
+}
+
+// split divides the tree into the nodes sorting before the key node and
+// the rest
+func (rs *RollingStat) split(t, key int32) (int32, int32) {
+	if t == 0 {
+		return 0, 0
+	}
+	if rs.less(t, key) {
+		l, r := rs.split(rs.nodes[t].right, key)
+		rs.nodes[t].right = l
+		rs.fix(t)
+		return t, r
+	}
+	l, r := rs.split(rs.nodes[t].left, key)
+	rs.nodes[t].left = r
+	rs.fix(t)
+	return l, t
+}
+
+// join combines two trees, where every node in a sorts before every node
+// in b
+func (rs *RollingStat) join(a, b int32) int32 {
+	if a == 0 {
+		return b
+	}
+	if b == 0 {
+		return a
+	}
+	if rs.nodes[a].pri > rs.nodes[b].pri {
+		rs.nodes[a].right = rs.join(rs.nodes[a].right, b)
+		rs.fix(a)
+		return a
+	}
+	rs.nodes[b].left = rs.join(a, rs.nodes[b].left)
+	rs.fix(b)
+	return b
+}
+
+// remove removes the key node from the tree rooted at t
+func (rs *RollingStat) remove(t, key int32) int32 {
+	if t == key {
+		return rs.join(rs.nodes[t].left, rs.nodes[t].right)
+	}
+	if rs.less(key, t) {
+		rs.nodes[t].left = rs.remove(rs.nodes[t].left, key)
+	} else {
+		rs.nodes[t].right = rs.remove(rs.nodes[t].right, key)
+	}
+	rs.fix(t)
+	return t
+}
+
+// kth returns the value with the given rank, counting from zero
+func (rs RollingStat) kth(k int) float64 {
+	t := rs.root
+	for {
+		n := &rs.nodes[t]
+		ls := int(rs.nodes[n.left].size)
+		switch {
+		case k < ls:
+			t = n.left
+		case k == ls:
+			return n.v
+		default:
+			k -= ls + 1
+			t = n.right
+		}
+	}
+}
+
+// Add adds at least one new value to the RollingStat, dropping the oldest
+// values once the window is full
+func (rs *RollingStat) Add(v float64, vals ...float64) {
+	rs.addVal(v)
+	for _, v := range vals {
+		rs.addVal(v)
+	}
+}
+
+// addVal adds a single new value to the RollingStat
+func (rs *RollingStat) addVal(v float64) {
+	var idx int32
+	if len(rs.ring) < cap(rs.ring) {
+		idx = int32(len(rs.nodes))
+		rs.nodes = append(rs.nodes, rollingNode{})
+		rs.ring = append(rs.ring, idx)
+	} else {
+		idx = rs.ring[rs.head]
+		rs.root = rs.remove(rs.root, idx)
+		rs.sum -= rs.nodes[idx].v
+		rs.head = (rs.head + 1) % len(rs.ring)
+	}
+
+	rs.seq++
+	rs.nodes[idx] = rollingNode{v: v, seq: rs.seq, pri: rs.nextPri(), size: 1}
+	l, r := rs.split(rs.root, idx)
+	rs.root = rs.join(rs.join(l, idx), r)
+	rs.sum += v
+	rs.total++
+}
+
+// Reset discards all the values
+func (rs *RollingStat) Reset() {
+	rs.nodes = rs.nodes[:1]
+	rs.root = 0
+	rs.ring = rs.ring[:0]
+	rs.head = 0
+	rs.sum = 0
+	rs.total = 0
+}
+
+// Units returns the units of the RollingStat
+func (rs RollingStat) Units() string {
+	return rs.units
+}
+
+// Window returns the number of values the RollingStat holds once it is
+// full
+func (rs RollingStat) Window() int {
+	return cap(rs.ring)
+}
+
+// Count returns the number of values currently held. This is never more
+// than the Window.
+func (rs RollingStat) Count() int {
+	return len(rs.ring)
+}
+
+// Total returns the number of values that have been added, including those
+// no longer held
+func (rs RollingStat) Total() int {
+	return rs.total
+}
+
+// Values returns a copy of the values currently held, oldest first
+func (rs RollingStat) Values() []float64 {
+	vals := make([]float64, 0, len(rs.ring))
+	for i := range rs.ring {
+		vals = append(vals, rs.nodes[rs.ring[(rs.head+i)%len(rs.ring)]].v)
+	}
+	return vals
+}
+
+// Percentile returns the value below which the given percentage of the
+// values currently held fall; p should be in the range [0, 100] and is
+// forced into that range if not. It interpolates linearly between the
+// values either side, as the Percentile method of a Stat does. It returns
+// NaN if p is NaN and 0.0 if there are no values.
+func (rs RollingStat) Percentile(p float64) float64 {
+	if math.IsNaN(p) {
+		return math.NaN()
+	}
+	n := len(rs.ring)
+	if n == 0 {
+		return 0.0
+	}
+	rank := clampPct(p) / 100.0 * float64(n-1)
+	lo := int(math.Floor(rank))
+	if lo >= n-1 {
+		return rs.kth(n - 1)
+	}
+	vLo, vHi := rs.kth(lo), rs.kth(lo+1)
+	return vLo + (rank-float64(lo))*(vHi-vLo)
+}
+
+// Median returns the median of the values currently held or 0.0 if there
+// are none
+func (rs RollingStat) Median() float64 {
+	return rs.Percentile(50)
+}
+
+// Min returns the smallest value currently held or 0.0 if there are none
+func (rs RollingStat) Min() float64 {
+	if len(rs.ring) == 0 {
+		return 0.0
+	}
+	return rs.kth(0)
+}
+
+// Max returns the largest value currently held or 0.0 if there are none
+func (rs RollingStat) Max() float64 {
+	if len(rs.ring) == 0 {
+		return 0.0
+	}
+	return rs.kth(len(rs.ring) - 1)
+}
+
+// Mean returns the mean of the values currently held or 0.0 if there are
+// none. The sum of the values is kept as they are added and dropped and
+// so, after very many values, it may drift slightly from the exact mean.
+func (rs RollingStat) Mean() float64 {
+	if len(rs.ring) == 0 {
+		return 0.0
+	}
+	return rs.sum / float64(len(rs.ring))
+}
+
+// String returns a summary of the values currently held showing the count,
+// the minimum, mean, median and maximum and the 99th percentile
+func (rs RollingStat) String() string {
+	return fmt.Sprintf(
+		"%7d observations (of the last %d),"+
+			" min: %8.2e,"+
+			" avg: %8.2e,"+
+			" p50: %8.2e,"+
+			" p99: %8.2e,"+
+			" max: %8.2e",
+		rs.Count(), rs.Window(), rs.Min(), rs.Mean(),
+		rs.Median(), rs.Percentile(99), rs.Max())
+}
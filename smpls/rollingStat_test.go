@@ -0,0 +1,60 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRollingStat(t *testing.T) {
+	_, err := NewRollingStat("ms", 0)
+	testhelper.CheckExpErrWithID(t, "bad window", err,
+		testhelper.MkExpErr("Invalid rolling window (0) - it must be >= 1"))
+
+	const window = 50
+	rs := NewRollingStatOrPanic("ms", window)
+	testhelper.DiffFloat(t, "empty", "median", rs.Median(), 0, 0)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	var all []float64
+	for i := range 1000 {
+		v := float64(r.IntN(100))
+		all = append(all, v)
+		rs.Add(v)
+
+		if i%97 != 0 && i != 999 {
+			continue
+		}
+		recent := all[max(0, len(all)-window):]
+		id := fmt.Sprintf("after %d values", i+1)
+		if err := testhelper.DiffVals(rs.Values(), recent); err != nil {
+			t.Log(id)
+			t.Errorf("\t: bad values: %v\n", err)
+		}
+		sorted := sortedCopy(recent)
+		testhelper.DiffFloat(t, id, "min", rs.Min(), sorted[0], 0)
+		testhelper.DiffFloat(t, id, "max", rs.Max(), slices.Max(recent), 0)
+		for _, p := range []float64{0, 10, 50, 90, 99, 100} {
+			testhelper.DiffFloat(t, id, "percentile",
+				rs.Percentile(p), sortedPercentile(sorted, p), 0)
+		}
+		testhelper.DiffFloat(t, id, "mean", rs.Mean(), calcMean(recent), 1e-9)
+	}
+	testhelper.DiffInt(t, "full", "count", rs.Count(), window)
+	testhelper.DiffInt(t, "full", "total", rs.Total(), 1000)
+	testhelper.DiffInt(t, "full", "window", rs.Window(), window)
+	testhelper.DiffBool(t, "full", "percentile NaN is NaN",
+		math.IsNaN(rs.Percentile(math.NaN())), true)
+
+	rs.Reset()
+	testhelper.DiffInt(t, "reset", "count", rs.Count(), 0)
+	rs.Add(3, 1, 2)
+	testhelper.DiffFloat(t, "reset", "median", rs.Median(), 2, 0)
+	testhelper.DiffString(t, "reset", "String", rs.String(),
+		"      3 observations (of the last 50), min: 1.00e+00,"+
+			" avg: 2.00e+00, p50: 2.00e+00, p99: 2.98e+00, max: 3.00e+00")
+}
@@ -0,0 +1,72 @@
+package smpls
+
+const (
+	dfltHistoryLen = 10
+	minHistoryLen  = 1
+)
+
+// StatHistoryLen returns a function that will set the number of
+// Snapshots kept in the history of the Stat (see Rotate). If this option
+// is not given then the most recent 10 Snapshots are kept.
+func StatHistoryLen(n int) StatOpt {
+	return func(s *Stat) error {
+		if n < minHistoryLen {
+			return invalidValue("Invalid history length (%d) - it must be >= %d",
+				n, minHistoryLen)
+		}
+
+		s.historyLen = n
+		return nil
+	}
+}
+
+// historyCap returns the maximum number of Snapshots to keep in the
+// history
+func (s Stat) historyCap() int {
+	if s.historyLen == 0 {
+		return dfltHistoryLen
+	}
+	return s.historyLen
+}
+
+// Rotate takes a Snapshot of the Stat, records it in the history and then
+// resets the Stat (see Reset). The Snapshot is also returned. Only the
+// most recent Snapshots are kept (see StatHistoryLen); the oldest is
+// discarded when the history is full.
+//
+// This lets you report statistics for each of a series of intervals, such
+// as each minute, and compare the current interval with earlier ones
+// without keeping a separate Stat for each.
+func (s *Stat) Rotate() Snapshot {
+	snap := s.Snapshot()
+
+	if n := s.historyCap(); len(s.history) >= n {
+		copy(s.history, s.history[len(s.history)-n+1:])
+		s.history = s.history[:n-1]
+	}
+	s.history = append(s.history, snap)
+
+	s.Reset()
+	return snap
+}
+
+// History returns the Snapshots recorded by Rotate, the oldest first
+func (s Stat) History() []Snapshot {
+	return append([]Snapshot(nil), s.history...)
+}
+
+// Previous returns the most recent Snapshot recorded by Rotate and true or
+// an empty Snapshot and false if Rotate has not been called
+func (s Stat) Previous() (Snapshot, bool) {
+	if len(s.history) == 0 {
+		return Snapshot{}, false
+	}
+	return s.history[len(s.history)-1], true
+}
+
+// ClearHistory discards the Snapshots recorded by Rotate. Note that Reset
+// does not discard them.
+func (s *Stat) ClearHistory() {
+	clear(s.history)
+	s.history = s.history[:0]
+}
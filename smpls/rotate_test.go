@@ -0,0 +1,82 @@
+package smpls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRotate(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistoryLen(3))
+	if _, ok := s.Previous(); ok {
+		t.Error("a new Stat should have no previous Snapshot")
+	}
+
+	for i := range 5 {
+		addSeq(s, float64(i*10), 1, i+1)
+		snap := s.Rotate()
+		id := testhelper.MkID("rotation").IDStr()
+		testhelper.DiffInt(t, id, "rotated count", snap.Count, i+1)
+		testhelper.DiffInt(t, id, "count after Rotate", s.Count(), 0)
+	}
+
+	history := s.History()
+	testhelper.DiffInt(t, "history", "length", len(history), 3)
+	for i, snap := range history {
+		testhelper.DiffInt(t, "history", "count", snap.Count, i+3)
+		testhelper.DiffFloat(t, "history", "min", snap.Min, float64(i+2)*10, 0)
+	}
+
+	prev, ok := s.Previous()
+	testhelper.DiffBool(t, "Previous", "ok", ok, true)
+	testhelper.DiffInt(t, "Previous", "count", prev.Count, 5)
+
+	s.Add(1)
+	s.Reset()
+	testhelper.DiffInt(t, "after Reset", "history length",
+		len(s.History()), 3)
+
+	clone := s.Clone()
+	s.ClearHistory()
+	testhelper.DiffInt(t, "after ClearHistory", "history length",
+		len(s.History()), 0)
+	testhelper.DiffInt(t, "clone", "history length", len(clone.History()), 3)
+}
+
+func TestRotateDefaultLen(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	for range dfltHistoryLen + 5 {
+		s.Add(1)
+		s.Rotate()
+	}
+	testhelper.DiffInt(t, "default", "history length",
+		len(s.History()), dfltHistoryLen)
+}
+
+func TestRotateSaveLoad(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistoryLen(2), StatCacheSize(10))
+	addSeq(s, 1, 1, 50)
+	s.Rotate()
+	addSeq(s, 2, 1, 5)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal("Couldn't load the Stat:", err)
+	}
+	if err := testhelper.DiffVals(loaded.History(), s.History()); err != nil {
+		t.Errorf("the loaded history differs: %v", err)
+	}
+	testhelper.DiffInt(t, "loaded", "history length",
+		loaded.historyCap(), 2)
+}
+
+func TestStatHistoryLen(t *testing.T) {
+	_, err := NewStat("ms", StatHistoryLen(0))
+	testhelper.CheckExpErrWithID(t, "zero length", err,
+		testhelper.MkExpErr("Invalid history length (0) - it must be >= 1"))
+}
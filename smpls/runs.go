@@ -0,0 +1,200 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+// runTracker records the order in which values are added: the first and
+// last values and the lengths of runs of increasing values and of values
+// above a threshold. The leading runs (those starting with the first
+// value) are kept so that two runTrackers can be joined end to end.
+type runTracker struct {
+	threshold float64
+
+	n     int
+	first float64
+	last  float64
+
+	incLead    int
+	incCur     int
+	incLongest int
+
+	aboveLead    int
+	aboveCur     int
+	aboveLongest int
+}
+
+// add records the next value
+func (rt *runTracker) add(v float64) {
+	rt.n++
+	if rt.n == 1 {
+		rt.first = v
+	}
+
+	if rt.n == 1 || v > rt.last {
+		rt.incCur++
+	} else {
+		rt.incCur = 1
+	}
+	if rt.incCur == rt.n {
+		rt.incLead = rt.n
+	}
+	rt.incLongest = max(rt.incLongest, rt.incCur)
+
+	if v > rt.threshold {
+		rt.aboveCur++
+	} else {
+		rt.aboveCur = 0
+	}
+	if rt.aboveCur == rt.n {
+		rt.aboveLead = rt.n
+	}
+	rt.aboveLongest = max(rt.aboveLongest, rt.aboveCur)
+
+	rt.last = v
+}
+
+// reset discards all the values
+func (rt *runTracker) reset() {
+	*rt = runTracker{threshold: rt.threshold}
+}
+
+// merge records the values from the other runTracker as if they had been
+// added after the values already recorded
+func (rt *runTracker) merge(other *runTracker) {
+	if other.n == 0 {
+		return
+	}
+	if rt.n == 0 {
+		*rt = *other
+		return
+	}
+
+	if other.first > rt.last {
+		rt.incLongest = max(rt.incLongest, rt.incCur+other.incLead)
+		if rt.incLead == rt.n {
+			rt.incLead += other.incLead
+		}
+		if other.incCur == other.n {
+			rt.incCur += other.n
+		} else {
+			rt.incCur = other.incCur
+		}
+	} else {
+		rt.incCur = other.incCur
+	}
+	rt.incLongest = max(rt.incLongest, other.incLongest)
+
+	rt.aboveLongest = max(rt.aboveLongest, other.aboveLongest,
+		rt.aboveCur+other.aboveLead)
+	if rt.aboveLead == rt.n {
+		rt.aboveLead += other.aboveLead
+	}
+	if other.aboveCur == other.n {
+		rt.aboveCur += other.n
+	} else {
+		rt.aboveCur = other.aboveCur
+	}
+
+	rt.n += other.n
+	rt.last = other.last
+}
+
+// check returns an error if the runTracker is not consistent
+func (rt runTracker) check() error {
+	if rt.n < 0 ||
+		rt.incLead < 0 || rt.incLead > rt.incLongest ||
+		rt.incCur < 0 || rt.incCur > rt.incLongest || rt.incLongest > rt.n ||
+		rt.aboveLead < 0 || rt.aboveLead > rt.aboveLongest ||
+		rt.aboveCur < 0 || rt.aboveCur > rt.aboveLongest ||
+		rt.aboveLongest > rt.n {
+		return fmt.Errorf("bad run lengths for %d values", rt.n)
+	}
+	return nil
+}
+
+// StatTrackRuns returns a function that will make the Stat record the
+// order in which values are added. The first and last values are kept
+// along with the longest run of increasing values and the longest run of
+// values above the threshold. This shows drift or a sustained degradation
+// during a run, which the mean, standard deviation and histogram, being
+// unaffected by the order of the values, cannot.
+func StatTrackRuns(threshold float64) StatOpt {
+	return func(s *Stat) error {
+		if s.runs != nil {
+			return repeatedOption("the runs are already being tracked")
+		}
+		if math.IsNaN(threshold) {
+			return invalidValue("Invalid run threshold (NaN)")
+		}
+
+		s.runs = &runTracker{threshold: threshold}
+		return nil
+	}
+}
+
+// TracksRuns returns true if the Stat is recording the order in which
+// values are added (see StatTrackRuns)
+func (s Stat) TracksRuns() bool {
+	return s.runs != nil
+}
+
+// First returns the first value added or 0.0 if no values have been added
+// or the Stat is not tracking runs (see StatTrackRuns)
+func (s Stat) First() float64 {
+	if s.runs == nil {
+		return 0.0
+	}
+	return s.runs.first
+}
+
+// Last returns the most recent value added or 0.0 if no values have been
+// added or the Stat is not tracking runs (see StatTrackRuns)
+func (s Stat) Last() float64 {
+	if s.runs == nil {
+		return 0.0
+	}
+	return s.runs.last
+}
+
+// RunThreshold returns the threshold used by LongestAboveThreshold or NaN
+// if the Stat is not tracking runs (see StatTrackRuns)
+func (s Stat) RunThreshold() float64 {
+	if s.runs == nil {
+		return math.NaN()
+	}
+	return s.runs.threshold
+}
+
+// LongestIncreasingRun returns the length of the longest run of
+// consecutive values each larger than the one before. A single value is a
+// run of length 1. It returns 0 if no values have been added or the Stat
+// is not tracking runs (see StatTrackRuns).
+func (s Stat) LongestIncreasingRun() int {
+	if s.runs == nil {
+		return 0
+	}
+	return s.runs.incLongest
+}
+
+// LongestAboveThreshold returns the length of the longest run of
+// consecutive values above the threshold (see StatTrackRuns). It returns
+// 0 if no values have been added or the Stat is not tracking runs.
+func (s Stat) LongestAboveThreshold() int {
+	if s.runs == nil {
+		return 0
+	}
+	return s.runs.aboveLongest
+}
+
+// CurrentAboveThreshold returns the length of the run of values above the
+// threshold which ends with the most recent value; it is 0 if the most
+// recent value is not above the threshold or the Stat is not tracking
+// runs (see StatTrackRuns).
+func (s Stat) CurrentAboveThreshold() int {
+	if s.runs == nil {
+		return 0
+	}
+	return s.runs.aboveCur
+}
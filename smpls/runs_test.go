@@ -0,0 +1,176 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// bruteRuns returns the longest run of increasing values and the longest
+// run of values above the threshold
+func bruteRuns(vals []float64, threshold float64) (inc, above int) {
+	for i := range vals {
+		j := i + 1
+		for j < len(vals) && vals[j] > vals[j-1] {
+			j++
+		}
+		inc = max(inc, j-i)
+
+		j = i
+		for j < len(vals) && vals[j] > threshold {
+			j++
+		}
+		above = max(above, j-i)
+	}
+	return inc, above
+}
+
+func TestRuns(t *testing.T) {
+	vals := []float64{5, 1, 2, 3, 2, 12, 13, 14, 11, 4, 15, 16, 17, 18, 3}
+	const threshold = 10
+
+	testCases := []struct {
+		testhelper.ID
+		vals     []float64
+		expFirst float64
+		expLast  float64
+		expInc   int
+		expAbove int
+		expCur   int
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:       testhelper.MkID("one value"),
+			vals:     []float64{11},
+			expFirst: 11,
+			expLast:  11,
+			expInc:   1,
+			expAbove: 1,
+			expCur:   1,
+		},
+		{
+			ID:       testhelper.MkID("decreasing"),
+			vals:     []float64{3, 2, 1},
+			expFirst: 3,
+			expLast:  1,
+			expInc:   1,
+		},
+		{
+			ID:       testhelper.MkID("mixed"),
+			vals:     vals,
+			expFirst: 5,
+			expLast:  3,
+			expInc:   5,
+			expAbove: 4,
+		},
+		{
+			ID:       testhelper.MkID("ends above"),
+			vals:     vals[:13],
+			expFirst: 5,
+			expLast:  17,
+			expInc:   4,
+			expAbove: 4,
+			expCur:   3,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms", StatTrackRuns(threshold))
+		s.AddSlice(tc.vals)
+		id := tc.IDStr()
+		testhelper.DiffFloat(t, id, "First", s.First(), tc.expFirst, 0)
+		testhelper.DiffFloat(t, id, "Last", s.Last(), tc.expLast, 0)
+		testhelper.DiffInt(t, id, "LongestIncreasingRun",
+			s.LongestIncreasingRun(), tc.expInc)
+		testhelper.DiffInt(t, id, "LongestAboveThreshold",
+			s.LongestAboveThreshold(), tc.expAbove)
+		testhelper.DiffInt(t, id, "CurrentAboveThreshold",
+			s.CurrentAboveThreshold(), tc.expCur)
+		testhelper.DiffFloat(t, id, "RunThreshold",
+			s.RunThreshold(), threshold, 0)
+
+		s.Reset()
+		testhelper.DiffInt(t, id, "LongestIncreasingRun after Reset",
+			s.LongestIncreasingRun(), 0)
+	}
+}
+
+func TestRunsMerge(t *testing.T) {
+	vals := []float64{
+		11, 12, 13, 1, 2, 3, 4, 15, 16, 17, 18, 19, 5, 11, 12, 2, 20,
+	}
+	const threshold = 10
+	expInc, expAbove := bruteRuns(vals, threshold)
+
+	for i := range len(vals) + 1 {
+		for j := i; j <= len(vals); j++ {
+			a := NewStatOrPanic("ms", StatTrackRuns(threshold))
+			b := NewStatOrPanic("ms", StatTrackRuns(threshold))
+			c := NewStatOrPanic("ms", StatTrackRuns(threshold))
+			a.AddSlice(vals[:i])
+			b.AddSlice(vals[i:j])
+			c.AddSlice(vals[j:])
+
+			if err := a.Merge(b); err != nil {
+				t.Fatal("unexpected error merging:", err)
+			}
+			if err := a.Merge(c); err != nil {
+				t.Fatal("unexpected error merging:", err)
+			}
+
+			if a.LongestIncreasingRun() != expInc ||
+				a.LongestAboveThreshold() != expAbove ||
+				a.First() != vals[0] || a.Last() != vals[len(vals)-1] {
+				t.Errorf("split at %d and %d: bad runs: %d, %d",
+					i, j, a.LongestIncreasingRun(),
+					a.LongestAboveThreshold())
+			}
+		}
+	}
+
+	a := NewStatOrPanic("ms", StatTrackRuns(1))
+	b := NewStatOrPanic("ms", StatTrackRuns(2))
+	err := a.Merge(b)
+	testhelper.CheckExpErrWithID(t, "different thresholds", err,
+		testhelper.MkExpErr("cannot merge Stats with different run thresholds"))
+}
+
+func TestStatTrackRuns(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("good"),
+			opts: []StatOpt{StatTrackRuns(0)},
+		},
+		{
+			ID:     testhelper.MkID("twice"),
+			opts:   []StatOpt{StatTrackRuns(0), StatTrackRuns(1)},
+			ExpErr: testhelper.MkExpErr("the runs are already being tracked"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("ms", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+
+	s := NewStatOrPanic("ms")
+	s.Add(1, 2, 3)
+	testhelper.DiffBool(t, "not tracked", "TracksRuns", s.TracksRuns(), false)
+	testhelper.DiffInt(t, "not tracked", "LongestIncreasingRun",
+		s.LongestIncreasingRun(), 0)
+
+	parent := NewStatOrPanic("ms", StatTrackRuns(0))
+	parent.Add(1, 2)
+	child := parent.Fork()
+	child.Add(3, 4, 5)
+	testhelper.DiffInt(t, "forked", "child LongestIncreasingRun",
+		child.LongestIncreasingRun(), 3)
+	testhelper.DiffInt(t, "forked", "parent LongestIncreasingRun",
+		parent.LongestIncreasingRun(), 5)
+}
@@ -0,0 +1,119 @@
+package smpls
+
+import (
+	"math"
+)
+
+// sampleRoundingAllowance is added when calculating the number of values
+// due to have been recorded so that rounding errors in the product of the
+// sample rate and the number of values added do not delay a value
+const sampleRoundingAllowance = 1e-9
+
+// StatSampleRate returns a function that will make the Stat record only a
+// fraction of the values added, given by the rate. For instance, a rate of
+// 0.01 will record one value in every hundred. The values recorded are
+// chosen deterministically, spread evenly through the values added. This
+// reduces the cost of adding values on very heavily used code paths at the
+// cost of accuracy.
+//
+// The Count method (and the count given by the Vals and Snapshot methods)
+// reports the number of values added, not the number recorded, and the sum
+// (and sum of squares) are scaled to match. The mean, standard deviation,
+// percentiles and so on are calculated from the recorded values alone, as
+// are the histogram counts. The SampledCount method reports the number of
+// values recorded.
+func StatSampleRate(rate float64) StatOpt {
+	return func(s *Stat) error {
+		if !(rate > 0 && rate <= 1) {
+			return invalidValue(
+				"Invalid sample rate (%g) - it must be > 0 and <= 1", rate)
+		}
+		if rate < 1 {
+			s.sampleRate = rate
+		}
+		return nil
+	}
+}
+
+// skipSample records that a value has been added and returns true if it
+// should not be recorded. It should only be called if the Stat has a sample
+// rate.
+func (s *Stat) skipSample() bool {
+	s.calls++
+	due := int(float64(s.calls)*s.sampleRate + sampleRoundingAllowance)
+	if due <= s.sampled {
+		return true
+	}
+	s.sampled++
+	return false
+}
+
+// countForwardedSkip records that a value added to a child Stat (see Fork)
+// was not recorded by its sampling so that the Stat's count of the values
+// added includes it
+func (s *Stat) countForwardedSkip() {
+	s.adds++
+	if s.sampleRate > 0 {
+		s.calls++
+	}
+}
+
+// sampleScale returns the factor by which the count and sum of the
+// recorded values should be scaled to give the count and sum of all the
+// values added
+func (s Stat) sampleScale() float64 {
+	if s.sampleRate == 0 || s.count == 0 {
+		return 1
+	}
+	return float64(s.calls) / float64(s.count)
+}
+
+// unsample changes the Stat, which may have a sample rate, into one without
+// a sample rate whose recorded values stand for all the values added. The
+// histogram is laid out, if it was not already, and its counts, like the
+// count and the sums, are scaled by the sampling factor. The counts are
+// rounded so that they still add up to the count. This is used when a
+// sampled Stat is merged into one which is not sampled.
+func (s *Stat) unsample() {
+	if s.sampleRate == 0 {
+		return
+	}
+	scale := s.sampleScale()
+	s.sampleRate = 0
+
+	if pending := s.histPending(); pending != nil && s.count > 0 {
+		s.layoutHist(pending)
+		s.cache = nil
+	}
+
+	prev, cum := 0, 0
+	scaleCount := func(n int) int {
+		cum += n
+		next := int(math.Round(float64(cum) * scale))
+		n, prev = next-prev, next
+		return n
+	}
+	s.underflow = scaleCount(s.underflow)
+	for i := range s.hist {
+		s.hist[i] = scaleCount(s.hist[i])
+	}
+	s.overflow = scaleCount(s.overflow)
+	for i := range s.histSums {
+		s.histSums[i] *= scale
+	}
+	s.underflowSum *= scale
+	s.overflowSum *= scale
+
+	s.sum *= scale
+	s.sumSq *= scale
+	s.sumC *= scale
+	s.sumSqC *= scale
+	s.count = s.calls
+	s.calls, s.sampled = 0, 0
+}
+
+// SampledCount returns the number of values recorded. This is the same as
+// the Count unless the Stat has a sample rate (see StatSampleRate).
+func (s Stat) SampledCount() int {
+	return s.count
+}
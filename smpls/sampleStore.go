@@ -0,0 +1,321 @@
+package smpls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// errNoMmap is returned by mapFile if files cannot be memory-mapped
+var errNoMmap = errors.New("files cannot be memory-mapped")
+
+// storeBuckets is the number of buckets used to narrow down the values
+// holding a percentile (see StorePercentile)
+const storeBuckets = 4096
+
+// SampleStore holds a sequence of values. It lets a Stat keep every value
+// added to it (see StatSampleStore) somewhere other than in memory so that
+// runs with very many values can still have exact percentiles calculated.
+type SampleStore interface {
+	// Append adds the value to the end of the store
+	Append(v float64) error
+	// Len returns the number of values in the store
+	Len() int
+	// Iterate calls the function with each value in the store, in the
+	// order they were appended, until it returns false
+	Iterate(f func(v float64) bool) error
+	// Reset discards all the values in the store
+	Reset() error
+}
+
+// MemStore is a SampleStore holding the values in memory
+type MemStore struct {
+	vals []float64
+}
+
+// NewMemStore creates a new MemStore with room for the given number of
+// values before it needs to grow
+func NewMemStore(capacity int) *MemStore {
+	return &MemStore{vals: make([]float64, 0, max(capacity, 0))}
+}
+
+// Append adds the value to the end of the store. It never returns an error.
+func (ms *MemStore) Append(v float64) error {
+	ms.vals = append(ms.vals, v)
+	return nil
+}
+
+// Len returns the number of values in the store
+func (ms *MemStore) Len() int {
+	return len(ms.vals)
+}
+
+// Iterate calls the function with each value in the store until it returns
+// false. It never returns an error.
+func (ms *MemStore) Iterate(f func(v float64) bool) error {
+	for _, v := range ms.vals {
+		if !f(v) {
+			break
+		}
+	}
+	return nil
+}
+
+// Reset discards all the values in the store. It never returns an error.
+func (ms *MemStore) Reset() error {
+	ms.vals = ms.vals[:0]
+	return nil
+}
+
+// FileStore is a SampleStore holding the values in a file, each as 8 bytes
+// holding the IEEE 754 bits in little-endian order. The values are
+// buffered as they are appended and, where the operating system allows,
+// the file is memory-mapped to iterate over them so that they need not fit
+// in memory.
+//
+// The file should be closed with Close once the store is no longer needed.
+type FileStore struct {
+	f   *os.File
+	w   *bufio.Writer
+	n   int
+	buf [8]byte
+}
+
+// NewFileStore creates a new FileStore writing to the named file. The file
+// is created if it does not exist and is truncated if it does.
+func NewFileStore(name string) (*FileStore, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the sample store: %w", err)
+	}
+	return &FileStore{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Append adds the value to the end of the store
+func (fs *FileStore) Append(v float64) error {
+	binary.LittleEndian.PutUint64(fs.buf[:], math.Float64bits(v))
+	if _, err := fs.w.Write(fs.buf[:]); err != nil {
+		return err
+	}
+	fs.n++
+	return nil
+}
+
+// Len returns the number of values in the store
+func (fs *FileStore) Len() int {
+	return fs.n
+}
+
+// Iterate calls the function with each value in the store until it returns
+// false. Any buffered values are first written to the file.
+func (fs *FileStore) Iterate(f func(v float64) bool) error {
+	if err := fs.w.Flush(); err != nil {
+		return err
+	}
+	if fs.n == 0 {
+		return nil
+	}
+
+	size := fs.n * 8
+	b, unmap, err := mapFile(fs.f, size)
+	if errors.Is(err, errNoMmap) {
+		return fs.readEach(f)
+	}
+	if err != nil {
+		return err
+	}
+	defer unmap()
+
+	for i := 0; i < size; i += 8 {
+		if !f(math.Float64frombits(binary.LittleEndian.Uint64(b[i:]))) {
+			break
+		}
+	}
+	return nil
+}
+
+// readEach reads the values from the file, calling the function with each
+// until it returns false. It is used where the file cannot be mapped.
+func (fs *FileStore) readEach(f func(v float64) bool) error {
+	r := bufio.NewReader(io.NewSectionReader(fs.f, 0, int64(fs.n)*8))
+	var buf [8]byte
+	for range fs.n {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		if !f(math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))) {
+			break
+		}
+	}
+	return nil
+}
+
+// Reset discards all the values in the store, truncating the file
+func (fs *FileStore) Reset() error {
+	fs.w.Reset(fs.f)
+	fs.n = 0
+	if err := fs.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := fs.f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close writes any buffered values to the file and closes it
+func (fs *FileStore) Close() error {
+	return errors.Join(fs.w.Flush(), fs.f.Close())
+}
+
+// StorePercentile returns the value below which the given percentage of
+// the values in the store fall; p should be in the range [0, 100] and is
+// forced into that range if not. The value is exact, interpolated between
+// the values either side as Percentile does, but the values are never all
+// held in memory at once: the store is read three times, first to find the
+// range of the values, then to count them into buckets and finally to
+// gather just the values in the buckets holding the percentile. It returns
+// an error if p is NaN or if the store is empty or cannot be read.
+func StorePercentile(store SampleStore, p float64) (float64, error) {
+	if math.IsNaN(p) {
+		return 0.0, invalidValue("Invalid percentile (NaN)")
+	}
+	n := store.Len()
+	if n == 0 {
+		return 0.0, errors.New("the sample store is empty")
+	}
+	p = clampPct(p)
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	err := store.Iterate(func(v float64) bool {
+		lo, hi = min(lo, v), max(hi, v)
+		return true
+	})
+	if err != nil {
+		return 0.0, err
+	}
+	if lo == hi {
+		return lo, nil
+	}
+
+	width := (hi - lo) / storeBuckets
+	bucket := func(v float64) int {
+		return min(int((v-lo)/width), storeBuckets-1)
+	}
+	counts := make([]int, storeBuckets)
+	if err := store.Iterate(func(v float64) bool {
+		counts[bucket(v)]++
+		return true
+	}); err != nil {
+		return 0.0, err
+	}
+
+	// the percentile lies between the values with these ranks
+	rank := p / 100.0 * float64(n-1)
+	rankLo := int(math.Floor(rank))
+	rankHi := min(rankLo+1, n-1)
+
+	below, first, last := 0, -1, -1
+	cum := 0
+	for i, c := range counts {
+		if first < 0 && cum+c > rankLo {
+			first, below = i, cum
+		}
+		if cum+c > rankHi {
+			last = i
+			break
+		}
+		cum += c
+	}
+
+	var vals []float64
+	if err := store.Iterate(func(v float64) bool {
+		if b := bucket(v); b >= first && b <= last {
+			vals = append(vals, v)
+		}
+		return true
+	}); err != nil {
+		return 0.0, err
+	}
+	slices.Sort(vals)
+
+	vLo, vHi := vals[rankLo-below], vals[rankHi-below]
+	return vLo + (rank-float64(rankLo))*(vHi-vLo), nil
+}
+
+// storeWriter appends the values recorded by a Stat to a SampleStore
+type storeWriter struct {
+	store SampleStore
+	err   error
+}
+
+// add appends the value to the store unless an error has been seen
+func (sw *storeWriter) add(v float64) {
+	if sw.err == nil {
+		sw.err = sw.store.Append(v)
+	}
+}
+
+// StatSampleStore returns a function that will make the Stat append every
+// value it records to the given SampleStore. This lets exact percentiles
+// be found (see StoredPercentile) however many values are added; use a
+// FileStore to keep them out of memory. Appending stops at the first error,
+// which is reported by StoreErr. The store is emptied when the Stat is
+// Reset. Values are not appended by copies of the Stat (see Clone) nor by
+// a Stat recreated by Load and the values from a merged Stat are not
+// appended.
+func StatSampleStore(store SampleStore) StatOpt {
+	return func(s *Stat) error {
+		if s.store != nil {
+			return repeatedOption("the sample store has already been set")
+		}
+		if store == nil {
+			return invalidValue("the sample store must not be nil")
+		}
+
+		s.store = &storeWriter{store: store}
+		return nil
+	}
+}
+
+// SampleStore returns the store to which the Stat appends its values (see
+// StatSampleStore) or nil if it has none
+func (s Stat) SampleStore() SampleStore {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.store
+}
+
+// StoreErr returns the first error seen while appending values to, or
+// resetting, the sample store (see StatSampleStore). It returns nil if the
+// Stat has no sample store.
+func (s Stat) StoreErr() error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.err
+}
+
+// StoredPercentile returns the exact value of the given percentile of the
+// values in the Stat's sample store (see StatSampleStore and
+// StorePercentile). It returns an error if the Stat has no sample store, if
+// appending to it has failed or if the store does not hold every value
+// recorded by the Stat, as happens after a Merge.
+func (s Stat) StoredPercentile(p float64) (float64, error) {
+	if s.store == nil {
+		return 0.0, errors.New("the Stat has no sample store")
+	}
+	if s.store.err != nil {
+		return 0.0, fmt.Errorf("the sample store is incomplete: %w",
+			s.store.err)
+	}
+	if n := s.store.store.Len(); n != s.count {
+		return 0.0, fmt.Errorf(
+			"the sample store holds %d values but the Stat has %d", n, s.count)
+	}
+	return StorePercentile(s.store.store, p)
+}
@@ -0,0 +1,156 @@
+package smpls
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// storeVals returns the values in the store
+func storeVals(t *testing.T, store SampleStore) []float64 {
+	t.Helper()
+	var vals []float64
+	err := store.Iterate(func(v float64) bool {
+		vals = append(vals, v)
+		return true
+	})
+	if err != nil {
+		t.Fatal("Couldn't iterate over the store:", err)
+	}
+	return vals
+}
+
+func TestSampleStores(t *testing.T) {
+	fs, err := NewFileStore(filepath.Join(t.TempDir(), "samples"))
+	if err != nil {
+		t.Fatal("Couldn't create the FileStore:", err)
+	}
+	defer fs.Close()
+
+	testCases := []struct {
+		testhelper.ID
+		store SampleStore
+	}{
+		{ID: testhelper.MkID("memory"), store: NewMemStore(10)},
+		{ID: testhelper.MkID("file"), store: fs},
+	}
+
+	for _, tc := range testCases {
+		exp := []float64{3, -1.5, math.Inf(1), 0, 7.25}
+		for _, v := range exp {
+			if err := tc.store.Append(v); err != nil {
+				t.Fatal(tc.IDStr(), ": Couldn't append:", err)
+			}
+		}
+		testhelper.DiffInt(t, tc.IDStr(), "Len", tc.store.Len(), len(exp))
+		if err := testhelper.DiffVals(storeVals(t, tc.store), exp); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad values: %v\n", err)
+		}
+
+		stopped := 0
+		_ = tc.store.Iterate(func(float64) bool {
+			stopped++
+			return stopped < 2
+		})
+		testhelper.DiffInt(t, tc.IDStr(), "stopped iteration", stopped, 2)
+
+		if err := tc.store.Reset(); err != nil {
+			t.Fatal(tc.IDStr(), ": Couldn't reset:", err)
+		}
+		testhelper.DiffInt(t, tc.IDStr(), "Len after Reset", tc.store.Len(), 0)
+		_ = tc.store.Append(42)
+		if err := testhelper.DiffVals(storeVals(t, tc.store),
+			[]float64{42}); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad values after Reset: %v\n", err)
+		}
+	}
+}
+
+func TestStorePercentile(t *testing.T) {
+	store := NewMemStore(0)
+	if _, err := StorePercentile(store, 50); err == nil {
+		t.Error("expected an error from an empty store")
+	}
+
+	vals := []float64{}
+	for i := range 1001 {
+		v := float64((i * 7919) % 1001)
+		vals = append(vals, v*v)
+		_ = store.Append(v * v)
+	}
+	s := NewStatOrPanic("units", StatCacheSize(len(vals)+1))
+	s.AddVals(vals...)
+
+	for _, p := range []float64{0, 1, 25, 33.3, 50, 99.9, 100} {
+		id := "percentile"
+		got, err := StorePercentile(store, p)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		testhelper.DiffFloat(t, id, "value", got, s.Percentile(p), 1e-9)
+	}
+
+	same := NewMemStore(3)
+	_ = same.Append(5)
+	_ = same.Append(5)
+	got, _ := StorePercentile(same, 90)
+	testhelper.DiffFloat(t, "all equal", "value", got, 5, 0)
+
+	_, err := StorePercentile(same, math.NaN())
+	testhelper.CheckExpErrWithID(t, "NaN", err,
+		testhelper.MkExpErr("Invalid percentile (NaN)"))
+}
+
+func TestStatSampleStore(t *testing.T) {
+	fs, err := NewFileStore(filepath.Join(t.TempDir(), "samples"))
+	if err != nil {
+		t.Fatal("Couldn't create the FileStore:", err)
+	}
+	defer fs.Close()
+
+	s := NewStatOrPanic("units", StatNoCache(), StatSampleStore(fs))
+	addSeq(s, 1, 1, 100)
+	testhelper.DiffInt(t, "store", "Len", s.SampleStore().Len(), 100)
+
+	got, err := s.StoredPercentile(50)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testhelper.DiffFloat(t, "store", "median", got, 50.5, 0)
+
+	c := s.Clone()
+	if c.SampleStore() != nil {
+		t.Error("a clone should have no sample store")
+	}
+
+	other := NewStatOrPanic("units", StatNoCache())
+	other.Add(1)
+	_ = s.Merge(other)
+	_, err = s.StoredPercentile(50)
+	testhelper.CheckExpErrWithID(t, "after merge", err,
+		testhelper.MkExpErr("the sample store holds 100 values",
+			"the Stat has 101"))
+
+	s.Reset()
+	testhelper.DiffInt(t, "after Reset", "Len", fs.Len(), 0)
+	if err := s.StoreErr(); err != nil {
+		t.Error("unexpected store error:", err)
+	}
+
+	_, err = NewStatOrPanic("units").StoredPercentile(50)
+	testhelper.CheckExpErrWithID(t, "no store", err,
+		testhelper.MkExpErr("the Stat has no sample store"))
+
+	_, err = NewStat("units", StatSampleStore(nil))
+	testhelper.CheckExpErrWithID(t, "nil store", err,
+		testhelper.MkExpErr("the sample store must not be nil"))
+
+	_, err = NewStat("units",
+		StatSampleStore(NewMemStore(0)), StatSampleStore(NewMemStore(0)))
+	testhelper.CheckExpErrWithID(t, "store set twice", err,
+		testhelper.MkExpErr("the sample store has already been set"))
+}
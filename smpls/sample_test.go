@@ -0,0 +1,80 @@
+package smpls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatSampleRate(t *testing.T) {
+	s := NewStatOrPanic("ms", StatSampleRate(0.1), StatCacheSize(50))
+	addSeq(s, 1, 1, 1000)
+
+	testhelper.DiffInt(t, "sampled", "count", s.Count(), 1000)
+	testhelper.DiffInt(t, "sampled", "sampled count", s.SampledCount(), 100)
+	testhelper.DiffFloat(t, "sampled", "min", s.Min(), 10, 0.0)
+	testhelper.DiffFloat(t, "sampled", "mean", s.Mean(), 505, 0.0)
+	testhelper.DiffFloat(t, "sampled", "sum", s.Sum(), 505000, 1e-6)
+	testhelper.DiffInt(t, "sampled", "histogram total", histTotal(s), 100)
+
+	snap := s.Snapshot()
+	testhelper.DiffInt(t, "sampled", "snapshot count", snap.Count, 1000)
+	testhelper.DiffFloat(t, "sampled", "snapshot sum", snap.Sum, 505000, 1e-6)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal("Couldn't load the Stat:", err)
+	}
+	addSeq(s, 1, 1, 25)
+	addSeq(&loaded, 1, 1, 25)
+	testhelper.DiffInt(t, "loaded", "count", loaded.Count(), s.Count())
+	testhelper.DiffInt(t, "loaded", "sampled count",
+		loaded.SampledCount(), s.SampledCount())
+
+	s.Reset()
+	testhelper.DiffInt(t, "Reset", "count", s.Count(), 0)
+	s.Add(1)
+	testhelper.DiffInt(t, "Reset", "sampled count after Add",
+		s.SampledCount(), 0)
+
+	all := NewStatOrPanic("ms", StatSampleRate(1))
+	all.Add(1, 2, 3)
+	testhelper.DiffInt(t, "rate 1", "sampled count", all.SampledCount(), 3)
+
+	for _, rate := range []float64{0, -0.5, 1.5} {
+		_, err := NewStat("ms", StatSampleRate(rate))
+		testhelper.CheckExpErrWithID(t, "bad rate", err,
+			testhelper.MkExpErr("Invalid sample rate"))
+	}
+}
+
+func TestForkSampled(t *testing.T) {
+	s := NewStatOrPanic("ms", StatSampleRate(0.1), StatCacheSize(50))
+	child := s.Fork()
+	addSeq(child, 1, 1, 1000)
+
+	testhelper.DiffInt(t, "child", "count", child.Count(), 1000)
+	testhelper.DiffInt(t, "child", "sampled count", child.SampledCount(), 100)
+	testhelper.DiffInt(t, "parent", "count", s.Count(), 1000)
+	testhelper.DiffInt(t, "parent", "sampled count", s.SampledCount(), 100)
+	testhelper.DiffFloat(t, "parent", "sum", s.Sum(), child.Sum(), 1e-6)
+
+	addSeq(s, 1, 1, 1000)
+	testhelper.DiffInt(t, "parent, added to", "count", s.Count(), 2000)
+	testhelper.DiffInt(t, "parent, added to", "sampled count",
+		s.SampledCount(), 200)
+
+	grandchild := child.Fork()
+	addSeq(grandchild, 1, 1, 100)
+	testhelper.DiffInt(t, "grandchild", "sampled count",
+		grandchild.SampledCount(), 10)
+	testhelper.DiffInt(t, "grandchild: child", "count", child.Count(), 1100)
+	testhelper.DiffInt(t, "grandchild: parent", "count", s.Count(), 2100)
+	testhelper.DiffInt(t, "grandchild: parent", "sampled count",
+		s.SampledCount(), 210)
+}
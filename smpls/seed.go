@@ -0,0 +1,248 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// StatFromSummary returns a function that will start the Stat off as if
+// values with the given count, sum, sum of squares, minimum and maximum had
+// already been added to it. This lets you carry on accumulating statistics
+// from a summary stored elsewhere, such as in a database.
+//
+// Since the individual values are not known, the summary cannot be used to
+// populate a histogram and so the Stat must not have one (see StatNoHist);
+// use StatFromSnapshot to restore the histogram too. The smallest and
+// largest values other than the minimum and maximum are not known either
+// and so MeanMin and MeanMax start off equal to the minimum and maximum.
+// The summary cannot be used with a sampled Stat (see StatSampleRate).
+func StatFromSummary(count int, sum, sumSq, minVal, maxVal float64) StatOpt {
+	return StatFromSnapshot(Snapshot{
+		Count:   count,
+		Sum:     sum,
+		SumSq:   sumSq,
+		Min:     minVal,
+		MeanMin: minVal,
+		Max:     maxVal,
+		MeanMax: maxVal,
+	})
+}
+
+// StatFromSnapshot returns a function that will start the Stat off as if
+// the values recorded in the Snapshot had already been added to it. The
+// count, sum, sum of squares and the minimum and maximum values are taken
+// from the Snapshot. The MeanMin and MeanMax values are kept as near as
+// possible to those in the Snapshot though they will only match if the
+// Stat has the same number of minimum and maximum values as the Stat the
+// Snapshot was taken from.
+//
+// If the Stat has a histogram then the Snapshot must have one too and the
+// histogram buckets are copied from it; the histogram will then keep the
+// same bucket boundaries. If the buckets are not evenly spaced, as when
+// the Snapshot is taken from a Stat with a quantile layout, the Stat must
+// have a quantile layout too (see StatHistQuantileLayout). The percentiles
+// recorded in the Snapshot are not used and the Snapshot cannot be used
+// with a sampled Stat (see StatSampleRate). Any metadata in the Snapshot
+// is recorded with the Stat (see SetMeta).
+func StatFromSnapshot(snap Snapshot) StatOpt {
+	return func(s *Stat) error {
+		if s.seed != nil {
+			return repeatedOption(
+				"the Stat has already been given a starting summary")
+		}
+		if snap.Count < 0 {
+			return invalidValue("Invalid summary count (%d) - it must be >= 0",
+				snap.Count)
+		}
+		if snap.Count > 0 && !(snap.Min <= snap.Max) {
+			return invalidValue("Invalid summary: min (%g) is not <= max (%g)",
+				snap.Min, snap.Max)
+		}
+
+		s.seed = &snap
+		return nil
+	}
+}
+
+// applySeed starts the Stat off with the values from the seed Snapshot
+// given by StatFromSummary or StatFromSnapshot. It is called once all the
+// options have been applied and the slices allocated.
+func (s *Stat) applySeed() error {
+	snap := *s.seed
+	s.seed = nil
+
+	for k, v := range snap.Meta {
+		s.SetMeta(k, v)
+	}
+	if snap.Count == 0 {
+		return nil
+	}
+	if s.sampleRate > 0 {
+		return conflictingOptions("a sampled Stat cannot start from a summary")
+	}
+	if !s.noHist {
+		if err := s.seedHist(snap); err != nil {
+			return err
+		}
+	}
+
+	s.count = snap.Count
+	s.sum = snap.Sum
+	s.sumSq = snap.SumSq
+	s.seedMinsMaxs(snap)
+	return nil
+}
+
+// seedMinsMaxs fills the slices of minimum and maximum values so that they
+// hold the minimum and maximum values from the Snapshot and, as far as
+// possible, have the same means as those in the Snapshot. If the count is
+// small enough that the slices hold all the values the values between the
+// minimum and maximum are chosen to give the right total.
+func (s *Stat) seedMinsMaxs(snap Snapshot) {
+	n := min(snap.Count, cap(s.mins))
+	s.mins = s.mins[:n]
+	s.maxs = s.maxs[:n]
+
+	clamp := func(v float64) float64 {
+		if math.IsNaN(v) {
+			return snap.Min
+		}
+		return math.Max(snap.Min, math.Min(snap.Max, v))
+	}
+
+	if snap.Count <= cap(s.mins) {
+		fill := snap.Min
+		if n > 2 {
+			fill = clamp((snap.Sum - snap.Min - snap.Max) / float64(n-2))
+		}
+		for i := range s.mins {
+			s.mins[i] = fill
+		}
+		s.mins[0] = snap.Min
+		s.mins[n-1] = snap.Max
+		copy(s.maxs, s.mins)
+	} else {
+		minFill := clamp((snap.MeanMin*float64(n) - snap.Min) / float64(n-1))
+		maxFill := clamp((snap.MeanMax*float64(n) - snap.Max) / float64(n-1))
+		for i := range s.mins {
+			s.mins[i] = minFill
+			s.maxs[i] = maxFill
+		}
+		s.mins[0] = snap.Min
+		s.maxs[n-1] = snap.Max
+	}
+
+	if s.minInfo != nil {
+		s.minInfo = make([]any, n, cap(s.minInfo))
+		s.maxInfo = make([]any, n, cap(s.maxInfo))
+	}
+}
+
+// seedBucketTolerance is the largest difference, as a fraction of the
+// bucket width, between the limits of the buckets in a Snapshot and those
+// of evenly spaced buckets for the buckets to be taken as evenly spaced
+const seedBucketTolerance = 1e-9
+
+// snapBucketsEven returns true if the buckets of the Snapshot are evenly
+// spaced on the scale of the histogram, starting at start and each of the
+// given width
+func (s Stat) snapBucketsEven(snap Snapshot, start, width float64) bool {
+	tol := seedBucketTolerance * math.Abs(width)
+	for i, bkt := range snap.Buckets {
+		lo := start + float64(i)*width
+		if math.Abs(s.histPos(bkt.Low)-lo) > tol ||
+			math.Abs(s.histPos(bkt.High)-(lo+width)) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// seedKnots sets the knots of a Stat with a quantile layout (see
+// StatHistQuantileLayout) to the limits of the buckets of the Snapshot,
+// which may be of different widths. The buckets must be contiguous.
+func (s *Stat) seedKnots(snap Snapshot) error {
+	if !s.quantileLayout {
+		return errors.New("the summary histogram buckets are not evenly" +
+			" spaced - it can only be used with a Stat having a" +
+			" quantile layout (see StatHistQuantileLayout)")
+	}
+
+	knots := make([]float64, 0, len(snap.Buckets)+1)
+	for i, bkt := range snap.Buckets {
+		if i > 0 {
+			prev := snap.Buckets[i-1].High
+			if math.Abs(bkt.Low-prev) >
+				seedBucketTolerance*(bkt.High-bkt.Low) {
+				return fmt.Errorf("the summary histogram bucket %d"+
+					" does not start where bucket %d ends", i, i-1)
+			}
+		}
+		knots = append(knots, bkt.Low)
+	}
+	s.knots = append(knots, snap.Buckets[len(snap.Buckets)-1].High)
+	return nil
+}
+
+// seedHist copies the histogram from the Snapshot. The cache is not used
+// since the values are not known. If the Snapshot buckets are not evenly
+// spaced, as when it was taken from a Stat with a quantile layout, the
+// Stat must have a quantile layout too and the bucket limits are copied.
+// If the Stat records the sum of the values in each bucket any sums missing
+// from the Snapshot are estimated from the middle of the bucket.
+func (s *Stat) seedHist(snap Snapshot) error {
+	if len(snap.Buckets) < minHistBucketCount {
+		return errors.New("the summary has no histogram" +
+			" - it can only be used with a Stat having no histogram")
+	}
+
+	total := snap.Underflow + snap.Overflow
+	start := s.histPos(snap.Buckets[0].Low)
+	width := s.histPos(snap.Buckets[0].High) - start
+	for i, bkt := range snap.Buckets {
+		if bkt.Count < 0 || !(bkt.High > bkt.Low) ||
+			math.IsInf(s.histPos(bkt.Low), -1) ||
+			(i > 0 && !(bkt.Low > snap.Buckets[i-1].Low)) {
+			return fmt.Errorf("bad histogram bucket %d in the summary", i)
+		}
+		total += bkt.Count
+	}
+	if total != snap.Count {
+		return fmt.Errorf("the summary histogram holds %d values, expected %d",
+			total, snap.Count)
+	}
+
+	if !s.snapBucketsEven(snap, start, width) {
+		if err := s.seedKnots(snap); err != nil {
+			return err
+		}
+		start, width = 0, 1
+	}
+
+	s.cache = nil
+	s.hist = make([]int, len(snap.Buckets))
+	s.histSizeChosen = true
+	s.bucketStart = start
+	s.bucketWidth = width
+	s.underflow = snap.Underflow
+	s.overflow = snap.Overflow
+	for i, bkt := range snap.Buckets {
+		s.hist[i] = bkt.Count
+	}
+
+	if s.bucketSums {
+		s.histSums = make([]float64, len(s.hist))
+		for i, bkt := range snap.Buckets {
+			s.histSums[i] = bkt.Sum
+			if bkt.Sum == 0 { // the sums may not have been recorded
+				lo, hi := s.bucketLimits(i)
+				s.histSums[i] = float64(bkt.Count) * (lo + hi) / 2
+			}
+		}
+		_, end := s.bucketLimits(len(s.hist) - 1)
+		s.underflowSum = float64(s.underflow) * (snap.Min + s.histStart()) / 2
+		s.overflowSum = float64(s.overflow) * (end + snap.Max) / 2
+	}
+	return nil
+}
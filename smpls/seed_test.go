@@ -0,0 +1,157 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatFromSummary(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID: testhelper.MkID("good"),
+			opts: []StatOpt{
+				StatNoHist(), StatFromSummary(100, 500, 3000, 1, 9),
+			},
+		},
+		{
+			ID: testhelper.MkID("bad count"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid summary count (-1) - it must be >= 0"),
+			opts: []StatOpt{StatNoHist(), StatFromSummary(-1, 0, 0, 0, 0)},
+		},
+		{
+			ID: testhelper.MkID("min > max"),
+			ExpErr: testhelper.MkExpErr(
+				"Invalid summary: min (9) is not <= max (1)"),
+			opts: []StatOpt{StatNoHist(), StatFromSummary(2, 10, 82, 9, 1)},
+		},
+		{
+			ID: testhelper.MkID("with a histogram"),
+			ExpErr: testhelper.MkExpErr("the summary has no histogram" +
+				" - it can only be used with a Stat having no histogram"),
+			opts: []StatOpt{StatFromSummary(2, 10, 82, 1, 9)},
+		},
+		{
+			ID: testhelper.MkID("sampled"),
+			ExpErr: testhelper.MkExpErr(
+				"a sampled Stat cannot start from a summary"),
+			opts: []StatOpt{
+				StatSampleRate(0.5), StatFromSummary(2, 10, 82, 1, 9),
+			},
+		},
+		{
+			ID: testhelper.MkID("repeated"),
+			ExpErr: testhelper.MkExpErr(
+				"the Stat has already been given a starting summary"),
+			opts: []StatOpt{
+				StatFromSummary(2, 10, 82, 1, 9),
+				StatFromSummary(2, 10, 82, 1, 9),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestStatFromSummaryContinue(t *testing.T) {
+	direct := NewStatOrPanic("units", StatNoHist(), StatMinMaxCount(5))
+	addSeq(direct, 1, 1, 100)
+	count, sum, sumSq := direct.Count(), direct.Sum(), direct.sumSq
+
+	s := NewStatOrPanic("units", StatNoHist(), StatMinMaxCount(5),
+		StatFromSummary(count, sum, sumSq, direct.Min(), direct.Max()))
+	testhelper.DiffInt(t, "seeded", "count", s.Count(), 100)
+	testhelper.DiffFloat(t, "seeded", "mean", s.Mean(), direct.Mean(), 1e-9)
+	testhelper.DiffFloat(t, "seeded", "SD", s.StdDev(), direct.StdDev(), 1e-9)
+	testhelper.DiffFloat(t, "seeded", "min", s.Min(), 1, 0)
+	testhelper.DiffFloat(t, "seeded", "max", s.Max(), 100, 0)
+
+	addSeq(direct, 0.5, 100, 3)
+	addSeq(s, 0.5, 100, 3)
+	testhelper.DiffInt(t, "continued", "count", s.Count(), direct.Count())
+	testhelper.DiffFloat(t, "continued", "mean", s.Mean(), direct.Mean(), 1e-9)
+	testhelper.DiffFloat(t, "continued", "min", s.Min(), 0.5, 0)
+	testhelper.DiffFloat(t, "continued", "max", s.Max(), 200.5, 0)
+
+	small := NewStatOrPanic("units", StatNoHist(), StatMinMaxCount(5),
+		StatFromSummary(4, 10, 30, 1, 4))
+	testhelper.DiffFloatSlice(t, "small count", "mins",
+		small.mins, []float64{1, 2.5, 2.5, 4}, 0)
+}
+
+func TestStatFromSnapshot(t *testing.T) {
+	orig := NewStatOrPanic("units", StatCacheSize(100), StatHistBucketCount(10))
+	addSeq(orig, 0, 1, 1000)
+	snap := orig.Snapshot()
+
+	s := NewStatOrPanic("units", StatFromSnapshot(snap))
+	testhelper.DiffInt(t, "snapshot", "count", s.Count(), orig.Count())
+	testhelper.DiffFloat(t, "snapshot", "mean min",
+		s.MeanMin(), orig.MeanMin(), 1e-9)
+	testhelper.DiffFloat(t, "snapshot", "mean max",
+		s.MeanMax(), orig.MeanMax(), 1e-9)
+	testhelper.DiffFloat(t, "snapshot", "p50",
+		s.Percentile(50), orig.Percentile(50), 1e-9)
+
+	addSeq(orig, 50, 1, 100)
+	addSeq(s, 50, 1, 100)
+	testhelper.DiffFloat(t, "snapshot continued", "p90",
+		s.Percentile(90), orig.Percentile(90), 1e-9)
+
+	snap.Underflow++
+	_, err := NewStat("units", StatFromSnapshot(snap))
+	testhelper.CheckExpErrWithID(t, "snapshot - bad total", err,
+		testhelper.MkExpErr(
+			"the summary histogram holds 1001 values, expected 1000"))
+}
+
+func TestStatFromSnapshotQuantileLayout(t *testing.T) {
+	orig := NewStatOrPanic("units", StatCacheSize(100),
+		StatHistBucketCount(10), StatHistQuantileLayout())
+	for i := range 1000 {
+		orig.Add(math.Pow(float64(i)/100, 3))
+	}
+	snap := orig.Snapshot()
+
+	s := NewStatOrPanic("units", StatHistQuantileLayout(),
+		StatFromSnapshot(snap))
+	restored := s.Snapshot()
+	testhelper.DiffInt(t, "quantile layout", "buckets",
+		len(restored.Buckets), len(snap.Buckets))
+	for i, bkt := range snap.Buckets {
+		id := fmt.Sprintf("quantile layout: bucket %d", i)
+		rb := restored.Buckets[i]
+		testhelper.DiffFloat(t, id, "low", rb.Low, bkt.Low, 1e-9)
+		testhelper.DiffFloat(t, id, "high", rb.High, bkt.High, 1e-9)
+		testhelper.DiffInt(t, id, "count", rb.Count, bkt.Count)
+	}
+	testhelper.DiffFloat(t, "quantile layout", "p90",
+		s.Percentile(90), orig.Percentile(90), 1e-9)
+
+	addSeq(orig, 50, 1, 100)
+	addSeq(s, 50, 1, 100)
+	testhelper.DiffFloat(t, "quantile layout continued", "p90",
+		s.Percentile(90), orig.Percentile(90), 1e-9)
+
+	_, err := NewStat("units", StatFromSnapshot(snap))
+	testhelper.CheckExpErrWithID(t, "quantile layout - even Stat", err,
+		testhelper.MkExpErr("the summary histogram buckets are not evenly"+
+			" spaced", "StatHistQuantileLayout"))
+
+	snap.Buckets[3].Low += 0.01
+	_, err = NewStat("units", StatHistQuantileLayout(),
+		StatFromSnapshot(snap))
+	testhelper.CheckExpErrWithID(t, "quantile layout - gap", err,
+		testhelper.MkExpErr(
+			"the summary histogram bucket 3 does not start where bucket 2 ends"))
+}
@@ -0,0 +1,1081 @@
+package smpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The serialized form of a Stat starts with a magic string and a version
+// number. The version must be incremented whenever the layout of the
+// serialized data changes and Load must be able to read all earlier
+// versions.
+const (
+	serialMagic   = "smpl"
+	serialVersion = 1
+
+	// maxSerialSliceLen is the largest slice length that Load will accept;
+	// it guards against huge allocations when reading corrupt data.
+	maxSerialSliceLen = 1 << 28
+)
+
+// encoder writes the primitive parts of the serialized form. The first
+// error is recorded and all subsequent writes are skipped.
+type encoder struct {
+	w   io.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+// write writes the bytes unless an error has already been seen
+func (e *encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+// uvarint writes an unsigned varint
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+// varint writes a signed varint
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+// int writes an int as a signed varint
+func (e *encoder) int(v int) {
+	e.varint(int64(v))
+}
+
+// float writes a float64 as 8 little-endian bytes
+func (e *encoder) float(v float64) {
+	binary.LittleEndian.PutUint64(e.buf[:8], math.Float64bits(v))
+	e.write(e.buf[:8])
+}
+
+// bool writes a bool as a single byte
+func (e *encoder) bool(v bool) {
+	e.buf[0] = 0
+	if v {
+		e.buf[0] = 1
+	}
+	e.write(e.buf[:1])
+}
+
+// str writes the length of the string followed by its bytes
+func (e *encoder) str(v string) {
+	e.uvarint(uint64(len(v)))
+	e.write([]byte(v))
+}
+
+// time writes a time as a flag indicating whether it is the zero time
+// followed, if not, by the nanoseconds since the Unix epoch
+func (e *encoder) time(v time.Time) {
+	e.bool(v.IsZero())
+	if !v.IsZero() {
+		e.varint(v.UnixNano())
+	}
+}
+
+// floats writes the capacity and length of the slice followed by its
+// contents. A nil slice is written with a capacity of zero.
+func (e *encoder) floats(v []float64) {
+	e.uvarint(uint64(cap(v)))
+	e.uvarint(uint64(len(v)))
+	for _, f := range v {
+		e.float(f)
+	}
+}
+
+// ints writes the length of the slice followed by its contents
+func (e *encoder) ints(v []int) {
+	e.uvarint(uint64(len(v)))
+	for _, i := range v {
+		e.int(i)
+	}
+}
+
+// byteReader reads a single byte at a time from the underlying
+// Reader. Unlike a bufio.Reader it never reads beyond the data it needs so
+// a Stat can be loaded from the middle of a stream.
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+// ReadByte reads a single byte
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.buf[:])
+	return br.buf[0], err
+}
+
+// Read reads from the underlying Reader
+func (br *byteReader) Read(p []byte) (int, error) {
+	return br.r.Read(p)
+}
+
+// decoder reads the primitive parts of the serialized form. The first
+// error is recorded and all subsequent reads return zero values.
+type decoder struct {
+	r   *byteReader
+	err error
+	buf [8]byte
+}
+
+// setErr records the error if no error has yet been seen. An unexpected EOF
+// is reported as such.
+func (d *decoder) setErr(err error) {
+	if d.err != nil {
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err = err
+}
+
+// read fills the buffer
+func (d *decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.setErr(err)
+	}
+}
+
+// uvarint reads an unsigned varint
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.setErr(err)
+	}
+	return v
+}
+
+// varint reads a signed varint
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.setErr(err)
+	}
+	return v
+}
+
+// int reads an int written as a signed varint
+func (d *decoder) int() int {
+	return int(d.varint())
+}
+
+// float reads a float64 written as 8 little-endian bytes
+func (d *decoder) float() float64 {
+	d.read(d.buf[:8])
+	if d.err != nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(d.buf[:8]))
+}
+
+// bool reads a bool written as a single byte
+func (d *decoder) bool() bool {
+	d.read(d.buf[:1])
+	return d.err == nil && d.buf[0] != 0
+}
+
+// sliceLen reads a slice length and checks that it is not too large
+func (d *decoder) sliceLen(name string) int {
+	l := d.uvarint()
+	if l > maxSerialSliceLen {
+		d.setErr(fmt.Errorf("the %s length (%d) is too large", name, l))
+		return 0
+	}
+	return int(l)
+}
+
+// str reads a string written as a length followed by its bytes
+func (d *decoder) str() string {
+	b := make([]byte, d.sliceLen("string"))
+	d.read(b)
+	return string(b)
+}
+
+// time reads a time written by the encoder time method
+func (d *decoder) time() time.Time {
+	if d.bool() {
+		return time.Time{}
+	}
+	return time.Unix(0, d.varint())
+}
+
+// floats reads a slice written by the encoder floats method. A capacity of
+// zero gives a nil slice.
+func (d *decoder) floats(name string) []float64 {
+	c := d.sliceLen(name + " capacity")
+	l := d.sliceLen(name)
+	if l > c {
+		d.setErr(fmt.Errorf("the %s length (%d) exceeds its capacity (%d)",
+			name, l, c))
+	}
+	if d.err != nil || c == 0 {
+		return nil
+	}
+	v := make([]float64, l, c)
+	for i := range v {
+		v[i] = d.float()
+	}
+	return v
+}
+
+// ints reads a slice written by the encoder ints method
+func (d *decoder) ints(name string) []int {
+	l := d.sliceLen(name)
+	if d.err != nil || l == 0 {
+		return nil
+	}
+	v := make([]int, l)
+	for i := range v {
+		v[i] = d.int()
+	}
+	return v
+}
+
+// Save writes the state of the Stat to the Writer in a compact, versioned
+// binary form. The Stat can be recreated from this by the Load method and
+// will then continue exactly as if it had not been saved.
+//
+// Some parts of the Stat cannot be written and are not preserved: the
+// transform (see StatTransform), any Accumulators, raw value writer,
+// sample store, string template or Sinks (see Subscribe), the information
+// recorded with the smallest and largest values (see StatTrackInfo) and
+// the link to the Stat it was forked from (see Fork). Nor are the
+// starting summary and memory limit given as options (see StatFromSummary
+// and StatMaxMemory) though their effect on the values is.
+func (s Stat) Save(w io.Writer) error {
+	e := &encoder{w: w}
+
+	e.write([]byte(serialMagic))
+	e.uvarint(serialVersion)
+
+	e.str(s.units)
+
+	e.float(s.sum)
+	e.float(s.sumSq)
+	e.int(s.count)
+	e.floats(s.mins)
+	e.floats(s.maxs)
+
+	e.floats(s.cache)
+	e.uvarint(uint64(cap(s.cacheBuf)))
+
+	e.int(s.underflow)
+	e.ints(s.hist)
+	e.uvarint(uint64(cap(s.hist)))
+	e.int(s.overflow)
+	e.float(s.bucketStart)
+	e.float(s.bucketWidth)
+	e.bool(s.histSizeChosen)
+
+	e.bool(s.rate != nil)
+	if s.rate != nil {
+		s.rate.save(e)
+	}
+
+	e.bool(s.noCache)
+	e.bool(s.noHist)
+
+	e.bool(s.compensated)
+	e.float(s.sumC)
+	e.float(s.sumSqC)
+
+	e.float(s.rebinFraction)
+
+	e.bool(s.freq != nil)
+	if s.freq != nil {
+		s.freq.save(e)
+	}
+
+	e.float(s.sampleRate)
+	e.int(s.calls)
+	e.int(s.sampled)
+
+	e.bool(s.bucketSums)
+	if s.bucketSums {
+		e.floats(s.histSums)
+		e.float(s.underflowSum)
+		e.float(s.overflowSum)
+	}
+
+	e.bool(s.distinct != nil)
+	if s.distinct != nil {
+		e.int(s.distinct.Precision())
+		e.write(s.distinct.registers)
+	}
+
+	e.bool(s.reservoir != nil)
+	if s.reservoir != nil {
+		e.floats(s.reservoir.vals)
+		e.int(s.reservoir.seen)
+		e.uvarint(s.reservoir.rng)
+	}
+
+	e.float(s.logBase)
+
+	e.bool(s.runs != nil)
+	if s.runs != nil {
+		s.runs.save(e)
+	}
+
+	e.bool(s.anchored)
+	if s.anchored {
+		e.float(s.anchor)
+	}
+
+	e.int(s.historyLen)
+	e.uvarint(uint64(len(s.history)))
+	for _, snap := range s.history {
+		b, err := snap.MarshalProto()
+		if err != nil {
+			return err
+		}
+		e.uvarint(uint64(len(b)))
+		e.write(b)
+	}
+
+	e.uvarint(uint64(len(s.meta)))
+	for _, k := range sortedMetaKeys(s.meta) {
+		e.str(k)
+		e.str(s.meta[k])
+	}
+
+	e.bool(s.deadband != nil)
+	if s.deadband != nil {
+		e.float(s.deadband.epsilon)
+		e.float(s.deadband.last)
+		e.bool(s.deadband.seen)
+		e.int(s.deadband.suppressed)
+	}
+
+	e.int(s.exactLim)
+	e.bool(s.bessel)
+
+	e.bool(s.quantileLayout)
+	e.floats(s.knots)
+
+	e.bool(s.autocorr != nil)
+	if s.autocorr != nil {
+		s.autocorr.save(e)
+	}
+
+	e.bool(s.valid != nil)
+	if s.valid != nil {
+		e.float(s.valid.lo)
+		e.float(s.valid.hi)
+		e.int(int(s.valid.action))
+		e.int(s.valid.outside)
+	}
+
+	e.bool(s.tails != nil)
+	if s.tails != nil {
+		s.tails.save(e)
+	}
+
+	e.bool(s.heavy != nil)
+	if s.heavy != nil {
+		s.heavy.save(e)
+	}
+
+	e.bool(s.niceBounds)
+
+	e.bool(s.emptyNaN)
+	e.bool(s.unitPrefixes)
+	e.bool(s.fmtOpts != nil)
+	if s.fmtOpts != nil {
+		s.fmtOpts.save(e)
+	}
+
+	e.varint(int64(s.tw.dur))
+	e.float(s.tw.mean)
+	e.float(s.tw.m2)
+
+	e.bool(s.expRange != nil)
+	if s.expRange != nil {
+		e.float(s.expRange.lo)
+		e.float(s.expRange.hi)
+	}
+
+	e.bool(s.trackChange)
+	e.bool(s.lastCycle != nil)
+	if s.lastCycle != nil {
+		b, err := s.lastCycle.MarshalProto()
+		if err != nil {
+			return err
+		}
+		e.uvarint(uint64(len(b)))
+		e.write(b)
+	}
+
+	e.bool(s.remedian != nil)
+	if s.remedian != nil {
+		s.remedian.save(e)
+	}
+
+	e.bool(s.warmup != nil)
+	if w := s.warmup; w != nil {
+		e.int(w.n)
+		e.varint(int64(w.dur))
+		e.time(w.start)
+		e.int(w.discarded)
+		e.bool(w.done)
+	}
+
+	e.int(s.adds)
+
+	return e.err
+}
+
+// save writes the state of the Rate to the encoder
+func (r Rate) save(e *encoder) {
+	e.time(r.first)
+	e.time(r.last)
+	e.int(r.count)
+	e.float(r.sum)
+	e.varint(int64(r.window))
+	e.uvarint(uint64(len(r.slots)))
+	for _, slot := range r.slots {
+		e.time(slot.start)
+		e.int(slot.count)
+		e.float(slot.sum)
+	}
+}
+
+// save writes the state of the freqTracker to the encoder. The values are
+// written in sorted order so that the same state always gives the same
+// serialized form.
+func (ft freqTracker) save(e *encoder) {
+	e.int(ft.maxSize)
+	e.int(ft.evicted)
+	vcs := ft.sorted()
+	e.uvarint(uint64(len(vcs)))
+	for _, vc := range vcs {
+		e.float(vc.Val)
+		e.int(vc.Count)
+	}
+}
+
+// loadFreqTracker reads the state of a freqTracker from the decoder
+func loadFreqTracker(d *decoder) *freqTracker {
+	maxSize := d.int()
+	evicted := d.int()
+	n := d.sliceLen("value frequencies")
+	if d.err != nil {
+		return nil
+	}
+	if maxSize < minFreqMaxSize || n > maxSize {
+		d.setErr(fmt.Errorf("bad frequency tracking size (%d) or count (%d)",
+			maxSize, n))
+		return nil
+	}
+
+	ft := newFreqTracker(maxSize)
+	ft.evicted = evicted
+	for i := 0; i < n; i++ {
+		v := d.float()
+		ft.counts[v] = d.int()
+	}
+	return ft
+}
+
+// Load replaces the state of the Stat with that read from the Reader which
+// should have been written by the Save method. Load reads no more data than
+// was written by Save. If an error is returned the Stat is unchanged.
+func (s *Stat) Load(r io.Reader) error {
+	d := &decoder{r: &byteReader{r: r}}
+
+	magic := make([]byte, len(serialMagic))
+	d.read(magic)
+	if d.err == nil && string(magic) != serialMagic {
+		return errors.New("the data is not a serialized Stat")
+	}
+	version := d.uvarint()
+	if d.err == nil && version != serialVersion {
+		return fmt.Errorf("unsupported Stat serialization version: %d",
+			version)
+	}
+
+	var ns Stat
+
+	ns.units = d.str()
+
+	ns.sum = d.float()
+	ns.sumSq = d.float()
+	ns.count = d.int()
+	ns.mins = d.floats("min values")
+	ns.maxs = d.floats("max values")
+
+	ns.cache = d.floats("cache")
+	ns.cacheBuf = loadCacheBuf(d, ns.cache)
+
+	ns.underflow = d.int()
+	ns.hist = loadHist(d)
+	ns.overflow = d.int()
+	ns.bucketStart = d.float()
+	ns.bucketWidth = d.float()
+	ns.histSizeChosen = d.bool()
+
+	if d.bool() {
+		ns.rate = loadRate(d)
+	}
+
+	ns.noCache = d.bool()
+	ns.noHist = d.bool()
+	ns.compensated = d.bool()
+	ns.sumC = d.float()
+	ns.sumSqC = d.float()
+	ns.rebinFraction = d.float()
+	if d.bool() {
+		ns.freq = loadFreqTracker(d)
+	}
+	ns.sampleRate = d.float()
+	ns.calls = d.int()
+	ns.sampled = d.int()
+	if d.bool() {
+		ns.bucketSums = true
+		ns.histSums = d.floats("bucket sums")
+		ns.underflowSum = d.float()
+		ns.overflowSum = d.float()
+	}
+	if d.bool() {
+		ns.distinct = loadCardinality(d)
+	}
+	if d.bool() {
+		ns.reservoir = &reservoir{
+			vals: d.floats("reservoir"),
+			seen: d.int(),
+			rng:  d.uvarint(),
+		}
+	}
+	ns.logBase = d.float()
+	if d.bool() {
+		ns.runs = loadRunTracker(d)
+	}
+	if d.bool() {
+		ns.anchored = true
+		ns.anchor = d.float()
+	}
+	ns.historyLen = d.int()
+	ns.history = loadHistory(d)
+	for range d.sliceLen("metadata") {
+		k := d.str()
+		ns.SetMeta(k, d.str())
+	}
+	if d.bool() {
+		ns.deadband = &deadband{
+			epsilon:    d.float(),
+			last:       d.float(),
+			seen:       d.bool(),
+			suppressed: d.int(),
+		}
+	}
+	ns.exactLim = d.int()
+	ns.bessel = d.bool()
+	ns.quantileLayout = d.bool()
+	ns.knots = d.floats("knots")
+	if d.bool() {
+		ns.autocorr = loadAutocorrTracker(d)
+	}
+	if d.bool() {
+		ns.valid = &validRange{
+			lo:      d.float(),
+			hi:      d.float(),
+			action:  RangeAction(d.int()),
+			outside: d.int(),
+		}
+	}
+	if d.bool() {
+		ns.tails = loadTailTracker(d)
+	}
+	if d.bool() {
+		ns.heavy = loadHeavyHitters(d)
+	}
+	ns.niceBounds = d.bool()
+	ns.emptyNaN = d.bool()
+	ns.unitPrefixes = d.bool()
+	if d.bool() {
+		ns.fmtOpts = loadFmtOpts(d)
+	}
+	ns.tw.dur = time.Duration(d.varint())
+	ns.tw.mean = d.float()
+	ns.tw.m2 = d.float()
+	if d.bool() {
+		ns.expRange = &expectedRange{lo: d.float(), hi: d.float()}
+	}
+	ns.trackChange = d.bool()
+	if d.bool() {
+		ns.lastCycle = loadSnapshot(d)
+	}
+	if d.bool() {
+		ns.remedian = loadRemedian(d)
+	}
+	if d.bool() {
+		ns.warmup = &warmup{
+			n:         d.int(),
+			dur:       time.Duration(d.varint()),
+			now:       time.Now,
+			start:     d.time(),
+			discarded: d.int(),
+			done:      d.bool(),
+		}
+	}
+	ns.adds = d.int()
+
+	if d.err != nil {
+		return fmt.Errorf("cannot load the Stat: %w", d.err)
+	}
+	if err := ns.checkLoaded(); err != nil {
+		return fmt.Errorf("cannot load the Stat: %w", err)
+	}
+	if ns.rebinFraction > 0 || ns.anchored {
+		ns.makeRebinBufs()
+	}
+
+	*s = ns
+	return nil
+}
+
+// loadCacheBuf reads the capacity of the storage for the cache and returns
+// that storage. This is the cache itself unless the values have been moved
+// out of it, in which case new storage of that capacity is made so that a
+// Reset restores the cache as it was.
+func loadCacheBuf(d *decoder, cache []float64) []float64 {
+	c := d.sliceLen("cache storage capacity")
+	if d.err != nil || c == 0 {
+		return nil
+	}
+	if cache != nil {
+		if c != cap(cache) {
+			d.setErr(fmt.Errorf(
+				"the cache storage capacity (%d) differs from that of"+
+					" the cache (%d)", c, cap(cache)))
+		}
+		return cache[:0]
+	}
+	return make([]float64, 0, c)
+}
+
+// loadHist reads the histogram written by the encoder ints method followed
+// by its capacity. The capacity is restored so that a Reset gives the
+// histogram as many buckets as it had before it was saved.
+func loadHist(d *decoder) []int {
+	hist := d.ints("histogram")
+	c := d.sliceLen("histogram capacity")
+	if d.err != nil || c == 0 {
+		return hist
+	}
+	if c < len(hist) {
+		d.setErr(fmt.Errorf(
+			"the histogram length (%d) exceeds its capacity (%d)",
+			len(hist), c))
+		return hist
+	}
+	h := make([]int, len(hist), c)
+	copy(h, hist)
+	return h
+}
+
+// save writes the FmtOpts to the encoder
+func (fo FmtOpts) save(e *encoder) {
+	e.int(fo.SigFigs)
+	e.int(int(fo.Style))
+	fields := make([]int, 0, len(fo.Fields))
+	for _, f := range fo.Fields {
+		fields = append(fields, int(f))
+	}
+	e.ints(fields)
+	e.str(fo.Number.Group)
+	e.str(fo.Number.Decimal)
+}
+
+// loadFmtOpts reads the FmtOpts from the decoder
+func loadFmtOpts(d *decoder) *FmtOpts {
+	fo := &FmtOpts{
+		SigFigs: d.int(),
+		Style:   FmtStyle(d.int()),
+	}
+	for _, f := range d.ints("format fields") {
+		fo.Fields = append(fo.Fields, StatField(f))
+	}
+	fo.Number.Group = d.str()
+	fo.Number.Decimal = d.str()
+	return fo
+}
+
+// save writes the state of the runTracker to the encoder
+func (rt runTracker) save(e *encoder) {
+	e.float(rt.threshold)
+	e.int(rt.n)
+	e.float(rt.first)
+	e.float(rt.last)
+	e.int(rt.incLead)
+	e.int(rt.incCur)
+	e.int(rt.incLongest)
+	e.int(rt.aboveLead)
+	e.int(rt.aboveCur)
+	e.int(rt.aboveLongest)
+}
+
+// loadRunTracker reads the state of a runTracker from the decoder
+func loadRunTracker(d *decoder) *runTracker {
+	return &runTracker{
+		threshold:    d.float(),
+		n:            d.int(),
+		first:        d.float(),
+		last:         d.float(),
+		incLead:      d.int(),
+		incCur:       d.int(),
+		incLongest:   d.int(),
+		aboveLead:    d.int(),
+		aboveCur:     d.int(),
+		aboveLongest: d.int(),
+	}
+}
+
+// save writes the state of the autocorrTracker to the encoder
+func (at autocorrTracker) save(e *encoder) {
+	e.int(at.maxLag)
+	e.float(at.shift)
+	e.int(at.n)
+	e.float(at.sum)
+	e.float(at.sumSq)
+	e.floats(at.lagSums)
+	e.floats(at.first)
+	e.floats(at.last)
+}
+
+// save writes the state of the tailTracker to the encoder
+func (tt tailTracker) save(e *encoder) {
+	e.int(tt.k)
+	e.floats(tt.lows)
+	e.int(tt.lowCount)
+	e.float(tt.lowSum)
+	e.floats(tt.highs)
+	e.int(tt.highCount)
+	e.float(tt.highSum)
+}
+
+// loadTailTracker reads the state of a tailTracker from the decoder
+func loadTailTracker(d *decoder) *tailTracker {
+	tt := &tailTracker{k: d.int()}
+	tt.lows = d.floats("low tail values")
+	tt.lowCount = d.int()
+	tt.lowSum = d.float()
+	tt.highs = d.floats("high tail values")
+	tt.highCount = d.int()
+	tt.highSum = d.float()
+	if tt.k >= minTailCapture && tt.k <= maxTailCapture {
+		tt.lows = append(make([]float64, 0, tt.k), tt.lows...)
+		tt.highs = append(make([]float64, 0, tt.k), tt.highs...)
+	}
+	return tt
+}
+
+// save writes the state of the heavyHitters tracker to the encoder
+func (hh heavyHitters) save(e *encoder) {
+	e.int(hh.k)
+	e.uvarint(uint64(len(hh.entries)))
+	for _, he := range hh.entries {
+		e.float(he.val)
+		e.int(he.count)
+		e.int(he.err)
+	}
+}
+
+// loadHeavyHitters reads the state of a heavyHitters tracker from the
+// decoder. The entries are added afresh to rebuild the heap and index.
+func loadHeavyHitters(d *decoder) *heavyHitters {
+	k := d.int()
+	n := d.sliceLen("heavy hitters")
+	if k < minHeavyHitters || k > maxHeavyHitters || n > k {
+		d.setErr(fmt.Errorf("bad heavy hitter count (%d of %d)", n, k))
+		return &heavyHitters{k: k}
+	}
+	hh := newHeavyHitters(k)
+	for range n {
+		he := hhEntry{val: d.float(), count: d.int(), err: d.int()}
+		hh.entries = append(hh.entries, he)
+	}
+	for i, he := range hh.entries {
+		hh.idx[he.val] = i
+	}
+	return hh
+}
+
+// save writes the state of the remedian to the encoder
+func (rm remedian) save(e *encoder) {
+	e.int(rm.base)
+	e.uvarint(uint64(len(rm.levels)))
+	for _, buf := range rm.levels {
+		e.floats(buf)
+	}
+}
+
+// loadRemedian reads the state of a remedian from the decoder
+func loadRemedian(d *decoder) *remedian {
+	rm := &remedian{base: d.int()}
+	n := d.sliceLen("remedian levels")
+	if d.err != nil {
+		return rm
+	}
+	for range n {
+		rm.levels = append(rm.levels, d.floats("remedian values"))
+	}
+	return rm
+}
+
+// loadAutocorrTracker reads the state of an autocorrTracker from the
+// decoder
+func loadAutocorrTracker(d *decoder) *autocorrTracker {
+	return &autocorrTracker{
+		maxLag:  d.int(),
+		shift:   d.float(),
+		n:       d.int(),
+		sum:     d.float(),
+		sumSq:   d.float(),
+		lagSums: d.floats("autocorrelation sums"),
+		first:   d.floats("autocorrelation first values"),
+		last:    d.floats("autocorrelation last values"),
+	}
+}
+
+// loadHistory reads the Snapshots recorded by Rotate from the decoder
+func loadHistory(d *decoder) []Snapshot {
+	n := d.sliceLen("history")
+	if d.err != nil || n == 0 {
+		return nil
+	}
+
+	history := make([]Snapshot, 0, n)
+	for range n {
+		snap := loadSnapshot(d)
+		if snap == nil {
+			return nil
+		}
+		history = append(history, *snap)
+	}
+	return history
+}
+
+// loadSnapshot reads a Snapshot from the decoder. It returns nil if the
+// Snapshot cannot be read.
+func loadSnapshot(d *decoder) *Snapshot {
+	b := make([]byte, d.sliceLen("snapshot"))
+	d.read(b)
+	if d.err != nil {
+		return nil
+	}
+	var snap Snapshot
+	if err := snap.UnmarshalProto(b); err != nil {
+		d.setErr(err)
+		return nil
+	}
+	return &snap
+}
+
+// loadCardinality reads the state of a Cardinality from the decoder
+func loadCardinality(d *decoder) *Cardinality {
+	precision := d.int()
+	if d.err != nil {
+		return nil
+	}
+	c, err := NewCardinality(precision)
+	if err != nil {
+		d.setErr(err)
+		return nil
+	}
+	d.read(c.registers)
+	return c
+}
+
+// loadRate reads the state of a Rate from the decoder
+func loadRate(d *decoder) *Rate {
+	r := &Rate{now: time.Now}
+
+	r.first = d.time()
+	r.last = d.time()
+	r.count = d.int()
+	r.sum = d.float()
+	window := time.Duration(d.varint())
+	slotCount := d.sliceLen("rate slots")
+	if d.err != nil {
+		return nil
+	}
+	if window <= 0 || slotCount < minRateSlotCount ||
+		window/time.Duration(slotCount) == 0 {
+		d.setErr(fmt.Errorf("bad rate window (%s) or slot count (%d)",
+			window, slotCount))
+		return nil
+	}
+
+	r.setWindow(window, slotCount)
+	for i := range r.slots {
+		r.slots[i].start = d.time()
+		r.slots[i].count = d.int()
+		r.slots[i].sum = d.float()
+	}
+	return r
+}
+
+// checkLoaded performs some consistency checks on a newly loaded Stat
+func (s Stat) checkLoaded() error {
+	if s.count < 0 || s.adds < 0 {
+		return fmt.Errorf("bad count (%d) or number of values added (%d)",
+			s.count, s.adds)
+	}
+	if s.sampleRate < 0 || s.sampleRate >= 1 ||
+		s.sampled < 0 || s.sampled > s.calls {
+		return fmt.Errorf("bad sample rate (%g) or count (%d)",
+			s.sampleRate, s.calls)
+	}
+	if cap(s.mins) < minMinMaxCount || cap(s.mins) != cap(s.maxs) {
+		return fmt.Errorf("bad min/max counts: %d, %d",
+			cap(s.mins), cap(s.maxs))
+	}
+	if len(s.mins) != len(s.maxs) ||
+		len(s.mins) != min(s.count, cap(s.mins)) {
+		return fmt.Errorf("bad number of min/max values: %d, %d",
+			len(s.mins), len(s.maxs))
+	}
+	if s.cacheBuf != nil && (cap(s.cacheBuf) < minCacheSize || s.noCache) {
+		return fmt.Errorf("bad cache size: %d", cap(s.cacheBuf))
+	}
+	if s.noHist {
+		if s.hist != nil || s.cache != nil {
+			return errors.New("unexpected histogram or cache")
+		}
+	} else if len(s.hist) < minHistBucketCount {
+		return fmt.Errorf("bad histogram bucket count: %d", len(s.hist))
+	}
+	if s.logBase != 0 && !(s.logBase > 1) {
+		return fmt.Errorf("bad log base: %g", s.logBase)
+	}
+	if s.anchored && (s.noHist || s.logBase != 0 || s.rebinFraction != 0 ||
+		math.IsNaN(s.anchor) || math.IsInf(s.anchor, 0)) {
+		return fmt.Errorf("bad histogram anchor: %g", s.anchor)
+	}
+	if er := s.expRange; er != nil && (s.noHist || s.quantileLayout ||
+		!(er.lo < er.hi) || math.IsInf(er.lo, 0) || math.IsInf(er.hi, 0)) {
+		return errors.New("bad expected range")
+	}
+	if s.tw.dur < 0 {
+		return fmt.Errorf("bad time-weighted duration: %s", s.tw.dur)
+	}
+	if s.niceBounds && (s.noHist || s.logBase != 0 || s.rebinFraction != 0 ||
+		s.quantileLayout) {
+		return errors.New("unexpected nice bucket boundaries")
+	}
+	if s.histSums != nil && len(s.histSums) != len(s.hist) {
+		return fmt.Errorf("bad number of bucket sums: %d", len(s.histSums))
+	}
+	if r := s.reservoir; r != nil && (cap(r.vals) < minReservoirSize ||
+		len(r.vals) != min(r.seen, cap(r.vals))) {
+		return fmt.Errorf("bad reservoir size (%d) or count (%d)",
+			cap(r.vals), r.seen)
+	}
+	if s.historyLen < 0 || len(s.history) > s.historyCap() {
+		return fmt.Errorf("bad history length (%d) or size (%d)",
+			s.historyLen, len(s.history))
+	}
+	if len(s.knots) > 0 && len(s.knots) != len(s.hist)+1 {
+		return fmt.Errorf("bad number of histogram knots (%d) for %d buckets",
+			len(s.knots), len(s.hist))
+	}
+	for i := 1; i < len(s.knots); i++ {
+		if !(s.knots[i] > s.knots[i-1]) {
+			return errors.New("the histogram knots are not in ascending order")
+		}
+	}
+	if s.exactLim < 0 {
+		return fmt.Errorf("bad exact limit (%d)", s.exactLim)
+	}
+	if w := s.warmup; w != nil &&
+		(w.n < 0 || w.dur < 0 || (w.n > 0) == (w.dur > 0) ||
+			w.discarded < 0) {
+		return fmt.Errorf("bad warm-up (%d values or %s) or discarded"+
+			" count (%d)", w.n, w.dur, w.discarded)
+	}
+	if db := s.deadband; db != nil &&
+		(!(db.epsilon >= 0) || db.suppressed < 0) {
+		return fmt.Errorf("bad deadband (%g) or suppressed count (%d)",
+			db.epsilon, db.suppressed)
+	}
+	if vr := s.valid; vr != nil &&
+		(!(vr.lo < vr.hi) || vr.action < 0 || vr.action >= rangeActionCount ||
+			vr.outside < 0) {
+		return fmt.Errorf("bad valid range (%g to %g, %s) or count (%d)",
+			vr.lo, vr.hi, vr.action, vr.outside)
+	}
+	if s.runs != nil {
+		if err := s.runs.check(); err != nil {
+			return err
+		}
+	}
+	if s.autocorr != nil {
+		if err := s.autocorr.check(); err != nil {
+			return err
+		}
+	}
+	if s.tails != nil {
+		if err := s.tails.check(); err != nil {
+			return err
+		}
+	}
+	if s.heavy != nil {
+		if err := s.heavy.check(); err != nil {
+			return err
+		}
+	}
+	if s.remedian != nil {
+		if err := s.remedian.check(); err != nil {
+			return err
+		}
+	}
+	if s.fmtOpts != nil {
+		if err := s.fmtOpts.check(); err != nil {
+			return fmt.Errorf("bad format: %w", err)
+		}
+	}
+	if s.bucketSums && s.noHist {
+		return errors.New("unexpected bucket sums")
+	}
+	return nil
+}
+
+// GobEncode encodes the Stat using the Save method. It allows a Stat to be
+// written using the encoding/gob package.
+func (s Stat) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes the Stat using the Load method. It allows a Stat to be
+// read using the encoding/gob package.
+func (s *Stat) GobDecode(b []byte) error {
+	return s.Load(bytes.NewReader(b))
+}
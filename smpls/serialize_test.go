@@ -0,0 +1,276 @@
+package smpls
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// addSeq adds count values to the Stat starting at init and incrementing by
+// incr
+func addSeq(s *Stat, init, incr float64, count int) {
+	v := init
+	for i := 0; i < count; i++ {
+		s.Add(v)
+		v += incr
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts  []StatOpt
+		count int
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:    testhelper.MkID("cache partly filled"),
+			opts:  []StatOpt{StatCacheSize(100)},
+			count: 50,
+		},
+		{
+			ID:    testhelper.MkID("cache full, histogram live"),
+			opts:  []StatOpt{StatCacheSize(100), StatHistBucketCount(10)},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("no cache"),
+			opts:  []StatOpt{StatNoCache()},
+			count: 15,
+		},
+		{
+			ID:    testhelper.MkID("no hist"),
+			opts:  []StatOpt{StatNoHist()},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("with frequencies"),
+			opts:  []StatOpt{StatTrackFreq(10)},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("with heavy hitters"),
+			opts:  []StatOpt{StatHeavyHitters(5)},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("with approximate median"),
+			opts:  []StatOpt{StatApproxMedian(5)},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("with warm-up"),
+			opts:  []StatOpt{StatWarmup(20)},
+			count: 150,
+		},
+		{
+			ID:    testhelper.MkID("with runs"),
+			opts:  []StatOpt{StatTrackRuns(10)},
+			count: 50,
+		},
+		{
+			ID:    testhelper.MkID("with deadband"),
+			opts:  []StatOpt{StatDeadband(0.75)},
+			count: 50,
+		},
+		{
+			ID:    testhelper.MkID("unit prefixes"),
+			opts:  []StatOpt{StatUnitPrefixes()},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("format"),
+			opts: []StatOpt{StatFormat(FmtOpts{
+				SigFigs: 4,
+				Style:   FmtFixed,
+				Fields:  []StatField{FieldCount, FieldMean, FieldMax},
+				Number:  NumFmtEuropean,
+			})},
+			count: 5000,
+		},
+		{
+			ID: testhelper.MkID("exact"),
+			opts: []StatOpt{
+				StatExact(100),
+				StatBesselCorrection(),
+				StatEmptyNaN(),
+			},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("quantile layout"),
+			opts: []StatOpt{
+				StatCacheSize(20),
+				StatHistBucketCount(5),
+				StatHistQuantileLayout(),
+			},
+			count: 50,
+		},
+		{
+			ID:    testhelper.MkID("with autocorrelation"),
+			opts:  []StatOpt{StatAutocorrelation(3)},
+			count: 50,
+		},
+		{
+			ID:    testhelper.MkID("with valid range"),
+			opts:  []StatOpt{StatRange(5, 20, RangeReject)},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("with tail capture"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistBucketCount(5),
+				StatTailCapture(3),
+			},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("anchored"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistBucketCount(5),
+				StatHistAnchor(0),
+			},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("expected range"),
+			opts: []StatOpt{
+				StatHistBucketCount(5),
+				StatExpectedRange(0, 20),
+			},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("nice bounds"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistBucketCount(5),
+				StatHistNiceBounds(),
+			},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("with rate"),
+			opts: []StatOpt{
+				StatCacheSize(100),
+				StatRate(RateWindow(time.Second, 10)),
+			},
+			count: 150,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", tc.opts...)
+		addSeq(s, 1.0, 0.5, tc.count)
+
+		var buf bytes.Buffer
+		if err := s.Save(&buf); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error saving the Stat: %v\n", err)
+			continue
+		}
+
+		var loaded Stat
+		if err := loaded.Load(&buf); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error loading the Stat: %v\n", err)
+			continue
+		}
+		if buf.Len() != 0 {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: Load left %d bytes unread\n", buf.Len())
+		}
+
+		addSeq(s, 2.0, 0.25, 100)
+		addSeq(&loaded, 2.0, 0.25, 100)
+
+		err := testhelper.DiffVals(loaded, *s, []string{"rate"})
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the loaded Stat differs: %v\n", err)
+		}
+		if s.rate != nil {
+			testhelper.DiffInt(t, tc.IDStr(), "rate count",
+				loaded.rate.Count(), s.rate.Count())
+			testhelper.DiffBool(t, tc.IDStr(), "rate first",
+				loaded.rate.First().Equal(s.rate.First()), true)
+			testhelper.DiffInt(t, tc.IDStr(), "rate window",
+				loaded.rate.Window(), s.rate.Window())
+		}
+		testhelper.DiffString(t, tc.IDStr(), "String",
+			loaded.String(), s.String())
+		testhelper.DiffString(t, tc.IDStr(), "Hist", loaded.Hist(), s.Hist())
+
+		s.Reset()
+		loaded.Reset()
+		testhelper.DiffInt(t, tc.IDStr(), "cache size after Reset",
+			cap(loaded.cache), cap(s.cache))
+		testhelper.DiffInt(t, tc.IDStr(), "buckets after Reset",
+			len(loaded.hist), len(s.hist))
+		addSeq(s, 3.0, 0.5, 150)
+		addSeq(&loaded, 3.0, 0.5, 150)
+		testhelper.DiffString(t, tc.IDStr(), "Hist after Reset",
+			loaded.Hist(), s.Hist())
+	}
+}
+
+func TestLoadErrs(t *testing.T) {
+	var good bytes.Buffer
+	s := NewStatOrPanic("units")
+	s.Add(1, 2, 3)
+	if err := s.Save(&good); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		data []byte
+	}{
+		{
+			ID:     testhelper.MkID("bad magic"),
+			data:   []byte("xxxx\x01"),
+			ExpErr: testhelper.MkExpErr("the data is not a serialized Stat"),
+		},
+		{
+			ID:   testhelper.MkID("bad version"),
+			data: []byte(serialMagic + "\x63"),
+			ExpErr: testhelper.MkExpErr(
+				"unsupported Stat serialization version: 99"),
+		},
+		{
+			ID:     testhelper.MkID("truncated"),
+			data:   good.Bytes()[:good.Len()-3],
+			ExpErr: testhelper.MkExpErr("cannot load the Stat", "EOF"),
+		},
+	}
+
+	for _, tc := range testCases {
+		var loaded Stat
+		err := loaded.Load(bytes.NewReader(tc.data))
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestGob(t *testing.T) {
+	s := NewStatOrPanic("units", StatCacheSize(10))
+	addSeq(s, 1.0, 1.0, 25)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		t.Fatal("Couldn't gob-encode the Stat:", err)
+	}
+
+	var loaded Stat
+	if err := gob.NewDecoder(&buf).Decode(&loaded); err != nil {
+		t.Fatal("Couldn't gob-decode the Stat:", err)
+	}
+	testhelper.DiffString(t, "gob", "String", loaded.String(), s.String())
+}
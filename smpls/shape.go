@@ -0,0 +1,328 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// shapeMinCount is the smallest number of values for which the shape
+	// of the distribution is calculated
+	shapeMinCount = 4
+
+	// shapeAlpha is the significance level at which the tests of normality
+	// are judged
+	shapeAlpha = 0.05
+
+	// adCritical is the critical value of the adjusted Anderson-Darling
+	// statistic at the 5% significance level when the mean and standard
+	// deviation are estimated from the values
+	adCritical = 0.752
+
+	// modeMinFrac is the smallest height of a peak in the histogram, as a
+	// fraction of the highest peak, for it to count as a mode
+	modeMinFrac = 0.1
+	// modeDipFrac is the fraction of the lower of two peaks that the
+	// histogram must dip below between them for both to count as modes
+	modeDipFrac = 0.75
+)
+
+// ShapeReport describes the shape of the distribution of the values in a
+// Stat and reports simple tests of whether the values are normally
+// distributed and whether they have more than one mode. If the values are
+// far from normal, or have several modes, then the mean and standard
+// deviation may be a poor summary of them.
+//
+// Where all the values are still held (or the Stat has a reservoir) the
+// calculations use the values themselves. Otherwise they use the
+// histogram, taking the values in each bucket to be at the mean of the
+// bucket (or its middle if the bucket sums are not being recorded) and so
+// they are less accurate.
+type ShapeReport struct {
+	// Count is the number of values used in the calculations
+	Count int
+	// FromSample is true if the values themselves were used rather than
+	// the histogram
+	FromSample bool
+
+	// Skewness measures the asymmetry of the distribution; it is 0 for a
+	// symmetric distribution
+	Skewness float64
+	// ExcessKurtosis measures the weight of the tails of the distribution
+	// compared with a normal distribution; it is 0 for a normal
+	// distribution
+	ExcessKurtosis float64
+
+	// JarqueBera is the Jarque-Bera statistic and JarqueBeraP is the
+	// probability of a value at least this large if the values are
+	// normally distributed
+	JarqueBera  float64
+	JarqueBeraP float64
+	// AndersonDarling is the Anderson-Darling statistic, adjusted for the
+	// number of values. It is NaN unless the values themselves were used.
+	AndersonDarling float64
+
+	// BimodalityCoef is Sarle's bimodality coefficient; values above 5/9
+	// suggest that the distribution has more than one mode or is strongly
+	// skewed
+	BimodalityCoef float64
+	// Modes is the number of distinct peaks found in the histogram of the
+	// values
+	Modes int
+}
+
+// Normal returns true if none of the tests rejects the hypothesis that the
+// values are normally distributed at the 5% significance level
+func (sr ShapeReport) Normal() bool {
+	if sr.Count < shapeMinCount {
+		return false
+	}
+	if sr.JarqueBeraP < shapeAlpha {
+		return false
+	}
+	return math.IsNaN(sr.AndersonDarling) || sr.AndersonDarling < adCritical
+}
+
+// Multimodal returns true if more than one mode has been found. Note that
+// the bimodality coefficient is not used as it is also large for strongly
+// skewed distributions.
+func (sr ShapeReport) Multimodal() bool {
+	return sr.Modes > 1
+}
+
+// String returns a description of the shape of the distribution
+func (sr ShapeReport) String() string {
+	if sr.Count < shapeMinCount {
+		return fmt.Sprintf("too few values (%d) to describe the shape",
+			sr.Count)
+	}
+
+	source := "histogram"
+	if sr.FromSample {
+		source = "values"
+	}
+	str := fmt.Sprintf("from %d %s: skewness: %.3g, excess kurtosis: %.3g\n",
+		sr.Count, source, sr.Skewness, sr.ExcessKurtosis)
+	str += fmt.Sprintf("Jarque-Bera: %.3g (p: %.3g)", sr.JarqueBera,
+		sr.JarqueBeraP)
+	if !math.IsNaN(sr.AndersonDarling) {
+		str += fmt.Sprintf(", Anderson-Darling: %.3g", sr.AndersonDarling)
+	}
+	str += fmt.Sprintf("\nbimodality coefficient: %.3g, modes: %d\n",
+		sr.BimodalityCoef, sr.Modes)
+
+	switch {
+	case sr.Multimodal():
+		str += "the values may have more than one mode;" +
+			" the mean and SD may be misleading"
+	case sr.Normal():
+		str += "the values are consistent with a normal distribution"
+	default:
+		str += "the values are probably not normally distributed"
+	}
+	return str + "\n"
+}
+
+// weightedVal is a value with a weight
+type weightedVal struct {
+	val    float64
+	weight float64
+}
+
+// shapeMoments returns the skewness and excess kurtosis of the weighted
+// values
+func shapeMoments(vals []weightedVal) (skew, kurt float64) {
+	var n, sum float64
+	for _, wv := range vals {
+		n += wv.weight
+		sum += wv.weight * wv.val
+	}
+	mean := sum / n
+
+	var m2, m3, m4 float64
+	for _, wv := range vals {
+		d := wv.val - mean
+		d2 := d * d
+		m2 += wv.weight * d2
+		m3 += wv.weight * d2 * d
+		m4 += wv.weight * d2 * d2
+	}
+	m2, m3, m4 = m2/n, m3/n, m4/n
+	if m2 == 0 {
+		return 0, 0
+	}
+	return m3 / math.Pow(m2, 1.5), m4/(m2*m2) - 3
+}
+
+// andersonDarling returns the Anderson-Darling statistic, adjusted for the
+// sample size, of the sorted values against a normal distribution with
+// the mean and standard deviation of the values
+func andersonDarling(sorted []float64) float64 {
+	n := len(sorted)
+	mean := calcMean(sorted)
+	sd := calcSD(sorted) * math.Sqrt(float64(n)/float64(n-1))
+	if sd == 0 {
+		return math.Inf(1)
+	}
+
+	normCDF := func(x float64) float64 {
+		return 0.5 * math.Erfc(-(x-mean)/(sd*math.Sqrt2))
+	}
+
+	var sum float64
+	for i, v := range sorted {
+		lo := math.Max(normCDF(v), math.SmallestNonzeroFloat64)
+		hi := math.Max(1-normCDF(sorted[n-1-i]), math.SmallestNonzeroFloat64)
+		sum += float64(2*i+1) * (math.Log(lo) + math.Log(hi))
+	}
+	fn := float64(n)
+	a2 := -fn - sum/fn
+	return a2 * (1 + 0.75/fn + 2.25/(fn*fn))
+}
+
+// countModes returns the number of distinct peaks in the counts. The counts
+// are smoothed and then only peaks of a reasonable height which are
+// separated by a clear dip are counted.
+func countModes(counts []int) int {
+	if len(counts) == 0 {
+		return 0
+	}
+
+	smoothed := make([]float64, len(counts))
+	highest := 0.0
+	for i := range counts {
+		lo, hi := max(0, i-1), min(len(counts)-1, i+1)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			sum += float64(counts[j])
+		}
+		smoothed[i] = sum / float64(hi-lo+1)
+		highest = math.Max(highest, smoothed[i])
+	}
+	if highest == 0 {
+		return 0
+	}
+
+	modes := 0
+	var lastPeak, dip float64
+	for i, v := range smoothed {
+		dip = math.Min(dip, v)
+		isPeak := (i == 0 || v > smoothed[i-1]) &&
+			(i == len(smoothed)-1 || v >= smoothed[i+1])
+		if !isPeak || v < modeMinFrac*highest {
+			continue
+		}
+		switch {
+		case modes == 0 || dip <= modeDipFrac*math.Min(lastPeak, v):
+			modes++
+			lastPeak, dip = v, v
+		case v > lastPeak:
+			lastPeak, dip = v, v
+		}
+	}
+	return modes
+}
+
+// sampleCounts returns the counts of the sorted values in a simple
+// histogram
+func sampleCounts(sorted []float64) []int {
+	n := len(sorted)
+	buckets := max(minHistBucketCount, min(dfltHistBucketCount, n/5))
+	lo, hi := sorted[0], sorted[n-1]
+	counts := make([]int, buckets)
+	if hi == lo {
+		counts[0] = n
+		return counts
+	}
+	width := (hi - lo) / float64(buckets)
+	for _, v := range sorted {
+		counts[min(buckets-1, int((v-lo)/width))]++
+	}
+	return counts
+}
+
+// shapeSample returns the values to use when calculating the shape of the
+// distribution, sorted, or nil if the values are not available
+func (s Stat) shapeSample() []float64 {
+	if vals := s.retained(); vals != nil {
+		return sortedCopy(vals)
+	}
+	if s.reservoir != nil && len(s.reservoir.vals) >= shapeMinCount {
+		return sortedCopy(s.reservoir.vals)
+	}
+	return nil
+}
+
+// histWeightedVals returns the populated segments of the histogram as
+// weighted values
+func (s Stat) histWeightedVals() []weightedVal {
+	segs := []histSegment{s.underflowSegment()}
+	for i := range s.hist {
+		segs = append(segs, s.bucketSegment(i))
+	}
+	segs = append(segs, s.overflowSegment())
+
+	vals := []weightedVal{}
+	for _, seg := range segs {
+		if seg.count == 0 {
+			continue
+		}
+		vals = append(vals, weightedVal{
+			val:    seg.knownSum() / float64(seg.count),
+			weight: float64(seg.count),
+		})
+	}
+	return vals
+}
+
+// ShapeReport returns a ShapeReport describing the shape of the
+// distribution of the values. If there are too few values, or the Stat
+// has neither the values nor a histogram, the statistics are NaN.
+func (s Stat) ShapeReport() ShapeReport {
+	sr := ShapeReport{
+		Skewness:        math.NaN(),
+		ExcessKurtosis:  math.NaN(),
+		JarqueBera:      math.NaN(),
+		JarqueBeraP:     math.NaN(),
+		AndersonDarling: math.NaN(),
+		BimodalityCoef:  math.NaN(),
+	}
+
+	var vals []weightedVal
+	var counts []int
+	if sorted := s.shapeSample(); sorted != nil {
+		sr.FromSample = true
+		sr.Count = len(sorted)
+		if sr.Count < shapeMinCount {
+			return sr
+		}
+		for _, v := range sorted {
+			vals = append(vals, weightedVal{val: v, weight: 1})
+		}
+		sr.AndersonDarling = andersonDarling(sorted)
+		counts = sampleCounts(sorted)
+	} else if s.hist != nil {
+		hs := s.histStat()
+		sr.Count = hs.count
+		if sr.Count < shapeMinCount {
+			return sr
+		}
+		vals = hs.histWeightedVals()
+		counts = slices.Clone(hs.hist)
+	} else {
+		sr.Count = s.count
+		return sr
+	}
+
+	n := float64(sr.Count)
+	sr.Skewness, sr.ExcessKurtosis = shapeMoments(vals)
+	sr.JarqueBera = n / 6 *
+		(sr.Skewness*sr.Skewness + sr.ExcessKurtosis*sr.ExcessKurtosis/4)
+	sr.JarqueBeraP = math.Exp(-sr.JarqueBera / 2)
+	sr.BimodalityCoef = (sr.Skewness*sr.Skewness + 1) /
+		(sr.ExcessKurtosis + 3*(n-1)*(n-1)/((n-2)*(n-3)))
+	sr.Modes = countModes(counts)
+
+	return sr
+}
@@ -0,0 +1,129 @@
+package smpls
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestShapeReport(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	normal := func() float64 { return 100 + 10*r.NormFloat64() }
+	bimodal := func() float64 {
+		if r.Intn(2) == 0 {
+			return 10 + r.NormFloat64()
+		}
+		return 20 + r.NormFloat64()
+	}
+	exponential := func() float64 { return r.ExpFloat64() }
+
+	testCases := []struct {
+		testhelper.ID
+		opts          []StatOpt
+		gen           func() float64
+		n             int
+		expFromSample bool
+		expNormal     bool
+		expMultimodal bool
+	}{
+		{
+			ID:            testhelper.MkID("normal, values"),
+			gen:           normal,
+			n:             2000,
+			expFromSample: true,
+			expNormal:     true,
+		},
+		{
+			ID:        testhelper.MkID("normal, histogram"),
+			opts:      []StatOpt{StatCacheSize(500)},
+			gen:       normal,
+			n:         5000,
+			expNormal: true,
+		},
+		{
+			ID:            testhelper.MkID("normal, reservoir"),
+			opts:          []StatOpt{StatNoHist(), StatReservoirSize(1000)},
+			gen:           normal,
+			n:             5000,
+			expFromSample: true,
+			expNormal:     true,
+		},
+		{
+			ID:            testhelper.MkID("bimodal, values"),
+			gen:           bimodal,
+			n:             2000,
+			expFromSample: true,
+			expMultimodal: true,
+		},
+		{
+			ID:            testhelper.MkID("bimodal, histogram"),
+			opts:          []StatOpt{StatCacheSize(500)},
+			gen:           bimodal,
+			n:             5000,
+			expMultimodal: true,
+		},
+		{
+			ID:            testhelper.MkID("exponential, values"),
+			gen:           exponential,
+			n:             2000,
+			expFromSample: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", tc.opts...)
+		for i := 0; i < tc.n; i++ {
+			s.Add(tc.gen())
+		}
+		sr := s.ShapeReport()
+		testhelper.DiffBool(t, tc.IDStr(), "from sample",
+			sr.FromSample, tc.expFromSample)
+		testhelper.DiffBool(t, tc.IDStr(), "normal", sr.Normal(), tc.expNormal)
+		testhelper.DiffBool(t, tc.IDStr(), "multimodal",
+			sr.Multimodal(), tc.expMultimodal)
+		if t.Failed() {
+			t.Log(sr)
+		}
+	}
+}
+
+func TestShapeReportFewValues(t *testing.T) {
+	s := NewStatOrPanic("units")
+	s.Add(1, 2, 3)
+	sr := s.ShapeReport()
+	testhelper.DiffInt(t, "few values", "count", sr.Count, 3)
+	testhelper.DiffBool(t, "few values", "NaN skewness",
+		math.IsNaN(sr.Skewness), true)
+	testhelper.DiffBool(t, "few values", "normal", sr.Normal(), false)
+	testhelper.DiffBool(t, "few values", "string",
+		strings.HasPrefix(sr.String(), "too few values (3)"), true)
+}
+
+func TestCountModes(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		counts []int
+		exp    int
+	}{
+		{ID: testhelper.MkID("empty"), counts: []int{0, 0, 0}, exp: 0},
+		{ID: testhelper.MkID("one"), counts: []int{1, 4, 9, 4, 1}, exp: 1},
+		{
+			ID:     testhelper.MkID("two"),
+			counts: []int{1, 8, 9, 8, 1, 0, 0, 1, 8, 9, 8, 1},
+			exp:    2,
+		},
+		{
+			ID:     testhelper.MkID("noise"),
+			counts: []int{1, 8, 9, 8, 9, 8, 9, 8, 1},
+			exp:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffInt(t, tc.IDStr(), "modes", countModes(tc.counts),
+			tc.exp)
+	}
+}
@@ -0,0 +1,218 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// cacheLineSize is the assumed size of a CPU cache line. Each shard of a
+// ShardedStat is padded to this size so that shards in use by different
+// goroutines do not share a cache line.
+const cacheLineSize = 64
+
+// statShard is one shard of a ShardedStat
+type statShard struct {
+	mu sync.Mutex
+	s  *Stat
+	_  [cacheLineSize - 16]byte
+}
+
+// ShardedStat records statistics from many goroutines at once. The values
+// are spread across a number of shards, each holding its own Stat with its
+// own lock, so that goroutines adding values rarely wait for one another.
+// The shards are merged into a single Stat only when the statistics are
+// read (see Stat).
+//
+// Unlike a Stat, this is safe for concurrent use by multiple goroutines.
+type ShardedStat struct {
+	units  string
+	opts   []StatOpt
+	shards []statShard
+	next   atomic.Uint64
+}
+
+// NewShardedStat creates a new ShardedStat with the given number of shards.
+// A shard count of 0 gives one shard per available processor (see
+// runtime.GOMAXPROCS). The units and options are used when creating the
+// Stat for each shard and when creating the merged Stat. The options must
+// not include a starting summary (see StatFromSummary) since each shard
+// would start from it. Nor can they include Accumulators (see
+// StatWithAccumulator), a raw value writer (see StatRawValues) or a sample
+// store (see StatSampleStore) since these would be shared by all the
+// shards and written to by several goroutines at once. Any transform (see
+// StatTransform) must be safe for concurrent use.
+func NewShardedStat(units string, shards int, opts ...StatOpt,
+) (*ShardedStat, error) {
+	if shards < 0 {
+		return nil, invalidValue("Invalid shard count (%d) - it must be >= 0",
+			shards)
+	}
+	if shards == 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	ss := &ShardedStat{
+		units:  units,
+		opts:   opts,
+		shards: make([]statShard, shards),
+	}
+	for i := range ss.shards {
+		s, err := NewStat(units, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if s.Count() != 0 {
+			return nil, errors.New(
+				"a ShardedStat cannot start from a summary")
+		}
+		if err := s.checkShardable("a ShardedStat", "shards"); err != nil {
+			return nil, err
+		}
+		ss.shards[i].s = s
+	}
+	return ss, nil
+}
+
+// checkShardable returns an error if the Stat holds state given in its
+// options which would be shared with the other Stats built from the same
+// options and updated concurrently, as are the shards of a ShardedStat.
+// The user names what is built and the parts are what would share it.
+func (s Stat) checkShardable(user, parts string) error {
+	var shared string
+	switch {
+	case s.accs != nil:
+		shared = "have Accumulators - they would"
+	case s.raw != nil:
+		shared = "write raw values - the writer would"
+	case s.store != nil:
+		shared = "have a sample store - it would"
+	default:
+		return nil
+	}
+	return conflictingOptions(
+		fmt.Sprintf("%s cannot %s be shared by the %s", user, shared, parts))
+}
+
+// NewShardedStatOrPanic creates a new ShardedStat and will panic if any
+// errors are detected
+func NewShardedStatOrPanic(units string, shards int, opts ...StatOpt,
+) *ShardedStat {
+	ss, err := NewShardedStat(units, shards, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+// lockShard locks and returns a shard. The shards are tried in turn,
+// starting from the next in a round-robin, and the first one which is not
+// locked is taken. If they are all locked it waits for the first one
+// tried.
+func (ss *ShardedStat) lockShard() *statShard {
+	n := uint64(len(ss.shards))
+	start := ss.next.Add(1)
+	for i := range n {
+		sh := &ss.shards[(start+i)%n]
+		if sh.mu.TryLock() {
+			return sh
+		}
+	}
+
+	sh := &ss.shards[start%n]
+	sh.mu.Lock()
+	return sh
+}
+
+// Add adds at least one new value to the ShardedStat. All the values are
+// added to the same shard.
+func (ss *ShardedStat) Add(v float64, vals ...float64) {
+	sh := ss.lockShard()
+	sh.s.Add(v, vals...)
+	sh.mu.Unlock()
+}
+
+// AddSlice adds the values to the ShardedStat. All the values are added to
+// the same shard.
+func (ss *ShardedStat) AddSlice(vals []float64) {
+	sh := ss.lockShard()
+	sh.s.AddSlice(vals)
+	sh.mu.Unlock()
+}
+
+// Units returns the units of the ShardedStat
+func (ss *ShardedStat) Units() string {
+	return ss.units
+}
+
+// Shards returns the number of shards
+func (ss *ShardedStat) Shards() int {
+	return len(ss.shards)
+}
+
+// Count returns the number of values that have been added
+func (ss *ShardedStat) Count() int {
+	count := 0
+	for i := range ss.shards {
+		sh := &ss.shards[i]
+		sh.mu.Lock()
+		count += sh.s.Count()
+		sh.mu.Unlock()
+	}
+	return count
+}
+
+// Stat returns a new Stat holding the values from all the shards. Each
+// shard is locked in turn while it is merged and so values may be added
+// while the merged Stat is being built. The merged Stat is independent of
+// the ShardedStat and is not changed by later values. Note that the
+// histogram of the merged Stat is only an approximation if the shards
+// have different histogram layouts (see Merge).
+func (ss *ShardedStat) Stat() (*Stat, error) {
+	merged, err := NewStat(ss.units, ss.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ss.shards {
+		sh := &ss.shards[i]
+		sh.mu.Lock()
+		err := merged.Merge(sh.s)
+		sh.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// StatOrPanic returns a new Stat holding the values from all the shards
+// and will panic if any errors are detected. See Stat.
+func (ss *ShardedStat) StatOrPanic() *Stat {
+	s, err := ss.Stat()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Reset discards the values in all the shards
+func (ss *ShardedStat) Reset() {
+	for i := range ss.shards {
+		sh := &ss.shards[i]
+		sh.mu.Lock()
+		sh.s.Reset()
+		sh.mu.Unlock()
+	}
+}
+
+// String returns a string summarising the values from all the shards
+func (ss *ShardedStat) String() string {
+	s, err := ss.Stat()
+	if err != nil {
+		return err.Error()
+	}
+	return s.String()
+}
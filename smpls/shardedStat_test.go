@@ -0,0 +1,129 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewShardedStat(t *testing.T) {
+	ss, err := NewShardedStat("ms", 0)
+	testhelper.CheckExpErrWithID(t, "default shards", err,
+		testhelper.ExpErr{})
+	if ss.Shards() < 1 {
+		t.Errorf("unexpected shard count: %d", ss.Shards())
+	}
+
+	_, err = NewShardedStat("ms", -1)
+	testhelper.CheckExpErrWithID(t, "bad shards", err,
+		testhelper.MkExpErr("Invalid shard count (-1) - it must be >= 0"))
+
+	_, err = NewShardedStat("ms", 2, StatNoHist(),
+		StatFromSummary(1, 1, 1, 1, 1))
+	testhelper.CheckExpErrWithID(t, "summary", err,
+		testhelper.MkExpErr("a ShardedStat cannot start from a summary"))
+
+	for _, tc := range []struct {
+		name   string
+		opt    StatOpt
+		expErr string
+	}{
+		{
+			name:   "accumulator",
+			opt:    StatWithAccumulator(&counter{}),
+			expErr: "a ShardedStat cannot have Accumulators",
+		},
+		{
+			name:   "raw values",
+			opt:    StatRawValues(io.Discard, 1, RawCSV),
+			expErr: "a ShardedStat cannot write raw values",
+		},
+		{
+			name:   "sample store",
+			opt:    StatSampleStore(NewMemStore(0)),
+			expErr: "a ShardedStat cannot have a sample store",
+		},
+	} {
+		_, err = NewShardedStat("ms", 2, tc.opt)
+		testhelper.CheckExpErrWithID(t, tc.name, err,
+			testhelper.MkExpErr(tc.expErr))
+		if !errors.Is(err, ErrConflictingOptions) {
+			t.Log(tc.name)
+			t.Errorf("\t: the error should be an ErrConflictingOptions\n")
+		}
+	}
+
+	testhelper.DiffInt(t, "shard", "size",
+		int(unsafe.Sizeof(statShard{})), cacheLineSize)
+}
+
+func TestShardedStat(t *testing.T) {
+	const (
+		workers = 64
+		perWork = 1000
+	)
+	ss := NewShardedStatOrPanic("ms", 8, StatCacheSize(100))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWork; i++ {
+				ss.Add(float64(w*perWork + i))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	const total = workers * perWork
+	testhelper.DiffInt(t, "ShardedStat", "count", ss.Count(), total)
+
+	s := ss.StatOrPanic()
+	testhelper.DiffInt(t, "merged", "count", s.Count(), total)
+	testhelper.DiffFloat(t, "merged", "min", s.Min(), 0, 0)
+	testhelper.DiffFloat(t, "merged", "max", s.Max(), total-1, 0)
+	testhelper.DiffFloat(t, "merged", "mean", s.Mean(), (total-1)/2.0, 1e-9)
+	testhelper.DiffString(t, "merged", "units", s.Units(), ss.Units())
+
+	ss.AddSlice([]float64{1, 2, 3})
+	testhelper.DiffInt(t, "AddSlice", "count", ss.Count(), total+3)
+
+	ss.Reset()
+	testhelper.DiffInt(t, "Reset", "count", ss.Count(), 0)
+}
+
+// TestShardedStatParallel adds values from many goroutines at once to a
+// ShardedStat with options giving each shard state of its own. Run it with
+// the race detector (go test -race) to check that no state is shared.
+func TestShardedStatParallel(t *testing.T) {
+	ss := NewShardedStatOrPanic("ms", 4,
+		StatRate(), StatTrackFreq(10), StatHeavyHitters(5),
+		StatApproxMedian(5), StatReservoirSize(10), StatTrackRuns(50),
+		StatAutocorrelation(2), StatWarmup(3),
+		StatTransform(func(v float64) float64 { return v * 2 }))
+
+	t.Run("adders", func(t *testing.T) {
+		for w := range 8 {
+			t.Run(fmt.Sprintf("adder %d", w), func(t *testing.T) {
+				t.Parallel()
+				for i := range 500 {
+					ss.Add(float64(i))
+				}
+			})
+		}
+		t.Run("reader", func(t *testing.T) {
+			t.Parallel()
+			for range 20 {
+				_ = ss.String()
+			}
+		})
+	})
+
+	testhelper.DiffInt(t, "parallel", "count", ss.Count(), 8*500-4*3)
+}
@@ -0,0 +1,101 @@
+package smpls
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	minShareBarWidth  = 1
+	dfltShareBarWidth = 40
+)
+
+// Share gives the sum of the values of a Stat and that sum as a percentage
+// of the total of the sums of a collection of Stats
+type Share struct {
+	Name string
+	Sum  float64
+	Pct  float64
+}
+
+// shares returns the Share of each of the named Stats, in the same order.
+// It returns an error if the Stats do not all have the same units, since
+// their sums could not then be added.
+func shares(stats []NamedStat) ([]Share, error) {
+	total := 0.0
+	for _, ns := range stats {
+		if ns.Stat.units != stats[0].Stat.units {
+			return nil, fmt.Errorf(
+				"the Stats have different units (%q and %q)"+
+					" so their shares cannot be calculated",
+				stats[0].Stat.units, ns.Stat.units)
+		}
+		total += ns.Stat.Sum()
+	}
+
+	sh := make([]Share, 0, len(stats))
+	for _, ns := range stats {
+		share := Share{Name: ns.Name, Sum: ns.Stat.Sum()}
+		if total != 0 {
+			share.Pct = 100 * share.Sum / total
+		}
+		sh = append(sh, share)
+	}
+	return sh, nil
+}
+
+// Shares returns the sum of each Stat in the StatSet as a percentage of the
+// total of all their sums, largest first (and then by name). This shows,
+// for instance, where the time went if each Stat records the time spent in
+// a phase of some work. It returns an error if the Stats do not all have
+// the same units. The percentages are all zero if the total is zero.
+func (ss StatSet) Shares() ([]Share, error) {
+	stats := make([]NamedStat, 0, len(ss.stats))
+	for _, name := range ss.Names() {
+		stats = append(stats, NamedStat{Name: name, Stat: ss.stats[name]})
+	}
+	sh, err := shares(stats)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(sh, func(a, b Share) int {
+		return cmp.Compare(b.Sum, a.Sum)
+	})
+	return sh, nil
+}
+
+// shareBar returns a bar of the given width scaled to the percentage. A
+// negative share gives no bar.
+func shareBar(pct float64, width int, r rune) string {
+	n := int(math.Round(math.Max(0, math.Min(100, pct)) / 100 * float64(width)))
+	return strings.Repeat(string(r), n)
+}
+
+// ReportShareOfSum returns a function that will add columns to the Report,
+// after the other values, giving the sum of each Stat, that sum as a
+// percentage of the total of all the sums and a bar of up to the given
+// width (or a default width if 0 is given) showing the percentage. If
+// largestFirst is true the rows are sorted with the largest share first,
+// taking precedence over ReportSortBy. This gives a profile-style report
+// of, for instance, where the time went. The Report cannot be written
+// unless all the Stats have the same units.
+func ReportShareOfSum(barWidth int, largestFirst bool) ReportOpt {
+	return func(r *Report) error {
+		if r.shareBarWidth != 0 {
+			return repeatedOption("the share of the sum is already shown")
+		}
+		if barWidth == 0 {
+			barWidth = dfltShareBarWidth
+		}
+		if barWidth < minShareBarWidth {
+			return invalidValue("Invalid bar width (%d) - it must be >= %d",
+				barWidth, minShareBarWidth)
+		}
+		r.shareBarWidth = barWidth
+		r.shareFirst = largestFirst
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package smpls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestShares(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	_ = ss.Add("parse", 10)
+	_ = ss.Add("plan", 20)
+	_ = ss.Add("exec", 50)
+	_ = ss.Add("exec", 20)
+
+	sh, err := ss.Shares()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	exp := []Share{
+		{Name: "exec", Sum: 70, Pct: 70},
+		{Name: "plan", Sum: 20, Pct: 20},
+		{Name: "parse", Sum: 10, Pct: 10},
+	}
+	if err := testhelper.DiffVals(sh, exp); err != nil {
+		t.Errorf("bad shares: %v\n", err)
+	}
+
+	r := NewReportOrPanic(
+		ReportFormat(FmtOpts{Fields: []StatField{FieldCount}, Style: FmtFixed}),
+		ReportShareOfSum(10, true))
+	r.AddStatSet(ss)
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testhelper.DiffString(t, "Report", "share of sum", b.String(),
+		"name   units  observations   sum  share\n"+
+			"exec   ms                2  70.0  70.0%  *******\n"+
+			"plan   ms                1  20.0  20.0%  **\n"+
+			"parse  ms                1  10.0  10.0%  *\n")
+
+	_ = ss.Register("size", NewStatOrPanic("bytes"))
+	_, err = ss.Shares()
+	testhelper.CheckExpErrWithID(t, "mixed units", err,
+		testhelper.MkExpErr("the Stats have different units"))
+	r = NewReportOrPanic(ReportShareOfSum(0, false))
+	r.AddStatSet(ss)
+	testhelper.CheckExpErrWithID(t, "Report - mixed units", r.Write(&b),
+		testhelper.MkExpErr("the Stats have different units"))
+
+	_, err = NewReport(ReportShareOfSum(-1, false))
+	testhelper.CheckExpErrWithID(t, "bad width", err,
+		testhelper.MkExpErr("Invalid bar width (-1) - it must be >= 1"))
+	_, err = NewReport(ReportShareOfSum(5, false), ReportShareOfSum(5, true))
+	testhelper.CheckExpErrWithID(t, "repeated", err,
+		testhelper.MkExpErr("the share of the sum is already shown"))
+}
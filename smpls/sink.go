@@ -0,0 +1,109 @@
+package smpls
+
+import (
+	"slices"
+)
+
+const minSinkBatchSize = 1
+
+// Sink receives the values recorded by a Stat (see Subscribe). The slice
+// passed to Send is reused once Send returns and so the Sink must copy any
+// values it wants to keep.
+type Sink interface {
+	Send(vals []float64)
+}
+
+// SinkFunc is a function which can be used as a Sink
+type SinkFunc func(vals []float64)
+
+// Send calls the function with the values
+func (f SinkFunc) Send(vals []float64) {
+	f(vals)
+}
+
+// ChanSink returns a Sink which sends a copy of each batch of values on
+// the channel. Note that sending blocks until the channel can accept the
+// batch and so adding values to the Stat will block too.
+func ChanSink(ch chan<- []float64) Sink {
+	return SinkFunc(func(vals []float64) {
+		ch <- slices.Clone(vals)
+	})
+}
+
+// sinkSub records a Sink subscribed to a Stat and the values not yet sent
+// to it
+type sinkSub struct {
+	sink  Sink
+	batch []float64
+}
+
+// add records the value and sends the batch of values to the Sink if it
+// is full
+func (ss *sinkSub) add(v float64) {
+	ss.batch = append(ss.batch, v)
+	if len(ss.batch) == cap(ss.batch) {
+		ss.flush()
+	}
+}
+
+// flush sends any values not yet sent to the Sink
+func (ss *sinkSub) flush() {
+	if len(ss.batch) == 0 {
+		return
+	}
+	ss.sink.Send(ss.batch)
+	ss.batch = ss.batch[:0]
+}
+
+// Subscribe adds the Sink to the Stat. Every value recorded by the Stat
+// is also sent to the Sink, in batches of the given size; pass 1 to send
+// each value as it is added. This lets another component see the stream of
+// values, for instance to write them to a trace file or across the
+// network, while the Stat still collects statistics from them. As with the
+// Accumulators (see StatWithAccumulator) only the values recorded are sent
+// so, for instance, if the Stat has a sample rate (see StatSampleRate) only
+// the sampled values are sent.
+//
+// It returns a function which will send any values not yet sent to the
+// Sink and then remove it from the Stat. FlushSinks should be called once
+// all the values have been added so that the Sinks receive any partly
+// filled batches. The Sinks are not subscribed to copies of the Stat (see
+// Clone) but values added to a Stat created by Fork are sent to the Sinks
+// of the parent Stat.
+func (s *Stat) Subscribe(sink Sink, batchSize int) (func(), error) {
+	if sink == nil {
+		return nil, invalidValue("the Sink must not be nil")
+	}
+	if batchSize < minSinkBatchSize {
+		return nil, invalidValue(
+			"Invalid Sink batch size (%d) - it must be >= %d",
+			batchSize, minSinkBatchSize)
+	}
+
+	sub := &sinkSub{
+		sink:  sink,
+		batch: make([]float64, 0, batchSize),
+	}
+	s.sinks = append(s.sinks, sub)
+	s.hot = false
+
+	return func() {
+		i := slices.Index(s.sinks, sub)
+		if i < 0 {
+			return
+		}
+		sub.flush()
+		s.sinks = slices.Delete(s.sinks, i, i+1)
+		if len(s.sinks) == 0 {
+			s.sinks = nil
+		}
+	}, nil
+}
+
+// FlushSinks sends any values not yet sent to the subscribed Sinks (see
+// Subscribe)
+func (s *Stat) FlushSinks() {
+	for _, ss := range s.sinks {
+		ss.flush()
+	}
+}
@@ -0,0 +1,85 @@
+package smpls
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSubscribe(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10))
+
+	var batches [][]float64
+	unsubscribe, err := s.Subscribe(SinkFunc(func(vals []float64) {
+		batches = append(batches, slices.Clone(vals))
+	}), 4)
+	if err != nil {
+		t.Fatal("Couldn't subscribe:", err)
+	}
+	var each []float64
+	_, err = s.Subscribe(SinkFunc(func(vals []float64) {
+		each = append(each, vals...)
+	}), 1)
+	if err != nil {
+		t.Fatal("Couldn't subscribe:", err)
+	}
+
+	// enough values for the Stat to switch to the hot path
+	addSeq(s, 1, 1, 30)
+	testhelper.DiffInt(t, "batched", "batches", len(batches), 7)
+	testhelper.DiffInt(t, "each", "values", len(each), 30)
+	testhelper.DiffInt(t, "Stat", "count", s.Count(), 30)
+
+	s.FlushSinks()
+	testhelper.DiffInt(t, "flushed", "batches", len(batches), 8)
+	err = testhelper.DiffVals(batches[7], []float64{29, 30})
+	if err != nil {
+		t.Error("flushed: the last batch differs:", err)
+	}
+
+	s.Add(31, 32)
+	unsubscribe()
+	s.Add(33)
+	unsubscribe() // a second call does nothing
+	testhelper.DiffInt(t, "unsubscribed", "batches", len(batches), 9)
+	testhelper.DiffInt(t, "unsubscribed", "values", len(each), 33)
+
+	clone := s.Clone()
+	clone.Add(34)
+	testhelper.DiffInt(t, "clone", "values", len(each), 33)
+
+	child := s.Fork()
+	child.Add(35)
+	testhelper.DiffInt(t, "fork", "values", len(each), 34)
+}
+
+func TestChanSink(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	ch := make(chan []float64, 2)
+	if _, err := s.Subscribe(ChanSink(ch), 2); err != nil {
+		t.Fatal("Couldn't subscribe:", err)
+	}
+	s.Add(1, 2, 3)
+	s.FlushSinks()
+
+	err := testhelper.DiffVals(<-ch, []float64{1, 2})
+	if err != nil {
+		t.Error("first batch differs:", err)
+	}
+	err = testhelper.DiffVals(<-ch, []float64{3})
+	if err != nil {
+		t.Error("second batch differs:", err)
+	}
+}
+
+func TestSubscribeErrs(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	_, err := s.Subscribe(nil, 1)
+	testhelper.DiffBool(t, "nil Sink", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+	_, err = s.Subscribe(SinkFunc(func([]float64) {}), 0)
+	testhelper.DiffBool(t, "zero batch size", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+}
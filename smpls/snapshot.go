@@ -0,0 +1,207 @@
+package smpls
+
+import (
+	"maps"
+	"math"
+)
+
+// snapshotPercentiles are the percentiles recorded in a Snapshot
+var snapshotPercentiles = []float64{25, 50, 75, 90, 99}
+
+// Bucket records the number of values in a histogram bucket. The bucket
+// holds values at or above Low and below High. The Sum of the values in the
+// bucket is only set if the Stat records it (see StatHistBucketSums).
+type Bucket struct {
+	Low   float64
+	High  float64
+	Count int
+	Sum   float64
+}
+
+// Pctile records the value below which the given percentage of the values
+// fall
+type Pctile struct {
+	Pct float64
+	Val float64
+}
+
+// Snapshot holds the values of a Stat at a point in time. It has no
+// connection to the Stat it was taken from and so it can be freely passed
+// between goroutines and serialized.
+type Snapshot struct {
+	Units string
+
+	Count   int
+	Sum     float64
+	SumSq   float64
+	Min     float64
+	MeanMin float64
+	Mean    float64
+	StdDev  float64
+	Max     float64
+	MeanMax float64
+
+	// SampleStdDev is the sample standard deviation (see SampleStdDev).
+	// StdDev is the population standard deviation unless the Stat uses
+	// the Bessel correction (see StatBesselCorrection).
+	SampleStdDev float64
+
+	Percentiles []Pctile
+
+	Underflow int
+	Buckets   []Bucket
+	Overflow  int
+
+	// Meta holds the metadata recorded with the Stat (see SetMeta)
+	Meta map[string]string
+}
+
+// Snapshot returns a Snapshot of the current state of the Stat. Note that
+// while the cache is still being populated the histogram buckets are
+// provisional and will change once the cache is full.
+func (s Stat) Snapshot() Snapshot {
+	snap := Snapshot{
+		Units: s.units,
+		Sum:   s.Sum(),
+		SumSq: s.fullSumSq() * s.sampleScale(),
+
+		SampleStdDev: s.SampleStdDev(),
+
+		Meta: s.Meta(),
+	}
+	snap.Min, snap.MeanMin, snap.Mean, snap.StdDev,
+		snap.Max, snap.MeanMax, snap.Count = s.vals()
+
+	if s.count == 0 {
+		return snap
+	}
+
+	for _, p := range snapshotPercentiles {
+		snap.Percentiles = append(snap.Percentiles,
+			Pctile{Pct: p, Val: s.Percentile(p)})
+	}
+
+	s = s.histStat()
+	if s.hist == nil {
+		return snap
+	}
+	snap.Underflow = s.underflow
+	snap.Overflow = s.overflow
+	snap.Buckets = make([]Bucket, 0, len(s.hist))
+	for i, count := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		bkt := Bucket{Low: lo, High: hi, Count: count}
+		if s.histSums != nil {
+			bkt.Sum = s.histSums[i]
+		}
+		snap.Buckets = append(snap.Buckets, bkt)
+	}
+
+	return snap
+}
+
+// Percentile returns the value of the given percentile and true if it is
+// recorded in the Snapshot. Otherwise it returns 0.0 and false.
+func (snap Snapshot) Percentile(p float64) (float64, bool) {
+	for _, pv := range snap.Percentiles {
+		if pv.Pct == p {
+			return pv.Val, true
+		}
+	}
+	return 0.0, false
+}
+
+// fieldVal returns the value of the field recorded in the Snapshot or NaN
+// if it is not recorded
+func (snap Snapshot) fieldVal(f StatField) float64 {
+	pctile := func(p float64) float64 {
+		if v, ok := snap.Percentile(p); ok {
+			return v
+		}
+		return math.NaN()
+	}
+
+	switch f {
+	case FieldCount:
+		return float64(snap.Count)
+	case FieldMin:
+		return snap.Min
+	case FieldMeanMin:
+		return snap.MeanMin
+	case FieldMean:
+		return snap.Mean
+	case FieldMax:
+		return snap.Max
+	case FieldMeanMax:
+		return snap.MeanMax
+	case FieldStdDev:
+		return snap.StdDev
+	case FieldSampleStdDev:
+		return snap.SampleStdDev
+	case FieldMedian:
+		return pctile(50)
+	case FieldP90:
+		return pctile(90)
+	case FieldP99:
+		return pctile(99)
+	}
+	return math.NaN()
+}
+
+// sameBuckets returns true if the two slices of buckets have the same
+// boundaries
+func sameBuckets(a, b []Bucket) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, bkt := range a {
+		if bkt.Low != b[i].Low || bkt.High != b[i].High {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffSnapshots returns a Snapshot holding the change in each value from
+// the prev Snapshot to the cur Snapshot (cur - prev). Percentiles are only
+// given for those recorded in both Snapshots. Bucket counts are only given
+// if both Snapshots have the same bucket boundaries. The units are those of
+// the cur Snapshot.
+func DiffSnapshots(prev, cur Snapshot) Snapshot {
+	diff := Snapshot{
+		Units: cur.Units,
+
+		Count:   cur.Count - prev.Count,
+		Sum:     cur.Sum - prev.Sum,
+		SumSq:   cur.SumSq - prev.SumSq,
+		Min:     cur.Min - prev.Min,
+		MeanMin: cur.MeanMin - prev.MeanMin,
+		Mean:    cur.Mean - prev.Mean,
+		StdDev:  cur.StdDev - prev.StdDev,
+		Max:     cur.Max - prev.Max,
+		MeanMax: cur.MeanMax - prev.MeanMax,
+
+		SampleStdDev: cur.SampleStdDev - prev.SampleStdDev,
+
+		Meta: maps.Clone(cur.Meta),
+	}
+
+	for _, pv := range cur.Percentiles {
+		if prevVal, ok := prev.Percentile(pv.Pct); ok {
+			diff.Percentiles = append(diff.Percentiles,
+				Pctile{Pct: pv.Pct, Val: pv.Val - prevVal})
+		}
+	}
+
+	if sameBuckets(prev.Buckets, cur.Buckets) {
+		diff.Underflow = cur.Underflow - prev.Underflow
+		diff.Overflow = cur.Overflow - prev.Overflow
+		for i, bkt := range cur.Buckets {
+			bkt.Count -= prev.Buckets[i].Count
+			bkt.Sum -= prev.Buckets[i].Sum
+			diff.Buckets = append(diff.Buckets, bkt)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,131 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+)
+
+// SnapshotDelta returns a Snapshot describing just the values added to a
+// Stat between the prev and cur Snapshots of it. This lets a cumulative
+// Stat which is scraped periodically report the behaviour in each interval.
+// Unlike DiffSnapshots, which gives the change in each value, this gives
+// the statistics of the values added in the interval.
+//
+// The Count, Sum and SumSq are exact and the Mean and standard deviations
+// are calculated from them; the standard deviations may be inaccurate if
+// the spread of the values is very small compared with their mean. The
+// bucket counts (and sums) are only given if both Snapshots have the same
+// bucket boundaries, in which case the percentiles are estimated from
+// them, as by HistPercentile, and the Min and Max are the limits of the
+// lowest and highest buckets used (or the Min or Max of the cur Snapshot
+// if values have gone into the underflow or overflow). Otherwise the
+// percentiles are not given and the Min and Max are NaN. The MeanMin and
+// MeanMax are always NaN.
+//
+// An error is returned if the Snapshots have different units or if the cur
+// Snapshot has fewer values than the prev Snapshot, as happens if the Stat
+// is Reset between them.
+func SnapshotDelta(prev, cur Snapshot) (Snapshot, error) {
+	if prev.Units != cur.Units {
+		return Snapshot{}, fmt.Errorf(
+			"the Snapshots have different units: %q and %q",
+			prev.Units, cur.Units)
+	}
+	if cur.Count < prev.Count {
+		return Snapshot{}, errors.New(
+			"the cur Snapshot has fewer values than the prev Snapshot" +
+				" - the Stat may have been reset")
+	}
+
+	delta := Snapshot{
+		Units:   cur.Units,
+		Count:   cur.Count - prev.Count,
+		Sum:     cur.Sum - prev.Sum,
+		SumSq:   cur.SumSq - prev.SumSq,
+		Min:     math.NaN(),
+		MeanMin: math.NaN(),
+		Max:     math.NaN(),
+		MeanMax: math.NaN(),
+		Meta:    maps.Clone(cur.Meta),
+	}
+	if delta.Count == 0 {
+		delta.Sum, delta.SumSq = 0, 0
+		return delta, nil
+	}
+
+	n := float64(delta.Count)
+	delta.Mean = delta.Sum / n
+	sqDev := math.Max(0, delta.SumSq-delta.Sum*delta.Mean)
+	delta.StdDev = math.Sqrt(sqDev / n)
+	if delta.Count > 1 {
+		delta.SampleStdDev = math.Sqrt(sqDev / (n - 1))
+	}
+
+	if !sameBuckets(prev.Buckets, cur.Buckets) || len(cur.Buckets) == 0 {
+		return delta, nil
+	}
+	delta.Underflow = cur.Underflow - prev.Underflow
+	delta.Overflow = cur.Overflow - prev.Overflow
+	for i, bkt := range cur.Buckets {
+		bkt.Count -= prev.Buckets[i].Count
+		bkt.Sum -= prev.Buckets[i].Sum
+		delta.Buckets = append(delta.Buckets, bkt)
+	}
+	delta.Min, delta.Max = delta.bucketRange(cur.Min, cur.Max)
+	for _, p := range snapshotPercentiles {
+		delta.Percentiles = append(delta.Percentiles,
+			Pctile{Pct: p, Val: delta.bucketPercentile(p)})
+	}
+
+	return delta, nil
+}
+
+// bucketRange returns the lowest and highest values which might be in the
+// buckets of the Snapshot. The given minimum and maximum are used for any
+// values in the underflow or overflow.
+func (snap Snapshot) bucketRange(minVal, maxVal float64) (float64, float64) {
+	lo, hi := minVal, maxVal
+	if snap.Underflow == 0 {
+		for _, bkt := range snap.Buckets {
+			if bkt.Count > 0 {
+				lo = math.Max(lo, bkt.Low)
+				break
+			}
+		}
+	}
+	if snap.Overflow == 0 {
+		for i := len(snap.Buckets) - 1; i >= 0; i-- {
+			if snap.Buckets[i].Count > 0 {
+				hi = math.Min(hi, snap.Buckets[i].High)
+				break
+			}
+		}
+	}
+	return lo, hi
+}
+
+// bucketPercentile estimates the given percentile from the bucket counts,
+// taking the values in each bucket to be evenly spread across it.
+// Percentiles in the underflow or overflow give the Min or Max.
+func (snap Snapshot) bucketPercentile(p float64) float64 {
+	target := p / 100.0 * float64(snap.Count)
+	if target <= float64(snap.Underflow) && snap.Underflow > 0 {
+		return snap.Min
+	}
+
+	cum := float64(snap.Underflow)
+	for _, bkt := range snap.Buckets {
+		if bkt.Count <= 0 {
+			continue
+		}
+		if target <= cum+float64(bkt.Count) {
+			frac := (target - cum) / float64(bkt.Count)
+			v := bkt.Low + frac*(bkt.High-bkt.Low)
+			return math.Min(snap.Max, math.Max(snap.Min, v))
+		}
+		cum += float64(bkt.Count)
+	}
+	return snap.Max
+}
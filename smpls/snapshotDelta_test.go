@@ -0,0 +1,62 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSnapshotDelta(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(10))
+	addSeq(s, 0.5, 1, 20)
+	prev := s.Snapshot()
+
+	interval := NewStatOrPanic("ms", StatNoHist())
+	for _, v := range []float64{4.5, 5.5, 6.5, 7.5} {
+		s.Add(v)
+		interval.Add(v)
+	}
+	cur := s.Snapshot()
+
+	delta, err := SnapshotDelta(prev, cur)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	id := "SnapshotDelta"
+	testhelper.DiffInt(t, id, "count", delta.Count, 4)
+	testhelper.DiffFloat(t, id, "sum", delta.Sum, 24, 0)
+	testhelper.DiffFloat(t, id, "mean", delta.Mean, 6, 0)
+	testhelper.DiffFloat(t, id, "SD", delta.StdDev, interval.StdDev(), 1e-9)
+	testhelper.DiffFloat(t, id, "sample SD",
+		delta.SampleStdDev, interval.SampleStdDev(), 1e-9)
+	testhelper.DiffInt(t, id, "bucket count", len(delta.Buckets), 10)
+	total := delta.Underflow + delta.Overflow
+	for _, b := range delta.Buckets {
+		total += b.Count
+	}
+	testhelper.DiffInt(t, id, "total bucket count", total, 4)
+	testhelper.DiffBool(t, id, "min <= 4.5", delta.Min <= 4.5, true)
+	testhelper.DiffBool(t, id, "max >= 7.5", delta.Max >= 7.5, true)
+	p50, ok := delta.Percentile(50)
+	testhelper.DiffBool(t, id, "has p50", ok, true)
+	testhelper.DiffFloat(t, id, "p50", p50, 6, 1.0)
+	testhelper.DiffBool(t, id, "mean min is NaN",
+		math.IsNaN(delta.MeanMin), true)
+
+	empty, err := SnapshotDelta(cur, cur)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testhelper.DiffInt(t, "no change", "count", empty.Count, 0)
+	testhelper.DiffFloat(t, "no change", "mean", empty.Mean, 0, 0)
+
+	_, err = SnapshotDelta(cur, prev)
+	testhelper.CheckExpErrWithID(t, "reversed", err,
+		testhelper.MkExpErr("the Stat may have been reset"))
+	other := cur
+	other.Units = "s"
+	_, err = SnapshotDelta(prev, other)
+	testhelper.CheckExpErrWithID(t, "units", err,
+		testhelper.MkExpErr(`the Snapshots have different units: "ms" and "s"`))
+}
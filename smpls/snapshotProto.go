@@ -0,0 +1,371 @@
+package smpls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The protocol buffer wire types used by the Snapshot messages. See
+// snapshot.proto for the message definitions.
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+	pbFixed32 = 5
+)
+
+// The field numbers of the Snapshot message
+const (
+	pbSnapUnits = iota + 1
+	pbSnapCount
+	pbSnapSum
+	pbSnapSumSq
+	pbSnapMin
+	pbSnapMeanMin
+	pbSnapMean
+	pbSnapStdDev
+	pbSnapMax
+	pbSnapMeanMax
+	pbSnapPercentiles
+	pbSnapUnderflow
+	pbSnapBuckets
+	pbSnapOverflow
+	pbSnapMeta
+	pbSnapSampleStdDev
+)
+
+// The field numbers of the map entry messages used for the metadata
+const (
+	pbMetaKey = iota + 1
+	pbMetaValue
+)
+
+// The field numbers of the Pctile message
+const (
+	pbPctilePct = iota + 1
+	pbPctileVal
+)
+
+// The field numbers of the Bucket message
+const (
+	pbBucketLow = iota + 1
+	pbBucketHigh
+	pbBucketCount
+	pbBucketSum
+)
+
+// pbEncoder builds a protocol buffer message. As in proto3, fields with
+// the zero value are not written.
+type pbEncoder struct {
+	b []byte
+}
+
+// tag writes the tag of a field
+func (e *pbEncoder) tag(field, wireType int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field<<3|wireType))
+}
+
+// int writes an int64 field
+func (e *pbEncoder) int(field, v int) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, pbVarint)
+	e.b = binary.AppendUvarint(e.b, uint64(int64(v)))
+}
+
+// float writes a double field
+func (e *pbEncoder) float(field int, v float64) {
+	bits := math.Float64bits(v)
+	if bits == 0 {
+		return
+	}
+	e.tag(field, pbFixed64)
+	e.b = binary.LittleEndian.AppendUint64(e.b, bits)
+}
+
+// bytes writes a length-delimited field. Unlike the other field types it
+// is always written so that empty elements of repeated fields are kept.
+func (e *pbEncoder) bytes(field int, v []byte) {
+	e.tag(field, pbBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(v)))
+	e.b = append(e.b, v...)
+}
+
+// str writes a string field
+func (e *pbEncoder) str(field int, v string) {
+	if v == "" {
+		return
+	}
+	e.bytes(field, []byte(v))
+}
+
+// pbField is a single field read from a protocol buffer message
+type pbField struct {
+	num      int
+	wireType int
+	u        uint64
+	b        []byte
+}
+
+// checkWireType returns an error if the field does not have the given wire
+// type
+func (pf pbField) checkWireType(wireType int) error {
+	if pf.wireType != wireType {
+		return fmt.Errorf("field %d has wire type %d, expected %d",
+			pf.num, pf.wireType, wireType)
+	}
+	return nil
+}
+
+// int returns the value of an int64 field
+func (pf pbField) int() (int, error) {
+	return int(int64(pf.u)), pf.checkWireType(pbVarint)
+}
+
+// float returns the value of a double field
+func (pf pbField) float() (float64, error) {
+	return math.Float64frombits(pf.u), pf.checkWireType(pbFixed64)
+}
+
+// str returns the value of a string field
+func (pf pbField) str() (string, error) {
+	return string(pf.b), pf.checkWireType(pbBytes)
+}
+
+// pbParse reads the fields of the protocol buffer message in turn and
+// passes each to f. Reading stops at the first error.
+func pbParse(b []byte, f func(pbField) error) error {
+	uvarint := func() (uint64, error) {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, errors.New("bad varint")
+		}
+		b = b[n:]
+		return v, nil
+	}
+	fixed := func(size int) (uint64, error) {
+		if len(b) < size {
+			return 0, errors.New("truncated fixed-size field")
+		}
+		var v uint64
+		if size == 8 {
+			v = binary.LittleEndian.Uint64(b)
+		} else {
+			v = uint64(binary.LittleEndian.Uint32(b))
+		}
+		b = b[size:]
+		return v, nil
+	}
+
+	for len(b) > 0 {
+		tag, err := uvarint()
+		if err != nil {
+			return err
+		}
+		pf := pbField{num: int(tag >> 3), wireType: int(tag & 0x7)}
+		if pf.num == 0 {
+			return errors.New("bad field number: 0")
+		}
+
+		switch pf.wireType {
+		case pbVarint:
+			pf.u, err = uvarint()
+		case pbFixed64:
+			pf.u, err = fixed(8)
+		case pbFixed32:
+			pf.u, err = fixed(4)
+		case pbBytes:
+			var l uint64
+			l, err = uvarint()
+			if err == nil && l > uint64(len(b)) {
+				err = errors.New("truncated length-delimited field")
+			}
+			if err == nil {
+				pf.b, b = b[:l], b[l:]
+			}
+		default:
+			err = fmt.Errorf("unsupported wire type: %d", pf.wireType)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := f(pf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalProto returns the Snapshot encoded as a Snapshot protocol buffer
+// message (see snapshot.proto) so that it can be passed to programs
+// written in other languages.
+func (snap Snapshot) MarshalProto() ([]byte, error) {
+	var e pbEncoder
+	e.str(pbSnapUnits, snap.Units)
+	e.int(pbSnapCount, snap.Count)
+	e.float(pbSnapSum, snap.Sum)
+	e.float(pbSnapSumSq, snap.SumSq)
+	e.float(pbSnapMin, snap.Min)
+	e.float(pbSnapMeanMin, snap.MeanMin)
+	e.float(pbSnapMean, snap.Mean)
+	e.float(pbSnapStdDev, snap.StdDev)
+	e.float(pbSnapMax, snap.Max)
+	e.float(pbSnapMeanMax, snap.MeanMax)
+
+	for _, p := range snap.Percentiles {
+		var pe pbEncoder
+		pe.float(pbPctilePct, p.Pct)
+		pe.float(pbPctileVal, p.Val)
+		e.bytes(pbSnapPercentiles, pe.b)
+	}
+
+	e.int(pbSnapUnderflow, snap.Underflow)
+	for _, bkt := range snap.Buckets {
+		var be pbEncoder
+		be.float(pbBucketLow, bkt.Low)
+		be.float(pbBucketHigh, bkt.High)
+		be.int(pbBucketCount, bkt.Count)
+		be.float(pbBucketSum, bkt.Sum)
+		e.bytes(pbSnapBuckets, be.b)
+	}
+	e.int(pbSnapOverflow, snap.Overflow)
+
+	for _, k := range sortedMetaKeys(snap.Meta) {
+		var me pbEncoder
+		me.str(pbMetaKey, k)
+		me.str(pbMetaValue, snap.Meta[k])
+		e.bytes(pbSnapMeta, me.b)
+	}
+	e.float(pbSnapSampleStdDev, snap.SampleStdDev)
+
+	return e.b, nil
+}
+
+// unmarshalPctile decodes a Pctile protocol buffer message
+func unmarshalPctile(b []byte) (Pctile, error) {
+	var p Pctile
+	err := pbParse(b, func(pf pbField) error {
+		var err error
+		switch pf.num {
+		case pbPctilePct:
+			p.Pct, err = pf.float()
+		case pbPctileVal:
+			p.Val, err = pf.float()
+		}
+		return err
+	})
+	return p, err
+}
+
+// unmarshalBucket decodes a Bucket protocol buffer message
+func unmarshalBucket(b []byte) (Bucket, error) {
+	var bkt Bucket
+	err := pbParse(b, func(pf pbField) error {
+		var err error
+		switch pf.num {
+		case pbBucketLow:
+			bkt.Low, err = pf.float()
+		case pbBucketHigh:
+			bkt.High, err = pf.float()
+		case pbBucketCount:
+			bkt.Count, err = pf.int()
+		case pbBucketSum:
+			bkt.Sum, err = pf.float()
+		}
+		return err
+	})
+	return bkt, err
+}
+
+// unmarshalMetaEntry decodes a metadata map entry message
+func unmarshalMetaEntry(b []byte) (string, string, error) {
+	var k, v string
+	err := pbParse(b, func(pf pbField) error {
+		var err error
+		switch pf.num {
+		case pbMetaKey:
+			k, err = pf.str()
+		case pbMetaValue:
+			v, err = pf.str()
+		}
+		return err
+	})
+	return k, v, err
+}
+
+// UnmarshalProto sets the Snapshot from a Snapshot protocol buffer message
+// (see snapshot.proto). Fields which are not known are ignored so that
+// messages from programs using a later version of the message can still
+// be read. The Snapshot is unchanged if an error is returned.
+func (snap *Snapshot) UnmarshalProto(b []byte) error {
+	var ns Snapshot
+	err := pbParse(b, func(pf pbField) error {
+		var err error
+		switch pf.num {
+		case pbSnapUnits:
+			ns.Units, err = pf.str()
+		case pbSnapCount:
+			ns.Count, err = pf.int()
+		case pbSnapSum:
+			ns.Sum, err = pf.float()
+		case pbSnapSumSq:
+			ns.SumSq, err = pf.float()
+		case pbSnapMin:
+			ns.Min, err = pf.float()
+		case pbSnapMeanMin:
+			ns.MeanMin, err = pf.float()
+		case pbSnapMean:
+			ns.Mean, err = pf.float()
+		case pbSnapStdDev:
+			ns.StdDev, err = pf.float()
+		case pbSnapMax:
+			ns.Max, err = pf.float()
+		case pbSnapMeanMax:
+			ns.MeanMax, err = pf.float()
+		case pbSnapPercentiles:
+			if err = pf.checkWireType(pbBytes); err != nil {
+				return err
+			}
+			var p Pctile
+			if p, err = unmarshalPctile(pf.b); err == nil {
+				ns.Percentiles = append(ns.Percentiles, p)
+			}
+		case pbSnapUnderflow:
+			ns.Underflow, err = pf.int()
+		case pbSnapBuckets:
+			if err = pf.checkWireType(pbBytes); err != nil {
+				return err
+			}
+			var bkt Bucket
+			if bkt, err = unmarshalBucket(pf.b); err == nil {
+				ns.Buckets = append(ns.Buckets, bkt)
+			}
+		case pbSnapOverflow:
+			ns.Overflow, err = pf.int()
+		case pbSnapMeta:
+			if err = pf.checkWireType(pbBytes); err != nil {
+				return err
+			}
+			var k, v string
+			if k, v, err = unmarshalMetaEntry(pf.b); err == nil {
+				if ns.Meta == nil {
+					ns.Meta = map[string]string{}
+				}
+				ns.Meta[k] = v
+			}
+		case pbSnapSampleStdDev:
+			ns.SampleStdDev, err = pf.float()
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal the Snapshot: %w", err)
+	}
+
+	*snap = ns
+	return nil
+}
@@ -0,0 +1,150 @@
+package smpls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSnapshotProtoRoundTrip(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts  []StatOpt
+		count int
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:    testhelper.MkID("cache only"),
+			opts:  []StatOpt{StatCacheSize(100)},
+			count: 50,
+		},
+		{
+			ID: testhelper.MkID("with histogram and sums"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistBucketCount(5),
+				StatHistBucketSums(),
+			},
+			count: 100,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("ms", tc.opts...)
+		addSeq(s, -5, 0.5, tc.count)
+		snap := s.Snapshot()
+
+		b, err := snap.MarshalProto()
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error marshalling: %v\n", err)
+			continue
+		}
+
+		var got Snapshot
+		if err := got.UnmarshalProto(b); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error unmarshalling: %v\n", err)
+			continue
+		}
+		if err := testhelper.DiffVals(got, snap); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the unmarshalled Snapshot differs: %v\n", err)
+		}
+	}
+}
+
+func TestSnapshotProtoBytes(t *testing.T) {
+	snap := Snapshot{
+		Units:       "ms",
+		Count:       3,
+		Sum:         1.0,
+		Percentiles: []Pctile{{Pct: 50}},
+		Underflow:   -1,
+	}
+	exp := []byte{
+		0x0a, 0x02, 'm', 's', // units
+		0x10, 0x03, // count
+		0x19, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, // sum
+		0x5a, 0x09, // percentiles
+		0x09, 0, 0, 0, 0, 0, 0, 0x49, 0x40, // pct
+		0x60, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+	}
+
+	b, err := snap.MarshalProto()
+	if err != nil {
+		t.Fatal("unexpected error marshalling:", err)
+	}
+	if !bytes.Equal(b, exp) {
+		t.Errorf("bad encoding:\n\t: got: % x\n\t: exp: % x\n", b, exp)
+	}
+}
+
+func TestSnapshotUnmarshalProto(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		data []byte
+		exp  Snapshot
+	}{
+		{
+			ID: testhelper.MkID("unknown fields are skipped"),
+			data: []byte{
+				0x0a, 0x02, 'm', 's', // units
+				0xa0, 0x06, 0x05, // field 100, varint
+				0xaa, 0x06, 0x01, 'x', // field 101, bytes
+				0xb5, 0x06, 1, 2, 3, 4, // field 102, fixed32
+				0x10, 0x07, // count
+			},
+			exp: Snapshot{Units: "ms", Count: 7},
+		},
+		{
+			ID:   testhelper.MkID("truncated string"),
+			data: []byte{0x0a, 0x05, 'm', 's'},
+			ExpErr: testhelper.MkExpErr("cannot unmarshal the Snapshot",
+				"truncated length-delimited field"),
+		},
+		{
+			ID:   testhelper.MkID("truncated double"),
+			data: []byte{0x19, 0, 0, 0},
+			ExpErr: testhelper.MkExpErr("cannot unmarshal the Snapshot",
+				"truncated fixed-size field"),
+		},
+		{
+			ID:   testhelper.MkID("bad wire type"),
+			data: []byte{0x18, 0x01},
+			ExpErr: testhelper.MkExpErr("cannot unmarshal the Snapshot",
+				"field 3 has wire type 0, expected 1"),
+		},
+		{
+			ID:   testhelper.MkID("bad bucket"),
+			data: []byte{0x6a, 0x02, 0x18, 0x80},
+			ExpErr: testhelper.MkExpErr("cannot unmarshal the Snapshot",
+				"bad varint"),
+		},
+		{
+			ID:   testhelper.MkID("bad field number"),
+			data: []byte{0x00, 0x01},
+			ExpErr: testhelper.MkExpErr("cannot unmarshal the Snapshot",
+				"bad field number: 0"),
+		},
+	}
+
+	for _, tc := range testCases {
+		snap := Snapshot{Units: "unchanged"}
+		err := snap.UnmarshalProto(tc.data)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			if err := testhelper.DiffVals(snap, tc.exp); err != nil {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: %v\n", err)
+			}
+		}
+		if err != nil {
+			testhelper.DiffString(t, tc.IDStr(), "units on error",
+				snap.Units, "unchanged")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5))
+	addSeq(s, 1, 1, 10)
+
+	snap := s.Snapshot()
+	id := "Snapshot"
+	testhelper.DiffString(t, id, "units", snap.Units, "ms")
+	testhelper.DiffInt(t, id, "count", snap.Count, 10)
+	testhelper.DiffFloat(t, id, "sum", snap.Sum, 55, 0.0)
+	testhelper.DiffFloat(t, id, "mean", snap.Mean, 5.5, 0.0)
+	testhelper.DiffFloat(t, id, "SD", snap.StdDev, s.StdDev(), 0.0)
+	testhelper.DiffFloat(t, id, "min", snap.Min, 1, 0.0)
+	testhelper.DiffFloat(t, id, "max", snap.Max, 10, 0.0)
+	testhelper.DiffInt(t, id, "bucket count", len(snap.Buckets), 5)
+	p50, ok := snap.Percentile(50)
+	testhelper.DiffBool(t, id, "has p50", ok, true)
+	testhelper.DiffFloat(t, id, "p50", p50, s.Percentile(50), 0.0)
+	_, ok = snap.Percentile(42)
+	testhelper.DiffBool(t, id, "has p42", ok, false)
+
+	total := snap.Underflow + snap.Overflow
+	for _, b := range snap.Buckets {
+		total += b.Count
+	}
+	testhelper.DiffInt(t, id, "total bucket count", total, 10)
+
+	s.Add(1, 2, 3)
+	later := s.Snapshot()
+	testhelper.DiffInt(t, id, "original count", snap.Count, 10)
+
+	diff := DiffSnapshots(snap, later)
+	id = "DiffSnapshots"
+	testhelper.DiffInt(t, id, "count", diff.Count, 3)
+	testhelper.DiffFloat(t, id, "sum", diff.Sum, 6, 0.0)
+	testhelper.DiffFloat(t, id, "mean", diff.Mean, 61.0/13.0-5.5, 0.00001)
+	testhelper.DiffFloat(t, id, "max", diff.Max, 0, 0.0)
+	testhelper.DiffInt(t, id, "bucket count", len(diff.Buckets), 5)
+	total = diff.Underflow + diff.Overflow
+	for _, b := range diff.Buckets {
+		total += b.Count
+	}
+	testhelper.DiffInt(t, id, "total bucket count", total, 3)
+
+	empty := NewStatOrPanic("ms").Snapshot()
+	testhelper.DiffInt(t, "empty", "bucket count", len(empty.Buckets), 0)
+	testhelper.DiffInt(t, "empty", "percentile count",
+		len(empty.Percentiles), 0)
+}
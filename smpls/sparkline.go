@@ -0,0 +1,84 @@
+package smpls
+
+import (
+	"math"
+	"strings"
+)
+
+// sparkRunes are the characters used to show the height of each column of
+// a sparkline, from lowest to highest
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// sparkNegligible is the proportion of the tallest column below which a
+// column is shown as empty. This stops the slivers of buckets which overlap
+// a column by a rounding error from showing as values.
+const sparkNegligible = 1e-4
+
+// Sparkline returns the histogram as a single line of Unicode block
+// characters, the height of each showing the proportion of values in that
+// part of the range from the minimum to the maximum value. The width gives
+// the number of characters; if it is less than 1 there is one character
+// per histogram bucket. Parts of the range with no (or a negligible number
+// of) values are shown as spaces. The values in each bucket, and in the
+// underflow and overflow, are taken to be evenly spread across it. It
+// returns the empty string if no values have been added or the Stat has no
+// histogram.
+func (s Stat) Sparkline(width int) string {
+	if s.count == 0 || s.hist == nil {
+		return ""
+	}
+	s = s.histStat()
+	if width < 1 {
+		width = len(s.hist)
+	}
+
+	cols := s.sparkCols(width)
+	maxCount := 0.0
+	for _, c := range cols {
+		maxCount = math.Max(maxCount, c)
+	}
+
+	var b strings.Builder
+	for _, c := range cols {
+		if c <= maxCount*sparkNegligible {
+			b.WriteRune(' ')
+			continue
+		}
+		level := int(math.Ceil(c/maxCount*float64(len(sparkRunes)))) - 1
+		b.WriteRune(sparkRunes[min(max(level, 0), len(sparkRunes)-1)])
+	}
+	return b.String()
+}
+
+// sparkCols returns the number of values in each of width equal parts of
+// the range from the minimum to the maximum value. The histogram must be
+// populated.
+func (s Stat) sparkCols(width int) []float64 {
+	cols := make([]float64, width)
+	lo, hi := s.Min(), s.Max()
+	colWidth := (hi - lo) / float64(width)
+
+	colIdx := func(v float64) int {
+		if colWidth <= 0 {
+			return 0
+		}
+		return min(max(int((v-lo)/colWidth), 0), width-1)
+	}
+
+	for _, seg := range s.histSegments() {
+		segLo, segHi := math.Max(seg.lo, lo), math.Min(seg.hi, hi)
+		if segHi <= segLo || colWidth <= 0 {
+			cols[colIdx(segLo)] += float64(seg.count)
+			continue
+		}
+		perUnit := float64(seg.count) / (segHi - segLo)
+		for i := colIdx(segLo); i <= colIdx(segHi); i++ {
+			cLo := lo + float64(i)*colWidth
+			overlap := math.Min(segHi, cLo+colWidth) - math.Max(segLo, cLo)
+			if overlap > 0 {
+				cols[i] += perUnit * overlap
+			}
+		}
+	}
+	return cols
+}
@@ -0,0 +1,76 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSparkline(t *testing.T) {
+	rising := NewStatOrPanic("units", StatHistBucketCount(8))
+	for i := 1; i <= 8; i++ {
+		for j := 0; j < i*10; j++ {
+			rising.Add(float64(i-1) + 0.5)
+		}
+	}
+
+	gap := NewStatOrPanic("units", StatHistBucketCount(5))
+	gap.Add(0, 0, 10, 10)
+
+	single := NewStatOrPanic("units")
+	single.Add(5, 5, 5)
+
+	testCases := []struct {
+		testhelper.ID
+		s      *Stat
+		width  int
+		expVal string
+	}{
+		{
+			ID:     testhelper.MkID("empty"),
+			s:      NewStatOrPanic("units"),
+			width:  10,
+			expVal: "",
+		},
+		{
+			ID:     testhelper.MkID("no hist"),
+			s:      NewStatOrPanic("units", StatNoHist()),
+			width:  10,
+			expVal: "",
+		},
+		{
+			ID:     testhelper.MkID("rising"),
+			s:      rising,
+			width:  8,
+			expVal: "▁▂▃▄▅▆▇█",
+		},
+		{
+			ID:     testhelper.MkID("rising - one per bucket"),
+			s:      rising,
+			expVal: "▁▂▃▄▅▆▇█",
+		},
+		{
+			ID:     testhelper.MkID("rising - narrow"),
+			s:      rising,
+			width:  4,
+			expVal: "▂▄▆█",
+		},
+		{
+			ID:     testhelper.MkID("gap"),
+			s:      gap,
+			width:  5,
+			expVal: "█   █",
+		},
+		{
+			ID:     testhelper.MkID("single value"),
+			s:      single,
+			width:  3,
+			expVal: "█  ",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "sparkline",
+			tc.s.Sparkline(tc.width), tc.expVal)
+	}
+}
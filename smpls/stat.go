@@ -1,13 +1,11 @@
 package smpls
 
 import (
-	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
-	"strings"
-
-	"github.com/nickwells/mathutil.mod/v2/mathutil"
+	"text/template"
 )
 
 // Created: Thu Aug  6 13:01:57 2020
@@ -46,15 +44,93 @@ type Stat struct {
 	mins  []float64
 	maxs  []float64
 
-	cache []float64
+	minInfo []any
+	maxInfo []any
+
+	compensated bool
+	sumC        float64
+	sumSqC      float64
+
+	cache    []float64
+	cacheBuf []float64 // the storage for the cache, reused after a Reset
 
 	underflow   int
 	hist        []int
 	overflow    int
 	bucketStart float64
 	bucketWidth float64
+	logBase     float64
+	anchor      float64
+	anchored    bool
+	niceBounds  bool
+
+	quantileLayout bool
+	knots          []float64 // the bucket boundaries of a quantile layout
+
+	underflowSum float64
+	histSums     []float64
+	overflowSum  float64
 
 	histSizeChosen bool
+	noCache        bool
+	noHist         bool
+	rebinFraction  float64
+	rebinHist      []int
+	rebinSums      []float64
+	unitPrefixes   bool
+	trackInfo      bool
+	bucketSums     bool
+
+	sampleRate float64
+	calls      int
+	sampled    int
+
+	hot bool
+
+	// adds counts the values added to the Stat, including those from a
+	// forked Stat or merged into it; unlike the count it is never reset
+	// so it shows whether the Stat is in use (see SetTTL)
+	adds int
+
+	seed      *Snapshot
+	maxMemory int
+
+	history    []Snapshot
+	historyLen int
+
+	trackChange bool
+	lastCycle   *Snapshot
+
+	meta map[string]string
+
+	rate      *Rate
+	freq      *freqTracker
+	heavy     *heavyHitters
+	remedian  *remedian
+	distinct  *Cardinality
+	reservoir *reservoir
+	runs      *runTracker
+	deadband  *deadband
+	warmup    *warmup
+	autocorr  *autocorrTracker
+	exactLim  int
+	bessel    bool
+	emptyNaN  bool
+	raw       *rawWriter
+	store     *storeWriter
+	sinks     []*sinkSub
+	valid     *validRange
+	tails     *tailTracker
+	expRange  *expectedRange
+	accs      []Accumulator
+	tw        timeWeight
+
+	transform func(float64) float64
+
+	strTmpl *template.Template
+	fmtOpts *FmtOpts
+
+	parent *Stat
 }
 
 // calcMean will calculate the average value of the entries in the slice
@@ -67,6 +143,17 @@ func calcMean(s []float64) float64 {
 	return sum / float64(len(s))
 }
 
+// calcSD will calculate the standard deviation of the entries in the slice
+// which must not be empty
+func calcSD(s []float64) float64 {
+	mean := calcMean(s)
+	var sumSq float64
+	for _, v := range s {
+		sumSq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sumSq / float64(len(s)))
+}
+
 // Vals returns the calculated values from the stat.
 //
 // The meanMin and meanMax values are the means of the smallest and largest N
@@ -78,31 +165,71 @@ func calcMean(s []float64) float64 {
 // other. The cache size can be changed when creating the Stat object by
 // passing the option function returned by StatMinMaxCount to the NewStat
 // function.
+//
+// If no values have been added the values are all 0.0 unless the Stat was
+// created with the StatEmptyNaN option in which case they are NaN.
 func (s Stat) Vals() (min, meanMin, avg, sd, max, meanMax float64, count int) {
+	if s.count == 0 {
+		min, meanMin, avg, sd, max, meanMax = s.emptyVals()
+		return
+	}
+	return s.vals()
+}
+
+// vals returns the calculated values from the stat. They are all 0.0 if no
+// values have been added.
+func (s Stat) vals() (min, meanMin, avg, sd, max, meanMax float64, count int) {
 	if s.count == 0 {
 		return
 	}
 	min = s.mins[0]
 	meanMin = calcMean(s.mins)
-	avg = s.sum / float64(s.count)
-	sd = 0
-	if s.count > 1 {
-		sd = math.Sqrt((s.sumSq / float64(s.count)) - (avg * avg))
-	}
+	avg, sd = s.meanSD()
 	max = s.maxs[len(s.maxs)-1]
 	meanMax = calcMean(s.maxs)
-	count = s.count
+	count = s.Count()
 	return
 }
 
-// Count returns the number of values that have been added
+// Units returns the units of the Stat
+func (s Stat) Units() string {
+	return s.units
+}
+
+// Count returns the number of values that have been added. If the Stat
+// has a sample rate (see StatSampleRate) this includes the values which
+// were not recorded.
 func (s Stat) Count() int {
+	if s.sampleRate > 0 {
+		return s.calls
+	}
 	return s.count
 }
 
-// Sum returns the sum of values that have been added
+// Sum returns the sum of values that have been added. If the Stat has a
+// sample rate (see StatSampleRate) this is estimated from the values which
+// were recorded.
 func (s Stat) Sum() float64 {
-	return s.sum
+	return s.fullSum() * s.sampleScale()
+}
+
+// fullSum returns the sum of values including any compensation for
+// rounding errors
+func (s Stat) fullSum() float64 {
+	return s.sum + s.sumC
+}
+
+// fullSumSq returns the sum of the squares of values including any
+// compensation for rounding errors
+func (s Stat) fullSumSq() float64 {
+	return s.sumSq + s.sumSqC
+}
+
+// Rate returns the Rate recording the rate at which values have been
+// added. It returns nil unless the Stat was created with the StatRate
+// option.
+func (s Stat) Rate() *Rate {
+	return s.rate
 }
 
 // Min returns the min of the collected values or 0.0 if no values have
@@ -123,6 +250,27 @@ func (s Stat) MeanMin() float64 {
 	return calcMean(s.mins)
 }
 
+// MinSpread returns the standard deviation of the N smallest collected
+// values (those averaged to give MeanMin) or 0.0 if no values have been
+// added. A small spread shows that the smallest values are tightly
+// clustered and so MeanMin is a good indicator of the lower limit of the
+// values; a large spread shows a long, varied tail.
+func (s Stat) MinSpread() float64 {
+	if s.count == 0 {
+		return 0.0
+	}
+	return calcSD(s.mins)
+}
+
+// MinRange returns the difference between the largest and smallest of the
+// N smallest collected values or 0.0 if no values have been added.
+func (s Stat) MinRange() float64 {
+	if s.count == 0 {
+		return 0.0
+	}
+	return s.mins[len(s.mins)-1] - s.mins[0]
+}
+
 // Max returns the max of the collected values or 0.0 if no values have
 // been added
 func (s Stat) Max() float64 {
@@ -141,28 +289,88 @@ func (s Stat) MeanMax() float64 {
 	return calcMean(s.maxs)
 }
 
-// Mean returns the mean of the collected values or 0.0 if no values have
-// been added
-func (s Stat) Mean() float64 {
+// MaxSpread returns the standard deviation of the N largest collected
+// values (those averaged to give MeanMax) or 0.0 if no values have been
+// added. A small spread shows that the largest values are tightly
+// clustered and so MeanMax is a good indicator of the upper limit of the
+// values; a large spread shows a long, varied tail.
+func (s Stat) MaxSpread() float64 {
 	if s.count == 0 {
 		return 0.0
 	}
-	return s.sum / float64(s.count)
+	return calcSD(s.maxs)
+}
+
+// MaxRange returns the difference between the largest and smallest of the
+// N largest collected values or 0.0 if no values have been added.
+func (s Stat) MaxRange() float64 {
+	if s.count == 0 {
+		return 0.0
+	}
+	return s.maxs[len(s.maxs)-1] - s.maxs[0]
+}
+
+// Mean returns the mean of the collected values or 0.0 if no values have
+// been added
+func (s Stat) Mean() float64 {
+	avg, _ := s.meanSD()
+	return avg
 }
 
 // StdDev returns the standard deviation of the collected values or 0.0 if
-// fewer than 2 values have been added
+// fewer than 2 values have been added. This is the population standard
+// deviation unless the Stat uses the Bessel correction (see
+// StatBesselCorrection).
 func (s Stat) StdDev() float64 {
+	_, sd := s.meanSD()
+	return sd
+}
+
+// PopulationStdDev returns the population standard deviation of the
+// collected values, which divides by n, or 0.0 if fewer than 2 values have
+// been added. This is the standard deviation of the values themselves.
+func (s Stat) PopulationStdDev() float64 {
 	if s.count < 2 {
 		return 0.0
 	}
+	_, variance := s.meanVar()
+	return math.Sqrt(variance)
+}
 
-	avg := s.sum / float64(s.count)
-	return math.Sqrt((s.sumSq / float64(s.count)) - (avg * avg))
+// SampleStdDev returns the sample standard deviation of the collected
+// values, which divides by n-1 (the Bessel correction), or 0.0 if fewer
+// than 2 values have been added. This is the usual estimate of the
+// standard deviation of the population from which the values are drawn
+// and is the one conventionally used in benchmark analysis.
+func (s Stat) SampleStdDev() float64 {
+	if s.count < 2 {
+		return 0.0
+	}
+	_, variance := s.meanVar()
+	return math.Sqrt(variance * float64(s.count) / float64(s.count-1))
 }
 
-// String prints the statistics from the given values
+// String prints the statistics from the given values. The format can be
+// changed by creating the Stat with the StatStringTemplate option; if the
+// template fails the default format is used. The StatFormat option gives
+// control over the fields shown and how the values are formatted. The
+// StatUnitPrefixes option will show the values with unit prefixes rather
+// than in scientific notation.
 func (s Stat) String() string {
+	if s.strTmpl != nil {
+		if str, err := s.RenderTemplate(s.strTmpl); err == nil {
+			return str
+		}
+	}
+
+	if s.fmtOpts != nil {
+		return s.StringWithOpts(*s.fmtOpts)
+	}
+
+	if s.unitPrefixes {
+		return s.prefixedString()
+	}
+
 	min, meanMin, avg, sd, max, meanMax, count := s.Vals()
 	return fmt.Sprintf(
 		"%7d observations,"+
@@ -175,54 +383,52 @@ func (s Stat) String() string {
 
 // Hist returns a string showing the histogram of values
 func (s Stat) Hist() string {
-	if s.count < cap(s.cache) {
-		s.populateHist()
-	}
+	return s.HistWithOpts(HistOpts{})
+}
 
-	if s.count < len(s.hist) {
-		return ""
+// histStat returns a copy of the Stat with the histogram populated. If the
+// cache is still in use the histogram is populated from it into a new slice
+// so that the original Stat is unaffected.
+func (s Stat) histStat() Stat {
+	if s.hist == nil || s.count == 0 {
+		return s
 	}
-
-	countFmt := fmt.Sprintf("%%%dd", mathutil.Digits(int64(s.count))) +
-		" %6.2f%% %s"
-
-	width, precision := mathutil.FmtValsForSigFigsMulti(3,
-		s.bucketStart,
-		s.bucketWidth,
-		s.bucketStart+s.bucketWidth*float64(len(s.hist)))
-	valFmt := fmt.Sprintf("%%%d.%df", width, precision)
-	valSpace := strings.Repeat(" ", width)
-	fromFmt := ">= " + valFmt
-	toFmt := "< " + valFmt
-
-	underflowFmt := valSpace + "      " + toFmt + ": %s\n"
-	overflowFmt := fromFmt + "     " + valSpace + ": %s\n"
-	stdFmt := fromFmt + " , " + toFmt + ": %s\n"
-
-	hist := "units: " + s.units + "\n"
-	hist += fmt.Sprintf(underflowFmt, s.bucketStart,
-		histValStr(s.underflow, s.count, countFmt))
-
-	minVal := s.bucketStart
-	maxVal := minVal + s.bucketWidth
-	for _, count := range s.hist {
-		hist += fmt.Sprintf(stdFmt, minVal, maxVal,
-			histValStr(count, s.count, countFmt))
-		minVal = maxVal
-		maxVal += s.bucketWidth
+	if pending := s.histPending(); pending != nil {
+		s.hist = make([]int, len(s.hist))
+		s.histSums = nil
+		if s.tails != nil {
+			s.tails = newTailTracker(s.tails.k)
+		}
+		s.layoutHist(pending)
+		s.cache = nil
 	}
+	return s
+}
 
-	hist += fmt.Sprintf(overflowFmt, minVal,
-		histValStr(s.overflow, s.count, countFmt))
-	return hist
+// retained returns all the values that have been added if they are still
+// held, either in the cache or in the slice of minimum values; the values
+// may not be in order. It returns nil if the values are no longer all held.
+func (s Stat) retained() []float64 {
+	if s.cache != nil {
+		return s.cache
+	}
+	if s.count <= cap(s.mins) {
+		return s.mins
+	}
+	return nil
 }
 
-// histValStr returns a string holding the formatted value. The value is
-// shown, followed by the value as a percentage of the total and a string of
-// stars corresponding to the percentage value
-func histValStr(val, tot int, fmtStr string) string {
-	pct := 100.0 * float64(val) / float64(tot)
-	return fmt.Sprintf(fmtStr, val, pct, strings.Repeat("*", int(pct*0.5)))
+// histPending returns the values which will be used to lay out the
+// histogram once enough have been added. It returns nil if the histogram
+// has already been laid out or if there is no histogram.
+func (s Stat) histPending() []float64 {
+	if s.cache != nil {
+		return s.cache
+	}
+	if s.noCache && !s.noHist && s.count < cap(s.mins) {
+		return s.mins
+	}
+	return nil
 }
 
 type StatOpt func(s *Stat) error
@@ -232,11 +438,11 @@ type StatOpt func(s *Stat) error
 func StatMinMaxCount(c int) StatOpt {
 	return func(s *Stat) error {
 		if s.mins != nil {
-			return errors.New(
+			return repeatedOption(
 				"the slice of minimum values has already been created")
 		}
 		if c < minMinMaxCount {
-			return fmt.Errorf(
+			return invalidValue(
 				"Invalid Min/Max Count (%d) - it must be >= %d",
 				c, minMinMaxCount)
 		}
@@ -252,11 +458,14 @@ func StatMinMaxCount(c int) StatOpt {
 func StatCacheSize(c int) StatOpt {
 	return func(s *Stat) error {
 		if s.cache != nil {
-			return errors.New(
+			return repeatedOption(
 				"the cache of values has already been created")
 		}
+		if s.noCache || s.noHist {
+			return conflictingOptions("the Stat has no cache")
+		}
 		if c < minCacheSize {
-			return fmt.Errorf(
+			return invalidValue(
 				"Invalid cache size (%d) - it must be >= %d",
 				c, minCacheSize)
 		}
@@ -271,11 +480,14 @@ func StatCacheSize(c int) StatOpt {
 func StatHistBucketCount(c int) StatOpt {
 	return func(s *Stat) error {
 		if s.hist != nil {
-			return errors.New(
+			return repeatedOption(
 				"the histogram slice has already been created")
 		}
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
 		if c < minHistBucketCount {
-			return fmt.Errorf(
+			return invalidValue(
 				"Invalid Hist Bucket Count (%d) - it must be >= %d",
 				c, minHistBucketCount)
 		}
@@ -287,6 +499,75 @@ func StatHistBucketCount(c int) StatOpt {
 	}
 }
 
+// StatCompensatedSum returns a function that will make the Stat use
+// compensated (Kahan-Neumaier) summation when accumulating the sum of the
+// values and of their squares. This greatly reduces the rounding errors
+// when adding many values, particularly small values to a large sum, at
+// the cost of slightly slower additions.
+func StatCompensatedSum() StatOpt {
+	return func(s *Stat) error {
+		s.compensated = true
+		return nil
+	}
+}
+
+// StatNoCache returns a function that will stop the Stat from keeping a
+// cache of values. The cache is used to choose the histogram boundaries;
+// without it they are chosen from the first values added, as held in the
+// slices of minimum and maximum values, once these slices are full. This
+// saves memory at the cost of a histogram which may fit the data less
+// well.
+func StatNoCache() StatOpt {
+	return func(s *Stat) error {
+		if s.cache != nil {
+			return conflictingOptions(
+				"the cache of values has already been created")
+		}
+
+		s.noCache = true
+		return nil
+	}
+}
+
+// StatNoHist returns a function that will stop the Stat from keeping a
+// histogram of values. Since the cache is only needed to choose the
+// histogram boundaries the Stat will not keep a cache either. This gives a
+// Stat recording only the count, sum, minima, maxima and standard
+// deviation with a correspondingly small memory footprint.
+func StatNoHist() StatOpt {
+	return func(s *Stat) error {
+		if s.hist != nil {
+			return conflictingOptions(
+				"the histogram slice has already been created")
+		}
+		if s.cache != nil {
+			return conflictingOptions(
+				"the cache of values has already been created")
+		}
+
+		s.noHist = true
+		return nil
+	}
+}
+
+// StatRate returns a function that will make the Stat record the rate at
+// which values are added. The options are passed to NewRate when creating
+// the Rate. The Rate can be retrieved with the Rate method.
+func StatRate(opts ...RateOpt) StatOpt {
+	return func(s *Stat) error {
+		if s.rate != nil {
+			return repeatedOption("the rate has already been created")
+		}
+
+		r, err := NewRate(opts...)
+		if err != nil {
+			return err
+		}
+		s.rate = r
+		return nil
+	}
+}
+
 // makeDfltHist creates a hist slice of default size if not already
 // created. Note that it makes it with length set so that the slice is
 // populated with zero initial values.
@@ -322,10 +603,40 @@ func NewStat(units string, opts ...StatOpt) (*Stat, error) {
 			return nil, err
 		}
 	}
+	if err := s.checkOpts(); err != nil {
+		return nil, err
+	}
 
-	s.makeDfltCache()
 	s.makeDfltMinsMaxs()
-	s.makeDfltHist()
+	if s.trackInfo {
+		s.makeInfo()
+	}
+	if s.maxMemory > 0 {
+		if err := s.fitMemory(); err != nil {
+			return nil, err
+		}
+	}
+	if !s.noCache && !s.noHist && s.expRange == nil {
+		s.makeDfltCache()
+		s.cacheBuf = s.cache
+	}
+	if !s.noHist {
+		s.makeDfltHist()
+		if s.rebinFraction > 0 || s.anchored {
+			s.makeRebinBufs()
+		}
+		if s.bucketSums {
+			s.histSums = make([]float64, len(s.hist))
+		}
+		if s.expRange != nil {
+			s.layoutExpectedRange()
+		}
+	}
+	if s.seed != nil {
+		if err := s.applySeed(); err != nil {
+			return nil, err
+		}
+	}
 
 	return s, nil
 }
@@ -340,42 +651,97 @@ func NewStatOrPanic(units string, opts ...StatOpt) *Stat {
 	return s
 }
 
-// resetFloat64Slice resets the contents of the slice to zeros
-func resetFloat64Slice(s []float64) {
-	if len(s) == 0 {
-		return
-	}
-	zeros := make([]float64, len(s))
-	copy(s, zeros)
-}
-
-// resetIntSlice resets the contents of the slice to zeros
-func resetIntSlice(s []int) {
-	if len(s) == 0 {
-		return
-	}
-	zeros := make([]int, len(s))
-	copy(s, zeros)
-}
-
-// Reset resets the Stat back to its initial state
+// Reset resets the Stat back to its initial state. All the storage used by
+// the Stat, including the cache and the histogram, is kept and reused so
+// that resetting and reusing a Stat does not allocate any memory. This
+// makes it suitable for keeping in a sync.Pool; call Reset before putting
+// it back in the pool or after taking it out. The history of Snapshots
+// (see Rotate) is kept and, if the Stat tracks changes (see
+// StatTrackChange), a Snapshot of the values is remembered.
 func (s *Stat) Reset() {
+	s.rememberCycle()
+	s.hot = false
 	s.sum = 0
 	s.sumSq = 0
+	s.sumC = 0
+	s.sumSqC = 0
 	s.count = 0
+	s.calls = 0
+	s.sampled = 0
 	s.mins = s.mins[:0]
 	s.maxs = s.maxs[:0]
+	if s.minInfo != nil {
+		clear(s.minInfo[:cap(s.minInfo)])
+		s.minInfo = s.minInfo[:0]
+		clear(s.maxInfo[:cap(s.maxInfo)])
+		s.maxInfo = s.maxInfo[:0]
+	}
 
-	resetFloat64Slice(s.cache)
+	if !s.noCache && !s.noHist && s.expRange == nil {
+		if s.cacheBuf == nil {
+			s.cacheBuf = make([]float64, 0, dfltCacheSize)
+		}
+		s.cache = s.cacheBuf[:0]
+	}
 
 	s.underflow = 0
-	resetIntSlice(s.hist)
+	s.hist = s.hist[:cap(s.hist)]
+	clear(s.hist)
 	s.overflow = 0
 	s.bucketStart = 0
 	s.bucketWidth = 0
+	s.knots = s.knots[:0]
+	s.underflowSum = 0
+	clear(s.histSums)
+	s.overflowSum = 0
+	if s.expRange != nil {
+		s.layoutExpectedRange()
+	}
+	s.tw = timeWeight{}
+
+	if s.rate != nil {
+		s.rate.Reset()
+	}
+	if s.freq != nil {
+		s.freq.reset()
+	}
+	if s.heavy != nil {
+		s.heavy.reset()
+	}
+	if s.remedian != nil {
+		s.remedian.reset()
+	}
+	if s.distinct != nil {
+		s.distinct.Reset()
+	}
+	if s.reservoir != nil {
+		s.reservoir.reset()
+	}
+	if s.deadband != nil {
+		s.deadband.reset()
+	}
+	if s.valid != nil {
+		s.valid.outside = 0
+	}
+	if s.tails != nil {
+		s.tails.reset()
+	}
+	if s.autocorr != nil {
+		s.autocorr.reset()
+	}
+	if s.runs != nil {
+		s.runs.reset()
+	}
+	if s.store != nil && s.store.err == nil {
+		s.store.err = s.store.store.Reset()
+	}
+	s.resetAccs()
 }
 
-// Add adds at least one new value to the Stat
+// Add adds at least one new value to the Stat. Once the Stat has been
+// created adding values does not allocate memory, unless the Stat is
+// recording the frequencies of the values (see StatTrackFreq) or has
+// Accumulators which allocate (see StatWithAccumulator).
 func (s *Stat) Add(v float64, vals ...float64) {
 	s.addVal(v)
 	for _, v := range vals {
@@ -392,21 +758,194 @@ func (s *Stat) AddVals(vals ...float64) {
 	}
 }
 
-// addVal adds a single new value to the Stat
+// compensatedAdd adds the value to the sum using Neumaier's variant of
+// Kahan summation. The lost low-order bits are accumulated in c which
+// should be added to the sum to give the corrected total.
+func compensatedAdd(sum, c *float64, v float64) {
+	t := *sum + v
+	if math.Abs(*sum) >= math.Abs(v) {
+		*c += (*sum - t) + v
+	} else {
+		*c += (v - t) + *sum
+	}
+	*sum = t
+}
+
+// addVal adds a single new value to the Stat. Once the histogram is in use
+// and the slices of minimum and maximum values are full, if none of the
+// optional features are in use, the value is added by the hot path which
+// does only what is needed for that case.
 func (s *Stat) addVal(v float64) {
-	maxIdx := cap(s.mins) - 1
+	if !s.hot {
+		s.addValInfo(v, nil)
+		return
+	}
+	s.adds++
 
 	s.sum += v
 	s.sumSq += v * v
 	s.count++
 
-	if s.count <= cap(s.mins) {
-		s.mins = append(s.mins, v)
-		s.maxs = append(s.maxs, v)
-		sort.Float64s(s.mins)
-		sort.Float64s(s.maxs)
+	if v < s.mins[len(s.mins)-1] { // smaller than the largest min value
+		insert(v, s.mins, dropFromEnd)
+	}
+	if v > s.maxs[0] { // larger than the smallest max value
+		insert(v, s.maxs, dropFromStart)
+	}
+
+	idx := int(math.Floor((v - s.bucketStart) / s.bucketWidth))
+	if uint(idx) < uint(len(s.hist)) {
+		s.hist[idx]++
+	} else if idx < 0 {
+		s.underflow++
+	} else {
+		s.overflow++
+	}
+}
+
+// canBeHot returns true if values can be added to the Stat through the hot
+// path in addVal. This is only possible once the histogram is in use and
+// the slices of minimum and maximum values are full and if none of the
+// optional features which need extra work when a value is added are in
+// use.
+func (s Stat) canBeHot() bool {
+	return s.count >= cap(s.mins) &&
+		!s.compensated &&
+		s.rebinFraction == 0 &&
+		s.rate == nil &&
+		s.freq == nil &&
+		s.heavy == nil &&
+		s.remedian == nil &&
+		s.distinct == nil &&
+		s.reservoir == nil &&
+		s.runs == nil &&
+		s.deadband == nil &&
+		(s.warmup == nil || s.warmup.done) &&
+		s.valid == nil &&
+		s.tails == nil &&
+		s.autocorr == nil &&
+		s.raw == nil &&
+		s.store == nil &&
+		s.sinks == nil &&
+		s.accs == nil &&
+		s.minInfo == nil &&
+		s.sampleRate == 0 &&
+		!s.bucketSums &&
+		s.logBase == 0 &&
+		!s.anchored &&
+		!s.quantileLayout &&
+		s.transform == nil &&
+		s.parent == nil
+}
+
+// addValInfo adds a single new value to the Stat, first transforming it if
+// the Stat has a transform (see StatTransform). The info is recorded
+// alongside the value if it is among the minimum or maximum values and the
+// Stat is tracking the information (see StatTrackInfo).
+func (s *Stat) addValInfo(v float64, info any) {
+	s.adds++
+	if s.transform != nil {
+		v = s.transform(v)
+	}
+	s.recordValInfo(v, info)
+}
+
+// recordValInfo records a single new value, which has already been
+// transformed, in the Stat and in any parent Stat (see Fork). The value is
+// first passed through the warm-up, valid range, deadband and sampling of
+// the Stat; those of any parent Stat are not applied again.
+func (s *Stat) recordValInfo(v float64, info any) {
+	if s.warmup != nil && s.warmup.skip() {
+		return
+	}
+	if s.valid != nil {
+		var ok bool
+		if v, ok = s.valid.check(v); !ok {
+			return
+		}
+	}
+	if s.deadband != nil && s.deadband.skip(v) {
+		return
+	}
+	if s.sampleRate > 0 && s.skipSample() {
+		for p := s.parent; p != nil; p = p.parent {
+			p.countForwardedSkip()
+		}
+		return
+	}
+
+	s.recordVal(v, info)
+}
+
+// recordForwarded records a value forwarded from a child Stat (see Fork).
+// The child has already passed the value through its warm-up, valid
+// range, deadband and sampling so it is recorded as it is, though it is
+// counted as sampled.
+func (s *Stat) recordForwarded(v float64, info any) {
+	s.adds++
+	if s.sampleRate > 0 {
+		s.calls++
+		s.sampled++
+	}
+	s.recordVal(v, info)
+}
+
+// recordVal records a single new value which has passed through any
+// filters in the Stat and in any parent Stat (see Fork)
+func (s *Stat) recordVal(v float64, info any) {
+	if s.compensated {
+		compensatedAdd(&s.sum, &s.sumC, v)
+		compensatedAdd(&s.sumSq, &s.sumSqC, v*v)
 	} else {
-		if v < s.mins[maxIdx] { // smaller than the largest min value
+		s.sum += v
+		s.sumSq += v * v
+	}
+	s.count++
+
+	if s.rate != nil {
+		s.rate.Add(v)
+	}
+	if s.freq != nil {
+		s.freq.add(v)
+	}
+	if s.heavy != nil {
+		s.heavy.add(v)
+	}
+	if s.remedian != nil {
+		s.remedian.add(v)
+	}
+	if s.distinct != nil {
+		s.distinct.Add(v)
+	}
+	if s.reservoir != nil {
+		s.reservoir.add(v)
+	}
+	if s.runs != nil {
+		s.runs.add(v)
+	}
+	if s.autocorr != nil {
+		s.autocorr.add(v)
+	}
+	if s.raw != nil {
+		s.raw.add(v)
+	}
+	if s.store != nil {
+		s.store.add(v)
+	}
+	for _, ss := range s.sinks {
+		ss.add(v)
+	}
+	for _, acc := range s.accs {
+		acc.Add(v)
+	}
+
+	if s.minInfo != nil {
+		s.addMinMaxInfo(v, info)
+	} else if s.count <= cap(s.mins) {
+		s.mins, _ = insertSorted(v, s.mins)
+		s.maxs, _ = insertSorted(v, s.maxs)
+	} else {
+		if v < s.mins[len(s.mins)-1] { // smaller than the largest min value
 			insert(v, s.mins, dropFromEnd)
 		}
 		if v > s.maxs[0] { // larger than the smallest max value
@@ -414,14 +953,27 @@ func (s *Stat) addVal(v float64) {
 		}
 	}
 
-	if len(s.cache) < cap(s.cache) {
+	switch {
+	case s.noHist:
+	case len(s.cache) < cap(s.cache):
 		s.cache = append(s.cache, v)
 
 		if len(s.cache) == cap(s.cache) {
 			s.populateHist()
 		}
-	} else {
+	case s.noCache && s.count < cap(s.mins):
+	case s.noCache && s.count == cap(s.mins):
+		s.layoutHist(s.mins)
+	default:
 		s.addToHist(v)
+		if s.rebinFraction > 0 {
+			s.checkRebin()
+		}
+		s.hot = s.canBeHot()
+	}
+
+	if s.parent != nil {
+		s.parent.recordForwarded(v, info)
 	}
 }
 
@@ -432,21 +984,29 @@ func (s *Stat) populateHist() {
 		return
 	}
 
+	s.layoutHist(s.cache)
+	s.cache = nil
+}
+
+// layoutHist initialises the histogram from the given values and then adds
+// them to the histogram
+func (s *Stat) layoutHist(vals []float64) {
 	s.makeDfltHist()
 
-	s.initHist()
+	s.initHist(vals)
 
-	for _, v := range s.cache {
+	for _, v := range vals {
 		s.addToHist(v)
 	}
-	s.cache = nil
 }
 
 // initHist initialises the histogram. Unless the hist size has been chosen
 // it will resize the histogram to ensure there are enough data values to
 // have at least a minimum average number of entries in each bucket.  It sets
-// the bucket start and bucket width values for the histogram.
-func (s *Stat) initHist() {
+// the bucket start and bucket width values for the histogram or, if the
+// histogram is laid out at quantiles, the bucket boundaries from the
+// values.
+func (s *Stat) initHist(vals []float64) {
 	const minPerBucket = 5
 
 	if !s.histSizeChosen {
@@ -459,33 +1019,71 @@ func (s *Stat) initHist() {
 		}
 	}
 
-	s.bucketStart = s.mins[0]
-	valRange := s.maxs[len(s.maxs)-1] - s.bucketStart
-	bucketCount := float64(len(s.hist))
-	s.bucketWidth = histBucketWidthScale * valRange / bucketCount
+	if s.quantileLayout {
+		s.setQuantileLayout(vals)
+	} else {
+		s.setHistLayout(s.histRange())
+	}
+
+	if s.bucketSums {
+		if cap(s.histSums) >= len(s.hist) {
+			s.histSums = s.histSums[:len(s.hist)]
+			clear(s.histSums)
+		} else {
+			s.histSums = make([]float64, len(s.hist))
+		}
+	}
 }
 
 // addToHist adds the value to the histogram of values
 func (s *Stat) addToHist(v float64) {
-	idx := int(math.Floor((v - s.bucketStart) / s.bucketWidth))
+	idx := s.bucketIdx(v)
+	if idx < 0 && s.anchored && s.bucketWidth > 0 && !math.IsInf(v, -1) {
+		s.expandDown(v)
+		idx = s.bucketIdx(v)
+	}
 
 	if idx < 0 {
 		s.underflow++
+		if s.histSums != nil {
+			s.underflowSum += v
+		}
+		if s.tails != nil {
+			s.tails.addLow(v)
+		}
 		return
 	}
 
 	if idx >= len(s.hist) {
 		s.overflow++
+		if s.histSums != nil {
+			s.overflowSum += v
+		}
+		if s.tails != nil {
+			s.tails.addHigh(v)
+		}
 		return
 	}
 
 	s.hist[idx]++
+	if s.histSums != nil {
+		s.histSums[idx] += v
+	}
+}
+
+// insertSorted inserts the value into the slice of values, which must have
+// room for it, keeping the values in ascending order. It returns the
+// extended slice and the index at which the value was inserted.
+func insertSorted(v float64, vals []float64) ([]float64, int) {
+	i := sort.SearchFloat64s(vals, v)
+	return slices.Insert(vals, i, v), i
 }
 
 // insert inserts the value into the slice of values shifting the remaining
 // values along and discarding from one end or the other according to the
 // discard type. The vals slice is assumed to be sorted in ascending order.
-func insert(v float64, vals []float64, discard discardType) {
+// It returns the index at which the value was inserted.
+func insert(v float64, vals []float64, discard discardType) int {
 	var i int
 	var cmp float64
 
@@ -496,16 +1094,27 @@ func insert(v float64, vals []float64, discard discardType) {
 				break
 			}
 		}
-
-		if i+1 < len(vals) {
-			copy(vals[i+1:], vals[i:len(vals)-1])
-		}
 	case dropFromStart:
 		for i = len(vals) - 1; i > 0; i-- {
 			if vals[i] < v {
 				break
 			}
 		}
+	}
+	insertAt(v, vals, i, discard)
+	return i
+}
+
+// insertAt sets the i'th entry in the slice to v having first shifted the
+// existing entries along to make room, discarding an entry from one end or
+// the other according to the discard type.
+func insertAt[T any](v T, vals []T, i int, discard discardType) {
+	switch discard {
+	case dropFromEnd:
+		if i+1 < len(vals) {
+			copy(vals[i+1:], vals[i:len(vals)-1])
+		}
+	case dropFromStart:
 		if i > 0 {
 			copy(vals[:i], vals[1:i+1])
 		}
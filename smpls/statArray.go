@@ -0,0 +1,95 @@
+package smpls
+
+// arenaFor returns a slice big enough to hold n copies of the template
+// slice, each with the template's capacity, or nil if the template is nil
+func arenaFor[T any](n int, tmpl []T) []T {
+	if tmpl == nil {
+		return nil
+	}
+	return make([]T, n*cap(tmpl))
+}
+
+// carve returns the i'th section of the arena holding a copy of the
+// template slice, with the same length and capacity, or nil if the
+// template is nil. The capacity is fixed so that appending to the section
+// cannot overwrite the next one.
+func carve[T any](arena []T, i int, tmpl []T) []T {
+	if tmpl == nil {
+		return nil
+	}
+	c := cap(tmpl)
+	section := arena[i*c : i*c+len(tmpl) : (i+1)*c]
+	copy(section, tmpl)
+	return section
+}
+
+// NewStatArray creates n Stats, each configured with the same units and
+// options. Rather than each Stat having its own storage for its smallest
+// and largest values, its cache and its histogram these are carved out of
+// a few arrays shared by all the Stats. This makes a handful of memory
+// allocations rather than several for each Stat and keeps the Stats close
+// together in memory. It is useful when a program needs thousands of small
+// Stats, such as one per client or per key.
+//
+// The Stats are independent of each other just as if each had been
+// created with NewStat; the storage of a Stat can still grow, in which
+// case it is reallocated for that Stat alone. Each Stat has its own copy
+// of those Accumulators which can be cloned (see Accumulator) and, as with
+// Clone, none of them writes raw values (see StatRawValues) or appends
+// them to a sample store (see StatSampleStore).
+func NewStatArray(n int, units string, opts ...StatOpt) ([]*Stat, error) {
+	if n < 0 {
+		return nil, invalidValue(
+			"Invalid Stat array size (%d) - it must be >= 0", n)
+	}
+	tmpl, err := NewStat(units, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mins := arenaFor(n, tmpl.mins)
+	maxs := arenaFor(n, tmpl.maxs)
+	cacheTmpl := tmpl.cacheBuf
+	if tmpl.cache != nil {
+		cacheTmpl = tmpl.cache
+	}
+	cache := arenaFor(n, cacheTmpl)
+	hist := arenaFor(n, tmpl.hist)
+	histSums := arenaFor(n, tmpl.histSums)
+	rebinHist := arenaFor(n, tmpl.rebinHist)
+	rebinSums := arenaFor(n, tmpl.rebinSums)
+	knots := arenaFor(n, tmpl.knots)
+
+	stats := make([]Stat, n)
+	ptrs := make([]*Stat, n)
+	for i := range stats {
+		s := &stats[i]
+		*s = *tmpl
+		s.mins = carve(mins, i, tmpl.mins)
+		s.maxs = carve(maxs, i, tmpl.maxs)
+		s.cacheBuf = carve(cache, i, cacheTmpl)
+		if tmpl.cache != nil {
+			s.cache = s.cacheBuf
+		} else if s.cacheBuf != nil {
+			s.cacheBuf = s.cacheBuf[:0]
+		}
+		s.hist = carve(hist, i, tmpl.hist)
+		s.histSums = carve(histSums, i, tmpl.histSums)
+		s.rebinHist = carve(rebinHist, i, tmpl.rebinHist)
+		s.rebinSums = carve(rebinSums, i, tmpl.rebinSums)
+		s.knots = carve(knots, i, tmpl.knots)
+		s.cloneRefs()
+		ptrs[i] = s
+	}
+	return ptrs, nil
+}
+
+// NewStatArrayOrPanic creates n Stats (see NewStatArray) and will panic if
+// any errors are detected
+func NewStatArrayOrPanic(n int, units string, opts ...StatOpt) []*Stat {
+	stats, err := NewStatArray(n, units, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return stats
+}
@@ -0,0 +1,90 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewStatArray(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts []StatOpt
+	}{
+		{
+			ID: testhelper.MkID("defaults"),
+		},
+		{
+			ID: testhelper.MkID("small"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistBucketCount(5),
+				StatMinMaxCount(2),
+			},
+		},
+		{
+			ID: testhelper.MkID("rebinning, with sums"),
+			opts: []StatOpt{
+				StatCacheSize(10),
+				StatHistAutoRebin(0.1),
+				StatHistBucketSums(),
+				StatTrackFreq(5),
+			},
+		},
+		{
+			ID:   testhelper.MkID("expected range"),
+			opts: []StatOpt{StatExpectedRange(0, 100)},
+		},
+		{
+			ID:   testhelper.MkID("exact"),
+			opts: []StatOpt{StatExact(20)},
+		},
+	}
+
+	for _, tc := range testCases {
+		stats := NewStatArrayOrPanic(3, "ms", tc.opts...)
+		testhelper.DiffInt(t, tc.IDStr(), "Stat count", len(stats), 3)
+
+		// each Stat must be independent of its neighbours and behave just
+		// as a Stat created with NewStat
+		for i, s := range stats {
+			addSeq(s, float64(i), 1.5, 50+i)
+		}
+		for i, s := range stats {
+			exp := NewStatOrPanic("ms", tc.opts...)
+			addSeq(exp, float64(i), 1.5, 50+i)
+			testhelper.DiffString(t, tc.IDStr(), "String",
+				s.String(), exp.String())
+			testhelper.DiffString(t, tc.IDStr(), "Hist", s.Hist(), exp.Hist())
+		}
+
+		stats[1].Reset()
+		testhelper.DiffInt(t, tc.IDStr(), "reset count", stats[1].Count(), 0)
+		testhelper.DiffInt(t, tc.IDStr(), "neighbour count",
+			stats[2].Count(), 52)
+	}
+}
+
+func TestNewStatArrayAllocs(t *testing.T) {
+	const n = 1000
+	allocs := testing.AllocsPerRun(10, func() {
+		NewStatArrayOrPanic(n, "ms", StatCacheSize(10), StatHistBucketCount(5))
+	})
+	if allocs > 20 {
+		t.Errorf("too many allocations for %d Stats: %g", n, allocs)
+	}
+}
+
+func TestNewStatArrayErrs(t *testing.T) {
+	_, err := NewStatArray(-1, "ms")
+	testhelper.DiffBool(t, "negative size", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+	_, err = NewStatArray(3, "ms", StatCacheSize(-1))
+	testhelper.DiffBool(t, "bad option", "invalid value error",
+		errors.Is(err, ErrInvalidValue), true)
+
+	stats, err := NewStatArray(0, "ms")
+	testhelper.DiffBool(t, "empty", "error", err == nil, true)
+	testhelper.DiffInt(t, "empty", "Stat count", len(stats), 0)
+}
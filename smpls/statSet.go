@@ -0,0 +1,227 @@
+package smpls
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StatSet holds a collection of Stats, each identified by a name. Stats
+// can be created as they are first needed, in which case they share the
+// units and options given when the StatSet was created, or existing Stats
+// can be registered with the StatSet.
+//
+// The names can form a hierarchy, as in "db.query.select" (see
+// StatNameSep), in which case the Stats below each part of the name can be
+// rolled up together (see RollUp and Tree).
+//
+// Stats which are no longer used can be removed automatically (see
+// SetTTL).
+//
+// Other Metrics, such as Counters and Gauges, can be registered alongside
+// the Stats (see RegisterMetric) so that they are reported with them. They
+// are not rolled up, shared or removed automatically.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type StatSet struct {
+	units string
+	opts  []StatOpt
+	stats map[string]*Stat
+	// metrics holds the registered Metrics which are not Stats
+	metrics map[string]Metric
+	corr    *corrTracker
+
+	ttl       time.Duration
+	onExpire  func(name string, snap Snapshot)
+	uses      map[string]*statUse
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewStatSet creates a new StatSet. The units and options are used when
+// creating each Stat. An error is returned if the options are invalid.
+func NewStatSet(units string, opts ...StatOpt) (*StatSet, error) {
+	if _, err := NewStat(units, opts...); err != nil {
+		return nil, err
+	}
+
+	return &StatSet{
+		units:   units,
+		opts:    opts,
+		stats:   map[string]*Stat{},
+		metrics: map[string]Metric{},
+	}, nil
+}
+
+// NewStatSetOrPanic creates a new StatSet and will panic if any errors are
+// detected
+func NewStatSetOrPanic(units string, opts ...StatOpt) *StatSet {
+	ss, err := NewStatSet(units, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+// Len returns the number of Stats in the StatSet. Any other Metrics are
+// not counted (see MetricNames).
+func (ss StatSet) Len() int {
+	return len(ss.stats)
+}
+
+// Stat returns the Stat with the given name, creating it if necessary. If
+// the StatSet has a TTL (see SetTTL) any unused Stats may be removed first.
+// An error is returned if the name is in use by a Metric which is not a
+// Stat.
+func (ss *StatSet) Stat(name string) (*Stat, error) {
+	if _, ok := ss.metrics[name]; ok {
+		return nil, fmt.Errorf(
+			"the name %q is in use by a Metric which is not a Stat", name)
+	}
+
+	var now time.Time
+	if ss.ttl > 0 {
+		now = ss.now()
+		ss.maybeExpire(now)
+	}
+
+	s, ok := ss.stats[name]
+	if !ok {
+		var err error
+		s, err = NewStat(ss.units, ss.opts...)
+		if err != nil {
+			return nil, err
+		}
+		ss.stats[name] = s
+	}
+	ss.touch(name, s, now)
+	return s, nil
+}
+
+// StatOrPanic returns the Stat with the given name, creating it if
+// necessary. It will panic if any errors are detected.
+func (ss *StatSet) StatOrPanic(name string) *Stat {
+	s, err := ss.Stat(name)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Lookup returns the Stat with the given name and true if it is in the
+// StatSet. Otherwise it returns nil and false.
+func (ss StatSet) Lookup(name string) (*Stat, bool) {
+	s, ok := ss.stats[name]
+	return s, ok
+}
+
+// Register adds an existing Stat to the StatSet with the given name. The
+// Stat need not have the units or options of the StatSet. An error is
+// returned if the name is already in use.
+func (ss *StatSet) Register(name string, s *Stat) error {
+	if ss.inUse(name) {
+		return fmt.Errorf("the name %q is already in use", name)
+	}
+	ss.stats[name] = s
+	if ss.ttl > 0 {
+		ss.touch(name, s, ss.now())
+	}
+	return nil
+}
+
+// inUse returns true if the name is used by any Metric in the StatSet
+func (ss StatSet) inUse(name string) bool {
+	_, isStat := ss.stats[name]
+	_, isMetric := ss.metrics[name]
+	return isStat || isMetric
+}
+
+// RegisterMetric adds the Metric, such as a Counter or a Gauge, to the
+// StatSet with the given name so that it is reported with the Stats (see
+// String and Report.AddStatSet). A Stat is registered as if by Register.
+// An error is returned if the name is already in use.
+func (ss *StatSet) RegisterMetric(name string, m Metric) error {
+	if s, ok := m.(*Stat); ok {
+		return ss.Register(name, s)
+	}
+	if ss.inUse(name) {
+		return fmt.Errorf("the name %q is already in use", name)
+	}
+	ss.metrics[name] = m
+	return nil
+}
+
+// LookupMetric returns the Metric, which may be a Stat, with the given name
+// and true if it is in the StatSet. Otherwise it returns nil and false.
+func (ss StatSet) LookupMetric(name string) (Metric, bool) {
+	if s, ok := ss.stats[name]; ok {
+		return s, true
+	}
+	m, ok := ss.metrics[name]
+	return m, ok
+}
+
+// MetricNames returns the names of the Metrics which are not Stats, sorted
+func (ss StatSet) MetricNames() []string {
+	names := make([]string, 0, len(ss.metrics))
+	for name := range ss.metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Remove removes the Stat or other Metric with the given name from the
+// StatSet. It returns false if there is no such Metric.
+func (ss *StatSet) Remove(name string) bool {
+	if !ss.inUse(name) {
+		return false
+	}
+	delete(ss.metrics, name)
+	delete(ss.stats, name)
+	delete(ss.uses, name)
+	return true
+}
+
+// Add adds the value to the Stat with the given name, creating it if
+// necessary
+func (ss *StatSet) Add(name string, v float64) error {
+	s, err := ss.Stat(name)
+	if err != nil {
+		return err
+	}
+	s.Add(v)
+	return nil
+}
+
+// Names returns the names of the Stats, sorted. The names of any other
+// Metrics are not included (see MetricNames).
+func (ss StatSet) Names() []string {
+	names := make([]string, 0, len(ss.stats))
+	for name := range ss.stats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// String returns a report of all the Stats and other Metrics, one per
+// line, sorted by name. See the Report type for a report with aligned
+// columns.
+func (ss StatSet) String() string {
+	names := append(ss.Names(), ss.MetricNames()...)
+	slices.Sort(names)
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	var b strings.Builder
+	for _, name := range names {
+		m, _ := ss.LookupMetric(name)
+		fmt.Fprintf(&b, "%-*s: %s\n", width, name, m)
+	}
+	return b.String()
+}
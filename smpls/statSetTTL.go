@@ -0,0 +1,118 @@
+package smpls
+
+import (
+	"time"
+)
+
+// statUse records when a Stat in a StatSet was last seen to be used
+type statUse struct {
+	activity int
+	lastUsed time.Time
+}
+
+// activity returns a number which changes whenever values are added to
+// the Stat. It is not changed by a Reset so a Stat which is Reset and
+// refilled is still seen to be in use.
+func (s Stat) activity() int {
+	return s.adds
+}
+
+// SetTTL makes the StatSet remove any Stat which has not been used for the
+// given time to live. A Stat is used when it is created or registered,
+// when it is returned by the Stat method or when values are added to it,
+// whether through the StatSet or directly. Stats are removed by Expire,
+// which is called by the Stat method at most once per ttl, so a StatSet
+// which is in use needs no further attention. If onExpire is not nil it is
+// called with the name and a Snapshot of each Stat before it is removed.
+//
+// This stops long-running programs keeping Stats for, say, each client
+// from growing without limit. It returns an error if the ttl is not
+// greater than zero.
+func (ss *StatSet) SetTTL(ttl time.Duration,
+	onExpire func(name string, snap Snapshot),
+) error {
+	if ttl <= 0 {
+		return invalidValue("Invalid TTL (%s) - it must be > 0", ttl)
+	}
+
+	if ss.now == nil {
+		ss.now = time.Now
+	}
+	ss.ttl = ttl
+	ss.onExpire = onExpire
+	now := ss.now()
+	ss.lastSweep = now
+	if ss.uses == nil {
+		ss.uses = make(map[string]*statUse, len(ss.stats))
+	}
+	for name, s := range ss.stats {
+		if _, ok := ss.uses[name]; !ok {
+			ss.uses[name] = &statUse{activity: s.activity(), lastUsed: now}
+		}
+	}
+	return nil
+}
+
+// TTL returns the time to live of the Stats in the StatSet (see SetTTL).
+// It returns zero if Stats are never removed.
+func (ss StatSet) TTL() time.Duration {
+	return ss.ttl
+}
+
+// touch records that the named Stat has just been used
+func (ss *StatSet) touch(name string, s *Stat, now time.Time) {
+	if ss.ttl == 0 {
+		return
+	}
+	u, ok := ss.uses[name]
+	if !ok {
+		u = &statUse{}
+		ss.uses[name] = u
+	}
+	u.activity = s.activity()
+	u.lastUsed = now
+}
+
+// maybeExpire calls Expire if it has not been called within the TTL
+func (ss *StatSet) maybeExpire(now time.Time) {
+	if ss.ttl > 0 && now.Sub(ss.lastSweep) >= ss.ttl {
+		ss.expire(now)
+	}
+}
+
+// Expire removes any Stat which has not been used within the TTL (see
+// SetTTL), first passing a Snapshot of it to the onExpire function, if
+// any. It returns the number of Stats removed. It does nothing if the
+// StatSet has no TTL.
+func (ss *StatSet) Expire() int {
+	if ss.ttl == 0 {
+		return 0
+	}
+	return ss.expire(ss.now())
+}
+
+// expire removes the Stats not used since the TTL before now
+func (ss *StatSet) expire(now time.Time) int {
+	ss.lastSweep = now
+
+	removed := 0
+	for _, name := range ss.Names() {
+		s := ss.stats[name]
+		u := ss.uses[name]
+		if act := s.activity(); act != u.activity {
+			u.activity = act
+			u.lastUsed = now
+			continue
+		}
+		if now.Sub(u.lastUsed) < ss.ttl {
+			continue
+		}
+
+		if ss.onExpire != nil {
+			ss.onExpire(name, s.Snapshot())
+		}
+		ss.Remove(name)
+		removed++
+	}
+	return removed
+}
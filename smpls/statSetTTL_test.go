@@ -0,0 +1,83 @@
+package smpls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatSetTTL(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	testhelper.CheckExpErrWithID(t, "bad TTL", ss.SetTTL(0, nil),
+		testhelper.MkExpErr("Invalid TTL (0s) - it must be > 0"))
+	testhelper.DiffInt(t, "no TTL", "Expire", ss.Expire(), 0)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ss.now = func() time.Time { return now }
+
+	_ = ss.Add("idle", 1)
+	expired := map[string]int{}
+	err := ss.SetTTL(time.Minute, func(name string, snap Snapshot) {
+		expired[name] = snap.Count
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testhelper.DiffInt(t, "TTL", "TTL", int(ss.TTL()), int(time.Minute))
+
+	_ = ss.Add("busy", 1)
+	direct := ss.StatOrPanic("direct")
+
+	now = now.Add(40 * time.Second)
+	_ = ss.Add("busy", 2)
+	direct.Add(5)
+	testhelper.DiffInt(t, "before TTL", "Expire", ss.Expire(), 0)
+
+	now = now.Add(30 * time.Second)
+	testhelper.DiffInt(t, "after TTL", "Expire", ss.Expire(), 1)
+	testhelper.DiffInt(t, "after TTL", "idle count", expired["idle"], 1)
+	testhelper.DiffStringSlice(t, "after TTL", "names",
+		ss.Names(), []string{"busy", "direct"})
+
+	// the Stat method sweeps once the TTL has passed since the last sweep
+	now = now.Add(2 * time.Minute)
+	_ = ss.StatOrPanic("new")
+	testhelper.DiffStringSlice(t, "lazy expiry", "names",
+		ss.Names(), []string{"new"})
+	testhelper.DiffInt(t, "lazy expiry", "busy count", expired["busy"], 2)
+	testhelper.DiffInt(t, "lazy expiry", "direct count", expired["direct"], 1)
+
+	testhelper.CheckExpErrWithID(t, "Register",
+		ss.Register("reg", NewStatOrPanic("ms")), testhelper.ExpErr{})
+	now = now.Add(time.Minute)
+	testhelper.DiffInt(t, "Register", "Expire", ss.Expire(), 2)
+	testhelper.DiffInt(t, "Register", "Len", ss.Len(), 0)
+}
+
+func TestStatSetTTLReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ss := NewStatSetOrPanic("ms")
+	ss.now = func() time.Time { return now }
+	if err := ss.SetTTL(time.Minute, nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	cycled := ss.StatOrPanic("cycled")
+	sliced := ss.StatOrPanic("sliced")
+	cycled.Add(1, 2)
+	sliced.AddSlice([]float64{1, 2})
+	testhelper.DiffInt(t, "filled", "Expire", ss.Expire(), 0)
+
+	// refilled to the same count so only the values added show it is used
+	now = now.Add(50 * time.Second)
+	cycled.Reset()
+	cycled.Add(3, 4)
+	sliced.Reset()
+	sliced.AddSlice([]float64{3, 4})
+	now = now.Add(50 * time.Second)
+	testhelper.DiffInt(t, "Reset and refilled", "Expire", ss.Expire(), 0)
+
+	now = now.Add(2 * time.Minute)
+	testhelper.DiffInt(t, "unused", "Expire", ss.Expire(), 2)
+}
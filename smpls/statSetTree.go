@@ -0,0 +1,110 @@
+package smpls
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// StatNameSep separates the parts of a hierarchical Stat name in a
+// StatSet. For instance, the Stats named "db.query.select" and
+// "db.query.update" are both below "db.query" which is itself below "db".
+const StatNameSep = "."
+
+// isBelow returns true if the name is the same as the prefix or is below it
+// in the hierarchy of names
+func isBelow(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+StatNameSep)
+}
+
+// RollUp returns a new Stat with the values of all the Stats in the
+// StatSet whose names are the same as or below the prefix in the hierarchy
+// of names (see StatNameSep) merged into it. So the roll-up of "db" has the
+// values of "db.query.select", "db.query.update" and "db.conn" but not
+// those of "dbx". The new Stat has the units and options of the StatSet.
+//
+// An error is returned if there are no such Stats or if any of them
+// cannot be merged (see Merge), as can happen with a Stat that has been
+// registered with different units.
+func (ss StatSet) RollUp(prefix string) (*Stat, error) {
+	s, err := NewStat(ss.units, ss.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, name := range ss.Names() {
+		if !isBelow(name, prefix) {
+			continue
+		}
+		found = true
+		if err := s.Merge(ss.stats[name]); err != nil {
+			return nil, fmt.Errorf("cannot roll up %q into %q: %w",
+				name, prefix, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("there are no Stats named %q or below it",
+			prefix)
+	}
+	return s, nil
+}
+
+// treeNodes returns the names of all the Stats and of all their parents in
+// the hierarchy of names, in the order they are shown in the tree
+func (ss StatSet) treeNodes() [][]string {
+	seen := map[string]bool{}
+	var nodes [][]string
+	for name := range ss.stats {
+		parts := strings.Split(name, StatNameSep)
+		for i := range parts {
+			node := parts[:i+1]
+			key := strings.Join(node, StatNameSep)
+			if !seen[key] {
+				seen[key] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	slices.SortFunc(nodes, slices.Compare)
+	return nodes
+}
+
+// Tree returns a report of the Stats arranged in the hierarchy of their
+// names (see StatNameSep), one per line. Each part of a name is shown on
+// its own line, indented below its parent. The lines for the parents show
+// their roll-ups, the values of all the Stats below them merged together
+// (see RollUp), so that the totals for each level of the hierarchy can be
+// seen at a glance. An error is returned if any roll-up cannot be made.
+func (ss StatSet) Tree() (string, error) {
+	const indent = "  "
+
+	type line struct {
+		label string
+		s     *Stat
+	}
+	nodes := ss.treeNodes()
+	lines := make([]line, 0, len(nodes))
+	width := 0
+	for i, node := range nodes {
+		name := strings.Join(node, StatNameSep)
+		s := ss.stats[name]
+		// the nodes are sorted so any children immediately follow
+		if i+1 < len(nodes) && len(nodes[i+1]) > len(node) {
+			var err error
+			if s, err = ss.RollUp(name); err != nil {
+				return "", err
+			}
+		}
+
+		label := strings.Repeat(indent, len(node)-1) + node[len(node)-1]
+		width = max(width, len(label))
+		lines = append(lines, line{label: label, s: s})
+	}
+
+	var b strings.Builder
+	for _, l := range lines {
+		fmt.Fprintf(&b, "%-*s: %s\n", width, l.label, l.s)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,116 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRollUp(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	for name, vals := range map[string][]float64{
+		"db.query.select": {1, 2, 3},
+		"db.query.update": {10, 20},
+		"db.conn":         {5},
+		"dbx":             {100},
+	} {
+		for _, v := range vals {
+			if err := ss.Add(name, v); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		prefix   string
+		expCount int
+		expSum   float64
+	}{
+		{
+			ID:       testhelper.MkID("top level"),
+			prefix:   "db",
+			expCount: 6,
+			expSum:   41,
+		},
+		{
+			ID:       testhelper.MkID("mid level"),
+			prefix:   "db.query",
+			expCount: 5,
+			expSum:   36,
+		},
+		{
+			ID:       testhelper.MkID("leaf"),
+			prefix:   "db.conn",
+			expCount: 1,
+			expSum:   5,
+		},
+		{
+			ID: testhelper.MkID("partial part"),
+			ExpErr: testhelper.MkExpErr(
+				`there are no Stats named "db.q" or below it`),
+			prefix: "db.q",
+		},
+	}
+
+	for _, tc := range testCases {
+		s, err := ss.RollUp(tc.prefix)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), tc.expCount)
+			testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 0)
+		}
+	}
+
+	err := ss.Register("db.size", NewStatOrPanic("bytes"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = ss.RollUp("db")
+	testhelper.CheckExpErrWithID(t, "different units", err,
+		testhelper.MkExpErr(`cannot roll up "db.size" into "db"`,
+			"cannot merge Stats with different units"))
+	_, err = ss.Tree()
+	testhelper.CheckExpErrWithID(t, "Tree - different units", err,
+		testhelper.MkExpErr(`cannot roll up "db.size" into "db"`))
+}
+
+func TestTree(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	for _, name := range []string{
+		"db.query.select", "db.query.update", "db.conn", "db", "db-x",
+	} {
+		if err := ss.Add(name, float64(len(name))); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	tree, err := ss.Tree()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	exp := "db        : " + mustRollUp(t, ss, "db") + "\n" +
+		"  conn    : " + ss.StatOrPanic("db.conn").String() + "\n" +
+		"  query   : " + mustRollUp(t, ss, "db.query") + "\n" +
+		"    select: " + ss.StatOrPanic("db.query.select").String() + "\n" +
+		"    update: " + ss.StatOrPanic("db.query.update").String() + "\n" +
+		"db-x      : " + ss.StatOrPanic("db-x").String() + "\n"
+	testhelper.DiffString(t, "Tree", "report", tree, exp)
+
+	db := ss.StatOrPanic("db")
+	rolled, _ := ss.RollUp("db")
+	testhelper.DiffInt(t, "Tree", "db roll-up count", rolled.Count(), 4)
+	testhelper.DiffInt(t, "Tree", "db own count", db.Count(), 1)
+}
+
+// mustRollUp returns the String of the roll-up of the Stats in the StatSet
+// below the prefix, failing the test if it cannot be made
+func mustRollUp(t *testing.T, ss *StatSet, prefix string) string {
+	t.Helper()
+	s, err := ss.RollUp(prefix)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return s.String()
+}
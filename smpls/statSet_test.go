@@ -0,0 +1,111 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatSet(t *testing.T) {
+	_, err := NewStatSet("ms", StatCacheSize(0))
+	testhelper.CheckExpErrWithID(t, "NewStatSet - bad opts", err,
+		testhelper.MkExpErr("Invalid cache size (0)"))
+
+	ss := NewStatSetOrPanic("ms", StatMinMaxCount(2))
+	for _, err := range []error{
+		ss.Add("read", 1),
+		ss.Add("write", 10),
+		ss.Add("read", 3),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	testhelper.DiffInt(t, "StatSet", "len", ss.Len(), 2)
+	testhelper.DiffStringSlice(t, "StatSet", "names",
+		ss.Names(), []string{"read", "write"})
+
+	read, ok := ss.Lookup("read")
+	testhelper.DiffBool(t, "Lookup", "found", ok, true)
+	testhelper.DiffInt(t, "Lookup", "count", read.Count(), 2)
+	testhelper.DiffInt(t, "Lookup", "min/max count", cap(read.mins), 2)
+	_, ok = ss.Lookup("delete")
+	testhelper.DiffBool(t, "Lookup - missing", "found", ok, false)
+
+	other := NewStatOrPanic("bytes")
+	testhelper.CheckExpErrWithID(t, "Register", ss.Register("size", other),
+		testhelper.ExpErr{})
+	testhelper.CheckExpErrWithID(t, "Register - repeated",
+		ss.Register("size", other),
+		testhelper.MkExpErr(`the name "size" is already in use`))
+
+	testhelper.DiffBool(t, "Remove", "removed", ss.Remove("size"), true)
+	testhelper.DiffBool(t, "Remove - missing", "removed",
+		ss.Remove("size"), false)
+
+	testhelper.DiffString(t, "StatSet", "String", ss.String(),
+		"read : "+read.String()+"\n"+
+			"write: "+ss.StatOrPanic("write").String()+"\n")
+}
+
+func TestStatSetMetrics(t *testing.T) {
+	ss := NewStatSetOrPanic("ms")
+	if err := ss.Add("latency", 5); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	reqs := NewCounter("requests")
+	reqs.Inc()
+	queue := NewGauge("jobs")
+	if err := queue.Set(3); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	size := NewStatOrPanic("bytes")
+
+	for _, name := range []string{"requests", "queue", "size"} {
+		var m Metric
+		switch name {
+		case "requests":
+			m = reqs
+		case "queue":
+			m = queue
+		case "size":
+			m = size
+		}
+		testhelper.CheckExpErrWithID(t, "RegisterMetric: "+name,
+			ss.RegisterMetric(name, m), testhelper.ExpErr{})
+	}
+	testhelper.CheckExpErrWithID(t, "RegisterMetric - repeated",
+		ss.RegisterMetric("latency", reqs),
+		testhelper.MkExpErr(`the name "latency" is already in use`))
+	testhelper.CheckExpErrWithID(t, "Register - used by a Metric",
+		ss.Register("queue", size),
+		testhelper.MkExpErr(`the name "queue" is already in use`))
+	_, err := ss.Stat("requests")
+	testhelper.CheckExpErrWithID(t, "Stat - used by a Metric", err,
+		testhelper.MkExpErr(`the name "requests" is in use by a Metric`))
+
+	testhelper.DiffInt(t, "StatSetMetrics", "len", ss.Len(), 2)
+	testhelper.DiffStringSlice(t, "StatSetMetrics", "names",
+		ss.Names(), []string{"latency", "size"})
+	testhelper.DiffStringSlice(t, "StatSetMetrics", "metric names",
+		ss.MetricNames(), []string{"queue", "requests"})
+
+	m, ok := ss.LookupMetric("size")
+	testhelper.DiffBool(t, "LookupMetric - Stat", "found", ok, true)
+	testhelper.DiffBool(t, "LookupMetric - Stat", "is size", m == size, true)
+	m, ok = ss.LookupMetric("requests")
+	testhelper.DiffBool(t, "LookupMetric - Counter", "found", ok, true)
+	testhelper.DiffBool(t, "LookupMetric - Counter", "is reqs",
+		m == reqs, true)
+
+	testhelper.DiffString(t, "StatSetMetrics", "String", ss.String(),
+		"latency : "+ss.StatOrPanic("latency").String()+"\n"+
+			"queue   : value: 3.00e+00, min: 3.00e+00, max: 3.00e+00\n"+
+			"requests: total: 1.00e+00\n"+
+			"size    : "+size.String()+"\n")
+
+	testhelper.DiffBool(t, "Remove - Counter", "removed",
+		ss.Remove("requests"), true)
+	_, ok = ss.LookupMetric("requests")
+	testhelper.DiffBool(t, "Remove - Counter", "found", ok, false)
+}
@@ -1,6 +1,8 @@
 package smpls
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/nickwells/testhelper.mod/v2/testhelper"
@@ -313,3 +315,312 @@ func floatSliceDiffers(a, b []float64) bool {
 	}
 	return false
 }
+
+func TestStatNoHistNoCache(t *testing.T) {
+	noHist := NewStatOrPanic("units", StatNoHist())
+	addSeq(noHist, 1, 1, 100)
+	id := "StatNoHist"
+	if noHist.cache != nil || noHist.hist != nil {
+		t.Log(id)
+		t.Errorf("\t: the Stat should have neither a cache nor a histogram\n")
+	}
+	testhelper.DiffFloat(t, id, "mean", noHist.Mean(), 50.5, 0.0)
+	testhelper.DiffFloat(t, id, "max", noHist.Max(), 100, 0.0)
+	testhelper.DiffString(t, id, "hist", noHist.Hist(), "")
+	testhelper.DiffBool(t, id, "percentile is NaN",
+		math.IsNaN(noHist.Percentile(50)), true)
+
+	noCache := NewStatOrPanic("units", StatNoCache())
+	id = "StatNoCache"
+	addSeq(noCache, 1, 1, 10)
+	testhelper.DiffFloat(t, id, "percentile - values held in mins",
+		noCache.Percentile(50), 5.5, 0.0)
+	if noCache.Hist() == "" {
+		t.Log(id)
+		t.Errorf("\t: the histogram should be available\n")
+	}
+	addSeq(noCache, 11, 1, 90)
+	if noCache.cache != nil {
+		t.Log(id)
+		t.Errorf("\t: the Stat should have no cache\n")
+	}
+	histCount := noCache.underflow + noCache.overflow
+	for _, count := range noCache.hist {
+		histCount += count
+	}
+	testhelper.DiffInt(t, id, "histogram count", histCount, 100)
+	testhelper.DiffFloat(t, id, "bucket start", noCache.bucketStart, 1, 0.0)
+}
+
+func TestNewStatErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:     testhelper.MkID("no hist, then bucket count"),
+			opts:   []StatOpt{StatNoHist(), StatHistBucketCount(5)},
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+		},
+		{
+			ID:   testhelper.MkID("bucket count, then no hist"),
+			opts: []StatOpt{StatHistBucketCount(5), StatNoHist()},
+			ExpErr: testhelper.MkExpErr(
+				"the histogram slice has already been created"),
+		},
+		{
+			ID:     testhelper.MkID("no cache, then cache size"),
+			opts:   []StatOpt{StatNoCache(), StatCacheSize(5)},
+			ExpErr: testhelper.MkExpErr("the Stat has no cache"),
+		},
+		{
+			ID:   testhelper.MkID("cache size, then no cache"),
+			opts: []StatOpt{StatCacheSize(5), StatNoCache()},
+			ExpErr: testhelper.MkExpErr(
+				"the cache of values has already been created"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestCompensatedSum(t *testing.T) {
+	const (
+		count  = 1000000
+		val    = 0.1
+		expSum = count * val
+	)
+
+	naive := NewStatOrPanic("units", StatNoHist())
+	compensated := NewStatOrPanic("units", StatNoHist(), StatCompensatedSum())
+	for i := 0; i < count; i++ {
+		naive.Add(val)
+		compensated.Add(val)
+	}
+
+	naiveErr := math.Abs(naive.Sum() - expSum)
+	compErr := math.Abs(compensated.Sum() - expSum)
+	testhelper.DiffFloat(t, "compensated sum", "sum",
+		compensated.Sum(), expSum, 1e-9)
+	if compErr >= naiveErr {
+		t.Log("compensated sum")
+		t.Logf("\t: naive error: %g, compensated error: %g\n",
+			naiveErr, compErr)
+		t.Errorf("\t: the compensated sum should be more accurate\n")
+	}
+	testhelper.DiffFloat(t, "compensated sum", "SD",
+		compensated.StdDev(), 0.0, 1e-6)
+
+	compensated.Reset()
+	testhelper.DiffFloat(t, "compensated sum - after Reset", "sum",
+		compensated.Sum(), 0.0, 0.0)
+}
+
+func TestHotPath(t *testing.T) {
+	// tracking the info stops the Stat from using the hot path
+	slow := NewStatOrPanic("units", StatCacheSize(50), StatTrackInfo())
+	hot := NewStatOrPanic("units", StatCacheSize(50))
+
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 1000; i++ {
+		v := r.NormFloat64() * 100
+		slow.Add(v)
+		hot.Add(v)
+	}
+
+	testhelper.DiffBool(t, "hot path", "slow is hot", slow.hot, false)
+	testhelper.DiffBool(t, "hot path", "hot is hot", hot.hot, true)
+	testhelper.DiffString(t, "hot path", "String", hot.String(), slow.String())
+	testhelper.DiffString(t, "hot path", "Hist", hot.Hist(), slow.Hist())
+	testhelper.DiffFloatSlice(t, "hot path", "mins", hot.mins, slow.mins, 0.0)
+	testhelper.DiffFloatSlice(t, "hot path", "maxs", hot.maxs, slow.maxs, 0.0)
+
+	hot.Reset()
+	testhelper.DiffBool(t, "hot path - after Reset", "hot", hot.hot, false)
+}
+
+func TestMinMaxSpread(t *testing.T) {
+	s := NewStatOrPanic("units", StatMinMaxCount(3))
+	testhelper.DiffFloat(t, "no values", "min spread", s.MinSpread(), 0, 0)
+	testhelper.DiffFloat(t, "no values", "max range", s.MaxRange(), 0, 0)
+
+	s.Add(1, 2, 3, 50, 100, 1000)
+	testhelper.DiffFloat(t, "values", "min spread",
+		s.MinSpread(), math.Sqrt(2.0/3.0), 1e-12)
+	testhelper.DiffFloat(t, "values", "min range", s.MinRange(), 2, 0)
+	testhelper.DiffFloat(t, "values", "max spread",
+		s.MaxSpread(), calcSD([]float64{50, 100, 1000}), 1e-12)
+	testhelper.DiffFloat(t, "values", "max range", s.MaxRange(), 950, 0)
+}
+
+// benchmarkAdd adds values to a Stat created with the given options
+func benchmarkAdd(b *testing.B, opts ...StatOpt) {
+	s := NewStatOrPanic("units", opts...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(float64(i % 1000))
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	benchmarkAdd(b)
+}
+
+func BenchmarkAddCompensated(b *testing.B) {
+	benchmarkAdd(b, StatCompensatedSum())
+}
+
+func BenchmarkAddRandom(b *testing.B) {
+	vals := make([]float64, 1024)
+	r := rand.New(rand.NewSource(1))
+	for i := range vals {
+		vals[i] = r.ExpFloat64() * 100
+	}
+
+	s := NewStatOrPanic("units")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(vals[i%len(vals)])
+	}
+}
+
+func BenchmarkAddFillMinMax(b *testing.B) {
+	s := NewStatOrPanic("units", StatNoHist())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%dfltMinMaxCount == 0 {
+			s.Reset()
+		}
+		s.Add(float64((i * 7919) % 1000))
+	}
+}
+
+func TestAddAllocs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts []StatOpt
+	}{
+		{ID: testhelper.MkID("default")},
+		{ID: testhelper.MkID("no cache"), opts: []StatOpt{StatNoCache()}},
+		{ID: testhelper.MkID("no hist"), opts: []StatOpt{StatNoHist()}},
+		{
+			ID:   testhelper.MkID("bucket sums"),
+			opts: []StatOpt{StatHistBucketSums()},
+		},
+		{
+			ID:   testhelper.MkID("compensated"),
+			opts: []StatOpt{StatCompensatedSum()},
+		},
+		{ID: testhelper.MkID("info"), opts: []StatOpt{StatTrackInfo()}},
+		{
+			ID: testhelper.MkID("rebin"),
+			opts: []StatOpt{
+				StatHistAutoRebin(0.01), StatHistBucketSums(),
+			},
+		},
+		{ID: testhelper.MkID("log"), opts: []StatOpt{StatLogTransform(10)}},
+		{
+			ID:   testhelper.MkID("anchored"),
+			opts: []StatOpt{StatHistAnchor(1e6), StatHistBucketSums()},
+		},
+		{ID: testhelper.MkID("runs"), opts: []StatOpt{StatTrackRuns(0)}},
+		{ID: testhelper.MkID("distinct"), opts: []StatOpt{StatTrackDistinct(0)}},
+		{
+			ID:   testhelper.MkID("reservoir"),
+			opts: []StatOpt{StatReservoirSize(100)},
+		},
+		{
+			ID:   testhelper.MkID("sampled"),
+			opts: []StatOpt{StatSampleRate(0.5)},
+		},
+		{ID: testhelper.MkID("rate"), opts: []StatOpt{StatRate()}},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", tc.opts...)
+		// the values grow so that the histogram is rebinned, if allowed
+		allocs := testing.AllocsPerRun(1, func() {
+			s.Reset()
+			for i := 0; i < 3*dfltCacheSize; i++ {
+				s.Add(float64(i % (1 + i/100)))
+			}
+		})
+		testhelper.DiffFloat(t, tc.IDStr(), "allocations", allocs, 0, 0)
+		if s.hist != nil && s.histPending() != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the histogram was not laid out after the Reset")
+		}
+	}
+}
+
+func TestResetReuse(t *testing.T) {
+	reused := NewStatOrPanic("units", StatCacheSize(100))
+	addSeq(reused, 100.0, -0.5, 300)
+	reused.Reset()
+
+	fresh := NewStatOrPanic("units", StatCacheSize(100))
+	for _, s := range []*Stat{reused, fresh} {
+		addSeq(s, 1.0, 0.25, 500)
+	}
+
+	testhelper.DiffString(t, "Reset", "histogram", reused.Hist(), fresh.Hist())
+	testhelper.DiffString(t, "Reset", "string", reused.String(), fresh.String())
+	testhelper.DiffInt(t, "Reset", "cache size",
+		cap(reused.cacheBuf), cap(fresh.cacheBuf))
+}
+
+func TestSampleStdDev(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts      []StatOpt
+		vals      []float64
+		expPopSD  float64
+		expSampSD float64
+		expSD     float64
+	}{
+		{
+			ID: testhelper.MkID("no values"),
+		},
+		{
+			ID:   testhelper.MkID("one value"),
+			vals: []float64{3},
+		},
+		{
+			ID:        testhelper.MkID("several values"),
+			vals:      []float64{2, 4, 4, 4, 5, 5, 7, 9},
+			expPopSD:  2,
+			expSampSD: math.Sqrt(32.0 / 7.0),
+			expSD:     2,
+		},
+		{
+			ID:        testhelper.MkID("several values, Bessel correction"),
+			opts:      []StatOpt{StatBesselCorrection()},
+			vals:      []float64{2, 4, 4, 4, 5, 5, 7, 9},
+			expPopSD:  2,
+			expSampSD: math.Sqrt(32.0 / 7.0),
+			expSD:     math.Sqrt(32.0 / 7.0),
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", tc.opts...)
+		s.AddVals(tc.vals...)
+		testhelper.DiffFloat(t, tc.IDStr(), "population SD",
+			s.PopulationStdDev(), tc.expPopSD, 1e-12)
+		testhelper.DiffFloat(t, tc.IDStr(), "sample SD",
+			s.SampleStdDev(), tc.expSampSD, 1e-12)
+		testhelper.DiffFloat(t, tc.IDStr(), "SD", s.StdDev(), tc.expSD, 1e-12)
+
+		snap := s.Snapshot()
+		testhelper.DiffFloat(t, tc.IDStr(), "Snapshot sample SD",
+			snap.SampleStdDev, tc.expSampSD, 1e-12)
+		testhelper.DiffFloat(t, tc.IDStr(), "Snapshot SD",
+			snap.StdDev, tc.expSD, 1e-12)
+		testhelper.DiffFloat(t, tc.IDStr(), "sample SD field",
+			s.fieldVal(FieldSampleStdDev), tc.expSampSD, 1e-12)
+	}
+}
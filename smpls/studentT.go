@@ -0,0 +1,117 @@
+package smpls
+
+import "math"
+
+const (
+	betaCFMaxIter = 200
+	betaCFEpsilon = 1e-14
+	betaCFTiny    = 1e-300
+)
+
+// betaCF evaluates the continued fraction for the regularized incomplete
+// beta function by the modified Lentz's method
+func betaCF(a, b, x float64) float64 {
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < betaCFTiny {
+		d = betaCFTiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= betaCFMaxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < betaCFTiny {
+			d = betaCFTiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < betaCFTiny {
+			c = betaCFTiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < betaCFTiny {
+			d = betaCFTiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < betaCFTiny {
+			c = betaCFTiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < betaCFEpsilon {
+			break
+		}
+	}
+	return h
+}
+
+// regIncBeta returns the regularized incomplete beta function, I_x(a, b)
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log1p(-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(a, b, x) / a
+	}
+	return 1 - front*betaCF(b, a, 1-x)/b
+}
+
+// studentTCDF returns the probability that a value from Student's t
+// distribution with df degrees of freedom is less than or equal to t
+func studentTCDF(t, df float64) float64 {
+	if math.IsNaN(t) || math.IsNaN(df) || df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(t, 0) {
+		if t > 0 {
+			return 1
+		}
+		return 0
+	}
+	tail := regIncBeta(df/2, 0.5, df/(df+t*t)) / 2
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentTQuantile returns the value below which the given fraction, p, of
+// Student's t distribution with df degrees of freedom lies. It is found by
+// bisection of the CDF.
+func studentTQuantile(p, df float64) float64 {
+	if math.IsNaN(p) || math.IsNaN(df) || df <= 0 || p <= 0 || p >= 1 {
+		return math.NaN()
+	}
+	if p < 0.5 {
+		return -studentTQuantile(1-p, df)
+	}
+
+	lo, hi := 0.0, 1.0
+	for studentTCDF(hi, df) < p {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 100 && hi-lo > 1e-12*hi; i++ {
+		mid := (lo + hi) / 2
+		if studentTCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,33 @@
+package smpls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStudentT(t *testing.T) {
+	// the values are taken from published tables of the t distribution
+	testCases := []struct {
+		df, p, t float64
+	}{
+		{df: 1, p: 0.975, t: 12.706},
+		{df: 2, p: 0.95, t: 2.920},
+		{df: 5, p: 0.975, t: 2.571},
+		{df: 10, p: 0.99, t: 2.764},
+		{df: 30, p: 0.975, t: 2.042},
+		{df: 1000, p: 0.975, t: 1.962},
+	}
+
+	for _, tc := range testCases {
+		id := fmt.Sprintf("df: %g, p: %g", tc.df, tc.p)
+		testhelper.DiffFloat(t, id, "quantile",
+			studentTQuantile(tc.p, tc.df), tc.t, 0.001)
+		testhelper.DiffFloat(t, id, "lower quantile",
+			studentTQuantile(1-tc.p, tc.df), -tc.t, 0.001)
+		testhelper.DiffFloat(t, id, "CDF",
+			studentTCDF(tc.t, tc.df), tc.p, 0.0001)
+	}
+	testhelper.DiffFloat(t, "median", "CDF", studentTCDF(0, 3), 0.5, 1e-12)
+}
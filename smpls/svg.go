@@ -0,0 +1,185 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const (
+	dfltSVGWidth  = 600
+	dfltSVGHeight = 300
+	minSVGWidth   = 100
+	minSVGHeight  = 80
+
+	dfltSVGBarColour = "steelblue"
+	dfltSVGCDFColour = "darkorange"
+
+	svgMargin = 40
+)
+
+// svgChart holds the settings for an SVG chart of the histogram
+type svgChart struct {
+	width, height int
+	title         string
+	barColour     string
+	cdfColour     string
+	showCDF       bool
+}
+
+// SVGOpt is the type of an option function that can be passed to
+// RenderSVG
+type SVGOpt func(c *svgChart) error
+
+// SVGSize returns a function that will set the width and height of the SVG
+// chart in pixels
+func SVGSize(width, height int) SVGOpt {
+	return func(c *svgChart) error {
+		if width < minSVGWidth {
+			return invalidValue("Invalid SVG width (%d) - it must be >= %d",
+				width, minSVGWidth)
+		}
+		if height < minSVGHeight {
+			return invalidValue("Invalid SVG height (%d) - it must be >= %d",
+				height, minSVGHeight)
+		}
+		c.width, c.height = width, height
+		return nil
+	}
+}
+
+// SVGTitle returns a function that will set the title shown above the SVG
+// chart. By default the title shows the units and the number of values.
+func SVGTitle(title string) SVGOpt {
+	return func(c *svgChart) error {
+		c.title = title
+		return nil
+	}
+}
+
+// SVGColours returns a function that will set the colours of the histogram
+// bars and of the CDF curve. The colours can be given in any form that SVG
+// accepts such as "red" or "#ff0000". An empty string leaves the colour
+// unchanged.
+func SVGColours(bar, cdf string) SVGOpt {
+	return func(c *svgChart) error {
+		if bar != "" {
+			c.barColour = bar
+		}
+		if cdf != "" {
+			c.cdfColour = cdf
+		}
+		return nil
+	}
+}
+
+// SVGShowCDF returns a function that will add the cumulative distribution
+// of the values to the SVG chart as a curve scaled from 0% at the bottom of
+// the chart to 100% at the top
+func SVGShowCDF() SVGOpt {
+	return func(c *svgChart) error {
+		c.showCDF = true
+		return nil
+	}
+}
+
+// RenderSVG writes a standalone SVG bar chart of the histogram to the
+// Writer. The options control the size, title and colours of the chart
+// and whether the cumulative distribution is shown as well. Each bar is
+// a histogram bucket; the number of values in the underflow and overflow
+// is shown below the chart. It returns an error if the options are bad, if
+// the Stat has no histogram or if the chart cannot be written.
+func (s Stat) RenderSVG(w io.Writer, opts ...SVGOpt) error {
+	c := svgChart{
+		width:     dfltSVGWidth,
+		height:    dfltSVGHeight,
+		barColour: dfltSVGBarColour,
+		cdfColour: dfltSVGCDFColour,
+		title:     fmt.Sprintf("%s: %d values", s.units, s.count),
+	}
+	for _, o := range opts {
+		if err := o(&c); err != nil {
+			return err
+		}
+	}
+	if s.hist == nil {
+		return errors.New("the Stat has no histogram")
+	}
+
+	s = s.histStat()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg"`+
+		` width="%d" height="%d" viewBox="0 0 %d %d"`+
+		` font-family="sans-serif" font-size="12">`+"\n",
+		c.width, c.height, c.width, c.height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n",
+		c.width/2, svgMargin/2, html.EscapeString(c.title))
+
+	if s.count > 0 {
+		s.svgBody(&b, c)
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// svgBody writes the axes, bars, labels and, optionally, the CDF curve.
+// The histogram must be populated and hold at least one value.
+func (s Stat) svgBody(b *strings.Builder, c svgChart) {
+	left, top := float64(svgMargin), float64(svgMargin)
+	plotW := float64(c.width - 2*svgMargin)
+	plotH := float64(c.height - 2*svgMargin)
+	bottom := top + plotH
+
+	maxCount := 1
+	for _, count := range s.hist {
+		maxCount = max(maxCount, count)
+	}
+	barW := plotW / float64(len(s.hist))
+
+	for i, count := range s.hist {
+		if count == 0 {
+			continue
+		}
+		h := plotH * float64(count) / float64(maxCount)
+		lo, hi := s.bucketLimits(i)
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f"`+
+			` fill="%s"><title>%.3g to %.3g: %d</title></rect>`+"\n",
+			left+float64(i)*barW, bottom-h, barW, h,
+			html.EscapeString(c.barColour), lo, hi, count)
+	}
+
+	fmt.Fprintf(b, `<path d="M%.1f %.1fV%.1fH%.1f" fill="none" stroke="black"/>`+
+		"\n", left, top, bottom, left+plotW)
+
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="start">%.3g</text>`+
+		"\n", left, bottom+15, s.histStart())
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end">%.3g</text>`+
+		"\n", left+plotW, bottom+15, end)
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end">%d</text>`+
+		"\n", left-4, top+4, maxCount)
+	fmt.Fprintf(b,
+		`<text x="%.1f" y="%.1f" text-anchor="middle">`+
+			`underflow: %d, overflow: %d</text>`+"\n",
+		left+plotW/2, bottom+30, s.underflow, s.overflow)
+
+	if c.showCDF {
+		var pts []string
+		for i, p := range s.CDF() {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f",
+				left+float64(i)*barW, bottom-plotH*p.Fraction))
+		}
+		fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s"`+
+			` stroke-width="2"/>`+"\n",
+			strings.Join(pts, " "), html.EscapeString(c.cdfColour))
+		fmt.Fprintf(b,
+			`<text x="%.1f" y="%.1f" text-anchor="start">100%%</text>`+"\n",
+			left+plotW+4, top+4)
+	}
+}
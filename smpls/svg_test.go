@@ -0,0 +1,107 @@
+package smpls
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// checkWellFormed reports an error if the SVG is not well-formed XML
+func checkWellFormed(t *testing.T, id, svg string) {
+	t.Helper()
+
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			t.Log(id)
+			t.Errorf("\t: the SVG is not well-formed: %v\n", err)
+			return
+		}
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	s := NewStatOrPanic("ms", StatHistBucketCount(4))
+	s.Add(1, 2, 2, 3, 3, 3, 4)
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		s         *Stat
+		opts      []SVGOpt
+		expStrs   []string
+		unexpStrs []string
+	}{
+		{
+			ID: testhelper.MkID("default"),
+			s:  s,
+			expStrs: []string{
+				`width="600" height="300"`,
+				">ms: 7 values</text>",
+				`fill="steelblue"`,
+				"underflow: 0, overflow: 0",
+			},
+			unexpStrs: []string{"<polyline"},
+		},
+		{
+			ID: testhelper.MkID("with options"),
+			s:  s,
+			opts: []SVGOpt{
+				SVGSize(400, 200),
+				SVGTitle("latency <p99>"),
+				SVGColours("red", ""),
+				SVGShowCDF(),
+			},
+			expStrs: []string{
+				`width="400" height="200"`,
+				">latency &lt;p99&gt;</text>",
+				`fill="red"`,
+				`<polyline points="40.0,160.0 `,
+				`stroke="darkorange"`,
+			},
+		},
+		{
+			ID:      testhelper.MkID("empty"),
+			s:       NewStatOrPanic("ms"),
+			expStrs: []string{">ms: 0 values</text>"},
+			unexpStrs: []string{
+				"<path",
+			},
+		},
+		{
+			ID:     testhelper.MkID("bad size"),
+			s:      s,
+			opts:   []SVGOpt{SVGSize(10, 200)},
+			ExpErr: testhelper.MkExpErr("Invalid SVG width (10)"),
+		},
+		{
+			ID:     testhelper.MkID("no hist"),
+			s:      NewStatOrPanic("ms", StatNoHist()),
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+		},
+	}
+
+	for _, tc := range testCases {
+		var b strings.Builder
+		err := tc.s.RenderSVG(&b, tc.opts...)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			svg := b.String()
+			checkWellFormed(t, tc.IDStr(), svg)
+			testhelper.ShouldContain(t, tc.IDStr(), "SVG", svg, tc.expStrs)
+			for _, str := range tc.unexpStrs {
+				if strings.Contains(svg, str) {
+					t.Log(tc.IDStr())
+					t.Errorf("\t: the SVG should not contain %q\n", str)
+				}
+			}
+		}
+	}
+}
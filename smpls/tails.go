@@ -0,0 +1,229 @@
+package smpls
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	minTailCapture = 1
+	maxTailCapture = 1000
+)
+
+// Tail describes the values which have gone into the underflow or the
+// overflow of the histogram (see StatTailCapture). Count and Sum are the
+// number and the sum of the values captured and Extremes holds the most
+// extreme of them, the most extreme first.
+type Tail struct {
+	Count    int
+	Sum      float64
+	Extremes []float64
+}
+
+// Mean returns the mean of the values captured or 0.0 if there are none
+func (t Tail) Mean() float64 {
+	if t.Count == 0 {
+		return 0.0
+	}
+	return t.Sum / float64(t.Count)
+}
+
+// String returns the count and mean of the values captured and the most
+// extreme of them
+func (t Tail) String() string {
+	vals := make([]string, 0, len(t.Extremes))
+	for _, v := range t.Extremes {
+		vals = append(vals, strconv.FormatFloat(v, 'g', 4, 64))
+	}
+	return fmt.Sprintf("%d values, mean: %.4g, extremes: %s",
+		t.Count, t.Mean(), strings.Join(vals, ", "))
+}
+
+// tailTracker records the values added to the underflow and the overflow
+// of the histogram. The lows are the smallest values, in ascending order,
+// and the highs the largest, in descending order, so that the most extreme
+// comes first in each.
+type tailTracker struct {
+	k int
+
+	lows     []float64
+	lowCount int
+	lowSum   float64
+
+	highs     []float64
+	highCount int
+	highSum   float64
+}
+
+// newTailTracker creates a tailTracker keeping the k most extreme values
+// in each tail
+func newTailTracker(k int) *tailTracker {
+	return &tailTracker{
+		k:     k,
+		lows:  make([]float64, 0, k),
+		highs: make([]float64, 0, k),
+	}
+}
+
+// keepExtreme adds the value to the slice of at most k values, ordered
+// most extreme first according to the cmp function, if it is among the
+// most extreme k values
+func keepExtreme(vals []float64, k int, v float64,
+	cmp func(a, b float64) int,
+) []float64 {
+	i, _ := slices.BinarySearchFunc(vals, v, cmp)
+	if i >= k {
+		return vals
+	}
+	if len(vals) == k {
+		vals = vals[:k-1]
+	}
+	return slices.Insert(vals, i, v)
+}
+
+// lowFirst orders values with the lowest first
+func lowFirst(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// highFirst orders values with the highest first
+func highFirst(a, b float64) int {
+	return lowFirst(b, a)
+}
+
+// addLow records a value added to the underflow
+func (tt *tailTracker) addLow(v float64) {
+	tt.lowCount++
+	tt.lowSum += v
+	tt.lows = keepExtreme(tt.lows, tt.k, v, lowFirst)
+}
+
+// addHigh records a value added to the overflow
+func (tt *tailTracker) addHigh(v float64) {
+	tt.highCount++
+	tt.highSum += v
+	tt.highs = keepExtreme(tt.highs, tt.k, v, highFirst)
+}
+
+// reset discards the values recorded
+func (tt *tailTracker) reset() {
+	tt.lows = tt.lows[:0]
+	tt.lowCount = 0
+	tt.lowSum = 0
+	tt.highs = tt.highs[:0]
+	tt.highCount = 0
+	tt.highSum = 0
+}
+
+// clone returns an independent copy of the tailTracker
+func (tt tailTracker) clone() *tailTracker {
+	tt.lows = append(make([]float64, 0, tt.k), tt.lows...)
+	tt.highs = append(make([]float64, 0, tt.k), tt.highs...)
+	return &tt
+}
+
+// merge adds the values recorded by the other tailTracker
+func (tt *tailTracker) merge(o *tailTracker) {
+	tt.lowCount += o.lowCount
+	tt.lowSum += o.lowSum
+	for _, v := range o.lows {
+		tt.lows = keepExtreme(tt.lows, tt.k, v, lowFirst)
+	}
+	tt.highCount += o.highCount
+	tt.highSum += o.highSum
+	for _, v := range o.highs {
+		tt.highs = keepExtreme(tt.highs, tt.k, v, highFirst)
+	}
+}
+
+// check returns an error if the tailTracker is not valid
+func (tt tailTracker) check() error {
+	if tt.k < minTailCapture || tt.k > maxTailCapture ||
+		len(tt.lows) > tt.k || len(tt.highs) > tt.k ||
+		len(tt.lows) > tt.lowCount || len(tt.highs) > tt.highCount ||
+		!slices.IsSortedFunc(tt.lows, lowFirst) ||
+		!slices.IsSortedFunc(tt.highs, highFirst) {
+		return fmt.Errorf("bad tail values (%d of %d and %d of %d, limit %d)",
+			len(tt.lows), tt.lowCount, len(tt.highs), tt.highCount, tt.k)
+	}
+	return nil
+}
+
+// StatTailCapture returns a function that will make the Stat keep the k
+// most extreme values that go into the underflow and into the overflow of
+// its histogram, together with the count and sum of all such values. This
+// shows how far outside the histogram those values were, which is
+// otherwise lost. The values are shown by Hist and given by UnderflowTail
+// and OverflowTail.
+//
+// The values are those added since the histogram was last laid out; when
+// the histogram is rebuilt to cover them (see StatHistAutoRebin) or when a
+// Stat is merged into it with a different layout they are discarded as
+// they are then in the histogram. Values reaching the underflow or
+// overflow through a Merge are only captured if both Stats capture them
+// and the histograms have the same layout.
+func StatTailCapture(k int) StatOpt {
+	return func(s *Stat) error {
+		if s.tails != nil {
+			return repeatedOption("the tail capture has already been set")
+		}
+		if s.noHist {
+			return conflictingOptions("the Stat has no histogram")
+		}
+		if k < minTailCapture || k > maxTailCapture {
+			return invalidValue(
+				"Invalid tail capture count (%d) - it must be >= %d and <= %d",
+				k, minTailCapture, maxTailCapture)
+		}
+
+		s.tails = newTailTracker(k)
+		return nil
+	}
+}
+
+// TailCapture returns the number of extreme values kept in each tail (see
+// StatTailCapture) or 0 if the Stat is not capturing them
+func (s Stat) TailCapture() int {
+	if s.tails == nil {
+		return 0
+	}
+	return s.tails.k
+}
+
+// UnderflowTail returns a description of the values which have gone into
+// the underflow of the histogram (see StatTailCapture). The Tail is empty
+// if the Stat is not capturing them.
+func (s Stat) UnderflowTail() Tail {
+	s = s.histStat()
+	if s.tails == nil {
+		return Tail{}
+	}
+	return Tail{
+		Count:    s.tails.lowCount,
+		Sum:      s.tails.lowSum,
+		Extremes: slices.Clone(s.tails.lows),
+	}
+}
+
+// OverflowTail returns a description of the values which have gone into
+// the overflow of the histogram (see StatTailCapture). The Tail is empty
+// if the Stat is not capturing them.
+func (s Stat) OverflowTail() Tail {
+	s = s.histStat()
+	if s.tails == nil {
+		return Tail{}
+	}
+	return Tail{
+		Count:    s.tails.highCount,
+		Sum:      s.tails.highSum,
+		Extremes: slices.Clone(s.tails.highs),
+	}
+}
@@ -0,0 +1,86 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTailCapture(t *testing.T) {
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5),
+		StatTailCapture(2))
+	testhelper.DiffInt(t, "capture", "TailCapture", s.TailCapture(), 2)
+	addSeq(s, 1, 1, 10)
+	s.Add(-5, 50, -100, 20, -7, 1000, 30)
+
+	low := s.UnderflowTail()
+	testhelper.DiffInt(t, "underflow", "count", low.Count, 3)
+	testhelper.DiffFloat(t, "underflow", "sum", low.Sum, -112, 0)
+	if err := testhelper.DiffVals(low.Extremes, []float64{-100, -7}); err != nil {
+		t.Errorf("underflow: bad extremes: %v\n", err)
+	}
+	high := s.OverflowTail()
+	testhelper.DiffInt(t, "overflow", "count", high.Count, 4)
+	testhelper.DiffFloat(t, "overflow", "mean", high.Mean(), 1100.0/4, 0)
+	if err := testhelper.DiffVals(high.Extremes, []float64{1000, 50}); err != nil {
+		t.Errorf("overflow: bad extremes: %v\n", err)
+	}
+	testhelper.DiffString(t, "overflow", "String", high.String(),
+		"4 values, mean: 275, extremes: 1000, 50")
+	testhelper.ShouldContain(t, "Hist", "tails", s.Hist(),
+		[]string{
+			"underflow tail: 3 values, mean: -37.33, extremes: -100, -7",
+			"overflow tail: 4 values",
+		})
+
+	other := s.Clone()
+	other.Add(-1000)
+	if err := s.Merge(other); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	low = s.UnderflowTail()
+	testhelper.DiffInt(t, "merged", "count", low.Count, 7)
+	if err := testhelper.DiffVals(low.Extremes,
+		[]float64{-1000, -100}); err != nil {
+		t.Errorf("merged: bad extremes: %v\n", err)
+	}
+
+	s.Reset()
+	testhelper.DiffInt(t, "reset", "count", s.OverflowTail().Count, 0)
+
+	pending := NewStatOrPanic("ms", StatCacheSize(100), StatTailCapture(2))
+	addSeq(pending, 1, 1, 10)
+	testhelper.DiffInt(t, "pending", "count", pending.OverflowTail().Count, 0)
+	testhelper.DiffInt(t, "no capture", "count",
+		NewStatOrPanic("ms").UnderflowTail().Count, 0)
+}
+
+func TestTailCaptureErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("bad count"),
+			opts: []StatOpt{StatTailCapture(0)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid tail capture count (0) - it must be >= 1 and <= 1000"),
+		},
+		{
+			ID:     testhelper.MkID("repeated"),
+			opts:   []StatOpt{StatTailCapture(1), StatTailCapture(2)},
+			ExpErr: testhelper.MkExpErr("the tail capture has already been set"),
+		},
+		{
+			ID:     testhelper.MkID("no histogram"),
+			opts:   []StatOpt{StatTailCapture(1), StatNoHist()},
+			ExpErr: testhelper.MkExpErr("the Stat has no histogram"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("ms", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
@@ -0,0 +1,420 @@
+package smpls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+const (
+	minTDigestCompression  = 10.0
+	dfltTDigestCompression = 100.0
+
+	// tdigestBufferFactor sets the number of values buffered before they
+	// are merged into the centroids as a multiple of the compression
+	tdigestBufferFactor = 5
+
+	tdigestSerialMagic   = "tdig"
+	tdigestSerialVersion = 1
+)
+
+// centroid records the mean of a group of neighbouring values and how many
+// values there are in the group
+type centroid struct {
+	mean  float64
+	count int
+}
+
+// TDigest is a compact, mergeable sketch of a distribution of values from
+// which quantiles can be estimated. It is particularly accurate at the
+// extremes, so it is a good choice for estimating percentiles such as the
+// p99 or p99.9. The size of the sketch is bounded by the compression, not
+// by the number of values, and TDigests built separately (for instance by
+// different workers) can be merged to give the quantiles of all the values.
+// A TDigest can be saved and loaded (see Save and Load) so that it can be
+// sent to the place where the TDigests are merged.
+//
+// This implements the merging t-digest of Ted Dunning and Otmar Ertl.
+//
+// Note that operations on this are not thread safe and it should be mutex
+// protected if it is going to be updated by multiple threads.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       int
+	minVal      float64
+	maxVal      float64
+}
+
+// NewTDigest creates a new TDigest. The compression controls the trade-off
+// between accuracy and size; the sketch holds no more than about the
+// compression number of centroids. It must be at least 10; a compression
+// of 0 gives the default of 100.
+func NewTDigest(compression float64) (*TDigest, error) {
+	if compression == 0 {
+		compression = dfltTDigestCompression
+	}
+	if !(compression >= minTDigestCompression) || math.IsInf(compression, 1) {
+		return nil, invalidValue("Invalid compression (%g) - it must be >= %g",
+			compression, minTDigestCompression)
+	}
+
+	return &TDigest{
+		compression: compression,
+		buffer: make([]centroid, 0,
+			int(tdigestBufferFactor*compression)),
+	}, nil
+}
+
+// NewTDigestOrPanic creates a new TDigest and will panic if any errors are
+// detected
+func NewTDigestOrPanic(compression float64) *TDigest {
+	td, err := NewTDigest(compression)
+	if err != nil {
+		panic(err)
+	}
+	return td
+}
+
+// Add adds at least one new value to the TDigest. NaN values are ignored.
+func (td *TDigest) Add(v float64, vals ...float64) {
+	td.addCentroid(centroid{mean: v, count: 1})
+	for _, v := range vals {
+		td.addCentroid(centroid{mean: v, count: 1})
+	}
+}
+
+// addCentroid adds the centroid to the buffer, compressing the TDigest if
+// the buffer is full
+func (td *TDigest) addCentroid(c centroid) {
+	if math.IsNaN(c.mean) || c.count <= 0 {
+		return
+	}
+	if td.count == 0 {
+		td.minVal, td.maxVal = c.mean, c.mean
+	} else {
+		td.minVal = math.Min(td.minVal, c.mean)
+		td.maxVal = math.Max(td.maxVal, c.mean)
+	}
+	td.count += c.count
+
+	td.buffer = append(td.buffer, c)
+	if len(td.buffer) == cap(td.buffer) {
+		td.compress()
+	}
+}
+
+// scale is the t-digest scale function, k1, which maps a quantile to an
+// index such that each centroid spans at most one unit
+func (td TDigest) scale(q float64) float64 {
+	return td.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// invScale is the inverse of the scale function
+func (td TDigest) invScale(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/td.compression) + 1) / 2
+}
+
+// merged returns the centroids merged with the buffered centroids
+func (td TDigest) merged() []centroid {
+	if len(td.buffer) == 0 {
+		return td.centroids
+	}
+
+	all := make([]centroid, 0, len(td.centroids)+len(td.buffer))
+	all = append(append(all, td.centroids...), td.buffer...)
+	slices.SortStableFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	total := float64(td.count)
+	merged := all[:1]
+	qSoFar := 0.0
+	qLimit := td.invScale(td.scale(qSoFar) + 1)
+	for _, c := range all[1:] {
+		cur := &merged[len(merged)-1]
+		if qSoFar+float64(cur.count+c.count)/total <= qLimit {
+			n := cur.count + c.count
+			cur.mean += (c.mean - cur.mean) * float64(c.count) / float64(n)
+			cur.count = n
+			continue
+		}
+		qSoFar += float64(cur.count) / total
+		qLimit = td.invScale(td.scale(qSoFar) + 1)
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// compress merges the buffered values into the centroids
+func (td *TDigest) compress() {
+	td.centroids = slices.Clip(td.merged())
+	td.buffer = td.buffer[:0]
+}
+
+// Count returns the number of values added
+func (td TDigest) Count() int {
+	return td.count
+}
+
+// Compression returns the compression of the TDigest
+func (td TDigest) Compression() float64 {
+	return td.compression
+}
+
+// Min returns the smallest value added or 0.0 if no values have been added
+func (td TDigest) Min() float64 {
+	return td.minVal
+}
+
+// Max returns the largest value added or 0.0 if no values have been added
+func (td TDigest) Max() float64 {
+	return td.maxVal
+}
+
+// Quantile returns an estimate of the value below which the given fraction
+// of the values fall; q should be in the range [0, 1] and is forced into
+// that range if not. It interpolates between the centroids and the
+// minimum and maximum values. It returns NaN if q is NaN and 0.0 if no
+// values have been added.
+func (td TDigest) Quantile(q float64) float64 {
+	if math.IsNaN(q) {
+		return math.NaN()
+	}
+	if td.count == 0 {
+		return 0.0
+	}
+	q = math.Max(0, math.Min(1, q))
+	cs := td.merged()
+	if len(cs) == 1 {
+		return cs[0].mean
+	}
+
+	target := q * float64(td.count)
+	first, last := cs[0], cs[len(cs)-1]
+	if target < float64(first.count)/2 {
+		return td.minVal + (first.mean-td.minVal)*
+			target/(float64(first.count)/2)
+	}
+	if target > float64(td.count)-float64(last.count)/2 {
+		tail := float64(td.count) - target
+		return td.maxVal - (td.maxVal-last.mean)*
+			tail/(float64(last.count)/2)
+	}
+
+	// find the neighbouring centroids whose centres lie either side of
+	// the target and interpolate between them
+	centre := float64(first.count) / 2
+	for i := 1; i < len(cs); i++ {
+		gap := float64(cs[i-1].count+cs[i].count) / 2
+		if target <= centre+gap {
+			return cs[i-1].mean +
+				(cs[i].mean-cs[i-1].mean)*(target-centre)/gap
+		}
+		centre += gap
+	}
+	return last.mean
+}
+
+// RankError returns an estimate of the error, as a fraction of the number
+// of values, in the rank of the value returned by Quantile for the given
+// fraction. The values summarised by each centroid are only known by their
+// mean and so the rank of a value within the centroid holding the quantile
+// is uncertain by up to half the number of values in the centroid. It is
+// smallest for quantiles near 0 and 1, where the centroids are smallest.
+// It returns NaN if q is NaN and 0.0 if no values have been added.
+func (td TDigest) RankError(q float64) float64 {
+	if math.IsNaN(q) {
+		return math.NaN()
+	}
+	if td.count == 0 {
+		return 0.0
+	}
+	q = math.Max(0, math.Min(1, q))
+	target := q * float64(td.count)
+	cum := 0
+	cs := td.merged()
+	for _, c := range cs {
+		cum += c.count
+		if target <= float64(cum) {
+			return float64(c.count) / 2 / float64(td.count)
+		}
+	}
+	return float64(cs[len(cs)-1].count) / 2 / float64(td.count)
+}
+
+// QuantileBounds returns the values of the quantiles either side of the
+// given fraction by its rank error (see RankError). The true value of the
+// quantile is very likely to lie between them. It returns zeros if no
+// values have been added.
+func (td TDigest) QuantileBounds(q float64) (lo, hi float64) {
+	e := td.RankError(q)
+	return td.Quantile(q - e), td.Quantile(q + e)
+}
+
+// Percentile returns an estimate of the value below which the given
+// percentage of the values fall; p should be in the range [0, 100]. See
+// Quantile.
+func (td TDigest) Percentile(p float64) float64 {
+	return td.Quantile(p / 100)
+}
+
+// CDF returns an estimate of the fraction of the values which are less
+// than or equal to the value. It returns 0.0 if no values have been added.
+func (td TDigest) CDF(v float64) float64 {
+	if td.count == 0 || v < td.minVal {
+		return 0.0
+	}
+	if v >= td.maxVal {
+		return 1.0
+	}
+	cs := td.merged()
+	total := float64(td.count)
+
+	prevMean, prevCum := td.minVal, 0.0
+	cum := 0.0
+	for _, c := range cs {
+		centre := cum + float64(c.count)/2
+		if v < c.mean {
+			if c.mean == prevMean {
+				return centre / total
+			}
+			return (prevCum + (centre-prevCum)*
+				(v-prevMean)/(c.mean-prevMean)) / total
+		}
+		prevMean, prevCum = c.mean, centre
+		cum += float64(c.count)
+	}
+	return (prevCum + (total-prevCum)*
+		(v-prevMean)/(td.maxVal-prevMean)) / total
+}
+
+// Merge adds the values summarised by the other TDigest to this one. The
+// TDigests need not have the same compression; the result has the
+// compression of this TDigest.
+func (td *TDigest) Merge(other *TDigest) {
+	if other.count == 0 {
+		return
+	}
+	for _, c := range other.merged() {
+		td.addCentroid(c)
+	}
+	td.minVal = math.Min(td.minVal, other.minVal)
+	td.maxVal = math.Max(td.maxVal, other.maxVal)
+}
+
+// Reset discards all the values
+func (td *TDigest) Reset() {
+	td.centroids = nil
+	td.buffer = td.buffer[:0]
+	td.count = 0
+	td.minVal = 0
+	td.maxVal = 0
+}
+
+// Save writes the state of the TDigest to the Writer in a compact,
+// versioned binary form. The TDigest can be recreated from this by the
+// Load method.
+func (td TDigest) Save(w io.Writer) error {
+	e := &encoder{w: w}
+
+	e.write([]byte(tdigestSerialMagic))
+	e.uvarint(tdigestSerialVersion)
+
+	e.float(td.compression)
+	e.int(td.count)
+	e.float(td.minVal)
+	e.float(td.maxVal)
+
+	cs := td.merged()
+	e.uvarint(uint64(len(cs)))
+	for _, c := range cs {
+		e.float(c.mean)
+		e.int(c.count)
+	}
+
+	return e.err
+}
+
+// Load replaces the state of the TDigest with that read from the Reader
+// which should have been written by the Save method. If an error is
+// returned the TDigest is unchanged.
+func (td *TDigest) Load(r io.Reader) error {
+	d := &decoder{r: &byteReader{r: r}}
+
+	magic := make([]byte, len(tdigestSerialMagic))
+	d.read(magic)
+	if d.err == nil && string(magic) != tdigestSerialMagic {
+		return errors.New("the data is not a serialized TDigest")
+	}
+	version := d.uvarint()
+	if d.err == nil && version != tdigestSerialVersion {
+		return fmt.Errorf("unsupported TDigest serialization version: %d",
+			version)
+	}
+
+	compression := d.float()
+	count := d.int()
+	minVal := d.float()
+	maxVal := d.float()
+	n := d.sliceLen("centroids")
+	if d.err != nil {
+		return fmt.Errorf("cannot load the TDigest: %w", d.err)
+	}
+
+	nt, err := NewTDigest(compression)
+	if err != nil {
+		return fmt.Errorf("cannot load the TDigest: %w", err)
+	}
+	nt.minVal, nt.maxVal = minVal, maxVal
+
+	total := 0
+	nt.centroids = make([]centroid, n)
+	for i := range nt.centroids {
+		nt.centroids[i].mean = d.float()
+		nt.centroids[i].count = d.int()
+		if nt.centroids[i].count <= 0 {
+			d.setErr(fmt.Errorf("bad centroid count: %d",
+				nt.centroids[i].count))
+		}
+		total += nt.centroids[i].count
+	}
+	if d.err != nil {
+		return fmt.Errorf("cannot load the TDigest: %w", d.err)
+	}
+	if total != count {
+		return fmt.Errorf(
+			"cannot load the TDigest: the centroids hold %d values, not %d",
+			total, count)
+	}
+	nt.count = count
+
+	*td = *nt
+	return nil
+}
+
+// GobEncode encodes the TDigest using the Save method. It allows a TDigest
+// to be written using the encoding/gob package.
+func (td TDigest) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := td.Save(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes the TDigest using the Load method. It allows a TDigest
+// to be read using the encoding/gob package.
+func (td *TDigest) GobDecode(b []byte) error {
+	return td.Load(bytes.NewReader(b))
+}
@@ -0,0 +1,131 @@
+package smpls
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestNewTDigest(t *testing.T) {
+	td, err := NewTDigest(0)
+	testhelper.CheckExpErrWithID(t, "default", err, testhelper.ExpErr{})
+	testhelper.DiffFloat(t, "default", "compression",
+		td.Compression(), dfltTDigestCompression, 0)
+
+	_, err = NewTDigest(5)
+	testhelper.CheckExpErrWithID(t, "too small", err,
+		testhelper.MkExpErr("Invalid compression (5) - it must be >= 10"))
+}
+
+func TestTDigestQuantile(t *testing.T) {
+	td := NewTDigestOrPanic(100)
+	testhelper.DiffFloat(t, "empty", "median", td.Quantile(0.5), 0, 0)
+
+	td.Add(42)
+	testhelper.DiffFloat(t, "one value", "median", td.Quantile(0.5), 42, 0)
+
+	td.Reset()
+	r := rand.New(rand.NewSource(7))
+	vals := make([]float64, 100000)
+	for i := range vals {
+		vals[i] = r.ExpFloat64()
+		td.Add(vals[i])
+	}
+	sorted := sortedCopy(vals)
+
+	testhelper.DiffInt(t, "exponential", "count", td.Count(), len(vals))
+	testhelper.DiffFloat(t, "exponential", "q0", td.Quantile(0), sorted[0], 0)
+	testhelper.DiffFloat(t, "exponential", "q1",
+		td.Quantile(1), sorted[len(sorted)-1], 0)
+	// the accuracy is measured as the error in the rank of the estimate
+	for _, q := range []float64{0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		act := td.Quantile(q)
+		rank := float64(sort.SearchFloat64s(sorted, act)) / float64(len(sorted))
+		if math.Abs(rank-q) > 0.002 {
+			t.Errorf("quantile %g: the estimate (%g) has rank %g", q, act, rank)
+		}
+		exp := sortedPercentile(sorted, q*100)
+		if cdf := td.CDF(exp); math.Abs(cdf-q) > 0.01 {
+			t.Errorf("CDF(%g): expected about %g, got %g", exp, q, cdf)
+		}
+	}
+	if n := len(td.merged()); n > 2*int(td.compression) {
+		t.Errorf("too many centroids: %d", n)
+	}
+
+	// the true quantile lies within the bounds
+	for _, q := range []float64{0.001, 0.5, 0.999} {
+		lo, hi := td.QuantileBounds(q)
+		exp := sortedPercentile(sorted, q*100)
+		if exp < lo || exp > hi {
+			t.Errorf("quantile %g: %g is not within the bounds [%g, %g]",
+				q, exp, lo, hi)
+		}
+	}
+	if td.RankError(0.999) >= td.RankError(0.5) {
+		t.Errorf("the rank error at the tail (%g) should be less than"+
+			" at the median (%g)", td.RankError(0.999), td.RankError(0.5))
+	}
+	testhelper.DiffFloat(t, "empty", "rank error",
+		NewTDigestOrPanic(100).RankError(0.5), 0, 0)
+	testhelper.DiffBool(t, "exponential", "quantile NaN is NaN",
+		math.IsNaN(td.Quantile(math.NaN())), true)
+}
+
+func TestTDigestMerge(t *testing.T) {
+	all := NewTDigestOrPanic(100)
+	workers := []*TDigest{
+		NewTDigestOrPanic(100),
+		NewTDigestOrPanic(200),
+		NewTDigestOrPanic(50),
+	}
+	for i := 0; i < 30000; i++ {
+		v := float64(i)
+		workers[i%len(workers)].Add(v)
+		all.Add(v)
+	}
+
+	merged := NewTDigestOrPanic(100)
+	for _, w := range workers {
+		merged.Merge(w)
+	}
+	testhelper.DiffInt(t, "merge", "count", merged.Count(), all.Count())
+	testhelper.DiffFloat(t, "merge", "min", merged.Min(), 0, 0)
+	testhelper.DiffFloat(t, "merge", "max", merged.Max(), 29999, 0)
+	for _, p := range []float64{1, 50, 99, 99.9} {
+		exp := p / 100 * 29999
+		testhelper.DiffFloat(t, "merge", "percentile",
+			merged.Percentile(p), exp, 0.01*29999)
+	}
+}
+
+func TestTDigestSaveLoad(t *testing.T) {
+	td := NewTDigestOrPanic(50)
+	for i := 0; i < 1000; i++ {
+		td.Add(float64(i % 97))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(td); err != nil {
+		t.Fatalf("unexpected encode error: %s", err)
+	}
+	var loaded TDigest
+	if err := gob.NewDecoder(&buf).Decode(&loaded); err != nil {
+		t.Fatalf("unexpected decode error: %s", err)
+	}
+
+	testhelper.DiffInt(t, "save/load", "count", loaded.Count(), td.Count())
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		testhelper.DiffFloat(t, "save/load", "quantile",
+			loaded.Quantile(q), td.Quantile(q), 0)
+	}
+
+	err := loaded.Load(bytes.NewReader([]byte("smpl\x01")))
+	testhelper.CheckExpErrWithID(t, "bad magic", err,
+		testhelper.MkExpErr("the data is not a serialized TDigest"))
+}
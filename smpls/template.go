@@ -0,0 +1,136 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+)
+
+// These are the texts of the built-in templates which can be passed to the
+// Render method. They can also be used as a starting point for your own
+// templates.
+const (
+	TmplCompact = `{{.Count}} obs,` +
+		` min: {{sci .Min}},` +
+		` avg: {{sci .Mean}},` +
+		` max: {{sci .Max}},` +
+		` SD: {{sci .SD}}`
+
+	TmplVerbose = `units:        {{.Units}}
+observations: {{.Count}}
+sum:          {{sci .Sum}}
+min:          {{sci .Min}} (mean of smallest: {{sci .MeanMin}})
+mean:         {{sci .Mean}}
+SD:           {{sci .SD}}
+max:          {{sci .Max}} (mean of largest: {{sci .MeanMax}})
+percentiles:  p25: {{sci .P25}} p50: {{sci .P50}} p75: {{sci .P75}}` +
+		` p90: {{sci .P90}} p99: {{sci .P99}}
+{{.Hist}}`
+
+	TmplMarkdown = `| units | count | min | mean | SD | p50 | p90 | p99 | max |
+|---|--:|--:|--:|--:|--:|--:|--:|--:|
+| {{.Units}} | {{.Count}} | {{sig .Min}} | {{sig .Mean}} | {{sig .SD}}` +
+		` | {{sig .P50}} | {{sig .P90}} | {{sig .P99}} | {{sig .Max}} |
+`
+)
+
+// tmplFuncs are the functions available to the templates used by the
+// Render methods:
+//
+//	sci - formats a value in scientific notation with 2 decimal places
+//	sig - formats a value to 3 significant figures
+//	pfx - formats a value in the given units with a unit prefix (see
+//	      FmtWithPrefix)
+var tmplFuncs = template.FuncMap{
+	"sci": func(v float64) string { return fmt.Sprintf("%.2e", v) },
+	"sig": func(v float64) string { return fmt.Sprintf("%.3g", v) },
+	"pfx": FmtWithPrefix,
+}
+
+// TemplateData holds the values made available to the templates used by
+// the Render methods. As well as the fields of the Snapshot it has the
+// standard deviation (as SD), some commonly used percentiles and the
+// histogram as a string (see the Hist method).
+type TemplateData struct {
+	Snapshot
+
+	SD  float64
+	P25 float64
+	P50 float64
+	P75 float64
+	P90 float64
+	P99 float64
+
+	Hist string
+}
+
+// snapshotPct returns the percentile from the Snapshot or NaN if it is
+// not recorded
+func snapshotPct(snap Snapshot, p float64) float64 {
+	if v, ok := snap.Percentile(p); ok {
+		return v
+	}
+	return math.NaN()
+}
+
+// TemplateData returns the values of the Stat to be passed to a template
+func (s Stat) TemplateData() TemplateData {
+	snap := s.Snapshot()
+	return TemplateData{
+		Snapshot: snap,
+		SD:       snap.StdDev,
+		P25:      snapshotPct(snap, 25),
+		P50:      snapshotPct(snap, 50),
+		P75:      snapshotPct(snap, 75),
+		P90:      snapshotPct(snap, 90),
+		P99:      snapshotPct(snap, 99),
+		Hist:     s.Hist(),
+	}
+}
+
+// NewTemplate creates a new template with the given name from the text.
+// The template has the functions available to the templates used by the
+// Render methods.
+func NewTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(tmplFuncs).Parse(text)
+}
+
+// Render returns the Stat formatted by the template text which may be one
+// of the built-in templates (TmplCompact, TmplVerbose or TmplMarkdown) or
+// your own. The template is given a TemplateData value.
+func (s Stat) Render(text string) (string, error) {
+	tmpl, err := NewTemplate("stat", text)
+	if err != nil {
+		return "", err
+	}
+	return s.RenderTemplate(tmpl)
+}
+
+// StatStringTemplate returns a function that will make the String method
+// of the Stat format it using the template text rather than the default
+// format. The text may be one of the built-in templates (TmplCompact,
+// TmplVerbose or TmplMarkdown) or your own. An error is returned if the
+// template cannot be parsed. Note that the template is not saved by the
+// Save method.
+func StatStringTemplate(text string) StatOpt {
+	return func(s *Stat) error {
+		tmpl, err := NewTemplate("String", text)
+		if err != nil {
+			return err
+		}
+		s.strTmpl = tmpl
+		return nil
+	}
+}
+
+// RenderTemplate returns the Stat formatted by the template. The template
+// is given a TemplateData value. Use NewTemplate to create a template which
+// can use the same functions as the built-in templates.
+func (s Stat) RenderTemplate(tmpl *template.Template) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, s.TemplateData()); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,74 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRender(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	addSeq(s, 1, 1, 10)
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		text   string
+		expVal string
+	}{
+		{
+			ID:   testhelper.MkID("compact"),
+			text: TmplCompact,
+			expVal: "10 obs, min: 1.00e+00, avg: 5.50e+00," +
+				" max: 1.00e+01, SD: 2.87e+00",
+		},
+		{
+			ID:   testhelper.MkID("markdown"),
+			text: TmplMarkdown,
+			expVal: "| units | count | min | mean | SD | p50 | p90 | p99 | max |\n" +
+				"|---|--:|--:|--:|--:|--:|--:|--:|--:|\n" +
+				"| ms | 10 | 1 | 5.5 | 2.87 | 5.5 | 9.1 | 9.91 | 10 |\n",
+		},
+		{
+			ID:     testhelper.MkID("user template"),
+			text:   `{{.Units}}: {{.Count}} {{printf "%.1f" .P50}}`,
+			expVal: "ms: 10 5.5",
+		},
+		{
+			ID:     testhelper.MkID("bad template"),
+			text:   `{{.Units`,
+			ExpErr: testhelper.MkExpErr("unclosed action"),
+		},
+		{
+			ID:     testhelper.MkID("bad field"),
+			text:   `{{.NoSuchField}}`,
+			ExpErr: testhelper.MkExpErr("can't evaluate field NoSuchField"),
+		},
+	}
+
+	for _, tc := range testCases {
+		str, err := s.Render(tc.text)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "rendered value",
+				str, tc.expVal)
+		}
+	}
+
+	verbose, err := s.Render(TmplVerbose)
+	if err != nil {
+		t.Fatal("unexpected error rendering the verbose template:", err)
+	}
+	testhelper.ShouldContain(t, "verbose", "rendered value", verbose,
+		[]string{"units:        ms\n", "observations: 10\n", "p99:"})
+}
+
+func TestStatStringTemplate(t *testing.T) {
+	s := NewStatOrPanic("ms", StatStringTemplate(TmplCompact))
+	s.Add(1, 2, 3)
+	testhelper.DiffString(t, "StatStringTemplate", "String", s.String(),
+		"3 obs, min: 1.00e+00, avg: 2.00e+00, max: 3.00e+00, SD: 8.16e-01")
+
+	_, err := NewStat("ms", StatStringTemplate("{{"))
+	testhelper.CheckExpErrWithID(t, "StatStringTemplate - bad", err,
+		testhelper.MkExpErr("unclosed action"))
+}
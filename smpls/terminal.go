@@ -0,0 +1,255 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nickwells/mathutil.mod/v2/mathutil"
+)
+
+const (
+	dfltTermWidth   = 80
+	minTermWidth    = 40
+	minTermBarWidth = 10
+
+	dfltTermBarRune = '█'
+
+	ansiReset = "\x1b[0m"
+)
+
+// TermColour is an ANSI colour used when rendering for a terminal
+type TermColour string
+
+// These are the available terminal colours
+const (
+	TermRed     TermColour = "31"
+	TermGreen   TermColour = "32"
+	TermYellow  TermColour = "33"
+	TermBlue    TermColour = "34"
+	TermMagenta TermColour = "35"
+	TermCyan    TermColour = "36"
+)
+
+// termChart holds the settings for a terminal chart of the histogram
+type termChart struct {
+	width        int
+	colour       bool
+	barRune      rune
+	barColour    TermColour
+	medianColour TermColour
+	p99Colour    TermColour
+}
+
+// paint returns the text in the colour, if colours are being used
+func (tc termChart) paint(text string, colour TermColour) string {
+	if !tc.colour || text == "" {
+		return text
+	}
+	return "\x1b[" + string(colour) + "m" + text + ansiReset
+}
+
+// TermOpt is the type of an option function that can be passed to
+// RenderTerm
+type TermOpt func(tc *termChart) error
+
+// TermWidth returns a function that will set the width of the terminal
+// chart in columns. By default the width of the terminal is used (see
+// TerminalWidth).
+func TermWidth(width int) TermOpt {
+	return func(tc *termChart) error {
+		if width < minTermWidth {
+			return invalidValue("Invalid terminal width (%d) - it must be >= %d",
+				width, minTermWidth)
+		}
+		tc.width = width
+		return nil
+	}
+}
+
+// TermNoColour returns a function that will stop the terminal chart using
+// ANSI colours. Colours are also not used if the NO_COLOR environment
+// variable is set.
+func TermNoColour() TermOpt {
+	return func(tc *termChart) error {
+		tc.colour = false
+		return nil
+	}
+}
+
+// TermColours returns a function that will set the colours of the
+// histogram bars and of the bars of the buckets holding the median and the
+// 99th percentile. An empty value leaves the colour unchanged.
+func TermColours(bar, median, p99 TermColour) TermOpt {
+	return func(tc *termChart) error {
+		if bar != "" {
+			tc.barColour = bar
+		}
+		if median != "" {
+			tc.medianColour = median
+		}
+		if p99 != "" {
+			tc.p99Colour = p99
+		}
+		return nil
+	}
+}
+
+// TermBarRune returns a function that will set the character used to draw
+// the bars of the terminal chart. By default a full block is used.
+func TermBarRune(r rune) TermOpt {
+	return func(tc *termChart) error {
+		if r == 0 || r == utf8.RuneError {
+			return invalidValue("Invalid bar rune (%q)", r)
+		}
+		tc.barRune = r
+		return nil
+	}
+}
+
+// TerminalWidth returns the width of the terminal in columns as given by
+// the COLUMNS environment variable or 80 if that is not set or is not
+// valid
+func TerminalWidth() int {
+	cols, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || cols < minTermWidth {
+		return dfltTermWidth
+	}
+	return cols
+}
+
+// termRow is a line of the terminal chart
+type termRow struct {
+	label  string
+	count  int
+	marker string
+	colour TermColour
+}
+
+// RenderTerm writes a chart of the histogram for reading in a terminal.
+// The bars are scaled to fill the width of the terminal (though they are
+// always at least 10 columns long, so in a very narrow terminal the lines
+// may be too wide) and are drawn in colour, with the buckets holding the
+// median and the 99th percentile highlighted and marked. The options
+// control the width and colours of the chart. It returns an error if the
+// options are bad, if the Stat has no histogram or if the chart cannot be
+// written.
+func (s Stat) RenderTerm(w io.Writer, opts ...TermOpt) error {
+	tc := termChart{
+		width:        TerminalWidth(),
+		colour:       os.Getenv("NO_COLOR") == "",
+		barRune:      dfltTermBarRune,
+		barColour:    TermBlue,
+		medianColour: TermGreen,
+		p99Colour:    TermRed,
+	}
+	for _, o := range opts {
+		if err := o(&tc); err != nil {
+			return err
+		}
+	}
+	if s.hist == nil {
+		return errors.New("the Stat has no histogram")
+	}
+
+	var b strings.Builder
+	if s.count == 0 {
+		fmt.Fprintf(&b, "%s: no values\n", s.units)
+	} else {
+		median, p99 := s.Percentile(50), s.Percentile(99)
+		fmt.Fprintf(&b, "%s: %d values, median: %s, p99: %s\n",
+			s.units, s.Count(),
+			tc.paint(fmt.Sprintf("%.4g", median), tc.medianColour),
+			tc.paint(fmt.Sprintf("%.4g", p99), tc.p99Colour))
+		s = s.histStat()
+		tc.writeRows(&b, s.termRows(tc, median, p99))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// termRows returns the rows of the terminal chart, including the
+// underflow and overflow if they hold any values. The histogram must be
+// populated.
+func (s Stat) termRows(tc termChart, median, p99 float64) []termRow {
+	_, end := s.bucketLimits(len(s.hist) - 1)
+	width, precision := mathutil.FmtValsForSigFigsMulti(3,
+		s.histStart(), s.histWidth(), end)
+	valFmt := fmt.Sprintf("%%%d.%df", width, precision)
+
+	medianIdx, p99Idx := s.bucketIdx(median), s.bucketIdx(p99)
+	row := func(idx int, label string, count int) termRow {
+		r := termRow{label: label, count: count, colour: tc.barColour}
+		var marks []string
+		if idx == medianIdx {
+			marks = append(marks, "median")
+			r.colour = tc.medianColour
+		}
+		if idx == p99Idx {
+			marks = append(marks, "p99")
+			if idx != medianIdx {
+				r.colour = tc.p99Colour
+			}
+		}
+		if len(marks) > 0 {
+			r.marker = "<- " + strings.Join(marks, ", ")
+		}
+		return r
+	}
+
+	var rows []termRow
+	if s.underflow > 0 {
+		rows = append(rows,
+			row(-1, "< "+fmt.Sprintf(valFmt, s.histStart()), s.underflow))
+	}
+	for i, count := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		rows = append(rows, row(i,
+			"["+fmt.Sprintf(valFmt, lo)+", "+fmt.Sprintf(valFmt, hi)+")",
+			count))
+	}
+	if s.overflow > 0 {
+		rows = append(rows,
+			row(len(s.hist), ">= "+fmt.Sprintf(valFmt, end), s.overflow))
+	}
+	return rows
+}
+
+// writeRows writes the rows of the chart, with the bars scaled so that
+// each line fits in the width of the chart
+func (tc termChart) writeRows(b *strings.Builder, rows []termRow) {
+	labelW, markerW, maxCount, total := 0, 0, 1, 0
+	for _, r := range rows {
+		labelW = max(labelW, len(r.label))
+		markerW = max(markerW, len(r.marker))
+		maxCount = max(maxCount, r.count)
+		total += r.count
+	}
+	countW := mathutil.Digits(int64(total))
+
+	// the label, count and percentage, each followed by a space
+	fixedW := labelW + 1 + countW + 1 + len("100.00%") + 1
+	if markerW > 0 {
+		fixedW += 1 + markerW
+	}
+	barW := max(minTermBarWidth, tc.width-fixedW)
+
+	for _, r := range rows {
+		barLen := barW * r.count / maxCount
+		bar := strings.Repeat(string(tc.barRune), barLen)
+		fmt.Fprintf(b, "%-*s %*d %6.2f%% %s",
+			labelW, r.label, countW, r.count,
+			100*float64(r.count)/float64(total),
+			tc.paint(bar, r.colour))
+		if r.marker != "" {
+			b.WriteString(strings.Repeat(" ", barW-barLen+1) +
+				tc.paint(r.marker, r.colour))
+		}
+		b.WriteString("\n")
+	}
+}
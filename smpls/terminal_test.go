@@ -0,0 +1,106 @@
+package smpls
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestRenderTermOpts(t *testing.T) {
+	s := NewStatOrPanic("ms")
+	s.Add(1, 2, 3)
+
+	var b strings.Builder
+	err := s.RenderTerm(&b, TermWidth(10))
+	testhelper.CheckExpErrWithID(t, "bad width", err,
+		testhelper.MkExpErr("Invalid terminal width (10) - it must be >= 40"))
+
+	err = s.RenderTerm(&b, TermBarRune(0))
+	testhelper.CheckExpErrWithID(t, "bad rune", err,
+		testhelper.MkExpErr("Invalid bar rune"))
+
+	err = NewStatOrPanic("ms", StatNoHist()).RenderTerm(&b)
+	testhelper.CheckExpErrWithID(t, "no hist", err,
+		testhelper.MkExpErr("the Stat has no histogram"))
+}
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "132")
+	testhelper.DiffInt(t, "COLUMNS set", "width", TerminalWidth(), 132)
+	t.Setenv("COLUMNS", "wide")
+	testhelper.DiffInt(t, "COLUMNS bad", "width", TerminalWidth(), 80)
+	t.Setenv("COLUMNS", "")
+	testhelper.DiffInt(t, "COLUMNS empty", "width", TerminalWidth(), 80)
+}
+
+func TestRenderTerm(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	s := NewStatOrPanic("ms", StatCacheSize(100), StatHistBucketCount(10))
+	addSeq(s, 0, 1, 100)
+	s.Add(-5, 200)
+
+	for _, width := range []int{60, 80, 120} {
+		var b strings.Builder
+		if err := s.RenderTerm(&b, TermWidth(width),
+			TermColours("", TermCyan, "")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out := b.String()
+		testhelper.ShouldContain(t, "colour", "chart", out, []string{
+			"ms: 102 values, median: ",
+			"\x1b[36m", "\x1b[31m", "\x1b[34m", ansiReset,
+			"<- median", "<- p99", "< ", ">= ",
+		})
+
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		testhelper.DiffInt(t, "colour", "line count", len(lines), 13)
+		for _, line := range lines[1:] {
+			plain := stripANSI(line)
+			if n := utf8.RuneCountInString(plain); n > width {
+				t.Errorf("width %d: the line is too long (%d): %q",
+					width, n, plain)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := s.RenderTerm(&b, TermNoColour(), TermBarRune('#')); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testhelper.ShouldContain(t, "no colour", "chart", b.String(),
+		[]string{"#", "<- median"})
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("there should be no ANSI codes: %q", b.String())
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	b.Reset()
+	if err := s.RenderTerm(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("NO_COLOR: there should be no ANSI codes: %q", b.String())
+	}
+
+	b.Reset()
+	if err := NewStatOrPanic("ms").RenderTerm(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testhelper.DiffString(t, "empty", "chart", b.String(), "ms: no values\n")
+}
+
+// stripANSI removes any ANSI colour sequences from the string
+func stripANSI(str string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(str, "\x1b[")
+		if i < 0 {
+			return b.String() + str
+		}
+		b.WriteString(str[:i])
+		str = str[i:]
+		str = str[strings.IndexByte(str, 'm')+1:]
+	}
+}
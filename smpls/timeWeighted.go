@@ -0,0 +1,96 @@
+package smpls
+
+import (
+	"math"
+	"time"
+)
+
+// timeWeight records the time-weighted mean and variance of the values
+// added with AddFor. It uses West's incremental algorithm so that the
+// variance does not suffer from the loss of precision of the naive
+// sum-of-squares formula.
+type timeWeight struct {
+	dur  time.Duration
+	mean float64
+	m2   float64 // the weighted sum of squared differences from the mean
+}
+
+// add records the value as having been in effect for the duration. Values
+// with a non-positive duration or which are not finite are ignored.
+func (tw *timeWeight) add(v float64, d time.Duration) {
+	if d <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	tw.dur += d
+	delta := v - tw.mean
+	tw.mean += delta * float64(d) / float64(tw.dur)
+	tw.m2 += float64(d) * delta * (v - tw.mean)
+}
+
+// merge combines the other time weighting into this one
+func (tw *timeWeight) merge(o timeWeight) {
+	if o.dur == 0 {
+		return
+	}
+	if tw.dur == 0 {
+		*tw = o
+		return
+	}
+	total := tw.dur + o.dur
+	delta := o.mean - tw.mean
+	tw.mean += delta * float64(o.dur) / float64(total)
+	tw.m2 += o.m2 +
+		delta*delta*float64(tw.dur)*float64(o.dur)/float64(total)
+	tw.dur = total
+}
+
+// AddFor adds the value to the Stat, as Add does, and also records it as
+// having been in effect for the given duration. This is the right way to
+// record a sampled gauge, such as the length of a queue or the memory in
+// use, where each reading holds until the next one; the time-weighted mean
+// gives the average over time rather than the average of the readings,
+// which would be skewed by readings taken more often at some times than at
+// others.
+//
+// The time-weighted statistics are of the values as given, before any
+// transform (see StatTransform), and include every value added with a
+// positive duration whatever the sample rate (see StatSampleRate), valid
+// range (see StatRange), deadband (see StatDeadband) or warm-up (see
+// StatWarmup). Values added with Add have no weight and are not included.
+func (s *Stat) AddFor(v float64, d time.Duration) {
+	s.addVal(v)
+	s.addTimeWeight(v, d)
+}
+
+// addTimeWeight records the value and duration in the time weighting of
+// the Stat and of any parent Stat (see Fork)
+func (s *Stat) addTimeWeight(v float64, d time.Duration) {
+	s.tw.add(v, d)
+	if s.parent != nil {
+		s.parent.addTimeWeight(v, d)
+	}
+}
+
+// TimeWeightedDuration returns the total of the durations of the values
+// added with AddFor
+func (s Stat) TimeWeightedDuration() time.Duration {
+	return s.tw.dur
+}
+
+// TimeWeightedMean returns the mean of the values added with AddFor, each
+// weighted by the duration it was in effect, or 0.0 if no such values have
+// been added
+func (s Stat) TimeWeightedMean() float64 {
+	return s.tw.mean
+}
+
+// TimeWeightedStdDev returns the standard deviation of the values added
+// with AddFor, each weighted by the duration it was in effect, or 0.0 if no
+// such values have been added. It is the standard deviation of the value
+// over the whole of the time covered.
+func (s Stat) TimeWeightedStdDev() float64 {
+	if s.tw.dur == 0 {
+		return 0.0
+	}
+	return math.Sqrt(math.Max(s.tw.m2, 0) / float64(s.tw.dur))
+}
@@ -0,0 +1,75 @@
+package smpls
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddFor(t *testing.T) {
+	s := NewStatOrPanic("items")
+	testhelper.DiffFloat(t, "empty", "mean", s.TimeWeightedMean(), 0, 0)
+	testhelper.DiffFloat(t, "empty", "SD", s.TimeWeightedStdDev(), 0, 0)
+
+	// a queue holding 10 items for 9s and then 100 items for 1s
+	s.AddFor(10, 9*time.Second)
+	s.AddFor(100, time.Second)
+	s.AddFor(50, 0)                     // no weight
+	s.AddFor(math.NaN(), 5*time.Second) // ignored
+
+	testhelper.DiffInt(t, "AddFor", "count", s.Count(), 4)
+	testhelper.DiffInt(t, "AddFor", "duration",
+		int(s.TimeWeightedDuration()), int(10*time.Second))
+	testhelper.DiffFloat(t, "AddFor", "mean", s.TimeWeightedMean(), 19, 1e-12)
+	// the weighted variance is (9*81 + 1*6561)/10 = 729
+	testhelper.DiffFloat(t, "AddFor", "SD", s.TimeWeightedStdDev(), 27, 1e-9)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal("Couldn't save the Stat:", err)
+	}
+	var loaded Stat
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal("Couldn't load the Stat:", err)
+	}
+	testhelper.DiffFloat(t, "loaded", "mean",
+		loaded.TimeWeightedMean(), 19, 1e-12)
+	testhelper.DiffFloat(t, "loaded", "SD",
+		loaded.TimeWeightedStdDev(), 27, 1e-9)
+
+	s.Reset()
+	testhelper.DiffInt(t, "Reset", "duration",
+		int(s.TimeWeightedDuration()), 0)
+}
+
+func TestAddForMergeAndFork(t *testing.T) {
+	whole := NewStatOrPanic("items")
+	whole.AddFor(10, 9*time.Second)
+	whole.AddFor(100, time.Second)
+	whole.AddFor(40, 5*time.Second)
+
+	a := NewStatOrPanic("items")
+	a.AddFor(10, 9*time.Second)
+	b := NewStatOrPanic("items")
+	b.AddFor(100, time.Second)
+	b.AddFor(40, 5*time.Second)
+	if err := a.Merge(b); err != nil {
+		t.Fatal("Couldn't merge the Stats:", err)
+	}
+	testhelper.DiffFloat(t, "merged", "mean",
+		a.TimeWeightedMean(), whole.TimeWeightedMean(), 1e-12)
+	testhelper.DiffFloat(t, "merged", "SD",
+		a.TimeWeightedStdDev(), whole.TimeWeightedStdDev(), 1e-9)
+
+	parent := NewStatOrPanic("items")
+	child := parent.Fork()
+	child.AddFor(10, 9*time.Second)
+	child.AddFor(100, time.Second)
+	testhelper.DiffFloat(t, "fork", "parent mean",
+		parent.TimeWeightedMean(), 19, 1e-12)
+	testhelper.DiffFloat(t, "fork", "child mean",
+		child.TimeWeightedMean(), 19, 1e-12)
+}
@@ -0,0 +1,76 @@
+package smpls
+
+import (
+	"time"
+)
+
+// durationUnits maps the units of a Stat to the duration of one unit, for
+// those units which are units of time
+var durationUnits = map[string]time.Duration{
+	"ns":      time.Nanosecond,
+	"us":      time.Microsecond,
+	"µs":      time.Microsecond,
+	"ms":      time.Millisecond,
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"seconds": time.Second,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hours":   time.Hour,
+}
+
+// AddDuration adds the duration to the Stat. It is added in the units of
+// the Stat if they are units of time, such as "ms" or "s", so that a Stat
+// in milliseconds records 1.5 for a duration of 1500µs; otherwise it is
+// added in seconds.
+func (s *Stat) AddDuration(d time.Duration) {
+	unit, ok := durationUnits[s.units]
+	if !ok {
+		unit = time.Second
+	}
+	s.Add(float64(d) / float64(unit))
+}
+
+// Timer measures the time taken by some work and adds it to a Stat (see
+// StartTimer)
+type Timer struct {
+	s     *Stat
+	start time.Time
+}
+
+// StartTimer returns a Timer which has started measuring the wall-clock
+// time. Calling its Stop method adds the time since it was started to the
+// Stat (see AddDuration). This removes the need to record the start time
+// and calculate the duration at each place where the time is measured:
+//
+//	t := s.StartTimer()
+//	doWork()
+//	t.Stop()
+//
+// or, to time the rest of a function:
+//
+//	defer s.StartTimer().Stop()
+func (s *Stat) StartTimer() Timer {
+	return Timer{s: s, start: time.Now()}
+}
+
+// Stop adds the time since the Timer was started to the Stat and returns
+// it. Each call adds the time since the start and so it should only be
+// called once.
+func (t Timer) Stop() time.Duration {
+	d := time.Since(t.start)
+	t.s.AddDuration(d)
+	return d
+}
+
+// Time calls the function, adds the wall-clock time it took to the Stat
+// (see AddDuration) and returns it
+func Time(s *Stat, fn func()) time.Duration {
+	t := s.StartTimer()
+	fn()
+	return t.Stop()
+}
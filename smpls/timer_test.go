@@ -0,0 +1,70 @@
+package smpls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddDuration(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		units string
+		d     time.Duration
+		exp   float64
+	}{
+		{
+			ID:    testhelper.MkID("milliseconds"),
+			units: "ms",
+			d:     1500 * time.Microsecond,
+			exp:   1.5,
+		},
+		{
+			ID:    testhelper.MkID("microseconds"),
+			units: "us",
+			d:     time.Millisecond,
+			exp:   1000,
+		},
+		{
+			ID:    testhelper.MkID("minutes"),
+			units: "min",
+			d:     90 * time.Second,
+			exp:   1.5,
+		},
+		{
+			ID:    testhelper.MkID("not time units"),
+			units: "bytes",
+			d:     time.Minute,
+			exp:   60,
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic(tc.units)
+		s.AddDuration(tc.d)
+		testhelper.DiffFloat(t, tc.IDStr(), "value", s.Sum(), tc.exp, 1e-12)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	const pause = 2 * time.Millisecond
+
+	s := NewStatOrPanic("ms")
+	timer := s.StartTimer()
+	time.Sleep(pause)
+	d := timer.Stop()
+	if d < pause {
+		t.Errorf("the Timer measured %s, less than the %s pause", d, pause)
+	}
+	testhelper.DiffInt(t, "Timer", "count", s.Count(), 1)
+	testhelper.DiffFloat(t, "Timer", "value",
+		s.Sum(), float64(d)/float64(time.Millisecond), 0)
+
+	d = Time(s, func() { time.Sleep(pause) })
+	if d < pause {
+		t.Errorf("Time measured %s, less than the %s pause", d, pause)
+	}
+	testhelper.DiffInt(t, "Time", "count", s.Count(), 2)
+	testhelper.DiffBool(t, "Time", "min", s.Min() >= 2, true)
+}
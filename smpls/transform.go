@@ -0,0 +1,24 @@
+package smpls
+
+// StatTransform returns a function that will make the Stat apply the
+// function to every value added before it is recorded. This can be used
+// to clamp values, to take their absolute value or to convert them to the
+// units of the Stat without changing the code which adds them. A Stat
+// created by Fork applies the same transform and the values it passes to
+// its parent are not transformed again.
+//
+// The function is not saved by Save and so a Stat recreated by Load does
+// not transform the values added to it.
+func StatTransform(f func(float64) float64) StatOpt {
+	return func(s *Stat) error {
+		if s.transform != nil {
+			return repeatedOption("the transform has already been set")
+		}
+		if f == nil {
+			return invalidValue("the transform function must not be nil")
+		}
+
+		s.transform = f
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatTransform(t *testing.T) {
+	toMillis := func(v float64) float64 { return v * 1000 }
+
+	s := NewStatOrPanic("ms", StatTransform(toMillis), StatCacheSize(10))
+	addSeq(s, 0.001, 0.001, 100)
+	testhelper.DiffFloat(t, "seconds to ms", "min", s.Min(), 1, 1e-9)
+	testhelper.DiffFloat(t, "seconds to ms", "max", s.Max(), 100, 1e-9)
+	testhelper.DiffFloat(t, "seconds to ms", "mean", s.Mean(), 50.5, 1e-9)
+
+	child := s.Fork()
+	child.Add(0.5)
+	testhelper.DiffFloat(t, "forked", "child max", child.Max(), 500, 1e-9)
+	testhelper.DiffFloat(t, "forked", "parent max", s.Max(), 500, 1e-9)
+
+	clamped := NewStatOrPanic("units",
+		StatTransform(func(v float64) float64 {
+			return math.Max(0, math.Min(10, v))
+		}))
+	clamped.AddSlice([]float64{-5, 5, 15})
+	clamped.AddWithInfo(20, "info")
+	testhelper.DiffFloat(t, "clamped", "sum", clamped.Sum(), 25, 0)
+}
+
+func TestStatTransformErrs(t *testing.T) {
+	abs := math.Abs
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID: testhelper.MkID("nil function"),
+			ExpErr: testhelper.MkExpErr(
+				"the transform function must not be nil"),
+			opts: []StatOpt{StatTransform(nil)},
+		},
+		{
+			ID:     testhelper.MkID("twice"),
+			ExpErr: testhelper.MkExpErr("the transform has already been set"),
+			opts:   []StatOpt{StatTransform(abs), StatTransform(abs)},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
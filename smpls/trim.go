@@ -0,0 +1,129 @@
+package smpls
+
+import (
+	"math"
+)
+
+// histSegment records the number of values in a part of the range of
+// values. The values are taken to be evenly spread between lo and hi. The
+// sum of the values is NaN unless the Stat records the sum of the values
+// in each bucket (see StatHistBucketSums).
+type histSegment struct {
+	lo, hi float64
+	count  int
+	sum    float64
+}
+
+// histSegments returns the histogram as a slice of segments including the
+// underflow (stretching from the minimum value to the start of the
+// histogram) and the overflow (stretching from the end of the histogram to
+// the maximum value). Empty segments are omitted.
+func (s Stat) histSegments() []histSegment {
+	s = s.histStat()
+
+	segs := make([]histSegment, 0, len(s.hist)+2)
+	if s.underflow > 0 {
+		segs = append(segs, s.underflowSegment())
+	}
+	for i, count := range s.hist {
+		if count > 0 {
+			segs = append(segs, s.bucketSegment(i))
+		}
+	}
+	if s.overflow > 0 {
+		segs = append(segs, s.overflowSegment())
+	}
+	return segs
+}
+
+// badTrimFraction returns true if the fraction is not in the range [0, 0.5)
+func badTrimFraction(fraction float64) bool {
+	return !(fraction >= 0 && fraction < 0.5)
+}
+
+// TrimmedMean returns the mean of the values after discarding the given
+// fraction of the values from each end of the distribution. The fraction
+// must be in the range [0, 0.5); NaN is returned if it is not. If all the
+// values are still held the result is exact, otherwise it is estimated
+// from the histogram taking the values to be evenly spread within each
+// bucket. NaN is returned if the values are no longer held and the Stat
+// has no histogram. It returns 0.0 if no values have been added.
+func (s Stat) TrimmedMean(fraction float64) float64 {
+	if badTrimFraction(fraction) {
+		return math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0
+	}
+
+	if vals := s.retained(); vals != nil {
+		sorted := sortedCopy(vals)
+		k := int(fraction * float64(len(sorted)))
+		return calcMean(sorted[k : len(sorted)-k])
+	}
+	if s.hist == nil {
+		return math.NaN()
+	}
+
+	sum, n := s.histMiddleSum(fraction)
+	return sum / n
+}
+
+// WinsorizedMean returns the mean of the values after replacing the given
+// fraction of the values at each end of the distribution with the nearest
+// remaining value. The fraction must be in the range [0, 0.5); NaN is
+// returned if it is not. If all the values are still held the result is
+// exact, otherwise it is estimated from the histogram taking the values to
+// be evenly spread within each bucket. NaN is returned if the values are no
+// longer held and the Stat has no histogram. It returns 0.0 if no values
+// have been added.
+func (s Stat) WinsorizedMean(fraction float64) float64 {
+	if badTrimFraction(fraction) {
+		return math.NaN()
+	}
+	if s.count == 0 {
+		return 0.0
+	}
+
+	if vals := s.retained(); vals != nil {
+		sorted := sortedCopy(vals)
+		n := len(sorted)
+		k := int(fraction * float64(n))
+		sum := float64(k) * (sorted[k] + sorted[n-k-1])
+		for _, v := range sorted[k : n-k] {
+			sum += v
+		}
+		return sum / float64(n)
+	}
+	if s.hist == nil {
+		return math.NaN()
+	}
+
+	sum, n := s.histMiddleSum(fraction)
+	trimmed := fraction * float64(s.count)
+	sum += trimmed * (s.histPercentile(100*fraction) +
+		s.histPercentile(100*(1-fraction)))
+	return sum / (n + 2*trimmed)
+}
+
+// histMiddleSum returns an estimate, from the histogram, of the sum of the
+// values remaining after discarding the given fraction of the values from
+// each end, along with the number of values remaining.
+func (s Stat) histMiddleSum(fraction float64) (sum, n float64) {
+	from := fraction * float64(s.count)
+	to := float64(s.count) - from
+
+	var cum float64
+	for _, seg := range s.histSegments() {
+		segFrom := math.Max(from, cum)
+		segTo := math.Min(to, cum+float64(seg.count))
+		if segTo > segFrom {
+			width := seg.hi - seg.lo
+			count := float64(seg.count)
+			midPos := ((segFrom - cum) + (segTo - cum)) / 2 / count
+			sum += (segTo - segFrom) * (seg.lo + width*midPos)
+		}
+		cum += float64(seg.count)
+	}
+	return sum, to - from
+}
@@ -0,0 +1,67 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTrimmedMean(t *testing.T) {
+	held := NewStatOrPanic("ms")
+	held.Add(1, 2, 3, 4, 5, 6, 7, 8, 9, 1000)
+
+	fromHist := NewStatOrPanic("ms", StatCacheSize(100),
+		StatHistBucketCount(10))
+	addSeq(fromHist, 0.5, 1, 100)
+
+	testCases := []struct {
+		testhelper.ID
+		s           *Stat
+		fraction    float64
+		expTrimmed  float64
+		expWinsored float64
+		epsilon     float64
+	}{
+		{
+			ID:          testhelper.MkID("held - no trimming"),
+			s:           held,
+			expTrimmed:  104.5,
+			expWinsored: 104.5,
+		},
+		{
+			ID:          testhelper.MkID("held - 10%"),
+			s:           held,
+			fraction:    0.1,
+			expTrimmed:  5.5,
+			expWinsored: 5.5,
+		},
+		{
+			ID:          testhelper.MkID("held - 20%"),
+			s:           held,
+			fraction:    0.2,
+			expTrimmed:  5.5,
+			expWinsored: 5.5,
+		},
+		{
+			ID:          testhelper.MkID("from hist - 10%"),
+			s:           fromHist,
+			fraction:    0.1,
+			expTrimmed:  50,
+			expWinsored: 50,
+			epsilon:     0.1,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffFloat(t, tc.IDStr(), "trimmed mean",
+			tc.s.TrimmedMean(tc.fraction), tc.expTrimmed, tc.epsilon)
+		testhelper.DiffFloat(t, tc.IDStr(), "winsorized mean",
+			tc.s.WinsorizedMean(tc.fraction), tc.expWinsored, tc.epsilon)
+	}
+
+	testhelper.DiffBool(t, "bad fraction", "trimmed mean is NaN",
+		math.IsNaN(held.TrimmedMean(0.5)), true)
+	testhelper.DiffBool(t, "bad fraction", "winsorized mean is NaN",
+		math.IsNaN(held.WinsorizedMean(-0.1)), true)
+}
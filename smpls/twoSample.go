@@ -0,0 +1,208 @@
+package smpls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ksSeriesTerms is the maximum number of terms of the series used to
+// calculate the Kolmogorov distribution
+const ksSeriesTerms = 100
+
+// TwoSampleTest holds the result of a statistical test of whether the
+// values in two Stats come from the same distribution
+type TwoSampleTest struct {
+	// Name is the name of the test
+	Name string
+	// CountA and CountB are the numbers of values used from each Stat
+	CountA int
+	CountB int
+
+	// Statistic is the test statistic: D for the Kolmogorov-Smirnov test
+	// and U for the Mann-Whitney test
+	Statistic float64
+	// Effect is a measure of the size of the difference. For the
+	// Kolmogorov-Smirnov test it is D, the largest difference between the
+	// cumulative distributions. For the Mann-Whitney test it is the
+	// probability that a value from A is larger than a value from B
+	// (counting ties as a half); 0.5 means neither tends to be larger.
+	Effect float64
+	// P is the probability of a Statistic at least this extreme if the
+	// values come from the same distribution
+	P float64
+}
+
+// Significant returns true if the P value is below the given significance
+// level (0.05 is a common choice)
+func (tst TwoSampleTest) Significant(alpha float64) bool {
+	return tst.P < alpha
+}
+
+// String returns a description of the result of the test
+func (tst TwoSampleTest) String() string {
+	return fmt.Sprintf("%s: %d vs %d values, statistic: %.4g,"+
+		" effect: %.3g, p: %.3g",
+		tst.Name, tst.CountA, tst.CountB, tst.Statistic, tst.Effect, tst.P)
+}
+
+// testSample returns the sorted values of the Stat to use in a two sample
+// test. These are all the values if they are still held, otherwise the
+// random sample of the values if the Stat keeps one.
+func (s Stat) testSample() ([]float64, error) {
+	vals := s.retained()
+	if vals == nil && s.reservoir != nil {
+		vals = s.reservoir.vals
+	}
+	if vals == nil {
+		return nil, errors.New("the Stat holds neither its values" +
+			" nor a random sample of them (see StatReservoirSize)")
+	}
+	if len(vals) == 0 {
+		return nil, errors.New("the Stat has no values")
+	}
+	return sortedCopy(vals), nil
+}
+
+// testSamples returns the sorted values of both Stats to use in a two
+// sample test
+func testSamples(a, b *Stat) ([]float64, []float64, error) {
+	valsA, err := a.testSample()
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad Stat A: %w", err)
+	}
+	valsB, err := b.testSample()
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad Stat B: %w", err)
+	}
+	return valsA, valsB, nil
+}
+
+// ksProb returns the probability that the Kolmogorov distribution exceeds
+// lambda
+func ksProb(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+
+	var sum float64
+	sign := 1.0
+	for k := 1; k <= ksSeriesTerms; k++ {
+		fk := float64(k)
+		term := sign * math.Exp(-2*fk*fk*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, 2*sum))
+}
+
+// KSTest performs the two-sample Kolmogorov-Smirnov test on the values in
+// the Stats. This tests whether the values come from the same
+// distribution and is sensitive to any difference in the distributions,
+// not just in their location.
+//
+// The test uses the values themselves and so each Stat must either still
+// hold all its values or keep a random sample of them (see
+// StatReservoirSize). The P value is from the asymptotic distribution of
+// the statistic and is approximate for small numbers of values.
+func KSTest(a, b *Stat) (TwoSampleTest, error) {
+	valsA, valsB, err := testSamples(a, b)
+	if err != nil {
+		return TwoSampleTest{}, err
+	}
+
+	nA, nB := float64(len(valsA)), float64(len(valsB))
+	var d float64
+	i, j := 0, 0
+	for i < len(valsA) && j < len(valsB) {
+		v := math.Min(valsA[i], valsB[j])
+		for i < len(valsA) && valsA[i] == v {
+			i++
+		}
+		for j < len(valsB) && valsB[j] == v {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/nA-float64(j)/nB))
+	}
+
+	ne := math.Sqrt(nA * nB / (nA + nB))
+	return TwoSampleTest{
+		Name:      "Kolmogorov-Smirnov",
+		CountA:    len(valsA),
+		CountB:    len(valsB),
+		Statistic: d,
+		Effect:    d,
+		P:         ksProb((ne + 0.12 + 0.11/ne) * d),
+	}, nil
+}
+
+// MannWhitney performs the Mann-Whitney U test (also known as the Wilcoxon
+// rank-sum test) on the values in the Stats. This tests whether values
+// from one Stat tend to be larger than those from the other and so is the
+// test to use to see if one version of some code is faster than another.
+// Unlike a comparison of the means it is not upset by outliers or by
+// values which are not normally distributed.
+//
+// The test uses the values themselves and so each Stat must either still
+// hold all its values or keep a random sample of them (see
+// StatReservoirSize). The P value is two-sided and is from the normal
+// approximation, with a correction for ties, and so it is approximate for
+// small numbers of values.
+func MannWhitney(a, b *Stat) (TwoSampleTest, error) {
+	valsA, valsB, err := testSamples(a, b)
+	if err != nil {
+		return TwoSampleTest{}, err
+	}
+
+	nA, nB := float64(len(valsA)), float64(len(valsB))
+	n := nA + nB
+
+	// sum the ranks of the A values, giving tied values the mean rank
+	var rankSumA, tieSum float64
+	i, j := 0, 0
+	for i < len(valsA) || j < len(valsB) {
+		v := math.Inf(1)
+		if i < len(valsA) {
+			v = valsA[i]
+		}
+		if j < len(valsB) {
+			v = math.Min(v, valsB[j])
+		}
+
+		startRank := float64(i + j + 1)
+		tiedA := 0
+		for i < len(valsA) && valsA[i] == v {
+			i++
+			tiedA++
+		}
+		for j < len(valsB) && valsB[j] == v {
+			j++
+		}
+		ties := float64(i+j+1) - startRank
+		rankSumA += float64(tiedA) * (startRank + (ties-1)/2)
+		tieSum += ties*ties*ties - ties
+	}
+
+	u := rankSumA - nA*(nA+1)/2
+	mean := nA * nB / 2
+	p := 1.0
+	if n > 1 {
+		sd := math.Sqrt(nA * nB / 12 * ((n + 1) - tieSum/(n*(n-1))))
+		if sd > 0 {
+			z := math.Max(0, math.Abs(u-mean)-0.5) / sd
+			p = math.Erfc(z / math.Sqrt2)
+		}
+	}
+
+	return TwoSampleTest{
+		Name:      "Mann-Whitney",
+		CountA:    len(valsA),
+		CountB:    len(valsB),
+		Statistic: u,
+		Effect:    u / (nA * nB),
+		P:         p,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestTwoSampleTests(t *testing.T) {
+	mkStat := func(vals ...float64) *Stat {
+		s := NewStatOrPanic("ms")
+		s.AddSlice(vals)
+		return s
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		a, b      *Stat
+		test      func(a, b *Stat) (TwoSampleTest, error)
+		expStat   float64
+		expEffect float64
+		expP      float64
+	}{
+		{
+			ID:        testhelper.MkID("Mann-Whitney: separate"),
+			a:         mkStat(1, 2, 3),
+			b:         mkStat(4, 5, 6),
+			test:      MannWhitney,
+			expStat:   0,
+			expEffect: 0,
+			expP:      0.0808556,
+		},
+		{
+			ID:        testhelper.MkID("Mann-Whitney: with ties"),
+			a:         mkStat(1, 2, 2, 3),
+			b:         mkStat(2, 3, 4, 5),
+			test:      MannWhitney,
+			expStat:   2.5,
+			expEffect: 2.5 / 16,
+			expP:      0.1366582,
+		},
+		{
+			ID:        testhelper.MkID("Mann-Whitney: identical"),
+			a:         mkStat(1, 2, 3),
+			b:         mkStat(1, 2, 3),
+			test:      MannWhitney,
+			expStat:   4.5,
+			expEffect: 0.5,
+			expP:      1,
+		},
+		{
+			ID:        testhelper.MkID("KS: separate"),
+			a:         mkStat(1, 2, 3),
+			b:         mkStat(4, 5, 6),
+			test:      KSTest,
+			expStat:   1,
+			expEffect: 1,
+			expP:      0.0326217,
+		},
+		{
+			ID:        testhelper.MkID("KS: identical"),
+			a:         mkStat(1, 2, 3),
+			b:         mkStat(1, 2, 3),
+			test:      KSTest,
+			expStat:   0,
+			expEffect: 0,
+			expP:      1,
+		},
+	}
+
+	for _, tc := range testCases {
+		res, err := tc.test(tc.a, tc.b)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %v\n", err)
+			continue
+		}
+		testhelper.DiffFloat(t, tc.IDStr(), "statistic",
+			res.Statistic, tc.expStat, 1e-9)
+		testhelper.DiffFloat(t, tc.IDStr(), "effect",
+			res.Effect, tc.expEffect, 1e-9)
+		testhelper.DiffFloat(t, tc.IDStr(), "P", res.P, tc.expP, 1e-6)
+	}
+}
+
+func TestTwoSampleReservoir(t *testing.T) {
+	a := NewStatOrPanic("ms", StatCacheSize(100), StatReservoirSize(500))
+	b := NewStatOrPanic("ms", StatCacheSize(100), StatReservoirSize(500))
+	for i := range 5000 {
+		a.Add(float64(i % 100))
+		b.Add(float64(i%100) + 10)
+	}
+
+	mw, err := MannWhitney(a, b)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testhelper.DiffInt(t, "reservoir", "count", mw.CountA, 500)
+	testhelper.DiffBool(t, "reservoir", "Significant",
+		mw.Significant(0.05), true)
+	if mw.Effect >= 0.5 {
+		t.Errorf("the A values should tend to be smaller: effect: %g",
+			mw.Effect)
+	}
+}
+
+func TestTwoSampleErrs(t *testing.T) {
+	good := NewStatOrPanic("ms")
+	good.Add(1, 2, 3)
+	noVals := NewStatOrPanic("ms", StatNoHist(), StatMinMaxCount(2))
+	noVals.Add(1, 2, 3)
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		a, b *Stat
+	}{
+		{
+			ID: testhelper.MkID("values not held"),
+			a:  noVals,
+			b:  good,
+			ExpErr: testhelper.MkExpErr("bad Stat A",
+				"the Stat holds neither its values nor a random sample"),
+		},
+		{
+			ID:     testhelper.MkID("empty"),
+			a:      good,
+			b:      NewStatOrPanic("ms"),
+			ExpErr: testhelper.MkExpErr("bad Stat B: the Stat has no values"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := KSTest(tc.a, tc.b)
+		testhelper.CheckExpErr(t, err, tc)
+		_, err = MannWhitney(tc.a, tc.b)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
@@ -0,0 +1,86 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// siPrefixes are the SI prefixes from atto (10^-18) to exa (10^18), the
+// entry with no prefix is at index siNoPrefixIdx
+var siPrefixes = []string{
+	"a", "f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E",
+}
+
+const siNoPrefixIdx = 6
+
+// iecPrefixes are the IEC binary prefixes from none to exbi (2^60)
+var iecPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+
+// unitSymbol returns the symbol to show for the units and whether values
+// in those units should be given binary (IEC) prefixes rather than SI
+// prefixes. Bytes are shown as "B" with binary prefixes and seconds as "s";
+// any other units are shown unchanged.
+func unitSymbol(units string) (string, bool) {
+	switch strings.ToLower(units) {
+	case "b", "byte", "bytes":
+		return "B", true
+	case "s", "sec", "secs", "second", "seconds":
+		return "s", false
+	}
+	return units, false
+}
+
+// FmtWithPrefix formats the value in the given units with an SI prefix (or,
+// if the units are bytes, an IEC binary prefix) chosen so that the number
+// shown is at least 1 and less than 1000 (or 1024). For instance, 0.0034
+// seconds is shown as "3.40 ms" and 1258291 bytes as "1.20 MiB". Values too
+// large or too small for the available prefixes are shown with the largest
+// or smallest prefix.
+func FmtWithPrefix(v float64, units string) string {
+	symbol, binary := unitSymbol(units)
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.2f %s", v, symbol)
+	}
+
+	prefixes, base, idx := siPrefixes, 1000.0, siNoPrefixIdx
+	if binary {
+		prefixes, base, idx = iecPrefixes, 1024.0, 0
+	}
+
+	exp := int(math.Floor(math.Log(math.Abs(v)) / math.Log(base)))
+	exp = max(exp, -idx)
+	exp = min(exp, len(prefixes)-1-idx)
+
+	m := v / math.Pow(base, float64(exp))
+	if math.Round(math.Abs(m)*100) >= base*100 && exp < len(prefixes)-1-idx {
+		exp++
+		m /= base
+	}
+
+	return fmt.Sprintf("%.2f %s%s", m, prefixes[idx+exp], symbol)
+}
+
+// StatUnitPrefixes returns a function that will make the String method of
+// the Stat show the values with SI prefixes appropriate to the units (see
+// FmtWithPrefix) rather than in scientific notation.
+func StatUnitPrefixes() StatOpt {
+	return func(s *Stat) error {
+		s.unitPrefixes = true
+		return nil
+	}
+}
+
+// prefixedString returns the statistics with the values shown with unit
+// prefixes
+func (s Stat) prefixedString() string {
+	min, meanMin, avg, sd, max, meanMax, count := s.Vals()
+	f := func(v float64) string { return FmtWithPrefix(v, s.units) }
+	return fmt.Sprintf(
+		"%7d observations,"+
+			" min: %s (%s),"+
+			" avg: %s,"+
+			" max: %s (%s),"+
+			" SD: %s",
+		count, f(min), f(meanMin), f(avg), f(max), f(meanMax), f(sd))
+}
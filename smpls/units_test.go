@@ -0,0 +1,78 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestFmtWithPrefix(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		v      float64
+		units  string
+		expVal string
+	}{
+		{ID: testhelper.MkID("zero"), v: 0, units: "s", expVal: "0.00 s"},
+		{ID: testhelper.MkID("ms"), v: 0.0034, units: "s", expVal: "3.40 ms"},
+		{
+			ID: testhelper.MkID("µs"), v: 12.5e-6, units: "seconds",
+			expVal: "12.50 µs",
+		},
+		{
+			ID: testhelper.MkID("negative"), v: -0.25, units: "V",
+			expVal: "-250.00 mV",
+		},
+		{
+			ID: testhelper.MkID("rounds up"), v: 999.999, units: "m",
+			expVal: "1.00 km",
+		},
+		{
+			ID: testhelper.MkID("MiB"), v: 1258291, units: "bytes",
+			expVal: "1.20 MiB",
+		},
+		{
+			ID: testhelper.MkID("small bytes"), v: 512, units: "B",
+			expVal: "512.00 B",
+		},
+		{
+			ID: testhelper.MkID("huge"), v: 1e24, units: "J",
+			expVal: "1000000.00 EJ",
+		},
+		{
+			ID: testhelper.MkID("tiny"), v: 1e-21, units: "s",
+			expVal: "0.00 as",
+		},
+		{
+			ID: testhelper.MkID("other units"), v: 2500, units: "requests",
+			expVal: "2.50 krequests",
+		},
+		{
+			ID: testhelper.MkID("NaN"), v: math.NaN(), units: "s",
+			expVal: "NaN s",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "formatted value",
+			FmtWithPrefix(tc.v, tc.units), tc.expVal)
+	}
+}
+
+func TestStatUnitPrefixes(t *testing.T) {
+	s := NewStatOrPanic("s", StatUnitPrefixes(), StatMinMaxCount(1))
+	s.Add(0.001, 0.003)
+	testhelper.DiffString(t, "StatUnitPrefixes", "String", s.String(),
+		"      2 observations,"+
+			" min: 1.00 ms (1.00 ms),"+
+			" avg: 2.00 ms,"+
+			" max: 3.00 ms (3.00 ms),"+
+			" SD: 1.00 ms")
+
+	str, err := s.Render(`{{pfx .Mean .Units}}`)
+	if err != nil {
+		t.Fatal("unexpected error rendering the template:", err)
+	}
+	testhelper.DiffString(t, "pfx", "rendered value", str, "2.00 ms")
+}
@@ -0,0 +1,122 @@
+package smpls
+
+import (
+	"fmt"
+	"math"
+)
+
+// RangeAction gives what a Stat does with a value outside its valid range
+// (see StatRange)
+type RangeAction int
+
+// These are the available range actions
+const (
+	// RangeClamp records the value as the nearest end of the range
+	RangeClamp RangeAction = iota
+	// RangeReject ignores the value
+	RangeReject
+	// RangeCount records the value as it is
+	RangeCount
+	rangeActionCount
+)
+
+// rangeActionNames gives the name of each RangeAction
+var rangeActionNames = [rangeActionCount]string{
+	RangeClamp:  "clamp",
+	RangeReject: "reject",
+	RangeCount:  "count",
+}
+
+// String returns the name of the RangeAction
+func (ra RangeAction) String() string {
+	if ra < 0 || ra >= rangeActionCount {
+		return fmt.Sprintf("RangeAction(%d)", int(ra))
+	}
+	return rangeActionNames[ra]
+}
+
+// ParseRangeAction returns the RangeAction with the given name (as given
+// by its String method) or an error if there is no such RangeAction
+func ParseRangeAction(name string) (RangeAction, error) {
+	for ra, n := range rangeActionNames {
+		if n == name {
+			return RangeAction(ra), nil
+		}
+	}
+	return 0, invalidValue("Invalid range action (%q)", name)
+}
+
+// validRange counts the values outside the range and clamps or rejects
+// them
+type validRange struct {
+	lo, hi  float64
+	action  RangeAction
+	outside int
+}
+
+// check returns the value to record and true or false if the value is to
+// be ignored, counting it if it is outside the range. A NaN is counted as
+// outside the range and is never clamped.
+func (vr *validRange) check(v float64) (float64, bool) {
+	if v >= vr.lo && v <= vr.hi {
+		return v, true
+	}
+	vr.outside++
+
+	switch {
+	case vr.action == RangeCount:
+		return v, true
+	case vr.action == RangeClamp && !math.IsNaN(v):
+		return min(max(v, vr.lo), vr.hi), true
+	}
+	return v, false
+}
+
+// StatRange returns a function that will make the Stat treat values below
+// lo or above hi as out of range; the action says whether they are
+// clamped into the range, rejected or recorded as they are. In every case
+// they are counted (see OutOfRangeCount). This stops the occasional wild
+// value, such as a glitch from a sensor, from distorting the statistics.
+// A NaN is always out of range and is rejected unless the action is
+// RangeCount.
+//
+// The range applies to values after any transform (see StatTransform)
+// and before any deadband (see StatDeadband) or sampling (see
+// StatSampleRate).
+func StatRange(lo, hi float64, action RangeAction) StatOpt {
+	return func(s *Stat) error {
+		if s.valid != nil {
+			return repeatedOption("the valid range has already been set")
+		}
+		if !(lo < hi) {
+			return invalidValue(
+				"Invalid range (%g to %g) - the minimum must be < the maximum",
+				lo, hi)
+		}
+		if action < 0 || action >= rangeActionCount {
+			return invalidValue("Invalid range action (%d)", action)
+		}
+
+		s.valid = &validRange{lo: lo, hi: hi, action: action}
+		return nil
+	}
+}
+
+// Range returns the valid range of the Stat, the action taken with values
+// outside it and true or zero values and false if the Stat has no valid
+// range (see StatRange)
+func (s Stat) Range() (float64, float64, RangeAction, bool) {
+	if s.valid == nil {
+		return 0, 0, 0, false
+	}
+	return s.valid.lo, s.valid.hi, s.valid.action, true
+}
+
+// OutOfRangeCount returns the number of values added which were outside
+// the valid range of the Stat (see StatRange)
+func (s Stat) OutOfRangeCount() int {
+	if s.valid == nil {
+		return 0
+	}
+	return s.valid.outside
+}
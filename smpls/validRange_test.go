@@ -0,0 +1,116 @@
+package smpls
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatRange(t *testing.T) {
+	vals := []float64{-5, 1, 2, 3, 100, math.NaN()}
+
+	testCases := []struct {
+		testhelper.ID
+		action   RangeAction
+		expCount int
+		expSum   float64
+	}{
+		{
+			ID:       testhelper.MkID("clamp"),
+			action:   RangeClamp,
+			expCount: 5,
+			expSum:   0 + 1 + 2 + 3 + 10,
+		},
+		{
+			ID:       testhelper.MkID("reject"),
+			action:   RangeReject,
+			expCount: 3,
+			expSum:   1 + 2 + 3,
+		},
+		{
+			ID:       testhelper.MkID("count"),
+			action:   RangeCount,
+			expCount: 6,
+			expSum:   math.NaN(),
+		},
+	}
+
+	for _, tc := range testCases {
+		s := NewStatOrPanic("units", StatRange(0, 10, tc.action))
+		s.AddVals(vals...)
+
+		testhelper.DiffInt(t, tc.IDStr(), "count", s.Count(), tc.expCount)
+		if math.IsNaN(tc.expSum) {
+			testhelper.DiffBool(t, tc.IDStr(), "sum is NaN",
+				math.IsNaN(s.Sum()), true)
+		} else {
+			testhelper.DiffFloat(t, tc.IDStr(), "sum", s.Sum(), tc.expSum, 0)
+		}
+		testhelper.DiffInt(t, tc.IDStr(), "out of range",
+			s.OutOfRangeCount(), 3)
+
+		lo, hi, action, ok := s.Range()
+		testhelper.DiffBool(t, tc.IDStr(), "has range", ok, true)
+		testhelper.DiffFloat(t, tc.IDStr(), "lo", lo, 0, 0)
+		testhelper.DiffFloat(t, tc.IDStr(), "hi", hi, 10, 0)
+		testhelper.DiffString(t, tc.IDStr(), "action",
+			action.String(), tc.action.String())
+
+		other := s.Clone()
+		_ = s.Merge(other)
+		testhelper.DiffInt(t, tc.IDStr(), "out of range after Merge",
+			s.OutOfRangeCount(), 6)
+		s.Reset()
+		testhelper.DiffInt(t, tc.IDStr(), "out of range after Reset",
+			s.OutOfRangeCount(), 0)
+	}
+
+	_, _, _, ok := NewStatOrPanic("units").Range()
+	testhelper.DiffBool(t, "no range", "has range", ok, false)
+}
+
+func TestStatRangeErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		opts []StatOpt
+	}{
+		{
+			ID:   testhelper.MkID("empty range"),
+			opts: []StatOpt{StatRange(1, 1, RangeClamp)},
+			ExpErr: testhelper.MkExpErr(
+				"Invalid range (1 to 1) - the minimum must be < the maximum"),
+		},
+		{
+			ID:     testhelper.MkID("bad action"),
+			opts:   []StatOpt{StatRange(0, 1, RangeAction(9))},
+			ExpErr: testhelper.MkExpErr("Invalid range action (9)"),
+		},
+		{
+			ID: testhelper.MkID("set twice"),
+			opts: []StatOpt{
+				StatRange(0, 1, RangeClamp),
+				StatRange(0, 1, RangeClamp),
+			},
+			ExpErr: testhelper.MkExpErr("the valid range has already been set"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("units", tc.opts...)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestParseRangeAction(t *testing.T) {
+	for _, ra := range []RangeAction{RangeClamp, RangeReject, RangeCount} {
+		got, err := ParseRangeAction(ra.String())
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", ra, err)
+		}
+		testhelper.DiffInt(t, ra.String(), "action", int(got), int(ra))
+	}
+	testhelper.DiffString(t, "bad action", "String",
+		RangeAction(-1).String(), "RangeAction(-1)")
+}
@@ -0,0 +1,116 @@
+package smpls
+
+import (
+	"time"
+)
+
+// warmup discards the values added while a process warms up, either the
+// first n values or those added in the first period after the first value
+type warmup struct {
+	n   int
+	dur time.Duration
+	now func() time.Time
+
+	start     time.Time
+	discarded int
+	done      bool
+}
+
+// skip returns true if the warm-up is still in progress, counting the value
+// as discarded. Once the warm-up is over it always returns false.
+func (w *warmup) skip() bool {
+	if w.done {
+		return false
+	}
+	if w.n > 0 {
+		if w.discarded < w.n {
+			w.discarded++
+			return true
+		}
+	} else {
+		t := w.now()
+		if w.start.IsZero() {
+			w.start = t
+		}
+		if t.Sub(w.start) < w.dur {
+			w.discarded++
+			return true
+		}
+	}
+	w.done = true
+	return false
+}
+
+// restart starts the warm-up again
+func (w *warmup) restart() {
+	w.start = time.Time{}
+	w.discarded = 0
+	w.done = false
+}
+
+// StatWarmup returns a function that will make the Stat discard the first
+// n values added. This keeps the transients seen while a process warms up,
+// such as slow requests while caches fill, out of the statistics of its
+// steady state. The number of values discarded is reported by
+// WarmupDiscarded; they are not counted in the Count or the Sum. The
+// warm-up only happens once; it is not restarted when the Stat is Reset
+// (see RestartWarmup). This cannot be used with StatWarmupDuration.
+func StatWarmup(n int) StatOpt {
+	return func(s *Stat) error {
+		if s.warmup != nil {
+			return repeatedOption("the warm-up has already been set")
+		}
+		if n < 1 {
+			return invalidValue(
+				"Invalid warm-up count (%d) - it must be >= 1", n)
+		}
+
+		s.warmup = &warmup{n: n, now: time.Now}
+		return nil
+	}
+}
+
+// StatWarmupDuration returns a function that will make the Stat discard
+// the values added in the given period from when the first value is added,
+// as StatWarmup does for the first n values. This cannot be used with
+// StatWarmup.
+func StatWarmupDuration(d time.Duration) StatOpt {
+	return func(s *Stat) error {
+		if s.warmup != nil {
+			return repeatedOption("the warm-up has already been set")
+		}
+		if d <= 0 {
+			return invalidValue(
+				"Invalid warm-up duration (%s) - it must be > 0", d)
+		}
+
+		s.warmup = &warmup{dur: d, now: time.Now}
+		return nil
+	}
+}
+
+// WarmingUp returns true if the Stat is still discarding the values added
+// while it warms up (see StatWarmup and StatWarmupDuration)
+func (s Stat) WarmingUp() bool {
+	return s.warmup != nil && !s.warmup.done
+}
+
+// WarmupDiscarded returns the number of values discarded while the Stat
+// was warming up (see StatWarmup and StatWarmupDuration)
+func (s Stat) WarmupDiscarded() int {
+	if s.warmup == nil {
+		return 0
+	}
+	return s.warmup.discarded
+}
+
+// RestartWarmup starts the warm-up of the Stat again so that the values
+// added next are discarded, as when the Stat was new. This is useful if
+// the process being measured is restarted. It does nothing if the Stat has
+// no warm-up.
+func (s *Stat) RestartWarmup() {
+	if s.warmup != nil {
+		s.warmup.restart()
+		s.hot = false
+	}
+}
@@ -0,0 +1,99 @@
+package smpls
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestStatWarmup(t *testing.T) {
+	s := NewStatOrPanic("ms", StatWarmup(5))
+	testhelper.DiffBool(t, "new", "warming up", s.WarmingUp(), true)
+
+	addSeq(s, 1, 1, 20)
+	testhelper.DiffBool(t, "warmed up", "warming up", s.WarmingUp(), false)
+	testhelper.DiffInt(t, "warmed up", "discarded", s.WarmupDiscarded(), 5)
+	testhelper.DiffInt(t, "warmed up", "count", s.Count(), 15)
+	testhelper.DiffFloat(t, "warmed up", "min", s.Min(), 6, 0)
+
+	s.Reset()
+	s.Add(1)
+	testhelper.DiffInt(t, "reset", "count", s.Count(), 1)
+	testhelper.DiffInt(t, "reset", "discarded", s.WarmupDiscarded(), 5)
+
+	s.RestartWarmup()
+	s.Add(1, 2, 3)
+	testhelper.DiffInt(t, "restarted", "count", s.Count(), 1)
+	testhelper.DiffInt(t, "restarted", "discarded", s.WarmupDiscarded(), 3)
+
+	f := s.Fork()
+	f.Add(1, 2)
+	testhelper.DiffInt(t, "forked", "count", f.Count(), 0)
+	testhelper.DiffInt(t, "forked", "parent count", s.Count(), 1)
+}
+
+func TestStatWarmupDuration(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStatOrPanic("ms", StatWarmupDuration(time.Minute))
+	s.warmup.now = func() time.Time { return now }
+
+	s.Add(1)
+	now = now.Add(59 * time.Second)
+	s.Add(2)
+	testhelper.DiffInt(t, "warming up", "count", s.Count(), 0)
+	testhelper.DiffInt(t, "warming up", "discarded", s.WarmupDiscarded(), 2)
+
+	now = now.Add(time.Second)
+	s.Add(3, 4)
+	testhelper.DiffInt(t, "warmed up", "count", s.Count(), 2)
+	testhelper.DiffFloat(t, "warmed up", "min", s.Min(), 3, 0)
+	testhelper.DiffBool(t, "warmed up", "warming up", s.WarmingUp(), false)
+}
+
+func TestStatWarmupErrs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		opts   []StatOpt
+		expErr error
+	}{
+		{
+			ID:     testhelper.MkID("bad count"),
+			opts:   []StatOpt{StatWarmup(0)},
+			expErr: ErrInvalidValue,
+		},
+		{
+			ID:     testhelper.MkID("bad duration"),
+			opts:   []StatOpt{StatWarmupDuration(-time.Second)},
+			expErr: ErrInvalidValue,
+		},
+		{
+			ID: testhelper.MkID("both"),
+			opts: []StatOpt{
+				StatWarmup(5),
+				StatWarmupDuration(time.Second),
+			},
+			expErr: ErrRepeatedOption,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewStat("ms", tc.opts...)
+		testhelper.DiffBool(t, tc.IDStr(), "expected error",
+			errors.Is(err, tc.expErr), true)
+	}
+}
+
+func TestStatWarmupForked(t *testing.T) {
+	s := NewStatOrPanic("ms", StatWarmup(5))
+	child := s.Fork()
+	addSeq(child, 1, 1, 10)
+
+	testhelper.DiffInt(t, "child", "count", child.Count(), 5)
+	testhelper.DiffInt(t, "child", "discarded", child.WarmupDiscarded(), 5)
+	// the values kept by the child are not discarded again by the parent
+	testhelper.DiffInt(t, "parent", "count", s.Count(), 5)
+	testhelper.DiffInt(t, "parent", "discarded", s.WarmupDiscarded(), 0)
+	testhelper.DiffBool(t, "parent", "warming up", s.WarmingUp(), true)
+}
@@ -0,0 +1,71 @@
+package smpls
+
+// WeightedValues returns the values of the Stat as a slice of values and a
+// slice of their weights, the form taken by the functions in the gonum
+// stat package (gonum.org/v1/gonum/stat) and by many other numerical
+// packages; for instance stat.Quantile(0.9, stat.Empirical, x, weights).
+// The values are in ascending order.
+//
+// While the Stat still holds all the values (see StatExact and
+// StatCacheSize) they are returned as they are and the weights are nil,
+// which gonum takes as giving each value a weight of 1. Once the values
+// are no longer held they are approximated from the histogram: each
+// non-empty bucket gives one value, the mean of the values in the bucket
+// if the Stat records the bucket sums (see StatHistBucketSums) or else its
+// mid-point, weighted by the number of values in it. Values in the
+// underflow or overflow are treated as lying in a bucket from the minimum
+// value to the start of the histogram, or from its end to the maximum
+// value. If the Stat has a sample rate (see StatSampleRate) the weights are
+// scaled to allow for the values not recorded.
+//
+// It returns nil slices if no values have been added or if the values are
+// no longer held and the Stat has no histogram.
+func (s Stat) WeightedValues() (x, weights []float64) {
+	if s.count == 0 {
+		return nil, nil
+	}
+	scale := s.sampleScale()
+
+	if vals := s.retained(); len(vals) == s.count {
+		x = sortedCopy(vals)
+		if scale != 1 {
+			weights = make([]float64, len(x))
+			for i := range weights {
+				weights[i] = scale
+			}
+		}
+		return x, weights
+	}
+
+	s = s.histStat()
+	if s.hist == nil {
+		return nil, nil
+	}
+
+	add := func(count int, lo, hi, sum float64) {
+		if count <= 0 {
+			return
+		}
+		v := (lo + hi) / 2
+		if s.bucketSums {
+			v = sum / float64(count)
+		}
+		x = append(x, v)
+		weights = append(weights, float64(count)*scale)
+	}
+
+	histLo, _ := s.bucketLimits(0)
+	_, histHi := s.bucketLimits(len(s.hist) - 1)
+	add(s.underflow, s.Min(), histLo, s.underflowSum)
+	for i, count := range s.hist {
+		lo, hi := s.bucketLimits(i)
+		sum := 0.0
+		if s.histSums != nil {
+			sum = s.histSums[i]
+		}
+		add(count, lo, hi, sum)
+	}
+	add(s.overflow, histHi, s.Max(), s.overflowSum)
+
+	return x, weights
+}
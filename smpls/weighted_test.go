@@ -0,0 +1,62 @@
+package smpls
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// weightedMean returns the mean of the values with the given weights; nil
+// weights give each value a weight of 1
+func weightedMean(x, weights []float64) float64 {
+	sum, total := 0.0, 0.0
+	for i, v := range x {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		sum += v * w
+		total += w
+	}
+	return sum / total
+}
+
+func TestWeightedValues(t *testing.T) {
+	x, w := NewStatOrPanic("ms").WeightedValues()
+	testhelper.DiffInt(t, "empty", "values", len(x), 0)
+	testhelper.DiffBool(t, "empty", "nil weights", w == nil, true)
+
+	s := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5))
+	s.Add(3, 1, 2)
+	x, w = s.WeightedValues()
+	if err := testhelper.DiffVals(x, []float64{1, 2, 3}); err != nil {
+		t.Errorf("retained: bad values: %v\n", err)
+	}
+	testhelper.DiffBool(t, "retained", "nil weights", w == nil, true)
+
+	addSeq(s, 0.5, 1, 100)
+	x, w = s.WeightedValues()
+	testhelper.DiffInt(t, "histogram", "values", len(x), len(w))
+	total := 0.0
+	for i, wt := range w {
+		total += wt
+		if i > 0 && x[i] < x[i-1] {
+			t.Errorf("histogram: values out of order at %d\n", i)
+		}
+	}
+	testhelper.DiffFloat(t, "histogram", "total weight", total, 103, 0)
+	testhelper.DiffFloat(t, "histogram", "mean",
+		weightedMean(x, w), s.Mean(), 2)
+
+	sums := NewStatOrPanic("ms", StatCacheSize(10), StatHistBucketCount(5),
+		StatHistBucketSums())
+	addSeq(sums, 0.5, 1, 100)
+	x, w = sums.WeightedValues()
+	testhelper.DiffFloat(t, "bucket sums", "mean",
+		weightedMean(x, w), sums.Mean(), 1e-9)
+
+	noHist := NewStatOrPanic("ms", StatNoHist())
+	addSeq(noHist, 1, 1, 100)
+	x, _ = noHist.WeightedValues()
+	testhelper.DiffInt(t, "no histogram", "values", len(x), 0)
+}